@@ -440,6 +440,74 @@ func (m *Server) osCapsOp(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+// getVolumeKey returns the master key of the encrypted volume, which wraps the data keys of
+// the files. The client needs both the API capability and the capability of the volume.
+func (m *Server) getVolumeKey(w http.ResponseWriter, r *http.Request) {
+	var (
+		plaintext []byte
+		err       error
+		jobj      proto.AuthVolumeKeyReq
+		ticket    cryptoutil.Ticket
+		ts        int64
+		message   string
+	)
+
+	if plaintext, err = m.extractClientReqInfo(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+
+	if err = json.Unmarshal([]byte(plaintext), &jobj); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "Unmarshal AuthVolumeKeyReq failed: " + err.Error()})
+		return
+	}
+
+	apiReq := jobj.APIReq
+	if apiReq.Type != proto.MsgAuthGetVolumeKeyReq || jobj.VolName == "" {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: fmt.Errorf("invalid request messge type %x or volume [%v]", int32(apiReq.Type), jobj.VolName).Error()})
+		return
+	}
+
+	if err = proto.VerifyAPIAccessReqIDs(&apiReq); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "VerifyAPIAccessReqIDs failed: " + err.Error()})
+		return
+	}
+
+	if ticket, ts, err = proto.ExtractAPIAccessTicket(&apiReq, m.cluster.AuthSecretKey); err != nil {
+		if err == proto.ErrExpiredTicket {
+			sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeExpiredTicket, Msg: "ExtractAPIAccessTicket failed: " + err.Error()})
+		} else {
+			sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "ExtractAPIAccessTicket failed: " + err.Error()})
+		}
+		return
+	}
+
+	if err = proto.CheckAPIAccessCaps(&ticket, proto.APIRsc, apiReq.Type, proto.APIAccess); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "CheckAPIAccessCaps failed: " + err.Error()})
+		return
+	}
+
+	if err = proto.CheckVOLAccessCaps(&ticket, jobj.VolName, proto.VOLAccess, proto.DataNode); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "CheckVOLAccessCaps failed: " + err.Error()})
+		return
+	}
+
+	if message, err = genAuthVolumeKeyResp(&apiReq, jobj.VolName, m.volumeKey(jobj.VolName), ts, ticket.SessionKey.Key); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeAuthAPIAccessGenRespError, Msg: err.Error()})
+		return
+	}
+
+	log.LogInfof("getVolumeKey: client(%v) volume(%v) remoteAddr(%v)", apiReq.ClientID, jobj.VolName, r.RemoteAddr)
+	sendOkReply(w, r, newSuccessHTTPAuthReply(message))
+	return
+}
+
+// volumeKey derives the master key of the volume from the root key of the keystore, so that
+// the key is the same on all the authnodes and never stored out of the authnodes.
+func (m *Server) volumeKey(volName string) []byte {
+	return cryptoutil.GenSecretKey(m.cluster.AuthRootKey, 0, volumeKeyPrefix+volName)
+}
+
 func (m *Server) genTicket(key []byte, serviceID string, IP string, caps []byte) (ticket cryptoutil.Ticket) {
 	currentTime := time.Now().Unix()
 	ticket.Version = cryptoutil.TicketVersion
@@ -657,6 +725,33 @@ func genAuthOSCapsOpResp(req *proto.APIAccessReq, akCaps *keystore.AccessKeyCaps
 	return
 }
 
+func genAuthVolumeKeyResp(req *proto.APIAccessReq, volName string, volKey []byte, ts int64, key []byte) (message string, err error) {
+	var (
+		jresp []byte
+		resp  proto.AuthVolumeKeyResp
+	)
+
+	resp.APIResp.Type = req.Type + 1
+	resp.APIResp.ClientID = req.ClientID
+	resp.APIResp.ServiceID = req.ServiceID
+	resp.APIResp.Verifier = ts + 1 // increase ts by one for client verify server
+
+	resp.VolName = volName
+	resp.Key = volKey
+
+	if jresp, err = json.Marshal(resp); err != nil {
+		err = fmt.Errorf("json marshal for response failed %s", err.Error())
+		return
+	}
+
+	if message, err = cryptoutil.EncodeMessage(jresp, key); err != nil {
+		err = fmt.Errorf("encode message for response failed %s", err.Error())
+		return
+	}
+
+	return
+}
+
 func newSuccessHTTPAuthReply(data interface{}) *proto.HTTPAuthReply {
 	return &proto.HTTPAuthReply{Code: proto.ErrCodeSuccess, Msg: proto.ErrSuc.Error(), Data: data}
 }
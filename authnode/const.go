@@ -37,3 +37,7 @@ const (
 	akAcronym = "ak"
 	akPrefix  = keySeparator + akAcronym + keySeparator
 )
+
+const (
+	volumeKeyPrefix = "volume" + keySeparator // derives the master keys of the encrypted volumes
+)
@@ -82,6 +82,8 @@ func (m *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch r.URL.Path {
 	case proto.ClientGetTicket:
 		m.getTicket(w, r)
+	case proto.ClientGetVolumeKey:
+		m.getVolumeKey(w, r)
 	case proto.AdminCreateKey:
 		fallthrough
 	case proto.AdminGetKey:
@@ -111,6 +113,7 @@ func (m *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (m *Server) handleFunctions() {
 	http.HandleFunc(proto.ClientGetTicket, m.getTicket)
+	http.HandleFunc(proto.ClientGetVolumeKey, m.getVolumeKey)
 	http.Handle(proto.AdminCreateKey, m.handlerWithInterceptor())
 	http.Handle(proto.AdminGetKey, m.handlerWithInterceptor())
 	http.Handle(proto.AdminDeleteKey, m.handlerWithInterceptor())
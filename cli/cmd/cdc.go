@@ -0,0 +1,138 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdCDCUse             = "cdc [COMMAND]"
+	cmdCDCShort           = "Read the change events of the namespace of volumes"
+	cmdCDCPartitionsUse   = "partitions [VOLNAME]"
+	cmdCDCPartitionsShort = "list the meta partitions of the volume, each of them has its own feed"
+	cmdCDCReadUse         = "read [VOLNAME] [PARTITION ID]"
+	cmdCDCReadShort       = "read the change events of the meta partition after the cursor"
+)
+
+func newCDCCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdCDCUse,
+		Short: cmdCDCShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	proto.InitBufferPool(32768)
+	cmd.AddCommand(
+		newCDCPartitionsCmd(client),
+		newCDCReadCmd(client),
+	)
+	return cmd
+}
+
+func newCDCPartitionsCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdCDCPartitionsUse,
+		Short: cmdCDCPartitionsShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			mw, err := newSnapshotMetaWrapper(client, args[0], 0)
+			if err != nil {
+				return
+			}
+			defer mw.Close()
+			for _, id := range mw.CDCPartitions() {
+				stdout("%v\n", id)
+			}
+		},
+	}
+	return cmd
+}
+
+func newCDCReadCmd(client *master.MasterClient) *cobra.Command {
+	var (
+		optFrom   uint64
+		optLimit  int
+		optFollow bool
+	)
+	cmd := &cobra.Command{
+		Use:   cmdCDCReadUse,
+		Short: cmdCDCReadShort,
+		Long: "Print the change events of the meta partition recorded after the cursor as json lines, the cursor is " +
+			"the applyId of the last event read. The events are recorded by the meta partitions of the volumes with " +
+			"enableCDC, and the oldest ones are dropped by the retention. A rename is the dentry deleted and created " +
+			"in the partitions of the parents, and the mutations of the transactions are not recorded.",
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			volName := args[0]
+			partitionID, err := strconv.ParseUint(args[1], 10, 64)
+			if err != nil {
+				err = fmt.Errorf("invalid partition id %v: %v", args[1], err)
+				return
+			}
+			mw, err := newSnapshotMetaWrapper(client, volName, 0)
+			if err != nil {
+				return
+			}
+			defer mw.Close()
+
+			cursor := optFrom
+			for {
+				var resp *proto.CDCReadResponse
+				if resp, err = mw.ReadCDC(partitionID, cursor, optLimit); err != nil {
+					err = fmt.Errorf("read events of partition %v after %v failed: %v", partitionID, cursor, err)
+					return
+				}
+				if cursor > 0 && resp.Oldest > cursor+1 && len(resp.Events) > 0 {
+					stdout("# the events before %v may have been dropped by the retention\n", resp.Oldest)
+				}
+				for _, event := range resp.Events {
+					var data []byte
+					if data, err = json.Marshal(event); err != nil {
+						return
+					}
+					stdout("%v\n", string(data))
+				}
+				cursor = resp.Next
+				if !optFollow {
+					stdout("# next %v, oldest %v, last %v, recording %v\n", resp.Next, resp.Oldest, resp.Last,
+						formatEnabledDisabled(resp.Enabled))
+					return
+				}
+				if len(resp.Events) == 0 {
+					time.Sleep(time.Second)
+				}
+			}
+		},
+	}
+	cmd.Flags().Uint64Var(&optFrom, CliFlagCDCFrom, 0, "Read the events after the cursor, 0 from the oldest one")
+	cmd.Flags().IntVar(&optLimit, CliFlagCDCLimit, proto.DefaultCDCReadLimit, "Specify the number of the events read at once")
+	cmd.Flags().BoolVar(&optFollow, CliFlagCDCFollow, false, "Keep reading the new events")
+	return cmd
+}
@@ -40,6 +40,7 @@ func newClusterCmd(client *master.MasterClient) *cobra.Command {
 		newClusterSetThresholdCmd(client),
 		newClusterSetParasCmd(client),
 		newClusterDisableMpDecommissionCmd(client),
+		newClusterRebalanceCmd(client),
 	)
 	return clusterCmd
 }
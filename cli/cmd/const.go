@@ -117,7 +117,21 @@ const (
 	CliFlagMaxConcurrencyInode = "maxConcurrencyInode"
 	CliFlagForceInode          = "forceInode"
 	CliFlagEnableQuota         = "enableQuota"
+	CliFlagEnableEncryption    = "enableEncryption"
+	CliFlagCompression         = "compression"
+	CliFlagEnableDedup         = "enableDedup"
+	CliFlagEnableCDC           = "enableCDC"
+	CliFlagECDataNum           = "ecDataNum"
+	CliFlagECParityNum         = "ecParityNum"
 	CliFlagDeleteLockTime      = "delete-lock-time"
+	CliFlagTrashInterval       = "trash-interval"
+	CliFlagTrashRestorePath    = "path"
+	CliFlagReplicationInterval = "interval"
+	CliFlagCDCFrom             = "from"
+	CliFlagCDCLimit            = "limit"
+	CliFlagCDCFollow           = "follow"
+	CliFlagConcurrency         = "concurrency"
+	CliFlagBandwidth           = "bandwidth"
 	CliFlagClientIDKey         = "clientIDKey"
 	CliFlagStoreMode           = "store-mode"
 	CliFlagCluster                = "cluster"
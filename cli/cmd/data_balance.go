@@ -0,0 +1,141 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdClusterRebalanceUse         = "rebalance [COMMAND]"
+	cmdClusterRebalanceShort       = "Balance the usage of the disks of the data nodes in the nodesets"
+	cmdClusterRebalancePlanShort   = "show the usage of the nodesets and the migrations planned, nothing is migrated"
+	cmdClusterRebalanceStartShort  = "start the data balancer, or change the config of it"
+	cmdClusterRebalanceStopShort   = "stop the data balancer, the migrations running are not interrupted"
+	cmdClusterRebalanceStatusShort = "show the config and the migrations of the data balancer"
+)
+
+func newClusterRebalanceCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdClusterRebalanceUse,
+		Short: cmdClusterRebalanceShort,
+		Long: "The data balancer of the master migrates the replicas of the data partitions from the fullest disks " +
+			"to the emptiest data nodes of the same nodeset, once the usage of a disk exceeds the average of the " +
+			"nodeset by the threshold.",
+	}
+	cmd.AddCommand(
+		newClusterRebalancePlanCmd(client),
+		newClusterRebalanceStartCmd(client),
+		newClusterRebalanceStopCmd(client),
+		newClusterRebalanceStatusCmd(client),
+	)
+	return cmd
+}
+
+func newClusterRebalancePlanCmd(client *master.MasterClient) *cobra.Command {
+	var optThreshold float64
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: cmdClusterRebalancePlanShort,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			view, err := client.AdminAPI().GetDataBalancePlan(optThreshold)
+			if err != nil {
+				return
+			}
+			stdout("%v", formatDataBalanceView(view))
+		},
+	}
+	cmd.Flags().Float64Var(&optThreshold, CliFlagThreshold, 0,
+		"Specify the usage above the average of the nodeset to be balanced, the threshold of the balancer by default")
+	return cmd
+}
+
+func newClusterRebalanceStartCmd(client *master.MasterClient) *cobra.Command {
+	var (
+		optThreshold   float64
+		optConcurrency int
+		optBandwidth   uint64
+	)
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: cmdClusterRebalanceStartShort,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			msg, err := client.AdminAPI().StartDataBalance(optThreshold, optConcurrency, optBandwidth)
+			if err != nil {
+				return
+			}
+			stdout("%v\n", msg)
+		},
+	}
+	cmd.Flags().Float64Var(&optThreshold, CliFlagThreshold, 0,
+		fmt.Sprintf("Specify the usage above the average of the nodeset to be balanced, default %v",
+			proto.DefaultDataBalanceThreshold))
+	cmd.Flags().IntVar(&optConcurrency, CliFlagConcurrency, 0,
+		fmt.Sprintf("Specify the replicas migrating at the same time, default %v", proto.DefaultDataBalanceConcurrency))
+	cmd.Flags().Uint64Var(&optBandwidth, CliFlagBandwidth, 0,
+		fmt.Sprintf("Specify the size of the replicas started to migrate per second in MB, default %v",
+			proto.DefaultDataBalanceBandwidthMB))
+	return cmd
+}
+
+func newClusterRebalanceStopCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: cmdClusterRebalanceStopShort,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			msg, err := client.AdminAPI().StopDataBalance()
+			if err != nil {
+				return
+			}
+			stdout("%v\n", msg)
+		},
+	}
+	return cmd
+}
+
+func newClusterRebalanceStatusCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: cmdClusterRebalanceStatusShort,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			view, err := client.AdminAPI().GetDataBalanceStatus()
+			if err != nil {
+				return
+			}
+			stdout("%v", formatDataBalanceView(view))
+		},
+	}
+	return cmd
+}
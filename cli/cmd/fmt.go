@@ -156,6 +156,7 @@ func formatSimpleVolView(svv *proto.SimpleVolView) string {
 	sb.WriteString(fmt.Sprintf("  Capacity                        : %v GB\n", svv.Capacity))
 	sb.WriteString(fmt.Sprintf("  Create time                     : %v\n", svv.CreateTime))
 	sb.WriteString(fmt.Sprintf("  DeleteLockTime                  : %v\n", svv.DeleteLockTime))
+	sb.WriteString(fmt.Sprintf("  TrashInterval                   : %v min\n", svv.TrashInterval))
 	sb.WriteString(fmt.Sprintf("  Cross zone                      : %v\n", formatEnabledDisabled(svv.CrossZone)))
 	sb.WriteString(fmt.Sprintf("  DefaultPriority                 : %v\n", svv.DefaultPriority))
 	sb.WriteString(fmt.Sprintf("  Dentry count                    : %v\n", svv.DentryCount))
@@ -181,6 +182,13 @@ func formatSimpleVolView(svv *proto.SimpleVolView) string {
 	sb.WriteString(fmt.Sprintf("  Forbidden                       : %v\n", svv.Forbidden))
 	sb.WriteString(fmt.Sprintf("  EnableAuditLog                  : %v\n", svv.EnableAuditLog))
 	sb.WriteString(fmt.Sprintf("  Quota                           : %v\n", formatEnabledDisabled(svv.EnableQuota)))
+	sb.WriteString(fmt.Sprintf("  Encryption                      : %v\n", formatEnabledDisabled(svv.EnableEncryption)))
+	sb.WriteString(fmt.Sprintf("  Compression                     : %v\n", formatCompression(svv.Compression)))
+	sb.WriteString(fmt.Sprintf("  Dedup                           : %v\n", formatEnabledDisabled(svv.EnableDedup)))
+	sb.WriteString(fmt.Sprintf("  CDC                             : %v\n", formatEnabledDisabled(svv.EnableCDC)))
+	if svv.ECDataNum > 0 {
+		sb.WriteString(fmt.Sprintf("  Erasure coding                  : RS %v+%v\n", svv.ECDataNum, svv.ECParityNum))
+	}
 
 	if svv.VolType == 1 {
 		sb.WriteString(fmt.Sprintf("  ObjBlockSize         : %v byte\n", svv.ObjBlockSize))
@@ -437,6 +445,9 @@ func formatDataPartitionInfo(partition *proto.DataPartitionInfo) string {
 	sb.WriteString(fmt.Sprintf("IsDiscard     : %v\n", partition.IsDiscard))
 	sb.WriteString(fmt.Sprintf("ReplicaNum    : %v\n", partition.ReplicaNum))
 	sb.WriteString(fmt.Sprintf("Forbidden     : %v\n", partition.Forbidden))
+	if partition.ECDataNum > 0 {
+		sb.WriteString(fmt.Sprintf("ErasureCoding : RS %v+%v\n", partition.ECDataNum, partition.ECParityNum))
+	}
 	sb.WriteString("\n")
 	sb.WriteString("Replicas : \n")
 	sb.WriteString(fmt.Sprintf("%v\n", formatDataReplicaTableHeader()))
@@ -464,6 +475,16 @@ func formatDataPartitionInfo(partition *proto.DataPartitionInfo) string {
 		sb.WriteString(fmt.Sprintf("  [%v]", host))
 	}
 	sb.WriteString("\n")
+	if partition.ECDataNum > 0 {
+		sb.WriteString("Shards :\n")
+		for i, host := range partition.ECHosts {
+			if host == "" {
+				host = "lost"
+			}
+			sb.WriteString(fmt.Sprintf("  [%v:%v]", i, host))
+		}
+		sb.WriteString("\n")
+	}
 	sb.WriteString("Zones :\n")
 	for _, zone := range partition.Zones {
 		sb.WriteString(fmt.Sprintf("  [%v]", zone))
@@ -628,6 +649,13 @@ func formatEnabledDisabled(b bool) string {
 	return "Disabled"
 }
 
+func formatCompression(compression string) string {
+	if compression == "" {
+		return "none"
+	}
+	return compression
+}
+
 func formatNodeStatus(status bool) string {
 	if status {
 		return "Active"
@@ -941,3 +969,117 @@ func formatDecommissionProgress(progress *proto.DecommissionProgress) string {
 	}
 	return sb.String()
 }
+
+var trashTableRowPattern = "%-12v %-40v %-20v %-20v    %v"
+
+func formatTrashTableHeader() string {
+	return fmt.Sprintf(trashTableRowPattern, "BUCKET", "NAME", "INODE", "DELETETIME", "PATH")
+}
+
+func formatTrashEntry(entry *proto.TrashEntry) string {
+	deleteTime := ""
+	if entry.DeleteTime > 0 {
+		deleteTime = time.Unix(entry.DeleteTime, 0).Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprintf(trashTableRowPattern, entry.Bucket, entry.Name, entry.Inode, deleteTime, entry.OrigPath)
+}
+
+func formatDuration(sec int64) string {
+	return (time.Duration(sec) * time.Second).String()
+}
+
+func formatVolReplication(view *proto.VolReplicationView) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Volume                          : %v\n", view.SrcVol))
+	sb.WriteString(fmt.Sprintf("  Target masters                  : %v\n", view.DstMasters))
+	sb.WriteString(fmt.Sprintf("  Target volume                   : %v\n", view.DstVol))
+	sb.WriteString(fmt.Sprintf("  Interval                        : %v sec\n", view.Interval))
+	sb.WriteString(fmt.Sprintf("  Status                          : %v\n", proto.ReplicationStatusString(view.Status)))
+	sb.WriteString(fmt.Sprintf("  Last snapshot                   : %v\n", view.LastVerSeq))
+	if view.LastVerTime > 0 {
+		sb.WriteString(fmt.Sprintf("  Last snapshot time              : %v\n", formatTime(view.LastVerTime)))
+		sb.WriteString(fmt.Sprintf("  Lag                             : %v\n", formatDuration(view.Lag)))
+	}
+	if view.LastSyncTime > 0 {
+		sb.WriteString(fmt.Sprintf("  Last round time                 : %v\n", formatTime(view.LastSyncTime)))
+	}
+	if view.FailoverTime > 0 {
+		sb.WriteString(fmt.Sprintf("  Failover time                   : %v\n", formatTime(view.FailoverTime)))
+	}
+	sb.WriteString(fmt.Sprintf("  Rounds                          : %v\n", view.Rounds))
+	sb.WriteString(fmt.Sprintf("  Synced files                    : %v\n", view.SyncedFiles))
+	sb.WriteString(fmt.Sprintf("  Synced bytes                    : %v\n", formatSize(uint64(view.SyncedBytes))))
+	if view.LastError != "" {
+		sb.WriteString(fmt.Sprintf("  Last error                      : %v\n", view.LastError))
+	}
+	return sb.String()
+}
+
+var volReplicationTableRowPattern = "%-20v    %-20v    %-10v    %-20v    %-12v    %v"
+
+func formatVolReplicationTableHeader() string {
+	return fmt.Sprintf(volReplicationTableRowPattern, "VOLUME", "TARGET", "STATUS", "LAST SNAPSHOT", "LAG", "MASTERS")
+}
+
+func formatVolReplicationRow(view *proto.VolReplicationView) string {
+	lag := "-"
+	if view.LastVerTime > 0 {
+		lag = formatDuration(view.Lag)
+	}
+	return fmt.Sprintf(volReplicationTableRowPattern, view.SrcVol, view.DstVol, proto.ReplicationStatusString(view.Status),
+		view.LastVerSeq, lag, view.DstMasters)
+}
+
+var (
+	dataBalanceUsageTableRowPattern = "%-16v    %-8v    %-8v    %-8v    %-40v    %v\n"
+	dataBalanceMoveTableRowPattern  = "%-10v    %-20v    %-24v    %-40v    %-24v    %-10v    %-10v    %v\n"
+)
+
+func formatDataBalanceDisk(usage *proto.DataBalanceUsage) string {
+	if usage == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%v:%v(%.2f%%)", usage.Addr, usage.Disk, usage.Usage*100)
+}
+
+func formatDataBalanceMoves(moves []*proto.DataBalanceMove) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf(dataBalanceMoveTableRowPattern, "PARTITION", "VOLUME", "SOURCE", "DISK", "TARGET",
+		"SIZE", "STATUS", "RESULT"))
+	for _, move := range moves {
+		sb.WriteString(fmt.Sprintf(dataBalanceMoveTableRowPattern, move.PartitionID, move.VolName, move.SrcAddr,
+			move.SrcDisk, move.DstAddr, formatSize(move.Size), move.Status, move.Result))
+	}
+	return sb.String()
+}
+
+func formatDataBalanceView(view *proto.DataBalanceView) string {
+	var sb = strings.Builder{}
+	if cfg := view.Config; cfg != nil {
+		sb.WriteString("[Config]\n")
+		sb.WriteString(fmt.Sprintf("  Enable                          : %v\n", cfg.Enable))
+		sb.WriteString(fmt.Sprintf("  Threshold                       : %v\n", cfg.Threshold))
+		sb.WriteString(fmt.Sprintf("  Concurrency                     : %v\n", cfg.Concurrency))
+		sb.WriteString(fmt.Sprintf("  Bandwidth                       : %v MB/s\n", cfg.BandwidthMB))
+	}
+	sb.WriteString("\n[Usage]\n")
+	sb.WriteString(fmt.Sprintf(dataBalanceUsageTableRowPattern, "ZONE/NODESET", "USAGE", "NODESKEW", "DISKSKEW",
+		"FULLEST DISK", "EMPTIEST DISK"))
+	for _, zone := range view.Zones {
+		sb.WriteString(fmt.Sprintf(dataBalanceUsageTableRowPattern, zone.Zone, fmt.Sprintf("%.2f%%", zone.Usage*100),
+			fmt.Sprintf("%.2f%%", zone.NodeSkew*100), fmt.Sprintf("%.2f%%", zone.DiskSkew*100), "", ""))
+		for _, ns := range zone.NodeSets {
+			sb.WriteString(fmt.Sprintf(dataBalanceUsageTableRowPattern, fmt.Sprintf("  %v", ns.ID),
+				fmt.Sprintf("%.2f%%", ns.Usage*100), fmt.Sprintf("%.2f%%", ns.NodeSkew*100),
+				fmt.Sprintf("%.2f%%", ns.DiskSkew*100), formatDataBalanceDisk(ns.FullestDisk),
+				formatDataBalanceDisk(ns.EmptiestDisk)))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("\n[Migrations] %v\n", len(view.Moves)))
+	sb.WriteString(formatDataBalanceMoves(view.Moves))
+	if len(view.Finished) > 0 {
+		sb.WriteString(fmt.Sprintf("\n[Finished] %v\n", len(view.Finished)))
+		sb.WriteString(formatDataBalanceMoves(view.Finished))
+	}
+	return sb.String()
+}
@@ -0,0 +1,219 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdReplicationUse           = "replication [COMMAND]"
+	cmdReplicationShort         = "Manage the asynchronous replication of volumes to another cluster"
+	cmdReplicationSetUse        = "set [VOLNAME] [DSTMASTERS] [DSTVOLNAME]"
+	cmdReplicationSetShort      = "set up the replication of the volume, or change the interval of it"
+	cmdReplicationInfoUse       = "info [VOLNAME]"
+	cmdReplicationInfoShort     = "show the progress of the replication of the volume"
+	cmdReplicationListShort     = "list the replications of the cluster"
+	cmdReplicationPauseUse      = "pause [VOLNAME]"
+	cmdReplicationPauseShort    = "pause the replication of the volume"
+	cmdReplicationResumeUse     = "resume [VOLNAME]"
+	cmdReplicationResumeShort   = "resume the replication of the volume"
+	cmdReplicationFailoverUse   = "failover [VOLNAME]"
+	cmdReplicationFailoverShort = "stop the replication of the volume for good, so the target volume can be written"
+	cmdReplicationDeleteUse     = "delete [VOLNAME]"
+	cmdReplicationDeleteShort   = "delete the replication of the volume"
+)
+
+func newReplicationCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdReplicationUse,
+		Short: cmdReplicationShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newReplicationSetCmd(client),
+		newReplicationInfoCmd(client),
+		newReplicationListCmd(client),
+		newReplicationStatusCmd(client, cmdReplicationPauseUse, cmdReplicationPauseShort, client.AdminAPI().PauseReplication),
+		newReplicationStatusCmd(client, cmdReplicationResumeUse, cmdReplicationResumeShort, client.AdminAPI().ResumeReplication),
+		newReplicationFailoverCmd(client),
+		newReplicationDeleteCmd(client),
+	)
+	return cmd
+}
+
+func newReplicationSetCmd(client *master.MasterClient) *cobra.Command {
+	var optInterval int64
+	cmd := &cobra.Command{
+		Use:   cmdReplicationSetUse,
+		Short: cmdReplicationSetShort,
+		Long: "Set up the replication of the hot volume to the volume of the cluster of DSTMASTERS, which are comma " +
+			"separated. Every interval the lcnode creates a snapshot of the volume and applies the paths changed since " +
+			"the snapshot replicated last time, the first round copies the whole tree. The target of an existing " +
+			"replication can not be changed, and the hard links are replicated as separate files.",
+		Args: cobra.RangeArgs(1, 3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err        error
+				dstMasters string
+				dstVol     string
+				view       *proto.VolReplicationView
+			)
+			defer func() {
+				errout(err)
+			}()
+			if len(args) == 2 {
+				err = fmt.Errorf("the masters and the volume of the target are required")
+				return
+			}
+			if len(args) == 3 {
+				dstMasters, dstVol = args[1], args[2]
+			}
+			if view, err = client.AdminAPI().SetReplication(args[0], dstMasters, dstVol, optInterval); err != nil {
+				return
+			}
+			stdout("[Replication]\n")
+			stdout("%v", formatVolReplication(view))
+		},
+	}
+	cmd.Flags().Int64Var(&optInterval, CliFlagReplicationInterval, 0,
+		fmt.Sprintf("Specify the interval of the rounds in seconds, default %v", proto.DefaultReplicationInterval))
+	return cmd
+}
+
+func newReplicationInfoCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdReplicationInfoUse,
+		Short: cmdReplicationInfoShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			view, err := client.AdminAPI().GetReplication(args[0])
+			if err != nil {
+				return
+			}
+			stdout("[Replication]\n")
+			stdout("%v", formatVolReplication(view))
+		},
+	}
+	return cmd
+}
+
+func newReplicationListCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   CliOpList,
+		Short: cmdReplicationListShort,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			views, err := client.AdminAPI().ListReplications()
+			if err != nil {
+				return
+			}
+			stdout("%v\n", formatVolReplicationTableHeader())
+			for _, view := range views {
+				stdout("%v\n", formatVolReplicationRow(view))
+			}
+		},
+	}
+	return cmd
+}
+
+func newReplicationStatusCmd(client *master.MasterClient, use, short string,
+	fn func(srcVol string) (*proto.VolReplicationView, error)) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			view, err := fn(args[0])
+			if err != nil {
+				return
+			}
+			stdout("The replication of volume %v is %v.\n", args[0], proto.ReplicationStatusString(view.Status))
+		},
+	}
+	return cmd
+}
+
+func newReplicationFailoverCmd(client *master.MasterClient) *cobra.Command {
+	var optYes bool
+	cmd := &cobra.Command{
+		Use:   cmdReplicationFailoverUse,
+		Short: cmdReplicationFailoverShort,
+		Long: "Stop the replication of the volume for good, the target volume has the tree of the snapshot replicated " +
+			"last time and the changes after it are not replicated. Delete the replication and set up the one from " +
+			"the target volume to replicate back.",
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			volName := args[0]
+			if !optYes {
+				stdout("Fail over the replication of volume %v, the replication can not be resumed.\n", volName)
+				stdout("\nConfirm (yes/no)[yes]: ")
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" && len(userConfirm) != 0 {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			view, err := client.AdminAPI().FailoverReplication(volName)
+			if err != nil {
+				return
+			}
+			stdout("The replication of volume %v is failed over, the target %v is %v behind.\n",
+				volName, view.DstVol, formatDuration(view.Lag))
+		},
+	}
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
+
+func newReplicationDeleteCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdReplicationDeleteUse,
+		Short: cmdReplicationDeleteShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			msg, err := client.AdminAPI().DeleteReplication(args[0])
+			if err != nil {
+				return
+			}
+			stdout("%v\n", msg)
+		},
+	}
+	return cmd
+}
@@ -78,6 +78,10 @@ func NewRootCmd(client *master.MasterClient) *CubeFSCmd {
 		newAclCmd(client),
 		newUidCmd(client),
 		newQuotaCmd(client),
+		newTrashCmd(client),
+		newSnapshotCmd(client),
+		newReplicationCmd(client),
+		newCDCCmd(client),
 		newDiskCmd(client),
 		newVersionCmd(client),
 	)
@@ -0,0 +1,172 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/master"
+	"github.com/cubefs/cubefs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdSnapshotUse        = "snapshot [COMMAND]"
+	cmdSnapshotShort      = "Manage the trees of volume snapshots"
+	cmdSnapshotCloneUse   = "clone [VOLNAME] [VERSEQ] [SRCPATH] [DSTPATH]"
+	cmdSnapshotCloneShort = "clone the tree of the snapshot to a writable tree of the volume without copying data"
+	cmdSnapshotDiffUse    = "diff [VOLNAME] [FROMVER] [TOVER]"
+	cmdSnapshotDiffShort  = "list the paths created, modified, deleted and renamed between the snapshots"
+)
+
+func newSnapshotCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdSnapshotUse,
+		Short: cmdSnapshotShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	proto.InitBufferPool(32768)
+	cmd.AddCommand(
+		newSnapshotCloneCmd(client),
+		newSnapshotDiffCmd(client),
+	)
+	return cmd
+}
+
+// newSnapshotMetaWrapper returns the meta wrapper reading the volume at the version, or the current
+// version if verSeq is 0.
+func newSnapshotMetaWrapper(client *master.MasterClient, volName string, verSeq uint64) (*meta.MetaWrapper, error) {
+	return meta.NewMetaWrapper(&meta.MetaConfig{
+		Volume:     volName,
+		Masters:    client.Nodes(),
+		VerReadSeq: verSeq,
+	})
+}
+
+func newSnapshotCloneCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdSnapshotCloneUse,
+		Short: cmdSnapshotCloneShort,
+		Long: "Clone the tree of the snapshot to a writable tree of the same volume. The files refer to the data of " +
+			"the snapshot and the data is copied on write, so the clone is cheap whatever the size of the tree is. " +
+			"Cloning to another volume is not supported, since the data partitions are owned by the volume.",
+		Args: cobra.MinimumNArgs(4),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			volName := args[0]
+			verSeq, err := strconv.ParseUint(args[1], 10, 64)
+			if err != nil {
+				err = fmt.Errorf("invalid version %v: %v", args[1], err)
+				return
+			}
+			srcPath, dstPath := path.Clean("/"+args[2]), path.Clean("/"+args[3])
+			if dstPath == "/" || dstPath == srcPath || strings.HasPrefix(dstPath, srcPath+"/") {
+				err = fmt.Errorf("invalid destination path %v", dstPath)
+				return
+			}
+
+			snap, err := newSnapshotMetaWrapper(client, volName, verSeq)
+			if err != nil {
+				return
+			}
+			defer snap.Close()
+			mw, err := newSnapshotMetaWrapper(client, volName, 0)
+			if err != nil {
+				return
+			}
+			defer mw.Close()
+
+			srcIno, err := snap.LookupPath(srcPath)
+			if err != nil {
+				err = fmt.Errorf("lookup %v of version %v failed: %v", srcPath, verSeq, err)
+				return
+			}
+			dstParent, err := mw.LookupPath(path.Dir(dstPath))
+			if err != nil {
+				err = fmt.Errorf("lookup %v failed: %v", path.Dir(dstPath), err)
+				return
+			}
+			stat, err := mw.CloneSnapshotTree(snap, srcIno, dstParent, path.Base(dstPath), dstPath)
+			if stat != nil {
+				stdout("dirs %v, files %v, links %v, bytes %v\n", stat.DirNum, stat.FileNum, stat.LinkNum, stat.TotalBytes)
+			}
+			if err != nil {
+				err = fmt.Errorf("clone %v of version %v to %v failed: %v", srcPath, verSeq, dstPath, err)
+				return
+			}
+			stdout("Clone %v of version %v to %v success.\n", srcPath, verSeq, dstPath)
+		},
+	}
+	return cmd
+}
+
+func newSnapshotDiffCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdSnapshotDiffUse,
+		Short: cmdSnapshotDiffShort,
+		Long: "List the paths changed from the version FROMVER to TOVER, TOVER 0 or omitted is the current version. " +
+			"Only a renamed directory itself is listed, not the entries moved with it.",
+		Args: cobra.RangeArgs(2, 3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				errout(err)
+			}()
+			volName := args[0]
+			fromVer, err := strconv.ParseUint(args[1], 10, 64)
+			if err != nil {
+				err = fmt.Errorf("invalid version %v: %v", args[1], err)
+				return
+			}
+			var toVer uint64
+			if len(args) > 2 {
+				if toVer, err = strconv.ParseUint(args[2], 10, 64); err != nil {
+					err = fmt.Errorf("invalid version %v: %v", args[2], err)
+					return
+				}
+			}
+
+			mw, err := newSnapshotMetaWrapper(client, volName, 0)
+			if err != nil {
+				return
+			}
+			defer mw.Close()
+
+			var count int
+			err = mw.SnapshotDiff(fromVer, toVer, func(item *proto.SnapshotDiffItem) bool {
+				count++
+				if item.Op == proto.SnapshotDiffRenamed {
+					stdout("%-8v %v -> %v\n", proto.SnapshotDiffOpString(item.Op), item.OldPath, item.Path)
+				} else {
+					stdout("%-8v %v\n", proto.SnapshotDiffOpString(item.Op), item.Path)
+				}
+				return true
+			})
+			if err != nil {
+				err = fmt.Errorf("diff version %v to %v failed: %v", fromVer, toVer, err)
+				return
+			}
+			stdout("%v paths changed.\n", count)
+		},
+	}
+	return cmd
+}
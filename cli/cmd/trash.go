@@ -0,0 +1,119 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"sort"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/master"
+	"github.com/cubefs/cubefs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdTrashUse          = "trash [COMMAND]"
+	cmdTrashShort        = "Manage the deleted files in volume trash"
+	cmdTrashListUse      = "list [volname]"
+	cmdTrashListShort    = "list the deleted files in trash"
+	cmdTrashRestoreUse   = "restore [volname] [bucket] [name]"
+	cmdTrashRestoreShort = "restore the deleted file to the original path or the specified path"
+)
+
+func newTrashCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdTrashUse,
+		Short: cmdTrashShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	proto.InitBufferPool(32768)
+	cmd.AddCommand(
+		newTrashListCmd(client),
+		newTrashRestoreCmd(client),
+	)
+	return cmd
+}
+
+func newTrashListCmd(client *master.MasterClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   cmdTrashListUse,
+		Short: cmdTrashListShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			volName := args[0]
+			metaConfig := &meta.MetaConfig{
+				Volume:  volName,
+				Masters: client.Nodes(),
+			}
+			metaWrapper, err := meta.NewMetaWrapper(metaConfig)
+			if err != nil {
+				stdout("NewMetaWrapper failed: %v\n", err)
+				return
+			}
+			defer metaWrapper.Close()
+
+			entries, err := metaWrapper.ListTrash_ll()
+			if err != nil {
+				stdout("volName %v trash list failed(%v)\n", volName, err)
+				return
+			}
+			sort.Slice(entries, func(i, j int) bool {
+				if entries[i].Bucket != entries[j].Bucket {
+					return entries[i].Bucket < entries[j].Bucket
+				}
+				return entries[i].Name < entries[j].Name
+			})
+			stdout("[trash]\n")
+			stdout("%v\n", formatTrashTableHeader())
+			for _, entry := range entries {
+				stdout("%v\n", formatTrashEntry(entry))
+			}
+		},
+	}
+	return cmd
+}
+
+func newTrashRestoreCmd(client *master.MasterClient) *cobra.Command {
+	var optPath string
+	cmd := &cobra.Command{
+		Use:   cmdTrashRestoreUse,
+		Short: cmdTrashRestoreShort,
+		Args:  cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			volName := args[0]
+			bucket := args[1]
+			name := args[2]
+			metaConfig := &meta.MetaConfig{
+				Volume:  volName,
+				Masters: client.Nodes(),
+			}
+			metaWrapper, err := meta.NewMetaWrapper(metaConfig)
+			if err != nil {
+				stdout("NewMetaWrapper failed: %v\n", err)
+				return
+			}
+			defer metaWrapper.Close()
+
+			restored, err := metaWrapper.RestoreTrash_ll(bucket, name, optPath)
+			if err != nil {
+				stdout("volName %v restore %v/%v failed(%v)\n", volName, bucket, name, err)
+				return
+			}
+			stdout("volName %v restore %v/%v to %v success.\n", volName, bucket, name, restored)
+		},
+	}
+	cmd.Flags().StringVar(&optPath, CliFlagTrashRestorePath, "", "Specify the path from volume root to restore to, default the original path")
+	return cmd
+}
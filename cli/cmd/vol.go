@@ -23,6 +23,7 @@ import (
 	"github.com/cubefs/cubefs/proto"
 	"github.com/cubefs/cubefs/sdk/master"
 	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/compressutil"
 	"github.com/spf13/cobra"
 )
 
@@ -137,6 +138,8 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 	var clientIDKey string
 	var optYes bool
 	var optStoreMode string
+	var optECDataNum int
+	var optECParityNum int
 	cmd := &cobra.Command{
 		Use:   cmdVolCreateUse,
 		Short: cmdVolCreateShort,
@@ -200,6 +203,9 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 				stdout("  TxConflictRetryNum       : %v\n", optTxConflictRetryNum)
 				stdout("  TxConflictRetryInterval  : %v ms\n", optTxConflictRetryInterval)
 				stdout("  StoreMode                : %v\n", optStoreMode)
+				if optECDataNum > 0 {
+					stdout("  ErasureCoding            : RS %v+%v\n", optECDataNum, optECParityNum)
+				}
 				stdout("\nConfirm (yes/no)[yes]: ")
 				var userConfirm string
 				_, _ = fmt.Scanln(&userConfirm)
@@ -227,7 +233,8 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 				optZoneName, optCacheRuleKey, optEbsBlkSize, optCacheCap,
 				optCacheAction, optCacheThreshold, optCacheTTL, optCacheHighWater,
 				optCacheLowWater, optCacheLRUInterval, dpReadOnlyWhenVolFull,
-				optTxMask, optTxTimeout, optTxConflictRetryNum, optTxConflictRetryInterval, optEnableQuota, clientIDKey, storeMode)
+				optTxMask, optTxTimeout, optTxConflictRetryNum, optTxConflictRetryInterval, optEnableQuota, clientIDKey, storeMode,
+				optECDataNum, optECParityNum)
 			if err != nil {
 				err = fmt.Errorf("Create volume failed case:\n%v\n", err)
 				return
@@ -266,6 +273,8 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 	cmd.Flags().StringVar(&optEnableQuota, CliFlagEnableQuota, "false", "Enable quota (default false)")
 	cmd.Flags().Int64Var(&optDeleteLockTime, CliFlagDeleteLockTime, 0, "Specify delete lock time[Unit: hour] for volume")
 	cmd.Flags().StringVar(&optStoreMode, CliFlagStoreMode, "memory", "Specify default store mode of mp")
+	cmd.Flags().IntVar(&optECDataNum, CliFlagECDataNum, 0, "Specify the data shards of the erasure coded data partitions, 0 for the replicated ones")
+	cmd.Flags().IntVar(&optECParityNum, CliFlagECParityNum, 0, "Specify the parity shards of the erasure coded data partitions")
 	return cmd
 }
 
@@ -299,7 +308,12 @@ func newVolUpdateCmd(client *master.MasterClient) *cobra.Command {
 	var optTxOpLimitVal int
 	var optReplicaNum string
 	var optDeleteLockTime int64
+	var optTrashInterval int64
 	var optEnableQuota string
+	var optEnableEncryption bool
+	var optCompression string
+	var optEnableDedup string
+	var optEnableCDC string
 	var optStoreMode string
 	confirmString := strings.Builder{}
 	var vv *proto.SimpleVolView
@@ -407,6 +421,62 @@ func newVolUpdateCmd(client *master.MasterClient) *cobra.Command {
 			}
 			confirmString.WriteString(fmt.Sprintf("  EnableQuota : %v\n", formatEnabledDisabled(vv.EnableQuota)))
 
+			if optEnableEncryption && !vv.EnableEncryption {
+				isChange = true
+				confirmString.WriteString(fmt.Sprintf("  Encryption                : %v -> %v\n", formatEnabledDisabled(vv.EnableEncryption), formatEnabledDisabled(true)))
+				vv.EnableEncryption = true
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Encryption                : %v\n", formatEnabledDisabled(vv.EnableEncryption)))
+			}
+
+			if optCompression != "" {
+				var codec uint8
+				if codec, err = compressutil.Parse(optCompression); err != nil {
+					return
+				}
+				if compression := compressutil.Name(codec); compression != formatCompression(vv.Compression) {
+					isChange = true
+					confirmString.WriteString(fmt.Sprintf("  Compression               : %v -> %v\n", formatCompression(vv.Compression), compression))
+					vv.Compression = compression
+				} else {
+					confirmString.WriteString(fmt.Sprintf("  Compression               : %v\n", formatCompression(vv.Compression)))
+				}
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Compression               : %v\n", formatCompression(vv.Compression)))
+			}
+
+			if optEnableDedup != "" {
+				var enable bool
+				if enable, err = strconv.ParseBool(optEnableDedup); err != nil {
+					return
+				}
+				if enable != vv.EnableDedup {
+					isChange = true
+					confirmString.WriteString(fmt.Sprintf("  Dedup                     : %v -> %v\n", formatEnabledDisabled(vv.EnableDedup), formatEnabledDisabled(enable)))
+					vv.EnableDedup = enable
+				} else {
+					confirmString.WriteString(fmt.Sprintf("  Dedup                     : %v\n", formatEnabledDisabled(vv.EnableDedup)))
+				}
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Dedup                     : %v\n", formatEnabledDisabled(vv.EnableDedup)))
+			}
+
+			if optEnableCDC != "" {
+				var enable bool
+				if enable, err = strconv.ParseBool(optEnableCDC); err != nil {
+					return
+				}
+				if enable != vv.EnableCDC {
+					isChange = true
+					confirmString.WriteString(fmt.Sprintf("  CDC                       : %v -> %v\n", formatEnabledDisabled(vv.EnableCDC), formatEnabledDisabled(enable)))
+					vv.EnableCDC = enable
+				} else {
+					confirmString.WriteString(fmt.Sprintf("  CDC                       : %v\n", formatEnabledDisabled(vv.EnableCDC)))
+				}
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  CDC                       : %v\n", formatEnabledDisabled(vv.EnableCDC)))
+			}
+
 			if optDeleteLockTime >= 0 {
 				if optDeleteLockTime != vv.DeleteLockTime {
 					isChange = true
@@ -419,6 +489,14 @@ func newVolUpdateCmd(client *master.MasterClient) *cobra.Command {
 				confirmString.WriteString(fmt.Sprintf("  DeleteLockTime            : %v h\n", vv.DeleteLockTime))
 			}
 
+			if optTrashInterval >= 0 && optTrashInterval != vv.TrashInterval {
+				isChange = true
+				confirmString.WriteString(fmt.Sprintf("  TrashInterval             : %v min -> %v min\n", vv.TrashInterval, optTrashInterval))
+				vv.TrashInterval = optTrashInterval
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  TrashInterval             : %v min\n", vv.TrashInterval))
+			}
+
 			// var maskStr string
 			if optTxMask != "" {
 				var oldMask, newMask proto.TxOpMask
@@ -654,7 +732,12 @@ func newVolUpdateCmd(client *master.MasterClient) *cobra.Command {
 	cmd.Flags().IntVar(&optTxOpLimitVal, CliTxOpLimit, 0, "Specify limitation[Unit: second] for transaction(default 0 unlimited)")
 	cmd.Flags().StringVar(&optReplicaNum, CliFlagReplicaNum, "", "Specify data partition replicas number(default 3 for normal volume,1 for low volume)")
 	cmd.Flags().StringVar(&optEnableQuota, CliFlagEnableQuota, "", "Enable quota")
+	cmd.Flags().BoolVar(&optEnableEncryption, CliFlagEnableEncryption, false, "Encrypt the files created from now on by the clients, which could not be disabled later")
+	cmd.Flags().StringVar(&optCompression, CliFlagCompression, "", "Compress the data written from now on by the clients [zstd|lz4|snappy|none]")
+	cmd.Flags().StringVar(&optEnableDedup, CliFlagEnableDedup, "", "Deduplicate the data appended from now on by the clients [true|false]")
+	cmd.Flags().StringVar(&optEnableCDC, CliFlagEnableCDC, "", "Record the mutations of the namespace for the change-data-capture [true|false]")
 	cmd.Flags().Int64Var(&optDeleteLockTime, CliFlagDeleteLockTime, -1, "Specify delete lock time[Unit: hour] for volume")
+	cmd.Flags().Int64Var(&optTrashInterval, CliFlagTrashInterval, -1, "Specify retention of the deleted files in trash[Unit: min], 0 to disable the trash")
 	cmd.Flags().StringVar(&clientIDKey, CliFlagClientIDKey, client.ClientIDKey(), CliUsageClientIDKey)
 	cmd.Flags().StringVar(&optStoreMode, CliFlagStoreMode, "", "Specify default store mode of mp")
 
@@ -55,6 +55,12 @@ const (
 	DefaultFlag = 0x0f
 )
 
+const (
+	// the polling interval of the blocking lock requests
+	LockWaitMinInterval = 10 * time.Millisecond
+	LockWaitMaxInterval = time.Second
+)
+
 var (
 	// The following two are used in the FUSE cache
 	// every time the lookup will be performed on the fly, and the result will not be cached
@@ -164,7 +164,7 @@ func (d *Dir) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.Cr
 		auditlog.LogClientOp("Create", fullPath, "nil", err, time.Since(start).Microseconds(), newInode, 0)
 	}()
 
-	info, err := d.super.mw.Create_ll(d.info.Inode, req.Name, proto.Mode(req.Mode.Perm()), req.Uid, req.Gid, nil, fullPath)
+	info, err := d.super.mw.CreateWithUmask_ll(d.info.Inode, req.Name, proto.Mode(req.Mode.Perm()), proto.Mode(req.Umask), req.Uid, req.Gid, nil, fullPath)
 	if err != nil {
 		log.LogErrorf("Create: parent(%v) req(%v) err(%v)", d.info.Inode, req, err)
 		return nil, nil, ParseError(err)
@@ -222,7 +222,7 @@ func (d *Dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error
 		auditlog.LogClientOp("Mkdir", fullPath, "nil", err, time.Since(start).Microseconds(), newInode, 0)
 	}()
 
-	info, err := d.super.mw.Create_ll(d.info.Inode, req.Name, proto.Mode(os.ModeDir|req.Mode.Perm()), req.Uid, req.Gid, nil, fullPath)
+	info, err := d.super.mw.CreateWithUmask_ll(d.info.Inode, req.Name, proto.Mode(os.ModeDir|req.Mode.Perm()), proto.Mode(req.Umask), req.Uid, req.Gid, nil, fullPath)
 	if err != nil {
 		log.LogErrorf("Mkdir: parent(%v) req(%v) err(%v)", d.info.Inode, req, err)
 		return nil, ParseError(err)
@@ -261,6 +261,16 @@ func (d *Dir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
 	}()
 
 	log.LogDebugf("[Remove] remove fullpath(%v)", fullPath)
+	// the emptied directories are deleted, and recreated on restoring the files in them
+	if volPath := path.Join(d.super.subDir, fullPath); !req.Dir && d.super.mw.TrashEnabled() && !meta.IsTrashPath(volPath) {
+		if err = d.super.mw.MoveToTrash_ll(d.info.Inode, req.Name, volPath); err != nil {
+			log.LogErrorf("Remove: move to trash parent(%v) name(%v) err(%v)", d.info.Inode, req.Name, err)
+			return ParseError(err)
+		}
+		d.super.ic.Delete(d.info.Inode)
+		log.LogDebugf("TRACE Remove: parent(%v) req(%v) moved to trash (%v)ns", d.info.Inode, req, time.Since(start).Nanoseconds())
+		return nil
+	}
 	info, err := d.super.mw.Delete_ll(d.info.Inode, req.Name, req.Dir, fullPath)
 	if err != nil {
 		log.LogErrorf("Remove: parent(%v) name(%v) err(%v)", d.info.Inode, req.Name, err)
@@ -662,6 +672,9 @@ func (d *Dir) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.
 			return ParseError(err)
 		}
 	}
+	if err = d.super.chmodPosixACL(ino, req); err != nil {
+		return err
+	}
 
 	fillAttr(info, &resp.Attr)
 
@@ -685,7 +698,7 @@ func (d *Dir) Mknod(ctx context.Context, req *fuse.MknodRequest) (fs.Node, error
 		metric.SetWithLabels(err, map[string]string{exporter.Vol: d.super.volname})
 	}()
 	fullPath := path.Join(d.getCwd(), req.Name)
-	info, err := d.super.mw.Create_ll(d.info.Inode, req.Name, proto.Mode(req.Mode), req.Uid, req.Gid, nil, fullPath)
+	info, err := d.super.mw.CreateWithUmask_ll(d.info.Inode, req.Name, proto.Mode(req.Mode), proto.Mode(req.Umask), req.Uid, req.Gid, nil, fullPath)
 	if err != nil {
 		log.LogErrorf("Mknod: parent(%v) req(%v) err(%v)", d.info.Inode, req, err)
 		return nil, ParseError(err)
@@ -782,6 +795,14 @@ func (d *Dir) Link(ctx context.Context, req *fuse.LinkRequest, old fs.Node) (fs.
 
 // Getxattr has not been implemented yet.
 func (d *Dir) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
+	if proto.IsPosixACLXAttr(req.Name) {
+		value, err := d.super.getPosixACL(d.info.Inode, req.Name)
+		if err != nil {
+			return err
+		}
+		resp.Xattr = value
+		return nil
+	}
 	if !d.super.enableXattr {
 		return fuse.ENOSYS
 	}
@@ -874,6 +895,9 @@ func (d *Dir) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *f
 
 // Setxattr has not been implemented yet.
 func (d *Dir) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
+	if proto.IsPosixACLXAttr(req.Name) {
+		return d.super.setPosixACL(d.info.Inode, req.Name, req.Xattr)
+	}
 	if !d.super.enableXattr {
 		return fuse.ENOSYS
 	}
@@ -902,6 +926,9 @@ func (d *Dir) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
 
 // Removexattr has not been implemented yet.
 func (d *Dir) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) error {
+	if proto.IsPosixACLXAttr(req.Name) {
+		return d.super.removePosixACL(d.info.Inode, req.Name)
+	}
 	if !d.super.enableXattr {
 		return fuse.ENOSYS
 	}
@@ -63,6 +63,10 @@ var (
 	_ fs.NodeListxattrer   = (*File)(nil)
 	_ fs.NodeSetxattrer    = (*File)(nil)
 	_ fs.NodeRemovexattrer = (*File)(nil)
+	_ fs.HandleLocker      = (*File)(nil)
+
+	_ fs.HandleFallocater     = (*File)(nil)
+	_ fs.HandleCopyFileRanger = (*File)(nil)
 )
 
 // NewFile returns a new file.
@@ -304,6 +308,12 @@ func (f *File) Release(ctx context.Context, req *fuse.ReleaseRequest) (err error
 	//	f.fWriter.Close()
 	//}
 
+	if f.super.enableLock && req.ReleaseFlags&fuse.ReleaseFlockUnlock != 0 {
+		if err = f.super.mw.ReleaseFileLocks_ll(ino, req.LockOwner, proto.FileLockFlock); err != nil {
+			log.LogWarnf("Release: release flock failed, ino(%v) req(%v) err(%v)", ino, req, err)
+		}
+	}
+
 	err = f.super.ec.CloseStream(ino)
 	if err != nil {
 		log.LogErrorf("Release: close writer failed, ino(%v) req(%v) err(%v)", ino, req, err)
@@ -487,7 +497,19 @@ func (f *File) Flush(ctx context.Context, req *fuse.FlushRequest) (err error) {
 		stat.EndStat("Flush", err, bgTime, 1)
 	}()
 
+	// the fcntl locks of the owner are released on close
+	if f.super.enableLock && req != nil {
+		if err = f.super.mw.ReleaseFileLocks_ll(f.info.Inode, req.LockOwner, proto.FileLockPOSIX); err != nil {
+			log.LogErrorf("Flush: release locks failed, ino(%v) owner(%v) err(%v)", f.info.Inode, req.LockOwner, err)
+			return ParseError(err)
+		}
+	}
+
 	if !f.super.fsyncOnClose {
+		if f.super.enableLock {
+			// keep the kernel sending the flush requests to release the locks
+			return nil
+		}
 		return fuse.ENOSYS
 	}
 	log.LogDebugf("TRACE Flush enter: ino(%v)", f.info.Inode)
@@ -599,6 +621,9 @@ func (f *File) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse
 			return ParseError(err)
 		}
 	}
+	if err = f.super.chmodPosixACL(ino, req); err != nil {
+		return err
+	}
 
 	fillAttr(info, &resp.Attr)
 
@@ -633,6 +658,14 @@ func (f *File) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fu
 		stat.EndStat("Getxattr", err, bgTime, 1)
 	}()
 
+	if proto.IsPosixACLXAttr(req.Name) {
+		var value []byte
+		if value, err = f.super.getPosixACL(f.info.Inode, req.Name); err != nil {
+			return err
+		}
+		resp.Xattr = value
+		return nil
+	}
 	if !f.super.enableXattr {
 		return fuse.ENOSYS
 	}
@@ -692,6 +725,10 @@ func (f *File) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
 		stat.EndStat("Setxattr", err, bgTime, 1)
 	}()
 
+	if proto.IsPosixACLXAttr(req.Name) {
+		err = f.super.setPosixACL(f.info.Inode, req.Name, req.Xattr)
+		return err
+	}
 	if !f.super.enableXattr {
 		return fuse.ENOSYS
 	}
@@ -715,6 +752,10 @@ func (f *File) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) er
 		stat.EndStat("Removexattr", err, bgTime, 1)
 	}()
 
+	if proto.IsPosixACLXAttr(req.Name) {
+		err = f.super.removePosixACL(f.info.Inode, req.Name)
+		return err
+	}
 	if !f.super.enableXattr {
 		return fuse.ENOSYS
 	}
@@ -728,6 +769,232 @@ func (f *File) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) er
 	return nil
 }
 
+// AcquireLock tries to acquire the flock or fcntl lock without waiting.
+func (f *File) AcquireLock(ctx context.Context, req *fuse.LockRequest) (err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("AcquireLock", err, bgTime, 1)
+	}()
+
+	if !f.super.enableLock {
+		return fuse.ENOSYS
+	}
+	lock := f.newFileLock(req.LockOwner, req.Lock, req.LockFlags)
+	conflict, err := f.super.mw.SetFileLock_ll(lock)
+	if err != nil {
+		log.LogDebugf("AcquireLock: lock(%v) conflict(%v) err(%v)", lock, conflict, err)
+		return ParseError(err)
+	}
+	log.LogDebugf("TRACE AcquireLock: lock(%v)", lock)
+	return nil
+}
+
+// AcquireLockWait acquires the flock or fcntl lock, polls the metanode until the lock is
+// acquired or the request is interrupted.
+func (f *File) AcquireLockWait(ctx context.Context, req *fuse.LockWaitRequest) (err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("AcquireLockWait", err, bgTime, 1)
+	}()
+
+	if !f.super.enableLock {
+		return fuse.ENOSYS
+	}
+	lock := f.newFileLock(req.LockOwner, req.Lock, req.LockFlags)
+	interval := LockWaitMinInterval
+	for {
+		_, err = f.super.mw.SetFileLock_ll(lock)
+		if err != syscall.EAGAIN {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			log.LogDebugf("AcquireLockWait: interrupted, lock(%v)", lock)
+			return fuse.EINTR
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > LockWaitMaxInterval {
+			interval = LockWaitMaxInterval
+		}
+	}
+	if err != nil {
+		log.LogErrorf("AcquireLockWait: lock(%v) err(%v)", lock, err)
+		return ParseError(err)
+	}
+	log.LogDebugf("TRACE AcquireLockWait: lock(%v)", lock)
+	return nil
+}
+
+// ReleaseLock releases the flock or fcntl lock.
+func (f *File) ReleaseLock(ctx context.Context, req *fuse.UnlockRequest) (err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("ReleaseLock", err, bgTime, 1)
+	}()
+
+	if !f.super.enableLock {
+		return fuse.ENOSYS
+	}
+	lock := f.newFileLock(req.LockOwner, req.Lock, req.LockFlags)
+	if _, err = f.super.mw.SetFileLock_ll(lock); err != nil {
+		log.LogErrorf("ReleaseLock: lock(%v) err(%v)", lock, err)
+		return ParseError(err)
+	}
+	log.LogDebugf("TRACE ReleaseLock: lock(%v)", lock)
+	return nil
+}
+
+// QueryLock returns the lock which conflicts with the requested one, as F_GETLK does.
+func (f *File) QueryLock(ctx context.Context, req *fuse.QueryLockRequest, resp *fuse.QueryLockResponse) (err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("QueryLock", err, bgTime, 1)
+	}()
+
+	if !f.super.enableLock {
+		return fuse.ENOSYS
+	}
+	lock := f.newFileLock(req.LockOwner, req.Lock, req.LockFlags)
+	conflict, err := f.super.mw.GetFileLock_ll(lock)
+	if err != nil {
+		log.LogErrorf("QueryLock: lock(%v) err(%v)", lock, err)
+		return ParseError(err)
+	}
+	if conflict != nil {
+		resp.Lock = fuse.FileLock{
+			Start: conflict.Start,
+			End:   conflict.End,
+			Type:  fuse.LockRead,
+			PID:   int32(conflict.Pid),
+		}
+		if conflict.Type == proto.FileLockWrite {
+			resp.Lock.Type = fuse.LockWrite
+		}
+	}
+	log.LogDebugf("TRACE QueryLock: lock(%v) conflict(%v)", lock, conflict)
+	return nil
+}
+
+// Fallocate preallocates, punches or zeroes the range of the file. The holes of the range are
+// reserved by the extents filled with zeros on the data nodes, so ENOSPC is returned by fallocate
+// rather than the writes later. EOPNOTSUPP makes posix_fallocate(3) write zeroes instead, if the
+// volume could not reserve the extents, such as the erasure coded or compressed volumes.
+func (f *File) Fallocate(ctx context.Context, req *fuse.FallocateRequest) (err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("Fallocate", err, bgTime, 1)
+	}()
+
+	if !proto.IsHot(f.super.volType) {
+		return fuse.ENOSYS
+	}
+	ino := f.info.Inode
+	if err = f.super.ec.Flush(ino); err != nil {
+		log.LogErrorf("Fallocate: flush ino(%v) err(%v)", ino, err)
+		return ParseError(err)
+	}
+	var (
+		eks        []proto.ExtentKey
+		reserveErr error
+	)
+	mode := req.Mode
+	if proto.IsPreallocate(mode) {
+		if err = proto.ValidateFallocate(mode, req.Offset, req.Length); err != nil {
+			log.LogWarnf("Fallocate: ino(%v) req(%v) err(%v)", ino, req, err)
+			return fuse.Errno(syscall.EOPNOTSUPP)
+		}
+		if eks, reserveErr = f.super.ec.Preallocate(ino, int(req.Offset), int(req.Length)); reserveErr != nil {
+			if len(eks) == 0 {
+				log.LogErrorf("Fallocate: preallocate ino(%v) req(%v) err(%v)", ino, req, reserveErr)
+				return ParseError(reserveErr)
+			}
+			// the extents reserved are kept by the file, but the size is not changed on failure
+			mode |= proto.FallocKeepSize
+		}
+	}
+	if err = f.super.mw.Fallocate_ll(ino, mode, req.Offset, req.Length, eks); err != nil {
+		log.LogErrorf("Fallocate: ino(%v) req(%v) err(%v)", ino, req, err)
+		return ParseError(err)
+	}
+	f.super.ic.Delete(ino)
+	f.super.ec.ForceRefreshExtentsCache(ino)
+	if reserveErr != nil {
+		log.LogErrorf("Fallocate: preallocate ino(%v) req(%v) reserved(%v) err(%v)", ino, req, eks, reserveErr)
+		return ParseError(reserveErr)
+	}
+	if !proto.IsPreallocate(mode) {
+		if err = f.super.ec.ZeroCompressedChunks(ino, int(req.Offset), int(req.Length)); err != nil {
+			log.LogErrorf("Fallocate: zero compressed chunks ino(%v) req(%v) err(%v)", ino, req, err)
+			return ParseError(err)
+		}
+	}
+	log.LogDebugf("TRACE Fallocate: ino(%v) req(%v)", ino, req)
+	return nil
+}
+
+// CopyFileRange clones the extents of the range to the out file, the data is shared by the
+// files until it is overwritten. EXDEV is returned if the extents cannot be shared, such as the
+// files of different meta partitions, so that the kernel copies the data instead.
+func (f *File) CopyFileRange(ctx context.Context, req *fuse.CopyFileRangeRequest, resp *fuse.CopyFileRangeResponse, out fs.Handle) (err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("CopyFileRange", err, bgTime, 1)
+	}()
+
+	if !proto.IsHot(f.super.volType) {
+		return fuse.ENOSYS
+	}
+	dst, ok := out.(*File)
+	if !ok {
+		return fuse.Errno(syscall.EXDEV)
+	}
+	src, dstIno := f.info.Inode, dst.info.Inode
+	for _, ino := range []uint64{src, dstIno} {
+		if err = f.super.ec.Flush(ino); err != nil {
+			log.LogErrorf("CopyFileRange: flush ino(%v) err(%v)", ino, err)
+			return ParseError(err)
+		}
+	}
+	n, err := f.super.mw.CloneExtents_ll(src, dstIno, req.Offset, req.OffsetOut, req.Len)
+	if err != nil {
+		log.LogWarnf("CopyFileRange: ino(%v) to ino(%v) req(%v) err(%v)", src, dstIno, req, err)
+		return ParseError(err)
+	}
+	f.super.ic.Delete(dstIno)
+	// the cloned extents of both files are copied on the later overwrite
+	f.super.ec.ForceRefreshExtentsCache(src)
+	f.super.ec.ForceRefreshExtentsCache(dstIno)
+	resp.Size = n
+	log.LogDebugf("TRACE CopyFileRange: ino(%v) to ino(%v) req(%v) size(%v)", src, dstIno, req, n)
+	return nil
+}
+
+func (f *File) newFileLock(owner uint64, l fuse.FileLock, flags fuse.LockFlags) *proto.FileLock {
+	lock := &proto.FileLock{
+		Inode: f.info.Inode,
+		Owner: owner,
+		Pid:   uint32(l.PID),
+		Kind:  proto.FileLockPOSIX,
+		Start: l.Start,
+		End:   l.End,
+	}
+	if flags&fuse.LockFlock != 0 {
+		lock.Kind = proto.FileLockFlock
+	}
+	switch l.Type {
+	case fuse.LockRead:
+		lock.Type = proto.FileLockRead
+	case fuse.LockWrite:
+		lock.Type = proto.FileLockWrite
+	default:
+		lock.Type = proto.FileLockUnlock
+	}
+	if lock.End > proto.FileLockMaxOffset {
+		lock.End = proto.FileLockMaxOffset
+	}
+	return lock
+}
+
 func (f *File) fileSize(ino uint64) (size int, gen uint64) {
 	size, gen, valid := f.super.ec.FileSize(ino)
 	if !valid {
@@ -0,0 +1,82 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fs
+
+import (
+	"syscall"
+
+	"github.com/cubefs/cubefs/depends/bazil.org/fuse"
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// The kernel reads and writes the POSIX ACLs by the xattrs in its binary format, while
+// the metanode keeps them in the structured format shared with libsdk and objectnode.
+
+func (s *Super) getPosixACL(ino uint64, name string) ([]byte, error) {
+	if !s.enableACL {
+		return nil, fuse.ENOSYS
+	}
+	acl, err := s.mw.GetPosixACL_ll(ino, name)
+	if err != nil {
+		log.LogErrorf("getPosixACL: ino(%v) name(%v) err(%v)", ino, name, err)
+		return nil, ParseError(err)
+	}
+	if acl == nil {
+		return nil, fuse.Errno(syscall.ENODATA)
+	}
+	return acl.XAttrBytes(), nil
+}
+
+func (s *Super) setPosixACL(ino uint64, name string, value []byte) error {
+	if !s.enableACL {
+		return fuse.ENOSYS
+	}
+	acl, err := proto.ParsePosixACLXAttr(value)
+	if err != nil {
+		log.LogWarnf("setPosixACL: ino(%v) name(%v) invalid acl, err(%v)", ino, name, err)
+		return fuse.Errno(syscall.EINVAL)
+	}
+	if err = s.mw.SetPosixACL_ll(ino, name, acl); err != nil {
+		log.LogErrorf("setPosixACL: ino(%v) name(%v) err(%v)", ino, name, err)
+		return ParseError(err)
+	}
+	s.ic.Delete(ino)
+	return nil
+}
+
+func (s *Super) removePosixACL(ino uint64, name string) error {
+	if !s.enableACL {
+		return fuse.ENOSYS
+	}
+	if err := s.mw.SetPosixACL_ll(ino, name, nil); err != nil {
+		log.LogErrorf("removePosixACL: ino(%v) name(%v) err(%v)", ino, name, err)
+		return ParseError(err)
+	}
+	return nil
+}
+
+// chmodPosixACL keeps the access ACL of the inode consistent with the permission bits
+// after chmod, which is left to the filesystem by the kernel.
+func (s *Super) chmodPosixACL(ino uint64, req *fuse.SetattrRequest) error {
+	if !s.enableACL || !req.Valid.Mode() {
+		return nil
+	}
+	if err := s.mw.ChmodPosixACL_ll(ino, proto.Mode(req.Mode)); err != nil {
+		log.LogErrorf("chmodPosixACL: ino(%v) mode(%v) err(%v)", ino, req.Mode, err)
+		return ParseError(err)
+	}
+	return nil
+}
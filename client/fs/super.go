@@ -16,6 +16,7 @@ package fs
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -64,6 +65,8 @@ type Super struct {
 	disableDcache bool
 	fsyncOnClose  bool
 	enableXattr   bool
+	enableLock    bool
+	enableACL     bool
 	rootIno       uint64
 
 	state     fs.FSStatType
@@ -159,6 +162,7 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 	s.disableDcache = opt.DisableDcache
 	s.fsyncOnClose = opt.FsyncOnClose
 	s.enableXattr = opt.EnableXattr
+	s.enableLock = opt.EnableFileLock
 	s.bcacheCheckInterval = opt.BcacheCheckIntervalS
 	s.bcacheFilterFiles = opt.BcacheFilterFiles
 	s.bcacheBatchCnt = opt.BcacheBatchCnt
@@ -212,6 +216,27 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 		s.bc = bcache.NewBcacheClient()
 	}
 
+	if proto.IsCold(opt.VolType) || opt.EbsEndpoint != "" {
+		s.ebsc, err = blobstore.NewEbsClient(access.Config{
+			ConnMode: access.NoLimitConnMode,
+			Consul: access.ConsulConfig{
+				Address: opt.EbsEndpoint,
+			},
+			MaxSizePutOnce: MaxSizePutOnce,
+			Logger: &access.Logger{
+				Filename: path.Join(opt.Logpath, "client/ebs.log"),
+			},
+		})
+		if err != nil && proto.IsCold(opt.VolType) {
+			return nil, errors.Trace(err, "NewEbsClient failed!")
+		}
+		if err != nil {
+			// the data of the hot volume transitioned to the blobstore could not be read
+			log.LogWarnf("NewSuper: NewEbsClient for hot vol(%v) err(%v)", opt.Volname, err)
+			s.ebsc, err = nil, nil
+		}
+	}
+
 	extentConfig := &stream.ExtentConfig{
 		Volume:            opt.Volname,
 		Masters:           masters,
@@ -232,36 +257,31 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 		OnLoadBcache:      s.bc.Get,
 		OnCacheBcache:     s.bc.Put,
 		OnEvictBcache:     s.bc.Evict,
+		OnLoadFileCipher:  s.mw.LoadFileCipher,
+		OnDedupExtents:    s.mw.DedupExtents,
+		OnAddFingerprints: s.mw.AddFingerprints,
 
 		DisableMetaCache:             DisableMetaCache,
 		MinWriteAbleDataPartitionCnt: opt.MinWriteAbleDataPartitionCnt,
 	}
 
+	if proto.IsHot(opt.VolType) && s.ebsc != nil {
+		extentConfig.OnGetObjExtents = s.mw.GetObjExtents
+		extentConfig.OnReadObjExtent = s.ebsc.Read
+	}
+
 	s.ec, err = stream.NewExtentClient(extentConfig)
 	if err != nil {
 		return nil, errors.Trace(err, "NewExtentClient failed!")
 	}
 	s.mw.VerReadSeq = s.ec.GetReadVer()
-	if proto.IsCold(opt.VolType) {
-		s.ebsc, err = blobstore.NewEbsClient(access.Config{
-			ConnMode: access.NoLimitConnMode,
-			Consul: access.ConsulConfig{
-				Address: opt.EbsEndpoint,
-			},
-			MaxSizePutOnce: MaxSizePutOnce,
-			Logger: &access.Logger{
-				Filename: path.Join(opt.Logpath, "client/ebs.log"),
-			},
-		})
-		if err != nil {
-			return nil, errors.Trace(err, "NewEbsClient failed!")
-		}
-	}
 	s.mw.Client = s.ec
 
 	if !opt.EnablePosixACL {
 		opt.EnablePosixACL = s.ec.GetEnablePosixAcl()
 	}
+	s.enableACL = opt.EnablePosixACL
+	s.mw.EnablePosixACL = opt.EnablePosixACL
 
 	if s.rootIno, err = s.mw.GetRootIno(opt.SubDir); err != nil {
 		return nil, err
@@ -495,6 +515,42 @@ func (s *Super) SetResume(w http.ResponseWriter, r *http.Request) {
 	replySucc(w, r, "set resume successfully")
 }
 
+// ListTrash replies the entries in the trash of the volume in json.
+func (s *Super) ListTrash(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.mw.ListTrash_ll()
+	if err != nil {
+		replyFail(w, r, fmt.Sprintf("list trash failed: %v", err))
+		return
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		replyFail(w, r, err.Error())
+		return
+	}
+	replySucc(w, r, string(data))
+}
+
+// RestoreTrash moves the entry in the trash back to the original path, or to the
+// path from the volume root if specified.
+func (s *Super) RestoreTrash(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		replyFail(w, r, err.Error())
+		return
+	}
+	bucket := r.FormValue("bucket")
+	name := r.FormValue("name")
+	if bucket == "" || name == "" {
+		replyFail(w, r, "parameter 'bucket' and 'name' are required")
+		return
+	}
+	restored, err := s.mw.RestoreTrash_ll(bucket, name, r.FormValue("path"))
+	if err != nil {
+		replyFail(w, r, fmt.Sprintf("restore %v/%v failed: %v", bucket, name, err))
+		return
+	}
+	replySucc(w, r, fmt.Sprintf("restore %v/%v to %v successfully\n", bucket, name, restored))
+}
+
 func (s *Super) EnableAuditLog(w http.ResponseWriter, r *http.Request) {
 	var err error
 	if err = r.ParseForm(); err != nil {
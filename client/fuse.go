@@ -82,6 +82,8 @@ const (
 	ControlCommandFreeOSMemory = "/debug/freeosmemory"
 	ControlCommandSuspend      = "/suspend"
 	ControlCommandResume       = "/resume"
+	ControlCommandTrashList    = "/trash/list"
+	ControlCommandTrashRestore = "/trash/restore"
 	Role                       = "Client"
 
 	DefaultIP            = "127.0.0.1"
@@ -609,6 +611,8 @@ func mount(opt *proto.MountOptions) (fsConn *fuse.Conn, super *cfs.Super, err er
 	http.HandleFunc(log.GetLogPath, log.GetLog)
 	http.HandleFunc(ControlCommandSuspend, super.SetSuspend)
 	http.HandleFunc(ControlCommandResume, super.SetResume)
+	http.HandleFunc(ControlCommandTrashList, super.ListTrash)
+	http.HandleFunc(ControlCommandTrashRestore, super.RestoreTrash)
 	// auditlog
 	http.HandleFunc(auditlog.EnableAuditLogReqPath, super.EnableAuditLog)
 	http.HandleFunc(auditlog.DisableAuditLogReqPath, auditlog.DisableAuditLog)
@@ -706,6 +710,10 @@ func mount(opt *proto.MountOptions) (fsConn *fuse.Conn, super *cfs.Super, err er
 		options = append(options, fuse.DefaultPermissions())
 	}
 
+	if opt.EnableFileLock {
+		options = append(options, fuse.LockingFlock(), fuse.LockingPOSIX())
+	}
+
 	fsConn, err = fuse.Mount(opt.MountPoint, opt.NeedRestoreFuse, options...)
 	return
 }
@@ -756,7 +764,8 @@ func parseMountOption(cfg *config.Config) (*proto.MountOptions, error) {
 	opt.KeepCache = GlobalMountOptions[proto.KeepCache].GetBool()
 	opt.FollowerRead = GlobalMountOptions[proto.FollowerRead].GetBool()
 	opt.Authenticate = GlobalMountOptions[proto.Authenticate].GetBool()
-	if opt.Authenticate {
+	// the authnode is also asked for the master key of the encrypted volume
+	if opt.Authenticate || GlobalMountOptions[proto.TicketHost].GetString() != "" {
 		opt.TicketMess.ClientKey = GlobalMountOptions[proto.ClientKey].GetString()
 		ticketHostConfig := GlobalMountOptions[proto.TicketHost].GetString()
 		ticketHosts := strings.Split(ticketHostConfig, ",")
@@ -775,6 +784,7 @@ func parseMountOption(cfg *config.Config) (*proto.MountOptions, error) {
 	opt.EnableXattr = GlobalMountOptions[proto.EnableXattr].GetBool()
 	opt.NearRead = GlobalMountOptions[proto.NearRead].GetBool()
 	opt.EnablePosixACL = GlobalMountOptions[proto.EnablePosixACL].GetBool()
+	opt.EnableFileLock = GlobalMountOptions[proto.EnableFileLock].GetBool()
 	opt.EnableSummary = GlobalMountOptions[proto.EnableSummary].GetBool()
 	opt.EnableUnixPermission = GlobalMountOptions[proto.EnableUnixPermission].GetBool()
 	opt.ReadThreads = GlobalMountOptions[proto.ReadThreads].GetInt64()
@@ -36,6 +36,7 @@ import (
 	"github.com/cubefs/cubefs/lcnode"
 	"github.com/cubefs/cubefs/master"
 	"github.com/cubefs/cubefs/metanode"
+	"github.com/cubefs/cubefs/nfsnode"
 	"github.com/cubefs/cubefs/objectnode"
 	"github.com/cubefs/cubefs/proto"
 	"github.com/cubefs/cubefs/util/auditlog"
@@ -67,6 +68,7 @@ const (
 	RoleObject    = "objectnode"
 	RoleConsole   = "console"
 	RoleLifeCycle = "lcnode"
+	RoleNfs       = "nfsnode"
 )
 
 const (
@@ -77,6 +79,7 @@ const (
 	ModuleObject    = "objectNode"
 	ModuleConsole   = "console"
 	ModuleLifeCycle = "lcnode"
+	ModuleNfs       = "nfsNode"
 )
 
 const (
@@ -197,6 +200,9 @@ func main() {
 	case RoleLifeCycle:
 		server = lcnode.NewServer()
 		module = ModuleLifeCycle
+	case RoleNfs:
+		server = nfsnode.NewServer()
+		module = ModuleNfs
 	default:
 		err = errors.NewErrorf("Fatal: role mismatch: %s", role)
 		fmt.Println(err)
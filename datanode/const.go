@@ -65,6 +65,9 @@ const (
 	ActionBatchMarkDelete            = "ActionBatchMarkDelete"
 	ActionUpdateVersion              = "ActionUpdateVersion"
 	ActionStopDataPartitionRepair    = "ActionStopDataPartitionRepair"
+	ActionRepairDataPartitionShard   = "ActionRepairDataPartitionShard"
+	ActionGetExtentHash              = "ActionGetExtentHash"
+	ActionPreallocExtent             = "ActionPreallocExtent"
 )
 
 // Apply the raft log operation. Currently we only have the random write operation.
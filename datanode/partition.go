@@ -66,6 +66,9 @@ type DataPartitionMetadata struct {
 	LastTruncateID          uint64
 	ReplicaNum              int
 	StopRecover             bool
+	ECDataNum               uint8
+	ECParityNum             uint8
+	ECHosts                 []string
 }
 
 func (md *DataPartitionMetadata) Validate() (err error) {
@@ -123,6 +126,7 @@ type DataPartition struct {
 	loadExtentHeaderStatus        int
 	DataPartitionCreateType       int
 	isLoadingDataPartition        int32
+	ecRepairing                   int32 // whether the shards of the erasure coded partition are repairing
 	persistMetaMutex              sync.RWMutex
 
 	// snapshot
@@ -242,6 +246,9 @@ func LoadDataPartition(partitionDir string, disk *Disk) (dp *DataPartition, err
 		RaftStore:     disk.space.GetRaftStore(),
 		NodeID:        disk.space.GetNodeID(),
 		ClusterID:     disk.space.GetClusterID(),
+		ECDataNum:     meta.ECDataNum,
+		ECParityNum:   meta.ECParityNum,
+		ECHosts:       meta.ECHosts,
 	}
 	if dp, err = newDataPartition(dpCfg, disk, false); err != nil {
 		return
@@ -543,6 +550,11 @@ func (dp *DataPartition) PersistMetadata() (err error) {
 		CreateTime:              time.Now().Format(TimeLayout),
 		LastTruncateID:          dp.lastTruncateID,
 		StopRecover:             dp.stopRecover,
+		ECDataNum:               dp.config.ECDataNum,
+		ECParityNum:             dp.config.ECParityNum,
+	}
+	if dp.isErasureCoded() {
+		md.ECHosts = dp.getECHosts()
 	}
 	if metaData, err = json.Marshal(md); err != nil {
 		return
@@ -670,6 +682,10 @@ func (dp *DataPartition) LaunchRepair(extentType uint8) {
 		log.LogErrorf("action[LaunchRepair] partition(%v) err(%v).", dp.partitionID, err)
 		return
 	}
+	// the shards of the normal extents of the erasure coded partition are repaired by the tasks of the master
+	if dp.isErasureCoded() && proto.IsNormalExtentType(extentType) {
+		return
+	}
 	if !dp.isLeader {
 		return
 	}
@@ -684,10 +700,11 @@ func (dp *DataPartition) updateReplicas(isForce bool) (err error) {
 		return
 	}
 	dp.isLeader = false
-	isLeader, replicas, err := dp.fetchReplicasFromMaster()
+	isLeader, replicas, ecHosts, err := dp.fetchReplicasFromMaster()
 	if err != nil {
 		return
 	}
+	dp.updateECHosts(ecHosts)
 	dp.replicasLock.Lock()
 	defer dp.replicasLock.Unlock()
 	if !dp.compareReplicas(dp.replicas, replicas) {
@@ -716,7 +733,7 @@ func (dp *DataPartition) compareReplicas(v1, v2 []string) (equals bool) {
 }
 
 // Fetch the replica information from the master.
-func (dp *DataPartition) fetchReplicasFromMaster() (isLeader bool, replicas []string, err error) {
+func (dp *DataPartition) fetchReplicasFromMaster() (isLeader bool, replicas, ecHosts []string, err error) {
 	var partition *proto.DataPartitionInfo
 	retry := 0
 	for {
@@ -733,6 +750,7 @@ func (dp *DataPartition) fetchReplicasFromMaster() (isLeader bool, replicas []st
 	}
 
 	replicas = append(replicas, partition.Hosts...)
+	ecHosts = append(ecHosts, partition.ECHosts...)
 	if partition.Hosts != nil && len(partition.Hosts) >= 1 {
 		leaderAddr := strings.Split(partition.Hosts[0], ":")
 		if len(leaderAddr) == 2 && strings.TrimSpace(leaderAddr[0]) == LocalIP {
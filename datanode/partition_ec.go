@@ -0,0 +1,289 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"hash/crc32"
+	"net"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/repl"
+	"github.com/cubefs/cubefs/storage"
+	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/ecstripe"
+	"github.com/cubefs/cubefs/util/errors"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// The extents of the erasure coded partition are written by the clients, every host keeps a shard
+// of the stripes, so the extents of the hosts are different from each other and they are not
+// repaired by the leader as the replicas. The master compares the shards of the hosts once the
+// partition is loaded, and asks the host of the shards missing or smaller than the others to
+// repair them, the part of the shard lost is rebuilt from the shards of the other hosts.
+const (
+	// the extents modified recently are skipped, the stripes of them may be written by the clients
+	ecRepairQuietPeriod = 5 * 60 // s
+	ecRepairReadSize    = util.BlockSize
+)
+
+func (dp *DataPartition) isErasureCoded() bool {
+	return dp.config.ECDataNum > 0
+}
+
+func (dp *DataPartition) ecLayout() ecstripe.Layout {
+	return ecstripe.Layout{DataNum: int(dp.config.ECDataNum), ParityNum: int(dp.config.ECParityNum)}
+}
+
+func (dp *DataPartition) getECHosts() []string {
+	dp.replicasLock.RLock()
+	defer dp.replicasLock.RUnlock()
+	ecHosts := make([]string, len(dp.config.ECHosts))
+	copy(ecHosts, dp.config.ECHosts)
+	return ecHosts
+}
+
+// updateECHosts takes the hosts of the shards from the master, the shard of the host removed is
+// given to the host added by the decommission.
+func (dp *DataPartition) updateECHosts(ecHosts []string) {
+	if !dp.isErasureCoded() || len(ecHosts) == 0 {
+		return
+	}
+	dp.replicasLock.Lock()
+	if dp.compareReplicas(dp.config.ECHosts, ecHosts) {
+		dp.replicasLock.Unlock()
+		return
+	}
+	log.LogInfof("action[updateECHosts] partition(%v) shard hosts changed from (%v) to (%v).",
+		dp.partitionID, dp.config.ECHosts, ecHosts)
+	dp.config.ECHosts = ecHosts
+	dp.replicasLock.Unlock()
+	if err := dp.PersistMetadata(); err != nil {
+		log.LogErrorf("action[updateECHosts] partition(%v) persist metadata err(%v).", dp.partitionID, err)
+	}
+}
+
+// ecDeleteRange returns the range of the shard to delete for the logical range of the extent. The
+// stripes of the extent key are not shared with the others unless the key is split, so the range
+// of the key split keeps the stripes shared, and false is returned if there is nothing to delete.
+func (dp *DataPartition) ecDeleteRange(offset, size int64, split bool) (shardOffset, shardSize int64, ok bool) {
+	if !split && offset == 0 && size == 0 {
+		return 0, 0, true
+	}
+	layout := dp.ecLayout()
+	var start, end int64
+	if split {
+		start, end = layout.InnerStripeRange(offset, size)
+	} else {
+		start, end = layout.StripeRange(offset, size)
+	}
+	if end <= start {
+		return
+	}
+	shardOffset = layout.ShardOffset(start)
+	return shardOffset, layout.ShardOffset(end) - shardOffset, true
+}
+
+// checkErasureCodedWrite rejects the writes which could not keep the stripes of the shards, the
+// data of the erasure coded partition is only appended to the normal extents.
+func (dp *DataPartition) checkErasureCodedWrite(p *repl.Packet) error {
+	if !dp.isErasureCoded() {
+		return nil
+	}
+	if p.IsRandomWrite() || p.IsSnapshotModWriteAppendOperation() ||
+		(p.IsNormalWriteOperation() && proto.IsTinyExtentType(p.ExtentType)) {
+		return fmt.Errorf("partition %v is erasure coded, op %v is not supported", p.PartitionID, p.GetOpMsg())
+	}
+	return nil
+}
+
+// repairErasureCoded rebuilds the part of the local shards of the extents lost. The size of a shard
+// is the one kept by DataNum hosts at least, which is the size the shard could be rebuilt to.
+func (dp *DataPartition) repairErasureCoded(extentIDs []uint64) {
+	if !AutoRepairStatus {
+		return
+	}
+	if dp.stopRecover && dp.isDecommissionRecovering() {
+		log.LogWarnf("action[repairErasureCoded] partition(%v) receive stop signal.", dp.partitionID)
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&dp.ecRepairing, 0, 1) {
+		log.LogWarnf("action[repairErasureCoded] partition(%v) is repairing, skip extents(%v).", dp.partitionID, len(extentIDs))
+		return
+	}
+	defer atomic.StoreInt32(&dp.ecRepairing, 0)
+	start := time.Now()
+	layout := dp.ecLayout()
+	ecHosts := dp.getECHosts()
+	local := -1
+	for i, host := range ecHosts {
+		if host == dp.dataNode.localServerAddr {
+			local = i
+		}
+	}
+	if local < 0 {
+		return
+	}
+
+	remotes := make([]map[uint64]*storage.ExtentInfo, len(ecHosts))
+	for i, host := range ecHosts {
+		if i == local || host == "" {
+			continue
+		}
+		extents, err := dp.getRemoteExtentInfo(proto.NormalExtentType, nil, host)
+		if err != nil {
+			log.LogWarnf("action[repairErasureCoded] partition(%v) get extents of shard %v from %v err(%v).",
+				dp.partitionID, i, host, err)
+			continue
+		}
+		remotes[i] = make(map[uint64]*storage.ExtentInfo, len(extents))
+		for _, ei := range extents {
+			remotes[i][ei.FileID] = ei
+		}
+	}
+
+	store := dp.ExtentStore()
+	now := time.Now().Unix()
+	var repaired int
+	for _, extentID := range extentIDs {
+		sources := make([]int, 0, len(ecHosts))
+		recent := false
+		for i, extents := range remotes {
+			ei, ok := extents[extentID]
+			if !ok || ei.IsDeleted {
+				continue
+			}
+			if now-ei.ModifyTime < ecRepairQuietPeriod {
+				recent = true
+			}
+			sources = append(sources, i)
+		}
+		if recent || len(sources) < layout.DataNum || store.IsDeletedNormalExtent(extentID) {
+			continue
+		}
+		sort.Slice(sources, func(i, j int) bool {
+			return remotes[sources[i]][extentID].Size > remotes[sources[j]][extentID].Size
+		})
+		sources = sources[:layout.DataNum]
+		target := remotes[sources[layout.DataNum-1]][extentID].Size
+
+		var localSize uint64
+		if store.HasExtent(extentID) {
+			ei, err := store.Watermark(extentID)
+			if err != nil {
+				continue
+			}
+			localSize = ei.Size
+		}
+		if localSize >= target {
+			continue
+		}
+		if !store.HasExtent(extentID) {
+			if err := store.Create(extentID); err != nil {
+				log.LogWarnf("action[repairErasureCoded] partition(%v) create extent(%v) err(%v).",
+					dp.partitionID, extentID, err)
+				continue
+			}
+		}
+		if err := dp.rebuildShard(extentID, local, localSize, target, sources, ecHosts); err != nil {
+			log.LogWarnf("action[repairErasureCoded] partition(%v) rebuild shard %v of extent(%v) from %v to %v err(%v).",
+				dp.partitionID, local, extentID, localSize, target, err)
+			continue
+		}
+		repaired++
+	}
+	log.LogInfof("action[repairErasureCoded] partition(%v) shard(%v) extents(%v) repaired(%v) cost(%v).",
+		dp.partitionID, local, len(extentIDs), repaired, time.Since(start))
+}
+
+// rebuildShard reconstructs the local shard of the extent from the offset to the target size with
+// the shards of the sources.
+func (dp *DataPartition) rebuildShard(extentID uint64, local int, offset, target uint64, sources []int, ecHosts []string) (err error) {
+	layout := dp.ecLayout()
+	encoder, err := layout.Encoder()
+	if err != nil {
+		return
+	}
+	store := dp.ExtentStore()
+	for offset < target {
+		size := util.Min(int(target-offset), ecRepairReadSize)
+		shards := make([][]byte, layout.ShardNum())
+		for i := range shards {
+			shards[i] = make([]byte, 0, size)
+		}
+		present := make(map[int]bool, len(sources))
+		for _, i := range sources {
+			if err = dp.readRemoteShard(ecHosts[i], extentID, offset, shards[i][:size]); err != nil {
+				return
+			}
+			shards[i] = shards[i][:size]
+			present[i] = true
+		}
+		badIdx := make([]int, 0, layout.ParityNum)
+		for i := range shards {
+			if !present[i] {
+				badIdx = append(badIdx, i)
+			}
+		}
+		if err = encoder.Reconstruct(shards, badIdx); err != nil {
+			return errors.Trace(err, "reconstruct offset(%v) size(%v)", offset, size)
+		}
+		data := shards[local]
+		crc := crc32.ChecksumIEEE(data)
+		if _, err = store.Write(extentID, int64(offset), int64(size), data, crc, storage.AppendWriteType, BufferWrite); err != nil {
+			return
+		}
+		offset += uint64(size)
+	}
+	return
+}
+
+// readRemoteShard reads the shard of the extent kept by the host to the buffer.
+func (dp *DataPartition) readRemoteShard(host string, extentID, offset uint64, buf []byte) (err error) {
+	var conn net.Conn
+	if conn, err = dp.getRepairConn(host); err != nil {
+		return errors.Trace(err, "readRemoteShard get conn from host(%v) error", host)
+	}
+	defer func() {
+		dp.putRepairConn(conn, err != nil)
+	}()
+	request := repl.NewExtentRepairReadPacket(dp.partitionID, extentID, int(offset), len(buf))
+	if err = request.WriteToConn(conn); err != nil {
+		return errors.Trace(err, "readRemoteShard send to host(%v) error", host)
+	}
+	for read := 0; read < len(buf); {
+		reply := repl.NewPacket()
+		if err = reply.ReadFromConnWithVer(conn, 60); err != nil {
+			return errors.Trace(err, "readRemoteShard receive from host(%v) error", host)
+		}
+		if reply.ResultCode != proto.OpOk {
+			return fmt.Errorf("readRemoteShard host(%v) extent(%v) offset(%v) reply(%v)", host, extentID,
+				offset+uint64(read), string(reply.Data[:util.Min(len(reply.Data), int(reply.Size))]))
+		}
+		if reply.ReqID != request.ReqID || reply.ExtentID != extentID || reply.ExtentOffset != int64(offset)+int64(read) ||
+			reply.Size == 0 || read+int(reply.Size) > len(buf) {
+			return fmt.Errorf("readRemoteShard host(%v) unavailable reply(%v) of request(%v)", host,
+				reply.GetUniqueLogId(), request.GetUniqueLogId())
+		}
+		if crc32.ChecksumIEEE(reply.Data[:reply.Size]) != reply.CRC {
+			return fmt.Errorf("readRemoteShard host(%v) reply(%v) crc mismatch", host, reply.GetUniqueLogId())
+		}
+		read += copy(buf[read:], reply.Data[:reply.Size])
+	}
+	return
+}
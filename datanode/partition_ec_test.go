@@ -0,0 +1,63 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/repl"
+	"github.com/cubefs/cubefs/util/ecstripe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErasureCodedDeleteRange(t *testing.T) {
+	dp := &DataPartition{config: &dataPartitionCfg{ECDataNum: 4, ECParityNum: 2}}
+	stripe := int64(4 * ecstripe.CellSize)
+
+	offset, size, ok := dp.ecDeleteRange(0, 0, false)
+	require.True(t, ok)
+	require.Zero(t, offset)
+	require.Zero(t, size)
+
+	// the padding of the last stripe of the key is deleted with it
+	offset, size, ok = dp.ecDeleteRange(stripe, stripe+10, false)
+	require.True(t, ok)
+	require.Equal(t, int64(ecstripe.CellSize), offset)
+	require.Equal(t, int64(2*ecstripe.CellSize), size)
+
+	// the stripes shared by the keys split are kept
+	offset, size, ok = dp.ecDeleteRange(10, 2*stripe, true)
+	require.True(t, ok)
+	require.Equal(t, int64(ecstripe.CellSize), offset)
+	require.Equal(t, int64(ecstripe.CellSize), size)
+	_, _, ok = dp.ecDeleteRange(10, stripe, true)
+	require.False(t, ok)
+}
+
+func TestErasureCodedWrite(t *testing.T) {
+	dp := &DataPartition{config: &dataPartitionCfg{}}
+	p := repl.NewPacket()
+	p.Opcode = proto.OpRandomWrite
+	require.NoError(t, dp.checkErasureCodedWrite(p))
+
+	dp.config.ECDataNum, dp.config.ECParityNum = 4, 2
+	require.Error(t, dp.checkErasureCodedWrite(p))
+	p.Opcode = proto.OpWrite
+	p.ExtentType = proto.TinyExtentType
+	require.Error(t, dp.checkErasureCodedWrite(p))
+	p.ExtentType = proto.NormalExtentType
+	require.NoError(t, dp.checkErasureCodedWrite(p))
+}
@@ -49,6 +49,9 @@ type dataPartitionCfg struct {
 	VerSeq        uint64 `json:"ver_seq"`
 	CreateType    int
 	Forbidden     bool
+	ECDataNum     uint8    `json:"ec_data_num"`
+	ECParityNum   uint8    `json:"ec_parity_num"`
+	ECHosts       []string `json:"ec_hosts"` // guarded by replicasLock
 }
 
 func (dp *DataPartition) raftPort() (heartbeat, replica int, err error) {
@@ -398,6 +398,9 @@ func (manager *SpaceManager) CreatePartition(request *proto.CreateDataPartitionR
 		VerSeq:        request.VerSeq,
 		CreateType:    request.CreateType,
 		Forbidden:     false,
+		ECDataNum:     request.ECDataNum,
+		ECParityNum:   request.ECParityNum,
+		ECHosts:       request.ECHosts,
 	}
 	log.LogInfof("action[CreatePartition] dp %v dpCfg.Peers %v request.Members %v",
 		dpCfg.PartitionID, dpCfg.Peers, request.Members)
@@ -475,7 +478,18 @@ func (s *DataNode) buildHeartBeatResponse(response *proto.DataNodeHeartbeatRespo
 	})
 
 	disks := space.GetDisks()
+	response.DiskStats = make([]proto.DiskStat, 0, len(disks))
 	for _, d := range disks {
+		d.RLock()
+		response.DiskStats = append(response.DiskStats, proto.DiskStat{
+			DiskPath:     d.Path,
+			Total:        d.Total,
+			Used:         d.Used,
+			Available:    d.Available,
+			Status:       d.Status,
+			PartitionCnt: len(d.partitionMap),
+		})
+		d.RUnlock()
 		if d.Status == proto.Unavailable {
 			response.BadDisks = append(response.BadDisks, d.Path)
 
@@ -16,6 +16,7 @@ package datanode
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -128,6 +129,8 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c net.Conn) (err error) {
 	switch p.Opcode {
 	case proto.OpCreateExtent:
 		s.handlePacketToCreateExtent(p)
+	case proto.OpPreallocExtent:
+		s.handlePacketToPreallocExtent(p)
 	case proto.OpWrite, proto.OpSyncWrite:
 		s.handleWritePacket(p)
 	case proto.OpStreamRead:
@@ -173,6 +176,8 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c net.Conn) (err error) {
 		s.handlePacketToGetPartitionSize(p)
 	case proto.OpGetMaxExtentIDAndPartitionSize:
 		s.handlePacketToGetMaxExtentIDAndPartitionSize(p)
+	case proto.OpGetExtentHash:
+		s.handlePacketToGetExtentHash(p)
 	case proto.OpReadTinyDeleteRecord:
 		s.handlePacketToReadTinyDeleteRecordFile(p, c)
 	case proto.OpBroadcastMinAppliedID:
@@ -181,6 +186,8 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c net.Conn) (err error) {
 		s.handleUpdateVerPacket(p)
 	case proto.OpStopDataPartitionRepair:
 		s.handlePacketToStopDataPartitionRepair(p)
+	case proto.OpRepairDataPartitionShard:
+		s.handlePacketToRepairDataPartitionShard(p)
 	default:
 		p.PackErrorBody(repl.ErrorUnknownOp.Error(), repl.ErrorUnknownOp.Error()+strconv.Itoa(int(p.Opcode)))
 	}
@@ -219,6 +226,44 @@ func (s *DataNode) handlePacketToCreateExtent(p *repl.Packet) {
 	})
 }
 
+// Handle OpPreallocExtent packet, the extent is created and filled with zeros of the size
+// carried after the inode in the data, so the space is reserved for fallocate.
+func (s *DataNode) handlePacketToPreallocExtent(p *repl.Packet) {
+	var err error
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionPreallocExtent, err.Error())
+		} else {
+			p.PacketOkReply()
+		}
+	}()
+	if p.Size < 16 || len(p.Data) < 16 {
+		err = fmt.Errorf("invalid prealloc extent packet size %v", p.Size)
+		return
+	}
+	size := int64(binary.BigEndian.Uint64(p.Data[8:16]))
+	partition := p.Object.(*DataPartition)
+	if int64(partition.Available()) < size || !partition.disk.CanWrite() {
+		err = storage.NoSpaceError
+		return
+	} else if partition.disk.Status == proto.Unavailable {
+		err = storage.BrokenDiskError
+		return
+	}
+	if partition.GetExtentCount() >= storage.MaxExtentCount+10 {
+		err = storage.NoSpaceError
+		return
+	}
+
+	partition.disk.allocCheckLimit(proto.IopsWriteType, 1)
+	partition.disk.limitWrite.Run(0, func() {
+		if err = partition.ExtentStore().Create(p.ExtentID); err != nil {
+			return
+		}
+		err = partition.ExtentStore().Preallocate(p.ExtentID, size)
+	})
+}
+
 // Handle OpCreateDataPartition packet.
 func (s *DataNode) handlePacketToCreateDataPartition(p *repl.Packet) {
 	var (
@@ -679,9 +724,16 @@ func (s *DataNode) handleMarkDeletePacket(p *repl.Packet, c net.Conn) {
 		if err == nil {
 			log.LogInfof("handleMarkDeletePacket Delete PartitionID(%v)_Extent(%v)_Offset(%v)_Size(%v)",
 				p.PartitionID, p.ExtentID, ext.ExtentOffset, ext.Size)
+			offset, size := int64(ext.ExtentOffset), int64(ext.Size)
+			if partition.isErasureCoded() && !proto.IsTinyExtentType(p.ExtentType) {
+				var ok bool
+				if offset, size, ok = partition.ecDeleteRange(offset, size, true); !ok {
+					return
+				}
+			}
 			partition.disk.allocCheckLimit(proto.IopsWriteType, 1)
 			partition.disk.limitWrite.Run(0, func() {
-				partition.ExtentStore().MarkDelete(p.ExtentID, int64(ext.ExtentOffset), int64(ext.Size))
+				partition.ExtentStore().MarkDelete(p.ExtentID, offset, size)
 			})
 		}
 	} else {
@@ -717,9 +769,16 @@ func (s *DataNode) handleBatchMarkDeletePacket(p *repl.Packet, c net.Conn) {
 		for _, ext := range exts {
 			if deleteLimiteRater.Allow() {
 				log.LogInfof(fmt.Sprintf("recive DeleteExtent (%v) from (%v)", ext, c.RemoteAddr().String()))
+				offset, size := int64(ext.ExtentOffset), int64(ext.Size)
+				if partition.isErasureCoded() && !storage.IsTinyExtent(ext.ExtentId) {
+					var ok bool
+					if offset, size, ok = partition.ecDeleteRange(offset, size, ext.IsSplit()); !ok {
+						continue
+					}
+				}
 				partition.disk.allocCheckLimit(proto.IopsWriteType, 1)
 				partition.disk.limitWrite.Run(0, func() {
-					store.MarkDelete(ext.ExtentId, int64(ext.ExtentOffset), int64(ext.Size))
+					store.MarkDelete(ext.ExtentId, offset, size)
 				})
 			} else {
 				log.LogInfof("delete limiter reach(%v), remote (%v) try again.", deleteLimiteRater.Limit(), c.RemoteAddr().String())
@@ -1269,6 +1328,32 @@ func (s *DataNode) handlePacketToGetMaxExtentIDAndPartitionSize(p *repl.Packet)
 	p.PacketOkWithBody(buf)
 }
 
+// handlePacketToGetExtentHash returns the sha256 of the range of the extent, the meta nodes verify the
+// fingerprints of the deduplicated chunks with it.
+func (s *DataNode) handlePacketToGetExtentHash(p *repl.Packet) {
+	var err error
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionGetExtentHash, err.Error())
+		}
+	}()
+	ext := &proto.ExtentKey{}
+	if err = json.Unmarshal(p.Data[:p.Size], ext); err != nil {
+		return
+	}
+	if storage.IsTinyExtent(ext.ExtentId) || ext.Size == 0 || ext.Size > proto.MaxDedupChunkSize {
+		err = fmt.Errorf("invalid extent %v to hash", ext)
+		return
+	}
+	partition := p.Object.(*DataPartition)
+	data := make([]byte, ext.Size)
+	if _, err = partition.ExtentStore().Read(ext.ExtentId, int64(ext.ExtentOffset), int64(ext.Size), data, false); err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+	p.PacketOkWithBody(sum[:])
+}
+
 func (s *DataNode) handlePacketToDecommissionDataPartition(p *repl.Packet) {
 	var (
 		err          error
@@ -1580,3 +1665,43 @@ func (s *DataNode) handlePacketToStopDataPartitionRepair(p *repl.Packet) {
 	dp.StopDecommissionRecover(request.Stop)
 	log.LogInfof("action[handlePacketToStopDataPartitionRepair] %v stop %v success", request.PartitionId, request.Stop)
 }
+
+// Handle OpRepairDataPartitionShard packet, the shards of the extents are rebuilt in the background.
+func (s *DataNode) handlePacketToRepairDataPartitionShard(p *repl.Packet) {
+	task := &proto.AdminTask{}
+	err := json.Unmarshal(p.Data, task)
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionRepairDataPartitionShard, err.Error())
+		} else {
+			p.PacketOkReply()
+		}
+	}()
+	if err != nil {
+		return
+	}
+	request := &proto.RepairDataPartitionShardRequest{}
+	if task.OpCode != proto.OpRepairDataPartitionShard {
+		err = fmt.Errorf("action[handlePacketToRepairDataPartitionShard] illegal opcode ")
+		log.LogWarnf("action[handlePacketToRepairDataPartitionShard] illegal opcode ")
+		return
+	}
+
+	bytes, _ := json.Marshal(task.Request)
+	err = json.Unmarshal(bytes, request)
+	if err != nil {
+		return
+	}
+	dp := s.space.Partition(request.PartitionId)
+	if dp == nil {
+		err = proto.ErrDataPartitionNotExists
+		log.LogWarnf("action[handlePacketToRepairDataPartitionShard] cannot find dp %v", request.PartitionId)
+		return
+	}
+	if !dp.isErasureCoded() {
+		err = fmt.Errorf("partition %v is not erasure coded", request.PartitionId)
+		return
+	}
+	log.LogInfof("action[handlePacketToRepairDataPartitionShard] dp %v repair extents %v", request.PartitionId, request.ExtentIDs)
+	go dp.repairErasureCoded(request.ExtentIDs)
+}
@@ -113,6 +113,9 @@ func (s *DataNode) checkPacketAndPrepare(p *repl.Packet) error {
 	)
 
 	log.LogDebugf("action[prepare.checkPacketAndPrepare] pack opcode (%v) p.IsLeaderPacket(%v) p (%v)", p.Opcode, p.IsLeaderPacket(), p)
+	if err = partition.checkErasureCodedWrite(p); err != nil {
+		return err
+	}
 	if p.IsRandomWrite() || p.IsSnapshotModWriteAppendOperation() || p.IsNormalWriteOperation() {
 		if err = partition.CheckWriteVer(p); err != nil {
 			return err
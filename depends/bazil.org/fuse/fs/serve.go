@@ -306,6 +306,42 @@ type HandleFlusher interface {
 	Flush(ctx context.Context, req *fuse.FlushRequest) error
 }
 
+// HandleLocker is implemented by the handles which support the flock(2)
+// and fcntl(2) locks, see the mount options fuse.LockingFlock and
+// fuse.LockingPOSIX.
+type HandleLocker interface {
+	// AcquireLock tries to acquire a lock on a byte range of the node. If
+	// it is already held by another owner, it returns fuse.Errno(syscall.EAGAIN).
+	AcquireLock(ctx context.Context, req *fuse.LockRequest) error
+
+	// AcquireLockWait acquires a lock on a byte range of the node, waiting
+	// until the lock can be obtained or ctx is cancelled.
+	AcquireLockWait(ctx context.Context, req *fuse.LockWaitRequest) error
+
+	// ReleaseLock releases the lock on a byte range of the node.
+	ReleaseLock(ctx context.Context, req *fuse.UnlockRequest) error
+
+	// QueryLock returns the lock which would prevent the requested lock
+	// from being acquired, or the Type of fuse.LockUnlock if there is none.
+	QueryLock(ctx context.Context, req *fuse.QueryLockRequest, resp *fuse.QueryLockResponse) error
+}
+
+// HandleFallocater is implemented by the handles which support fallocate(2).
+type HandleFallocater interface {
+	// Fallocate preallocates, punches or zeroes the range of the handle
+	// according to req.Mode.
+	Fallocate(ctx context.Context, req *fuse.FallocateRequest) error
+}
+
+// HandleCopyFileRanger is implemented by the handles which support
+// copy_file_range(2). The kernel copies the data itself if the handle
+// returns fuse.Errno(syscall.EXDEV).
+type HandleCopyFileRanger interface {
+	// CopyFileRange copies the range of the handle to the out handle,
+	// and sets resp.Size to the bytes copied.
+	CopyFileRange(ctx context.Context, req *fuse.CopyFileRangeRequest, resp *fuse.CopyFileRangeResponse, out Handle) error
+}
+
 type HandleReadAller interface {
 	ReadAll(ctx context.Context) ([]byte, error)
 }
@@ -1950,6 +1986,112 @@ func (c *Server) handleRequest(ctx context.Context, node Node, snode *serveNode,
 		r.Respond()
 		return nil
 
+	case *fuse.LockRequest:
+		shandle := c.getHandle(r.Handle)
+		if shandle == nil {
+			return fuse.ESTALE
+		}
+		h, ok := shandle.handle.(HandleLocker)
+		if !ok {
+			return fuse.ENOSYS
+		}
+		if err := h.AcquireLock(ctx, r); err != nil {
+			return err
+		}
+		done(nil)
+		r.Respond()
+		return nil
+
+	case *fuse.LockWaitRequest:
+		shandle := c.getHandle(r.Handle)
+		if shandle == nil {
+			return fuse.ESTALE
+		}
+		h, ok := shandle.handle.(HandleLocker)
+		if !ok {
+			return fuse.ENOSYS
+		}
+		if err := h.AcquireLockWait(ctx, r); err != nil {
+			return err
+		}
+		done(nil)
+		r.Respond()
+		return nil
+
+	case *fuse.UnlockRequest:
+		shandle := c.getHandle(r.Handle)
+		if shandle == nil {
+			return fuse.ESTALE
+		}
+		h, ok := shandle.handle.(HandleLocker)
+		if !ok {
+			return fuse.ENOSYS
+		}
+		if err := h.ReleaseLock(ctx, r); err != nil {
+			return err
+		}
+		done(nil)
+		r.Respond()
+		return nil
+
+	case *fuse.QueryLockRequest:
+		shandle := c.getHandle(r.Handle)
+		if shandle == nil {
+			return fuse.ESTALE
+		}
+		h, ok := shandle.handle.(HandleLocker)
+		if !ok {
+			return fuse.ENOSYS
+		}
+		s := &fuse.QueryLockResponse{
+			Lock: fuse.FileLock{
+				Type: fuse.LockUnlock,
+			},
+		}
+		if err := h.QueryLock(ctx, r, s); err != nil {
+			return err
+		}
+		done(s)
+		r.Respond(s)
+		return nil
+
+	case *fuse.FallocateRequest:
+		shandle := c.getHandle(r.Handle)
+		if shandle == nil {
+			return fuse.ESTALE
+		}
+		h, ok := shandle.handle.(HandleFallocater)
+		if !ok {
+			return fuse.ENOSYS
+		}
+		if err := h.Fallocate(ctx, r); err != nil {
+			return err
+		}
+		done(nil)
+		r.Respond()
+		return nil
+
+	case *fuse.CopyFileRangeRequest:
+		shandle := c.getHandle(r.Handle)
+		if shandle == nil {
+			return fuse.ESTALE
+		}
+		outHandle := c.getHandle(r.HandleOut)
+		if outHandle == nil {
+			return fuse.ESTALE
+		}
+		h, ok := shandle.handle.(HandleCopyFileRanger)
+		if !ok {
+			return fuse.ENOSYS
+		}
+		s := &fuse.CopyFileRangeResponse{}
+		if err := h.CopyFileRange(ctx, r, s, outHandle.handle); err != nil {
+			return err
+		}
+		done(s)
+		r.Respond(s)
+		return nil
+
 	case *fuse.DestroyRequest:
 		if fs, ok := c.fs.(FSDestroyer); ok {
 			fs.Destroy()
@@ -2030,9 +2172,6 @@ func (c *Server) handleRequest(ctx context.Context, node Node, snode *serveNode,
 		/*	case *FsyncdirRequest:
 				return ENOSYS
 
-			case *GetlkRequest, *SetlkRequest, *SetlkwRequest:
-				return ENOSYS
-
 			case *BmapRequest:
 				return ENOSYS
 
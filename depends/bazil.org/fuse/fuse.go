@@ -981,11 +981,38 @@ loop:
 		}
 
 	case opGetlk:
-		panic("opGetlk")
-	case opSetlk:
-		panic("opSetlk")
-	case opSetlkw:
-		panic("opSetlkw")
+		in := (*lkIn)(m.data())
+		if m.len() < lkInSize(c.proto) {
+			goto corrupt
+		}
+		req = &QueryLockRequest{
+			Header:    m.Header(),
+			Handle:    HandleID(in.Fh),
+			LockOwner: in.Owner,
+			Lock:      in.Lk.fileLock(),
+			LockFlags: LockFlags(in.LkFlags),
+		}
+
+	case opSetlk, opSetlkw:
+		in := (*lkIn)(m.data())
+		if m.len() < lkInSize(c.proto) {
+			goto corrupt
+		}
+		lockReq := LockRequest{
+			Header:    m.Header(),
+			Handle:    HandleID(in.Fh),
+			LockOwner: in.Owner,
+			Lock:      in.Lk.fileLock(),
+			LockFlags: LockFlags(in.LkFlags),
+		}
+		switch {
+		case lockReq.Lock.Type == LockUnlock:
+			req = (*UnlockRequest)(&lockReq)
+		case m.hdr.Opcode == opSetlkw:
+			req = (*LockWaitRequest)(&lockReq)
+		default:
+			req = &lockReq
+		}
 
 	case opAccess:
 		in := (*accessIn)(m.data())
@@ -1037,6 +1064,35 @@ loop:
 			Header: m.Header(),
 		}
 
+	case opFallocate:
+		in := (*fallocateIn)(m.data())
+		if m.len() < unsafe.Sizeof(*in) {
+			goto corrupt
+		}
+		req = &FallocateRequest{
+			Header: m.Header(),
+			Handle: HandleID(in.Fh),
+			Offset: in.Offset,
+			Length: in.Length,
+			Mode:   in.Mode,
+		}
+
+	case opCopyFileRange:
+		in := (*copyFileRangeIn)(m.data())
+		if m.len() < unsafe.Sizeof(*in) {
+			goto corrupt
+		}
+		req = &CopyFileRangeRequest{
+			Header:    m.Header(),
+			Handle:    HandleID(in.FhIn),
+			Offset:    in.OffIn,
+			NodeOut:   NodeID(in.NodeIdOut),
+			HandleOut: HandleID(in.FhOut),
+			OffsetOut: in.OffOut,
+			Len:       in.Len,
+			Flags:     in.Flags,
+		}
+
 	// OS X
 	case opSetvolname:
 		panic("opSetvolname")
@@ -1825,7 +1881,7 @@ type ReleaseRequest struct {
 	Handle       HandleID
 	Flags        OpenFlags // flags from OpenRequest
 	ReleaseFlags ReleaseFlags
-	LockOwner    uint32
+	LockOwner    uint64
 }
 
 var _ = Request(&ReleaseRequest{})
@@ -2125,6 +2181,219 @@ func (r *FlushRequest) Respond() {
 	r.respond(buf)
 }
 
+// LockType is the type of a file lock.
+type LockType uint32
+
+const (
+	LockRead   LockType = syscall.F_RDLCK
+	LockWrite  LockType = syscall.F_WRLCK
+	LockUnlock LockType = syscall.F_UNLCK
+)
+
+func (t LockType) String() string {
+	switch t {
+	case LockRead:
+		return "LockRead"
+	case LockWrite:
+		return "LockWrite"
+	case LockUnlock:
+		return "LockUnlock"
+	}
+	return fmt.Sprintf("LockType(%d)", uint32(t))
+}
+
+// LockFlags are bit flags that can be seen in the lock requests.
+type LockFlags uint32
+
+const (
+	// LockFlock indicates the lock is a flock(2) lock rather than a fcntl(2) lock.
+	LockFlock LockFlags = 1 << 0
+)
+
+func (fl LockFlags) String() string {
+	return flagString(uint32(fl), lockFlagNames)
+}
+
+var lockFlagNames = []flagName{
+	{uint32(LockFlock), "LockFlock"},
+}
+
+// FileLock describes the byte range [Start, End] of a lock, the End of
+// math.MaxInt64 means the lock extends to the end of file.
+type FileLock struct {
+	Start uint64
+	End   uint64
+	Type  LockType
+	PID   int32
+}
+
+func (l FileLock) String() string {
+	return fmt.Sprintf("%v [%d, %d] pid=%d", l.Type, l.Start, l.End, l.PID)
+}
+
+func (l fileLock) fileLock() FileLock {
+	return FileLock{
+		Start: l.Start,
+		End:   l.End,
+		Type:  LockType(l.Type),
+		PID:   int32(l.Pid),
+	}
+}
+
+// A LockRequest asks to try to acquire a byte range lock on a node,
+// the response should be immediate, EAGAIN is returned if the lock
+// cannot be acquired.
+//
+// The lock of the same owner is replaced or split as fcntl(2) does.
+type LockRequest struct {
+	Header    `json:"-"`
+	Handle    HandleID
+	LockOwner uint64
+	Lock      FileLock
+	LockFlags LockFlags
+}
+
+var _ = Request(&LockRequest{})
+
+func (r *LockRequest) String() string {
+	return fmt.Sprintf("Lock [%s] %v owner=%#x lock=%v fl=%v", &r.Header, r.Handle, r.LockOwner, r.Lock, r.LockFlags)
+}
+
+// Respond replies to the request, indicating that the lock succeeded.
+func (r *LockRequest) Respond() {
+	buf := newBuffer(0)
+	r.respond(buf)
+}
+
+// A LockWaitRequest asks to acquire a byte range lock on a node, waiting
+// until the lock can be obtained or the request is interrupted.
+type LockWaitRequest LockRequest
+
+var _ = Request(&LockWaitRequest{})
+
+func (r *LockWaitRequest) String() string {
+	return fmt.Sprintf("LockWait [%s] %v owner=%#x lock=%v fl=%v", &r.Header, r.Handle, r.LockOwner, r.Lock, r.LockFlags)
+}
+
+// Respond replies to the request, indicating that the lock succeeded.
+func (r *LockWaitRequest) Respond() {
+	buf := newBuffer(0)
+	r.respond(buf)
+}
+
+// An UnlockRequest asks to release the byte range lock of the owner on a node.
+type UnlockRequest LockRequest
+
+var _ = Request(&UnlockRequest{})
+
+func (r *UnlockRequest) String() string {
+	return fmt.Sprintf("Unlock [%s] %v owner=%#x lock=%v fl=%v", &r.Header, r.Handle, r.LockOwner, r.Lock, r.LockFlags)
+}
+
+// Respond replies to the request, indicating that the unlock succeeded.
+func (r *UnlockRequest) Respond() {
+	buf := newBuffer(0)
+	r.respond(buf)
+}
+
+// A QueryLockRequest queries the lock which would prevent the requested lock
+// from being acquired, as F_GETLK does.
+type QueryLockRequest struct {
+	Header    `json:"-"`
+	Handle    HandleID
+	LockOwner uint64
+	Lock      FileLock
+	LockFlags LockFlags
+}
+
+var _ = Request(&QueryLockRequest{})
+
+func (r *QueryLockRequest) String() string {
+	return fmt.Sprintf("QueryLock [%s] %v owner=%#x lock=%v fl=%v", &r.Header, r.Handle, r.LockOwner, r.Lock, r.LockFlags)
+}
+
+// Respond replies to the request with the conflicting lock, the Type of
+// LockUnlock means there is no conflict.
+func (r *QueryLockRequest) Respond(resp *QueryLockResponse) {
+	buf := newBuffer(unsafe.Sizeof(lkOut{}))
+	out := (*lkOut)(buf.alloc(unsafe.Sizeof(lkOut{})))
+	out.Lk = fileLock{
+		Start: resp.Lock.Start,
+		End:   resp.Lock.End,
+		Type:  uint32(resp.Lock.Type),
+		Pid:   uint32(resp.Lock.PID),
+	}
+	r.respond(buf)
+}
+
+// A QueryLockResponse is the response to a QueryLockRequest.
+type QueryLockResponse struct {
+	Lock FileLock
+}
+
+func (r *QueryLockResponse) String() string {
+	return fmt.Sprintf("QueryLock %v", r.Lock)
+}
+
+// A FallocateRequest asks to preallocate, punch or zero the range of
+// the open file, as fallocate(2) does.
+type FallocateRequest struct {
+	Header `json:"-"`
+	Handle HandleID
+	Offset uint64
+	Length uint64
+	Mode   uint32
+}
+
+var _ = Request(&FallocateRequest{})
+
+func (r *FallocateRequest) String() string {
+	return fmt.Sprintf("Fallocate [%s] %v %d @%d mode=%#x", &r.Header, r.Handle, r.Length, r.Offset, r.Mode)
+}
+
+// Respond replies to the request, indicating that the range was allocated.
+func (r *FallocateRequest) Respond() {
+	buf := newBuffer(0)
+	r.respond(buf)
+}
+
+// A CopyFileRangeRequest asks to copy the range of the open file r.Handle
+// to the open file HandleOut of the node NodeOut, as copy_file_range(2) does.
+type CopyFileRangeRequest struct {
+	Header    `json:"-"`
+	Handle    HandleID
+	Offset    uint64
+	NodeOut   NodeID
+	HandleOut HandleID
+	OffsetOut uint64
+	Len       uint64
+	Flags     uint64
+}
+
+var _ = Request(&CopyFileRangeRequest{})
+
+func (r *CopyFileRangeRequest) String() string {
+	return fmt.Sprintf("CopyFileRange [%s] %v @%d -> %v %v @%d len=%d fl=%#x",
+		&r.Header, r.Handle, r.Offset, r.NodeOut, r.HandleOut, r.OffsetOut, r.Len, r.Flags)
+}
+
+// Respond replies to the request with the number of bytes copied.
+func (r *CopyFileRangeRequest) Respond(resp *CopyFileRangeResponse) {
+	buf := newBuffer(unsafe.Sizeof(writeOut{}))
+	out := (*writeOut)(buf.alloc(unsafe.Sizeof(writeOut{})))
+	out.Size = uint32(resp.Size)
+	r.respond(buf)
+}
+
+// A CopyFileRangeResponse replies to a copy indicating how many bytes were copied.
+type CopyFileRangeResponse struct {
+	Size uint64
+}
+
+func (r *CopyFileRangeResponse) String() string {
+	return fmt.Sprintf("CopyFileRange %d", r.Size)
+}
+
 // A RemoveRequest asks to remove a file or directory from the
 // directory r.Node.
 type RemoveRequest struct {
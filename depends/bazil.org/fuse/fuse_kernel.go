@@ -339,7 +339,8 @@ func flagString(f uint32, names []flagName) string {
 type ReleaseFlags uint32
 
 const (
-	ReleaseFlush ReleaseFlags = 1 << 0
+	ReleaseFlush       ReleaseFlags = 1 << 0
+	ReleaseFlockUnlock ReleaseFlags = 1 << 1
 )
 
 func (fl ReleaseFlags) String() string {
@@ -348,6 +349,7 @@ func (fl ReleaseFlags) String() string {
 
 var releaseFlagNames = []flagName{
 	{uint32(ReleaseFlush), "ReleaseFlush"},
+	{uint32(ReleaseFlockUnlock), "ReleaseFlockUnlock"},
 }
 
 // Opcodes
@@ -391,6 +393,9 @@ const (
 	opIoctl       = 39 // Linux?
 	opPoll        = 40 // Linux?
 
+	opFallocate     = 43
+	opCopyFileRange = 47
+
 	// OS X
 	opSetvolname = 61
 	opGetxtimes  = 62
@@ -549,7 +554,7 @@ type releaseIn struct {
 	Fh           uint64
 	Flags        uint32
 	ReleaseFlags uint32
-	LockOwner    uint32
+	LockOwner    uint64
 }
 
 type flushIn struct {
@@ -671,6 +676,24 @@ type getxattrOut struct {
 	_    uint32
 }
 
+type fallocateIn struct {
+	Fh     uint64
+	Offset uint64
+	Length uint64
+	Mode   uint32
+	_      uint32
+}
+
+type copyFileRangeIn struct {
+	FhIn      uint64
+	OffIn     uint64
+	NodeIdOut uint64
+	FhOut     uint64
+	OffOut    uint64
+	Len       uint64
+	Flags     uint64
+}
+
 type lkIn struct {
 	Fh      uint64
 	Owner   uint64
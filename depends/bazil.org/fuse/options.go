@@ -330,6 +330,24 @@ func PosixACL() MountOption {
 	}
 }
 
+// LockingFlock enables flock(2) locks, otherwise the kernel emulates
+// them locally. The file system must implement fs.HandleLocker.
+func LockingFlock() MountOption {
+	return func(conf *mountConfig) error {
+		conf.initFlags |= InitFlockLocks
+		return nil
+	}
+}
+
+// LockingPOSIX enables fcntl(2) byte range locks, otherwise the kernel
+// handles them locally. The file system must implement fs.HandleLocker.
+func LockingPOSIX() MountOption {
+	return func(conf *mountConfig) error {
+		conf.initFlags |= InitPosixLocks
+		return nil
+	}
+}
+
 // RequestTimeout set request timeout.
 func RequestTimeout(timeout int64) MountOption {
 	return func(conf *mountConfig) error {
@@ -39,6 +39,7 @@ module.exports = [
             'user-guide/volume.md',
             'user-guide/file.md',
             'user-guide/objectnode.md',
+            'user-guide/nfs.md',
             'user-guide/blobstore.md',
             'user-guide/hadoop.md',
             'user-guide/k8s.md',
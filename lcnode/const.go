@@ -55,6 +55,7 @@ const (
 
 	defaultUnboundedChanInitCapacity = 10000
 	defaultLcNodeTaskCountLimit      = 1
+	defaultTransitionBlockSize       = 8 * 1024 * 1024
 )
 
 var (
@@ -40,6 +40,8 @@ func (l *LcNode) opMasterHeartbeat(conn net.Conn, p *proto.Packet, remoteAddr st
 		resp = &proto.LcNodeHeartbeatResponse{
 			LcScanningTasks:       make(map[string]*proto.LcNodeRuleTaskResponse),
 			SnapshotScanningTasks: make(map[string]*proto.SnapshotVerDelTaskResponse),
+			TrashScanningTasks:    make(map[string]*proto.TrashCleanTaskResponse),
+			ReplicationTasks:      make(map[string]*proto.ReplicationTaskResponse),
 		}
 		adminTask = &proto.AdminTask{
 			Request: req,
@@ -68,6 +70,9 @@ func (l *LcNode) opMasterHeartbeat(conn net.Conn, p *proto.Packet, remoteAddr st
 					FileScannedNum:       atomic.LoadInt64(&scanner.currentStat.FileScannedNum),
 					DirScannedNum:        atomic.LoadInt64(&scanner.currentStat.DirScannedNum),
 					ExpiredNum:           atomic.LoadInt64(&scanner.currentStat.ExpiredNum),
+					TransitionedNum:      atomic.LoadInt64(&scanner.currentStat.TransitionedNum),
+					NoncurrentExpiredNum: atomic.LoadInt64(&scanner.currentStat.NoncurrentExpiredNum),
+					AbortedMultipartNum:  atomic.LoadInt64(&scanner.currentStat.AbortedMultipartNum),
 					ErrorSkippedNum:      atomic.LoadInt64(&scanner.currentStat.ErrorSkippedNum),
 				},
 			}
@@ -89,6 +94,30 @@ func (l *LcNode) opMasterHeartbeat(conn net.Conn, p *proto.Packet, remoteAddr st
 			}
 			resp.SnapshotScanningTasks[scanner.ID] = info
 		}
+		for _, scanner := range l.trashScanners {
+			info := &proto.TrashCleanTaskResponse{
+				ID:             scanner.ID,
+				LcNode:         l.localServerAddr,
+				TrashCleanTask: scanner.cleanReq.Task,
+				TrashStatistics: proto.TrashStatistics{
+					VolName:         scanner.Volume,
+					BucketNum:       atomic.LoadInt64(&scanner.currentStat.BucketNum),
+					FileNum:         atomic.LoadInt64(&scanner.currentStat.FileNum),
+					DirNum:          atomic.LoadInt64(&scanner.currentStat.DirNum),
+					ErrorSkippedNum: atomic.LoadInt64(&scanner.currentStat.ErrorSkippedNum),
+				},
+			}
+			resp.TrashScanningTasks[scanner.ID] = info
+		}
+		for _, replicator := range l.replicators {
+			info := &proto.ReplicationTaskResponse{
+				ID:                    replicator.ID,
+				LcNode:                l.localServerAddr,
+				ReplicationTask:       replicator.replReq.Task,
+				ReplicationStatistics: replicator.statistics(),
+			}
+			resp.ReplicationTasks[replicator.ID] = info
+		}
 		l.scannerMutex.RUnlock()
 
 		resp.LcTaskCountLimit = lcNodeTaskCountLimit
@@ -187,3 +216,67 @@ func (l *LcNode) opSnapshotVerDel(conn net.Conn, p *proto.Packet) (err error) {
 
 	return
 }
+
+func (l *LcNode) opTrashClean(conn net.Conn, p *proto.Packet) (err error) {
+	go func() {
+		p.PacketOkReply()
+		if err := p.WriteToConn(conn); err != nil {
+			log.LogErrorf("ack master response: %s", err.Error())
+		}
+	}()
+	data := p.Data
+	var (
+		req       = &proto.TrashCleanTaskRequest{}
+		resp      = &proto.TrashCleanTaskResponse{}
+		adminTask = &proto.AdminTask{
+			Request: req,
+		}
+	)
+
+	decoder := json.NewDecoder(bytes.NewBuffer(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(adminTask); err != nil {
+		resp.Status = proto.TaskFailed
+		resp.Result = err.Error()
+		adminTask.Response = resp
+		l.respondToMaster(adminTask)
+		return
+	}
+
+	l.startTrashScan(adminTask)
+	l.respondToMaster(adminTask)
+
+	return
+}
+
+func (l *LcNode) opReplication(conn net.Conn, p *proto.Packet) (err error) {
+	go func() {
+		p.PacketOkReply()
+		if err := p.WriteToConn(conn); err != nil {
+			log.LogErrorf("ack master response: %s", err.Error())
+		}
+	}()
+	data := p.Data
+	var (
+		req       = &proto.ReplicationTaskRequest{}
+		resp      = &proto.ReplicationTaskResponse{}
+		adminTask = &proto.AdminTask{
+			Request: req,
+		}
+	)
+
+	decoder := json.NewDecoder(bytes.NewBuffer(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(adminTask); err != nil {
+		resp.Status = proto.TaskFailed
+		resp.Result = err.Error()
+		adminTask.Response = resp
+		l.respondToMaster(adminTask)
+		return
+	}
+
+	l.startReplication(adminTask)
+	l.respondToMaster(adminTask)
+
+	return
+}
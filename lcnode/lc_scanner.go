@@ -23,6 +23,8 @@ import (
 	"time"
 
 	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/blobstore"
+	"github.com/cubefs/cubefs/sdk/data/stream"
 	"github.com/cubefs/cubefs/sdk/meta"
 	"github.com/cubefs/cubefs/util/log"
 	"github.com/cubefs/cubefs/util/routinepool"
@@ -50,6 +52,11 @@ type LcScanner struct {
 	limiter       *rate.Limiter
 	now           time.Time
 	stopC         chan bool
+
+	// copy the data of the transition rule to the blobstore
+	ebsc      *blobstore.BlobStoreClient
+	ec        *stream.ExtentClient
+	blockSize int
 }
 
 func NewS3Scanner(adminTask *proto.AdminTask, l *LcNode) (*LcScanner, error) {
@@ -86,6 +93,12 @@ func NewS3Scanner(adminTask *proto.AdminTask, l *LcNode) (*LcScanner, error) {
 		now:           time.Now(),
 		stopC:         make(chan bool),
 	}
+	if scanTask.Rule.Transition != nil {
+		if err = scanner.openTransition(metaWrapper); err != nil {
+			metaWrapper.Close()
+			return nil, err
+		}
+	}
 
 	return scanner, nil
 }
@@ -124,7 +137,7 @@ func (l *LcNode) startLcScan(adminTask *proto.AdminTask) (err error) {
 
 func (s *LcScanner) Start() (err error) {
 	response := s.adminTask.Response.(*proto.LcNodeRuleTaskResponse)
-	parentId, prefixDirs, err := s.FindPrefixInode()
+	firstDentries, err := s.firstDentries()
 	if err != nil {
 		log.LogErrorf("startScan err(%v): volume(%v), rule id(%v), scanning done!",
 			err, s.Volume, s.rule.ID)
@@ -141,28 +154,58 @@ func (s *LcScanner) Start() (err error) {
 
 	go s.scan()
 
-	var currentPath string
-	if len(prefixDirs) > 0 {
-		currentPath = strings.Join(prefixDirs, pathSep)
-	}
-
-	firstDentry := &proto.ScanDentry{
-		Inode: parentId,
-		Path:  strings.TrimPrefix(currentPath, pathSep),
-		Type:  uint32(os.ModeDir),
-	}
 	t := time.Now()
 	response.StartTime = &t
 
-	log.LogInfof("startScan: first dentry(%v) in dirChan!", firstDentry)
-	s.dirChan.In <- firstDentry
+	if s.rule.AbortIncompleteMultipartUpload != nil {
+		if _, err = s.fileRPoll.Submit(s.abortIncompleteMultipartUploads); err != nil {
+			log.LogErrorf("startScan: submit abort multipart uploads err(%v)", err)
+			err = nil
+		}
+	}
+	for _, firstDentry := range firstDentries {
+		log.LogInfof("startScan: first dentry(%v) in dirChan!", firstDentry)
+		s.dirChan.In <- firstDentry
+	}
 
 	go s.checkScanning()
 
 	return
 }
 
+// firstDentries returns the directories of the prefix to scan, which are the one of the current objects for
+// the expiration and the transition, and the one in the versions directory for the noncurrent versions.
+func (s *LcScanner) firstDentries() (dentries []*proto.ScanDentry, err error) {
+	if s.rule.Expire != nil || s.rule.Transition != nil {
+		var parentId uint64
+		var prefixDirs []string
+		if parentId, prefixDirs, err = s.FindPrefixInode(); err != nil {
+			return
+		}
+		dentries = append(dentries, &proto.ScanDentry{
+			Inode: parentId,
+			Path:  strings.TrimPrefix(strings.Join(prefixDirs, pathSep), pathSep),
+			Type:  uint32(os.ModeDir),
+		})
+	}
+
+	if s.rule.NoncurrentVersionExpire != nil {
+		var dentry *proto.ScanDentry
+		if dentry, err = s.findVersionsPrefixDentry(); err != nil {
+			return
+		}
+		if dentry != nil {
+			dentries = append(dentries, dentry)
+		}
+	}
+	return
+}
+
 func (s *LcScanner) FindPrefixInode() (inode uint64, prefixDirs []string, err error) {
+	return s.findPrefixInode(proto.RootIno)
+}
+
+func (s *LcScanner) findPrefixInode(root uint64) (inode uint64, prefixDirs []string, err error) {
 	prefixDirs = make([]string, 0)
 	var prefix string
 	if s.rule.Filter != nil {
@@ -175,10 +218,10 @@ func (s *LcScanner) FindPrefixInode() (inode uint64, prefixDirs []string, err er
 		log.LogInfof("FindPrefixInode: volume(%v), prefix(%v), dirs(%v), len(%v)", s.Volume, prefix, dirs, len(dirs))
 	}
 	if len(dirs) <= 1 {
-		return proto.RootIno, prefixDirs, nil
+		return root, prefixDirs, nil
 	}
 
-	parentId := proto.RootIno
+	parentId := root
 	for index, dir := range dirs {
 
 		// Because lookup can only retrieve dentry whose name exactly matches,
@@ -237,7 +280,7 @@ func (s *LcScanner) scan() {
 				return
 			}
 			dentry := val.(*proto.ScanDentry)
-			if !strings.HasPrefix(dentry.Path, prefix) {
+			if !strings.HasPrefix(objectKey(dentry.Path), prefix) {
 				continue
 			}
 
@@ -258,7 +301,7 @@ func (s *LcScanner) scan() {
 					return
 				}
 				dentry := val.(*proto.ScanDentry)
-				if !strings.HasPrefix(dentry.Path, prefix) {
+				if !strings.HasPrefix(objectKey(dentry.Path), prefix) {
 					continue
 				}
 
@@ -308,7 +351,25 @@ func (s *LcScanner) handleFile(dentry *proto.ScanDentry) {
 func (s *LcScanner) batchHandleFile() {
 	dentries, inodes := s.batchDentries.BatchGetAndClear()
 
+	var versions []*proto.ScanDentry
+	currentInodes := make([]uint64, 0, len(inodes))
+	for _, ino := range inodes {
+		if d := dentries[ino]; d != nil && isNoncurrentVersion(d.Path) {
+			versions = append(versions, d)
+		} else {
+			currentInodes = append(currentInodes, ino)
+		}
+	}
+	if len(versions) > 0 {
+		s.handleNoncurrentVersions(versions)
+	}
+	if len(currentInodes) == 0 {
+		return
+	}
+	inodes = currentInodes
+
 	var expiredDentries []*proto.ScanDentry
+	var coldInodes []uint64
 	inodesInfo := s.mw.BatchInodeGet(inodes)
 	for _, info := range inodesInfo {
 		if s.inodeExpired(info, s.rule.Expire) {
@@ -316,6 +377,8 @@ func (s *LcScanner) batchHandleFile() {
 			if d != nil {
 				expiredDentries = append(expiredDentries, d)
 			}
+		} else if s.inodeCold(info, s.rule.Transition) {
+			coldInodes = append(coldInodes, info.Inode)
 		}
 	}
 
@@ -340,6 +403,19 @@ func (s *LcScanner) batchHandleFile() {
 		}
 	}
 	atomic.AddInt64(&s.currentStat.ExpiredNum, int64(len(expiredDentries)))
+
+	for _, ino := range coldInodes {
+		s.limiter.Wait(context.Background())
+		transitioned, err := s.transitionFile(ino)
+		if err != nil {
+			atomic.AddInt64(&s.currentStat.ErrorSkippedNum, 1)
+			log.LogWarnf("batchHandleFile transitionFile err: %v, ino: %v, skip it", err, ino)
+			continue
+		}
+		if transitioned {
+			atomic.AddInt64(&s.currentStat.TransitionedNum, 1)
+		}
+	}
 }
 
 func (s *LcScanner) inodeExpired(inode *proto.InodeInfo, cond *proto.ExpirationConfig) bool {
@@ -407,6 +483,9 @@ func (s *LcScanner) handleDirLimitDepthFirst(dentry *proto.ScanDentry) {
 				Path:     strings.TrimPrefix(dentry.Path+pathSep+child.Name, pathSep),
 				Type:     child.Type,
 			}
+			if isVersionsDir(childDentry) {
+				continue
+			}
 
 			if os.FileMode(childDentry.Type).IsDir() {
 				dirs = append(dirs, childDentry)
@@ -472,6 +551,9 @@ func (s *LcScanner) handleDirLimitBreadthFirst(dentry *proto.ScanDentry) {
 				Path:     strings.TrimPrefix(dentry.Path+pathSep+child.Name, pathSep),
 				Type:     child.Type,
 			}
+			if isVersionsDir(childDentry) {
+				continue
+			}
 			if !os.FileMode(childDentry.Type).IsDir() {
 				s.fileChan.In <- childDentry
 			} else {
@@ -515,6 +597,9 @@ func (s *LcScanner) checkScanning() {
 					response.Volume = s.Volume
 					response.RuleId = s.rule.ID
 					response.ExpiredNum = s.currentStat.ExpiredNum
+					response.TransitionedNum = s.currentStat.TransitionedNum
+					response.NoncurrentExpiredNum = s.currentStat.NoncurrentExpiredNum
+					response.AbortedMultipartNum = s.currentStat.AbortedMultipartNum
 					response.FileScannedNum = s.currentStat.FileScannedNum
 					response.DirScannedNum = s.currentStat.DirScannedNum
 					response.TotalInodeScannedNum = s.currentStat.TotalInodeScannedNum
@@ -547,5 +632,8 @@ func (s *LcScanner) Stop() {
 	close(s.dirChan.In)
 	close(s.fileChan.In)
 	s.mw.Close()
+	if s.ec != nil {
+		s.ec.Close()
+	}
 	log.LogInfof("scanner(%v) stopped", s.ID)
 }
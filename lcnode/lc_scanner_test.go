@@ -52,3 +52,53 @@ func TestLcScanner(t *testing.T) {
 	time.Sleep(time.Second * 5)
 	require.Equal(t, true, scanner.DoneScanning())
 }
+
+func TestInodeCold(t *testing.T) {
+	now := time.Now()
+	scanner := &LcScanner{now: now}
+	cond := &proto.TransitionConfig{Days: 1}
+	inode := &proto.InodeInfo{
+		Mode:       uint32(0o644),
+		Size:       100,
+		AccessTime: now.Add(-48 * time.Hour),
+		ModifyTime: now.Add(-48 * time.Hour),
+	}
+	require.True(t, scanner.inodeCold(inode, cond))
+
+	inode.AccessTime = now
+	require.False(t, scanner.inodeCold(inode, cond))
+
+	inode.AccessTime = now.Add(-48 * time.Hour)
+	inode.Size = 0
+	require.False(t, scanner.inodeCold(inode, cond))
+
+	inode.Size = 100
+	date := now.Add(time.Hour)
+	require.False(t, scanner.inodeCold(inode, &proto.TransitionConfig{Date: &date}))
+	date = now.Add(-time.Hour)
+	require.True(t, scanner.inodeCold(inode, &proto.TransitionConfig{Date: &date}))
+}
+
+func TestNoncurrentVersionPath(t *testing.T) {
+	require.True(t, isVersionsDir(&proto.ScanDentry{Path: proto.S3VersionsDirName}))
+	require.False(t, isVersionsDir(&proto.ScanDentry{Path: "logs/" + proto.S3VersionsDirName}))
+
+	require.False(t, isNoncurrentVersion("logs/a.log"))
+	require.True(t, isNoncurrentVersion(proto.S3VersionsDirName+"/logs/a.log#0001"))
+
+	require.Equal(t, "logs/a.log", objectKey("logs/a.log"))
+	require.Equal(t, "logs/a#b.log", objectKey(proto.S3VersionsDirName+"/logs/a#b.log#0001"))
+}
+
+func TestTransitionBlocks(t *testing.T) {
+	// the blocks of the data not encrypted are cut by the block size only
+	eks := []proto.ExtentKey{{FileOffset: 0, Size: 150}, {FileOffset: 200, Size: 100}}
+	require.Equal(t, []transitionBlock{{0, 100, 0}, {100, 100, 0}, {200, 100, 0}, {300, 50, 0}},
+		transitionBlocks(eks, 350, 100))
+
+	// the blocks are cut where the nonce changes, the holes are not encrypted
+	eks = []proto.ExtentKey{{FileOffset: 0, Size: 150, CipherNonce: 1}, {FileOffset: 150, Size: 30, CipherNonce: 2},
+		{FileOffset: 200, Size: 100, CipherNonce: 2}}
+	require.Equal(t, []transitionBlock{{0, 100, 1}, {100, 50, 1}, {150, 30, 2}, {180, 20, 0}, {200, 100, 2}},
+		transitionBlocks(eks, 300, 100))
+}
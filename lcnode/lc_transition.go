@@ -0,0 +1,231 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package lcnode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+
+	"github.com/cubefs/cubefs/blobstore/api/access"
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/blobstore"
+	"github.com/cubefs/cubefs/sdk/data/stream"
+	"github.com/cubefs/cubefs/sdk/meta"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// newEbsClient returns the client of the blobstore the data of the hot volumes is transitioned to.
+func (l *LcNode) newEbsClient(blockSize int) (*blobstore.BlobStoreClient, error) {
+	if l.ebsAddr == "" {
+		return nil, fmt.Errorf("no blobstore in cluster(%v)", l.clusterID)
+	}
+	return blobstore.NewEbsClient(access.Config{
+		ConnMode: access.NoLimitConnMode,
+		Consul: access.ConsulConfig{
+			Address: l.ebsAddr,
+		},
+		MaxSizePutOnce: int64(blockSize),
+		Logger:         &access.Logger{Filename: path.Join(log.LogDir, "ebs.log")},
+	})
+}
+
+// openTransition creates the clients to copy the data of the files to the blobstore. The data is copied as
+// it is kept on the data nodes, so the extent client does not load the file ciphers, and the obj extent
+// keys keep the cipher nonces of the data of the encrypted files instead.
+func (s *LcScanner) openTransition(mw *meta.MetaWrapper) (err error) {
+	view, err := s.lcnode.mc.AdminAPI().GetVolumeSimpleInfo(s.Volume)
+	if err != nil {
+		return
+	}
+	if !proto.IsHot(view.VolType) {
+		return fmt.Errorf("vol(%v) is not hot", s.Volume)
+	}
+	s.blockSize = view.ObjBlockSize
+	if s.blockSize <= 0 {
+		s.blockSize = defaultTransitionBlockSize
+	}
+	if s.ebsc, err = s.lcnode.newEbsClient(s.blockSize); err != nil {
+		return
+	}
+	s.ec, err = stream.NewExtentClient(&stream.ExtentConfig{
+		Volume:            s.Volume,
+		Masters:           s.lcnode.masters,
+		FollowerRead:      true,
+		OnAppendExtentKey: mw.AppendExtentKey,
+		OnSplitExtentKey:  mw.SplitExtentKey,
+		OnGetExtents:      mw.GetExtents,
+		OnTruncate:        mw.Truncate,
+	})
+	return
+}
+
+func (s *LcScanner) inodeCold(inode *proto.InodeInfo, cond *proto.TransitionConfig) bool {
+	if inode == nil || cond == nil || !proto.IsRegular(inode.Mode) || inode.Size == 0 {
+		return false
+	}
+
+	now := s.now.Unix()
+	if cond.Days > 0 {
+		accessTime := inode.AccessTime
+		if inode.ModifyTime.After(accessTime) {
+			accessTime = inode.ModifyTime
+		}
+		if now-accessTime.Unix() < int64(cond.Days*24*60*60) {
+			return false
+		}
+	}
+
+	if cond.Date != nil {
+		if now < cond.Date.Unix() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// transitionFile copies the data of the file to the blobstore block by block, and replaces the extents of the
+// inode with the object extents then. The holes of the file are copied as zero, so the object extents cover
+// the whole file. The copy is dropped if the file is modified meanwhile. The file transitioned already is
+// checked for the copy restored by the objectnode, which is dropped once it expires.
+func (s *LcScanner) transitionFile(inode uint64) (transitioned bool, err error) {
+	gen, size, eks, oeks, err := s.mw.GetObjExtents(inode)
+	if err != nil || size == 0 {
+		return
+	}
+	if len(oeks) > 0 {
+		if len(eks) == 0 {
+			return
+		}
+		return s.dropRestoredCopy(inode, gen, oeks)
+	}
+	if err = s.ec.OpenStream(inode); err != nil {
+		return
+	}
+	defer s.ec.CloseStream(inode)
+
+	ctx := context.Background()
+	buf := make([]byte, s.blockSize)
+	blocks := transitionBlocks(eks, size, uint64(s.blockSize))
+	written := make([]proto.ObjExtentKey, 0, len(blocks))
+	defer func() {
+		if err != nil && len(written) > 0 {
+			if e := s.ebsc.Delete(written); e != nil {
+				log.LogWarnf("transitionFile: vol(%v) ino(%v) delete obj extents err(%v)", s.Volume, inode, e)
+			}
+		}
+	}()
+	for _, block := range blocks {
+		offset, data := block.offset, buf[:block.size]
+		var read int
+		read, err = s.ec.Read(inode, data, int(offset), len(data))
+		if err == io.EOF && read == len(data) {
+			err = nil
+		}
+		if err != nil {
+			return
+		}
+		if read < len(data) {
+			// the file is truncated meanwhile
+			return false, io.ErrUnexpectedEOF
+		}
+		var location access.Location
+		if location, err = s.ebsc.Write(ctx, s.Volume, data, uint32(len(data))); err != nil {
+			return
+		}
+		oek := blobstore.NewObjExtentKey(location, offset)
+		oek.CipherNonce = block.nonce
+		written = append(written, oek)
+	}
+	if err = s.mw.TransitionExtents(inode, gen, written); err != nil {
+		return
+	}
+	log.LogInfof("transitionFile: vol(%v) ino(%v) gen(%v) size(%v) obj extents(%v)", s.Volume, inode, gen, size, len(written))
+	if e := s.mw.XAttrSet_ll(inode, []byte(proto.S3XAttrKeyStorageClass), []byte(proto.S3StorageClassGlacier)); e != nil {
+		log.LogWarnf("transitionFile: vol(%v) ino(%v) set storage class err(%v)", s.Volume, inode, e)
+	}
+	return true, nil
+}
+
+type transitionBlock struct {
+	offset uint64
+	size   uint64
+	nonce  uint64
+}
+
+// transitionBlocks splits the file into the blocks copied to the blobstore. The blocks are cut where the
+// cipher nonce of the data changes as well, so that the data of each obj extent key has one nonce.
+func transitionBlocks(eks []proto.ExtentKey, size, blockSize uint64) (blocks []transitionBlock) {
+	appendRange := func(offset, end, nonce uint64) {
+		if end > size {
+			end = size
+		}
+		if offset >= end {
+			return
+		}
+		if n := len(blocks); n > 0 && blocks[n-1].nonce == nonce && blocks[n-1].size < blockSize {
+			last := &blocks[n-1]
+			grow := end - offset
+			if last.size+grow > blockSize {
+				grow = blockSize - last.size
+			}
+			last.size += grow
+			offset += grow
+		}
+		for ; offset < end; offset += blockSize {
+			blockEnd := offset + blockSize
+			if blockEnd > end {
+				blockEnd = end
+			}
+			blocks = append(blocks, transitionBlock{offset: offset, size: blockEnd - offset, nonce: nonce})
+		}
+	}
+	offset := uint64(0)
+	for _, ek := range eks {
+		// the holes are not encrypted
+		appendRange(offset, ek.FileOffset, 0)
+		appendRange(ek.FileOffset, ek.FileOffset+uint64(ek.Size), ek.CipherNonce)
+		offset = ek.FileOffset + uint64(ek.Size)
+	}
+	appendRange(offset, size, 0)
+	return
+}
+
+// dropRestoredCopy drops the extents of the copy restored for the file transitioned once the restore expires.
+// The copy left without the expiry by a failed restore is dropped as well.
+func (s *LcScanner) dropRestoredCopy(inode, gen uint64, oeks []proto.ObjExtentKey) (dropped bool, err error) {
+	xattrs, err := s.mw.BatchGetXAttr([]uint64{inode}, []string{proto.S3XAttrKeyRestoreExpiry})
+	if err != nil {
+		return
+	}
+	var expiry []byte
+	if len(xattrs) > 0 && xattrs[0] != nil {
+		expiry = xattrs[0].Get(proto.S3XAttrKeyRestoreExpiry)
+	}
+	if expiry, e := strconv.ParseInt(string(expiry), 10, 64); e == nil && s.now.Unix() < expiry {
+		return
+	}
+	if err = s.mw.TransitionExtents(inode, gen, oeks); err != nil {
+		return
+	}
+	if e := s.mw.XAttrDel_ll(inode, proto.S3XAttrKeyRestoreExpiry); e != nil {
+		log.LogWarnf("dropRestoredCopy: vol(%v) ino(%v) delete restore expiry err(%v)", s.Volume, inode, e)
+	}
+	log.LogInfof("dropRestoredCopy: vol(%v) ino(%v) gen(%v) obj extents(%v)", s.Volume, inode, gen, len(oeks))
+	return true, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package lcnode
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// The noncurrent versions of the objects are kept by the objectnode in the versions directory of the volume
+// root as "<versions dir>/<object path>#<version id>".
+const versionNameSep = "#"
+
+// isVersionsDir checks whether the dentry is the versions directory of the volume root, which is skipped
+// while scanning the current objects.
+func isVersionsDir(dentry *proto.ScanDentry) bool {
+	return dentry.Path == proto.S3VersionsDirName
+}
+
+func isNoncurrentVersion(path string) bool {
+	return strings.HasPrefix(path, proto.S3VersionsDirName+pathSep)
+}
+
+// objectKey returns the key of the object the path of the scanned dentry belongs to, which is matched
+// with the prefix of the rule.
+func objectKey(path string) string {
+	if !isNoncurrentVersion(path) {
+		return path
+	}
+	key := strings.TrimPrefix(path, proto.S3VersionsDirName+pathSep)
+	if idx := strings.LastIndex(key, versionNameSep); idx > 0 {
+		key = key[:idx]
+	}
+	return key
+}
+
+// findVersionsPrefixDentry returns the directory of the prefix in the versions directory, nil is returned
+// if there is no noncurrent version under the prefix.
+func (s *LcScanner) findVersionsPrefixDentry() (*proto.ScanDentry, error) {
+	versionsIno, mode, err := s.mw.Lookup_ll(proto.RootIno, proto.S3VersionsDirName)
+	if err == syscall.ENOENT || (err == nil && !os.FileMode(mode).IsDir()) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	parentId, prefixDirs, err := s.findPrefixInode(versionsIno)
+	if err == syscall.ENOENT {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &proto.ScanDentry{
+		Inode: parentId,
+		Path:  strings.Join(append([]string{proto.S3VersionsDirName}, prefixDirs...), pathSep),
+		Type:  uint32(os.ModeDir),
+	}, nil
+}
+
+// handleNoncurrentVersions deletes the noncurrent versions which have been noncurrent for the days of the
+// rule. The time the version becomes noncurrent is set by the objectnode once it is archived, and the
+// versions archived without it are given the current time, so they expire the days later. The delete
+// markers are kept.
+func (s *LcScanner) handleNoncurrentVersions(dentries []*proto.ScanDentry) {
+	cond := s.rule.NoncurrentVersionExpire
+	if cond == nil {
+		return
+	}
+
+	inodes := make([]uint64, 0, len(dentries))
+	for _, d := range dentries {
+		inodes = append(inodes, d.Inode)
+	}
+	xattrs, err := s.mw.BatchGetXAttr(inodes, []string{proto.S3XAttrKeyNoncurrentTime, proto.S3XAttrKeyDeleteMarker})
+	if err != nil {
+		atomic.AddInt64(&s.currentStat.ErrorSkippedNum, int64(len(dentries)))
+		log.LogWarnf("handleNoncurrentVersions BatchGetXAttr err: %v, num: %v, skip them", err, len(dentries))
+		return
+	}
+	xattrMap := make(map[uint64]*proto.XAttrInfo, len(xattrs))
+	for _, xattr := range xattrs {
+		xattrMap[xattr.Inode] = xattr
+	}
+
+	now := s.now.Unix()
+	for _, dentry := range dentries {
+		var noncurrentTime []byte
+		if xattr := xattrMap[dentry.Inode]; xattr != nil {
+			if string(xattr.Get(proto.S3XAttrKeyDeleteMarker)) == "true" {
+				continue
+			}
+			noncurrentTime = xattr.Get(proto.S3XAttrKeyNoncurrentTime)
+		}
+		since, err := strconv.ParseInt(string(noncurrentTime), 10, 64)
+		if err != nil {
+			if err = s.mw.XAttrSet_ll(dentry.Inode, []byte(proto.S3XAttrKeyNoncurrentTime),
+				[]byte(strconv.FormatInt(now, 10))); err != nil {
+				log.LogWarnf("handleNoncurrentVersions XAttrSet_ll err: %v, dentry: %+v", err, dentry)
+			}
+			continue
+		}
+		if now-since < int64(cond.NoncurrentDays*24*60*60) {
+			continue
+		}
+
+		s.limiter.Wait(context.Background())
+		if _, err = s.mw.DeleteWithCond_ll(dentry.ParentId, dentry.Inode, dentry.Name, false, dentry.Path); err != nil {
+			log.LogWarnf("handleNoncurrentVersions DeleteWithCond_ll err: %v, dentry: %+v, skip it", err, dentry)
+			continue
+		}
+		if err = s.mw.Evict(dentry.Inode, dentry.Path); err != nil {
+			log.LogWarnf("handleNoncurrentVersions Evict err: %v, dentry: %+v", err, dentry)
+		}
+		atomic.AddInt64(&s.currentStat.NoncurrentExpiredNum, 1)
+	}
+}
+
+// abortIncompleteMultipartUploads aborts the multipart uploads of the prefix initiated the days of the rule
+// ago, the parts uploaded are removed as the objectnode aborts the upload.
+func (s *LcScanner) abortIncompleteMultipartUploads() {
+	var prefix string
+	if s.rule.Filter != nil {
+		prefix = s.rule.Filter.Prefix
+	}
+	days := s.rule.AbortIncompleteMultipartUpload.DaysAfterInitiation
+	expired, err := s.mw.BatchGetExpiredMultipart(prefix, days)
+	if err == syscall.ENOENT {
+		return
+	}
+	if err != nil {
+		atomic.AddInt64(&s.currentStat.ErrorSkippedNum, 1)
+		log.LogErrorf("abortIncompleteMultipartUploads: vol(%v) prefix(%v) days(%v) err(%v)", s.Volume, prefix, days, err)
+		return
+	}
+
+	for _, info := range expired {
+		s.limiter.Wait(context.Background())
+		for _, ino := range info.Inodes {
+			if _, err = s.mw.InodeUnlink_ll(ino, info.Path); err != nil {
+				log.LogWarnf("abortIncompleteMultipartUploads: vol(%v) path(%v) multipartId(%v) unlink part ino(%v) err(%v)",
+					s.Volume, info.Path, info.MultipartId, ino, err)
+				continue
+			}
+			if err = s.mw.Evict(ino, info.Path); err != nil {
+				log.LogWarnf("abortIncompleteMultipartUploads: vol(%v) path(%v) multipartId(%v) evict part ino(%v) err(%v)",
+					s.Volume, info.Path, info.MultipartId, ino, err)
+			}
+		}
+		if err = s.mw.RemoveMultipart_ll(info.Path, info.MultipartId); err != nil {
+			atomic.AddInt64(&s.currentStat.ErrorSkippedNum, 1)
+			log.LogWarnf("abortIncompleteMultipartUploads: vol(%v) path(%v) multipartId(%v) remove err(%v), skip it",
+				s.Volume, info.Path, info.MultipartId, err)
+			continue
+		}
+		atomic.AddInt64(&s.currentStat.AbortedMultipartNum, 1)
+		log.LogInfof("abortIncompleteMultipartUploads: vol(%v) path(%v) multipartId(%v) parts(%v) aborted",
+			s.Volume, info.Path, info.MultipartId, len(info.Inodes))
+	}
+}
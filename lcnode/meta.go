@@ -24,5 +24,13 @@ type MetaWrapper interface {
 	DeleteWithCond_ll(parentID, cond uint64, name string, isDir bool, fullPath string) (inode *proto.InodeInfo, err error)
 	Evict(inode uint64, fullPath string) error
 	ReadDirLimit_ll(parentID uint64, from string, limit uint64) ([]proto.Dentry, error)
+	GetObjExtents(inode uint64) (gen uint64, size uint64, extents []proto.ExtentKey, objExtents []proto.ObjExtentKey, err error)
+	TransitionExtents(inode, gen uint64, oeks []proto.ObjExtentKey) error
+	BatchGetXAttr(inodes []uint64, keys []string) ([]*proto.XAttrInfo, error)
+	XAttrSet_ll(inode uint64, name, value []byte) error
+	XAttrDel_ll(inode uint64, name string) error
+	InodeUnlink_ll(inode uint64, fullPath string) (*proto.InodeInfo, error)
+	BatchGetExpiredMultipart(prefix string, days int) ([]*proto.ExpiredMultipartInfo, error)
+	RemoveMultipart_ll(path, multipartID string) error
 	Close() error
 }
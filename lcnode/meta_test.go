@@ -50,6 +50,38 @@ func (*MockMetaWrapper) ReadDirLimit_ll(parentID uint64, from string, limit uint
 	return nil, nil
 }
 
+func (*MockMetaWrapper) GetObjExtents(inode uint64) (gen uint64, size uint64, extents []proto.ExtentKey, objExtents []proto.ObjExtentKey, err error) {
+	return
+}
+
+func (*MockMetaWrapper) TransitionExtents(inode, gen uint64, oeks []proto.ObjExtentKey) error {
+	return nil
+}
+
+func (*MockMetaWrapper) BatchGetXAttr(inodes []uint64, keys []string) ([]*proto.XAttrInfo, error) {
+	return nil, nil
+}
+
+func (*MockMetaWrapper) XAttrSet_ll(inode uint64, name, value []byte) error {
+	return nil
+}
+
+func (*MockMetaWrapper) XAttrDel_ll(inode uint64, name string) error {
+	return nil
+}
+
+func (*MockMetaWrapper) InodeUnlink_ll(inode uint64, fullPath string) (*proto.InodeInfo, error) {
+	return nil, nil
+}
+
+func (*MockMetaWrapper) BatchGetExpiredMultipart(prefix string, days int) ([]*proto.ExpiredMultipartInfo, error) {
+	return nil, nil
+}
+
+func (*MockMetaWrapper) RemoveMultipart_ll(path, multipartID string) error {
+	return nil
+}
+
 func (*MockMetaWrapper) Close() error {
 	return nil
 }
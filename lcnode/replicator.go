@@ -0,0 +1,854 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package lcnode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	gopath "path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/blobstore"
+	"github.com/cubefs/cubefs/sdk/data/stream"
+	"github.com/cubefs/cubefs/sdk/master"
+	"github.com/cubefs/cubefs/sdk/meta"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const replicationCopyBufSize = 4 * 1024 * 1024
+
+var errReplicationStopped = errors.New("replication stopped")
+
+// Replicator runs a round of the replication of a volume. It creates a snapshot of the source
+// volume, and applies the paths changed since the snapshot replicated last time to the target
+// volume. The first round, or a round without the last snapshot, mirrors the whole tree. All the
+// changes are applied idempotently, so a round failed is simply run again from the last snapshot.
+type Replicator struct {
+	ID          string
+	Volume      string
+	lcnode      *LcNode
+	adminTask   *proto.AdminTask
+	replReq     *proto.ReplicationTaskRequest
+	replication *proto.VolReplication
+	srcMc       *master.MasterClient
+	currentStat *proto.ReplicationStatistics
+	stopC       chan bool
+
+	verSeq  uint64 // the snapshot replicated by the round
+	verTime int64
+	fromMw  *meta.MetaWrapper // reads the snapshot replicated last time
+	snapMw  *meta.MetaWrapper // reads the snapshot replicated by the round
+	snapEc  *stream.ExtentClient
+	dstMw   *meta.MetaWrapper
+	dstEc   *stream.ExtentClient
+	renamed []replicationRename
+	final   []string // the paths already the same as the snapshot
+}
+
+type replicationRename struct {
+	from string
+	to   string
+}
+
+func NewReplicator(adminTask *proto.AdminTask, l *LcNode) (*Replicator, error) {
+	request := adminTask.Request.(*proto.ReplicationTaskRequest)
+	if request.Task.Replication == nil {
+		return nil, fmt.Errorf("replication task %v without replication", request.Task.Id)
+	}
+	r := &Replicator{
+		ID:          request.Task.Id,
+		Volume:      request.Task.Replication.SrcVol,
+		lcnode:      l,
+		adminTask:   adminTask,
+		replReq:     request,
+		replication: request.Task.Replication,
+		srcMc:       l.mc,
+		currentStat: &proto.ReplicationStatistics{},
+		stopC:       make(chan bool),
+	}
+	return r, nil
+}
+
+func (l *LcNode) startReplication(adminTask *proto.AdminTask) (err error) {
+	request := adminTask.Request.(*proto.ReplicationTaskRequest)
+	log.LogInfof("startReplication: replication task(%v) received!", request.Task)
+	response := &proto.ReplicationTaskResponse{}
+	adminTask.Response = response
+
+	l.scannerMutex.Lock()
+	if _, ok := l.replicators[request.Task.Id]; ok {
+		log.LogInfof("startReplication: replication task(%v) is already running!", request.Task)
+		l.scannerMutex.Unlock()
+		return
+	}
+
+	var replicator *Replicator
+	replicator, err = NewReplicator(adminTask, l)
+	if err != nil {
+		log.LogErrorf("startReplication: NewReplicator err(%v)", err)
+		response.Status = proto.TaskFailed
+		response.Result = err.Error()
+		response.ID = request.Task.Id
+		response.ReplicationTask = request.Task
+		response.Done = true
+		t := time.Now()
+		response.EndTime = &t
+		l.scannerMutex.Unlock()
+		return
+	}
+	l.replicators[replicator.ID] = replicator
+	l.scannerMutex.Unlock()
+
+	go replicator.Start()
+	return
+}
+
+func (r *Replicator) Stop() {
+	close(r.stopC)
+	log.LogDebugf("replicator(%v) stopped", r.ID)
+}
+
+func (r *Replicator) stopped() bool {
+	select {
+	case <-r.stopC:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Replicator) Start() {
+	response := r.adminTask.Response.(*proto.ReplicationTaskResponse)
+	t := time.Now()
+	response.StartTime = &t
+
+	err := r.replicate()
+
+	t = time.Now()
+	response.EndTime = &t
+	response.UpdateTime = &t
+	response.Done = true
+	response.ID = r.ID
+	response.LcNode = r.lcnode.localServerAddr
+	response.ReplicationTask = r.replReq.Task
+	response.VerSeq = r.verSeq
+	response.VerTime = r.verTime
+	response.ReplicationStatistics = r.statistics()
+	if err != nil {
+		response.Status = proto.TaskFailed
+		response.Result = err.Error()
+	} else {
+		response.Status = proto.TaskSucceeds
+	}
+
+	r.lcnode.scannerMutex.Lock()
+	if r.stopped() {
+		r.lcnode.scannerMutex.Unlock()
+		return
+	}
+	r.Stop()
+	delete(r.lcnode.replicators, r.ID)
+	r.lcnode.scannerMutex.Unlock()
+
+	r.lcnode.respondToMaster(r.adminTask)
+	log.LogInfof("replication completed for task(%v) err(%v)", r.adminTask, err)
+}
+
+func (r *Replicator) statistics() proto.ReplicationStatistics {
+	return proto.ReplicationStatistics{
+		SrcVol:     r.Volume,
+		DirNum:     atomic.LoadInt64(&r.currentStat.DirNum),
+		FileNum:    atomic.LoadInt64(&r.currentStat.FileNum),
+		DeletedNum: atomic.LoadInt64(&r.currentStat.DeletedNum),
+		RenamedNum: atomic.LoadInt64(&r.currentStat.RenamedNum),
+		TotalBytes: atomic.LoadInt64(&r.currentStat.TotalBytes),
+	}
+}
+
+func (r *Replicator) replicate() (err error) {
+	fromVer, err := r.lastSnapshot()
+	if err != nil {
+		return
+	}
+	if err = r.createSnapshot(); err != nil {
+		return
+	}
+	defer func() {
+		// the snapshot replicated last time is kept until the next one is replicated
+		delVer := fromVer
+		if err != nil {
+			delVer = r.verSeq
+		}
+		r.deleteSnapshot(delVer)
+	}()
+	defer r.close()
+	if err = r.open(fromVer); err != nil {
+		return
+	}
+
+	if fromVer == 0 {
+		log.LogInfof("replicate: vol(%v) mirror snapshot(%v) to %v/%v", r.Volume, r.verSeq, r.replication.DstMasters, r.replication.DstVol)
+		err = r.syncPath("/")
+	} else {
+		log.LogInfof("replicate: vol(%v) apply snapshot(%v) to(%v) to %v/%v", r.Volume, fromVer, r.verSeq, r.replication.DstMasters, r.replication.DstVol)
+		err = r.applyDiff(fromVer)
+	}
+	if err != nil {
+		log.LogErrorf("replicate: vol(%v) snapshot(%v) err(%v) stat(%+v)", r.Volume, r.verSeq, err, r.statistics())
+		return
+	}
+	log.LogInfof("replicate: vol(%v) snapshot(%v) replicated, stat(%+v)", r.Volume, r.verSeq, r.statistics())
+	return
+}
+
+// lastSnapshot returns the snapshot replicated last time, or 0 if the whole tree is mirrored
+// since the snapshot is not replicated or deleted.
+func (r *Replicator) lastSnapshot() (uint64, error) {
+	if r.replication.LastVerSeq == 0 {
+		return 0, nil
+	}
+	verList, err := r.srcMc.AdminAPI().GetVerList(r.Volume)
+	if err != nil {
+		log.LogErrorf("replicate: vol(%v) get versions err(%v)", r.Volume, err)
+		return 0, err
+	}
+	for _, ver := range verList.VerList {
+		if ver.Ver == r.replication.LastVerSeq && ver.Status == proto.VersionNormal {
+			return ver.Ver, nil
+		}
+	}
+	log.LogWarnf("replicate: vol(%v) snapshot(%v) replicated last time is not found, mirror the tree", r.Volume, r.replication.LastVerSeq)
+	return 0, nil
+}
+
+// createSnapshot creates a version of the source volume, the version current before is the snapshot
+// of the volume when the version is created.
+func (r *Replicator) createSnapshot() error {
+	created, err := r.srcMc.AdminAPI().CreateVersion(r.Volume)
+	if err != nil {
+		log.LogErrorf("replicate: vol(%v) create version err(%v)", r.Volume, err)
+		return err
+	}
+	verList, err := r.srcMc.AdminAPI().GetVerList(r.Volume)
+	if err != nil {
+		log.LogErrorf("replicate: vol(%v) get versions err(%v)", r.Volume, err)
+		return err
+	}
+	for _, ver := range verList.VerList {
+		if ver.Ver < created.Ver && ver.Status == proto.VersionNormal {
+			r.verSeq = ver.Ver
+		}
+	}
+	if r.verSeq == 0 {
+		return fmt.Errorf("no snapshot before version %v", created.Ver)
+	}
+	r.verTime = int64(created.Ver / uint64(time.Second/time.Microsecond))
+	log.LogInfof("replicate: vol(%v) version(%v) created, snapshot(%v)", r.Volume, created.Ver, r.verSeq)
+	return nil
+}
+
+func (r *Replicator) deleteSnapshot(verSeq uint64) {
+	if verSeq == 0 {
+		return
+	}
+	if err := r.srcMc.AdminAPI().DeleteVersion(r.Volume, strconv.FormatUint(verSeq, 10)); err != nil {
+		log.LogWarnf("replicate: vol(%v) delete snapshot(%v) err(%v)", r.Volume, verSeq, err)
+	}
+}
+
+func newReplicationExtentClient(volume string, masters []string, mw *meta.MetaWrapper, verSeq uint64,
+	ebsc *blobstore.BlobStoreClient,
+) (*stream.ExtentClient, error) {
+	config := &stream.ExtentConfig{
+		Volume:            volume,
+		Masters:           masters,
+		FollowerRead:      true,
+		VerReadSeq:        verSeq,
+		OnAppendExtentKey: mw.AppendExtentKey,
+		OnSplitExtentKey:  mw.SplitExtentKey,
+		OnGetExtents:      mw.GetExtents,
+		OnTruncate:        mw.Truncate,
+		OnLoadFileCipher:  mw.LoadFileCipher,
+	}
+	if ebsc != nil {
+		config.OnGetObjExtents = mw.GetObjExtents
+		config.OnReadObjExtent = ebsc.Read
+	}
+	return stream.NewExtentClient(config)
+}
+
+func (r *Replicator) open(fromVer uint64) (err error) {
+	dstMasters := strings.Split(r.replication.DstMasters, ",")
+	if fromVer != 0 {
+		if r.fromMw, err = meta.NewMetaWrapper(&meta.MetaConfig{Volume: r.Volume, Masters: r.lcnode.masters, VerReadSeq: fromVer}); err != nil {
+			return
+		}
+	}
+	if r.snapMw, err = meta.NewMetaWrapper(&meta.MetaConfig{Volume: r.Volume, Masters: r.lcnode.masters, VerReadSeq: r.verSeq}); err != nil {
+		return
+	}
+	// the data of the source transitioned to the blobstore is read through the holes of the extents
+	var ebsc *blobstore.BlobStoreClient
+	if r.lcnode.ebsAddr != "" {
+		if ebsc, err = r.lcnode.newEbsClient(defaultTransitionBlockSize); err != nil {
+			return
+		}
+	}
+	if r.snapEc, err = newReplicationExtentClient(r.Volume, r.lcnode.masters, r.snapMw, r.verSeq, ebsc); err != nil {
+		return
+	}
+	if r.dstMw, err = meta.NewMetaWrapper(&meta.MetaConfig{Volume: r.replication.DstVol, Masters: dstMasters}); err != nil {
+		return
+	}
+	if r.dstEc, err = newReplicationExtentClient(r.replication.DstVol, dstMasters, r.dstMw, 0, nil); err != nil {
+		return
+	}
+	return
+}
+
+func (r *Replicator) close() {
+	if r.snapEc != nil {
+		r.snapEc.Close()
+	}
+	if r.dstEc != nil {
+		r.dstEc.Close()
+	}
+	for _, mw := range []*meta.MetaWrapper{r.fromMw, r.snapMw, r.dstMw} {
+		if mw != nil {
+			mw.Close()
+		}
+	}
+}
+
+// applyDiff applies the paths changed since the snapshot replicated last time. The deleted paths
+// are removed before the renamed ones are moved, and the created and modified ones are synced at
+// last, so the paths of each step are the ones of the tree of the target at the time.
+func (r *Replicator) applyDiff(fromVer uint64) (err error) {
+	var deleted, renamed, changed []*proto.SnapshotDiffItem
+	err = r.snapMw.SnapshotDiff(fromVer, r.verSeq, func(item *proto.SnapshotDiffItem) bool {
+		if isReplicationSkipped(item.Path) || (item.OldPath != "" && isReplicationSkipped(item.OldPath)) {
+			return true
+		}
+		switch item.Op {
+		case proto.SnapshotDiffDeleted:
+			deleted = append(deleted, item)
+		case proto.SnapshotDiffRenamed:
+			renamed = append(renamed, item)
+		default:
+			changed = append(changed, item)
+		}
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	for _, item := range deleted {
+		if r.stopped() {
+			return errReplicationStopped
+		}
+		if err = r.removePath(item.Path); err != nil {
+			return
+		}
+	}
+	sort.SliceStable(renamed, func(i, j int) bool { return renamed[i].OldPath < renamed[j].OldPath })
+	for _, item := range renamed {
+		if r.stopped() {
+			return errReplicationStopped
+		}
+		if err = r.renamePath(item); err != nil {
+			return
+		}
+	}
+	for _, item := range changed {
+		if r.stopped() {
+			return errReplicationStopped
+		}
+		if item.Op == proto.SnapshotDiffModified && proto.IsRegular(item.Type) {
+			err = r.syncModifiedFile(item)
+		} else {
+			err = r.syncPath(item.Path)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// isReplicationSkipped checks whether the path is the trash or in the trash, the target volume
+// keeps its own trash.
+func isReplicationSkipped(path string) bool {
+	trash := "/" + proto.TrashDirName
+	return path == trash || strings.HasPrefix(path, trash+"/") || strings.Contains(path, "<ino ")
+}
+
+// translate returns the path in the target of the path of the snapshot replicated last time,
+// after the directories renamed in the round.
+func (r *Replicator) translate(path string) string {
+	for i := len(r.renamed) - 1; i >= 0; i-- {
+		rn := r.renamed[i]
+		if path == rn.from {
+			return rn.to
+		}
+		if strings.HasPrefix(path, rn.from+"/") {
+			return rn.to + path[len(rn.from):]
+		}
+	}
+	return path
+}
+
+func (r *Replicator) isFinal(path string) bool {
+	for _, p := range r.final {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Replicator) renamePath(item *proto.SnapshotDiffItem) (err error) {
+	src := r.translate(item.OldPath)
+	if !r.isFinal(src) {
+		err = r.rename(src, item.Path)
+		if err == nil {
+			atomic.AddInt64(&r.currentStat.RenamedNum, 1)
+			if proto.IsDir(item.Type) {
+				r.renamed = append(r.renamed, replicationRename{from: item.OldPath, to: item.Path})
+			}
+			r.final = append(r.final, item.Path)
+			return
+		}
+		log.LogWarnf("replicate: vol(%v) rename %v to %v err(%v), sync it", r.Volume, src, item.Path, err)
+		if err = r.removePath(src); err != nil {
+			return
+		}
+	}
+	// the source is moved or replaced by the renames before, so the path is synced from the snapshot
+	if err = r.syncPath(item.Path); err != nil {
+		return
+	}
+	r.final = append(r.final, item.Path)
+	return
+}
+
+func (r *Replicator) rename(src, dst string) (err error) {
+	srcParent, err := r.dstMw.LookupPath(gopath.Dir(src))
+	if err != nil {
+		return
+	}
+	dstParent, err := r.ensureDir(gopath.Dir(dst))
+	if err != nil {
+		return
+	}
+	return r.dstMw.Rename_ll(srcParent, gopath.Base(src), dstParent, gopath.Base(dst), src, dst, false)
+}
+
+// ensureDir returns the directory of the target, the missing directories are created by the ones
+// of the snapshot.
+func (r *Replicator) ensureDir(path string) (ino uint64, err error) {
+	ino = proto.RootIno
+	if path == "/" {
+		return
+	}
+	cur := "/"
+	for _, name := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		cur = gopath.Join(cur, name)
+		var child uint64
+		child, _, err = r.dstMw.Lookup_ll(ino, name)
+		if err == nil {
+			ino = child
+			continue
+		}
+		if err != syscall.ENOENT {
+			return
+		}
+		var info *proto.InodeInfo
+		if info, err = r.snapshotInode(cur); err != nil {
+			return
+		}
+		if info, err = r.dstMw.Create_ll(ino, name, info.Mode, info.Uid, info.Gid, nil, cur); err != nil {
+			return
+		}
+		atomic.AddInt64(&r.currentStat.DirNum, 1)
+		ino = info.Inode
+	}
+	return
+}
+
+func (r *Replicator) snapshotInode(path string) (*proto.InodeInfo, error) {
+	ino, err := r.snapMw.LookupPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.snapMw.InodeGet_ll(ino)
+}
+
+// removePath removes the path of the target and all the entries under it.
+func (r *Replicator) removePath(path string) (err error) {
+	if path == "/" {
+		return
+	}
+	parent, err := r.dstMw.LookupPath(gopath.Dir(path))
+	if err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	ino, mode, err := r.dstMw.Lookup_ll(parent, gopath.Base(path))
+	if err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	return r.removeEntry(parent, gopath.Base(path), ino, mode, path)
+}
+
+func (r *Replicator) removeEntry(parent uint64, name string, ino uint64, mode uint32, path string) (err error) {
+	isDir := proto.IsDir(mode)
+	if isDir {
+		var children []proto.Dentry
+		if children, err = r.readDir(r.dstMw, ino); err != nil {
+			return
+		}
+		for _, child := range children {
+			if r.stopped() {
+				return errReplicationStopped
+			}
+			if err = r.removeEntry(ino, child.Name, child.Inode, child.Type, gopath.Join(path, child.Name)); err != nil {
+				return
+			}
+		}
+	}
+	info, err := r.dstMw.Delete_ll(parent, name, isDir, path)
+	if err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil {
+		log.LogErrorf("replicate: vol(%v) delete %v err(%v)", r.replication.DstVol, path, err)
+		return
+	}
+	if !isDir && info != nil {
+		if err = r.dstMw.Evict(info.Inode, path); err != nil {
+			log.LogWarnf("replicate: vol(%v) evict %v err(%v)", r.replication.DstVol, path, err)
+		}
+	}
+	atomic.AddInt64(&r.currentStat.DeletedNum, 1)
+	return nil
+}
+
+func (r *Replicator) readDir(mw *meta.MetaWrapper, parentID uint64) (children []proto.Dentry, err error) {
+	marker := ""
+	for {
+		var dentries []proto.Dentry
+		dentries, err = mw.ReadDirLimit_ll(parentID, marker, uint64(defaultReadDirLimit))
+		if err != nil {
+			log.LogErrorf("replicate: ReadDirLimit_ll failed, parent[%v] marker[%v] err[%v]", parentID, marker, err)
+			return
+		}
+		full := len(dentries) >= defaultReadDirLimit
+		if marker != "" && len(dentries) > 0 && dentries[0].Name == marker {
+			dentries = dentries[1:]
+		}
+		children = append(children, dentries...)
+		if !full || len(dentries) == 0 {
+			return
+		}
+		marker = dentries[len(dentries)-1].Name
+	}
+}
+
+// syncPath makes the path of the target the same as the one of the snapshot.
+func (r *Replicator) syncPath(path string) (err error) {
+	info, err := r.snapshotInode(path)
+	if err == syscall.ENOENT {
+		return r.removePath(path)
+	}
+	if err != nil {
+		return
+	}
+	if path == "/" {
+		return r.syncDir(info, proto.RootIno, path)
+	}
+	parent, err := r.ensureDir(gopath.Dir(path))
+	if err != nil {
+		return
+	}
+	return r.syncEntry(info, parent, gopath.Base(path), path)
+}
+
+func (r *Replicator) syncEntry(info *proto.InodeInfo, parent uint64, name, path string) (err error) {
+	var dstInfo *proto.InodeInfo
+	ino, mode, err := r.dstMw.Lookup_ll(parent, name)
+	switch {
+	case err == syscall.ENOENT:
+	case err != nil:
+		return
+	case proto.IsDir(mode) != proto.IsDir(info.Mode) || proto.IsSymlink(mode) != proto.IsSymlink(info.Mode):
+		if err = r.removeEntry(parent, name, ino, mode, path); err != nil {
+			return
+		}
+	default:
+		if dstInfo, err = r.dstMw.InodeGet_ll(ino); err != nil {
+			return
+		}
+		if proto.IsSymlink(info.Mode) && string(dstInfo.Target) != string(info.Target) {
+			if err = r.removeEntry(parent, name, ino, mode, path); err != nil {
+				return
+			}
+			dstInfo = nil
+		}
+	}
+
+	if dstInfo == nil {
+		var target []byte
+		if proto.IsSymlink(info.Mode) {
+			target = info.Target
+		}
+		if dstInfo, err = r.dstMw.Create_ll(parent, name, info.Mode, info.Uid, info.Gid, target, path); err != nil {
+			log.LogErrorf("replicate: vol(%v) create %v err(%v)", r.replication.DstVol, path, err)
+			return
+		}
+		if proto.IsDir(info.Mode) {
+			atomic.AddInt64(&r.currentStat.DirNum, 1)
+		}
+	}
+
+	switch {
+	case proto.IsDir(info.Mode):
+		if err = r.syncDir(info, dstInfo.Inode, path); err != nil {
+			return
+		}
+	case proto.IsRegular(info.Mode):
+		if dstInfo.Size != info.Size || dstInfo.ModifyTime.Unix() != info.ModifyTime.Unix() {
+			if err = r.copyFile(info, dstInfo.Inode, parent, path, []fileRange{{0, info.Size}}); err != nil {
+				return
+			}
+		}
+	}
+	return r.syncAttr(info, dstInfo)
+}
+
+// syncDir makes the entries of the directory of the target the same as the ones of the snapshot.
+func (r *Replicator) syncDir(info *proto.InodeInfo, dstIno uint64, path string) (err error) {
+	children, err := r.readDir(r.snapMw, info.Inode)
+	if err != nil {
+		return
+	}
+	dstChildren, err := r.readDir(r.dstMw, dstIno)
+	if err != nil {
+		return
+	}
+	names := make(map[string]struct{}, len(children))
+	for _, child := range children {
+		names[child.Name] = struct{}{}
+	}
+	for _, child := range dstChildren {
+		childPath := gopath.Join(path, child.Name)
+		if _, ok := names[child.Name]; ok || isReplicationSkipped(childPath) {
+			continue
+		}
+		if err = r.removeEntry(dstIno, child.Name, child.Inode, child.Type, childPath); err != nil {
+			return
+		}
+	}
+
+	for _, child := range children {
+		if r.stopped() {
+			return errReplicationStopped
+		}
+		childPath := gopath.Join(path, child.Name)
+		if isReplicationSkipped(childPath) {
+			continue
+		}
+		var childInfo *proto.InodeInfo
+		childInfo, err = r.snapMw.InodeGet_ll(child.Inode)
+		if err == syscall.ENOENT {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if err = r.syncEntry(childInfo, dstIno, child.Name, childPath); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+func (r *Replicator) syncAttr(info, dstInfo *proto.InodeInfo) error {
+	if info.Mode == dstInfo.Mode && info.Uid == dstInfo.Uid && info.Gid == dstInfo.Gid &&
+		(proto.IsDir(info.Mode) || info.ModifyTime.Unix() == dstInfo.ModifyTime.Unix()) {
+		return nil
+	}
+	valid := proto.AttrMode | proto.AttrUid | proto.AttrGid | proto.AttrModifyTime | proto.AttrAccessTime
+	return r.dstMw.Setattr(dstInfo.Inode, valid, info.Mode, info.Uid, info.Gid, info.AccessTime.Unix(), info.ModifyTime.Unix())
+}
+
+// syncModifiedFile copies the ranges of the file changed since the snapshot replicated last time,
+// the whole file is copied if the target is not the same as the snapshot replicated last time.
+func (r *Replicator) syncModifiedFile(item *proto.SnapshotDiffItem) (err error) {
+	_, fromSize, fromEks, err := r.fromMw.GetExtents(item.Inode)
+	if err != nil {
+		return r.syncPath(item.Path)
+	}
+	info, err := r.snapshotInode(item.Path)
+	if err == syscall.ENOENT {
+		return r.removePath(item.Path)
+	}
+	if err != nil {
+		return
+	}
+	_, _, eks, err := r.snapMw.GetExtents(info.Inode)
+	if err != nil {
+		return
+	}
+	parent, err := r.dstMw.LookupPath(gopath.Dir(item.Path))
+	if err != nil {
+		return r.syncPath(item.Path)
+	}
+	ino, mode, err := r.dstMw.Lookup_ll(parent, gopath.Base(item.Path))
+	if err != nil || !proto.IsRegular(mode) {
+		return r.syncPath(item.Path)
+	}
+	dstInfo, err := r.dstMw.InodeGet_ll(ino)
+	if err != nil {
+		return
+	}
+	if dstInfo.Size != fromSize || info.Inode != item.Inode {
+		return r.syncPath(item.Path)
+	}
+	if err = r.copyFile(info, ino, parent, item.Path, changedRanges(fromEks, eks, info.Size)); err != nil {
+		return
+	}
+	return r.syncAttr(info, dstInfo)
+}
+
+type fileRange struct {
+	offset uint64
+	size   uint64
+}
+
+// changedRanges returns the ranges of the file to copy, which are covered by the extent keys not
+// in both of the versions. The ranges not covered by the keys of the new version are holes and read
+// as zero, and the ranges beyond the size are truncated.
+func changedRanges(oldEks, newEks []proto.ExtentKey, size uint64) (ranges []fileRange) {
+	type ekID struct {
+		fileOffset, partitionID, extentID, extentOffset uint64
+		size                                            uint32
+	}
+	id := func(ek *proto.ExtentKey) ekID {
+		return ekID{ek.FileOffset, ek.PartitionId, ek.ExtentId, ek.ExtentOffset, ek.Size}
+	}
+	olds := make(map[ekID]struct{}, len(oldEks))
+	for i := range oldEks {
+		olds[id(&oldEks[i])] = struct{}{}
+	}
+	news := make(map[ekID]struct{}, len(newEks))
+	for i := range newEks {
+		news[id(&newEks[i])] = struct{}{}
+	}
+
+	add := func(ek *proto.ExtentKey) {
+		start, end := ek.FileOffset, ek.FileOffset+uint64(ek.Size)
+		if end > size {
+			end = size
+		}
+		if start < end {
+			ranges = append(ranges, fileRange{start, end - start})
+		}
+	}
+	for i := range newEks {
+		if _, ok := olds[id(&newEks[i])]; !ok {
+			add(&newEks[i])
+		}
+	}
+	for i := range oldEks {
+		if _, ok := news[id(&oldEks[i])]; !ok {
+			add(&oldEks[i])
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].offset < ranges[j].offset })
+	merged := ranges[:0]
+	for _, rg := range ranges {
+		if n := len(merged); n > 0 && rg.offset <= merged[n-1].offset+merged[n-1].size {
+			if end := rg.offset + rg.size; end > merged[n-1].offset+merged[n-1].size {
+				merged[n-1].size = end - merged[n-1].offset
+			}
+			continue
+		}
+		merged = append(merged, rg)
+	}
+	return merged
+}
+
+// copyFile copies the ranges of the file of the snapshot to the file of the target, and truncates
+// the target to the size of the snapshot.
+func (r *Replicator) copyFile(info *proto.InodeInfo, dstIno, dstParent uint64, path string, ranges []fileRange) (err error) {
+	if err = r.snapEc.OpenStream(info.Inode); err != nil {
+		return
+	}
+	defer r.snapEc.CloseStream(info.Inode)
+	if err = r.dstEc.OpenStream(dstIno); err != nil {
+		return
+	}
+	defer r.dstEc.CloseStream(dstIno)
+
+	buf := make([]byte, replicationCopyBufSize)
+	for _, rg := range ranges {
+		for off, end := rg.offset, rg.offset+rg.size; off < end; {
+			if r.stopped() {
+				return errReplicationStopped
+			}
+			size := end - off
+			if size > uint64(len(buf)) {
+				size = uint64(len(buf))
+			}
+			var n int
+			n, err = r.snapEc.Read(info.Inode, buf[:size], int(off), int(size))
+			if err != nil && err != io.EOF {
+				log.LogErrorf("replicate: vol(%v) read %v offset(%v) err(%v)", r.Volume, path, off, err)
+				return
+			}
+			if n <= 0 {
+				break
+			}
+			if _, err = r.dstEc.Write(dstIno, int(off), buf[:n], 0, nil); err != nil {
+				log.LogErrorf("replicate: vol(%v) write %v offset(%v) err(%v)", r.replication.DstVol, path, off, err)
+				return
+			}
+			off += uint64(n)
+			atomic.AddInt64(&r.currentStat.TotalBytes, int64(n))
+		}
+	}
+	if err = r.dstEc.Flush(dstIno); err != nil {
+		return
+	}
+	if err = r.dstEc.Truncate(r.dstMw, dstParent, dstIno, int(info.Size), path); err != nil {
+		return
+	}
+	atomic.AddInt64(&r.currentStat.FileNum, 1)
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package lcnode
+
+import (
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedRanges(t *testing.T) {
+	oldEks := []proto.ExtentKey{
+		{FileOffset: 0, PartitionId: 1, ExtentId: 1, Size: 100},
+		{FileOffset: 100, PartitionId: 1, ExtentId: 2, Size: 100},
+		{FileOffset: 200, PartitionId: 1, ExtentId: 3, Size: 100},
+	}
+
+	// unchanged
+	require.Empty(t, changedRanges(oldEks, oldEks, 300))
+
+	// the second key is overwritten and the file is appended
+	newEks := []proto.ExtentKey{
+		{FileOffset: 0, PartitionId: 1, ExtentId: 1, Size: 100},
+		{FileOffset: 100, PartitionId: 2, ExtentId: 7, Size: 50},
+		{FileOffset: 150, PartitionId: 1, ExtentId: 2, ExtentOffset: 50, Size: 50},
+		{FileOffset: 200, PartitionId: 1, ExtentId: 3, Size: 100},
+		{FileOffset: 300, PartitionId: 2, ExtentId: 8, Size: 100},
+	}
+	require.Equal(t, []fileRange{{100, 100}, {300, 100}}, changedRanges(oldEks, newEks, 400))
+
+	// the hole punched is copied as zero, and the ranges are clipped by the size truncated
+	newEks = []proto.ExtentKey{
+		{FileOffset: 0, PartitionId: 1, ExtentId: 1, Size: 100},
+		{FileOffset: 200, PartitionId: 1, ExtentId: 3, Size: 100},
+	}
+	require.Equal(t, []fileRange{{100, 100}}, changedRanges(oldEks, newEks, 300))
+	require.Equal(t, []fileRange{{100, 50}}, changedRanges(oldEks, newEks, 150))
+	require.Empty(t, changedRanges(oldEks, newEks, 100))
+}
+
+func TestReplicatorPaths(t *testing.T) {
+	require.True(t, isReplicationSkipped("/"+proto.TrashDirName))
+	require.True(t, isReplicationSkipped("/"+proto.TrashDirName+"/1700000000/a"))
+	require.True(t, isReplicationSkipped("/<ino 10>/a"))
+	require.False(t, isReplicationSkipped("/a/"+proto.TrashDirName))
+
+	r := &Replicator{}
+	r.renamed = append(r.renamed, replicationRename{from: "/a", to: "/b"})
+	require.Equal(t, "/b", r.translate("/a"))
+	require.Equal(t, "/b/c/d", r.translate("/a/c/d"))
+	require.Equal(t, "/ab", r.translate("/ab"))
+
+	r.final = append(r.final, "/b")
+	require.True(t, r.isFinal("/b"))
+	require.True(t, r.isFinal("/b/c"))
+	require.False(t, r.isFinal("/bc"))
+}
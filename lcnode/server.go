@@ -42,6 +42,7 @@ type LcNode struct {
 	listen           string
 	localServerAddr  string
 	clusterID        string
+	ebsAddr          string
 	nodeID           uint64
 	masters          []string
 	mc               *master.MasterClient
@@ -51,12 +52,16 @@ type LcNode struct {
 	control          common.Control
 	lcScanners       map[string]*LcScanner
 	snapshotScanners map[string]*SnapshotScanner
+	trashScanners    map[string]*TrashScanner
+	replicators      map[string]*Replicator
 }
 
 func NewServer() *LcNode {
 	return &LcNode{
 		lcScanners:       make(map[string]*LcScanner),
 		snapshotScanners: make(map[string]*SnapshotScanner),
+		trashScanners:    make(map[string]*TrashScanner),
+		replicators:      make(map[string]*Replicator),
 	}
 }
 
@@ -233,6 +238,7 @@ func (l *LcNode) register() {
 			}
 			masterAddr := l.mc.Leader()
 			l.clusterID = ci.Cluster
+			l.ebsAddr = ci.EbsAddr
 			localIP := ci.Ip
 			l.localServerAddr = fmt.Sprintf("%s:%v", localIP, l.listen)
 			if !util.IsIPV4(localIP) {
@@ -336,6 +342,10 @@ func (l *LcNode) handlePacket(conn net.Conn, p *proto.Packet, remoteAddr string)
 		err = l.opLcScan(conn, p)
 	case proto.OpLcNodeSnapshotVerDel:
 		err = l.opSnapshotVerDel(conn, p)
+	case proto.OpLcNodeTrashClean:
+		err = l.opTrashClean(conn, p)
+	case proto.OpLcNodeReplication:
+		err = l.opReplication(conn, p)
 	default:
 		err = fmt.Errorf("%s unknown Opcode: %d, reqId: %d", remoteAddr,
 			p.Opcode, p.GetReqID())
@@ -370,4 +380,12 @@ func (l *LcNode) stopScanners() {
 		s.Stop()
 		delete(l.snapshotScanners, s.ID)
 	}
+	for _, s := range l.trashScanners {
+		s.Stop()
+		delete(l.trashScanners, s.ID)
+	}
+	for _, r := range l.replicators {
+		r.Stop()
+		delete(l.replicators, r.ID)
+	}
 }
@@ -0,0 +1,253 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package lcnode
+
+import (
+	"os"
+	gopath "path"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/meta"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// TrashScanner purges the expired buckets in the trash of a volume, the entries in
+// a bucket are deleted depth first and then the bucket itself.
+type TrashScanner struct {
+	ID          string
+	Volume      string
+	mw          MetaWrapper
+	lcnode      *LcNode
+	adminTask   *proto.AdminTask
+	cleanReq    *proto.TrashCleanTaskRequest
+	currentStat *proto.TrashStatistics
+	now         time.Time
+	stopC       chan bool
+}
+
+func NewTrashScanner(adminTask *proto.AdminTask, l *LcNode) (*TrashScanner, error) {
+	request := adminTask.Request.(*proto.TrashCleanTaskRequest)
+	var err error
+	metaConfig := &meta.MetaConfig{
+		Volume:        request.Task.VolName,
+		Masters:       l.masters,
+		Authenticate:  false,
+		ValidateOwner: false,
+	}
+
+	var metaWrapper *meta.MetaWrapper
+	if metaWrapper, err = meta.NewMetaWrapper(metaConfig); err != nil {
+		return nil, err
+	}
+
+	scanner := &TrashScanner{
+		ID:          request.Task.Id,
+		Volume:      request.Task.VolName,
+		mw:          metaWrapper,
+		lcnode:      l,
+		adminTask:   adminTask,
+		cleanReq:    request,
+		currentStat: &proto.TrashStatistics{},
+		now:         time.Now(),
+		stopC:       make(chan bool),
+	}
+	return scanner, nil
+}
+
+func (l *LcNode) startTrashScan(adminTask *proto.AdminTask) (err error) {
+	request := adminTask.Request.(*proto.TrashCleanTaskRequest)
+	log.LogInfof("startTrashScan: scan task(%v) received!", request.Task)
+	response := &proto.TrashCleanTaskResponse{}
+	adminTask.Response = response
+
+	l.scannerMutex.Lock()
+	if _, ok := l.trashScanners[request.Task.Id]; ok {
+		log.LogInfof("startTrashScan: scan task(%v) is already running!", request.Task)
+		l.scannerMutex.Unlock()
+		return
+	}
+
+	var scanner *TrashScanner
+	scanner, err = NewTrashScanner(adminTask, l)
+	if err != nil {
+		log.LogErrorf("startTrashScan: NewTrashScanner err(%v)", err)
+		response.Status = proto.TaskFailed
+		response.Result = err.Error()
+		response.ID = request.Task.Id
+		response.Done = true
+		t := time.Now()
+		response.EndTime = &t
+		l.scannerMutex.Unlock()
+		return
+	}
+	l.trashScanners[scanner.ID] = scanner
+	l.scannerMutex.Unlock()
+
+	go scanner.Start()
+	return
+}
+
+func (s *TrashScanner) Stop() {
+	close(s.stopC)
+	s.mw.Close()
+	log.LogDebugf("trash scanner(%v) stopped", s.ID)
+}
+
+func (s *TrashScanner) stopped() bool {
+	select {
+	case <-s.stopC:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *TrashScanner) Start() {
+	response := s.adminTask.Response.(*proto.TrashCleanTaskResponse)
+	t := time.Now()
+	response.StartTime = &t
+
+	err := s.clean()
+
+	t = time.Now()
+	response.EndTime = &t
+	response.UpdateTime = &t
+	response.Done = true
+	response.ID = s.ID
+	response.LcNode = s.lcnode.localServerAddr
+	response.TrashCleanTask = s.cleanReq.Task
+	response.VolName = s.Volume
+	response.BucketNum = atomic.LoadInt64(&s.currentStat.BucketNum)
+	response.FileNum = atomic.LoadInt64(&s.currentStat.FileNum)
+	response.DirNum = atomic.LoadInt64(&s.currentStat.DirNum)
+	response.ErrorSkippedNum = atomic.LoadInt64(&s.currentStat.ErrorSkippedNum)
+	if err != nil {
+		response.Status = proto.TaskFailed
+		response.Result = err.Error()
+	} else {
+		response.Status = proto.TaskSucceeds
+	}
+
+	s.lcnode.scannerMutex.Lock()
+	if s.stopped() {
+		s.lcnode.scannerMutex.Unlock()
+		return
+	}
+	s.Stop()
+	delete(s.lcnode.trashScanners, s.ID)
+	s.lcnode.scannerMutex.Unlock()
+
+	s.lcnode.respondToMaster(s.adminTask)
+	log.LogInfof("trash clean completed for task(%v)", s.adminTask)
+}
+
+func (s *TrashScanner) clean() (err error) {
+	trashIno, _, err := s.mw.Lookup_ll(proto.RootIno, proto.TrashDirName)
+	if err == syscall.ENOENT {
+		log.LogInfof("trash clean: volume(%v) has no trash", s.Volume)
+		return nil
+	}
+	if err != nil {
+		log.LogErrorf("trash clean: volume(%v) lookup trash err(%v)", s.Volume, err)
+		return
+	}
+
+	buckets, err := s.readDir(trashIno)
+	if err != nil {
+		return
+	}
+	for _, bucket := range buckets {
+		if s.stopped() {
+			return
+		}
+		if !os.FileMode(bucket.Type).IsDir() || !proto.IsTrashBucketExpired(bucket.Name, s.cleanReq.Task.TrashInterval, s.now) {
+			continue
+		}
+		log.LogInfof("trash clean: volume(%v) purge expired bucket(%v)", s.Volume, bucket.Name)
+		s.handleDeleteDepthFirst(&proto.ScanDentry{
+			ParentId: trashIno,
+			Name:     bucket.Name,
+			Inode:    bucket.Inode,
+			Path:     gopath.Join("/", proto.TrashDirName, bucket.Name),
+			Type:     bucket.Type,
+		})
+		atomic.AddInt64(&s.currentStat.BucketNum, 1)
+	}
+	return nil
+}
+
+func (s *TrashScanner) readDir(parentID uint64) (children []proto.Dentry, err error) {
+	marker := ""
+	for {
+		var dentries []proto.Dentry
+		dentries, err = s.mw.ReadDirLimit_ll(parentID, marker, uint64(defaultReadDirLimit))
+		if err != nil {
+			log.LogErrorf("trash clean: ReadDirLimit_ll failed, parent[%v] marker[%v] err[%v]", parentID, marker, err)
+			return
+		}
+		full := len(dentries) >= defaultReadDirLimit
+		if marker != "" && len(dentries) > 0 && dentries[0].Name == marker {
+			dentries = dentries[1:]
+		}
+		children = append(children, dentries...)
+		if !full || len(dentries) == 0 {
+			return
+		}
+		marker = dentries[len(dentries)-1].Name
+	}
+}
+
+func (s *TrashScanner) handleDeleteDepthFirst(dentry *proto.ScanDentry) {
+	if os.FileMode(dentry.Type).IsDir() {
+		children, err := s.readDir(dentry.Inode)
+		if err != nil {
+			atomic.AddInt64(&s.currentStat.ErrorSkippedNum, 1)
+			return
+		}
+		for _, child := range children {
+			if s.stopped() {
+				return
+			}
+			s.handleDeleteDepthFirst(&proto.ScanDentry{
+				ParentId: dentry.Inode,
+				Name:     child.Name,
+				Inode:    child.Inode,
+				Path:     gopath.Join(dentry.Path, child.Name),
+				Type:     child.Type,
+			})
+		}
+	}
+
+	isDir := os.FileMode(dentry.Type).IsDir()
+	ino, err := s.mw.DeleteWithCond_ll(dentry.ParentId, dentry.Inode, dentry.Name, isDir, dentry.Path)
+	if err != nil {
+		log.LogWarnf("trash clean: DeleteWithCond_ll failed, dentry(%+v) err(%v), skip it", dentry, err)
+		atomic.AddInt64(&s.currentStat.ErrorSkippedNum, 1)
+		return
+	}
+	if isDir {
+		atomic.AddInt64(&s.currentStat.DirNum, 1)
+		return
+	}
+	if ino != nil {
+		if err = s.mw.Evict(ino.Inode, dentry.Path); err != nil {
+			log.LogWarnf("trash clean: Evict failed, dentry(%+v) err(%v)", dentry, err)
+		}
+	}
+	atomic.AddInt64(&s.currentStat.FileNum, 1)
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package lcnode
+
+import (
+	"os"
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+// treeMetaWrapper keeps a directory tree in memory for the trash scanner.
+type treeMetaWrapper struct {
+	MockMetaWrapper
+	nextIno  uint64
+	children map[uint64][]proto.Dentry
+	evicted  []uint64
+}
+
+func newTreeMetaWrapper() *treeMetaWrapper {
+	return &treeMetaWrapper{
+		nextIno:  proto.RootIno + 1,
+		children: make(map[uint64][]proto.Dentry),
+	}
+}
+
+func (mw *treeMetaWrapper) add(parentID uint64, name string, isDir bool) uint64 {
+	ino := mw.nextIno
+	mw.nextIno++
+	mode := uint32(0o644)
+	if isDir {
+		mode = proto.Mode(os.ModeDir | 0o755)
+	}
+	mw.children[parentID] = append(mw.children[parentID], proto.Dentry{Name: name, Inode: ino, Type: mode})
+	sort.Slice(mw.children[parentID], func(i, j int) bool {
+		return mw.children[parentID][i].Name < mw.children[parentID][j].Name
+	})
+	return ino
+}
+
+func (mw *treeMetaWrapper) Lookup_ll(parentID uint64, name string) (inode uint64, mode uint32, err error) {
+	for _, d := range mw.children[parentID] {
+		if d.Name == name {
+			return d.Inode, d.Type, nil
+		}
+	}
+	return 0, 0, syscall.ENOENT
+}
+
+func (mw *treeMetaWrapper) ReadDirLimit_ll(parentID uint64, from string, limit uint64) ([]proto.Dentry, error) {
+	result := make([]proto.Dentry, 0)
+	for _, d := range mw.children[parentID] {
+		if d.Name >= from && uint64(len(result)) < limit {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+func (mw *treeMetaWrapper) DeleteWithCond_ll(parentID, cond uint64, name string, isDir bool, fullPath string) (*proto.InodeInfo, error) {
+	for i, d := range mw.children[parentID] {
+		if d.Name != name || d.Inode != cond {
+			continue
+		}
+		if isDir && len(mw.children[d.Inode]) > 0 {
+			return nil, syscall.ENOTEMPTY
+		}
+		mw.children[parentID] = append(mw.children[parentID][:i], mw.children[parentID][i+1:]...)
+		return &proto.InodeInfo{Inode: d.Inode, Mode: d.Type}, nil
+	}
+	return nil, syscall.ENOENT
+}
+
+func (mw *treeMetaWrapper) Evict(inode uint64, fullPath string) error {
+	mw.evicted = append(mw.evicted, inode)
+	return nil
+}
+
+func TestTrashScannerClean(t *testing.T) {
+	now := time.Now()
+	mw := newTreeMetaWrapper()
+	trash := mw.add(proto.RootIno, proto.TrashDirName, true)
+	expired := mw.add(trash, proto.TrashBucketName(now.Add(-2*time.Hour)), true)
+	file := mw.add(expired, "a_100", false)
+	dir := mw.add(expired, "d", true)
+	mw.add(dir, "b_101", false)
+	current := mw.add(trash, proto.TrashBucketName(now), true)
+	mw.add(current, "c_102", false)
+	mw.add(trash, "foo", true)
+
+	scanner := &TrashScanner{
+		ID:     "test_id",
+		Volume: "test_vol",
+		mw:     mw,
+		lcnode: &LcNode{},
+		cleanReq: &proto.TrashCleanTaskRequest{
+			Task: &proto.TrashCleanTask{TrashInterval: 30},
+		},
+		currentStat: &proto.TrashStatistics{},
+		now:         now,
+		stopC:       make(chan bool),
+	}
+	require.NoError(t, scanner.clean())
+
+	_, _, err := mw.Lookup_ll(trash, proto.TrashBucketName(now.Add(-2*time.Hour)))
+	require.Equal(t, syscall.ENOENT, err)
+	_, _, err = mw.Lookup_ll(current, "c_102")
+	require.NoError(t, err)
+	_, _, err = mw.Lookup_ll(trash, "foo")
+	require.NoError(t, err)
+	require.Contains(t, mw.evicted, file)
+	require.EqualValues(t, 1, scanner.currentStat.BucketNum)
+	require.EqualValues(t, 2, scanner.currentStat.FileNum)
+	require.EqualValues(t, 2, scanner.currentStat.DirNum)
+	require.EqualValues(t, 0, scanner.currentStat.ErrorSkippedNum)
+
+	// no trash in the volume
+	scanner.mw = newTreeMetaWrapper()
+	require.NoError(t, scanner.clean())
+}
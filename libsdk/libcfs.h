@@ -29,6 +29,7 @@ typedef struct { const char *p; ptrdiff_t n; } _GoString_;
 #include <sys/stat.h>
 #include <dirent.h>
 #include <fcntl.h>
+#include <sys/file.h>
 
 struct cfs_stat_info {
     uint64_t ino;
@@ -149,6 +150,8 @@ extern int cfs_rmdir(int64_t id, char* path);
 extern int cfs_unlink(int64_t id, char* path);
 extern int cfs_rename(int64_t id, char* from, char* to);
 extern int cfs_fchmod(int64_t id, int fd, mode_t mode);
+extern int cfs_flock(int64_t id, int fd, int op);
+extern int cfs_fcntl_lock(int64_t id, int fd, int cmd, struct flock* lk);
 extern int cfs_getsummary(int64_t id, char* path, struct cfs_summary_info* summary, char* useCache, int goroutine_num);
 
 #ifdef __cplusplus
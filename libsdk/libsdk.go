@@ -23,6 +23,7 @@ package main
 #include <sys/stat.h>
 #include <dirent.h>
 #include <fcntl.h>
+#include <sys/file.h>
 
 struct cfs_stat_info {
     uint64_t ino;
@@ -241,6 +242,11 @@ type client struct {
 	cluster             string
 	dirChildrenNumLimit uint32
 	enableAudit         bool
+	uid                 uint32 // user to check the permissions with posix acl enabled
+	gid                 uint32
+	owner               string // client id in authnode to get the key of the encrypted volume
+	clientKey           string
+	ticketHost          string
 
 	// runtime context
 	cwd    string // current working directory
@@ -324,6 +330,24 @@ func cfs_set_client(id C.int64_t, key, val *C.char) C.int {
 		} else {
 			c.enableAudit = false
 		}
+	case "uid":
+		uid, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return statusEINVAL
+		}
+		c.uid = uint32(uid)
+	case "gid":
+		gid, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return statusEINVAL
+		}
+		c.gid = uint32(gid)
+	case "owner":
+		c.owner = v
+	case "clientKey":
+		c.clientKey = v
+	case "ticketHost":
+		c.ticketHost = v
 	default:
 		return statusEINVAL
 	}
@@ -478,7 +502,7 @@ func cfs_open(id C.int64_t, path *C.char, flags C.int, mode C.mode_t) C.int {
 	var parentIno uint64
 
 	/*
-	 * Note that the rwx mode is ignored when using libsdk
+	 * Note that the rwx mode is ignored when using libsdk, unless posix acl is enabled on the volume
 	 */
 
 	if fuseFlags&uint32(C.O_CREAT) != 0 {
@@ -498,13 +522,24 @@ func cfs_open(id C.int64_t, path *C.char, flags C.int, mode C.mode_t) C.int {
 				auditlog.LogClientOp("Create", dirpath, "nil", err, time.Since(start).Microseconds(), 0, 0)
 			}
 		}()
-		newInfo, err := c.create(dirInfo.Inode, name, fuseMode, absPath)
+		var newInfo *proto.InodeInfo
+		if err = c.checkAccess(dirInfo.Inode, proto.ACLWrite|proto.ACLExecute); err == nil {
+			newInfo, err = c.create(dirInfo.Inode, name, fuseMode, absPath)
+		}
 		if err != nil {
-			if err != syscall.EEXIST {
+			if err != syscall.EEXIST && err != syscall.EACCES {
 				return errorToStatus(err)
 			}
+			// opening the existing file only needs the permissions on the file
+			createErr := err
 			newInfo, err = c.lookupPath(absPath)
 			if err != nil {
+				if createErr == syscall.EACCES {
+					return statusEACCES
+				}
+				return errorToStatus(err)
+			}
+			if err = c.checkAccess(newInfo.Inode, openPermission(accFlags, fuseFlags)); err != nil {
 				return errorToStatus(err)
 			}
 		}
@@ -521,6 +556,9 @@ func cfs_open(id C.int64_t, path *C.char, flags C.int, mode C.mode_t) C.int {
 			return errorToStatus(err)
 		}
 		info = newInfo
+		if err = c.checkAccess(info.Inode, openPermission(accFlags, fuseFlags)); err != nil {
+			return errorToStatus(err)
+		}
 	}
 	var fileCache bool
 	if c.cacheRuleKey == "" {
@@ -582,6 +620,7 @@ func cfs_close(id C.int64_t, fd C.int) {
 	f := c.releaseFD(uint(fd))
 	if f != nil {
 		c.flush(f)
+		c.releaseLocks(f)
 		c.closeStream(f)
 	}
 }
@@ -936,6 +975,10 @@ func cfs_mkdirs(id C.int64_t, path *C.char, mode C.mode_t) C.int {
 		child, _, err := c.mw.Lookup_ll(pino, dir)
 		if err != nil {
 			if err == syscall.ENOENT {
+				if err = c.checkAccess(pino, proto.ACLWrite|proto.ACLExecute); err != nil {
+					gerr = err
+					return errorToStatus(err)
+				}
 				info, err := c.mkdir(pino, dir, uint32(mode), dirpath)
 
 				if err != nil {
@@ -981,6 +1024,9 @@ func cfs_rmdir(id C.int64_t, path *C.char) C.int {
 	if err != nil {
 		return errorToStatus(err)
 	}
+	if err = c.checkAccess(dirInfo.Inode, proto.ACLWrite|proto.ACLExecute); err != nil {
+		return errorToStatus(err)
+	}
 
 	info, err = c.mw.Delete_ll(dirInfo.Inode, name, true, absPath)
 	c.ic.Delete(dirInfo.Inode)
@@ -1021,6 +1067,16 @@ func cfs_unlink(id C.int64_t, path *C.char) C.int {
 	if proto.IsDir(mode) {
 		return statusEISDIR
 	}
+	if err = c.checkAccess(dirInfo.Inode, proto.ACLWrite|proto.ACLExecute); err != nil {
+		return errorToStatus(err)
+	}
+
+	if c.mw.TrashEnabled() && !meta.IsTrashPath(absPath) {
+		err = c.mw.MoveToTrash_ll(dirInfo.Inode, name, absPath)
+		c.ic.Delete(dirInfo.Inode)
+		c.dc.Delete(absPath)
+		return errorToStatus(err)
+	}
 
 	info, err = c.mw.Delete_ll(dirInfo.Inode, name, false, absPath)
 	if err != nil {
@@ -1062,6 +1118,11 @@ func cfs_rename(id C.int64_t, from *C.char, to *C.char) C.int {
 	if err != nil {
 		return errorToStatus(err)
 	}
+	for _, ino := range []uint64{srcDirInfo.Inode, dstDirInfo.Inode} {
+		if err = c.checkAccess(ino, proto.ACLWrite|proto.ACLExecute); err != nil {
+			return errorToStatus(err)
+		}
+	}
 
 	err = c.mw.Rename_ll(srcDirInfo.Inode, srcName, dstDirInfo.Inode, dstName, absFrom, absTo, false)
 	c.ic.Delete(srcDirInfo.Inode)
@@ -1095,6 +1156,127 @@ func cfs_fchmod(id C.int64_t, fd C.int, mode C.mode_t) C.int {
 	return statusOK
 }
 
+// cfs_flock applies or removes the flock(2) lock on the open file, the lock is
+// shared with the clients on other hosts.
+//
+//export cfs_flock
+func cfs_flock(id C.int64_t, fd C.int, op C.int) C.int {
+	c, exist := getClient(int64(id))
+	if !exist {
+		return statusEINVAL
+	}
+
+	f := c.getFile(uint(fd))
+	if f == nil {
+		return statusEBADFD
+	}
+
+	lock := &proto.FileLock{
+		Inode: f.ino,
+		Owner: uint64(f.fd),
+		Kind:  proto.FileLockFlock,
+		Start: 0,
+		End:   proto.FileLockMaxOffset,
+	}
+	switch op &^ C.LOCK_NB {
+	case C.LOCK_SH:
+		lock.Type = proto.FileLockRead
+	case C.LOCK_EX:
+		lock.Type = proto.FileLockWrite
+	case C.LOCK_UN:
+		lock.Type = proto.FileLockUnlock
+	default:
+		return statusEINVAL
+	}
+	return errorToStatus(c.setFileLock(lock, op&C.LOCK_NB == 0))
+}
+
+// cfs_fcntl_lock handles the F_GETLK, F_SETLK and F_SETLKW commands of fcntl(2), the
+// locks are owned by the client like the locks owned by a process.
+//
+//export cfs_fcntl_lock
+func cfs_fcntl_lock(id C.int64_t, fd C.int, cmd C.int, lk *C.struct_flock) C.int {
+	c, exist := getClient(int64(id))
+	if !exist {
+		return statusEINVAL
+	}
+
+	f := c.getFile(uint(fd))
+	if f == nil {
+		return statusEBADFD
+	}
+
+	start := int64(lk.l_start)
+	switch lk.l_whence {
+	case C.SEEK_SET:
+	case C.SEEK_END:
+		info, err := c.mw.InodeGet_ll(f.ino)
+		if err != nil {
+			return errorToStatus(err)
+		}
+		start += int64(info.Size)
+	default:
+		return statusEINVAL
+	}
+	end := int64(proto.FileLockMaxOffset)
+	if length := int64(lk.l_len); length > 0 {
+		end = start + length - 1
+	} else if length < 0 {
+		start, end = start+length, start-1
+	}
+	if start < 0 || end < start {
+		return statusEINVAL
+	}
+
+	lock := &proto.FileLock{
+		Inode: f.ino,
+		Kind:  proto.FileLockPOSIX,
+		Pid:   uint32(os.Getpid()),
+		Start: uint64(start),
+		End:   uint64(end),
+	}
+	switch lk.l_type {
+	case C.F_RDLCK:
+		lock.Type = proto.FileLockRead
+	case C.F_WRLCK:
+		lock.Type = proto.FileLockWrite
+	case C.F_UNLCK:
+		lock.Type = proto.FileLockUnlock
+	default:
+		return statusEINVAL
+	}
+
+	switch cmd {
+	case C.F_GETLK:
+		conflict, err := c.mw.GetFileLock_ll(lock)
+		if err != nil {
+			return errorToStatus(err)
+		}
+		if conflict == nil {
+			lk.l_type = C.F_UNLCK
+			return statusOK
+		}
+		lk.l_type = C.F_RDLCK
+		if conflict.Type == proto.FileLockWrite {
+			lk.l_type = C.F_WRLCK
+		}
+		lk.l_whence = C.SEEK_SET
+		lk.l_start = C.off_t(conflict.Start)
+		lk.l_len = 0
+		if conflict.End != proto.FileLockMaxOffset {
+			lk.l_len = C.off_t(conflict.End - conflict.Start + 1)
+		}
+		lk.l_pid = C.pid_t(conflict.Pid)
+		return statusOK
+	case C.F_SETLK:
+		return errorToStatus(c.setFileLock(lock, false))
+	case C.F_SETLKW:
+		return errorToStatus(c.setFileLock(lock, true))
+	default:
+		return statusEINVAL
+	}
+}
+
 //export cfs_getsummary
 func cfs_getsummary(id C.int64_t, path *C.char, summary *C.struct_cfs_summary_info, useCache *C.char, goroutine_num C.int) C.int {
 	c, exist := getClient(int64(id))
@@ -1198,13 +1380,19 @@ func (c *client) start() (err error) {
 			return
 		}
 	}
-	var mw *meta.MetaWrapper
-	if mw, err = meta.NewMetaWrapper(&meta.MetaConfig{
+	metaConfig := &meta.MetaConfig{
 		Volume:        c.volName,
 		Masters:       masters,
 		ValidateOwner: false,
 		EnableSummary: c.enableSummary,
-	}); err != nil {
+		Owner:         c.owner,
+	}
+	if c.ticketHost != "" {
+		metaConfig.TicketMess.ClientKey = c.clientKey
+		metaConfig.TicketMess.TicketHosts = strings.Split(c.ticketHost, ",")
+	}
+	var mw *meta.MetaWrapper
+	if mw, err = meta.NewMetaWrapper(metaConfig); err != nil {
 		log.LogErrorf("newClient NewMetaWrapper failed(%v)", err)
 		return err
 	}
@@ -1222,6 +1410,9 @@ func (c *client) start() (err error) {
 		OnLoadBcache:      c.bc.Get,
 		OnCacheBcache:     c.bc.Put,
 		OnEvictBcache:     c.bc.Evict,
+		OnLoadFileCipher:  mw.LoadFileCipher,
+		OnDedupExtents:    mw.DedupExtents,
+		OnAddFingerprints: mw.AddFingerprints,
 		DisableMetaCache:  true,
 	}); err != nil {
 		log.LogErrorf("newClient NewExtentClient failed(%v)", err)
@@ -1365,18 +1556,52 @@ func (c *client) setattr(info *proto.InodeInfo, valid uint32, mode, uid, gid uin
 		mode = info.Mode &^ uint32(0o777) // clear rwx mode bit
 		mode |= fuseMode
 	}
-	return c.mw.Setattr(info.Inode, valid, mode, uid, gid, atime, mtime)
+	if err := c.mw.Setattr(info.Inode, valid, mode, uid, gid, atime, mtime); err != nil {
+		return err
+	}
+	if valid&proto.AttrMode != 0 {
+		return c.mw.ChmodPosixACL_ll(info.Inode, mode)
+	}
+	return nil
+}
+
+// checkAccess checks the wanted permissions of the user of the client on the inode by the
+// posix acl and the mode, which is only enforced when posix acl is enabled on the volume.
+func (c *client) checkAccess(ino uint64, want uint16) error {
+	if !c.mw.PosixACLEnabled() {
+		return nil
+	}
+	info, err := c.mw.InodeGet_ll(ino)
+	if err != nil {
+		return err
+	}
+	return c.mw.CheckPermission_ll(info, c.uid, []uint32{c.gid}, want)
+}
+
+func openPermission(accFlags, flags uint32) (want uint16) {
+	switch accFlags {
+	case uint32(C.O_WRONLY):
+		want = proto.ACLWrite
+	case uint32(C.O_RDWR):
+		want = proto.ACLRead | proto.ACLWrite
+	default:
+		want = proto.ACLRead
+	}
+	if flags&uint32(C.O_TRUNC) != 0 {
+		want |= proto.ACLWrite
+	}
+	return
 }
 
 func (c *client) create(pino uint64, name string, mode uint32, fullPath string) (info *proto.InodeInfo, err error) {
 	fuseMode := mode & 0o777
-	return c.mw.Create_ll(pino, name, fuseMode, 0, 0, nil, fullPath)
+	return c.mw.Create_ll(pino, name, fuseMode, c.uid, c.gid, nil, fullPath)
 }
 
 func (c *client) mkdir(pino uint64, name string, mode uint32, fullPath string) (info *proto.InodeInfo, err error) {
 	fuseMode := mode & 0o777
 	fuseMode |= uint32(os.ModeDir)
-	return c.mw.Create_ll(pino, name, fuseMode, 0, 0, nil, fullPath)
+	return c.mw.Create_ll(pino, name, fuseMode, c.uid, c.gid, nil, fullPath)
 }
 
 func (c *client) openStream(f *file) {
@@ -1402,6 +1627,31 @@ func (c *client) flush(f *file) error {
 	return nil
 }
 
+func (c *client) setFileLock(lock *proto.FileLock, wait bool) error {
+	interval := fs.LockWaitMinInterval
+	for {
+		_, err := c.mw.SetFileLock_ll(lock)
+		if err != syscall.EAGAIN || !wait {
+			return err
+		}
+		time.Sleep(interval)
+		if interval *= 2; interval > fs.LockWaitMaxInterval {
+			interval = fs.LockWaitMaxInterval
+		}
+	}
+}
+
+// releaseLocks releases the flock lock of the file and the fcntl locks of the client on the inode,
+// as closing any descriptor of the file releases the fcntl locks of the process.
+func (c *client) releaseLocks(f *file) {
+	if err := c.mw.ReleaseFileLocks_ll(f.ino, uint64(f.fd), proto.FileLockFlock); err != nil {
+		log.LogWarnf("releaseLocks: release flock failed, ino(%v) fd(%v) err(%v)", f.ino, f.fd, err)
+	}
+	if err := c.mw.ReleaseFileLocks_ll(f.ino, 0, proto.FileLockPOSIX); err != nil {
+		log.LogWarnf("releaseLocks: release fcntl locks failed, ino(%v) err(%v)", f.ino, err)
+	}
+}
+
 func (c *client) truncate(f *file, size int) error {
 	err := c.ec.Truncate(c.mw, f.pino, f.ino, size, f.path)
 	if err != nil {
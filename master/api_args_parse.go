@@ -28,7 +28,9 @@ import (
 
 	"github.com/cubefs/cubefs/proto"
 	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/compressutil"
 	"github.com/cubefs/cubefs/util/cryptoutil"
+	"github.com/cubefs/cubefs/util/ecstripe"
 	"github.com/cubefs/cubefs/util/log"
 )
 
@@ -384,9 +386,14 @@ type updateVolReq struct {
 	authKey                 string
 	capacity                uint64
 	deleteLockTime          int64
+	trashInterval           int64
 	followerRead            bool
 	authenticate            bool
 	enablePosixAcl          bool
+	enableEncryption        bool
+	compression             string
+	enableDedup             bool
+	enableCDC               bool
 	enableTransaction       proto.TxOpMask
 	txTimeout               int64
 	txConflictRetryNum      int64
@@ -485,10 +492,68 @@ func parseVolUpdateReq(r *http.Request, vol *Vol, req *updateVolReq) (err error)
 		return
 	}
 
+	if req.trashInterval, err = extractInt64WithDefault(r, volTrashIntervalKey, vol.TrashInterval); err != nil {
+		return
+	}
+	if req.trashInterval < 0 {
+		err = fmt.Errorf("invalid %v %v, should not be negative", volTrashIntervalKey, req.trashInterval)
+		return
+	}
+
 	if req.enablePosixAcl, err = extractBoolWithDefault(r, enablePosixAclKey, vol.enablePosixAcl); err != nil {
 		return
 	}
 
+	// the files encrypted could not be read once encryption is disabled
+	if req.enableEncryption, err = extractBoolWithDefault(r, enableEncryptionKey, vol.EnableEncryption); err != nil {
+		return
+	}
+	if vol.EnableEncryption && !req.enableEncryption {
+		err = fmt.Errorf("%v could not be disabled once enabled", enableEncryptionKey)
+		return
+	}
+	if req.enableEncryption && !proto.IsHot(vol.VolType) {
+		err = fmt.Errorf("%v is only supported by the hot volumes", enableEncryptionKey)
+		return
+	}
+
+	// the data compressed before keeps the codec when the compression is changed
+	req.compression = vol.Compression
+	if value := r.FormValue(compressionKey); value != "" {
+		var codec uint8
+		if codec, err = compressutil.Parse(value); err != nil {
+			return
+		}
+		req.compression = ""
+		if codec != compressutil.None {
+			if !proto.IsHot(vol.VolType) {
+				err = fmt.Errorf("%v is only supported by the hot volumes", compressionKey)
+				return
+			}
+			if vol.ECDataNum > 0 {
+				err = fmt.Errorf("%v is not supported by the erasure coded volumes", compressionKey)
+				return
+			}
+			req.compression = compressutil.Name(codec)
+		}
+	}
+
+	if req.enableDedup, err = extractBoolWithDefault(r, enableDedupKey, vol.EnableDedup); err != nil {
+		return
+	}
+	if req.enableDedup && !proto.IsHot(vol.VolType) {
+		err = fmt.Errorf("%v is only supported by the hot volumes", enableDedupKey)
+		return
+	}
+	if req.enableDedup && vol.ECDataNum > 0 {
+		err = fmt.Errorf("%v is not supported by the erasure coded volumes", enableDedupKey)
+		return
+	}
+
+	if req.enableCDC, err = extractBoolWithDefault(r, enableCDCKey, vol.EnableCDC); err != nil {
+		return
+	}
+
 	var txMask proto.TxOpMask
 	if txMask, err = parseTxMask(r, vol.enableTransaction); err != nil {
 		return
@@ -705,6 +770,9 @@ type createVolReq struct {
 	// cold vol args
 	coldArgs  coldVolArgs
 	storeMode proto.StoreMode
+	// the data partitions are erasure coded if ecDataNum is not 0
+	ecDataNum   uint8
+	ecParityNum uint8
 }
 
 func checkCacheAction(action int) error {
@@ -886,6 +954,20 @@ func parseRequestToCreateVol(r *http.Request, req *createVolReq) (err error) {
 		}
 	}
 	req.storeMode = proto.StoreMode(storeMode)
+
+	var ecDataNum, ecParityNum int
+	if ecDataNum, err = extractUint(r, ecDataNumKey); err != nil {
+		return
+	}
+	if ecParityNum, err = extractUint(r, ecParityNumKey); err != nil {
+		return
+	}
+	if ecDataNum != 0 || ecParityNum != 0 {
+		if err = ecstripe.Check(ecDataNum, ecParityNum); err != nil {
+			return
+		}
+		req.ecDataNum, req.ecParityNum = uint8(ecDataNum), uint8(ecParityNum)
+	}
 	return
 }
 
@@ -1056,6 +1138,46 @@ func parseRequestToLoadMetaPartition(r *http.Request) (partitionID uint64, err e
 	return
 }
 
+func parseRequestToMigrateMetaRange(r *http.Request) (partitionID, start, end, targetID uint64, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if partitionID, err = extractMetaPartitionID(r); err != nil {
+		return
+	}
+	if r.FormValue(startKey) == "" {
+		err = keyNotFound(startKey)
+		return
+	}
+	if start, err = extractUint64(r, startKey); err != nil {
+		return
+	}
+	if end, err = extractUint64(r, endKey); err != nil {
+		return
+	}
+	targetID, err = extractUint64(r, targetKey)
+	return
+}
+
+// parseRequestToDataBalance overrides the config of the data balancer by the params given.
+func parseRequestToDataBalance(r *http.Request, cfg *proto.DataBalanceConfig) (err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if value := r.FormValue(thresholdKey); value != "" {
+		if cfg.Threshold, err = strconv.ParseFloat(value, 64); err != nil {
+			return
+		}
+	}
+	if cfg.Concurrency, err = extractUintWithDefault(r, concurrencyKey, cfg.Concurrency); err != nil {
+		return
+	}
+	if cfg.BandwidthMB, err = extractUint64WithDefault(r, bandwidthKey, cfg.BandwidthMB); err != nil {
+		return
+	}
+	return cfg.Validate()
+}
+
 func parseRequestToDecommissionMetaPartition(r *http.Request) (partitionID uint64, nodeAddr string, err error) {
 	return extractMetaPartitionIDAndAddr(r)
 }
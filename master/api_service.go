@@ -22,6 +22,7 @@ import (
 	"math"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -2057,6 +2058,10 @@ func (m *Server) checkReplicaNum(r *http.Request, vol *Vol, req *updateVolReq) (
 			err = fmt.Errorf("vol type(%v) replicaNum cann't be changed", vol.VolType)
 			return
 		}
+		if vol.ECDataNum > 0 {
+			err = fmt.Errorf("replicaNum of the erasure coded vol cann't be changed")
+			return
+		}
 		if ok, dpArry := vol.isOkUpdateRepCnt(); !ok {
 			err = fmt.Errorf("vol have dataPartitions[%v] with inconsistent dataPartitions cnt to volume's ", dpArry)
 			return
@@ -2117,11 +2122,16 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 	newArgs.description = req.description
 	newArgs.capacity = req.capacity
 	newArgs.deleteLockTime = req.deleteLockTime
+	newArgs.trashInterval = req.trashInterval
 	newArgs.followerRead = req.followerRead
 	newArgs.authenticate = req.authenticate
 	newArgs.dpSelectorName = req.dpSelectorName
 	newArgs.dpSelectorParm = req.dpSelectorParm
 	newArgs.enablePosixAcl = req.enablePosixAcl
+	newArgs.enableEncryption = req.enableEncryption
+	newArgs.compression = req.compression
+	newArgs.enableDedup = req.enableDedup
+	newArgs.enableCDC = req.enableCDC
 	newArgs.enableTransaction = req.enableTransaction
 	newArgs.txTimeout = req.txTimeout
 	newArgs.txConflictRetryNum = req.txConflictRetryNum
@@ -2256,7 +2266,21 @@ func (m *Server) checkCreateReq(req *createVolReq) (err error) {
 		return fmt.Errorf("dpCount[%d] exceeds maximum limit[%d]", req.dpCount, maxInitDataPartitionCnt)
 	}
 
+	if req.ecDataNum > 0 && !proto.IsHot(req.volType) {
+		return fmt.Errorf("erasure coded data partitions are only supported by the hot volumes")
+	}
+
 	if proto.IsHot(req.volType) {
+		// every host of the erasure coded partition keeps a shard
+		if req.ecDataNum > 0 {
+			replicaNum := req.ecDataNum + req.ecParityNum
+			if req.dpReplicaNum != 0 && req.dpReplicaNum != replicaNum {
+				return fmt.Errorf("replicaNum[%v] of the erasure coded vol should be %v", req.dpReplicaNum, replicaNum)
+			}
+			req.dpReplicaNum = replicaNum
+			return nil
+		}
+
 		if req.dpReplicaNum == 0 {
 			req.dpReplicaNum = defaultReplicaNum
 		}
@@ -2497,6 +2521,13 @@ func newSimpleView(vol *Vol) (view *proto.SimpleVolView) {
 		DpCnt:                   len(vol.dataPartitions.partitionMap),
 		CreateTime:              time.Unix(vol.createTime, 0).Format(proto.TimeFormat),
 		DeleteLockTime:          vol.DeleteLockTime,
+		TrashInterval:           vol.TrashInterval,
+		EnableEncryption:        vol.EnableEncryption,
+		Compression:             vol.Compression,
+		EnableDedup:             vol.EnableDedup,
+		EnableCDC:               vol.EnableCDC,
+		ECDataNum:               vol.ECDataNum,
+		ECParityNum:             vol.ECParityNum,
 		Description:             vol.description,
 		DpSelectorName:          vol.dpSelectorName,
 		DpSelectorParm:          vol.dpSelectorParm,
@@ -4317,6 +4348,44 @@ func (m *Server) loadMetaPartition(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(msg))
 }
 
+func (m *Server) migrateMetaRange(w http.ResponseWriter, r *http.Request) {
+	var (
+		msg         string
+		vol         *Vol
+		mp          *MetaPartition
+		target      *MetaPartition
+		partitionID uint64
+		start       uint64
+		end         uint64
+		targetID    uint64
+		err         error
+	)
+	metric := exporter.NewTPCnt(apiToMetricsName(proto.AdminMigrateMetaRange))
+	defer func() {
+		doStatAndMetric(proto.AdminMigrateMetaRange, metric, err, nil)
+	}()
+
+	if partitionID, start, end, targetID, err = parseRequestToMigrateMetaRange(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if mp, err = m.cluster.getMetaPartitionByID(partitionID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrMetaPartitionNotExists))
+		return
+	}
+	if vol, err = m.cluster.getVol(mp.volName); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrVolNotExists))
+		return
+	}
+	if target, err = m.cluster.migrateMetaRange(vol, mp, start, end, targetID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg = fmt.Sprintf(proto.AdminMigrateMetaRange+" partitionID :%v range from %v is migrating to partitionID :%v",
+		partitionID, start, target.PartitionID)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
 func (m *Server) migrateMetaNodeHandler(w http.ResponseWriter, r *http.Request) {
 	var (
 		srcAddr    string
@@ -6149,6 +6218,157 @@ func (m *Server) DelBucketLifecycle(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(msg))
 }
 
+func (m *Server) setReplication(w http.ResponseWriter, r *http.Request) {
+	var (
+		err         error
+		name        string
+		interval    uint64
+		replication *proto.VolReplication
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if interval, err = extractUint64(r, intervalKey); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if _, err = m.cluster.getVol(name); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrVolNotExists))
+		return
+	}
+	if replication, err = m.cluster.setReplication(name, extractStr(r, dstAddrKey), extractStr(r, dstVolKey), int64(interval)); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(newVolReplicationView(replication, time.Now())))
+}
+
+func (m *Server) getReplication(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		name string
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	replication := m.cluster.replicationMgr.get(name)
+	if replication == nil {
+		sendErrReply(w, r, newErrHTTPReply(fmt.Errorf("the replication of vol %v is not found", name)))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(newVolReplicationView(replication, time.Now())))
+}
+
+func (m *Server) listReplications(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	views := make([]*proto.VolReplicationView, 0)
+	for _, replication := range m.cluster.replicationMgr.list() {
+		views = append(views, newVolReplicationView(replication, now))
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].SrcVol < views[j].SrcVol })
+	sendOkReply(w, r, newSuccessHTTPReply(views))
+}
+
+func (m *Server) pauseReplication(w http.ResponseWriter, r *http.Request) {
+	m.setReplicationStatus(w, r, proto.ReplicationPaused)
+}
+
+func (m *Server) resumeReplication(w http.ResponseWriter, r *http.Request) {
+	m.setReplicationStatus(w, r, proto.ReplicationActive)
+}
+
+// failoverReplication stops the replication for good, so the target volume can be written by the
+// clients. The paths changed after the snapshot replicated last time are not replicated.
+func (m *Server) failoverReplication(w http.ResponseWriter, r *http.Request) {
+	m.setReplicationStatus(w, r, proto.ReplicationFailedOver)
+}
+
+func (m *Server) setReplicationStatus(w http.ResponseWriter, r *http.Request, status uint8) {
+	var (
+		err         error
+		name        string
+		replication *proto.VolReplication
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if replication, err = m.cluster.setReplicationStatus(name, status); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(newVolReplicationView(replication, time.Now())))
+}
+
+func (m *Server) deleteReplication(w http.ResponseWriter, r *http.Request) {
+	var (
+		err         error
+		name        string
+		replication *proto.VolReplication
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if replication, err = m.cluster.deleteReplication(name); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("delete the replication of vol[%v] successfully", name)
+	if replication.LastVerSeq != 0 {
+		msg += fmt.Sprintf(", the snapshot[%v] replicated last time is kept", replication.LastVerSeq)
+	}
+	log.LogWarn(msg)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+// getDataBalancePlan returns the migrations the data balancer would start, nothing is migrated.
+func (m *Server) getDataBalancePlan(w http.ResponseWriter, r *http.Request) {
+	cfg := m.cluster.dataBalancer.getConfig()
+	if err := parseRequestToDataBalance(r, cfg); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getDataBalancePlan(cfg)))
+}
+
+func (m *Server) startDataBalance(w http.ResponseWriter, r *http.Request) {
+	cfg := m.cluster.dataBalancer.getConfig()
+	if err := parseRequestToDataBalance(r, cfg); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	cfg.Enable = true
+	if err := m.cluster.setDataBalanceConfig(cfg); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("start data balance successfully, threshold[%v] concurrency[%v] bandwidth[%vMB/s]",
+		cfg.Threshold, cfg.Concurrency, cfg.BandwidthMB)
+	log.LogWarn(msg)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+// stopDataBalance stops starting new migrations, the ones running are not interrupted.
+func (m *Server) stopDataBalance(w http.ResponseWriter, r *http.Request) {
+	cfg := m.cluster.dataBalancer.getConfig()
+	cfg.Enable = false
+	if err := m.cluster.setDataBalanceConfig(cfg); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("stop data balance successfully, %v migrations running are not interrupted",
+		m.cluster.dataBalancer.movingCount())
+	log.LogWarn(msg)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) getDataBalanceStatus(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getDataBalanceStatus()))
+}
+
 func (m *Server) lcnodeInfo(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
@@ -487,6 +487,7 @@ func TestUpdateVol(t *testing.T) {
 	low := 40
 	lru := 6
 	rule := "test"
+	trashInterval := 60
 
 	checkParam(volCapacityKey, proto.AdminUpdateVol, req, "tt", cap, t)
 	setParam(descriptionKey, proto.AdminUpdateVol, req, desc, t)
@@ -505,6 +506,13 @@ func TestUpdateVol(t *testing.T) {
 	checkParam(cacheLowWaterKey, proto.AdminUpdateVol, req, 78, low, t)
 	checkParam(cacheLowWaterKey, proto.AdminUpdateVol, req, 93, low, t)
 	checkParam(cacheLRUIntervalKey, proto.AdminUpdateVol, req, -1, lru, t)
+	checkParam(volTrashIntervalKey, proto.AdminUpdateVol, req, -1, trashInterval, t)
+	// the files of cold volumes are not encrypted
+	checkParam(enableEncryptionKey, proto.AdminUpdateVol, req, true, false, t)
+	checkParam(compressionKey, proto.AdminUpdateVol, req, "gzip", "none", t)
+	checkParam(compressionKey, proto.AdminUpdateVol, req, "zstd", "none", t)
+	checkParam(enableDedupKey, proto.AdminUpdateVol, req, true, false, t)
+	checkParam(enableCDCKey, proto.AdminUpdateVol, req, "tt", true, t)
 	setParam(cacheRuleKey, proto.AdminUpdateVol, req, rule, t)
 
 	view = getSimpleVol(volName, true, t)
@@ -524,6 +532,11 @@ func TestUpdateVol(t *testing.T) {
 	assert.True(t, view.CacheLowWater == low)
 	assert.True(t, view.CacheLruInterval == lru)
 	assert.True(t, view.CacheRule == rule)
+	assert.True(t, view.TrashInterval == int64(trashInterval))
+	assert.False(t, view.EnableEncryption)
+	assert.Empty(t, view.Compression)
+	assert.False(t, view.EnableDedup)
+	assert.True(t, view.EnableCDC)
 
 	// update cacheRule to empty
 	setUpdateVolParm(emptyCacheRuleKey, req, true, t)
@@ -682,6 +695,23 @@ func TestGetTopo(t *testing.T) {
 	process(reqURL, t)
 }
 
+func TestDataBalance(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v?threshold=0.2", hostAddr, proto.AdminDataBalancePlan)
+	process(reqURL, t)
+	reqURL = fmt.Sprintf("%v%v?threshold=0.2&concurrency=2&bandwidth=50", hostAddr, proto.AdminDataBalanceStart)
+	process(reqURL, t)
+	cfg := server.cluster.dataBalancer.getConfig()
+	assert.True(t, cfg.Enable)
+	assert.Equal(t, 0.2, cfg.Threshold)
+	assert.Equal(t, 2, cfg.Concurrency)
+	assert.EqualValues(t, 50, cfg.BandwidthMB)
+	reqURL = fmt.Sprintf("%v%v", hostAddr, proto.AdminDataBalanceStop)
+	process(reqURL, t)
+	assert.False(t, server.cluster.dataBalancer.getConfig().Enable)
+	reqURL = fmt.Sprintf("%v%v", hostAddr, proto.AdminDataBalanceStatus)
+	process(reqURL, t)
+}
+
 func TestGetMetaNode(t *testing.T) {
 	reqURL := fmt.Sprintf("%v%v?addr=%v", hostAddr, proto.GetMetaNode, mms1Addr)
 	process(reqURL, t)
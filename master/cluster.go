@@ -97,6 +97,9 @@ type Cluster struct {
 	lcNodes                      sync.Map
 	lcMgr                        *lifecycleManager
 	snapshotMgr                  *snapshotDelManager
+	trashMgr                     *trashCleanManager
+	replicationMgr               *replicationManager
+	dataBalancer                 *dataBalancer
 	DecommissionDiskFactor       float64
 	S3ApiQosQuota                *sync.Map // (api,uid,limtType) -> limitQuota
 }
@@ -336,6 +339,11 @@ func newCluster(name string, leaderInfo *LeaderInfo, fsm *MetadataFsm, partition
 	c.lcMgr.cluster = c
 	c.snapshotMgr = newSnapshotManager()
 	c.snapshotMgr.cluster = c
+	c.trashMgr = newTrashCleanManager()
+	c.trashMgr.cluster = c
+	c.replicationMgr = newReplicationManager()
+	c.replicationMgr.cluster = c
+	c.dataBalancer = newDataBalancer()
 	c.S3ApiQosQuota = new(sync.Map)
 	return
 }
@@ -361,6 +369,9 @@ func (c *Cluster) scheduleTask() {
 	c.scheduleToCheckDataReplicas()
 	c.scheduleToLcScan()
 	c.scheduleToSnapshotDelVerScan()
+	c.scheduleToTrashClean()
+	c.scheduleToReplicate()
+	c.scheduleToBalanceData()
 	c.scheduleToBadDisk()
 }
 
@@ -1493,6 +1504,12 @@ func (c *Cluster) createDataPartition(volName string, preload *DataPartitionPreL
 	dp = newDataPartition(partitionID, dpReplicaNum, volName, vol.ID, proto.GetDpType(vol.VolType, isPreload), partitionTTL)
 	dp.Hosts = targetHosts
 	dp.Peers = targetPeers
+	if vol.ECDataNum > 0 && !isPreload {
+		dp.ECDataNum = vol.ECDataNum
+		dp.ECParityNum = vol.ECParityNum
+		dp.ECHosts = make([]string, len(targetHosts))
+		copy(dp.ECHosts, targetHosts)
+	}
 
 	log.LogInfof("action[createDataPartition] partitionID [%v] get host [%v]", partitionID, targetHosts)
 
@@ -3295,6 +3312,8 @@ func (c *Cluster) doCreateVol(req *createVolReq) (vol *Vol, err error) {
 
 		DpReadOnlyWhenVolFull: req.DpReadOnlyWhenVolFull,
 		DefaultStoreMode:      req.storeMode,
+		ECDataNum:             req.ecDataNum,
+		ECParityNum:           req.ecParityNum,
 	}
 
 	log.LogInfof("[doCreateVol] volView, %v", vv)
@@ -4357,6 +4376,14 @@ func (c *Cluster) addLcNode(nodeAddr string) (id uint64, err error) {
 	c.snapshotMgr.lcNodeStatus.Lock()
 	c.snapshotMgr.lcNodeStatus.WorkingCount[nodeAddr] = 0
 	c.snapshotMgr.lcNodeStatus.Unlock()
+
+	c.trashMgr.lcNodeStatus.Lock()
+	c.trashMgr.lcNodeStatus.WorkingCount[nodeAddr] = 0
+	c.trashMgr.lcNodeStatus.Unlock()
+
+	c.replicationMgr.lcNodeStatus.Lock()
+	c.replicationMgr.lcNodeStatus.WorkingCount[nodeAddr] = 0
+	c.replicationMgr.lcNodeStatus.Unlock()
 	log.LogInfof("action[addLcNode], clusterID[%v], lcNodeAddr: %v, id: %v, add idleNodes", c.Name, nodeAddr, ln.ID)
 	return ln.ID, nil
 
@@ -4372,12 +4399,16 @@ type LcNodeStatInfo struct {
 }
 
 type LcNodeInfoResponse struct {
-	RegisterInfos      []*LcNodeStatInfo
-	LcConfigurations   map[string]*proto.LcConfiguration
-	LcRuleTaskStatus   *lcRuleTaskStatus
-	LcNodeStatus       *lcNodeStatus
-	SnapshotVerStatus  *lcSnapshotVerStatus
-	SnapshotNodeStatus *lcNodeStatus
+	RegisterInfos         []*LcNodeStatInfo
+	LcConfigurations      map[string]*proto.LcConfiguration
+	LcRuleTaskStatus      *lcRuleTaskStatus
+	LcNodeStatus          *lcNodeStatus
+	SnapshotVerStatus     *lcSnapshotVerStatus
+	SnapshotNodeStatus    *lcNodeStatus
+	TrashTaskStatus       *lcTrashTaskStatus
+	TrashNodeStatus       *lcNodeStatus
+	ReplicationTaskStatus *lcReplicationTaskStatus
+	ReplicationNodeStatus *lcNodeStatus
 }
 
 func (c *Cluster) getAllLcNodeInfo() (rsp *LcNodeInfoResponse, err error) {
@@ -4395,6 +4426,10 @@ func (c *Cluster) getAllLcNodeInfo() (rsp *LcNodeInfoResponse, err error) {
 	rsp.LcNodeStatus = c.lcMgr.lcNodeStatus
 	rsp.SnapshotVerStatus = c.snapshotMgr.lcSnapshotTaskStatus
 	rsp.SnapshotNodeStatus = c.snapshotMgr.lcNodeStatus
+	rsp.TrashTaskStatus = c.trashMgr.lcTrashTaskStatus
+	rsp.TrashNodeStatus = c.trashMgr.lcNodeStatus
+	rsp.ReplicationTaskStatus = c.replicationMgr.lcReplicationTaskStatus
+	rsp.ReplicationNodeStatus = c.replicationMgr.lcNodeStatus
 	return
 }
 
@@ -4407,9 +4442,17 @@ func (c *Cluster) clearLcNodes() {
 	})
 }
 
+func (c *Cluster) clearReplications() {
+	c.replicationMgr.Lock()
+	defer c.replicationMgr.Unlock()
+	c.replicationMgr.replications = make(map[string]*proto.VolReplication)
+}
+
 func (c *Cluster) delLcNode(nodeAddr string) (err error) {
 	c.lcMgr.lcNodeStatus.RemoveNode(nodeAddr)
 	c.snapshotMgr.lcNodeStatus.RemoveNode(nodeAddr)
+	c.trashMgr.lcNodeStatus.RemoveNode(nodeAddr)
+	c.replicationMgr.lcNodeStatus.RemoveNode(nodeAddr)
 
 	lcNode, err := c.lcNode(nodeAddr)
 	if err != nil {
@@ -4497,6 +4540,50 @@ func (c *Cluster) getSnapshotDelVer() {
 	log.LogDebug("getSnapshotDelVer DeleteOldResult finish")
 }
 
+func (c *Cluster) scheduleToTrashClean() {
+	go c.trashMgr.process()
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.getTrashCleanTasks()
+			}
+			time.Sleep(time.Second * defaultIntervalToCleanTrash)
+		}
+	}()
+}
+
+// getTrashCleanTasks adds a task for every volume with trash enabled, the task of a volume
+// is not added again until the last one is done.
+func (c *Cluster) getTrashCleanTasks() {
+	for _, task := range planTrashCleanTasks(c.allVols()) {
+		c.trashMgr.lcTrashTaskStatus.AddTask(task)
+	}
+	c.trashMgr.lcTrashTaskStatus.DeleteOldResult()
+	log.LogDebug("getTrashCleanTasks finish")
+}
+
+func (c *Cluster) scheduleToReplicate() {
+	go c.replicationMgr.process()
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.getReplicationTasks()
+			}
+			time.Sleep(time.Second * defaultIntervalToCheckReplication)
+		}
+	}()
+}
+
+// getReplicationTasks adds a task for every active replication whose interval has elapsed since
+// the last round succeeded, the task of a replication is not added again while a round is running.
+func (c *Cluster) getReplicationTasks() {
+	for _, task := range planReplicationTasks(c.replicationMgr.list(), c.allVols(), time.Now()) {
+		c.replicationMgr.lcReplicationTaskStatus.AddTask(task)
+	}
+	c.replicationMgr.lcReplicationTaskStatus.DeleteOldResult()
+	log.LogDebug("getReplicationTasks finish")
+}
+
 func (c *Cluster) SetBucketLifecycle(req *proto.LcConfiguration) error {
 	lcConf := &proto.LcConfiguration{
 		VolName: req.VolName,
@@ -4540,6 +4627,104 @@ func (c *Cluster) DelBucketLifecycle(VolName string) {
 	return
 }
 
+// setReplication creates the replication of the volume, or changes the interval of the existing one.
+// The target of an existing replication can not be changed, since the target is replicated from the
+// snapshot replicated last time.
+func (c *Cluster) setReplication(srcVol, dstMasters, dstVol string, interval int64) (r *proto.VolReplication, err error) {
+	vol, err := c.getVol(srcVol)
+	if err != nil {
+		return
+	}
+	if !proto.IsHot(vol.VolType) {
+		err = fmt.Errorf("vol %v is not a hot volume, the snapshots are not supported", srcVol)
+		return
+	}
+
+	c.replicationMgr.Lock()
+	defer c.replicationMgr.Unlock()
+	replication, op, err := newReplicationConfig(c.replicationMgr.replications[srcVol], srcVol, dstMasters, dstVol, interval)
+	if err != nil {
+		return
+	}
+	if err = c.syncPutReplication(op, replication); err != nil {
+		log.LogErrorf("action[setReplication] vol(%v) err(%v)", srcVol, err)
+		return
+	}
+	c.replicationMgr.replications[srcVol] = replication
+	log.LogInfof("action[setReplication],clusterID[%v] replication:%+v", c.Name, *replication)
+	r = replication
+	return
+}
+
+// updateReplication applies fn to the replication and persists it.
+func (c *Cluster) updateReplication(srcVol string, fn func(r *proto.VolReplication) error) (r *proto.VolReplication, err error) {
+	c.replicationMgr.Lock()
+	defer c.replicationMgr.Unlock()
+	old, ok := c.replicationMgr.replications[srcVol]
+	if !ok {
+		err = fmt.Errorf("the replication of vol %v is not found", srcVol)
+		return
+	}
+	replication := *old
+	if err = fn(&replication); err != nil {
+		return
+	}
+	if err = c.syncPutReplication(opSyncUpdateReplication, &replication); err != nil {
+		log.LogErrorf("action[updateReplication] vol(%v) err(%v)", srcVol, err)
+		return
+	}
+	c.replicationMgr.replications[srcVol] = &replication
+	r = &replication
+	return
+}
+
+func (c *Cluster) setReplicationStatus(srcVol string, status uint8) (r *proto.VolReplication, err error) {
+	r, err = c.updateReplication(srcVol, func(r *proto.VolReplication) error {
+		switch {
+		case r.Status == proto.ReplicationFailedOver:
+			return fmt.Errorf("the replication of vol %v is failed over, delete it and set up the replication from the target", srcVol)
+		case status == proto.ReplicationFailedOver:
+			r.FailoverTime = time.Now().Unix()
+		}
+		r.Status = status
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	if status != proto.ReplicationActive {
+		// the round running is finished, but no more rounds are dispatched
+		c.replicationMgr.lcReplicationTaskStatus.DeleteTask(srcVol)
+	}
+	log.LogInfof("action[setReplicationStatus],clusterID[%v] vol:%v status:%v", c.Name, srcVol, proto.ReplicationStatusString(status))
+	return
+}
+
+func (c *Cluster) deleteReplication(srcVol string) (r *proto.VolReplication, err error) {
+	c.replicationMgr.Lock()
+	defer c.replicationMgr.Unlock()
+	r, ok := c.replicationMgr.replications[srcVol]
+	if !ok {
+		err = fmt.Errorf("the replication of vol %v is not found", srcVol)
+		return
+	}
+	if err = c.syncPutReplication(opSyncDeleteReplication, r); err != nil {
+		log.LogErrorf("action[deleteReplication] vol(%v) err(%v)", srcVol, err)
+		return
+	}
+	delete(c.replicationMgr.replications, srcVol)
+	c.replicationMgr.lcReplicationTaskStatus.DeleteTask(srcVol)
+	log.LogInfof("action[deleteReplication],clusterID[%v] vol:%v", c.Name, srcVol)
+	return
+}
+
+func newVolReplicationView(r *proto.VolReplication, now time.Time) *proto.VolReplicationView {
+	return &proto.VolReplicationView{
+		VolReplication: *r,
+		Lag:            int64(r.Lag(now) / time.Second),
+	}
+}
+
 func (c *Cluster) addDecommissionDiskToNodeset(dd *DecommissionDisk) (err error) {
 	var (
 		node *DataNode
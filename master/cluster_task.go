@@ -673,6 +673,10 @@ func (c *Cluster) doLoadMetaPartition(mp *MetaPartition) {
 
 func (c *Cluster) doLoadDataPartition(dp *DataPartition) {
 	log.LogInfo(fmt.Sprintf("action[doLoadDataPartition],partitionID:%v", dp.PartitionID))
+	if dp.isErasureCoded() {
+		c.doLoadECDataPartition(dp)
+		return
+	}
 	if !dp.needsToCompareCRC() {
 		log.LogInfo(fmt.Sprintf("action[doLoadDataPartition],partitionID:%v isRecover[%v] don't need compare", dp.PartitionID, dp.isRecover))
 		return
@@ -702,6 +706,30 @@ func (c *Cluster) doLoadDataPartition(dp *DataPartition) {
 	dp.setToNormal()
 }
 
+// doLoadECDataPartition loads the shards of the erasure coded partition and repairs the ones missing
+// or smaller than the others, the partition recovering is checked as well, since the shards of the
+// host added are rebuilt by the repair.
+func (c *Cluster) doLoadECDataPartition(dp *DataPartition) {
+	dp.releaseDataPartition()
+	loadTasks := dp.createLoadTasks()
+	c.addDataNodeTasks(loadTasks)
+	success := false
+	for i := 0; i < timeToWaitForResponse; i++ {
+		if dp.checkLoadResponse(c.cfg.DataPartitionTimeOutSec) {
+			success = true
+			break
+		}
+		time.Sleep(time.Second)
+	}
+
+	if !success {
+		return
+	}
+
+	dp.getFileCount()
+	dp.checkECShards(c)
+}
+
 func (c *Cluster) handleMetaNodeTaskResponse(nodeAddr string, task *proto.AdminTask) (err error) {
 	if task == nil {
 		return
@@ -730,6 +758,9 @@ func (c *Cluster) handleMetaNodeTaskResponse(nodeAddr string, task *proto.AdminT
 	case proto.OpVersionOperation:
 		response := task.Response.(*proto.MultiVersionOpResponse)
 		err = c.dealOpMetaNodeMultiVerResp(task.OperatorAddr, response)
+	case proto.OpMigrateMetaRange:
+		response := task.Response.(*proto.MigrateMetaRangeResponse)
+		err = c.dealMigrateMetaRangeResp(task.OperatorAddr, response)
 	default:
 		err := fmt.Errorf("unknown operate code %v", task.OpCode)
 		log.LogError(err)
@@ -1174,7 +1205,7 @@ func (c *Cluster) updateInodeIDUpperBound(mp *MetaPartition, mr *proto.MetaParti
 	}
 
 	maxPartitionID := vol.maxPartitionID()
-	if mr.PartitionID < maxPartitionID {
+	if mr.PartitionID != maxPartitionID {
 		return
 	}
 	var end uint64
@@ -46,6 +46,7 @@ const (
 	cfgDomainBuildAsPossible            = "faultDomainBuildAsPossible"
 	cfgmetaPartitionInodeIdStep         = "metaPartitionInodeIdStep"
 	cfgMaxQuotaNumPerVol                = "maxQuotaNumPerVol"
+	cfgMetaPartitionMigrateThreshold    = "metaPartitionMigrateThreshold"
 	disableAutoCreate                   = "disableAutoCreate"
 	cfgMonitorPushAddr                  = "monitorPushAddr"
 	intervalToScanS3Expiration          = "intervalToScanS3Expiration"
@@ -60,6 +61,12 @@ const (
 	defaultSecondsToFreeDataPartitionAfterLoad = 5 * 60 // a data partition can only be freed after loading 5 mins
 	defaultIntervalToFreeDataPartition         = 10     // in terms of seconds
 	defaultIntervalToCheck                     = 60
+	defaultIntervalToCleanTrash                = 600
+	defaultIntervalToCheckReplication          = 60
+	defaultIntervalToBalanceData               = 60
+	defaultDataBalanceMoveTimeout              = 24 * 3600 // in terms of seconds
+	defaultDataBalanceFinishedCount            = 100
+	defaultDataBalancePlanMoves                = 1000
 	defaultIntervalToCheckHeartbeat            = 6
 	defaultIntervalToCheckDataPartition        = 5
 	defaultIntervalToCheckQos                  = 1
@@ -137,6 +144,7 @@ type clusterConfig struct {
 	QosMasterAcceptLimit                uint64
 	DirChildrenNumLimit                 uint32
 	MetaPartitionInodeIdStep            uint64
+	MetaPartitionMigrateThreshold       uint64 // migrate half of the inode range once the inodes and dentries exceed it, 0 is off
 	MaxQuotaNumPerVol                   int
 	DisableAutoCreate                   bool
 	MonitorPushAddr                     string
@@ -29,6 +29,8 @@ const (
 	idKey                 = "id"
 	countKey              = "count"
 	startKey              = "start"
+	endKey                = "end"
+	targetKey             = "target"
 	enableKey             = "enable"
 	thresholdKey          = "threshold"
 	dirQuotaKey           = "dirQuota"
@@ -38,6 +40,12 @@ const (
 	dataPartitionCountKey = "dpCount"
 	volCapacityKey        = "capacity"
 	volDeleteLockTimeKey  = "deleteLockTime"
+	volTrashIntervalKey   = "trashInterval"
+	dstAddrKey            = "dstAddr"
+	dstVolKey             = "dstVol"
+	intervalKey           = "interval"
+	concurrencyKey        = "concurrency"
+	bandwidthKey          = "bandwidth"
 	volTypeKey            = "volType"
 	cacheRuleKey          = "cacheRuleKey"
 	emptyCacheRuleKey     = "emptyCacheRule"
@@ -93,6 +101,12 @@ const (
 	forceKey                   = "force"
 	raftForceDelKey            = "raftForceDel"
 	enablePosixAclKey          = "enablePosixAcl"
+	enableEncryptionKey        = "enableEncryption"
+	compressionKey             = "compression"
+	enableDedupKey             = "enableDedup"
+	enableCDCKey               = "enableCDC"
+	ecDataNumKey               = "ecDataNum"
+	ecParityNumKey             = "ecParityNum"
 	enableTxMaskKey            = "enableTxMask"
 	txTimeoutKey               = "txTimeout"
 	txConflictRetryNumKey      = "txConflictRetryNum"
@@ -283,6 +297,10 @@ const (
 	opSyncAcl          uint32 = 0x36
 	opSyncUid          uint32 = 0x37
 
+	opSyncAddReplication    uint32 = 0x38
+	opSyncDeleteReplication uint32 = 0x39
+	opSyncUpdateReplication uint32 = 0x3A
+
 	opSyncAllocQuotaID uint32 = 0x40
 	opSyncSetQuota     uint32 = 0x41
 	opSyncDeleteQuota  uint32 = 0x42
@@ -308,6 +326,7 @@ const (
 	domainAcronym          = "zoneDomain"
 	apiLimiterAcronym      = "al"
 	lcConfigurationAcronym = "lc"
+	replicationAcronym     = "vrep"
 	S3QoS                  = "s3qos"
 	maxDataPartitionIDKey  = keySeparator + "max_dp_id"
 	maxMetaPartitionIDKey  = keySeparator + "max_mp_id"
@@ -330,6 +349,8 @@ const (
 	AclPrefix        = keySeparator + "acl" + keySeparator
 	UidPrefix        = keySeparator + "uid" + keySeparator
 
+	replicationPrefix = keySeparator + replicationAcronym + keySeparator
+
 	akAcronym        = "ak"
 	userAcronym      = "user"
 	volUserAcronym   = "voluser"
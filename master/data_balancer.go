@@ -0,0 +1,515 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// dataBalancer migrates the replicas of the data partitions from the fullest disks to the emptiest
+// nodes of the same nodeset, so the nodes added to a nodeset are filled by the existing data. The
+// config is persisted with the cluster value, the migrations running are only kept by the leader.
+type dataBalancer struct {
+	sync.RWMutex
+	cfg      *proto.DataBalanceConfig
+	moving   map[uint64]*proto.DataBalanceMove
+	finished []*proto.DataBalanceMove
+}
+
+func newDataBalancer() *dataBalancer {
+	return &dataBalancer{
+		cfg:    proto.NewDataBalanceConfig(),
+		moving: make(map[uint64]*proto.DataBalanceMove),
+	}
+}
+
+func (b *dataBalancer) getConfig() *proto.DataBalanceConfig {
+	b.RLock()
+	defer b.RUnlock()
+	cfg := *b.cfg
+	return &cfg
+}
+
+func (b *dataBalancer) setConfig(cfg *proto.DataBalanceConfig) {
+	b.Lock()
+	defer b.Unlock()
+	b.cfg = cfg
+}
+
+func (b *dataBalancer) isMoving(partitionID uint64) bool {
+	b.RLock()
+	defer b.RUnlock()
+	_, ok := b.moving[partitionID]
+	return ok
+}
+
+func (b *dataBalancer) movingCount() int {
+	b.RLock()
+	defer b.RUnlock()
+	return len(b.moving)
+}
+
+func (b *dataBalancer) start(move *proto.DataBalanceMove) {
+	b.Lock()
+	defer b.Unlock()
+	move.Status = proto.DataBalanceMoveRunning
+	move.StartTime = time.Now().Unix()
+	b.moving[move.PartitionID] = move
+}
+
+func (b *dataBalancer) finish(move *proto.DataBalanceMove, err error) {
+	b.Lock()
+	defer b.Unlock()
+	move.EndTime = time.Now().Unix()
+	if err != nil {
+		move.Status = proto.DataBalanceMoveFailed
+		move.Result = err.Error()
+	} else {
+		move.Status = proto.DataBalanceMoveSucceeded
+	}
+	delete(b.moving, move.PartitionID)
+	b.finished = append(b.finished, move)
+	if len(b.finished) > defaultDataBalanceFinishedCount {
+		b.finished = b.finished[len(b.finished)-defaultDataBalanceFinishedCount:]
+	}
+}
+
+func (b *dataBalancer) list() (moving, finished []*proto.DataBalanceMove) {
+	b.RLock()
+	defer b.RUnlock()
+	moving = make([]*proto.DataBalanceMove, 0, len(b.moving))
+	for _, move := range b.moving {
+		m := *move
+		moving = append(moving, &m)
+	}
+	sort.Slice(moving, func(i, j int) bool { return moving[i].PartitionID < moving[j].PartitionID })
+	finished = make([]*proto.DataBalanceMove, 0, len(b.finished))
+	for _, move := range b.finished {
+		m := *move
+		finished = append(finished, &m)
+	}
+	return
+}
+
+type balanceReplica struct {
+	partitionID uint64
+	volName     string
+	size        uint64
+	hosts       []string
+}
+
+type balanceDisk struct {
+	node     *balanceNode
+	path     string
+	total    uint64
+	used     uint64
+	writable bool
+	replicas []*balanceReplica
+}
+
+func (d *balanceDisk) usage() float64 {
+	if d.total == 0 {
+		return 0
+	}
+	return float64(d.used) / float64(d.total)
+}
+
+func (d *balanceDisk) toUsage() *proto.DataBalanceUsage {
+	return &proto.DataBalanceUsage{Addr: d.node.addr, Disk: d.path, Total: d.total, Used: d.used, Usage: d.usage()}
+}
+
+type balanceNode struct {
+	addr  string
+	disks []*balanceDisk
+}
+
+func (n *balanceNode) usage() float64 {
+	var total, used uint64
+	for _, d := range n.disks {
+		total += d.total
+		used += d.used
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total)
+}
+
+// emptiestDisk returns the writable disk with the lowest usage, which is where the datanode is
+// most likely to create the replica migrated to it.
+func (n *balanceNode) emptiestDisk() (disk *balanceDisk) {
+	for _, d := range n.disks {
+		if !d.writable {
+			continue
+		}
+		if disk == nil || d.usage() < disk.usage() {
+			disk = d
+		}
+	}
+	return
+}
+
+func balanceDisks(nodes []*balanceNode) (disks []*balanceDisk) {
+	for _, n := range nodes {
+		disks = append(disks, n.disks...)
+	}
+	return
+}
+
+// balanceUsage returns the usage of all the disks, and the skew of the usage of the nodes and the disks.
+func balanceUsage(nodes []*balanceNode) (usage, nodeSkew, diskSkew float64) {
+	var total, used uint64
+	minNode, maxNode := 1.0, 0.0
+	for _, n := range nodes {
+		u := n.usage()
+		if u < minNode {
+			minNode = u
+		}
+		if u > maxNode {
+			maxNode = u
+		}
+	}
+	minDisk, maxDisk := 1.0, 0.0
+	for _, d := range balanceDisks(nodes) {
+		total += d.total
+		used += d.used
+		if d.usage() < minDisk {
+			minDisk = d.usage()
+		}
+		if d.usage() > maxDisk {
+			maxDisk = d.usage()
+		}
+	}
+	if total == 0 {
+		return
+	}
+	return float64(used) / float64(total), maxNode - minNode, maxDisk - minDisk
+}
+
+func newNodeSetBalanceView(id uint64, nodes []*balanceNode) (view *proto.NodeSetBalanceView) {
+	view = &proto.NodeSetBalanceView{ID: id}
+	view.Usage, view.NodeSkew, view.DiskSkew = balanceUsage(nodes)
+	var fullest, emptiest *balanceDisk
+	for _, d := range balanceDisks(nodes) {
+		if fullest == nil || d.usage() > fullest.usage() {
+			fullest = d
+		}
+		if emptiest == nil || d.usage() < emptiest.usage() {
+			emptiest = d
+		}
+	}
+	if fullest != nil {
+		view.FullestDisk = fullest.toUsage()
+		view.EmptiestDisk = emptiest.toUsage()
+	}
+	return
+}
+
+// planDataBalance plans at most limit migrations among the nodes of a nodeset. Every time the replica
+// is taken from the fullest disk exceeding the average usage by the threshold, and moved to the node
+// with the emptiest disk below the average, as long as the target is still emptier than the source
+// after the migration. A data partition is migrated at most once in a plan.
+func planDataBalance(nodes []*balanceNode, threshold float64, limit int) (moves []*proto.DataBalanceMove) {
+	disks := balanceDisks(nodes)
+	planned := make(map[uint64]bool)
+	for len(moves) < limit {
+		avg, _, _ := balanceUsage(nodes)
+		sort.SliceStable(disks, func(i, j int) bool { return disks[i].usage() > disks[j].usage() })
+		var move *proto.DataBalanceMove
+		for _, src := range disks {
+			if src.usage()-avg <= threshold {
+				break
+			}
+			if move = planDiskBalance(src, nodes, avg, planned); move != nil {
+				break
+			}
+		}
+		if move == nil {
+			return
+		}
+		planned[move.PartitionID] = true
+		moves = append(moves, move)
+	}
+	return
+}
+
+func planDiskBalance(src *balanceDisk, nodes []*balanceNode, avg float64, planned map[uint64]bool) *proto.DataBalanceMove {
+	targets := make([]*balanceDisk, 0, len(nodes))
+	for _, n := range nodes {
+		if n == src.node {
+			continue
+		}
+		if d := n.emptiestDisk(); d != nil && d.usage() < avg {
+			targets = append(targets, d)
+		}
+	}
+	sort.SliceStable(targets, func(i, j int) bool { return targets[i].usage() < targets[j].usage() })
+
+	replicas := src.replicas
+	sort.SliceStable(replicas, func(i, j int) bool { return replicas[i].size > replicas[j].size })
+	for _, dst := range targets {
+		for i, r := range replicas {
+			if r.size == 0 || r.size > src.used || planned[r.partitionID] || contains(r.hosts, dst.node.addr) {
+				continue
+			}
+			srcUsage := float64(src.used-r.size) / float64(src.total)
+			dstUsage := float64(dst.used+r.size) / float64(dst.total)
+			if dstUsage >= srcUsage {
+				continue
+			}
+			src.used -= r.size
+			dst.used += r.size
+			src.replicas = append(replicas[:i:i], replicas[i+1:]...)
+			dst.replicas = append(dst.replicas, r)
+			return &proto.DataBalanceMove{
+				PartitionID: r.partitionID,
+				VolName:     r.volName,
+				SrcAddr:     src.node.addr,
+				SrcDisk:     src.path,
+				DstAddr:     dst.node.addr,
+				Size:        r.size,
+				Status:      proto.DataBalanceMovePlanned,
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) canBalanceDataNode(dataNode *DataNode) bool {
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	return dataNode.isActive && !dataNode.RdOnly && !dataNode.ToBeOffline &&
+		dataNode.GetDecommissionStatus() == DecommissionInitial
+}
+
+// canBalanceDataPartition returns the hosts of the data partition if it can be migrated by the balancer.
+func (c *Cluster) canBalanceDataPartition(partitionID uint64) (hosts []string, ok bool) {
+	dp, err := c.getDataPartitionByID(partitionID)
+	if err != nil {
+		return nil, false
+	}
+	if _, err = c.getVol(dp.VolName); err != nil {
+		return nil, false
+	}
+	if c.dataBalancer.isMoving(partitionID) {
+		return nil, false
+	}
+	dp.RLock()
+	defer dp.RUnlock()
+	if !proto.IsNormalDp(dp.PartitionType) || dp.isErasureCoded() || dp.isRecover ||
+		!dp.IsDecommissionInitial() || len(dp.Hosts) < int(dp.ReplicaNum) {
+		return nil, false
+	}
+	return append([]string{}, dp.Hosts...), true
+}
+
+// newBalanceNode builds the disks of the data node by the disk stats of the heartbeat, the node is
+// taken as a single disk if they are not reported by the data node of an old version.
+func (c *Cluster) newBalanceNode(dataNode *DataNode) (node *balanceNode) {
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	node = &balanceNode{addr: dataNode.Addr}
+	disks := make(map[string]*balanceDisk)
+	for _, stat := range dataNode.DiskStats {
+		if stat.Total == 0 {
+			continue
+		}
+		d := &balanceDisk{
+			node:     node,
+			path:     stat.DiskPath,
+			total:    stat.Total,
+			used:     stat.Used,
+			writable: stat.Status == proto.ReadWrite,
+		}
+		node.disks = append(node.disks, d)
+		disks[d.path] = d
+	}
+	if len(node.disks) == 0 && dataNode.Total > 0 {
+		d := &balanceDisk{node: node, total: dataNode.Total, used: dataNode.Used, writable: true}
+		node.disks = append(node.disks, d)
+	}
+	if len(node.disks) == 0 {
+		return
+	}
+	for _, report := range dataNode.DataPartitionReports {
+		d := disks[report.DiskPath]
+		if d == nil {
+			if len(dataNode.DiskStats) > 0 {
+				continue
+			}
+			d = node.disks[0]
+		}
+		hosts, ok := c.canBalanceDataPartition(report.PartitionID)
+		if !ok {
+			continue
+		}
+		d.replicas = append(d.replicas, &balanceReplica{
+			partitionID: report.PartitionID,
+			volName:     report.VolName,
+			size:        report.Used,
+			hosts:       hosts,
+		})
+	}
+	return
+}
+
+func (c *Cluster) getBalanceNodes(ns *nodeSet) (nodes []*balanceNode) {
+	ns.dataNodes.Range(func(key, value interface{}) bool {
+		dataNode := value.(*DataNode)
+		if !c.canBalanceDataNode(dataNode) {
+			return true
+		}
+		if node := c.newBalanceNode(dataNode); len(node.disks) > 0 {
+			nodes = append(nodes, node)
+		}
+		return true
+	})
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].addr < nodes[j].addr })
+	return
+}
+
+// planDataBalance computes the usage of the zones and the nodesets, and plans at most limit migrations.
+func (c *Cluster) planDataBalance(threshold float64, limit int) (zones []*proto.ZoneBalanceView, moves []*proto.DataBalanceMove) {
+	allZones := c.t.getAllZones()
+	sort.Slice(allZones, func(i, j int) bool { return allZones[i].name < allZones[j].name })
+	for _, zone := range allZones {
+		nodeSets := zone.getAllNodeSet()
+		sort.Slice(nodeSets, func(i, j int) bool { return nodeSets[i].ID < nodeSets[j].ID })
+		zoneView := &proto.ZoneBalanceView{Zone: zone.name}
+		var zoneNodes []*balanceNode
+		for _, ns := range nodeSets {
+			nodes := c.getBalanceNodes(ns)
+			if len(nodes) == 0 {
+				continue
+			}
+			zoneNodes = append(zoneNodes, nodes...)
+			zoneView.NodeSets = append(zoneView.NodeSets, newNodeSetBalanceView(ns.ID, nodes))
+			if len(moves) < limit {
+				moves = append(moves, planDataBalance(nodes, threshold, limit-len(moves))...)
+			}
+		}
+		zoneView.Usage, zoneView.NodeSkew, zoneView.DiskSkew = balanceUsage(zoneNodes)
+		zones = append(zones, zoneView)
+	}
+	return
+}
+
+// getDataBalancePlan returns the plan of the data balancer without migrating anything.
+func (c *Cluster) getDataBalancePlan(cfg *proto.DataBalanceConfig) *proto.DataBalanceView {
+	zones, moves := c.planDataBalance(cfg.Threshold, defaultDataBalancePlanMoves)
+	return &proto.DataBalanceView{Config: cfg, Zones: zones, Moves: moves}
+}
+
+func (c *Cluster) getDataBalanceStatus() *proto.DataBalanceView {
+	zones, _ := c.planDataBalance(0, 0)
+	moving, finished := c.dataBalancer.list()
+	return &proto.DataBalanceView{Config: c.dataBalancer.getConfig(), Zones: zones, Moves: moving, Finished: finished}
+}
+
+func (c *Cluster) setDataBalanceConfig(cfg *proto.DataBalanceConfig) (err error) {
+	if err = cfg.Validate(); err != nil {
+		return
+	}
+	oldCfg := c.dataBalancer.getConfig()
+	c.dataBalancer.setConfig(cfg)
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setDataBalanceConfig] err[%v]", err)
+		c.dataBalancer.setConfig(oldCfg)
+		return proto.ErrPersistenceByRaft
+	}
+	log.LogInfof("action[setDataBalanceConfig] config %+v", cfg)
+	return
+}
+
+func (c *Cluster) scheduleToBalanceData() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() && c.metaReady {
+				c.balanceData()
+			}
+			time.Sleep(time.Second * defaultIntervalToBalanceData)
+		}
+	}()
+}
+
+// balanceData starts the migrations planned within the free slots of the concurrency, and the size of
+// the replicas started in a round is limited by the bandwidth, except the first one.
+func (c *Cluster) balanceData() {
+	cfg := c.dataBalancer.getConfig()
+	if !cfg.Enable {
+		return
+	}
+	slots := cfg.Concurrency - c.dataBalancer.movingCount()
+	if slots <= 0 {
+		return
+	}
+	_, moves := c.planDataBalance(cfg.Threshold, slots)
+	budget := cfg.BandwidthMB * util.MB * defaultIntervalToBalanceData
+	var size uint64
+	for i, move := range moves {
+		if i > 0 && size+move.Size > budget {
+			break
+		}
+		size += move.Size
+		c.dataBalancer.start(move)
+		go c.runDataBalanceMove(move)
+	}
+}
+
+// runDataBalanceMove migrates the replica and waits for the recovery of it, so the migration keeps a
+// slot of the concurrency until the data is copied to the target.
+func (c *Cluster) runDataBalanceMove(move *proto.DataBalanceMove) {
+	var (
+		dp  *DataPartition
+		err error
+	)
+	defer func() {
+		c.dataBalancer.finish(move, err)
+		log.LogInfof("action[runDataBalanceMove] dp[%v] from %v to %v size %v status %v err %v",
+			move.PartitionID, move.SrcAddr, move.DstAddr, move.Size, move.Status, err)
+	}()
+	if dp, err = c.getDataPartitionByID(move.PartitionID); err != nil {
+		return
+	}
+	if err = c.migrateDataPartition(move.SrcAddr, move.DstAddr, dp, false, "dataBalance"); err != nil {
+		return
+	}
+	deadline := time.Now().Add(defaultDataBalanceMoveTimeout * time.Second)
+	for {
+		dp.RLock()
+		moved, recovering := dp.hasHost(move.DstAddr), dp.isRecover
+		dp.RUnlock()
+		if !moved {
+			err = fmt.Errorf("dp %v is not migrated to %v", move.PartitionID, move.DstAddr)
+			return
+		}
+		if !recovering {
+			return
+		}
+		if time.Now().After(deadline) {
+			err = fmt.Errorf("dp %v is not recovered on %v in %v seconds", move.PartitionID, move.DstAddr,
+				defaultDataBalanceMoveTimeout)
+			return
+		}
+		time.Sleep(time.Second * defaultIntervalToCheckDataPartition)
+	}
+}
@@ -0,0 +1,70 @@
+package master
+
+import (
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBalanceNodeForTest(addr string, used []uint64, replicas map[uint64]uint64) *balanceNode {
+	node := &balanceNode{addr: addr}
+	for i, u := range used {
+		node.disks = append(node.disks, &balanceDisk{node: node, path: "/disk" + string(rune('0'+i)), total: 100, used: u, writable: true})
+	}
+	for pid, size := range replicas {
+		node.disks[0].replicas = append(node.disks[0].replicas, &balanceReplica{partitionID: pid, size: size, hosts: []string{addr}})
+	}
+	return node
+}
+
+func TestPlanDataBalance(t *testing.T) {
+	full := newBalanceNodeForTest("full", []uint64{90, 50}, map[uint64]uint64{1: 20, 2: 10, 3: 5})
+	empty := newBalanceNodeForTest("empty", []uint64{10, 10}, nil)
+	nodes := []*balanceNode{full, empty}
+
+	moves := planDataBalance(nodes, 0.1, 10)
+	if assert.Len(t, moves, 3) {
+		assert.EqualValues(t, 1, moves[0].PartitionID)
+		assert.Equal(t, "full", moves[0].SrcAddr)
+		assert.Equal(t, "/disk0", moves[0].SrcDisk)
+		assert.Equal(t, "empty", moves[0].DstAddr)
+		assert.Equal(t, proto.DataBalanceMovePlanned, moves[0].Status)
+		assert.EqualValues(t, 2, moves[1].PartitionID)
+	}
+	assert.EqualValues(t, 55, full.disks[0].used)
+	_, _, diskSkew := balanceUsage(nodes)
+	assert.InDelta(t, 0.3, diskSkew, 1e-9)
+
+	// the disks within the threshold are not balanced
+	assert.Empty(t, planDataBalance(nodes, 0.3, 10))
+}
+
+func TestPlanDataBalanceExcludeHosts(t *testing.T) {
+	full := newBalanceNodeForTest("full", []uint64{90}, map[uint64]uint64{1: 20})
+	empty := newBalanceNodeForTest("empty", []uint64{10}, nil)
+	full.disks[0].replicas[0].hosts = []string{"full", "empty"}
+
+	// the replica is not moved to the node hosting the partition already
+	assert.Empty(t, planDataBalance([]*balanceNode{full, empty}, 0.1, 10))
+
+	// the replica is not moved if the target would be fuller than the source
+	full = newBalanceNodeForTest("full", []uint64{60}, map[uint64]uint64{1: 50})
+	empty = newBalanceNodeForTest("empty", []uint64{20}, nil)
+	assert.Empty(t, planDataBalance([]*balanceNode{full, empty}, 0.1, 10))
+}
+
+func TestDataBalancerFinish(t *testing.T) {
+	b := newDataBalancer()
+	for i := 0; i < defaultDataBalanceFinishedCount+1; i++ {
+		move := &proto.DataBalanceMove{PartitionID: uint64(i)}
+		b.start(move)
+		assert.True(t, b.isMoving(move.PartitionID))
+		b.finish(move, nil)
+	}
+	moving, finished := b.list()
+	assert.Empty(t, moving)
+	assert.Len(t, finished, defaultDataBalanceFinishedCount)
+	assert.EqualValues(t, 1, finished[0].PartitionID)
+	assert.Equal(t, proto.DataBalanceMoveSucceeded, finished[0].Status)
+}
@@ -50,6 +50,7 @@ type DataNode struct {
 	PersistenceDataPartitions []uint64
 	BadDisks                  []string            // Keep this old field for compatibility
 	BadDiskStats              []proto.BadDiskStat // key: disk path
+	DiskStats                 []proto.DiskStat
 	DecommissionedDisks       sync.Map
 	ToBeOffline               bool
 	RdOnly                    bool
@@ -155,6 +156,7 @@ func (dataNode *DataNode) updateNodeMetric(resp *proto.DataNodeHeartbeatResponse
 
 	dataNode.BadDisks = resp.BadDisks
 	dataNode.BadDiskStats = resp.BadDiskStats
+	dataNode.DiskStats = resp.DiskStats
 
 	dataNode.StartTime = resp.StartTime
 	if dataNode.Total == 0 {
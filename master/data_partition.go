@@ -74,6 +74,9 @@ type DataPartition struct {
 	VerSeq                         uint64
 	RecoverStartTime               time.Time
 	RecoverLastConsumeTime         time.Duration
+	ECDataNum                      uint8 // the partition is erasure coded if it is not 0
+	ECParityNum                    uint8
+	ECHosts                        []string // the host keeping the shard i, "" if the shard is lost
 }
 
 type DataPartitionPreLoad struct {
@@ -283,10 +286,45 @@ func (partition *DataPartition) createTaskToCreateDataPartition(addr string, dat
 		partition.VolName, partition.PartitionID, int(partition.ReplicaNum),
 		peers, int(dataPartitionSize), leaderSize, hosts, createType,
 		partitionType, decommissionedDisks, partition.VerSeq))
+	if partition.isErasureCoded() {
+		req := task.Request.(*proto.CreateDataPartitionRequest)
+		req.ECDataNum = partition.ECDataNum
+		req.ECParityNum = partition.ECParityNum
+		req.ECHosts = make([]string, len(partition.ECHosts))
+		copy(req.ECHosts, partition.ECHosts)
+	}
 	partition.resetTaskID(task)
 	return
 }
 
+func (partition *DataPartition) isErasureCoded() bool {
+	return partition.ECDataNum > 0
+}
+
+// buildECHosts returns the hosts of the shards after the hosts of the partition are changed. The
+// shard of the host removed is lost, and it is given to the host added, which rebuilds the shard
+// from the other ones by the repair of the partition.
+func (partition *DataPartition) buildECHosts(hosts []string) (ecHosts []string) {
+	ecHosts = make([]string, len(partition.ECHosts))
+	for i, host := range partition.ECHosts {
+		if contains(hosts, host) {
+			ecHosts[i] = host
+		}
+	}
+	for _, host := range hosts {
+		if contains(ecHosts, host) {
+			continue
+		}
+		for i := range ecHosts {
+			if ecHosts[i] == "" {
+				ecHosts[i] = host
+				break
+			}
+		}
+	}
+	return
+}
+
 func (partition *DataPartition) createTaskToDeleteDataPartition(addr string) (task *proto.AdminTask) {
 	task = proto.NewAdminTask(proto.OpDeleteDataPartition, addr, newDeleteDataPartitionRequest(partition.PartitionID))
 	partition.resetTaskID(task)
@@ -450,6 +488,12 @@ func (partition *DataPartition) convertToDataPartitionResponse() (dpr *proto.Dat
 	dpr.LeaderAddr = partition.getLeaderAddr()
 	dpr.IsRecover = partition.isRecover
 	dpr.IsDiscard = partition.IsDiscard
+	if partition.isErasureCoded() {
+		dpr.ECDataNum = partition.ECDataNum
+		dpr.ECParityNum = partition.ECParityNum
+		dpr.ECHosts = make([]string, len(partition.ECHosts))
+		copy(dpr.ECHosts, partition.ECHosts)
+	}
 
 	return
 }
@@ -693,11 +737,16 @@ func (partition *DataPartition) update(action, volName string, newPeers []proto.
 	copy(orgHosts, partition.Hosts)
 	oldPeers := make([]proto.Peer, len(partition.Peers))
 	copy(oldPeers, partition.Peers)
+	oldECHosts := partition.ECHosts
 	partition.Hosts = newHosts
 	partition.Peers = newPeers
+	if partition.isErasureCoded() {
+		partition.ECHosts = partition.buildECHosts(newHosts)
+	}
 	if err = c.syncUpdateDataPartition(partition); err != nil {
 		partition.Hosts = orgHosts
 		partition.Peers = oldPeers
+		partition.ECHosts = oldECHosts
 		return errors.Trace(err, "action[%v] update partition[%v] vol[%v] failed", action, partition.PartitionID, volName)
 	}
 	msg := fmt.Sprintf("action[%v] success,vol[%v] partitionID:%v "+
@@ -995,6 +1044,9 @@ func (partition *DataPartition) buildDpInfo(c *Cluster) *proto.DataPartitionInfo
 		IsDiscard:                partition.IsDiscard,
 		SingleDecommissionStatus: partition.GetSpecialReplicaDecommissionStep(),
 		Forbidden:                forbidden,
+		ECDataNum:                partition.ECDataNum,
+		ECParityNum:              partition.ECParityNum,
+		ECHosts:                  partition.ECHosts,
 	}
 }
 
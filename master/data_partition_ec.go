@@ -0,0 +1,121 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/storage"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// The hosts of the erasure coded partition keep the shards of the extents, which are different from
+// each other, so the crc of them is never compared. The sizes of the shards are compared instead once
+// the partition is loaded, and the hosts of the shards missing or smaller than the others are asked
+// to rebuild them from the shards of the other hosts.
+
+// the extents modified recently are skipped, the stripes of them may be written by the clients. The
+// partition recovering is loaded again after it, so that the shards of the host added are rebuilt.
+const ecShardQuietPeriod = 5 * 60 // s
+
+// planECShardRepair returns the extents to repair of every host of the shards. The size of a shard is
+// the one kept by dataNum hosts at least, which is the size the shard could be rebuilt to, so the
+// shards missing or smaller than it are repaired. The extents kept by less than dataNum hosts could
+// not be rebuilt and are returned as lost.
+func planECShardRepair(dataNum int, ecHosts []string, files map[string]*FileInCore, now int64) (repairs map[string][]uint64, lost []uint64) {
+	repairs = make(map[string][]uint64)
+	for _, fc := range files {
+		extentID, err := strconv.ParseUint(fc.Name, 10, 64)
+		if err != nil || storage.IsTinyExtent(extentID) || now-fc.LastModify < ecShardQuietPeriod {
+			continue
+		}
+		sizes := make([]int64, len(ecHosts))
+		present := make([]uint32, 0, len(ecHosts))
+		for i, host := range ecHosts {
+			sizes[i] = -1
+			for _, fm := range fc.MetadataArray {
+				if host != "" && fm.LocAddr == host {
+					sizes[i] = int64(fm.Size)
+					present = append(present, fm.Size)
+					break
+				}
+			}
+		}
+		if len(present) < dataNum {
+			lost = append(lost, extentID)
+			continue
+		}
+		sort.Slice(present, func(i, j int) bool { return present[i] > present[j] })
+		target := int64(present[dataNum-1])
+		for i, host := range ecHosts {
+			if host != "" && sizes[i] < target {
+				repairs[host] = append(repairs[host], extentID)
+			}
+		}
+	}
+	for _, extentIDs := range repairs {
+		sort.Slice(extentIDs, func(i, j int) bool { return extentIDs[i] < extentIDs[j] })
+	}
+	sort.Slice(lost, func(i, j int) bool { return lost[i] < lost[j] })
+	return
+}
+
+// checkECShards sends the tasks to repair the shards missing or smaller than the others to their
+// hosts, the files of the partition are loaded before.
+func (partition *DataPartition) checkECShards(c *Cluster) {
+	partition.RLock()
+	ecHosts := make([]string, len(partition.ECHosts))
+	copy(ecHosts, partition.ECHosts)
+	repairs, lost := planECShardRepair(int(partition.ECDataNum), ecHosts, partition.FileInCoreMap, time.Now().Unix())
+	partition.RUnlock()
+
+	for i, host := range ecHosts {
+		if host == "" {
+			Warn(c.Name, fmt.Sprintf("action[checkECShards] vol[%v],dpId[%v] shard[%v] lost, waiting for a host added",
+				partition.VolName, partition.PartitionID, i))
+		}
+	}
+	if len(lost) > 0 {
+		Warn(c.Name, fmt.Sprintf("action[checkECShards] vol[%v],dpId[%v] extents%v kept by less than %v hosts could not be repaired",
+			partition.VolName, partition.PartitionID, lost, partition.ECDataNum))
+	}
+	for _, host := range ecHosts {
+		extentIDs := repairs[host]
+		if len(extentIDs) == 0 {
+			continue
+		}
+		dataNode, err := c.dataNode(host)
+		if err != nil {
+			log.LogWarnf("action[checkECShards] dp[%v] can't find dataNode %v", partition.PartitionID, host)
+			continue
+		}
+		task := partition.createTaskToRepairDataPartitionShard(host, extentIDs)
+		if _, err = dataNode.TaskManager.syncSendAdminTask(task); err != nil {
+			log.LogWarnf("action[checkECShards] dp[%v] send repair task to %v failed %v", partition.PartitionID, host, err)
+			continue
+		}
+		log.LogInfof("action[checkECShards] dp[%v] host %v repair extents %v", partition.PartitionID, host, extentIDs)
+	}
+}
+
+func (partition *DataPartition) createTaskToRepairDataPartitionShard(addr string, extentIDs []uint64) (task *proto.AdminTask) {
+	task = proto.NewAdminTask(proto.OpRepairDataPartitionShard, addr, newRepairDataPartitionShardRequest(partition.PartitionID, extentIDs))
+	partition.resetTaskID(task)
+	return
+}
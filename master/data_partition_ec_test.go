@@ -0,0 +1,61 @@
+package master
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestECFile(extentID uint64, lastModify int64, sizes map[string]uint32) *FileInCore {
+	fc := newFileInCore(strconv.FormatUint(extentID, 10))
+	fc.LastModify = lastModify
+	for addr, size := range sizes {
+		fc.MetadataArray = append(fc.MetadataArray, newFileMetadata(0, addr, 0, size, 0))
+	}
+	return fc
+}
+
+func TestPlanECShardRepair(t *testing.T) {
+	const (
+		now = int64(100000)
+		old = now - ecShardQuietPeriod
+	)
+	ecHosts := []string{"a", "b", "c", "d"}
+	cases := []struct {
+		name    string
+		ecHosts []string
+		file    *FileInCore
+		repairs map[string][]uint64
+		lost    []uint64
+	}{
+		{"shards consistent", ecHosts, newTestECFile(1025, old, map[string]uint32{"a": 8, "b": 8, "c": 8, "d": 8}), map[string][]uint64{}, nil},
+		{"shard missing", ecHosts, newTestECFile(1025, old, map[string]uint32{"a": 8, "b": 8, "d": 8}), map[string][]uint64{"c": {1025}}, nil},
+		{"shard smaller", ecHosts, newTestECFile(1025, old, map[string]uint32{"a": 8, "b": 4, "c": 8, "d": 8}), map[string][]uint64{"b": {1025}}, nil},
+		// the shard larger than dataNum hosts keep could not be rebuilt to, the others are left alone
+		{"shard larger", ecHosts, newTestECFile(1025, old, map[string]uint32{"a": 12, "b": 8, "c": 8, "d": 4}), map[string][]uint64{"d": {1025}}, nil},
+		{"modified recently", ecHosts, newTestECFile(1025, now-10, map[string]uint32{"a": 8, "b": 8}), map[string][]uint64{}, nil},
+		{"tiny extent", ecHosts, newTestECFile(1, old, map[string]uint32{"a": 8}), map[string][]uint64{}, nil},
+		{"shards lost", ecHosts, newTestECFile(1025, old, map[string]uint32{"a": 8, "b": 8}), map[string][]uint64{}, []uint64{1025}},
+		// the shard of the host removed is left to the host added, and the one it keeps is not counted
+		{"host removed", []string{"a", "", "c", "d"}, newTestECFile(1025, old, map[string]uint32{"a": 8, "b": 8, "c": 8, "d": 8}), map[string][]uint64{}, nil},
+		{"host removed with shards lost", []string{"a", "", "c", "d"}, newTestECFile(1025, old, map[string]uint32{"a": 8, "b": 8, "c": 8}), map[string][]uint64{}, []uint64{1025}},
+		{"host added", []string{"a", "e", "c", "d"}, newTestECFile(1025, old, map[string]uint32{"a": 8, "b": 8, "c": 8, "d": 8}), map[string][]uint64{"e": {1025}}, nil},
+	}
+	for _, c := range cases {
+		files := map[string]*FileInCore{c.file.Name: c.file}
+		repairs, lost := planECShardRepair(3, c.ecHosts, files, now)
+		assert.Equal(t, c.repairs, repairs, c.name)
+		assert.Equal(t, c.lost, lost, c.name)
+	}
+
+	// the extents of a host are repaired together
+	files := map[string]*FileInCore{
+		"1030": newTestECFile(1030, old, map[string]uint32{"a": 8, "b": 8, "c": 8}),
+		"1026": newTestECFile(1026, old, map[string]uint32{"a": 8, "b": 8, "c": 8}),
+		"1027": newTestECFile(1027, old, map[string]uint32{"a": 8, "b": 8, "c": 8, "d": 8}),
+	}
+	repairs, lost := planECShardRepair(3, ecHosts, files, now)
+	assert.Equal(t, map[string][]uint64{"d": {1026, 1030}}, repairs)
+	assert.Empty(t, lost)
+}
@@ -84,6 +84,10 @@ func (c *Cluster) checkDiskRecoveryProgress() {
 				continue
 			}
 			if newReplica.isRepairing() {
+				// the shards of the erasure coded partition are repaired after it is loaded
+				if partition.isErasureCoded() && time.Now().Unix()-partition.LastLoadedTime > ecShardQuietPeriod {
+					c.loadDataPartition(partition)
+				}
 				if !partition.isSpecialReplicaCnt() &&
 					time.Now().Sub(partition.RecoverStartTime) > c.GetDecommissionDataPartitionRecoverTimeOut() {
 					partition.DecommissionNeedRollback = true
@@ -432,6 +432,43 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 		Path(proto.AdminLcNode).
 		HandlerFunc(m.lcnodeInfo)
 
+	// async volume replication APIs
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetReplication).
+		HandlerFunc(m.setReplication)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetReplication).
+		HandlerFunc(m.getReplication)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListReplication).
+		HandlerFunc(m.listReplications)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminPauseReplication).
+		HandlerFunc(m.pauseReplication)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminResumeReplication).
+		HandlerFunc(m.resumeReplication)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminFailoverReplication).
+		HandlerFunc(m.failoverReplication)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDeleteReplication).
+		HandlerFunc(m.deleteReplication)
+
+	// data balance APIs
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminDataBalancePlan).
+		HandlerFunc(m.getDataBalancePlan)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDataBalanceStart).
+		HandlerFunc(m.startDataBalance)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDataBalanceStop).
+		HandlerFunc(m.stopDataBalance)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminDataBalanceStatus).
+		HandlerFunc(m.getDataBalanceStatus)
+
 	// node task response APIs
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.GetDataNodeTaskResponse).
@@ -450,6 +487,9 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminDecommissionMetaPartition).
 		HandlerFunc(m.decommissionMetaPartition)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminMigrateMetaRange).
+		HandlerFunc(m.migrateMetaRange)
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminChangeMetaPartitionLeader).
 		HandlerFunc(m.changeMetaPartitionLeader)
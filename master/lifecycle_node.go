@@ -84,3 +84,23 @@ func (lcNode *LcNode) createSnapshotVerDelTask(masterAddr string, sTask *proto.S
 	task = proto.NewAdminTaskEx(proto.OpLcNodeSnapshotVerDel, lcNode.Addr, request, request.Task.Id)
 	return
 }
+
+func (lcNode *LcNode) createTrashCleanTask(masterAddr string, tTask *proto.TrashCleanTask) (task *proto.AdminTask) {
+	request := &proto.TrashCleanTaskRequest{
+		MasterAddr: masterAddr,
+		LcNodeAddr: lcNode.Addr,
+		Task:       tTask,
+	}
+	task = proto.NewAdminTaskEx(proto.OpLcNodeTrashClean, lcNode.Addr, request, request.Task.Id)
+	return
+}
+
+func (lcNode *LcNode) createReplicationTask(masterAddr string, rTask *proto.ReplicationTask) (task *proto.AdminTask) {
+	request := &proto.ReplicationTaskRequest{
+		MasterAddr: masterAddr,
+		LcNodeAddr: lcNode.Addr,
+		Task:       rTask,
+	}
+	task = proto.NewAdminTaskEx(proto.OpLcNodeReplication, lcNode.Addr, request, request.Task.Id)
+	return
+}
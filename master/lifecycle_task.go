@@ -51,6 +51,12 @@ func (c *Cluster) handleLcNodeTaskResponse(nodeAddr string, task *proto.AdminTas
 	case proto.OpLcNodeSnapshotVerDel:
 		response := task.Response.(*proto.SnapshotVerDelTaskResponse)
 		err = c.handleLcNodeSnapshotScanResp(task.OperatorAddr, response)
+	case proto.OpLcNodeTrashClean:
+		response := task.Response.(*proto.TrashCleanTaskResponse)
+		err = c.handleLcNodeTrashCleanResp(task.OperatorAddr, response)
+	case proto.OpLcNodeReplication:
+		response := task.Response.(*proto.ReplicationTaskResponse)
+		err = c.handleLcNodeReplicationResp(task.OperatorAddr, response)
 	default:
 		err = fmt.Errorf(fmt.Sprintf("lc unknown operate code %v", task.OpCode))
 		goto errHandler
@@ -86,9 +92,12 @@ func (c *Cluster) handleLcNodeHeartbeatResp(nodeAddr string, resp *proto.LcNodeH
 	lcNode.Unlock()
 
 	// update lcNodeStatus
-	log.LogInfof("action[handleLcNodeHeartbeatResp], lcNode[%v], LcScanningTasks[%v], SnapshotScanningTasks[%v]", nodeAddr, len(resp.LcScanningTasks), len(resp.SnapshotScanningTasks))
+	log.LogInfof("action[handleLcNodeHeartbeatResp], lcNode[%v], LcScanningTasks[%v], SnapshotScanningTasks[%v], TrashScanningTasks[%v], ReplicationTasks[%v]",
+		nodeAddr, len(resp.LcScanningTasks), len(resp.SnapshotScanningTasks), len(resp.TrashScanningTasks), len(resp.ReplicationTasks))
 	c.lcMgr.lcNodeStatus.UpdateNode(nodeAddr, len(resp.LcScanningTasks))
 	c.snapshotMgr.lcNodeStatus.UpdateNode(nodeAddr, len(resp.SnapshotScanningTasks))
+	c.trashMgr.lcNodeStatus.UpdateNode(nodeAddr, len(resp.TrashScanningTasks))
+	c.replicationMgr.lcNodeStatus.UpdateNode(nodeAddr, len(resp.ReplicationTasks))
 
 	// handle LcScanningTasks
 	for _, taskRsp := range resp.LcScanningTasks {
@@ -132,6 +141,46 @@ func (c *Cluster) handleLcNodeHeartbeatResp(nodeAddr string, resp *proto.LcNodeH
 		c.snapshotMgr.notifyIdleLcNode()
 	}
 
+	// handle TrashScanningTasks
+	for _, taskRsp := range resp.TrashScanningTasks {
+		c.trashMgr.lcTrashTaskStatus.Lock()
+
+		if c.trashMgr.lcTrashTaskStatus.TaskResults[taskRsp.ID] != nil && c.trashMgr.lcTrashTaskStatus.TaskResults[taskRsp.ID].Done {
+			log.LogInfof("action[handleLcNodeHeartbeatResp], lcNode[%v] trash task[%v] already done", nodeAddr, taskRsp.ID)
+		} else {
+			t := time.Now()
+			taskRsp.UpdateTime = &t
+			c.trashMgr.lcTrashTaskStatus.TaskResults[taskRsp.ID] = taskRsp
+		}
+
+		c.trashMgr.lcTrashTaskStatus.Unlock()
+		log.LogDebugf("action[handleLcNodeHeartbeatResp], lcNode[%v] trash taskRsp: %v", nodeAddr, taskRsp)
+	}
+	if len(resp.TrashScanningTasks) < resp.LcTaskCountLimit {
+		log.LogInfof("action[handleLcNodeHeartbeatResp], notify idle lcNode[%v], now TrashScanningTasks[%v]", nodeAddr, len(resp.TrashScanningTasks))
+		c.trashMgr.notifyIdleLcNode()
+	}
+
+	// handle ReplicationTasks
+	for _, taskRsp := range resp.ReplicationTasks {
+		c.replicationMgr.lcReplicationTaskStatus.Lock()
+
+		if c.replicationMgr.lcReplicationTaskStatus.TaskResults[taskRsp.ID] != nil && c.replicationMgr.lcReplicationTaskStatus.TaskResults[taskRsp.ID].Done {
+			log.LogInfof("action[handleLcNodeHeartbeatResp], lcNode[%v] replication task[%v] already done", nodeAddr, taskRsp.ID)
+		} else {
+			t := time.Now()
+			taskRsp.UpdateTime = &t
+			c.replicationMgr.lcReplicationTaskStatus.TaskResults[taskRsp.ID] = taskRsp
+		}
+
+		c.replicationMgr.lcReplicationTaskStatus.Unlock()
+		log.LogDebugf("action[handleLcNodeHeartbeatResp], lcNode[%v] replication taskRsp: %v", nodeAddr, taskRsp)
+	}
+	if len(resp.ReplicationTasks) < resp.LcTaskCountLimit {
+		log.LogInfof("action[handleLcNodeHeartbeatResp], notify idle lcNode[%v], now ReplicationTasks[%v]", nodeAddr, len(resp.ReplicationTasks))
+		c.replicationMgr.notifyIdleLcNode()
+	}
+
 	log.LogInfof("action[handleLcNodeHeartbeatResp], lcNode[%v], heartbeat success", nodeAddr)
 	return
 }
@@ -189,3 +238,75 @@ func (c *Cluster) handleLcNodeSnapshotScanResp(nodeAddr string, resp *proto.Snap
 
 	return
 }
+
+func (c *Cluster) handleLcNodeTrashCleanResp(nodeAddr string, resp *proto.TrashCleanTaskResponse) (err error) {
+	log.LogDebugf("action[handleLcNodeTrashCleanResp] lcNode[%v] task[%v] Enter", nodeAddr, resp.ID)
+	defer func() {
+		log.LogDebugf("action[handleLcNodeTrashCleanResp] lcNode[%v] task[%v] Exit", nodeAddr, resp.ID)
+	}()
+
+	switch resp.Status {
+	case proto.TaskFailed:
+		// the volume is cleaned again in the next round
+		c.trashMgr.lcTrashTaskStatus.AddResult(resp)
+		log.LogWarnf("action[handleLcNodeTrashCleanResp] cleaning failed, resp(%v)", resp)
+		return
+	case proto.TaskSucceeds:
+		c.trashMgr.lcTrashTaskStatus.AddResult(resp)
+		log.LogInfof("action[handleLcNodeTrashCleanResp] cleaning completed, resp(%v)", resp)
+		return
+	default:
+		log.LogInfof("action[handleLcNodeTrashCleanResp] cleaning received, resp(%v)", resp)
+	}
+
+	return
+}
+
+func (c *Cluster) handleLcNodeReplicationResp(nodeAddr string, resp *proto.ReplicationTaskResponse) (err error) {
+	log.LogDebugf("action[handleLcNodeReplicationResp] lcNode[%v] task[%v] Enter", nodeAddr, resp.ID)
+	defer func() {
+		log.LogDebugf("action[handleLcNodeReplicationResp] lcNode[%v] task[%v] Exit", nodeAddr, resp.ID)
+	}()
+
+	switch resp.Status {
+	case proto.TaskFailed, proto.TaskSucceeds:
+		c.replicationMgr.lcReplicationTaskStatus.AddResult(resp)
+	default:
+		log.LogInfof("action[handleLcNodeReplicationResp] replication received, resp(%v)", resp)
+		return
+	}
+	if resp.ReplicationTask == nil || resp.ReplicationTask.Replication == nil {
+		log.LogWarnf("action[handleLcNodeReplicationResp] replication without task, resp(%v)", resp)
+		return
+	}
+
+	fromVer := resp.ReplicationTask.Replication.LastVerSeq
+	_, err = c.updateReplication(resp.ID, func(r *proto.VolReplication) error {
+		if r.LastVerSeq != fromVer {
+			return fmt.Errorf("the replication is replicated from %v, but the round is from %v", r.LastVerSeq, fromVer)
+		}
+		r.LastSyncTime = time.Now().Unix()
+		if resp.Status == proto.TaskFailed {
+			// the next round is started from the same snapshot after the interval
+			r.LastError = resp.Result
+			return nil
+		}
+		r.LastVerSeq = resp.VerSeq
+		r.LastVerTime = resp.VerTime
+		r.LastError = ""
+		r.Rounds++
+		r.SyncedFiles += resp.FileNum
+		r.SyncedBytes += resp.TotalBytes
+		return nil
+	})
+	if err != nil {
+		log.LogWarnf("action[handleLcNodeReplicationResp] update replication failed, resp(%v) err(%v)", resp, err)
+		return
+	}
+	if resp.Status == proto.TaskFailed {
+		log.LogWarnf("action[handleLcNodeReplicationResp] replication failed, resp(%v)", resp)
+	} else {
+		log.LogInfof("action[handleLcNodeReplicationResp] replication completed, resp(%v)", resp)
+	}
+	return
+}
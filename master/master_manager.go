@@ -226,6 +226,12 @@ func (m *Server) loadMetadata() {
 	}
 	log.LogInfo("action[loadLcConfs] end")
 
+	log.LogInfo("action[loadReplications] begin")
+	if err = m.cluster.loadReplications(); err != nil {
+		panic(err)
+	}
+	log.LogInfo("action[loadReplications] end")
+
 	log.LogInfo("action[loadLcNodes] begin")
 	if err = m.cluster.loadLcNodes(); err != nil {
 		panic(err)
@@ -245,6 +251,7 @@ func (m *Server) clearMetadata() {
 	m.cluster.clearDataNodes()
 	m.cluster.clearMetaNodes()
 	m.cluster.clearLcNodes()
+	m.cluster.clearReplications()
 	m.cluster.clearVols()
 
 	if m.user != nil {
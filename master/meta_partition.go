@@ -76,6 +76,8 @@ type MetaPartition struct {
 	EqualCheckPass   bool
 	VerSeq           uint64
 	heartBeatDone    bool
+	MigrateSrc       uint64 // the source partition of the migration the hidden target is created for
+	Migration        *MetaRangeMigration
 
 	sync.RWMutex
 }
@@ -212,7 +214,7 @@ func (mp *MetaPartition) dataSize() uint64 {
 }
 
 func (mp *MetaPartition) checkEnd(c *Cluster, maxPartitionID uint64) {
-	if mp.PartitionID < maxPartitionID {
+	if mp.PartitionID != maxPartitionID {
 		return
 	}
 	vol, err := c.getVol(mp.volName)
@@ -359,7 +361,7 @@ func (mp *MetaPartition) checkStatus(clusterID string, writeLog bool, replicaNum
 		}
 	}
 
-	if mp.PartitionID >= maxPartitionID && mp.Status == proto.ReadOnly && !forbiddenVol {
+	if mp.PartitionID == maxPartitionID && mp.Status == proto.ReadOnly && !forbiddenVol {
 		mp.Status = proto.ReadWrite
 	}
 
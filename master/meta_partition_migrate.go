@@ -0,0 +1,459 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/errors"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// status of the migration of the inode range
+const (
+	metaRangeMigrating uint8 = iota
+	metaRangeCutover
+	metaRangeAborting
+)
+
+const (
+	defaultMetaRangeMigrateTimeout  = 30 * 60 // resend the migration task if there is no response in 30 minutes
+	defaultMetaRangeMigrateMaxRetry = 3
+)
+
+// MetaRangeMigration records the migration of the inode range [Start, End] of the partition to the target
+// one. The inode range is the tail of the source partition, and the target is either a new partition
+// created for the range or the partition next to the source, which extends its start to the range.
+//
+// The target leader copies the range from the source through raft, freezes the range on the source and
+// copies the changes made before the freeze. Then master commits the new ranges of both partitions and
+// cuts over the target and the source, the source redirects the requests of the moved range to the target.
+type MetaRangeMigration struct {
+	TargetID   uint64
+	Start      uint64
+	End        uint64
+	NewTarget  bool
+	Status     uint8
+	Retry      int
+	UpdateTime int64
+	Result     string
+}
+
+func (mig *MetaRangeMigration) String() string {
+	return fmt.Sprintf("target(%v) range(%v-%v) newTarget(%v) status(%v) retry(%v)",
+		mig.TargetID, mig.Start, mig.End, mig.NewTarget, mig.Status, mig.Retry)
+}
+
+// metaRangeMigration returns the source partition of the ongoing migration of the volume.
+func (vol *Vol) metaRangeMigration() (src *MetaPartition) {
+	vol.mpsLock.RLock()
+	defer vol.mpsLock.RUnlock()
+	for _, mp := range vol.MetaPartitions {
+		if mp.Migration != nil {
+			return mp
+		}
+	}
+	return nil
+}
+
+// migrateMetaRange starts the migration of the inode range from start to the end of the source partition.
+// The range is migrated to a new partition if targetID is 0, otherwise to the partition next to the source.
+// The range inside the source is refused, since the range of a partition is kept contiguous, the end of the
+// range is the end of the source if it is 0.
+func (c *Cluster) migrateMetaRange(vol *Vol, src *MetaPartition, start, end, targetID uint64) (target *MetaPartition, err error) {
+	if vol.Forbidden {
+		return nil, fmt.Errorf("volume %v is forbidden", vol.Name)
+	}
+
+	vol.createMpMutex.Lock()
+	defer vol.createMpMutex.Unlock()
+
+	if ongoing := vol.metaRangeMigration(); ongoing != nil {
+		return nil, fmt.Errorf("vol[%v] mp[%v] is migrating %v", vol.Name, ongoing.PartitionID, ongoing.Migration)
+	}
+
+	src.RLock()
+	srcStart, srcEnd, srcMaxInodeID := src.Start, src.End, src.MaxInodeID
+	_, err = src.getMetaReplicaLeader()
+	src.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("mp[%v] %v", src.PartitionID, err)
+	}
+	if start <= srcStart+1 || start > srcEnd {
+		return nil, fmt.Errorf("start[%v] is out of the range(%v-%v] of mp[%v]", start, srcStart+1, srcEnd, src.PartitionID)
+	}
+	if end != 0 && end != srcEnd {
+		return nil, fmt.Errorf("range[%v-%v] is inside the range(%v-%v) of mp[%v], only the range to the end of it could be migrated",
+			start, end, srcStart, srcEnd, src.PartitionID)
+	}
+	if start > srcMaxInodeID {
+		return nil, fmt.Errorf("start[%v] is larger than max inode[%v] of mp[%v], nothing to migrate",
+			start, srcMaxInodeID, src.PartitionID)
+	}
+
+	mig := &MetaRangeMigration{Start: start, End: srcEnd, Status: metaRangeMigrating}
+	if targetID != 0 {
+		if target, err = vol.metaPartition(targetID); err != nil {
+			return nil, err
+		}
+		if target.MigrateSrc != 0 || target.Start != srcEnd+1 {
+			return nil, fmt.Errorf("mp[%v] range(%v-%v) is not next to mp[%v] range(%v-%v)",
+				target.PartitionID, target.Start, target.End, src.PartitionID, srcStart, srcEnd)
+		}
+		if !target.isLeaderExist() {
+			return nil, fmt.Errorf("mp[%v] %v", target.PartitionID, proto.ErrNoLeader)
+		}
+	} else {
+		if target, err = vol.doCreateMetaPartition(c, start, srcEnd); err != nil {
+			return nil, err
+		}
+		target.MigrateSrc = src.PartitionID
+		mig.NewTarget = true
+	}
+	mig.TargetID = target.PartitionID
+
+	cmdMap := make(map[string]*RaftCmd)
+	src.Lock()
+	src.Migration = mig
+	cmd, err := c.buildMetaPartitionRaftCmd(opSyncUpdateMetaPartition, src)
+	src.Unlock()
+	if err == nil {
+		cmdMap[cmd.K] = cmd
+		if mig.NewTarget {
+			if cmd, err = c.buildMetaPartitionRaftCmd(opSyncAddMetaPartition, target); err == nil {
+				cmdMap[cmd.K] = cmd
+			}
+		}
+	}
+	if err == nil {
+		err = c.syncBatchCommitCmd(cmdMap)
+	}
+	if err != nil {
+		src.Lock()
+		src.Migration = nil
+		src.Unlock()
+		if mig.NewTarget {
+			c.addMetaNodeTasks(target.tasksToDeleteReplicas())
+		}
+		return nil, errors.NewError(err)
+	}
+	if mig.NewTarget {
+		vol.addMetaPartition(target)
+	}
+
+	log.LogWarnf("action[migrateMetaRange] vol[%v] mp[%v] range(%v-%v) start to migrate %v",
+		vol.Name, src.PartitionID, srcStart, srcEnd, mig)
+	c.sendMigrateMetaRangeTask(src, target)
+	return
+}
+
+func (mp *MetaPartition) tasksToDeleteReplicas() (tasks []*proto.AdminTask) {
+	mp.RLock()
+	defer mp.RUnlock()
+	for _, host := range mp.Hosts {
+		if mr, err := mp.getMetaReplica(host); err == nil {
+			tasks = append(tasks, mr.createTaskToDeleteReplica(mp.PartitionID))
+		}
+	}
+	return
+}
+
+// sendMigrateMetaRangeTask sends the migration task to the target leader, it's sent again by the check of the
+// migration if the target has no leader yet.
+func (c *Cluster) sendMigrateMetaRangeTask(src, target *MetaPartition) {
+	src.Lock()
+	defer src.Unlock()
+	mig := src.Migration
+	if mig == nil || mig.Status != metaRangeMigrating {
+		return
+	}
+
+	target.RLock()
+	mr, err := target.getMetaReplicaLeader()
+	target.RUnlock()
+	if err != nil {
+		log.LogWarnf("action[sendMigrateMetaRangeTask] mp[%v] migrate %v, target no leader", src.PartitionID, mig)
+		return
+	}
+	req := &proto.MigrateMetaRangeRequest{
+		PartitionID:    target.PartitionID,
+		VolName:        src.volName,
+		SrcPartitionID: src.PartitionID,
+		SrcAddrs:       append([]string{}, src.Hosts...),
+		Start:          mig.Start,
+		End:            mig.End,
+	}
+	t := proto.NewAdminTask(proto.OpMigrateMetaRange, mr.Addr, req)
+	resetMetaPartitionTaskID(t, target.PartitionID)
+	c.addMetaNodeTasks([]*proto.AdminTask{t})
+	mig.UpdateTime = time.Now().Unix()
+	log.LogInfof("action[sendMigrateMetaRangeTask] mp[%v] migrate %v to %v", src.PartitionID, mig, mr.Addr)
+}
+
+func (c *Cluster) dealMigrateMetaRangeResp(nodeAddr string, resp *proto.MigrateMetaRangeResponse) (err error) {
+	var (
+		vol    *Vol
+		src    *MetaPartition
+		target *MetaPartition
+	)
+	if vol, err = c.getVol(resp.VolName); err != nil {
+		return
+	}
+	if src, err = vol.metaPartition(resp.SrcPartitionID); err != nil {
+		return
+	}
+	if target, err = vol.metaPartition(resp.PartitionID); err != nil {
+		return
+	}
+
+	src.Lock()
+	mig := src.Migration
+	if mig == nil || mig.Status != metaRangeMigrating || mig.TargetID != resp.PartitionID ||
+		mig.Start != resp.Start || mig.End != resp.End {
+		src.Unlock()
+		log.LogWarnf("action[dealMigrateMetaRangeResp] nodeAddr %v stale resp %v of mp[%v]", nodeAddr, resp, src.PartitionID)
+		return
+	}
+	if resp.Status == proto.TaskFailed {
+		mig.Retry++
+		mig.Result = resp.Result
+		mig.UpdateTime = 0
+		abort := mig.Retry >= defaultMetaRangeMigrateMaxRetry
+		if abort {
+			mig.Status = metaRangeAborting
+		}
+		if err = c.syncUpdateMetaPartition(src); err != nil {
+			log.LogErrorf("action[dealMigrateMetaRangeResp] mp[%v] persist %v err[%v]", src.PartitionID, mig, err)
+		}
+		src.Unlock()
+		msg := fmt.Sprintf("action[dealMigrateMetaRangeResp],clusterID[%v] nodeAddr %v mp[%v] migrate %v failed,err %v",
+			c.Name, nodeAddr, src.PartitionID, mig, resp.Result)
+		log.LogError(msg)
+		Warn(c.Name, msg)
+		if abort {
+			c.abortMetaRangeMigration(vol, src, target)
+		}
+		return
+	}
+	src.Unlock()
+
+	log.LogWarnf("action[dealMigrateMetaRangeResp] mp[%v] migrate %v done, inodes[%v] dentries[%v]",
+		src.PartitionID, mig, resp.InodeCount, resp.DentryCount)
+	return c.cutoverMetaRange(vol, src, target)
+}
+
+// cutoverMetaRange commits the new ranges of the source and the target, then switches the target and the
+// source to the new ranges. The target is switched first, so the requests redirected by the source are
+// served by the target.
+func (c *Cluster) cutoverMetaRange(vol *Vol, src, target *MetaPartition) (err error) {
+	vol.createMpMutex.Lock()
+	defer vol.createMpMutex.Unlock()
+
+	src.Lock()
+	mig := src.Migration
+	if mig == nil || mig.TargetID != target.PartitionID {
+		src.Unlock()
+		return
+	}
+	if mig.Status == metaRangeMigrating {
+		target.Lock()
+		oldSrcEnd, oldTargetStart, oldMigrateSrc := src.End, target.Start, target.MigrateSrc
+		mig.Status = metaRangeCutover
+		src.End = mig.Start - 1
+		target.Start = mig.Start
+		target.MigrateSrc = 0
+		cmdMap := make(map[string]*RaftCmd)
+		var cmd *RaftCmd
+		for _, mp := range []*MetaPartition{src, target} {
+			if cmd, err = c.buildMetaPartitionRaftCmd(opSyncUpdateMetaPartition, mp); err != nil {
+				break
+			}
+			cmdMap[cmd.K] = cmd
+		}
+		if err == nil {
+			err = c.syncBatchCommitCmd(cmdMap)
+		}
+		if err != nil {
+			mig.Status = metaRangeMigrating
+			src.End, target.Start, target.MigrateSrc = oldSrcEnd, oldTargetStart, oldMigrateSrc
+			target.Unlock()
+			src.Unlock()
+			log.LogErrorf("action[cutoverMetaRange] mp[%v] migrate %v commit err[%v]", src.PartitionID, mig, err)
+			return
+		}
+		src.updateInodeIDRangeForAllReplicas()
+		for _, mr := range target.Replicas {
+			mr.start = target.Start
+		}
+		target.Unlock()
+		src.Unlock()
+		vol.updateViewCache(c)
+	} else {
+		src.Unlock()
+	}
+
+	if err = c.sendCutoverMetaRange(target, &proto.CutoverMetaRangeRequest{}); err != nil {
+		return
+	}
+	moved := &proto.MovedMetaRange{
+		MetaRange:   proto.MetaRange{Start: mig.Start, End: mig.End},
+		PartitionID: target.PartitionID,
+	}
+	if err = c.sendCutoverMetaRange(src, &proto.CutoverMetaRangeRequest{Moved: moved}); err != nil {
+		return
+	}
+
+	src.Lock()
+	src.Migration = nil
+	if err = c.syncUpdateMetaPartition(src); err != nil {
+		src.Migration = mig
+		src.Unlock()
+		return
+	}
+	src.Unlock()
+	log.LogWarnf("action[cutoverMetaRange] vol[%v] mp[%v] range(%v-%v) migrated to mp[%v] range(%v-%v)",
+		vol.Name, src.PartitionID, mig.Start, mig.End, target.PartitionID, target.Start, target.End)
+	return
+}
+
+// abortMetaRangeMigration unfreezes the range on the source and deletes the new target. The items copied to
+// the partition next to the source are out of its range and overwritten by the next migration of the range.
+func (c *Cluster) abortMetaRangeMigration(vol *Vol, src, target *MetaPartition) (err error) {
+	src.RLock()
+	mig := src.Migration
+	src.RUnlock()
+	if mig == nil || mig.Status != metaRangeAborting {
+		return
+	}
+
+	vol.createMpMutex.Lock()
+	defer vol.createMpMutex.Unlock()
+	if err = c.sendCutoverMetaRange(src, &proto.CutoverMetaRangeRequest{Abort: true}); err != nil {
+		return
+	}
+	if mig.NewTarget && target != nil {
+		c.addMetaNodeTasks(target.tasksToDeleteReplicas())
+		if err = c.syncDeleteMetaPartition(target); err != nil {
+			log.LogErrorf("action[abortMetaRangeMigration] delete mp[%v] err[%v]", target.PartitionID, err)
+			return
+		}
+		vol.deleteMetaPartition(target.PartitionID)
+	}
+
+	src.Lock()
+	src.Migration = nil
+	if err = c.syncUpdateMetaPartition(src); err != nil {
+		src.Migration = mig
+		src.Unlock()
+		return
+	}
+	src.Unlock()
+	msg := fmt.Sprintf("action[abortMetaRangeMigration] vol[%v] mp[%v] migration %v aborted, result %v",
+		vol.Name, src.PartitionID, mig, mig.Result)
+	log.LogWarn(msg)
+	Warn(c.Name, msg)
+	return
+}
+
+// sendCutoverMetaRange switches the partition to its current range, the range in req is filled from mp.
+func (c *Cluster) sendCutoverMetaRange(mp *MetaPartition, req *proto.CutoverMetaRangeRequest) (err error) {
+	mp.RLock()
+	req.PartitionID, req.VolName, req.Start, req.End = mp.PartitionID, mp.volName, mp.Start, mp.End
+	mr, err := mp.getMetaReplicaLeader()
+	mp.RUnlock()
+	if err != nil {
+		return fmt.Errorf("mp[%v] %v", mp.PartitionID, err)
+	}
+	metaNode, err := c.metaNode(mr.Addr)
+	if err != nil {
+		return
+	}
+	t := proto.NewAdminTask(proto.OpCutoverMetaRange, mr.Addr, req)
+	resetMetaPartitionTaskID(t, mp.PartitionID)
+	if _, err = metaNode.Sender.syncSendAdminTask(t); err != nil {
+		log.LogErrorf("action[sendCutoverMetaRange] mp[%v] req %v err[%v]", mp.PartitionID, req, err)
+	}
+	return
+}
+
+// checkMetaRangeMigration drives the ongoing migration of the volume, or starts one for the partition of
+// which the inodes and dentries exceed the threshold.
+func (vol *Vol) checkMetaRangeMigration(c *Cluster) {
+	src := vol.metaRangeMigration()
+	if src == nil {
+		vol.autoMigrateMetaRange(c)
+		return
+	}
+
+	src.RLock()
+	mig := *src.Migration
+	src.RUnlock()
+	target, err := vol.metaPartition(mig.TargetID)
+	if err != nil {
+		log.LogErrorf("action[checkMetaRangeMigration] vol[%v] mp[%v] migrate %v err[%v]", vol.Name, src.PartitionID, &mig, err)
+		if mig.Status != metaRangeAborting {
+			return
+		}
+		target = nil
+	}
+
+	switch mig.Status {
+	case metaRangeMigrating:
+		if time.Now().Unix()-mig.UpdateTime > defaultMetaRangeMigrateTimeout {
+			c.sendMigrateMetaRangeTask(src, target)
+		}
+	case metaRangeCutover:
+		err = c.cutoverMetaRange(vol, src, target)
+	case metaRangeAborting:
+		err = c.abortMetaRangeMigration(vol, src, target)
+	}
+	if err != nil {
+		log.LogWarnf("action[checkMetaRangeMigration] vol[%v] mp[%v] migrate %v err[%v]", vol.Name, src.PartitionID, &mig, err)
+	}
+}
+
+func (vol *Vol) autoMigrateMetaRange(c *Cluster) {
+	threshold := c.cfg.MetaPartitionMigrateThreshold
+	if threshold == 0 || c.cfg.DisableAutoCreate || c.DisableAutoAllocate || vol.Forbidden {
+		return
+	}
+
+	var (
+		src   *MetaPartition
+		count uint64
+	)
+	for _, mp := range vol.cloneMetaPartitionMap() {
+		mp.RLock()
+		if mp.MigrateSrc == 0 && mp.Status != proto.Unavailable {
+			if n := mp.InodeCount + mp.DentryCount; n > threshold && n > count && mp.MaxInodeID > mp.Start+2 {
+				src, count = mp, n
+			}
+		}
+		mp.RUnlock()
+	}
+	if src == nil {
+		return
+	}
+
+	src.RLock()
+	start := src.Start + (src.MaxInodeID-src.Start)/2 + 1
+	src.RUnlock()
+	if _, err := c.migrateMetaRange(vol, src, start, 0, 0); err != nil {
+		Warn(c.Name, fmt.Sprintf("action[autoMigrateMetaRange] vol[%v] mp[%v] inodes and dentries[%v] migrate from[%v] err[%v]",
+			vol.Name, src.PartitionID, count, start, err))
+	}
+}
@@ -61,6 +61,7 @@ type clusterValue struct {
 	DpRepairTimeOut             uint64
 	EnableAutoDecommissionDisk  bool
 	DecommissionDiskFactor      float64
+	DataBalance                 *bsProto.DataBalanceConfig
 }
 
 func newClusterValue(c *Cluster) (cv *clusterValue) {
@@ -91,6 +92,7 @@ func newClusterValue(c *Cluster) (cv *clusterValue) {
 		DpRepairTimeOut:             c.cfg.DpRepairTimeOut,
 		EnableAutoDecommissionDisk:  c.EnableAutoDecommissionDisk,
 		DecommissionDiskFactor:      c.DecommissionDiskFactor,
+		DataBalance:                 c.dataBalancer.getConfig(),
 	}
 	return cv
 }
@@ -107,6 +109,8 @@ type metaPartitionValue struct {
 	OfflinePeerID uint64
 	Peers         []bsProto.Peer
 	IsRecover     bool
+	MigrateSrc    uint64
+	Migration     *MetaRangeMigration
 }
 
 func newMetaPartitionValue(mp *MetaPartition) (mpv *metaPartitionValue) {
@@ -122,6 +126,8 @@ func newMetaPartitionValue(mp *MetaPartition) (mpv *metaPartitionValue) {
 		Peers:         mp.Peers,
 		OfflinePeerID: mp.OfflinePeerID,
 		IsRecover:     mp.IsRecover,
+		MigrateSrc:    mp.MigrateSrc,
+		Migration:     mp.Migration,
 	}
 	return
 }
@@ -154,6 +160,9 @@ type dataPartitionValue struct {
 	RecoverStartTime               int64
 	RecoverLastConsumeTime         float64
 	Forbidden                      bool
+	ECDataNum                      uint8
+	ECParityNum                    uint8
+	ECHosts                        []string
 }
 
 func (dpv *dataPartitionValue) Restore(c *Cluster) (dp *DataPartition) {
@@ -182,6 +191,9 @@ func (dpv *dataPartitionValue) Restore(c *Cluster) (dp *DataPartition) {
 	dp.DecommissionNeedRollback = dpv.DecommissionNeedRollback
 	dp.RecoverStartTime = time.Unix(dpv.RecoverStartTime, 0)
 	dp.RecoverLastConsumeTime = time.Duration(dpv.RecoverLastConsumeTime) * time.Second
+	dp.ECDataNum = dpv.ECDataNum
+	dp.ECParityNum = dpv.ECParityNum
+	dp.ECHosts = dpv.ECHosts
 	for _, rv := range dpv.Replicas {
 		if !contains(dp.Hosts, rv.Addr) {
 			continue
@@ -224,6 +236,9 @@ func newDataPartitionValue(dp *DataPartition) (dpv *dataPartitionValue) {
 		DecommissionNeedRollback:       dp.DecommissionNeedRollback,
 		RecoverStartTime:               dp.RecoverStartTime.Unix(),
 		RecoverLastConsumeTime:         dp.RecoverLastConsumeTime.Seconds(),
+		ECDataNum:                      dp.ECDataNum,
+		ECParityNum:                    dp.ECParityNum,
+		ECHosts:                        dp.ECHosts,
 	}
 	for _, replica := range dp.Replicas {
 		rv := &replicaValue{Addr: replica.Addr, DiskPath: replica.DiskPath}
@@ -245,19 +260,26 @@ type volValue struct {
 	Authenticate          bool
 	DpReadOnlyWhenVolFull bool
 
-	CrossZone       bool
-	DomainOn        bool
-	ZoneName        string
-	OSSAccessKey    string
-	OSSSecretKey    string
-	CreateTime      int64
-	DeleteLockTime  int64
-	Description     string
-	DpSelectorName  string
-	DpSelectorParm  string
-	DefaultPriority bool
-	DomainId        uint64
-	VolType         int
+	CrossZone        bool
+	DomainOn         bool
+	ZoneName         string
+	OSSAccessKey     string
+	OSSSecretKey     string
+	CreateTime       int64
+	DeleteLockTime   int64
+	TrashInterval    int64
+	EnableEncryption bool
+	Compression      string
+	EnableDedup      bool
+	EnableCDC        bool
+	ECDataNum        uint8
+	ECParityNum      uint8
+	Description      string
+	DpSelectorName   string
+	DpSelectorParm   string
+	DefaultPriority  bool
+	DomainId         uint64
+	VolType          int
 
 	EbsBlkSize       int
 	CacheCapacity    uint64
@@ -313,6 +335,13 @@ func newVolValue(vol *Vol) (vv *volValue) {
 		OSSSecretKey:            vol.OSSSecretKey,
 		CreateTime:              vol.createTime,
 		DeleteLockTime:          vol.DeleteLockTime,
+		TrashInterval:           vol.TrashInterval,
+		EnableEncryption:        vol.EnableEncryption,
+		Compression:             vol.Compression,
+		EnableDedup:             vol.EnableDedup,
+		EnableCDC:               vol.EnableCDC,
+		ECDataNum:               vol.ECDataNum,
+		ECParityNum:             vol.ECParityNum,
 		Description:             vol.description,
 		DpSelectorName:          vol.dpSelectorName,
 		DpSelectorParm:          vol.dpSelectorParm,
@@ -1121,6 +1150,9 @@ func (c *Cluster) loadClusterValue() (err error) {
 		c.DecommissionLimit = cv.DecommissionLimit
 		c.EnableAutoDecommissionDisk = cv.EnableAutoDecommissionDisk
 		c.DecommissionDiskFactor = cv.DecommissionDiskFactor
+		if cv.DataBalance != nil {
+			c.dataBalancer.setConfig(cv.DataBalance)
+		}
 		if c.cfg.QosMasterAcceptLimit < QosMasterAcceptCnt {
 			c.cfg.QosMasterAcceptLimit = QosMasterAcceptCnt
 		}
@@ -1503,6 +1535,8 @@ func (c *Cluster) loadMetaPartitions() (err error) {
 		mp.setPeers(mpv.Peers)
 		mp.OfflinePeerID = mpv.OfflinePeerID
 		mp.IsRecover = mpv.IsRecover
+		mp.MigrateSrc = mpv.MigrateSrc
+		mp.Migration = mpv.Migration
 		vol.addMetaPartition(mp)
 		c.addBadMetaParitionIdMap(mp)
 		log.LogInfof("action[loadMetaPartitions],vol[%v],mp[%v]", vol.Name, mp.PartitionID)
@@ -1797,3 +1831,33 @@ func (c *Cluster) loadLcConfs() (err error) {
 	}
 	return
 }
+
+func (c *Cluster) syncPutReplication(opType uint32, r *bsProto.VolReplication) (err error) {
+	metadata := new(RaftCmd)
+	metadata.Op = opType
+	metadata.K = replicationPrefix + r.SrcVol
+	metadata.V, err = json.Marshal(r)
+	if err != nil {
+		return errors.New(err.Error())
+	}
+	return c.submit(metadata)
+}
+
+func (c *Cluster) loadReplications() (err error) {
+	result, err := c.fsm.store.SeekForPrefix([]byte(replicationPrefix))
+	if err != nil {
+		err = fmt.Errorf("action[loadReplications],err:%v", err.Error())
+		return err
+	}
+
+	for _, value := range result {
+		r := &bsProto.VolReplication{}
+		if err = json.Unmarshal(value, r); err != nil {
+			err = fmt.Errorf("action[loadReplications],value:%v,unmarshal err:%v", string(value), err)
+			return
+		}
+		c.replicationMgr.put(r)
+		log.LogInfof("action[loadReplications],vol[%v]", r.SrcVol)
+	}
+	return
+}
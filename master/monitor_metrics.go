@@ -88,6 +88,7 @@ const (
 	MetricLcTotalFileScanned         = "lc_total_file_scanned"
 	MetricLcTotalDirScanned          = "lc_total_dirs_scanned"
 	MetricLcTotalExpired             = "lc_total_expired"
+	MetricLcTotalTransitioned        = "lc_total_transitioned"
 )
 
 var WarnMetrics *warningMetrics
@@ -150,6 +151,7 @@ type monitorMetrics struct {
 	lcTotalFileScanned *exporter.GaugeVec
 	lcTotalDirScanned  *exporter.GaugeVec
 	lcTotalExpired     *exporter.GaugeVec
+	lcTotalTransition  *exporter.GaugeVec
 }
 
 func newMonitorMetrics(c *Cluster) *monitorMetrics {
@@ -478,6 +480,7 @@ func (mm *monitorMetrics) start() {
 	mm.lcTotalFileScanned = exporter.NewGaugeVec(MetricLcTotalFileScanned, "", []string{"volName", "type"})
 	mm.lcTotalDirScanned = exporter.NewGaugeVec(MetricLcTotalDirScanned, "", []string{"volName", "type"})
 	mm.lcTotalExpired = exporter.NewGaugeVec(MetricLcTotalExpired, "", []string{"volName", "type"})
+	mm.lcTotalTransition = exporter.NewGaugeVec(MetricLcTotalTransitioned, "", []string{"volName", "type"})
 	go mm.statMetrics()
 }
 
@@ -914,6 +917,9 @@ func (mm *monitorMetrics) deleteS3LcVolMetric(volName string) {
 	mm.lcTotalFileScanned.DeleteLabelValues(volName, "file")
 	mm.lcTotalDirScanned.DeleteLabelValues(volName, "dir")
 	mm.lcTotalExpired.DeleteLabelValues(volName, "expired")
+	mm.lcTotalExpired.DeleteLabelValues(volName, "noncurrent_expired")
+	mm.lcTotalExpired.DeleteLabelValues(volName, "aborted_multipart")
+	mm.lcTotalTransition.DeleteLabelValues(volName, "transitioned")
 }
 
 func (mm *monitorMetrics) setLcMetrics() {
@@ -935,6 +941,9 @@ func (mm *monitorMetrics) setLcMetrics() {
 		mm.lcTotalFileScanned.SetWithLabelValues(float64(stat.FileScannedNum), key, "file")
 		mm.lcTotalDirScanned.SetWithLabelValues(float64(stat.DirScannedNum), key, "dir")
 		mm.lcTotalExpired.SetWithLabelValues(float64(stat.ExpiredNum), key, "expired")
+		mm.lcTotalExpired.SetWithLabelValues(float64(stat.NoncurrentExpiredNum), key, "noncurrent_expired")
+		mm.lcTotalExpired.SetWithLabelValues(float64(stat.AbortedMultipartNum), key, "aborted_multipart")
+		mm.lcTotalTransition.SetWithLabelValues(float64(stat.TransitionedNum), key, "transitioned")
 	}
 }
 
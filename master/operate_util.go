@@ -86,6 +86,14 @@ func newStopDataPartitionRepairRequest(ID uint64, stop bool) (req *proto.StopDat
 	return
 }
 
+func newRepairDataPartitionShardRequest(ID uint64, extentIDs []uint64) (req *proto.RepairDataPartitionShardRequest) {
+	req = &proto.RepairDataPartitionShardRequest{
+		PartitionId: ID,
+		ExtentIDs:   extentIDs,
+	}
+	return
+}
+
 func unmarshalTaskResponse(task *proto.AdminTask) (err error) {
 	bytes, err := json.Marshal(task.Response)
 	if err != nil {
@@ -111,12 +119,18 @@ func unmarshalTaskResponse(task *proto.AdminTask) (err error) {
 		response = &proto.MetaPartitionDecommissionResponse{}
 	case proto.OpVersionOperation:
 		response = &proto.MultiVersionOpResponse{}
+	case proto.OpMigrateMetaRange:
+		response = &proto.MigrateMetaRangeResponse{}
 	case proto.OpLcNodeHeartbeat:
 		response = &proto.LcNodeHeartbeatResponse{}
 	case proto.OpLcNodeScan:
 		response = &proto.LcNodeRuleTaskResponse{}
 	case proto.OpLcNodeSnapshotVerDel:
 		response = &proto.SnapshotVerDelTaskResponse{}
+	case proto.OpLcNodeTrashClean:
+		response = &proto.TrashCleanTaskResponse{}
+	case proto.OpLcNodeReplication:
+		response = &proto.ReplicationTaskResponse{}
 
 	default:
 		log.LogError(fmt.Sprintf("unknown operate code(%v)", task.OpCode))
@@ -0,0 +1,269 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// replicationManager keeps the replications of the volumes and dispatches the rounds of the
+// active ones to the lcnodes. The progress of the replications is persisted by raft, so a
+// round failed or lost is started again from the snapshot replicated last time.
+type replicationManager struct {
+	sync.RWMutex
+	cluster                 *Cluster
+	replications            map[string]*proto.VolReplication
+	lcReplicationTaskStatus *lcReplicationTaskStatus
+	lcNodeStatus            *lcNodeStatus
+	idleNodeCh              chan struct{}
+	exitCh                  chan struct{}
+}
+
+func newReplicationManager() *replicationManager {
+	log.LogInfof("action[newReplicationManager] construct")
+	replicationMgr := &replicationManager{
+		replications:            make(map[string]*proto.VolReplication),
+		lcReplicationTaskStatus: newLcReplicationTaskStatus(),
+		lcNodeStatus:            newLcNodeStatus(),
+		idleNodeCh:              make(chan struct{}),
+		exitCh:                  make(chan struct{}),
+	}
+	return replicationMgr
+}
+
+func (m *replicationManager) process() {
+	for {
+		select {
+		case <-m.exitCh:
+			log.LogInfo("exitCh notified, replicationManager process exit")
+			return
+		case <-m.idleNodeCh:
+			log.LogDebug("idleLcNodeCh notified")
+
+			task := m.lcReplicationTaskStatus.GetOneTask()
+			if task == nil {
+				log.LogDebugf("lcReplicationTaskStatus.GetOneTask, no task")
+				continue
+			}
+
+			nodeAddr := m.lcNodeStatus.GetIdleNode()
+			if nodeAddr == "" {
+				log.LogWarn("no idle lcnode, redo task")
+				m.lcReplicationTaskStatus.RedoTask(task)
+				continue
+			}
+
+			val, ok := m.cluster.lcNodes.Load(nodeAddr)
+			if !ok {
+				log.LogErrorf("lcNodes.Load, nodeAddr(%v) is not available, redo task", nodeAddr)
+				m.lcNodeStatus.RemoveNode(nodeAddr)
+				m.lcReplicationTaskStatus.RedoTask(task)
+				continue
+			}
+
+			node := val.(*LcNode)
+			adminTask := node.createReplicationTask(m.cluster.masterAddr(), task)
+			m.cluster.addLcNodeTasks([]*proto.AdminTask{adminTask})
+			// the round is running until the lcnode reports it, so it is not dispatched twice
+			t := time.Now()
+			m.lcReplicationTaskStatus.AddResult(&proto.ReplicationTaskResponse{
+				ID:              task.Id,
+				LcNode:          nodeAddr,
+				UpdateTime:      &t,
+				ReplicationTask: task,
+			})
+			log.LogDebugf("add replication task(%v) to lcnode(%v)", *task, nodeAddr)
+		}
+	}
+}
+
+// planReplicationTasks returns the tasks of the replications due at now, whose source volumes
+// are still in use.
+func planReplicationTasks(replications []*proto.VolReplication, vols map[string]*Vol, now time.Time) (tasks []*proto.ReplicationTask) {
+	for _, r := range replications {
+		if !r.IsDue(now) {
+			continue
+		}
+		if vol, ok := vols[r.SrcVol]; !ok || vol.Status == proto.VolStatusMarkDelete {
+			continue
+		}
+		tasks = append(tasks, &proto.ReplicationTask{
+			Id:          r.SrcVol,
+			Replication: r,
+		})
+	}
+	return
+}
+
+// newReplicationConfig returns the replication to persist for the setting, based on the existing
+// replication old, which is nil if the volume is not replicated yet. The target of an existing
+// replication can not be changed, and the interval is kept if it is not set.
+func newReplicationConfig(old *proto.VolReplication, srcVol, dstMasters, dstVol string, interval int64) (r *proto.VolReplication, op uint32, err error) {
+	op = opSyncUpdateReplication
+	if old == nil {
+		if dstMasters == "" || dstVol == "" {
+			err = fmt.Errorf("the masters and the volume of the target are required")
+			return
+		}
+		old = &proto.VolReplication{
+			SrcVol:     srcVol,
+			DstMasters: dstMasters,
+			DstVol:     dstVol,
+			Status:     proto.ReplicationActive,
+		}
+		op = opSyncAddReplication
+	} else if (dstMasters != "" && dstMasters != old.DstMasters) || (dstVol != "" && dstVol != old.DstVol) {
+		err = fmt.Errorf("the target of the replication of vol %v can not be changed, delete it first", srcVol)
+		return
+	}
+
+	replication := *old
+	if interval > 0 {
+		replication.Interval = interval
+	}
+	return &replication, op, nil
+}
+
+func (m *replicationManager) notifyIdleLcNode() {
+	m.lcReplicationTaskStatus.RLock()
+	defer m.lcReplicationTaskStatus.RUnlock()
+
+	if len(m.lcReplicationTaskStatus.Tasks) > 0 {
+		select {
+		case m.idleNodeCh <- struct{}{}:
+			log.LogDebug("action[handleLcNodeHeartbeatResp], replicationManager scan routine notified!")
+		default:
+			log.LogDebug("action[handleLcNodeHeartbeatResp], replicationManager skipping notify!")
+		}
+	}
+}
+
+func (m *replicationManager) get(srcVol string) *proto.VolReplication {
+	m.RLock()
+	defer m.RUnlock()
+	if r, ok := m.replications[srcVol]; ok {
+		replication := *r
+		return &replication
+	}
+	return nil
+}
+
+func (m *replicationManager) list() (replications []*proto.VolReplication) {
+	m.RLock()
+	defer m.RUnlock()
+	for _, r := range m.replications {
+		replication := *r
+		replications = append(replications, &replication)
+	}
+	return
+}
+
+func (m *replicationManager) put(r *proto.VolReplication) {
+	m.Lock()
+	defer m.Unlock()
+	replication := *r
+	m.replications[r.SrcVol] = &replication
+}
+
+func (m *replicationManager) delete(srcVol string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.replications, srcVol)
+}
+
+//----------------------------------------------
+
+type lcReplicationTaskStatus struct {
+	sync.RWMutex
+	Tasks       map[string]*proto.ReplicationTask
+	TaskResults map[string]*proto.ReplicationTaskResponse
+}
+
+func newLcReplicationTaskStatus() *lcReplicationTaskStatus {
+	return &lcReplicationTaskStatus{
+		Tasks:       make(map[string]*proto.ReplicationTask, 0),
+		TaskResults: make(map[string]*proto.ReplicationTaskResponse, 0),
+	}
+}
+
+func (ts *lcReplicationTaskStatus) GetOneTask() (task *proto.ReplicationTask) {
+	ts.Lock()
+	defer ts.Unlock()
+	for _, t := range ts.Tasks {
+		task = t
+		break
+	}
+	if task != nil {
+		delete(ts.Tasks, task.Id)
+	}
+	return
+}
+
+func (ts *lcReplicationTaskStatus) RedoTask(task *proto.ReplicationTask) {
+	ts.Lock()
+	defer ts.Unlock()
+	if task == nil {
+		return
+	}
+
+	ts.Tasks[task.Id] = task
+}
+
+func (ts *lcReplicationTaskStatus) AddTask(task *proto.ReplicationTask) {
+	ts.Lock()
+	defer ts.Unlock()
+
+	// the rounds are started by the interval of the replications, so only a round running blocks the next one
+	if r, ok := ts.TaskResults[task.Id]; ok && !r.Done {
+		log.LogDebugf("replication task: %v is in TaskResults, already in processing", task)
+		return
+	}
+	ts.Tasks[task.Id] = task
+	log.LogDebugf("Add replication task: %v", task)
+}
+
+func (ts *lcReplicationTaskStatus) DeleteTask(id string) {
+	ts.Lock()
+	defer ts.Unlock()
+	delete(ts.Tasks, id)
+}
+
+func (ts *lcReplicationTaskStatus) AddResult(resp *proto.ReplicationTaskResponse) {
+	ts.Lock()
+	defer ts.Unlock()
+	ts.TaskResults[resp.ID] = resp
+}
+
+func (ts *lcReplicationTaskStatus) DeleteOldResult() {
+	ts.Lock()
+	defer ts.Unlock()
+	for k, v := range ts.TaskResults {
+		// delete result that already done, the progress is persisted in the replication
+		if v.Done == true && time.Now().After(v.EndTime.Add(time.Minute*10)) {
+			delete(ts.TaskResults, k)
+			log.LogDebugf("delete replication result already done: %v", v)
+		}
+		// delete result that not done but no updating
+		if v.Done != true && time.Now().After(v.UpdateTime.Add(time.Minute*10)) {
+			delete(ts.TaskResults, k)
+			log.LogWarnf("delete replication result that not done but no updating: %v", v)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package master
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanReplicationTasks(t *testing.T) {
+	now := time.Now()
+	vols := map[string]*Vol{
+		"src":     {},
+		"deleted": {Status: proto.VolStatusMarkDelete},
+	}
+	cases := []struct {
+		name string
+		r    *proto.VolReplication
+		due  bool
+	}{
+		{"first round", &proto.VolReplication{SrcVol: "src", Status: proto.ReplicationActive}, true},
+		{"interval elapsed", &proto.VolReplication{SrcVol: "src", Status: proto.ReplicationActive, Interval: 60, LastSyncTime: now.Unix() - 60}, true},
+		{"interval not elapsed", &proto.VolReplication{SrcVol: "src", Status: proto.ReplicationActive, Interval: 60, LastSyncTime: now.Unix() - 30}, false},
+		{"default interval", &proto.VolReplication{SrcVol: "src", Status: proto.ReplicationActive, LastSyncTime: now.Unix() - 60}, false},
+		{"paused", &proto.VolReplication{SrcVol: "src", Status: proto.ReplicationPaused}, false},
+		{"failed over", &proto.VolReplication{SrcVol: "src", Status: proto.ReplicationFailedOver}, false},
+		{"volume not found", &proto.VolReplication{SrcVol: "none", Status: proto.ReplicationActive}, false},
+		{"volume deleted", &proto.VolReplication{SrcVol: "deleted", Status: proto.ReplicationActive}, false},
+	}
+	for _, c := range cases {
+		tasks := planReplicationTasks([]*proto.VolReplication{c.r}, vols, now)
+		if !c.due {
+			assert.Empty(t, tasks, c.name)
+			continue
+		}
+		if assert.Len(t, tasks, 1, c.name) {
+			assert.Equal(t, c.r.SrcVol, tasks[0].Id, c.name)
+			assert.Equal(t, c.r, tasks[0].Replication, c.name)
+		}
+	}
+}
+
+func TestNewReplicationConfig(t *testing.T) {
+	old := &proto.VolReplication{SrcVol: "src", DstMasters: "m1,m2", DstVol: "dst", Interval: 600, Status: proto.ReplicationPaused, LastVerSeq: 10}
+	cases := []struct {
+		name       string
+		old        *proto.VolReplication
+		dstMasters string
+		dstVol     string
+		interval   int64
+		op         uint32
+		want       *proto.VolReplication
+		err        bool
+	}{
+		{
+			name: "create", dstMasters: "m1,m2", dstVol: "dst", interval: 60, op: opSyncAddReplication,
+			want: &proto.VolReplication{SrcVol: "src", DstMasters: "m1,m2", DstVol: "dst", Interval: 60, Status: proto.ReplicationActive},
+		},
+		{
+			name: "create with the default interval", dstMasters: "m1,m2", dstVol: "dst", op: opSyncAddReplication,
+			want: &proto.VolReplication{SrcVol: "src", DstMasters: "m1,m2", DstVol: "dst", Status: proto.ReplicationActive},
+		},
+		{name: "create without the target masters", dstVol: "dst", err: true},
+		{name: "create without the target volume", dstMasters: "m1,m2", err: true},
+		{
+			name: "change the interval", old: old, interval: 60, op: opSyncUpdateReplication,
+			want: &proto.VolReplication{SrcVol: "src", DstMasters: "m1,m2", DstVol: "dst", Interval: 60, Status: proto.ReplicationPaused, LastVerSeq: 10},
+		},
+		{name: "keep the same target", old: old, dstMasters: "m1,m2", dstVol: "dst", op: opSyncUpdateReplication, want: old},
+		{name: "change the target masters", old: old, dstMasters: "m3", err: true},
+		{name: "change the target volume", old: old, dstVol: "other", err: true},
+	}
+	for _, c := range cases {
+		r, op, err := newReplicationConfig(c.old, "src", c.dstMasters, c.dstVol, c.interval)
+		if c.err {
+			assert.Error(t, err, c.name)
+			continue
+		}
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, c.op, op, c.name)
+		assert.Equal(t, c.want, r, c.name)
+	}
+	// the existing replication is never changed in place
+	assert.EqualValues(t, 600, old.Interval)
+}
+
+func TestLcReplicationTaskStatus(t *testing.T) {
+	ts := newLcReplicationTaskStatus()
+	task := &proto.ReplicationTask{Id: "src"}
+	now := time.Now()
+
+	// the next round is not added while a round is running
+	ts.AddResult(&proto.ReplicationTaskResponse{ID: "src", UpdateTime: &now})
+	ts.AddTask(task)
+	assert.Nil(t, ts.GetOneTask())
+
+	// but added once the round is done, whatever the result is
+	ts.AddResult(&proto.ReplicationTaskResponse{ID: "src", Done: true, EndTime: &now})
+	ts.AddTask(task)
+	assert.Equal(t, task, ts.GetOneTask())
+
+	ts.AddTask(task)
+	ts.DeleteTask("src")
+	assert.Nil(t, ts.GetOneTask())
+
+	old := now.Add(-time.Hour)
+	cases := []struct {
+		name string
+		resp *proto.ReplicationTaskResponse
+		kept bool
+	}{
+		{"running", &proto.ReplicationTaskResponse{UpdateTime: &now}, true},
+		{"running without updating", &proto.ReplicationTaskResponse{UpdateTime: &old}, false},
+		{"done just now", &proto.ReplicationTaskResponse{Done: true, EndTime: &now}, true},
+		{"done long ago", &proto.ReplicationTaskResponse{Done: true, EndTime: &old}, false},
+	}
+	for _, c := range cases {
+		c.resp.ID = c.name
+		ts.AddResult(c.resp)
+	}
+	ts.DeleteOldResult()
+	for _, c := range cases {
+		_, ok := ts.TaskResults[c.name]
+		assert.Equal(t, c.kept, ok, c.name)
+	}
+}
@@ -356,6 +356,12 @@ func (m *Server) checkConfig(cfg *config.Config) (err error) {
 		}
 	}
 
+	if migrateThreshold := cfg.GetString(cfgMetaPartitionMigrateThreshold); migrateThreshold != "" {
+		if m.config.MetaPartitionMigrateThreshold, err = strconv.ParseUint(migrateThreshold, 10, 64); err != nil {
+			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
+		}
+	}
+
 	m.config.MonitorPushAddr = cfg.GetString(cfgMonitorPushAddr)
 
 	m.config.volForceDeletion = cfg.GetBoolWithDefault(cfgVolForceDeletion, true)
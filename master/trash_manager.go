@@ -0,0 +1,184 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// trashCleanManager dispatches the tasks to purge the expired trash buckets of the
+// volumes with trash enabled to the lcnodes.
+type trashCleanManager struct {
+	cluster           *Cluster
+	lcTrashTaskStatus *lcTrashTaskStatus
+	lcNodeStatus      *lcNodeStatus
+	idleNodeCh        chan struct{}
+	exitCh            chan struct{}
+}
+
+func newTrashCleanManager() *trashCleanManager {
+	log.LogInfof("action[newTrashCleanManager] construct")
+	trashMgr := &trashCleanManager{
+		lcTrashTaskStatus: newLcTrashTaskStatus(),
+		lcNodeStatus:      newLcNodeStatus(),
+		idleNodeCh:        make(chan struct{}),
+		exitCh:            make(chan struct{}),
+	}
+	return trashMgr
+}
+
+func (m *trashCleanManager) process() {
+	for {
+		select {
+		case <-m.exitCh:
+			log.LogInfo("exitCh notified, trashCleanManager process exit")
+			return
+		case <-m.idleNodeCh:
+			log.LogDebug("idleLcNodeCh notified")
+
+			task := m.lcTrashTaskStatus.GetOneTask()
+			if task == nil {
+				log.LogDebugf("lcTrashTaskStatus.GetOneTask, no task")
+				continue
+			}
+
+			nodeAddr := m.lcNodeStatus.GetIdleNode()
+			if nodeAddr == "" {
+				log.LogWarn("no idle lcnode, redo task")
+				m.lcTrashTaskStatus.RedoTask(task)
+				continue
+			}
+
+			val, ok := m.cluster.lcNodes.Load(nodeAddr)
+			if !ok {
+				log.LogErrorf("lcNodes.Load, nodeAddr(%v) is not available, redo task", nodeAddr)
+				m.lcNodeStatus.RemoveNode(nodeAddr)
+				m.lcTrashTaskStatus.RedoTask(task)
+				continue
+			}
+
+			node := val.(*LcNode)
+			adminTask := node.createTrashCleanTask(m.cluster.masterAddr(), task)
+			m.cluster.addLcNodeTasks([]*proto.AdminTask{adminTask})
+			log.LogDebugf("add trash clean task(%v) to lcnode(%v)", *task, nodeAddr)
+		}
+	}
+}
+
+// planTrashCleanTasks returns the tasks of the volumes with trash enabled.
+func planTrashCleanTasks(vols map[string]*Vol) (tasks []*proto.TrashCleanTask) {
+	for volName, vol := range vols {
+		if vol.TrashInterval <= 0 || vol.Status == proto.VolStatusMarkDelete {
+			continue
+		}
+		tasks = append(tasks, &proto.TrashCleanTask{
+			Id:            volName,
+			VolName:       volName,
+			TrashInterval: vol.TrashInterval,
+		})
+	}
+	return
+}
+
+func (m *trashCleanManager) notifyIdleLcNode() {
+	m.lcTrashTaskStatus.RLock()
+	defer m.lcTrashTaskStatus.RUnlock()
+
+	if len(m.lcTrashTaskStatus.Tasks) > 0 {
+		select {
+		case m.idleNodeCh <- struct{}{}:
+			log.LogDebug("action[handleLcNodeHeartbeatResp], trashCleanManager scan routine notified!")
+		default:
+			log.LogDebug("action[handleLcNodeHeartbeatResp], trashCleanManager skipping notify!")
+		}
+	}
+}
+
+//----------------------------------------------
+
+type lcTrashTaskStatus struct {
+	sync.RWMutex
+	Tasks       map[string]*proto.TrashCleanTask
+	TaskResults map[string]*proto.TrashCleanTaskResponse
+}
+
+func newLcTrashTaskStatus() *lcTrashTaskStatus {
+	return &lcTrashTaskStatus{
+		Tasks:       make(map[string]*proto.TrashCleanTask, 0),
+		TaskResults: make(map[string]*proto.TrashCleanTaskResponse, 0),
+	}
+}
+
+func (ts *lcTrashTaskStatus) GetOneTask() (task *proto.TrashCleanTask) {
+	ts.Lock()
+	defer ts.Unlock()
+	for _, t := range ts.Tasks {
+		task = t
+		break
+	}
+	if task != nil {
+		delete(ts.Tasks, task.Id)
+	}
+	return
+}
+
+func (ts *lcTrashTaskStatus) RedoTask(task *proto.TrashCleanTask) {
+	ts.Lock()
+	defer ts.Unlock()
+	if task == nil {
+		return
+	}
+
+	ts.Tasks[task.Id] = task
+}
+
+func (ts *lcTrashTaskStatus) AddTask(task *proto.TrashCleanTask) {
+	ts.Lock()
+	defer ts.Unlock()
+
+	if _, ok := ts.TaskResults[task.Id]; ok {
+		log.LogDebugf("trash task: %v is in TaskResults, already in processing", task)
+		return
+	}
+	ts.Tasks[task.Id] = task
+	log.LogDebugf("Add trash task: %v", task)
+}
+
+func (ts *lcTrashTaskStatus) AddResult(resp *proto.TrashCleanTaskResponse) {
+	ts.Lock()
+	defer ts.Unlock()
+	ts.TaskResults[resp.ID] = resp
+}
+
+func (ts *lcTrashTaskStatus) DeleteOldResult() {
+	ts.Lock()
+	defer ts.Unlock()
+	for k, v := range ts.TaskResults {
+		// delete result that already done, the volume is cleaned again after that
+		if v.Done == true && time.Now().After(v.EndTime.Add(time.Minute*10)) {
+			delete(ts.TaskResults, k)
+			log.LogDebugf("delete trash result already done: %v", v)
+		}
+		// delete result that not done but no updating
+		if v.Done != true && time.Now().After(v.UpdateTime.Add(time.Minute*10)) {
+			delete(ts.TaskResults, k)
+			log.LogWarnf("delete trash result that not done but no updating: %v", v)
+		}
+	}
+}
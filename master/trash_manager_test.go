@@ -0,0 +1,100 @@
+package master
+
+import (
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanTrashCleanTasks(t *testing.T) {
+	cases := []struct {
+		name string
+		vols map[string]*Vol
+		want []string
+	}{
+		{"no volume", nil, nil},
+		{"trash disabled", map[string]*Vol{"a": {TrashInterval: 0}}, nil},
+		{"trash enabled", map[string]*Vol{"a": {TrashInterval: 60}, "b": {TrashInterval: 0}, "c": {TrashInterval: 10}}, []string{"a", "c"}},
+		{"volume deleted", map[string]*Vol{"a": {TrashInterval: 60, Status: proto.VolStatusMarkDelete}}, nil},
+	}
+	for _, c := range cases {
+		var names []string
+		for _, task := range planTrashCleanTasks(c.vols) {
+			assert.Equal(t, task.VolName, task.Id, c.name)
+			assert.Equal(t, c.vols[task.VolName].TrashInterval, task.TrashInterval, c.name)
+			names = append(names, task.VolName)
+		}
+		sort.Strings(names)
+		assert.Equal(t, c.want, names, c.name)
+	}
+}
+
+func TestLcTrashTaskStatus(t *testing.T) {
+	ts := newLcTrashTaskStatus()
+	ts.AddTask(&proto.TrashCleanTask{Id: "a", VolName: "a"})
+	ts.AddTask(&proto.TrashCleanTask{Id: "a", VolName: "a"})
+	assert.Len(t, ts.Tasks, 1)
+
+	task := ts.GetOneTask()
+	assert.Equal(t, "a", task.Id)
+	assert.Nil(t, ts.GetOneTask())
+	ts.RedoTask(task)
+	assert.Len(t, ts.Tasks, 1)
+	ts.GetOneTask()
+
+	// the volume is not cleaned again while the last task is running or just done
+	now := time.Now()
+	ts.AddResult(&proto.TrashCleanTaskResponse{ID: "a", UpdateTime: &now})
+	ts.AddTask(&proto.TrashCleanTask{Id: "a", VolName: "a"})
+	assert.Empty(t, ts.Tasks)
+
+	old := now.Add(-time.Hour)
+	cases := []struct {
+		name string
+		resp *proto.TrashCleanTaskResponse
+		kept bool
+	}{
+		{"running", &proto.TrashCleanTaskResponse{UpdateTime: &now}, true},
+		{"running without updating", &proto.TrashCleanTaskResponse{UpdateTime: &old}, false},
+		{"done just now", &proto.TrashCleanTaskResponse{Done: true, EndTime: &now}, true},
+		{"done long ago", &proto.TrashCleanTaskResponse{Done: true, EndTime: &old}, false},
+	}
+	for _, c := range cases {
+		c.resp.ID = c.name
+		ts.AddResult(c.resp)
+	}
+	ts.DeleteOldResult()
+	for _, c := range cases {
+		_, ok := ts.TaskResults[c.name]
+		assert.Equal(t, c.kept, ok, c.name)
+	}
+}
+
+func TestParseVolUpdateTrashInterval(t *testing.T) {
+	cases := []struct {
+		value string
+		want  int64
+		err   bool
+	}{
+		{"", 30, false},
+		{"0", 0, false},
+		{"1440", 1440, false},
+		{"-1", 0, true},
+		{"abc", 0, true},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/vol/update?"+volTrashIntervalKey+"="+c.value, nil)
+		req := &updateVolReq{}
+		err := parseVolUpdateReq(r, &Vol{VolType: proto.VolumeTypeHot, TrashInterval: 30}, req)
+		if c.err {
+			assert.Error(t, err, c.value)
+			continue
+		}
+		assert.NoError(t, err, c.value)
+		assert.Equal(t, c.want, req.trashInterval, c.value)
+	}
+}
@@ -34,6 +34,11 @@ type VolVarargs struct {
 	description             string
 	capacity                uint64 // GB
 	deleteLockTime          int64  // h
+	trashInterval           int64  // min
+	enableEncryption        bool
+	compression             string
+	enableDedup             bool
+	enableCDC               bool
 	followerRead            bool
 	authenticate            bool
 	dpSelectorName          string
@@ -99,6 +104,13 @@ type Vol struct {
 	createMpMutex           sync.RWMutex
 	createTime              int64
 	DeleteLockTime          int64
+	TrashInterval           int64
+	EnableEncryption        bool   // the files created are encrypted by the clients
+	Compression             string // the codec of the data compressed by the clients
+	EnableDedup             bool   // the data appended is deduplicated by the clients
+	EnableCDC               bool   // the mutations are recorded by the meta partitions
+	ECDataNum               uint8  // the data partitions are erasure coded if it is not 0, fixed once created
+	ECParityNum             uint8
 	description             string
 	dpSelectorName          string
 	dpSelectorParm          string
@@ -140,6 +152,13 @@ func newVol(vv volValue) (vol *Vol) {
 	vol.mpsCache = make([]byte, 0)
 	vol.createTime = vv.CreateTime
 	vol.DeleteLockTime = vv.DeleteLockTime
+	vol.TrashInterval = vv.TrashInterval
+	vol.EnableEncryption = vv.EnableEncryption
+	vol.Compression = vv.Compression
+	vol.EnableDedup = vv.EnableDedup
+	vol.EnableCDC = vv.EnableCDC
+	vol.ECDataNum = vv.ECDataNum
+	vol.ECParityNum = vv.ECParityNum
 	vol.description = vv.Description
 	vol.defaultPriority = vv.DefaultPriority
 	vol.domainId = vv.DomainId
@@ -410,6 +429,12 @@ func (vol *Vol) addMetaPartition(mp *MetaPartition) {
 	vol.MetaPartitions[mp.PartitionID] = mp
 }
 
+func (vol *Vol) deleteMetaPartition(partitionID uint64) {
+	vol.mpsLock.Lock()
+	defer vol.mpsLock.UnLock()
+	delete(vol.MetaPartitions, partitionID)
+}
+
 func (vol *Vol) metaPartition(partitionID uint64) (mp *MetaPartition, err error) {
 	vol.mpsLock.RLock()
 	defer vol.mpsLock.RUnlock()
@@ -420,12 +445,18 @@ func (vol *Vol) metaPartition(partitionID uint64) (mp *MetaPartition, err error)
 	return
 }
 
+// maxPartitionID returns the id of the last meta partition of the inode range. It's not always the largest
+// id as the partition created for the migration of an inode range may be in the middle of the range.
 func (vol *Vol) maxPartitionID() (maxPartitionID uint64) {
 	vol.mpsLock.RLock()
 	defer vol.mpsLock.RUnlock()
-	for id := range vol.MetaPartitions {
-		if id > maxPartitionID {
-			maxPartitionID = id
+	var maxStart uint64
+	for id, mp := range vol.MetaPartitions {
+		if mp.MigrateSrc != 0 {
+			continue
+		}
+		if maxPartitionID == 0 || mp.Start > maxStart {
+			maxPartitionID, maxStart = id, mp.Start
 		}
 	}
 	return
@@ -675,6 +706,7 @@ func (vol *Vol) checkMetaPartitions(c *Cluster) {
 	}
 	c.addMetaNodeTasks(tasks)
 	vol.checkSplitMetaPartition(c, metaPartitionInodeIdStep)
+	vol.checkMetaRangeMigration(c)
 }
 
 func (vol *Vol) checkSplitMetaPartition(c *Cluster, metaPartitionInodeStep uint64) {
@@ -1009,6 +1041,9 @@ func (vol *Vol) ebsUsedSpace() uint64 {
 func (vol *Vol) updateViewCache(c *Cluster) {
 	view := proto.NewVolView(vol.Name, vol.Status, vol.FollowerRead, vol.createTime, vol.CacheTTL, vol.VolType, vol.DeleteLockTime)
 	view.SetOwner(vol.Owner)
+	view.TrashInterval = vol.TrashInterval
+	view.EnableEncryption = vol.EnableEncryption
+	view.EnablePosixAcl = vol.enablePosixAcl
 	view.SetOSSSecure(vol.OSSAccessKey, vol.OSSSecretKey)
 	mpViews := vol.getMetaPartitionsView()
 	view.MetaPartitions = mpViews
@@ -1041,6 +1076,10 @@ func (vol *Vol) getMetaPartitionsView() (mpViews []*proto.MetaPartitionView) {
 
 	mpViews = make([]*proto.MetaPartitionView, 0)
 	for _, mp := range mps {
+		// the target of an ongoing range migration is hidden from the clients until the cutover
+		if mp.MigrateSrc != 0 {
+			continue
+		}
 		mpViews = append(mpViews, getMetaPartitionView(mp))
 	}
 	return
@@ -1330,6 +1369,11 @@ func (vol *Vol) splitMetaPartition(c *Cluster, mp *MetaPartition, end uint64, me
 	vol.createMpMutex.Lock()
 	defer vol.createMpMutex.Unlock()
 
+	if src := vol.metaRangeMigration(); src != nil {
+		err = fmt.Errorf("mp[%v] is migrating %v", src.PartitionID, src.Migration)
+		return
+	}
+
 	maxPartitionID := vol.maxPartitionID()
 	if maxPartitionID != mp.PartitionID {
 		err = fmt.Errorf("mp[%v] is not the last meta partition[%v]", mp.PartitionID, maxPartitionID)
@@ -1450,6 +1494,11 @@ func setVolFromArgs(args *VolVarargs, vol *Vol) {
 	vol.zoneName = args.zoneName
 	vol.Capacity = args.capacity
 	vol.DeleteLockTime = args.deleteLockTime
+	vol.TrashInterval = args.trashInterval
+	vol.EnableEncryption = args.enableEncryption
+	vol.Compression = args.compression
+	vol.EnableDedup = args.enableDedup
+	vol.EnableCDC = args.enableCDC
 	vol.FollowerRead = args.followerRead
 	vol.authenticate = args.authenticate
 	vol.enablePosixAcl = args.enablePosixAcl
@@ -1500,6 +1549,11 @@ func getVolVarargs(vol *Vol) *VolVarargs {
 		description:             vol.description,
 		capacity:                vol.Capacity,
 		deleteLockTime:          vol.DeleteLockTime,
+		trashInterval:           vol.TrashInterval,
+		enableEncryption:        vol.EnableEncryption,
+		compression:             vol.Compression,
+		enableDedup:             vol.EnableDedup,
+		enableCDC:               vol.EnableCDC,
 		followerRead:            vol.FollowerRead,
 		authenticate:            vol.authenticate,
 		dpSelectorName:          vol.dpSelectorName,
@@ -417,3 +417,26 @@ func TestConcurrentReadWriteDataPartitionMap(t *testing.T) {
 		vol.updateViewCache(server.cluster)
 	}
 }
+
+func TestVolMaxPartitionIDWithMigration(t *testing.T) {
+	vol := newVol(volValue{ID: 1, Name: "TestVolMaxPartitionIDWithMigration", ReplicaNum: defaultReplicaNum})
+	vol.addMetaPartition(newMetaPartition(1, 1, 1000, defaultReplicaNum, vol.Name, vol.ID, 0))
+	vol.addMetaPartition(newMetaPartition(2, 1001, defaultMaxMetaPartitionInodeID, defaultReplicaNum, vol.Name, vol.ID, 0))
+	assert.EqualValues(t, 2, vol.maxPartitionID())
+
+	// the target of the migration is hidden until the cutover
+	target := newMetaPartition(3, 500, 1000, defaultReplicaNum, vol.Name, vol.ID, 0)
+	target.MigrateSrc = 1
+	vol.addMetaPartition(target)
+	assert.EqualValues(t, 2, vol.maxPartitionID())
+	assert.Len(t, vol.getMetaPartitionsView(), 2)
+
+	// the partition in the middle of the range is never the last one
+	target.MigrateSrc = 0
+	assert.EqualValues(t, 2, vol.maxPartitionID())
+	assert.Len(t, vol.getMetaPartitionsView(), 3)
+
+	vol.deleteMetaPartition(3)
+	_, err := vol.metaPartition(3)
+	assert.Error(t, err)
+}
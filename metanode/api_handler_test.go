@@ -74,9 +74,10 @@ func createMetaPartition(rootDir string, t *testing.T) (mp *metaPartition) {
 	require.NotNil(t, snap)
 	defer snap.Close()
 	msg := &storeMsg{
-		command:     1,
-		snap:        snap,
-		uniqChecker: newUniqChecker(),
+		command:       1,
+		snap:          snap,
+		uniqChecker:   newUniqChecker(),
+		sharedExtents: newSharedExtents(),
 	}
 	mp.uidManager = NewUidMgr(mpC.VolName, mpC.PartitionId)
 	mp.mqMgr = NewQuotaManager(mpC.VolName, mpC.PartitionId)
@@ -38,6 +38,7 @@ const (
 	TransactionRollbackDentryType
 	DeletedExtentsType
 	DeletedObjExtentsType
+	FingerprintType
 	MaxType
 )
 
@@ -61,6 +62,8 @@ func (t TreeType) String() string {
 		return "deleted extents"
 	case DeletedObjExtentsType:
 		return "deleted obj extents"
+	case FingerprintType:
+		return "fingerprint tree"
 	default:
 		return "unknown"
 	}
@@ -85,6 +88,7 @@ func NewSnapshot(mp *metaPartition) (snap Snapshot, err error) {
 			transactionRbDentry: &TransactionRollbackDentryBTree{mp.txProcessor.txResource.txRbDentryTree.(*TransactionRollbackDentryBTree).GetTree()},
 			deletedExtents:      &DeletedExtentsBTree{mp.deletedExtentsTree.(*DeletedExtentsBTree).GetTree()},
 			deletedObjExtents:   &DeletedObjExtentsBTree{mp.deletedObjExtentsTree.(*DeletedObjExtentsBTree).GetTree()},
+			fingerprint:         &FingerprintBTree{mp.fingerprintTree.(*FingerprintBTree).GetTree()},
 			deletedExtentId:     mp.GetDeletedExtentId(),
 		}
 	}
@@ -264,3 +268,14 @@ type DeletedObjExtentsTree interface {
 	Count() uint64
 	Len() int
 }
+
+type FingerprintTree interface {
+	Tree
+	Get(hash []byte) (*Fingerprint, error)
+	Put(dbHandle interface{}, fp *Fingerprint) error
+	Delete(dbHandle interface{}, fp *Fingerprint) (bool, error)
+	Range(start, end *Fingerprint, cb func(fp *Fingerprint) (bool, error)) error
+	RealCount() uint64
+	Count() uint64
+	Len() int
+}
@@ -43,6 +43,7 @@ type MemSnapShot struct {
 	transactionRbDentry *TransactionRollbackDentryBTree
 	deletedExtents      *DeletedExtentsBTree
 	deletedObjExtents   *DeletedObjExtentsBTree
+	fingerprint         *FingerprintBTree
 	txID                uint64
 	deletedExtentId     uint64
 }
@@ -164,6 +165,18 @@ func (b *MemSnapShot) RangeWithScope(tp TreeType, start, end interface{}, cb fun
 			endDoek = start.(*DeletedObjExtentKey)
 		}
 		return b.deletedObjExtents.Range(startDoek, endDoek, callbackFunc)
+	case FingerprintType:
+		callbackFunc := func(fp *Fingerprint) (bool, error) {
+			return cb(fp)
+		}
+		var startFp, endFp *Fingerprint
+		if start != nil {
+			startFp = start.(*Fingerprint)
+		}
+		if end != nil {
+			endFp = end.(*Fingerprint)
+		}
+		return b.fingerprint.Range(startFp, endFp, callbackFunc)
 	default:
 	}
 	panic("out of type")
@@ -191,6 +204,8 @@ func (b *MemSnapShot) Count(tp TreeType) uint64 {
 		return b.deletedExtents.Count()
 	case DeletedObjExtentsType:
 		return b.deletedObjExtents.Count()
+	case FingerprintType:
+		return b.fingerprint.Count()
 	default:
 	}
 	panic("out of type")
@@ -302,6 +317,17 @@ func (b *MemSnapShot) CrcSum(tp TreeType) (crcSum uint32, err error) {
 			return true, nil
 		}
 		err = b.deletedObjExtents.Range(nil, nil, cb)
+	case FingerprintType:
+		cb := func(fp *Fingerprint) (bool, error) {
+			if data, err = fp.Marshal(); err != nil {
+				return false, err
+			}
+			if _, err = crc.Write(data); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+		err = b.fingerprint.Range(nil, nil, cb)
 	default:
 		panic("out of type")
 	}
@@ -332,6 +358,7 @@ var _ TransactionTree = &TransactionBTree{}
 var _ TransactionRollbackInodeTree = &TransactionRollbackInodeBTree{}
 var _ TransactionRollbackDentryTree = &TransactionRollbackDentryBTree{}
 var _ DeletedExtentsTree = &DeletedExtentsBTree{}
+var _ FingerprintTree = &FingerprintBTree{}
 
 type InodeBTree struct {
 	*BTree
@@ -369,6 +396,10 @@ type DeletedObjExtentsBTree struct {
 	*BTree
 }
 
+type FingerprintBTree struct {
+	*BTree
+}
+
 func (i *InodeBTree) GetMaxInode() (uint64, error) {
 	i.Lock()
 	item := i.tree.Max()
@@ -428,6 +459,18 @@ func (i *ExtendBTree) Get(ino uint64) (*Extend, error) {
 	return nil, nil
 }
 
+func (i *FingerprintBTree) Get(hash []byte) (*Fingerprint, error) {
+	key, err := NewFingerprint(hash)
+	if err != nil {
+		return nil, err
+	}
+	item := i.BTree.CopyGet(key)
+	if item != nil {
+		return item.(*Fingerprint), nil
+	}
+	return nil, nil
+}
+
 func (i *MultipartBTree) RefGet(key, id string) (*Multipart, error) {
 	item := i.BTree.Get(&Multipart{key: key, id: id})
 	if item != nil {
@@ -555,6 +598,11 @@ func (i *DeletedObjExtentsBTree) Put(dbHandle interface{}, doek *DeletedObjExten
 	return nil
 }
 
+func (i *FingerprintBTree) Put(dbHandle interface{}, fp *Fingerprint) error {
+	i.BTree.ReplaceOrInsert(fp, true)
+	return nil
+}
+
 func (i *TransactionRollbackInodeBTree) Update(dbHandle interface{}, inode *TxRollbackInode) error {
 	i.BTree.ReplaceOrInsert(inode, false)
 	return nil
@@ -687,6 +735,13 @@ func (i *DeletedObjExtentsBTree) Delete(dbHandle interface{}, doek *DeletedObjEx
 	return true, nil
 }
 
+func (i *FingerprintBTree) Delete(dbHandle interface{}, fp *Fingerprint) (bool, error) {
+	if old := i.BTree.Delete(fp); old == nil {
+		return false, nil
+	}
+	return true, nil
+}
+
 // range
 func (i *InodeBTree) Range(start, end *Inode, cb func(i *Inode) (bool, error)) error {
 	var (
@@ -934,6 +989,30 @@ func (i *DeletedObjExtentsBTree) RangeWithPrefix(prefix, start, end *DeletedObjE
 	return i.Range(start, end, cb)
 }
 
+func (i *FingerprintBTree) Range(start, end *Fingerprint, cb func(fp *Fingerprint) (bool, error)) error {
+	var (
+		err  error
+		next bool
+	)
+	callback := func(i BtreeItem) bool {
+		next, err = cb(i.(*Fingerprint))
+		if err != nil {
+			return false
+		}
+		return next
+	}
+	if start == nil {
+		start = &Fingerprint{}
+	}
+
+	if end == nil {
+		i.BTree.AscendGreaterOrEqual(start, callback)
+	} else {
+		i.BTree.AscendRange(start, end, callback)
+	}
+	return err
+}
+
 // MaxItem returns the largest item in the btree.
 func (i *InodeBTree) MaxItem() *Inode {
 	i.RLock()
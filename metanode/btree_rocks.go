@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"hash/crc32"
+	"io"
 	"sync"
 	"sync/atomic"
 	"unsafe"
@@ -43,6 +44,7 @@ type RocksBaseInfo struct {
 	deletedExtentId      uint64
 	deletedExtentsCnt    uint64
 	deletedObjExtentsCnt uint64
+	fingerprintCnt       uint64
 }
 
 func (info *RocksBaseInfo) Marshal() (result []byte, err error) {
@@ -93,6 +95,9 @@ func (info *RocksBaseInfo) Marshal() (result []byte, err error) {
 	if err = binary.Write(buff, binary.BigEndian, atomic.LoadUint64(&info.deletedObjExtentsCnt)); err != nil {
 		panic(err)
 	}
+	if err = binary.Write(buff, binary.BigEndian, atomic.LoadUint64(&info.fingerprintCnt)); err != nil {
+		panic(err)
+	}
 	return buff.Bytes(), nil
 }
 
@@ -143,6 +148,9 @@ func (info *RocksBaseInfo) MarshalWithoutApplyID() (result []byte, err error) {
 	if err = binary.Write(buff, binary.BigEndian, atomic.LoadUint64(&info.deletedObjExtentsCnt)); err != nil {
 		panic(err)
 	}
+	if err = binary.Write(buff, binary.BigEndian, atomic.LoadUint64(&info.fingerprintCnt)); err != nil {
+		panic(err)
+	}
 	return buff.Bytes(), nil
 }
 
@@ -195,6 +203,10 @@ func (info *RocksBaseInfo) Unmarshal(raw []byte) (err error) {
 	if err = binary.Read(buff, binary.BigEndian, &info.deletedObjExtentsCnt); err != nil {
 		return
 	}
+	// the base info written before the fingerprint tree has no count of it
+	if err = binary.Read(buff, binary.BigEndian, &info.fingerprintCnt); err == io.EOF {
+		err = nil
+	}
 	return
 }
 
@@ -679,6 +691,7 @@ var _ TransactionRollbackInodeTree = &TransactionRollbackInodeRocks{}
 var _ TransactionRollbackDentryTree = &TransactionRollbackDentryRocks{}
 var _ DeletedExtentsTree = &DeletedExtentsRocks{}
 var _ DeletedObjExtentsTree = &DeletedObjExtentsRocks{}
+var _ FingerprintTree = &FingerprintRocks{}
 
 func NewInodeRocks(tree *RocksTree) (*InodeRocks, error) {
 	return &InodeRocks{
@@ -770,6 +783,16 @@ func NewDeletedObjExtentsRocks(tree *RocksTree) (*DeletedObjExtentsRocks, error)
 	}, nil
 }
 
+type FingerprintRocks struct {
+	*RocksTree
+}
+
+func NewFingerprintRocks(tree *RocksTree) (*FingerprintRocks, error) {
+	return &FingerprintRocks{
+		RocksTree: tree,
+	}, nil
+}
+
 func inodeEncodingKey(ino uint64) []byte {
 	buff := new(bytes.Buffer)
 	buff.WriteByte(byte(InodeTable))
@@ -898,6 +921,13 @@ func deletedObjExtentsEncodingPrefix(inode uint64) []byte {
 	return buff.Bytes()
 }
 
+func fingerprintEncodingKey(hash []byte) []byte {
+	buff := &bytes.Buffer{}
+	buff.WriteByte(byte(FingerprintTable))
+	buff.Write(hash)
+	return buff.Bytes()
+}
+
 func (b *InodeRocks) GetMaxInode() (uint64, error) {
 	snapshot := b.RocksTree.OpenSnap()
 	if snapshot == nil {
@@ -956,6 +986,10 @@ func (b *DeletedObjExtentsRocks) Count() uint64 {
 	return atomic.LoadUint64(&b.baseInfo.deletedObjExtentsCnt)
 }
 
+func (b *FingerprintRocks) Count() uint64 {
+	return atomic.LoadUint64(&b.baseInfo.fingerprintCnt)
+}
+
 func (b *InodeRocks) Len() int {
 	return int(b.Count())
 }
@@ -992,6 +1026,10 @@ func (b *DeletedObjExtentsRocks) Len() int {
 	return int(b.Count())
 }
 
+func (b *FingerprintRocks) Len() int {
+	return int(b.Count())
+}
+
 // real count by type
 func (b *InodeRocks) RealCount() uint64 {
 	return b.IteratorCount(InodeTable)
@@ -1029,6 +1067,10 @@ func (b *DeletedObjExtentsRocks) RealCount() uint64 {
 	return b.IteratorCount(DeletedObjExtentsTable)
 }
 
+func (b *FingerprintRocks) RealCount() uint64 {
+	return b.IteratorCount(FingerprintTable)
+}
+
 // Get
 func (b *InodeRocks) RefGet(ino uint64) (*Inode, error) {
 	return b.Get(ino)
@@ -1057,6 +1099,28 @@ func (b *InodeRocks) Get(ino uint64) (inode *Inode, err error) {
 	return
 }
 
+func (b *FingerprintRocks) Get(hash []byte) (fp *Fingerprint, err error) {
+	defer func() {
+		if err != nil {
+			log.LogErrorf("[FingerprintRocks] Get failed, hash: %x, error: %v", hash, err)
+		}
+	}()
+
+	var bs []byte
+	bs, err = b.RocksTree.GetBytes(fingerprintEncodingKey(hash))
+	if err != nil {
+		return
+	}
+	if len(bs) == 0 {
+		return
+	}
+	fp = &Fingerprint{}
+	if err = fp.Unmarshal(bs); err != nil {
+		return
+	}
+	return
+}
+
 func (b *DentryRocks) RefGet(ino uint64, name string) (*Dentry, error) {
 	return b.Get(ino, name)
 }
@@ -1331,6 +1395,20 @@ func (b *DeletedObjExtentsRocks) Put(dbHandle interface{}, doek *DeletedObjExten
 	return
 }
 
+func (b *FingerprintRocks) Put(dbHandle interface{}, fp *Fingerprint) (err error) {
+	var bs []byte
+	if bs, err = fp.Marshal(); err != nil {
+		log.LogErrorf("FingerprintRocks fingerprint marshal failed, fp: %v, error: %v", fp, err)
+		return
+	}
+	err = b.RocksTree.Put(dbHandle, &b.baseInfo.fingerprintCnt, fingerprintEncodingKey(fp.Hash[:]), bs)
+	if err != nil {
+		log.LogErrorf("FingerprintRocks fingerprint put failed, fp: %v, error: %v", fp, err)
+		return
+	}
+	return
+}
+
 // update
 func (b *InodeRocks) Update(dbHandle interface{}, inode *Inode) (err error) {
 	var bs []byte
@@ -1673,6 +1751,10 @@ func (b *DeletedObjExtentsRocks) Delete(dbHandle interface{}, doek *DeletedObjEx
 	return b.RocksTree.Delete(dbHandle, &b.baseInfo.deletedObjExtentsCnt, deletedObjExtentsEncodingKey(doek.Inode, doek.ObjExtentKey.Cid, doek.DeletedExtentId))
 }
 
+func (b *FingerprintRocks) Delete(dbHandle interface{}, fp *Fingerprint) (bool, error) {
+	return b.RocksTree.Delete(dbHandle, &b.baseInfo.fingerprintCnt, fingerprintEncodingKey(fp.Hash[:]))
+}
+
 // Range begin
 // Range , if end is nil , it will range all of this type , it range not include end
 func (b *InodeRocks) Range(start, end *Inode, cb func(i *Inode) (bool, error)) error {
@@ -1997,6 +2079,25 @@ func (b *DeletedObjExtentsRocks) RangeWithPrefix(prefix, start, end *DeletedObjE
 	return b.RocksTree.RangeWithPrefix(prefixByte, startByte, endByte, callback)
 }
 
+func (b *FingerprintRocks) Range(start, end *Fingerprint, cb func(fp *Fingerprint) (bool, error)) error {
+	startByte := []byte{byte(FingerprintTable)}
+	endByte := []byte{byte(FingerprintTable) + 1}
+	if end != nil {
+		endByte = fingerprintEncodingKey(end.Hash[:])
+	}
+	if start != nil {
+		startByte = fingerprintEncodingKey(start.Hash[:])
+	}
+	callback := func(v []byte) (bool, error) {
+		fp := &Fingerprint{}
+		if err := fp.Unmarshal(v); err != nil {
+			return false, err
+		}
+		return cb(fp)
+	}
+	return b.RocksTree.Range(startByte, endByte, callback)
+}
+
 func (b *InodeRocks) MaxItem() *Inode {
 	var maxItem *Inode
 	snapshot := b.RocksTree.OpenSnap()
@@ -2065,6 +2166,11 @@ func (b *DeletedObjExtentsRocks) Clear(handle interface{}) (err error) {
 	return
 }
 
+func (b *FingerprintRocks) Clear(handle interface{}) (err error) {
+	err = b.DelRangeToBatch(handle, []byte{byte(FingerprintTable)}, []byte{byte(FingerprintTable + 1)})
+	return
+}
+
 var _ Snapshot = &RocksSnapShot{}
 
 type RocksSnapShot struct {
@@ -2125,6 +2231,8 @@ func (r *RocksSnapShot) Count(tp TreeType) uint64 {
 		count = r.baseInfo.deletedExtentsCnt
 	case DeletedObjExtentsType:
 		count = r.baseInfo.deletedObjExtentsCnt
+	case FingerprintType:
+		count = r.baseInfo.fingerprintCnt
 	}
 	return count
 }
@@ -2187,6 +2295,12 @@ func (r *RocksSnapShot) RangeWithScope(tp TreeType, start, end interface{}, cb f
 				return false, err
 			}
 			return cb(doek)
+		case FingerprintType:
+			fp := &Fingerprint{}
+			if err := fp.Unmarshal(v); err != nil {
+				return false, err
+			}
+			return cb(fp)
 		default:
 			return false, fmt.Errorf("error type")
 		}
@@ -2222,6 +2336,9 @@ func (r *RocksSnapShot) RangeWithScope(tp TreeType, start, end interface{}, cb f
 		case DeletedObjExtentsType:
 			doek := item.(*DeletedObjExtentKey)
 			return doek.Marshal()
+		case FingerprintType:
+			fp := item.(*Fingerprint)
+			return fingerprintEncodingKey(fp.Hash[:]), nil
 		default:
 			return nil, fmt.Errorf("error type")
 		}
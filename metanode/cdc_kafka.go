@@ -0,0 +1,117 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const (
+	cdcPublishInterval   = time.Second
+	cdcPublishBatchLimit = 1000
+	cdcPublishMaxBatches = 10 // per partition every interval, so a busy partition does not starve the others
+)
+
+// startCDCPublisher publishes the change events of the partitions led by the node to Kafka, if the
+// producer is configured. Every replica keeps the cursor published by itself, so the events are
+// published at least once and a new leader may publish the events published by the old one again.
+// The events are keyed by the partition, so the ones of a partition are kept in order by Kafka.
+func (m *metadataManager) startCDCPublisher() {
+	if m.metaNode == nil || m.metaNode.cdcKafka == nil {
+		return
+	}
+	conf := m.metaNode.cdcKafka
+	go func() {
+		var producer sarama.SyncProducer
+		ticker := time.NewTicker(cdcPublishInterval)
+		defer func() {
+			ticker.Stop()
+			if producer != nil {
+				producer.Close()
+			}
+		}()
+		for {
+			select {
+			case <-m.stopC:
+				log.LogInfof("[startCDCPublisher] cdc publisher exit")
+				return
+			case <-ticker.C:
+			}
+			if producer == nil {
+				var err error
+				if producer, err = conf.BuildSyncProducer(); err != nil {
+					log.LogErrorf("[startCDCPublisher] build kafka producer failed: %v", err)
+					continue
+				}
+			}
+			m.mu.RLock()
+			partitions := make([]*metaPartition, 0, len(m.partitions))
+			for _, p := range m.partitions {
+				if mp, ok := p.(*metaPartition); ok {
+					partitions = append(partitions, mp)
+				}
+			}
+			m.mu.RUnlock()
+			for _, mp := range partitions {
+				if _, ok := mp.IsLeader(); !ok {
+					continue
+				}
+				if err := mp.publishCDC(producer, conf.Topic); err != nil {
+					log.LogWarnf("[startCDCPublisher] mp(%v) publish events failed: %v", mp.config.PartitionId, err)
+				}
+			}
+		}
+	}()
+}
+
+// publishCDC sends the events recorded after the cursor published last time.
+func (mp *metaPartition) publishCDC(producer sarama.SyncProducer, topic string) (err error) {
+	cl, err := mp.getCDCLog(false)
+	if err != nil || cl == nil {
+		return
+	}
+	cursor, err := cl.loadKafkaCursor()
+	if err != nil {
+		return
+	}
+	key := sarama.StringEncoder(fmt.Sprintf("%v/%v", mp.config.VolName, mp.config.PartitionId))
+	for i := 0; i < cdcPublishMaxBatches; i++ {
+		resp, err := cl.read(cursor, cdcPublishBatchLimit)
+		if err != nil || len(resp.Events) == 0 {
+			return err
+		}
+		msgs := make([]*sarama.ProducerMessage, 0, len(resp.Events))
+		for _, event := range resp.Events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, &sarama.ProducerMessage{Topic: topic, Key: key, Value: sarama.ByteEncoder(data)})
+		}
+		if err = producer.SendMessages(msgs); err != nil {
+			return err
+		}
+		if err = cl.storeKafkaCursor(resp.Next); err != nil {
+			return err
+		}
+		cursor = resp.Next
+	}
+	return
+}
@@ -0,0 +1,280 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util"
+)
+
+const (
+	cdcDirName            = "cdc"
+	cdcSegmentPrefix      = "segment_"
+	cdcKafkaCursorFile    = "kafka_cursor"
+	defaultCDCSegmentSize = 16 * util.MB
+	defaultCDCRetainSize  = 256 * util.MB
+)
+
+type cdcSegment struct {
+	first uint64 // the ApplyID of the first event of the segment
+	name  string
+	size  int64
+}
+
+// cdcLog is the append-only log of the change events of a meta partition. The events are appended
+// by the raft apply as json lines to the segments named by their first ApplyID, and the oldest
+// segments are removed once the size of the log exceeds the retention. The events replayed by raft
+// after a restart are skipped by the ApplyID, so an event is never recorded twice.
+type cdcLog struct {
+	sync.RWMutex
+	dir         string
+	segmentSize int64
+	retainSize  int64
+	segments    []*cdcSegment
+	tail        *os.File
+	last        uint64 // the ApplyID of the newest event
+}
+
+func openCDCLog(dir string, segmentSize, retainSize int64) (l *cdcLog, err error) {
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	l = &cdcLog{
+		dir:         dir,
+		segmentSize: segmentSize,
+		retainSize:  retainSize,
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), cdcSegmentPrefix) {
+			continue
+		}
+		first, parseErr := strconv.ParseUint(strings.TrimPrefix(entry.Name(), cdcSegmentPrefix), 10, 64)
+		if parseErr != nil {
+			continue
+		}
+		l.segments = append(l.segments, &cdcSegment{first: first, name: entry.Name()})
+	}
+	sort.Slice(l.segments, func(i, j int) bool { return l.segments[i].first < l.segments[j].first })
+	for _, seg := range l.segments {
+		var info os.FileInfo
+		if info, err = os.Stat(path.Join(dir, seg.name)); err != nil {
+			return
+		}
+		seg.size = info.Size()
+	}
+	if len(l.segments) == 0 {
+		return
+	}
+	if err = l.recoverTail(); err != nil {
+		return
+	}
+	return
+}
+
+// recoverTail drops the event torn by a crash at the end of the newest segment, and loads the
+// ApplyID of the newest event.
+func (l *cdcLog) recoverTail() (err error) {
+	seg := l.segments[len(l.segments)-1]
+	name := path.Join(l.dir, seg.name)
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return
+	}
+	valid := bytes.LastIndexByte(data, '\n') + 1
+	if int64(valid) != seg.size {
+		if err = os.Truncate(name, int64(valid)); err != nil {
+			return
+		}
+		seg.size = int64(valid)
+	}
+	l.last = seg.first - 1
+	if valid > 0 {
+		lineStart := bytes.LastIndexByte(data[:valid-1], '\n') + 1
+		event := &proto.CDCEvent{}
+		if err = json.Unmarshal(data[lineStart:valid], event); err != nil {
+			return fmt.Errorf("invalid event of cdc segment %v: %v", name, err)
+		}
+		l.last = event.ApplyID
+	}
+	l.tail, err = os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0o644)
+	return
+}
+
+func (l *cdcLog) close() {
+	l.Lock()
+	defer l.Unlock()
+	if l.tail != nil {
+		l.tail.Close()
+		l.tail = nil
+	}
+}
+
+// append records the events of the raft entry of applyID. The entries applied again after a
+// restart are skipped.
+func (l *cdcLog) append(applyID uint64, events []*proto.CDCEvent) (err error) {
+	l.Lock()
+	defer l.Unlock()
+	if applyID <= l.last {
+		return
+	}
+	buf := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(buf)
+	for _, event := range events {
+		if err = encoder.Encode(event); err != nil {
+			return
+		}
+	}
+	if l.tail == nil || l.segments[len(l.segments)-1].size >= l.segmentSize {
+		if err = l.roll(applyID); err != nil {
+			return
+		}
+	}
+	seg := l.segments[len(l.segments)-1]
+	n, err := l.tail.Write(buf.Bytes())
+	seg.size += int64(n)
+	if err != nil {
+		return
+	}
+	l.last = applyID
+	return
+}
+
+// roll starts a new segment from applyID and removes the oldest segments beyond the retention.
+func (l *cdcLog) roll(applyID uint64) (err error) {
+	seg := &cdcSegment{first: applyID, name: fmt.Sprintf("%v%020d", cdcSegmentPrefix, applyID)}
+	file, err := os.OpenFile(path.Join(l.dir, seg.name), os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return
+	}
+	if l.tail != nil {
+		l.tail.Close()
+	}
+	l.tail = file
+	l.segments = append(l.segments, seg)
+
+	var total int64
+	for _, s := range l.segments {
+		total += s.size
+	}
+	for len(l.segments) > 1 && total > l.retainSize {
+		oldest := l.segments[0]
+		if err = os.Remove(path.Join(l.dir, oldest.name)); err != nil && !os.IsNotExist(err) {
+			return
+		}
+		err = nil
+		total -= oldest.size
+		l.segments = l.segments[1:]
+	}
+	return
+}
+
+// read returns up to limit events after the cursor from, except that the events of an ApplyID are
+// never split. The segments are read without the lock, so the raft apply is not blocked by the readers.
+func (l *cdcLog) read(from uint64, limit int) (resp *proto.CDCReadResponse, err error) {
+	l.RLock()
+	segments := make([]cdcSegment, 0, len(l.segments))
+	for _, seg := range l.segments {
+		segments = append(segments, *seg)
+	}
+	resp = &proto.CDCReadResponse{Next: from, Last: l.last}
+	l.RUnlock()
+
+	if len(segments) == 0 {
+		return
+	}
+	resp.Oldest = segments[0].first
+	start := sort.Search(len(segments), func(i int) bool { return segments[i].first > from+1 }) - 1
+	if start < 0 {
+		start = 0
+	}
+	for _, seg := range segments[start:] {
+		var done bool
+		if done, err = l.readSegment(seg, from, limit, resp); err != nil || done {
+			return
+		}
+	}
+	return
+}
+
+func (l *cdcLog) readSegment(seg cdcSegment, from uint64, limit int, resp *proto.CDCReadResponse) (done bool, err error) {
+	file, err := os.Open(path.Join(l.dir, seg.name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// removed by the retention after the segments were listed
+			err = nil
+		}
+		return
+	}
+	defer file.Close()
+	reader := bufio.NewReader(io.LimitReader(file, seg.size))
+	for {
+		var line []byte
+		if line, err = reader.ReadBytes('\n'); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return
+		}
+		event := &proto.CDCEvent{}
+		if err = json.Unmarshal(line, event); err != nil {
+			return
+		}
+		if event.ApplyID <= from {
+			continue
+		}
+		if len(resp.Events) >= limit && event.ApplyID != resp.Next {
+			done = true
+			return
+		}
+		resp.Events = append(resp.Events, event)
+		resp.Next = event.ApplyID
+	}
+}
+
+func (l *cdcLog) loadKafkaCursor() (cursor uint64, err error) {
+	data, err := os.ReadFile(path.Join(l.dir, cdcKafkaCursorFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func (l *cdcLog) storeKafkaCursor(cursor uint64) (err error) {
+	name := path.Join(l.dir, cdcKafkaCursorFile)
+	tmpName := name + ".tmp"
+	if err = os.WriteFile(tmpName, []byte(strconv.FormatUint(cursor, 10)), 0o644); err != nil {
+		return
+	}
+	return os.Rename(tmpName, name)
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCDCEvents(applyID uint64, n int) (events []*proto.CDCEvent) {
+	for i := 0; i < n; i++ {
+		events = append(events, &proto.CDCEvent{ApplyID: applyID, Op: proto.CDCCreateInode, Inode: applyID*10 + uint64(i)})
+	}
+	return
+}
+
+func TestCDCLogReadAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openCDCLog(dir, 1024, 1024*1024)
+	require.NoError(t, err)
+	for applyID := uint64(1); applyID <= 20; applyID++ {
+		n := 1
+		if applyID == 5 {
+			n = 3
+		}
+		require.NoError(t, l.append(applyID, newTestCDCEvents(applyID, n)))
+	}
+	require.True(t, len(l.segments) > 1)
+
+	// the events of an ApplyID are not split
+	resp, err := l.read(2, 3)
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 5)
+	require.Equal(t, uint64(5), resp.Next)
+	require.Equal(t, uint64(1), resp.Oldest)
+	require.Equal(t, uint64(20), resp.Last)
+
+	resp, err = l.read(resp.Next, 100)
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 15)
+	require.Equal(t, uint64(6), resp.Events[0].ApplyID)
+	require.Equal(t, uint64(20), resp.Next)
+
+	resp, err = l.read(20, 100)
+	require.NoError(t, err)
+	require.Empty(t, resp.Events)
+	require.Equal(t, uint64(20), resp.Next)
+
+	// an event torn by a crash is dropped, and the entries replayed are skipped
+	tail := l.segments[len(l.segments)-1]
+	l.close()
+	file, err := os.OpenFile(path.Join(dir, tail.name), os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = file.Write([]byte(`{"applyId":21,"op"`))
+	require.NoError(t, err)
+	file.Close()
+
+	l, err = openCDCLog(dir, 1024, 1024*1024)
+	require.NoError(t, err)
+	defer l.close()
+	require.Equal(t, uint64(20), l.last)
+	require.NoError(t, l.append(19, newTestCDCEvents(19, 1)))
+	require.NoError(t, l.append(21, newTestCDCEvents(21, 1)))
+	resp, err = l.read(18, 100)
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 3)
+	require.Equal(t, uint64(19), resp.Events[0].ApplyID)
+	require.Equal(t, uint64(21), resp.Next)
+}
+
+func TestCDCLogRetention(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openCDCLog(dir, 256, 1024)
+	require.NoError(t, err)
+	defer l.close()
+	for applyID := uint64(1); applyID <= 100; applyID++ {
+		require.NoError(t, l.append(applyID, newTestCDCEvents(applyID, 1)))
+	}
+	var total int64
+	for _, seg := range l.segments {
+		total += seg.size
+	}
+	require.True(t, total <= 1024+256)
+
+	resp, err := l.read(0, 1000)
+	require.NoError(t, err)
+	require.True(t, resp.Oldest > 1)
+	require.Equal(t, resp.Oldest, resp.Events[0].ApplyID)
+	require.Equal(t, uint64(100), resp.Next)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, len(l.segments))
+}
+
+func TestCDCEvents(t *testing.T) {
+	mp := &metaPartition{config: &MetaPartitionConfig{PartitionId: 1, VolName: "vol"}}
+	den := &Dentry{ParentId: 1, Name: "a", Inode: 100, Type: FileModeType}
+	data, err := den.Marshal()
+	require.NoError(t, err)
+	msg := &MetaItem{Op: opFSMCreateDentry, V: data}
+
+	events := mp.cdcEvents(msg, proto.OpOk)
+	require.Len(t, events, 1)
+	require.Equal(t, proto.CDCCreateDentry, events[0].Op)
+	require.Equal(t, uint64(1), events[0].ParentId)
+	require.Equal(t, "a", events[0].Name)
+	require.Equal(t, uint64(100), events[0].Inode)
+	require.Empty(t, mp.cdcEvents(msg, proto.OpExistErr))
+
+	// the dentry of the response has the inode replaced
+	msg.Op = opFSMUpdateDentry
+	events = mp.cdcEvents(msg, &DentryResponse{Status: proto.OpOk, Msg: &Dentry{ParentId: 1, Name: "a", Inode: 99}})
+	require.Len(t, events, 1)
+	require.Equal(t, proto.CDCUpdateDentry, events[0].Op)
+	require.Equal(t, uint64(100), events[0].Inode)
+	require.Equal(t, uint64(99), events[0].OldInode)
+}
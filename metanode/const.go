@@ -195,6 +195,35 @@ const (
 	opFSMDeleteObjExtentFromTree = 77
 	opFSMDeletedExtentsSnap      = 78
 	opFSMDeletedObjExtentsSnap   = 79
+
+	// NOTE: fallocate and copy_file_range
+	opFSMFallocate            = 80
+	opFSMCloneExtents         = 81
+	opFSMReleaseSharedExtents = 82
+	opFSMSharedExtentsSnap    = 83
+
+	// NOTE: deduplication
+	opFSMDedupExtents    = 84
+	opFSMAddFingerprints = 85
+	opFSMFingerprintSnap = 86
+
+	// NOTE: writable clones of snapshots
+	opFSMCloneSnapshotInode = 87
+
+	// NOTE: tiering to the blobstore
+	opFSMTransitionExtents = 88
+	opFSMRestoreExtents    = 89
+
+	// NOTE: migration of the inode ranges
+	opFSMFreezeMetaRange  = 90
+	opFSMSyncMetaRange    = 91
+	opFSMCutoverMetaRange = 92
+
+	// NOTE: fingerprints sharded among the partitions of the volume
+	opFSMAcquireFingerprints = 93
+	opFSMIndexFingerprints   = 94
+	opFSMReleaseFingerprints = 95
+	opFSMDropDedupExtents    = 96
 )
 
 var exporterKey string
@@ -233,6 +262,7 @@ const (
 	cfgRetainLogs                = "retainLogs"                // string, raft RetainLogs
 	cfgRaftSyncSnapFormatVersion = "raftSyncSnapFormatVersion" // int, format version of snapshot that raft leader sent to follower
 	cfgServiceIDKey              = "serviceIDKey"
+	cfgCDCKafka                  = "cdcKafka" // object, the Kafka producer of the change events
 
 	metaNodeDeleteBatchCountKey = "batchCount"
 	configNameResolveInterval   = "nameResolveInterval" // int
@@ -0,0 +1,235 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// dedupShardsExpiration is how long the meta partitions of a volume are cached, the fingerprints of the
+// partitions added are sharded to them once the cache expires.
+const dedupShardsExpiration = time.Minute
+
+// dedupShardView is the meta partitions of the volume the fingerprints are sharded to.
+type dedupShardView struct {
+	partitions map[uint64]*proto.MetaPartitionView
+	ids        []uint64
+	expire     time.Time
+}
+
+type dedupShardCache struct {
+	sync.RWMutex
+	vols map[string]*dedupShardView
+}
+
+var dedupShards = &dedupShardCache{vols: make(map[string]*dedupShardView)}
+
+func (c *dedupShardCache) get(volName string) (view *dedupShardView, err error) {
+	c.RLock()
+	view = c.vols[volName]
+	c.RUnlock()
+	if view != nil && time.Now().Before(view.expire) {
+		return
+	}
+	partitions, err := masterClient.ClientAPI().GetMetaPartitions(volName)
+	if err != nil {
+		if view != nil {
+			log.LogWarnf("[dedupShardCache] vol(%v) get meta partitions err(%v), the expired view is used", volName, err)
+			return view, nil
+		}
+		return
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("no meta partitions of vol %v", volName)
+	}
+	view = &dedupShardView{
+		partitions: make(map[uint64]*proto.MetaPartitionView, len(partitions)),
+		ids:        make([]uint64, 0, len(partitions)),
+		expire:     time.Now().Add(dedupShardsExpiration),
+	}
+	for _, partition := range partitions {
+		view.partitions[partition.PartitionID] = partition
+		view.ids = append(view.ids, partition.PartitionID)
+	}
+	c.Lock()
+	c.vols[volName] = view
+	c.Unlock()
+	return
+}
+
+// dedupShardOf returns the partition the fingerprint is sharded to by rendezvous hashing, so that only the
+// fingerprints sharded to the partitions added are moved once the volume grows.
+func dedupShardOf(hash []byte, ids []uint64) (shard uint64) {
+	var (
+		max uint64
+		buf [8]byte
+	)
+	for _, id := range ids {
+		h := fnv.New64a()
+		h.Write(hash)
+		binary.BigEndian.PutUint64(buf[:], id)
+		h.Write(buf[:])
+		if weight := h.Sum64(); shard == 0 || weight > max {
+			shard, max = id, weight
+		}
+	}
+	return
+}
+
+// newDedupToken returns a token unique to the request referring to the chunks.
+func newDedupToken() (token uint64, err error) {
+	var buf [8]byte
+	if _, err = rand.Read(buf[:]); err != nil {
+		return
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// sendToDedupShard sends the request to the replicas of the fingerprint partition in turn, starting from the
+// leader, the request is proxied to the leader by the replica.
+func (mp *metaPartition) sendToDedupShard(shard uint64, opcode uint8, req interface{}) (data []byte, err error) {
+	view, err := dedupShards.get(mp.config.VolName)
+	if err != nil {
+		return
+	}
+	partition, ok := view.partitions[shard]
+	if !ok {
+		return nil, fmt.Errorf("fingerprint partition %v not found", shard)
+	}
+	p := NewPacketToMetaRange(opcode, req)
+	if p == nil {
+		return nil, fmt.Errorf("marshal request %v", req)
+	}
+	addrs := make([]string, 0, len(partition.Members)+1)
+	if partition.LeaderAddr != "" {
+		addrs = append(addrs, partition.LeaderAddr)
+	}
+	addrs = append(addrs, partition.Members...)
+	for _, addr := range addrs {
+		if data, err = mp.sendMetaRangePacket(addr, p); err == nil {
+			return
+		}
+		log.LogWarnf("[sendToDedupShard] mp(%v) send %v to mp(%v) addr(%v) err(%v)",
+			mp.config.PartitionId, p.GetOpMsg(), shard, addr, err)
+	}
+	if err == nil {
+		err = fmt.Errorf("no replica of fingerprint partition %v", shard)
+	}
+	return
+}
+
+func (mp *metaPartition) acquireFromShard(shard uint64, req *proto.AcquireFingerprintsRequest) (resp *proto.AcquireFingerprintsResponse, err error) {
+	if shard == mp.config.PartitionId {
+		return mp.acquireFingerprints(req)
+	}
+	data, err := mp.sendToDedupShard(shard, proto.OpMetaAcquireFingerprints, req)
+	if err != nil {
+		return
+	}
+	resp = &proto.AcquireFingerprintsResponse{}
+	if err = json.Unmarshal(data, resp); err == nil && len(resp.Extents) != len(req.Chunks) {
+		err = fmt.Errorf("acquired %v of %v chunks", len(resp.Extents), len(req.Chunks))
+	}
+	return
+}
+
+func (mp *metaPartition) indexToShard(shard uint64, req *proto.IndexFingerprintsRequest) (resp *proto.IndexFingerprintsResponse, err error) {
+	if shard == mp.config.PartitionId {
+		return mp.indexFingerprints(req)
+	}
+	data, err := mp.sendToDedupShard(shard, proto.OpMetaIndexFingerprints, req)
+	if err != nil {
+		return
+	}
+	resp = &proto.IndexFingerprintsResponse{}
+	if err = json.Unmarshal(data, resp); err == nil && len(resp.Indexed) != len(req.Extents) {
+		err = fmt.Errorf("indexed %v of %v chunks", len(resp.Indexed), len(req.Extents))
+	}
+	return
+}
+
+func (mp *metaPartition) releaseToShard(shard uint64, req *proto.ReleaseFingerprintsRequest) (resp *proto.ReleaseFingerprintsResponse, err error) {
+	if shard == mp.config.PartitionId {
+		return mp.releaseFingerprints(req)
+	}
+	data, err := mp.sendToDedupShard(shard, proto.OpMetaReleaseFingerprints, req)
+	if err != nil {
+		return
+	}
+	resp = &proto.ReleaseFingerprintsResponse{}
+	if err = json.Unmarshal(data, resp); err == nil && len(resp.Owned) != len(req.Releases) {
+		err = fmt.Errorf("released %v of %v chunks", len(resp.Owned), len(req.Releases))
+	}
+	return
+}
+
+// getExtentHash reads the sha256 of the chunk from the data node.
+func (mp *metaPartition) getExtentHash(ext *proto.DedupExtent) (hash []byte, err error) {
+	dp := mp.vol.GetPartition(ext.PartitionId)
+	if dp == nil {
+		return nil, ErrDataPartitionNotFound
+	}
+	if len(dp.Hosts) < 1 {
+		return nil, ErrInvalidDataPartition
+	}
+	addr := util.ShiftAddrPort(dp.Hosts[0], smuxPortShift)
+	conn, err := smuxPool.GetConnect(addr)
+	if err != nil {
+		return nil, ErrDataPartitionUnreachable
+	}
+	defer smuxPool.PutConnect(conn, ForceClosedConnect)
+
+	p := NewPacketToGetExtentHash(ext)
+	if err = p.WriteToConn(conn); err != nil {
+		return nil, ErrDataPartitionUnreachable
+	}
+	if err = p.ReadFromConnWithVer(conn, proto.ReadDeadlineTime); err != nil {
+		return nil, ErrDataPartitionUnreachable
+	}
+	if p.ResultCode != proto.OpOk {
+		return nil, fmt.Errorf("get hash of dp(%v) extent(%v) offset(%v): %v",
+			ext.PartitionId, ext.ExtentId, ext.ExtentOffset, p.GetResultMsg())
+	}
+	return p.Data[:p.Size], nil
+}
+
+// verifyDedupExtent checks the fingerprint against the data kept by the data node, the fingerprints sent by
+// the clients are never trusted.
+func (mp *metaPartition) verifyDedupExtent(ext *proto.DedupExtent) bool {
+	if len(ext.Fingerprint) != proto.FingerprintSize || ext.Size == 0 || ext.Size > proto.MaxDedupChunkSize {
+		return false
+	}
+	hash, err := mp.getExtentHash(ext)
+	if err != nil {
+		log.LogWarnf("[verifyDedupExtent] mp(%v) ext(%v) err(%v)", mp.config.PartitionId, ext, err)
+		return false
+	}
+	if !bytes.Equal(hash, ext.Fingerprint) {
+		log.LogWarnf("[verifyDedupExtent] mp(%v) fingerprint of ext(%v) mismatch %x", mp.config.PartitionId, ext, hash)
+		return false
+	}
+	return true
+}
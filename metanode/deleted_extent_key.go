@@ -104,7 +104,8 @@ func (dek *DeletedExtentKey) UnmarshalWithBuffer(buf *bytes.Buffer) (err error)
 
 func NewDeletedExtentKey(ek *proto.ExtentKey, ino, deletedExtentId uint64) (dek *DeletedExtentKey) {
 	return &DeletedExtentKey{
-		ExtentKey:       *ek,
+		// the frame of a compressed chunk is freed as a whole
+		ExtentKey:       ek.StorageKey(),
 		Inode:           ino,
 		DeletedExtentId: deletedExtentId,
 	}
@@ -0,0 +1,165 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+)
+
+const (
+	// the client renews the lease in a third of the lease
+	fileLockLease = 30 * time.Second
+)
+
+// fileLockTable holds the advisory file locks of the meta partition on the leader. The locks are
+// not replicated by raft, instead each client holds a lease which is renewed periodically and all
+// the locks of the client are dropped once the lease expires. The new leader starts with an empty
+// table and refuses the new locks in a grace period of a lease, so that the clients could reclaim
+// their locks by the renewals.
+type fileLockTable struct {
+	sync.Mutex
+	locks      map[uint64][]*proto.FileLock // inode -> locks
+	leases     map[string]time.Time         // client -> expire time
+	graceUntil time.Time
+	lastPurge  time.Time
+	lease      time.Duration
+	now        func() time.Time
+}
+
+func newFileLockTable() *fileLockTable {
+	return &fileLockTable{
+		locks:  make(map[uint64][]*proto.FileLock),
+		leases: make(map[string]time.Time),
+		lease:  fileLockLease,
+		now:    time.Now,
+	}
+}
+
+// reset drops all the locks, and starts the grace period if the partition becomes the leader.
+func (t *fileLockTable) reset(leader bool) {
+	t.Lock()
+	defer t.Unlock()
+	t.locks = make(map[uint64][]*proto.FileLock)
+	t.leases = make(map[string]time.Time)
+	t.graceUntil = time.Time{}
+	if leader {
+		t.graceUntil = t.now().Add(t.lease)
+	}
+}
+
+func (t *fileLockTable) inGrace(now time.Time) bool {
+	return now.Before(t.graceUntil)
+}
+
+func (t *fileLockTable) alive(client string, now time.Time) bool {
+	expire, ok := t.leases[client]
+	return ok && now.Before(expire)
+}
+
+// inodeLocks returns the locks of the inode whose lease is alive.
+func (t *fileLockTable) inodeLocks(ino uint64, now time.Time) []*proto.FileLock {
+	locks := t.locks[ino]
+	alive := locks[:0]
+	for _, l := range locks {
+		if t.alive(l.Client, now) {
+			alive = append(alive, l)
+		}
+	}
+	if len(alive) == 0 {
+		delete(t.locks, ino)
+		return nil
+	}
+	t.locks[ino] = alive
+	return alive
+}
+
+func (t *fileLockTable) setInodeLocks(ino uint64, locks []*proto.FileLock) {
+	if len(locks) == 0 {
+		delete(t.locks, ino)
+		return
+	}
+	t.locks[ino] = locks
+}
+
+// set applies the lock, the conflicting lock is returned if the lock cannot be acquired.
+func (t *fileLockTable) set(lock *proto.FileLock) (conflict *proto.FileLock, again bool) {
+	t.Lock()
+	defer t.Unlock()
+	now := t.now()
+	t.purge(now)
+	locks := t.inodeLocks(lock.Inode, now)
+	if lock.Type != proto.FileLockUnlock {
+		if t.inGrace(now) && !t.alive(lock.Client, now) {
+			return nil, true
+		}
+		if conflict = proto.FindFileLockConflict(locks, lock); conflict != nil {
+			return conflict, false
+		}
+		t.leases[lock.Client] = now.Add(t.lease)
+	}
+	t.setInodeLocks(lock.Inode, proto.ApplyFileLock(locks, lock))
+	return nil, false
+}
+
+// get returns the first lock which conflicts with the lock.
+func (t *fileLockTable) get(lock *proto.FileLock) *proto.FileLock {
+	t.Lock()
+	defer t.Unlock()
+	return proto.FindFileLockConflict(t.inodeLocks(lock.Inode, t.now()), lock)
+}
+
+// renew extends the lease of the client, and reclaims the locks if the lease has been lost.
+// The locks which cannot be reclaimed because of conflicts are returned.
+func (t *fileLockTable) renew(client string, locks []*proto.FileLock) (lost []*proto.FileLock) {
+	t.Lock()
+	defer t.Unlock()
+	now := t.now()
+	if t.alive(client, now) {
+		t.leases[client] = now.Add(t.lease)
+		return nil
+	}
+	t.leases[client] = now.Add(t.lease)
+	for _, lock := range locks {
+		if lock.Client != client || lock.Type == proto.FileLockUnlock {
+			continue
+		}
+		inodeLocks := t.inodeLocks(lock.Inode, now)
+		if proto.FindFileLockConflict(inodeLocks, lock) != nil {
+			lost = append(lost, lock)
+			continue
+		}
+		t.setInodeLocks(lock.Inode, proto.ApplyFileLock(inodeLocks, lock))
+	}
+	return lost
+}
+
+// purge drops the expired leases and their locks at most once a lease.
+func (t *fileLockTable) purge(now time.Time) {
+	if now.Sub(t.lastPurge) < t.lease {
+		return
+	}
+	t.lastPurge = now
+	for client, expire := range t.leases {
+		if !now.Before(expire) {
+			delete(t.leases, client)
+		}
+	}
+	for ino := range t.locks {
+		t.inodeLocks(ino, now)
+	}
+}
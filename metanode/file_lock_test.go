@@ -0,0 +1,97 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFileLockTable() (*fileLockTable, *time.Time) {
+	now := time.Unix(1000, 0)
+	table := newFileLockTable()
+	table.now = func() time.Time { return now }
+	return table, &now
+}
+
+func newTestWriteLock(client string) *proto.FileLock {
+	return &proto.FileLock{Inode: 1, Client: client, Owner: 1, Type: proto.FileLockWrite, Start: 0, End: proto.FileLockMaxOffset}
+}
+
+func TestFileLockTableSet(t *testing.T) {
+	table, _ := newTestFileLockTable()
+
+	conflict, again := table.set(newTestWriteLock("a"))
+	require.Nil(t, conflict)
+	require.False(t, again)
+
+	conflict, _ = table.set(newTestWriteLock("b"))
+	require.NotNil(t, conflict)
+	require.Equal(t, "a", conflict.Client)
+	require.NotNil(t, table.get(newTestWriteLock("b")))
+	require.Nil(t, table.get(newTestWriteLock("a")))
+
+	unlock := newTestWriteLock("a")
+	unlock.Type = proto.FileLockUnlock
+	conflict, _ = table.set(unlock)
+	require.Nil(t, conflict)
+	conflict, _ = table.set(newTestWriteLock("b"))
+	require.Nil(t, conflict)
+}
+
+func TestFileLockTableLeaseExpire(t *testing.T) {
+	table, now := newTestFileLockTable()
+
+	conflict, _ := table.set(newTestWriteLock("a"))
+	require.Nil(t, conflict)
+
+	*now = now.Add(fileLockLease / 2)
+	require.Nil(t, table.renew("a", nil))
+	*now = now.Add(fileLockLease / 2)
+	conflict, _ = table.set(newTestWriteLock("b"))
+	require.NotNil(t, conflict)
+
+	// the locks of client a are dropped once its lease expires
+	*now = now.Add(fileLockLease)
+	conflict, _ = table.set(newTestWriteLock("b"))
+	require.Nil(t, conflict)
+
+	// client a cannot reclaim the lock taken by others
+	lost := table.renew("a", []*proto.FileLock{newTestWriteLock("a")})
+	require.Len(t, lost, 1)
+}
+
+func TestFileLockTableGrace(t *testing.T) {
+	table, now := newTestFileLockTable()
+	table.reset(true)
+
+	_, again := table.set(newTestWriteLock("b"))
+	require.True(t, again)
+
+	// the locks are reclaimed by the renewal in the grace period
+	require.Len(t, table.renew("a", []*proto.FileLock{newTestWriteLock("a")}), 0)
+	conflict, again := table.set(newTestWriteLock("a"))
+	require.Nil(t, conflict)
+	require.False(t, again)
+
+	*now = now.Add(fileLockLease)
+	require.Nil(t, table.renew("a", nil))
+	conflict, again = table.set(newTestWriteLock("b"))
+	require.False(t, again)
+	require.NotNil(t, conflict)
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/cubefs/cubefs/proto"
+)
+
+// Fingerprint locates the data of a deduplicated chunk. The fingerprints are sharded among the meta
+// partitions of the volume, the partition indexing the fingerprint keeps the references to the chunk
+// from the partitions of the inodes, and frees the data once none of them refers to it.
+type Fingerprint struct {
+	Hash         [proto.FingerprintSize]byte
+	PartitionId  uint64
+	ExtentId     uint64
+	ExtentOffset uint64
+	Size         uint32
+	Refs         []FingerprintRef
+}
+
+// FingerprintRef is a reference of the meta partition to the chunk, the token is unique to the request
+// deduplicating or indexing the chunk.
+type FingerprintRef struct {
+	PartitionId uint64
+	Token       uint64
+}
+
+var _ BtreeItem = &Fingerprint{}
+
+func NewFingerprint(hash []byte) (fp *Fingerprint, err error) {
+	if len(hash) != proto.FingerprintSize {
+		return nil, fmt.Errorf("invalid fingerprint size %v", len(hash))
+	}
+	fp = &Fingerprint{}
+	copy(fp.Hash[:], hash)
+	return
+}
+
+func (fp *Fingerprint) Less(than BtreeItem) bool {
+	other := than.(*Fingerprint)
+	return bytes.Compare(fp.Hash[:], other.Hash[:]) < 0
+}
+
+func (fp *Fingerprint) Copy() BtreeItem {
+	other := *fp
+	other.Refs = append([]FingerprintRef(nil), fp.Refs...)
+	return &other
+}
+
+// Locates checks whether the fingerprint locates the data of the extent.
+func (fp *Fingerprint) Locates(ext *proto.DedupExtent) bool {
+	return fp.PartitionId == ext.PartitionId && fp.ExtentId == ext.ExtentId &&
+		fp.ExtentOffset == ext.ExtentOffset && fp.Size == ext.Size
+}
+
+// DedupExtent returns the data located by the fingerprint.
+func (fp *Fingerprint) DedupExtent() *proto.DedupExtent {
+	return &proto.DedupExtent{
+		Fingerprint:  append([]byte(nil), fp.Hash[:]...),
+		PartitionId:  fp.PartitionId,
+		ExtentId:     fp.ExtentId,
+		ExtentOffset: fp.ExtentOffset,
+		Size:         fp.Size,
+	}
+}
+
+// AddRef records that the partition refers to the chunk with the token.
+func (fp *Fingerprint) AddRef(pid, token uint64) {
+	for _, ref := range fp.Refs {
+		if ref.PartitionId == pid && ref.Token == token {
+			return
+		}
+	}
+	fp.Refs = append(fp.Refs, FingerprintRef{PartitionId: pid, Token: token})
+}
+
+// ReleaseRefs drops the references of the partition with the tokens.
+func (fp *Fingerprint) ReleaseRefs(pid uint64, tokens []uint64) {
+	refs := fp.Refs[:0]
+	for _, ref := range fp.Refs {
+		released := false
+		if ref.PartitionId == pid {
+			for _, token := range tokens {
+				if ref.Token == token {
+					released = true
+					break
+				}
+			}
+		}
+		if !released {
+			refs = append(refs, ref)
+		}
+	}
+	fp.Refs = refs
+}
+
+func (fp *Fingerprint) String() string {
+	return fmt.Sprintf("Fingerprint{%x dp(%v) extent(%v) offset(%v) size(%v) refs(%v)}",
+		fp.Hash, fp.PartitionId, fp.ExtentId, fp.ExtentOffset, fp.Size, fp.Refs)
+}
+
+func (fp *Fingerprint) Marshal() (v []byte, err error) {
+	buff := bytes.NewBuffer(make([]byte, 0, proto.FingerprintSize+32+len(fp.Refs)*16))
+	if _, err = buff.Write(fp.Hash[:]); err != nil {
+		return
+	}
+	if err = binary.Write(buff, binary.BigEndian, fp.PartitionId); err != nil {
+		return
+	}
+	if err = binary.Write(buff, binary.BigEndian, fp.ExtentId); err != nil {
+		return
+	}
+	if err = binary.Write(buff, binary.BigEndian, fp.ExtentOffset); err != nil {
+		return
+	}
+	if err = binary.Write(buff, binary.BigEndian, fp.Size); err != nil {
+		return
+	}
+	if err = binary.Write(buff, binary.BigEndian, uint32(len(fp.Refs))); err != nil {
+		return
+	}
+	if err = binary.Write(buff, binary.BigEndian, fp.Refs); err != nil {
+		return
+	}
+	v = buff.Bytes()
+	return
+}
+
+func (fp *Fingerprint) Unmarshal(v []byte) (err error) {
+	buff := bytes.NewBuffer(v)
+	if _, err = buff.Read(fp.Hash[:]); err != nil {
+		return
+	}
+	if err = binary.Read(buff, binary.BigEndian, &fp.PartitionId); err != nil {
+		return
+	}
+	if err = binary.Read(buff, binary.BigEndian, &fp.ExtentId); err != nil {
+		return
+	}
+	if err = binary.Read(buff, binary.BigEndian, &fp.ExtentOffset); err != nil {
+		return
+	}
+	if err = binary.Read(buff, binary.BigEndian, &fp.Size); err != nil {
+		return
+	}
+	var cnt uint32
+	if err = binary.Read(buff, binary.BigEndian, &cnt); err != nil {
+		return
+	}
+	if uint64(cnt)*16 != uint64(buff.Len()) {
+		return fmt.Errorf("invalid fingerprint refs count %v", cnt)
+	}
+	fp.Refs = make([]FingerprintRef, cnt)
+	return binary.Read(buff, binary.BigEndian, fp.Refs)
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintMarshal(t *testing.T) {
+	sum := sha256.Sum256([]byte("fingerprint"))
+	fp, err := NewFingerprint(sum[:])
+	require.NoError(t, err)
+	fp.PartitionId, fp.ExtentId, fp.ExtentOffset, fp.Size = 1, 100, 4096, 65536
+	fp.AddRef(1, 10)
+	fp.AddRef(2, 20)
+	fp.AddRef(1, 10)
+	require.Len(t, fp.Refs, 2)
+
+	data, err := fp.Marshal()
+	require.NoError(t, err)
+	loaded := &Fingerprint{}
+	require.NoError(t, loaded.Unmarshal(data))
+	require.Equal(t, fp, loaded)
+	require.Error(t, loaded.Unmarshal(data[:len(data)-2]))
+
+	// the tokens of the other partitions are kept
+	fp.ReleaseRefs(1, []uint64{10, 20})
+	require.Equal(t, []FingerprintRef{{PartitionId: 2, Token: 20}}, fp.Refs)
+
+	_, err = NewFingerprint(sum[:10])
+	require.Error(t, err)
+}
+
+func TestFingerprintBTree(t *testing.T) {
+	tree := &FingerprintBTree{NewBtree()}
+	sum := sha256.Sum256([]byte("fingerprint"))
+	fp, err := NewFingerprint(sum[:])
+	require.NoError(t, err)
+	fp.PartitionId, fp.ExtentId, fp.Size = 1, 100, 65536
+	require.NoError(t, tree.Put(nil, fp))
+
+	got, err := tree.Get(sum[:])
+	require.NoError(t, err)
+	require.Equal(t, fp, got)
+	ext := got.DedupExtent()
+	require.True(t, got.Locates(ext))
+	require.Equal(t, proto.ExtentKey{FileOffset: 1024, PartitionId: 1, ExtentId: 100, Size: 65536}, ext.ExtentKey(1024))
+	ext.ExtentOffset = 4096
+	require.False(t, got.Locates(ext))
+
+	other := sha256.Sum256([]byte("other"))
+	got, err = tree.Get(other[:])
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	_, err = tree.Delete(nil, fp)
+	require.NoError(t, err)
+	require.Zero(t, tree.Len())
+}
+
+func TestInodeCoversChunk(t *testing.T) {
+	ino := NewInode(10, proto.Mode(0o644))
+	ino.Extents.eks = append(ino.Extents.eks, proto.ExtentKey{FileOffset: 0, Size: 1000, PartitionId: 1, ExtentId: 100, ExtentOffset: 1000})
+
+	require.True(t, inodeCoversChunk(ino, 0, &proto.DedupExtent{PartitionId: 1, ExtentId: 100, ExtentOffset: 1000, Size: 1000}))
+	require.True(t, inodeCoversChunk(ino, 500, &proto.DedupExtent{PartitionId: 1, ExtentId: 100, ExtentOffset: 1500, Size: 100}))
+	require.False(t, inodeCoversChunk(ino, 500, &proto.DedupExtent{PartitionId: 1, ExtentId: 100, ExtentOffset: 1000, Size: 100}))
+	require.False(t, inodeCoversChunk(ino, 500, &proto.DedupExtent{PartitionId: 1, ExtentId: 100, ExtentOffset: 1500, Size: 1000}))
+	require.False(t, inodeCoversChunk(ino, 0, &proto.DedupExtent{PartitionId: 1, ExtentId: 101, ExtentOffset: 1000, Size: 10}))
+}
+
+func TestDedupShardOf(t *testing.T) {
+	ids := []uint64{1, 2, 3, 4}
+	counts := make(map[uint64]int)
+	moved := 0
+	for n := 0; n < 1000; n++ {
+		sum := sha256.Sum256([]byte{byte(n), byte(n >> 8)})
+		shard := dedupShardOf(sum[:], ids)
+		require.Equal(t, shard, dedupShardOf(sum[:], []uint64{4, 3, 2, 1}))
+		counts[shard]++
+		// only the fingerprints sharded to the partition added are moved
+		if grown := dedupShardOf(sum[:], append(ids, 5)); grown != shard {
+			require.EqualValues(t, 5, grown)
+			moved++
+		}
+	}
+	require.Len(t, counts, len(ids))
+	require.Greater(t, moved, 0)
+	require.Less(t, moved, 400)
+	require.Zero(t, dedupShardOf([]byte("fp"), nil))
+}
+
+func TestUncoveredRanges(t *testing.T) {
+	ek := &proto.ExtentKey{PartitionId: 1, ExtentId: 100, ExtentOffset: 0, Size: 1000}
+	chunks := []*dedupChunk{
+		{ExtentOffset: 600, Size: 200},
+		{ExtentOffset: 100, Size: 300},
+		{ExtentOffset: 2000, Size: 100},
+	}
+	ranges := uncoveredRanges(ek, chunks)
+	require.Equal(t, []*proto.ExtentKey{
+		{PartitionId: 1, ExtentId: 100, ExtentOffset: 0, Size: 100},
+		{PartitionId: 1, ExtentId: 100, ExtentOffset: 400, Size: 200},
+		{PartitionId: 1, ExtentId: 100, ExtentOffset: 800, Size: 200},
+	}, ranges)
+	require.Empty(t, uncoveredRanges(&proto.ExtentKey{PartitionId: 1, ExtentId: 100, ExtentOffset: 150, Size: 100}, chunks))
+}
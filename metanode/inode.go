@@ -41,6 +41,11 @@ var (
 	// InodeV1Flag uint64 = 0x01
 	V2EnableColdInodeFlag uint64 = 0x02
 	V3EnableSnapInodeFlag uint64 = 0x04
+	// V4EnableCompressedExtentFlag is set if the compression of the extent keys follows the version
+	V4EnableCompressedExtentFlag uint64 = 0x08
+	// V5EnableCipherNonceFlag is set if the cipher nonces of the extent keys and the obj extent keys
+	// follow the compression
+	V5EnableCipherNonceFlag uint64 = 0x10
 )
 
 // Inode wraps necessary properties of `Inode` information in the file system.
@@ -249,7 +254,9 @@ func (inode *Inode) ClearAllExtsOfflineInode(mpID uint64) (extInfo map[uint64][]
 
 				log.LogDebugf("deleteMarkedInodes. ClearAllExtsOfflineInode.mp[%v] inode[%v] ek [%v] be removed", mpID, inode.Inode, ek)
 			}
-			extInfo[ek.PartitionId] = append(extInfo[ek.PartitionId], &ek)
+			// the frames of the compressed chunks are a part of the extent
+			sk := ek.StorageKey()
+			extInfo[ek.PartitionId] = append(extInfo[ek.PartitionId], &sk)
 			// NOTE: unnecessary to set ext
 			log.LogWritef("ClearAllExtsOfflineInode. mp[%v] ino(%v) deleteExtent(%v)", mpID, inode.Inode, ek.String())
 			return true
@@ -670,6 +677,22 @@ func (i *Inode) MarshalInodeValue(buff *bytes.Buffer) {
 		i.Reserved |= V2EnableColdInodeFlag
 	}
 	i.Reserved |= V3EnableSnapInodeFlag
+	compData := i.Extents.MarshalCompression()
+	if len(compData) > 0 {
+		i.Reserved |= V4EnableCompressedExtentFlag
+	} else {
+		i.Reserved &^= V4EnableCompressedExtentFlag
+	}
+	extNonceData := i.Extents.MarshalCipherNonce()
+	var objNonceData []byte
+	if i.ObjExtents != nil {
+		objNonceData = i.ObjExtents.MarshalCipherNonce()
+	}
+	if len(extNonceData) > 0 || len(objNonceData) > 0 {
+		i.Reserved |= V5EnableCipherNonceFlag
+	} else {
+		i.Reserved &^= V5EnableCipherNonceFlag
+	}
 
 	// log.LogInfof("action[MarshalInodeValue] inode[%v] Reserved %v", i.Inode, i.Reserved)
 	if err = binary.Write(buff, binary.BigEndian, &i.Reserved); err != nil {
@@ -706,6 +729,26 @@ func (i *Inode) MarshalInodeValue(buff *bytes.Buffer) {
 		panic(err)
 	}
 
+	if i.Reserved&V4EnableCompressedExtentFlag > 0 {
+		if err = binary.Write(buff, binary.BigEndian, uint32(len(compData))); err != nil {
+			panic(err)
+		}
+		if _, err = buff.Write(compData); err != nil {
+			panic(err)
+		}
+	}
+
+	if i.Reserved&V5EnableCipherNonceFlag > 0 {
+		for _, nonceData := range [][]byte{extNonceData, objNonceData} {
+			if err = binary.Write(buff, binary.BigEndian, uint32(len(nonceData))); err != nil {
+				panic(err)
+			}
+			if _, err = buff.Write(nonceData); err != nil {
+				panic(err)
+			}
+		}
+	}
+
 	return
 }
 
@@ -852,6 +895,36 @@ func (i *Inode) UnmarshalInodeValue(buff *bytes.Buffer) (err error) {
 		}
 	}
 
+	if i.Reserved&V4EnableCompressedExtentFlag > 0 {
+		compSize := uint32(0)
+		if err = binary.Read(buff, binary.BigEndian, &compSize); err != nil {
+			return
+		}
+		compBytes := make([]byte, compSize)
+		if _, err = io.ReadFull(buff, compBytes); err != nil {
+			return
+		}
+		if err = i.Extents.UnmarshalCompression(compBytes); err != nil {
+			return
+		}
+	}
+
+	if i.Reserved&V5EnableCipherNonceFlag > 0 {
+		for _, unmarshal := range []func([]byte) error{i.Extents.UnmarshalCipherNonce, i.ObjExtents.UnmarshalCipherNonce} {
+			nonceSize := uint32(0)
+			if err = binary.Read(buff, binary.BigEndian, &nonceSize); err != nil {
+				return
+			}
+			nonceBytes := make([]byte, nonceSize)
+			if _, err = io.ReadFull(buff, nonceBytes); err != nil {
+				return
+			}
+			if err = unmarshal(nonceBytes); err != nil {
+				return
+			}
+		}
+	}
+
 	return
 }
 
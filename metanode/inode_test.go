@@ -17,6 +17,7 @@ package metanode
 import (
 	"testing"
 
+	"github.com/cubefs/cubefs/proto"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,3 +30,45 @@ func TestInodeMarshal(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, inode, unmarshalInode)
 }
+
+func TestInodeMarshalCompressedExtents(t *testing.T) {
+	inode := NewInode(1, FileModeType)
+	inode.Extents.Append(proto.ExtentKey{FileOffset: 0, Size: proto.CompressChunkSize, PartitionId: 1, ExtentId: 100})
+	inode.Extents.Append(proto.ExtentKey{FileOffset: proto.CompressChunkSize, Size: proto.CompressChunkSize + 2000, PartitionId: 1, ExtentId: 1025,
+		Compression: &proto.ExtCompression{Codec: 2, Frames: []uint32{300, 2000 | proto.CompressFrameRaw}}})
+	buf, err := inode.Marshal()
+	require.NoError(t, err)
+	require.NotZero(t, inode.Reserved&V4EnableCompressedExtentFlag)
+	unmarshalInode := &Inode{}
+	err = unmarshalInode.Unmarshal(buf)
+	require.NoError(t, err)
+	require.Equal(t, inode.Extents.eks, unmarshalInode.Extents.eks)
+
+	// the flag is cleared once the compressed keys are removed
+	inode.Extents.Truncate(proto.CompressChunkSize, nil, nil)
+	_, err = inode.Marshal()
+	require.NoError(t, err)
+	require.Zero(t, inode.Reserved&V4EnableCompressedExtentFlag)
+}
+
+func TestInodeMarshalCipherNonce(t *testing.T) {
+	inode := NewInode(1, FileModeType)
+	inode.Extents.Append(proto.ExtentKey{FileOffset: 0, Size: 1000, PartitionId: 1, ExtentId: 100})
+	inode.Extents.Append(proto.ExtentKey{FileOffset: 1000, Size: 2000, PartitionId: 1, ExtentId: 101, CipherNonce: 7})
+	inode.ObjExtents.Append(proto.ObjExtentKey{FileOffset: 3000, Size: 1000, Blobs: []proto.Blob{}, CipherNonce: 8})
+	buf, err := inode.Marshal()
+	require.NoError(t, err)
+	require.NotZero(t, inode.Reserved&V5EnableCipherNonceFlag)
+	unmarshalInode := &Inode{}
+	err = unmarshalInode.Unmarshal(buf)
+	require.NoError(t, err)
+	require.Equal(t, inode.Extents.eks, unmarshalInode.Extents.eks)
+	require.Equal(t, inode.ObjExtents.eks, unmarshalInode.ObjExtents.eks)
+
+	// the flag is cleared once the keys with the nonces are removed
+	inode.Extents.Truncate(1000, nil, nil)
+	inode.ObjExtents.Truncate(0)
+	_, err = inode.Marshal()
+	require.NoError(t, err)
+	require.Zero(t, inode.Reserved&V5EnableCipherNonceFlag)
+}
@@ -143,6 +143,14 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet, remo
 		}
 	}()
 
+	if release, ok := m.acquireMetaRange(p); !ok {
+		release()
+		err = m.respondToClient(conn, p)
+		return
+	} else if release != nil {
+		defer release()
+	}
+
 	switch p.Opcode {
 	case proto.OpMetaCreateInode:
 		err = m.opCreateInode(conn, p, remoteAddr)
@@ -286,6 +294,47 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet, remo
 		err = m.opQuotaCreateDentry(conn, p, remoteAddr)
 	case proto.OpMetaGetUniqID:
 		err = m.opMetaGetUniqID(conn, p, remoteAddr)
+	// advisory file lock
+	case proto.OpMetaSetFileLock:
+		err = m.opMetaSetFileLock(conn, p, remoteAddr)
+	case proto.OpMetaGetFileLock:
+		err = m.opMetaGetFileLock(conn, p, remoteAddr)
+	case proto.OpMetaRenewFileLocks:
+		err = m.opMetaRenewFileLocks(conn, p, remoteAddr)
+	// fallocate and copy_file_range
+	case proto.OpMetaFallocate:
+		err = m.opMetaFallocate(conn, p, remoteAddr)
+	case proto.OpMetaCloneExtents:
+		err = m.opMetaCloneExtents(conn, p, remoteAddr)
+	case proto.OpMetaDedupExtents:
+		err = m.opMetaDedupExtents(conn, p, remoteAddr)
+	case proto.OpMetaAddFingerprints:
+		err = m.opMetaAddFingerprints(conn, p, remoteAddr)
+	case proto.OpMetaAcquireFingerprints:
+		err = m.opMetaAcquireFingerprints(conn, p, remoteAddr)
+	case proto.OpMetaIndexFingerprints:
+		err = m.opMetaIndexFingerprints(conn, p, remoteAddr)
+	case proto.OpMetaReleaseFingerprints:
+		err = m.opMetaReleaseFingerprints(conn, p, remoteAddr)
+	case proto.OpMetaCloneSnapshotInode:
+		err = m.opMetaCloneSnapshotInode(conn, p, remoteAddr)
+	case proto.OpMetaSnapshotDiff:
+		err = m.opMetaSnapshotDiff(conn, p, remoteAddr)
+	case proto.OpMetaCDCRead:
+		err = m.opMetaCDCRead(conn, p, remoteAddr)
+	case proto.OpMetaTransitionExtents:
+		err = m.opMetaTransitionExtents(conn, p, remoteAddr)
+	case proto.OpMetaRestoreExtents:
+		err = m.opMetaRestoreExtents(conn, p, remoteAddr)
+	// migration of the inode ranges
+	case proto.OpMetaReadRange:
+		err = m.opMetaReadRange(conn, p, remoteAddr)
+	case proto.OpMetaFreezeRange:
+		err = m.opMetaFreezeRange(conn, p, remoteAddr)
+	case proto.OpMigrateMetaRange:
+		err = m.opMigrateMetaRange(conn, p, remoteAddr)
+	case proto.OpCutoverMetaRange:
+		err = m.opCutoverMetaRange(conn, p, remoteAddr)
 	// multi version
 	case proto.OpVersionOperation:
 		err = m.opMultiVersionOp(conn, p, remoteAddr)
@@ -371,9 +420,23 @@ func (m *metadataManager) onStart() (err error) {
 	// start sampler
 	m.startCpuSample()
 	m.startSnapshotVersionPromote()
+	m.startCDCPublisher()
 	return
 }
 
+// acquireMetaRange checks the request against the migrating ranges of the partition it is sent to, release
+// is nil if the partition is not found.
+func (m *metadataManager) acquireMetaRange(p *Packet) (release func(), ok bool) {
+	if p.PartitionID == 0 || isMetaRangeOp(p.Opcode) {
+		return nil, true
+	}
+	mp, err := m.getPartition(p.PartitionID)
+	if err != nil {
+		return nil, true
+	}
+	return mp.acquireMetaRange(p)
+}
+
 // onStop stops each meta partitions.
 func (m *metadataManager) onStop() {
 	if m.partitions != nil {
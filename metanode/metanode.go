@@ -15,6 +15,7 @@
 package metanode
 
 import (
+	"encoding/json"
 	"fmt"
 	syslog "log"
 	"os"
@@ -37,6 +38,7 @@ import (
 	"github.com/cubefs/cubefs/util/config"
 	"github.com/cubefs/cubefs/util/errors"
 	"github.com/cubefs/cubefs/util/exporter"
+	"github.com/cubefs/cubefs/util/kafkautil"
 	"github.com/cubefs/cubefs/util/log"
 )
 
@@ -89,6 +91,8 @@ type MetaNode struct {
 	diskStopCh        chan struct{}
 	disks             map[string]*diskmon.FsCapMon
 	diskReservedSpace uint64
+
+	cdcKafka *kafkautil.Config // the producer of the change events, nil if not published to Kafka
 }
 
 // Start starts up the meta node with the specified configuration.
@@ -418,6 +422,18 @@ func (m *MetaNode) parseConfig(cfg *config.Config) (err error) {
 		m.diskReservedSpace = defaultDiskReservedSpace
 	}
 
+	if raw := cfg.GetJsonObjectBytes(cfgCDCKafka); len(raw) > 0 {
+		cdcKafka := &kafkautil.Config{}
+		if err = json.Unmarshal(raw, cdcKafka); err != nil {
+			return fmt.Errorf("invalid %v: %v", cfgCDCKafka, err)
+		}
+		if err = cdcKafka.FixConfig(); err != nil {
+			return fmt.Errorf("invalid %v: %v", cfgCDCKafka, err)
+		}
+		m.cdcKafka = cdcKafka
+		log.LogInfof("[parseConfig] load cdcKafka brokers[%v] topic[%v].", cdcKafka.Brokers, cdcKafka.Topic)
+	}
+
 	constCfg := config.ConstConfig{
 		Listen:           m.listen,
 		RaftHeartbetPort: m.raftHeartbeatPort,
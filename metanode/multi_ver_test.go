@@ -62,6 +62,8 @@ func newPartition(conf *MetaPartitionConfig, manager *metadataManager) (mp *meta
 		manager:        manager,
 		verSeq:         conf.VerSeq,
 		rocksdbManager: manager.rocksdbManager,
+		fileLocks:      newFileLockTable(),
+		sharedExtents:  newSharedExtents(),
 	}
 	if conf.StoreMode == proto.StoreModeRocksDb {
 		err := mp.rocksdbManager.Register(conf.RocksDBDir)
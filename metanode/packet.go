@@ -106,7 +106,43 @@ func NewPacketToBatchDeleteExtent(dp *DataPartition, exts []*proto.ExtentKey) *P
 	return p
 }
 
+// NewPacketToGetExtentHash returns a new packet to get the sha256 of the chunk from the data node.
+func NewPacketToGetExtentHash(ext *proto.DedupExtent) *Packet {
+	p := new(Packet)
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpGetExtentHash
+	p.ExtentType = proto.NormalExtentType
+	p.PartitionID = ext.PartitionId
+	p.ExtentID = ext.ExtentId
+	p.Data, _ = json.Marshal(&proto.ExtentKey{
+		PartitionId:  ext.PartitionId,
+		ExtentId:     ext.ExtentId,
+		ExtentOffset: ext.ExtentOffset,
+		Size:         ext.Size,
+	})
+	p.Size = uint32(len(p.Data))
+	p.ReqID = proto.GenerateRequestID()
+	return p
+}
+
 // NewPacketToDeleteExtent returns a new packet to delete the extent.
+// NewPacketToMetaRange returns a new packet of the request to the source partition of the migrating range.
+func NewPacketToMetaRange(opcode uint8, req interface{}) *Packet {
+	data, err := json.Marshal(req)
+	if err != nil {
+		log.LogErrorf("NewPacketToMetaRange: marshal req(%v) err(%v)", req, err)
+		return nil
+	}
+	p := new(Packet)
+	p.Magic = proto.ProtoMagic
+	p.Opcode = opcode
+	p.ExtentType = proto.NormalExtentType
+	p.ReqID = proto.GenerateRequestID()
+	p.Data = data
+	p.Size = uint32(len(data))
+	return p
+}
+
 func NewPacketToFreeInodeOnRaftFollower(partitionID uint64, freeInodes []byte) *Packet {
 	p := new(Packet)
 	p.Magic = proto.ProtoMagic
@@ -108,6 +108,9 @@ type MetaPartitionConfig struct {
 	RocksLogReversedTime uint64          `json:"rocks_log_reversed"`
 	RocksLogReVersedCnt  uint64          `json:"rocks_log_re_versed_cnt"`
 	RocksWalTTL          uint64          `json:"rocks_wal_ttl"`
+
+	FrozenRange *proto.MetaRange        `json:"frozen_range,omitempty"` // the range being migrated out, held off until the cutover
+	MovedRanges []*proto.MovedMetaRange `json:"moved_ranges,omitempty"` // the ranges migrated out, redirected to the target partition
 }
 
 func (c *MetaPartitionConfig) checkMeta() (err error) {
@@ -221,6 +224,58 @@ type OpMultipart interface {
 	GetExpiredMultipart(req *proto.GetExpiredMultipartRequest, p *Packet) (err error)
 }
 
+// OpFileLock defines the interface for the advisory file lock operations.
+type OpFileLock interface {
+	SetFileLock(req *proto.SetFileLockRequest, p *Packet) (err error)
+	GetFileLock(req *proto.GetFileLockRequest, p *Packet) (err error)
+	RenewFileLocks(req *proto.RenewFileLocksRequest, p *Packet) (err error)
+}
+
+// OpFallocate defines the interface for the fallocate and copy_file_range operations.
+type OpFallocate interface {
+	Fallocate(req *proto.FallocateRequest, p *Packet) (err error)
+	CloneExtents(req *proto.CloneExtentsRequest, p *Packet) (err error)
+}
+
+// OpDedup defines the interface for the deduplication of the chunks.
+type OpDedup interface {
+	DedupExtents(req *proto.DedupExtentsRequest, p *Packet) (err error)
+	AddFingerprints(req *proto.AddFingerprintsRequest, p *Packet) (err error)
+	AcquireFingerprints(req *proto.AcquireFingerprintsRequest, p *Packet) (err error)
+	IndexFingerprints(req *proto.IndexFingerprintsRequest, p *Packet) (err error)
+	ReleaseFingerprints(req *proto.ReleaseFingerprintsRequest, p *Packet) (err error)
+}
+
+// OpSnapshotClone defines the interface for the writable clones of the snapshots.
+type OpSnapshotClone interface {
+	CloneSnapshotInode(req *proto.CloneSnapshotInodeRequest, p *Packet) (err error)
+}
+
+// OpSnapshotDiff defines the interface for the diffs between the snapshots.
+type OpSnapshotDiff interface {
+	SnapshotDiff(req *proto.SnapshotDiffRequest, p *Packet) (err error)
+}
+
+// OpCDC defines the interface for the change events of the partition.
+type OpCDC interface {
+	CDCRead(req *proto.CDCReadRequest, p *Packet) (err error)
+}
+
+// OpTiering defines the interface for the tiering of the data to the blobstore.
+type OpTiering interface {
+	TransitionExtents(req *proto.TransitionExtentsRequest, p *Packet) (err error)
+	RestoreExtents(req *proto.RestoreExtentsRequest, p *Packet) (err error)
+}
+
+// OpMetaRange defines the interface for the migration of the inode ranges between the partitions.
+type OpMetaRange interface {
+	FreezeMetaRange(req *proto.FreezeMetaRangeRequest, p *Packet) (err error)
+	ReadMetaRange(req *proto.ReadMetaRangeRequest, p *Packet) (err error)
+	MigrateMetaRange(req *proto.MigrateMetaRangeRequest, done func(resp *proto.MigrateMetaRangeResponse)) (err error)
+	CutoverMetaRange(req *proto.CutoverMetaRangeRequest, p *Packet) (err error)
+	acquireMetaRange(p *Packet) (release func(), ok bool)
+}
+
 // MultiVersion operation from master or client
 type OpMultiVersion interface {
 	GetVerSeq() uint64
@@ -246,6 +301,14 @@ type OpMeta interface {
 	OpTransaction
 	OpQuota
 	OpMultiVersion
+	OpFileLock
+	OpFallocate
+	OpDedup
+	OpSnapshotClone
+	OpSnapshotDiff
+	OpCDC
+	OpTiering
+	OpMetaRange
 }
 
 // OpPartition defines the interface for the partition operations.
@@ -513,6 +576,7 @@ type metaPartition struct {
 	multipartTree         MultipartTree         // collection for multipart management
 	deletedExtentsTree    DeletedExtentsTree    // deleted extents
 	deletedObjExtentsTree DeletedObjExtentsTree // deleted obj extents
+	fingerprintTree       FingerprintTree       // fingerprints of the deduplicated chunks
 	deletedExtentId       uint64
 	rocksdbManager        RocksdbManager
 	db                    *RocksdbOperator
@@ -542,12 +606,20 @@ type metaPartition struct {
 	mqMgr                  *MetaQuotaManager
 	nonIdempotent          sync.Mutex
 	uniqChecker            *uniqChecker
+	fileLocks              *fileLockTable
+	sharedExtents          *sharedExtents
 	verSeq                 uint64
 	multiVersionList       *proto.VolVersionInfoList
 	versionLock            sync.Mutex
 	verUpdateChan          chan []byte
 	enableAuditLog         bool
 	waitPersistCommitCnt   uint64
+	cdcEnabled             int32      // the change events are recorded, set by the volume
+	cdcLock                sync.Mutex // protects cdc
+	cdc                    *cdcLog
+	rangeLock              sync.RWMutex // held by the requests in flight, locked while the range is frozen
+	rangeState             atomic.Value // *metaRangeState
+	rangeMigrating         int32        // the range is being copied from the source partition
 }
 
 var _ MetaPartition = &metaPartition{}
@@ -742,6 +814,19 @@ func (mp *metaPartition) versionInit(isCreate bool) (err error) {
 	return
 }
 
+func newEbsClient(ebsAddr string, blockSize int) (*blobstore.BlobStoreClient, error) {
+	return blobstore.NewEbsClient(
+		access.Config{
+			ConnMode: access.NoLimitConnMode,
+			Consul: access.ConsulConfig{
+				Address: ebsAddr,
+			},
+			MaxSizePutOnce: int64(blockSize),
+			Logger:         &access.Logger{Filename: path.Join(log.LogDir, "ebs.log")},
+		},
+	)
+}
+
 func (mp *metaPartition) onStart(isCreate bool) (err error) {
 	defer func() {
 		if err == nil {
@@ -761,6 +846,7 @@ func (mp *metaPartition) onStart(isCreate bool) (err error) {
 	log.LogDebugf("[onStart] mp(%v) start deleted extents traveler", mp.config.PartitionId)
 	mp.startDeleteExtentsTraveler()
 	mp.startDeleteObjExtentsTraveler()
+	mp.startDropMovedRanges()
 
 	// set EBS Client
 	if clusterInfo, err = masterClient.AdminAPI().GetClusterInfo(); err != nil {
@@ -775,23 +861,14 @@ func (mp *metaPartition) onStart(isCreate bool) (err error) {
 	}
 
 	mp.vol.volDeleteLockTime = volumeInfo.DeleteLockTime
+	mp.setCDCEnabled(volumeInfo.EnableCDC)
 
 	go mp.runVersionOp()
 
 	mp.volType = volumeInfo.VolType
 	var ebsClient *blobstore.BlobStoreClient
 	if clusterInfo.EbsAddr != "" && proto.IsCold(mp.volType) {
-		ebsClient, err = blobstore.NewEbsClient(
-			access.Config{
-				ConnMode: access.NoLimitConnMode,
-				Consul: access.ConsulConfig{
-					Address: clusterInfo.EbsAddr,
-				},
-				MaxSizePutOnce: int64(volumeInfo.ObjBlockSize),
-				Logger:         &access.Logger{Filename: path.Join(log.LogDir, "ebs.log")},
-			},
-		)
-
+		ebsClient, err = newEbsClient(clusterInfo.EbsAddr, volumeInfo.ObjBlockSize)
 		if err != nil {
 			log.LogErrorf("action[onStart] err[%v]", err)
 			return
@@ -801,6 +878,13 @@ func (mp *metaPartition) onStart(isCreate bool) (err error) {
 			return
 		}
 		mp.ebsClient = ebsClient
+	} else if clusterInfo.EbsAddr != "" {
+		// the data of the hot volume may be transitioned to the blobstore
+		if ebsClient, err = newEbsClient(clusterInfo.EbsAddr, volumeInfo.ObjBlockSize); err != nil {
+			log.LogWarnf("action[onStart] mp(%v) new ebs client for hot vol err[%v]", mp.config.PartitionId, err)
+			err = nil
+		}
+		mp.ebsClient = ebsClient
 	}
 
 	go mp.startCheckerEvict()
@@ -840,6 +924,7 @@ func (mp *metaPartition) startScheduleTask() {
 func (mp *metaPartition) onStop() {
 	mp.stopRaft()
 	mp.stop()
+	mp.closeCDCLog()
 }
 
 func (mp *metaPartition) startRaft() (err error) {
@@ -912,13 +997,15 @@ func (mp *metaPartition) getRaftPort() (heartbeat, replica int, err error) {
 // NewMetaPartition creates a new meta partition with the specified configuration.
 func NewMetaPartition(conf *MetaPartitionConfig, manager *metadataManager) MetaPartition {
 	mp := &metaPartition{
-		config:      conf,
-		stopC:       make(chan bool),
-		storeChan:   make(chan *storeMsg, 100),
-		vol:         NewVol(),
-		manager:     manager,
-		uniqChecker: newUniqChecker(),
-		verSeq:      conf.VerSeq,
+		config:        conf,
+		stopC:         make(chan bool),
+		storeChan:     make(chan *storeMsg, 100),
+		vol:           NewVol(),
+		manager:       manager,
+		uniqChecker:   newUniqChecker(),
+		fileLocks:     newFileLockTable(),
+		sharedExtents: newSharedExtents(),
+		verSeq:        conf.VerSeq,
 		multiVersionList: &proto.VolVersionInfoList{
 			TemporaryVerMap: make(map[uint64]*proto.VolVersionInfo),
 		},
@@ -937,6 +1024,7 @@ func (mp *metaPartition) initMemoryTree() {
 	mp.multipartTree = &MultipartBTree{NewBtree()}
 	mp.deletedExtentsTree = &DeletedExtentsBTree{NewBtree()}
 	mp.deletedObjExtentsTree = &DeletedObjExtentsBTree{NewBtree()}
+	mp.fingerprintTree = &FingerprintBTree{NewBtree()}
 }
 
 func (mp *metaPartition) initRocksDBTree() (err error) {
@@ -964,6 +1052,9 @@ func (mp *metaPartition) initRocksDBTree() (err error) {
 	if mp.deletedObjExtentsTree, err = NewDeletedObjExtentsRocks(tree); err != nil {
 		return
 	}
+	if mp.fingerprintTree, err = NewFingerprintRocks(tree); err != nil {
+		return
+	}
 	return
 }
 
@@ -1116,6 +1207,8 @@ const (
 	CRC_COUNT_MULTI_VER           int = 9
 	CRC_COUNT_DELETED_EXTENTS     int = 10
 	CRC_COUNT_DELETED_OBJ_EXTENTS int = 11
+	CRC_COUNT_SHARED_EXTENTS      int = 12
+	CRC_COUNT_FINGERPRINT         int = 13
 )
 
 func (mp *metaPartition) LoadDataFromRocksDb() (err error) {
@@ -1125,6 +1218,7 @@ func (mp *metaPartition) LoadDataFromRocksDb() (err error) {
 		mp.txProcessor.txManager.txIdAlloc.setTransactionID(txId)
 	}
 	log.LogDebugf("[LoadDataFromRocksDb] mp(%v) load tx id(%v)", mp.config.PartitionId, mp.txProcessor.txManager.txIdAlloc.getTransactionID())
+	err = mp.loadSharedExtentsFromRocksDb()
 	return
 }
 
@@ -1145,7 +1239,9 @@ func (mp *metaPartition) LoadSnapshot(snapshotPath string) (err error) {
 	crc_count := len(crcs)
 	if crc_count != CRC_COUNT_BASIC && crc_count != CRC_COUNT_TX_STUFF && crc_count != CRC_COUNT_UINQ_STUFF && crc_count != CRC_COUNT_MULTI_VER &&
 		crc_count != CRC_COUNT_DELETED_EXTENTS &&
-		crc_count != CRC_COUNT_DELETED_OBJ_EXTENTS {
+		crc_count != CRC_COUNT_DELETED_OBJ_EXTENTS &&
+		crc_count != CRC_COUNT_SHARED_EXTENTS &&
+		crc_count != CRC_COUNT_FINGERPRINT {
 		log.LogErrorf("action[LoadSnapshot] crc array length %d not match", len(crcs))
 		return ErrSnapshotCrcMismatch
 	}
@@ -1182,6 +1278,14 @@ func (mp *metaPartition) LoadSnapshot(snapshotPath string) (err error) {
 		loadFuncs = append(loadFuncs, mp.loadDeletedObjExtents)
 	}
 
+	if crc_count >= CRC_COUNT_SHARED_EXTENTS {
+		loadFuncs = append(loadFuncs, mp.loadSharedExtents)
+	}
+
+	if crc_count >= CRC_COUNT_FINGERPRINT {
+		loadFuncs = append(loadFuncs, mp.loadFingerprint)
+	}
+
 	// NOTE: load inodes first
 	errs := make([]error, len(loadFuncs))
 	errs[0] = mp.loadInode(snapshotPath, crcs[0])
@@ -1281,6 +1385,14 @@ func (mp *metaPartition) Clear() (err error) {
 		log.LogErrorf("[Clear] mp(%v) failed to clear deleted obj extents tree, err(%v)", mp.config.PartitionId, err)
 		return
 	}
+	if err = mp.fingerprintTree.Clear(handle); err != nil {
+		log.LogErrorf("[Clear] mp(%v) failed to clear fingerprint tree, err(%v)", mp.config.PartitionId, err)
+		return
+	}
+	if err = mp.clearSharedExtents(handle); err != nil {
+		log.LogErrorf("[Clear] mp(%v) failed to clear shared extents, err(%v)", mp.config.PartitionId, err)
+		return
+	}
 	// NOTE: delete metadata
 	if err = mp.inodeTree.DeleteMetadata(handle); err != nil {
 		log.LogErrorf("[Clear] mp(%v) failed to delete metadata, err(%v)", mp.config.PartitionId, err)
@@ -1409,6 +1521,7 @@ func (mp *metaPartition) load(isCreate bool) (err error) {
 		log.LogDebugf("[load] mp(%v) deleted extent id(%v)", mp.config.PartitionId, mp.deletedExtentsTree.GetDeletedExtentId())
 		log.LogDebugf("[load] mp(%v) deleted extents real len(%v)", mp.config.PartitionId, mp.deletedExtentsTree.RealCount())
 		log.LogDebugf("[load] mp(%v) deleted obj extents real len(%v)", mp.config.PartitionId, mp.deletedObjExtentsTree.RealCount())
+		log.LogDebugf("[load] mp(%v) fingerprint real len(%v)", mp.config.PartitionId, mp.fingerprintTree.RealCount())
 	}
 
 	return
@@ -1455,6 +1568,8 @@ func (mp *metaPartition) store(sm *storeMsg) (err error) {
 		mp.storeMultiVersion,
 		mp.storeDeletedExtents,
 		mp.storeDeletedObjExtents,
+		mp.storeSharedExtents,
+		mp.storeFingerprint,
 	}
 	for i, storeFunc := range storeFuncs {
 		var crc uint32
@@ -1537,6 +1652,10 @@ func (mp *metaPartition) nextInodeID() (inodeId uint64, err error) {
 			return 0, ErrInodeIDOutOfRange
 		}
 		newId := cur + 1
+		if mp.isInodeFrozen(newId) {
+			log.LogWarnf("nextInodeID: can't create inode in the frozen range, cur %d", cur)
+			return 0, ErrInodeIDOutOfRange
+		}
 		if atomic.CompareAndSwapUint64(&mp.config.Cursor, cur, newId) {
 			return newId, nil
 		}
@@ -2080,10 +2199,11 @@ func (mp *metaPartition) storeSnapshotFiles() (err error) {
 	}
 	defer snap.Close()
 	msg := &storeMsg{
-		snap:         snap,
-		uniqId:       mp.GetUniqId(),
-		uniqChecker:  newUniqChecker(),
-		multiVerList: mp.multiVersionList.VerList,
+		snap:          snap,
+		uniqId:        mp.GetUniqId(),
+		uniqChecker:   newUniqChecker(),
+		sharedExtents: mp.sharedExtents.clone(),
+		multiVerList:  mp.multiVersionList.VerList,
 	}
 
 	return mp.store(msg)
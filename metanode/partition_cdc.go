@@ -0,0 +1,261 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const maxCDCReadLimit = 10000
+
+func (mp *metaPartition) isCDCEnabled() bool {
+	return atomic.LoadInt32(&mp.cdcEnabled) == 1
+}
+
+// setCDCEnabled switches the recording of the change events by the volume. The events recorded
+// are kept for the readers after the recording is disabled, until they are removed by the retention.
+func (mp *metaPartition) setCDCEnabled(enable bool) {
+	var val int32
+	if enable {
+		val = 1
+	}
+	if atomic.SwapInt32(&mp.cdcEnabled, val) != val {
+		log.LogInfof("[setCDCEnabled] mp(%v) vol(%v) cdc enabled(%v)", mp.config.PartitionId, mp.config.VolName, enable)
+	}
+}
+
+// getCDCLog opens the log of the change events on the first use, it returns nil if the log
+// does not exist and create is false.
+func (mp *metaPartition) getCDCLog(create bool) (cl *cdcLog, err error) {
+	mp.cdcLock.Lock()
+	defer mp.cdcLock.Unlock()
+	if mp.cdc != nil || !create && !mp.hasCDCLog() {
+		return mp.cdc, nil
+	}
+	if mp.cdc, err = openCDCLog(path.Join(mp.config.RootDir, cdcDirName), defaultCDCSegmentSize,
+		defaultCDCRetainSize); err != nil {
+		mp.cdc = nil
+		return
+	}
+	return mp.cdc, nil
+}
+
+func (mp *metaPartition) hasCDCLog() bool {
+	_, err := os.Stat(path.Join(mp.config.RootDir, cdcDirName))
+	return err == nil
+}
+
+func (mp *metaPartition) closeCDCLog() {
+	mp.cdcLock.Lock()
+	defer mp.cdcLock.Unlock()
+	if mp.cdc != nil {
+		mp.cdc.close()
+		mp.cdc = nil
+	}
+}
+
+// recordCDC records the change events of the raft entry applied successfully.
+func (mp *metaPartition) recordCDC(msg *MetaItem, index uint64, resp interface{}) {
+	if !mp.isCDCEnabled() {
+		return
+	}
+	events := mp.cdcEvents(msg, resp)
+	if len(events) == 0 {
+		return
+	}
+	now := time.Now().Unix()
+	for _, event := range events {
+		event.Vol = mp.config.VolName
+		event.PartitionID = mp.config.PartitionId
+		event.ApplyID = index
+		event.Time = now
+	}
+	cl, err := mp.getCDCLog(true)
+	if err == nil {
+		err = cl.append(index, events)
+	}
+	if err != nil {
+		log.LogErrorf("[recordCDC] mp(%v) apply(%v) op(%v) record events failed: %v",
+			mp.config.PartitionId, index, msg.Op, err)
+	}
+}
+
+func newCDCInodeEvent(op uint8, ino *Inode) *proto.CDCEvent {
+	return &proto.CDCEvent{Op: op, Inode: ino.Inode, Type: ino.Type, Size: ino.Size}
+}
+
+func newCDCDentryEvent(op uint8, d *Dentry) *proto.CDCEvent {
+	return &proto.CDCEvent{Op: op, ParentId: d.ParentId, Name: d.Name, Inode: d.Inode, Type: d.Type}
+}
+
+func newCDCXAttrEvent(op uint8, extend *Extend) *proto.CDCEvent {
+	event := &proto.CDCEvent{Op: op, Inode: extend.GetInode()}
+	extend.Range(func(key, value []byte) bool {
+		event.Keys = append(event.Keys, string(key))
+		return true
+	})
+	return event
+}
+
+func isInodeRespOk(resp interface{}) bool {
+	r, ok := resp.(*InodeResponse)
+	return ok && r.Status == proto.OpOk
+}
+
+func isDentryRespOk(resp interface{}) bool {
+	r, ok := resp.(*DentryResponse)
+	return ok && r.Status == proto.OpOk
+}
+
+func isXAttrRespOk(resp interface{}) bool {
+	r, ok := resp.(*proto.XAttrRaftResponse)
+	return ok && r.Status == proto.OpOk
+}
+
+// cdcEvents decodes the mutations of the namespace from the raft entry again, the entries of the
+// other ops and the ones failed record nothing. The mutations of the transactions are not recorded.
+func (mp *metaPartition) cdcEvents(msg *MetaItem, resp interface{}) (events []*proto.CDCEvent) {
+	var err error
+	switch msg.Op {
+	case opFSMCreateInode:
+		ino := NewInode(0, 0)
+		if err = ino.Unmarshal(msg.V); err == nil && resp == proto.OpOk {
+			events = append(events, newCDCInodeEvent(proto.CDCCreateInode, ino))
+		}
+	case opFSMCreateInodeQuota:
+		qinode := &MetaQuotaInode{}
+		if err = qinode.Unmarshal(msg.V); err == nil && resp == proto.OpOk {
+			events = append(events, newCDCInodeEvent(proto.CDCCreateInode, qinode.inode))
+		}
+	case opFSMUnlinkInode, opFSMUnlinkInodeOnce, opFSMCreateLinkInode, opFSMCreateLinkInodeOnce:
+		if !isInodeRespOk(resp) {
+			return
+		}
+		op := proto.CDCUnlinkInode
+		if msg.Op == opFSMCreateLinkInode || msg.Op == opFSMCreateLinkInodeOnce {
+			op = proto.CDCLinkInode
+		}
+		if ino := resp.(*InodeResponse).Msg; ino != nil {
+			events = append(events, &proto.CDCEvent{Op: op, Inode: ino.Inode, Type: ino.Type, Size: ino.Size})
+		}
+	case opFSMUnlinkInodeBatch:
+		results, _ := resp.([]*InodeResponse)
+		for _, r := range results {
+			if r.Status == proto.OpOk && r.Msg != nil {
+				events = append(events, newCDCInodeEvent(proto.CDCUnlinkInode, r.Msg))
+			}
+		}
+	case opFSMEvictInode:
+		ino := NewInode(0, 0)
+		if err = ino.Unmarshal(msg.V); err == nil && isInodeRespOk(resp) {
+			events = append(events, &proto.CDCEvent{Op: proto.CDCEvictInode, Inode: ino.Inode})
+		}
+	case opFSMEvictInodeBatch:
+		var inodes InodeBatch
+		results, _ := resp.([]*InodeResponse)
+		if inodes, err = InodeBatchUnmarshal(msg.V); err != nil || len(inodes) != len(results) {
+			return
+		}
+		for i, r := range results {
+			if r.Status == proto.OpOk {
+				events = append(events, &proto.CDCEvent{Op: proto.CDCEvictInode, Inode: inodes[i].Inode})
+			}
+		}
+	case opFSMSetAttr:
+		req := &SetattrRequest{}
+		if err = json.Unmarshal(msg.V, req); err == nil && isInodeRespOk(resp) {
+			events = append(events, &proto.CDCEvent{Op: proto.CDCSetAttr, Inode: req.Inode})
+		}
+	case opFSMExtentTruncate:
+		ino := NewInode(0, 0)
+		if err = ino.Unmarshal(msg.V); err == nil && isInodeRespOk(resp) {
+			events = append(events, &proto.CDCEvent{Op: proto.CDCTruncate, Inode: ino.Inode, Size: ino.Size})
+		}
+	case opFSMCreateDentry:
+		den := &Dentry{}
+		if err = den.Unmarshal(msg.V); err == nil && resp == proto.OpOk {
+			events = append(events, newCDCDentryEvent(proto.CDCCreateDentry, den))
+		}
+	case opFSMDeleteDentry:
+		if isDentryRespOk(resp) && resp.(*DentryResponse).Msg != nil {
+			events = append(events, newCDCDentryEvent(proto.CDCDeleteDentry, resp.(*DentryResponse).Msg))
+		}
+	case opFSMDeleteDentryBatch:
+		results, _ := resp.([]*DentryResponse)
+		for _, r := range results {
+			if r.Status == proto.OpOk && r.Msg != nil {
+				events = append(events, newCDCDentryEvent(proto.CDCDeleteDentry, r.Msg))
+			}
+		}
+	case opFSMUpdateDentry:
+		// the dentry of the response has the inode replaced
+		den := &Dentry{}
+		if err = den.Unmarshal(msg.V); err == nil && isDentryRespOk(resp) && resp.(*DentryResponse).Msg != nil {
+			event := newCDCDentryEvent(proto.CDCUpdateDentry, den)
+			event.OldInode = resp.(*DentryResponse).Msg.Inode
+			events = append(events, event)
+		}
+	case opFSMSetXAttr, opFSMRemoveXAttr:
+		var extend *Extend
+		if extend, err = NewExtendFromBytes(msg.V); err == nil && isXAttrRespOk(resp) {
+			op := proto.CDCSetXAttr
+			if msg.Op == opFSMRemoveXAttr {
+				op = proto.CDCRemoveXAttr
+			}
+			events = append(events, newCDCXAttrEvent(op, extend))
+		}
+	}
+	if err != nil {
+		log.LogWarnf("[cdcEvents] mp(%v) op(%v) decode failed: %v", mp.config.PartitionId, msg.Op, err)
+	}
+	return
+}
+
+// CDCRead reads the change events recorded after the cursor of the request.
+func (mp *metaPartition) CDCRead(req *proto.CDCReadRequest, p *Packet) (err error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = proto.DefaultCDCReadLimit
+	}
+	if limit > maxCDCReadLimit {
+		limit = maxCDCReadLimit
+	}
+
+	resp := &proto.CDCReadResponse{Next: req.From}
+	cl, err := mp.getCDCLog(false)
+	if err == nil && cl != nil {
+		resp, err = cl.read(req.From, limit)
+	}
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp.Enabled = mp.isCDCEnabled()
+
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
@@ -505,6 +505,13 @@ var (
 
 func (mp *metaPartition) batchDeleteExtentsHotVol(dpId uint64, deks []*DeletedExtentKey) (err error) {
 	log.LogDebugf("[batchDeleteExtentsHotVol] mp(%v) delete dp(%v) extents count(%v)", mp.config.PartitionId, dpId, len(deks))
+	eks := make([]*proto.ExtentKey, 0, len(deks))
+	for _, dek := range deks {
+		eks = append(eks, &dek.ExtentKey)
+	}
+	if eks, err = mp.checkSharedExtents(eks); err != nil || len(eks) == 0 {
+		return
+	}
 	// get the data node view
 	dp := mp.vol.GetPartition(dpId)
 	if dp == nil {
@@ -533,10 +540,6 @@ func (mp *metaPartition) batchDeleteExtentsHotVol(dpId uint64, deks []*DeletedEx
 		err = ErrDataPartitionUnreachable
 		return
 	}
-	eks := make([]*proto.ExtentKey, 0, len(deks))
-	for _, dek := range deks {
-		eks = append(eks, &dek.ExtentKey)
-	}
 	p := NewPacketToBatchDeleteExtent(dp, eks)
 	if err = p.WriteToConn(conn); err != nil {
 		err = ErrDataPartitionUnreachable
@@ -565,6 +568,9 @@ func (mp *metaPartition) batchDeleteExtentsHotVol(dpId uint64, deks []*DeletedEx
 const maxDelCntOnce = 512
 
 func (mp *metaPartition) batchDeleteExtentsColdVol(doeks []*DeletedObjExtentKey) (err error) {
+	if mp.ebsClient == nil {
+		return fmt.Errorf("mp(%v) has no ebs client", mp.config.PartitionId)
+	}
 	total := len(doeks)
 	oeks := make([]proto.ObjExtentKey, maxDelCntOnce)
 	for i := 0; i < total; i += maxDelCntOnce {
@@ -727,6 +733,7 @@ func (mp *metaPartition) deletedExtentsTreeTraveler() (err error) {
 		if err != nil {
 			return
 		}
+		mp.dropDedupExtents()
 	}
 }
 
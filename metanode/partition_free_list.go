@@ -43,6 +43,7 @@ func (mp *metaPartition) UpdateVolView(volView *proto.SimpleVolView, dataView *D
 	mp.vol.UpdatePartitions(dataView)
 
 	mp.vol.volDeleteLockTime = volView.DeleteLockTime
+	mp.setCDCEnabled(volView.EnableCDC)
 }
 
 // TODO(NaturalSelect): remove those code
@@ -110,6 +111,15 @@ func (mp *metaPartition) deleteWorker() {
 				break
 			}
 
+			// the inodes of the moved range are freed by the target partition
+			if mp.isInodeMoved(ino) {
+				continue
+			}
+			if mp.isInodeFrozen(ino) {
+				delayDeleteInos = append(delayDeleteInos, ino)
+				continue
+			}
+
 			// check inode nlink == 0 and deleteMarkFlag unset
 			if inode, err := mp.inodeTree.RefGet(ino); err == nil {
 				inTx, _, err := mp.txProcessor.txResource.isInodeInTransction(inode)
@@ -241,7 +251,7 @@ func (mp *metaPartition) deleteMarkedInodes(inoSlice []uint64) {
 		allInodes = append(allInodes, inode)
 	}
 
-	if proto.IsCold(mp.volType) {
+	if proto.IsCold(mp.volType) || mp.ebsClient != nil {
 		// delete ebs obj extents
 		shouldCommit, shouldRePushToFreeList = mp.doBatchDeleteObjExtentsInEBS(allInodes)
 		log.LogInfof("[deleteMarkedInodes] metaPartition(%v) deleteInodeCnt(%d) shouldRePush(%d)",
@@ -390,6 +400,9 @@ func (mp *metaPartition) doDeleteMarkedInodes(ext *proto.ExtentKey) (err error)
 }
 
 func (mp *metaPartition) doBatchDeleteExtentsByPartition(partitionID uint64, exts []*proto.ExtentKey) (err error) {
+	if exts, err = mp.checkSharedExtents(exts); err != nil || len(exts) == 0 {
+		return
+	}
 	// get the data node view
 	dp := mp.vol.GetPartition(partitionID)
 	if dp == nil {
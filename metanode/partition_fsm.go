@@ -70,7 +70,9 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 		err = mp.inodeTree.CommitAndReleaseBatchWriteHandle(dbWriteHandle, true)
 		if err != nil {
 			log.LogErrorf("[Apply] failed to commit write batch, is disk broken? err(%v)", err)
+			return
 		}
+		mp.recordCDC(msg, index, resp)
 	}()
 	log.LogInfof("[Apply] apply mp(%v) op(%v)", mp.config.PartitionId, msg.Op)
 	switch msg.Op {
@@ -306,6 +308,7 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 		quotaRebuild := mp.mqMgr.statisticRebuildStart()
 		uidRebuild := mp.acucumRebuildStart()
 		uniqChecker := mp.uniqChecker.clone()
+		sharedExtents := mp.sharedExtents.clone()
 		// NOTE: already got lock
 		var snap Snapshot
 		snap, err = mp.GetSnapShot()
@@ -314,12 +317,13 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			return
 		}
 		snapMsg := &storeMsg{
-			command:      opFSMStoreTick,
-			snap:         snap,
-			quotaRebuild: quotaRebuild,
-			uidRebuild:   uidRebuild,
-			uniqChecker:  uniqChecker,
-			multiVerList: mp.GetAllVerList(),
+			command:       opFSMStoreTick,
+			snap:          snap,
+			quotaRebuild:  quotaRebuild,
+			uidRebuild:    uidRebuild,
+			uniqChecker:   uniqChecker,
+			sharedExtents: sharedExtents,
+			multiVerList:  mp.GetAllVerList(),
 		}
 
 		log.LogDebugf("opFSMStoreTick: quotaRebuild [%v] uidRebuild [%v]", quotaRebuild, uidRebuild)
@@ -517,6 +521,100 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			return
 		}
 		err = mp.fsmDeleteObjExtentsFromTree(dbWriteHandle, req)
+	case opFSMFallocate:
+		req := &proto.FallocateRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmFallocate(dbWriteHandle, req)
+	case opFSMCloneExtents:
+		req := &proto.CloneExtentsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmCloneExtents(dbWriteHandle, req)
+	case opFSMCloneSnapshotInode:
+		req := &proto.CloneSnapshotInodeRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		if mp.config.Cursor < req.NewInode {
+			mp.config.Cursor = req.NewInode
+			mp.inodeTree.SetCursor(req.NewInode)
+		}
+		resp, err = mp.fsmCloneSnapshotInode(dbWriteHandle, req)
+	case opFSMReleaseSharedExtents:
+		req := &fsmReleaseSharedExtentsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmReleaseSharedExtents(dbWriteHandle, req)
+	case opFSMDedupExtents:
+		req := &fsmDedupExtentsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmDedupExtents(dbWriteHandle, req)
+	case opFSMAddFingerprints:
+		req := &fsmAddFingerprintsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmAddFingerprints(dbWriteHandle, req)
+	case opFSMAcquireFingerprints:
+		req := &proto.IndexFingerprintsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmAcquireFingerprints(dbWriteHandle, req)
+	case opFSMIndexFingerprints:
+		req := &proto.IndexFingerprintsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmIndexFingerprints(dbWriteHandle, req)
+	case opFSMReleaseFingerprints:
+		req := &proto.ReleaseFingerprintsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmReleaseFingerprints(dbWriteHandle, req)
+	case opFSMDropDedupExtents:
+		req := &fsmReleaseSharedExtentsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmDropDedupExtents(dbWriteHandle, req)
+	case opFSMTransitionExtents:
+		req := &proto.TransitionExtentsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmTransitionExtents(dbWriteHandle, req)
+	case opFSMRestoreExtents:
+		req := &proto.RestoreExtentsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmRestoreExtents(dbWriteHandle, req)
+	case opFSMFreezeMetaRange:
+		req := &proto.FreezeMetaRangeRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp = mp.fsmFreezeMetaRange(req)
+	case opFSMSyncMetaRange:
+		batch := &metaRangeBatch{}
+		if err = json.Unmarshal(msg.V, batch); err != nil {
+			return
+		}
+		resp, err = mp.fsmSyncMetaRange(dbWriteHandle, batch)
+	case opFSMCutoverMetaRange:
+		req := &proto.CutoverMetaRangeRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp = mp.fsmCutoverMetaRange(req)
 	default:
 		// do nothing
 	}
@@ -692,6 +790,7 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 		uniqID           uint64
 		cursor           uint64
 		uniqChecker      = newUniqChecker()
+		sharedExtents    = newSharedExtents()
 		verList          []*proto.VolVersionInfo
 		deletedExtentsId uint64
 		dbWriteHandle    interface{}
@@ -770,12 +869,17 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 			mp.txProcessor.txManager.txTree.SetTxId(txID)
 			mp.config.Cursor = cursor
 			mp.uniqChecker = uniqChecker
+			mp.sharedExtents = sharedExtents
 			mp.multiVersionList.VerList = make([]*proto.VolVersionInfo, len(verList))
 			copy(mp.multiVersionList.VerList, verList)
 			mp.verSeq = mp.multiVersionList.GetLastVer()
 			log.LogInfof("mp[%v] updateVerList (%v) seq [%v]", mp.config.PartitionId, mp.multiVersionList.VerList, mp.verSeq)
 			mp.SetDeletedExtentId(deletedExtentsId)
 			mp.deletedExtentsTree.SetDeletedExtentId(deletedExtentsId)
+			if err = mp.persistSharedExtents(dbWriteHandle, sharedExtents, sharedExtents.ids()...); err != nil {
+				log.LogErrorf("[ApplySnapshot] mp(%v) failed to write shared extents, err(%v)", mp.config.PartitionId, err)
+				return
+			}
 			// NOTE: store rocksdb metadata
 			err = mp.inodeTree.CommitBatchWrite(dbWriteHandle, true)
 			if err != nil {
@@ -795,10 +899,11 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 				return
 			}
 			mp.storeChan <- &storeMsg{
-				command:      opFSMStoreTick,
-				snap:         snap,
-				uniqChecker:  uniqChecker.clone(),
-				multiVerList: mp.GetVerList(),
+				command:       opFSMStoreTick,
+				snap:          snap,
+				uniqChecker:   uniqChecker.clone(),
+				sharedExtents: sharedExtents.clone(),
+				multiVerList:  mp.GetVerList(),
 			}
 			select {
 			case <-mp.stopC:
@@ -981,6 +1086,12 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 				return
 			}
 			log.LogDebugf("ApplySnapshot: write snap uniqChecker")
+		case opFSMSharedExtentsSnap:
+			if err = sharedExtents.UnMarshal(snap.V); err != nil {
+				log.LogErrorf("ApplySnapshot: write snap sharedExtents fail")
+				return
+			}
+			log.LogDebugf("ApplySnapshot: write snap sharedExtents")
 		case opFSMDeletedExtentsId:
 			deletedExtentsId = binary.BigEndian.Uint64(snap.V)
 			log.LogDebugf("ApplySnapshot: partitionID(%v) deleted extents id:%v", mp.config.PartitionId, deletedExtentsId)
@@ -1006,6 +1117,16 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 				return
 			}
 			log.LogDebugf("[ApplySnapshot] mp(%v) create doek", mp.config.PartitionId)
+		case opFSMFingerprintSnap:
+			fp := &Fingerprint{}
+			if err = fp.Unmarshal(snap.V); err != nil {
+				log.LogErrorf("[ApplySnapshot] mp(%v) failed to unmarshal fingerprint, err(%v)", mp.config.PartitionId, err)
+				return
+			}
+			if err = mp.fingerprintTree.Put(dbWriteHandle, fp); err != nil {
+				return
+			}
+			log.LogDebugf("[ApplySnapshot] mp(%v) create fingerprint", mp.config.PartitionId)
 		default:
 			if leaderSnapFormatVer != math.MaxUint32 && leaderSnapFormatVer > mp.manager.metaNode.raftSyncSnapFormatVersion {
 				log.LogWarnf("ApplySnapshot: unknown op=%d, leaderSnapFormatVer:%v, mySnapFormatVer:%v, skip it",
@@ -1060,11 +1181,13 @@ func (mp *metaPartition) HandleLeaderChange(leader uint64) {
 	if mp.config.NodeId != leader {
 		log.LogDebugf("[metaPartition] pid: %v HandleLeaderChange become unleader nodeId: %v, leader: %v", mp.config.PartitionId, mp.config.NodeId, leader)
 		exporter.Warning(fmt.Sprintf("[metaPartition] pid: %v HandleLeaderChange become unleader nodeId: %v, leader: %v", mp.config.PartitionId, mp.config.NodeId, leader))
+		mp.fileLocks.reset(false)
 		mp.storeChan <- &storeMsg{
 			command: stopStoreTick,
 		}
 		return
 	}
+	mp.fileLocks.reset(true)
 	mp.storeChan <- &storeMsg{
 		command: startStoreTick,
 	}
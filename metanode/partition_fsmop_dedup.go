@@ -0,0 +1,369 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/storage"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// dedupLink is a chunk of the inode at the file offset, referring to the data located by the fingerprint
+// partition Shard with the token.
+type dedupLink struct {
+	FileOffset uint64
+	Shard      uint64
+	Extent     proto.DedupExtent
+}
+
+func (l *dedupLink) chunk() *dedupChunk {
+	c := &dedupChunk{ExtentOffset: l.Extent.ExtentOffset, Size: l.Extent.Size, Shard: l.Shard}
+	copy(c.Hash[:], l.Extent.Fingerprint)
+	return c
+}
+
+// fsmDedupExtentsRequest links the chunks acquired from the fingerprint partitions to the inode, the
+// links are nil for the chunks not found.
+type fsmDedupExtentsRequest struct {
+	Inode      uint64
+	ModifyTime int64
+	Token      uint64
+	Links      []*dedupLink
+}
+
+// fsmAddFingerprintsRequest records the chunks written by the inode before they are indexed.
+type fsmAddFingerprintsRequest struct {
+	Inode uint64
+	Token uint64
+	Links []*dedupLink
+}
+
+type DedupExtentsResp struct {
+	Status  uint8
+	Extents []*proto.ExtentKey
+}
+
+// FingerprintsResp returns the result of each chunk of the fingerprint ops in the order of the request.
+type FingerprintsResp struct {
+	Status  uint8
+	Results []bool
+}
+
+// inodeCoversChunk checks whether a single key of the inode writes the chunk at the file offset to the
+// data of the extent.
+func inodeCoversChunk(i *Inode, fileOffset uint64, ext *proto.DedupExtent) (ok bool) {
+	end := fileOffset + uint64(ext.Size)
+	i.Extents.Range(func(_ int, key proto.ExtentKey) bool {
+		if key.FileOffset > fileOffset {
+			return false
+		}
+		if key.FileOffset+uint64(key.Size) < end {
+			return true
+		}
+		ok = key.PartitionId == ext.PartitionId && key.ExtentId == ext.ExtentId &&
+			key.ExtentOffset+fileOffset-key.FileOffset == ext.ExtentOffset &&
+			!key.IsCompressed() && !storage.IsTinyExtent(key.ExtentId)
+		return false
+	})
+	return
+}
+
+// fsmDedupExtents makes the chunks of the inode refer to the data acquired from the fingerprint partitions.
+// The chunks acquired are recorded even if they are not linked, and the keys of them not linked are freed
+// at once, so that the references of them are released by the fingerprint partitions.
+func (mp *metaPartition) fsmDedupExtents(dbHandle interface{}, req *fsmDedupExtentsRequest) (resp *DedupExtentsResp, err error) {
+	resp = &DedupExtentsResp{Status: proto.OpOk, Extents: make([]*proto.ExtentKey, len(req.Links))}
+	log.LogDebugf("[fsmDedupExtents] mp(%v) req(%v)", mp.config.PartitionId, req)
+
+	ids := make([]uint64, 0, len(req.Links))
+	for _, link := range req.Links {
+		if link == nil {
+			continue
+		}
+		ek := link.Extent.ExtentKey(link.FileOffset)
+		mp.sharedExtents.addChunk(ek.GenerateId(), link.chunk(), dedupRef{Inode: req.Inode, Token: req.Token})
+		ids = append(ids, ek.GenerateId())
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if err = mp.persistSharedExtents(dbHandle, mp.sharedExtents, ids...); err != nil {
+		resp.Status = proto.OpErr
+		return
+	}
+	if resp.Status, err = mp.linkDedupExtents(dbHandle, req, resp.Extents); err != nil {
+		return
+	}
+	if resp.Status != proto.OpOk {
+		for idx := range resp.Extents {
+			resp.Extents[idx] = nil
+		}
+	}
+	for idx, link := range req.Links {
+		if link == nil || resp.Extents[idx] != nil {
+			continue
+		}
+		ek := link.Extent.ExtentKey(link.FileOffset)
+		if err = mp.deletedExtentsTree.Put(dbHandle, NewDeletedExtentKey(&ek, req.Inode, mp.AllocDeletedExtentId())); err != nil {
+			resp.Status = proto.OpErr
+			return
+		}
+	}
+	return
+}
+
+func (mp *metaPartition) linkDedupExtents(dbHandle interface{}, req *fsmDedupExtentsRequest, extents []*proto.ExtentKey) (status uint8, err error) {
+	if mp.verSeq != 0 {
+		// the extents of snapshots are tracked by the split references of a single inode
+		return proto.OpNotPerm, nil
+	}
+	i, status := mp.getRegularInode(req.Inode)
+	if status != proto.OpOk {
+		return
+	}
+
+	eks := make([]proto.ExtentKey, 0, len(req.Links))
+	for idx, link := range req.Links {
+		if link == nil || i.Extents.HasCompressed(link.FileOffset, uint64(link.Extent.Size)) {
+			continue
+		}
+		ek := link.Extent.ExtentKey(link.FileOffset)
+		eks = append(eks, ek)
+		extents[idx] = &ek
+	}
+	if len(eks) == 0 {
+		return
+	}
+	if status = mp.uidManager.addUidSpace(i.Uid, i.Inode, eks); status != proto.OpOk {
+		return
+	}
+
+	oldSize := int64(i.Size)
+	var delExtents []proto.ExtentKey
+	for _, ek := range eks {
+		delExtents = append(delExtents, i.Extents.PunchHole(ek.FileOffset, uint64(ek.Size), func(ek *proto.ExtentKey) {
+			i.insertEkRefMap(mp.config.PartitionId, ek)
+		})...)
+		i.Extents.InsertRange([]proto.ExtentKey{ek})
+		if end := ek.FileOffset + uint64(ek.Size); end > i.Size {
+			i.Size = end
+		}
+	}
+	i.DecSplitExts(mp.config.PartitionId, delExtents)
+	i.ModifyTime = req.ModifyTime
+	i.Generation++
+	if err = mp.inodeTree.Put(dbHandle, i); err != nil {
+		log.LogErrorf("[fsmDedupExtents] mp(%v) inode(%v) put error:%v", mp.config.PartitionId, i.Inode, err)
+		return proto.OpErr, err
+	}
+	if err = mp.putDeletedExtents(dbHandle, i, delExtents); err != nil {
+		return proto.OpErr, err
+	}
+	mp.updateUsedInfo(int64(i.Size)-oldSize, 0, i.Inode)
+	log.LogInfof("[fsmDedupExtents] mp(%v) inode(%v) dedup extents(%v) delete extents(%v)",
+		mp.config.PartitionId, i.Inode, eks, delExtents)
+	return
+}
+
+// fsmAddFingerprints records the chunks written by the inode before they are indexed by the fingerprint
+// partitions, so that the client overwrites them by append and the references of them are released once
+// the inode no longer refers to them.
+func (mp *metaPartition) fsmAddFingerprints(dbHandle interface{}, req *fsmAddFingerprintsRequest) (resp *FingerprintsResp, err error) {
+	resp = &FingerprintsResp{Status: proto.OpOk, Results: make([]bool, len(req.Links))}
+	log.LogDebugf("[fsmAddFingerprints] mp(%v) req(%v)", mp.config.PartitionId, req)
+	if mp.verSeq != 0 {
+		resp.Status = proto.OpNotPerm
+		return
+	}
+	i, status := mp.getRegularInode(req.Inode)
+	if status != proto.OpOk {
+		resp.Status = status
+		return
+	}
+
+	ids := make([]uint64, 0, len(req.Links))
+	for idx, link := range req.Links {
+		if !inodeCoversChunk(i, link.FileOffset, &link.Extent) {
+			continue
+		}
+		ek := link.Extent.ExtentKey(link.FileOffset)
+		mp.sharedExtents.addChunk(ek.GenerateId(), link.chunk(), dedupRef{Inode: i.Inode, Token: req.Token})
+		ids = append(ids, ek.GenerateId())
+		resp.Results[idx] = true
+	}
+	if err = mp.persistSharedExtents(dbHandle, mp.sharedExtents, ids...); err != nil {
+		resp.Status = proto.OpErr
+		return
+	}
+	log.LogDebugf("[fsmAddFingerprints] mp(%v) inode(%v) add fingerprints of extents(%v)", mp.config.PartitionId, i.Inode, ids)
+	return
+}
+
+// fsmAcquireFingerprints refers to the chunks verified by the leader if the fingerprints still locate them.
+func (mp *metaPartition) fsmAcquireFingerprints(dbHandle interface{}, req *proto.IndexFingerprintsRequest) (resp *FingerprintsResp, err error) {
+	resp = &FingerprintsResp{Status: proto.OpOk, Results: make([]bool, len(req.Extents))}
+	for idx := range req.Extents {
+		ext := &req.Extents[idx]
+		var fp *Fingerprint
+		if fp, err = mp.fingerprintTree.Get(ext.Fingerprint); err != nil {
+			resp.Status = proto.OpErr
+			return
+		}
+		if fp == nil || !fp.Locates(ext) || len(fp.Refs) == 0 {
+			continue
+		}
+		fp = fp.Copy().(*Fingerprint)
+		fp.AddRef(req.RefPartitionID, req.RefToken)
+		if err = mp.fingerprintTree.Put(dbHandle, fp); err != nil {
+			resp.Status = proto.OpErr
+			return
+		}
+		resp.Results[idx] = true
+	}
+	log.LogDebugf("[fsmAcquireFingerprints] mp(%v) ref mp(%v) token(%v) acquired(%v)",
+		mp.config.PartitionId, req.RefPartitionID, req.RefToken, resp.Results)
+	return
+}
+
+// fsmIndexFingerprints indexes the chunks verified by the leader, a chunk is not indexed if the fingerprint
+// locates another copy of the data.
+func (mp *metaPartition) fsmIndexFingerprints(dbHandle interface{}, req *proto.IndexFingerprintsRequest) (resp *FingerprintsResp, err error) {
+	resp = &FingerprintsResp{Status: proto.OpOk, Results: make([]bool, len(req.Extents))}
+	for idx := range req.Extents {
+		ext := &req.Extents[idx]
+		var fp *Fingerprint
+		if fp, err = mp.fingerprintTree.Get(ext.Fingerprint); err != nil {
+			resp.Status = proto.OpErr
+			return
+		}
+		if fp == nil {
+			if fp, err = NewFingerprint(ext.Fingerprint); err != nil {
+				resp.Status = proto.OpArgMismatchErr
+				return resp, nil
+			}
+			fp.PartitionId = ext.PartitionId
+			fp.ExtentId = ext.ExtentId
+			fp.ExtentOffset = ext.ExtentOffset
+			fp.Size = ext.Size
+		} else if fp.Locates(ext) {
+			fp = fp.Copy().(*Fingerprint)
+		} else {
+			continue
+		}
+		fp.AddRef(req.RefPartitionID, req.RefToken)
+		if err = mp.fingerprintTree.Put(dbHandle, fp); err != nil {
+			resp.Status = proto.OpErr
+			return
+		}
+		resp.Results[idx] = true
+	}
+	log.LogDebugf("[fsmIndexFingerprints] mp(%v) ref mp(%v) token(%v) indexed(%v)",
+		mp.config.PartitionId, req.RefPartitionID, req.RefToken, resp.Results)
+	return
+}
+
+// fsmReleaseFingerprints drops the references to the chunks, the fingerprint is deleted and the data of it
+// is freed once nothing refers to it. The result of a chunk is false if it is not indexed here.
+func (mp *metaPartition) fsmReleaseFingerprints(dbHandle interface{}, req *proto.ReleaseFingerprintsRequest) (resp *FingerprintsResp, err error) {
+	resp = &FingerprintsResp{Status: proto.OpOk, Results: make([]bool, len(req.Releases))}
+	for idx := range req.Releases {
+		release := &req.Releases[idx]
+		var fp *Fingerprint
+		if fp, err = mp.fingerprintTree.Get(release.Fingerprint); err != nil {
+			resp.Status = proto.OpErr
+			return
+		}
+		if fp == nil || !fp.Locates(&release.DedupExtent) {
+			continue
+		}
+		resp.Results[idx] = true
+		fp = fp.Copy().(*Fingerprint)
+		fp.ReleaseRefs(req.RefPartitionID, release.RefTokens)
+		if len(fp.Refs) > 0 {
+			if err = mp.fingerprintTree.Put(dbHandle, fp); err != nil {
+				resp.Status = proto.OpErr
+				return
+			}
+			continue
+		}
+		if _, err = mp.fingerprintTree.Delete(dbHandle, fp); err != nil {
+			resp.Status = proto.OpErr
+			return
+		}
+		ek := release.ExtentKey(0)
+		if err = mp.deletedExtentsTree.Put(dbHandle, NewDeletedExtentKey(&ek, 0, mp.AllocDeletedExtentId())); err != nil {
+			resp.Status = proto.OpErr
+			return
+		}
+		log.LogInfof("[fsmReleaseFingerprints] mp(%v) free %v", mp.config.PartitionId, fp)
+	}
+	return
+}
+
+// isDedupExtentReferred checks whether the alive inodes recorded still refer to the extent deduplicated, and
+// whether the keys of it freed from the inodes recorded are still to be freed.
+func (mp *metaPartition) isDedupExtentReferred(id uint64) (alive, pending bool, err error) {
+	inodes := mp.sharedExtents.get(id)
+	for _, c := range mp.sharedExtents.getChunks(id) {
+		inodes = appendInodes(inodes, c.inodes()...)
+	}
+	ek := &proto.ExtentKey{PartitionId: id >> 32, ExtentId: id & 0xFFFFFFFF}
+	for _, ino := range inodes {
+		var i *Inode
+		if i, err = mp.inodeTree.Get(ino); err != nil {
+			return
+		}
+		if i != nil && inodeRefersExtent(i, ek, false) {
+			if !i.ShouldDelete() {
+				return true, true, nil
+			}
+			pending = true
+			continue
+		}
+		start := &DeletedExtentKey{Inode: ino, ExtentKey: proto.ExtentKey{PartitionId: ek.PartitionId, ExtentId: ek.ExtentId}}
+		end := &DeletedExtentKey{Inode: ino, ExtentKey: proto.ExtentKey{PartitionId: ek.PartitionId, ExtentId: ek.ExtentId + 1}}
+		err = mp.deletedExtentsTree.Range(start, end, func(dek *DeletedExtentKey) (bool, error) {
+			pending = true
+			return false, nil
+		})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// fsmDropDedupExtents forgets the extents deduplicated which are no longer referred by the partition.
+func (mp *metaPartition) fsmDropDedupExtents(dbHandle interface{}, req *fsmReleaseSharedExtentsRequest) (dropped []uint64, err error) {
+	for _, id := range req.Extents {
+		if !mp.sharedExtents.isDedup(id) {
+			continue
+		}
+		var alive, pending bool
+		if alive, pending, err = mp.isDedupExtentReferred(id); err != nil {
+			return
+		}
+		if alive || pending {
+			continue
+		}
+		mp.sharedExtents.dropDedup(id)
+		if err = mp.persistSharedExtents(dbHandle, mp.sharedExtents, id); err != nil {
+			return
+		}
+		dropped = append(dropped, id)
+	}
+	log.LogInfof("[fsmDropDedupExtents] mp(%v) drop dedup extents(%v)", mp.config.PartitionId, dropped)
+	return
+}
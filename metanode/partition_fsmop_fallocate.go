@@ -0,0 +1,304 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/storage"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+type fsmReleaseSharedExtentsRequest struct {
+	Extents []uint64
+}
+
+type CloneExtentsResp struct {
+	Status uint8
+	Size   uint64
+}
+
+func (mp *metaPartition) getRegularInode(ino uint64) (i *Inode, status uint8) {
+	i, err := mp.inodeTree.Get(ino)
+	if err != nil {
+		return nil, proto.OpErr
+	}
+	if i == nil || i.ShouldDelete() {
+		return nil, proto.OpNotExistErr
+	}
+	if !proto.IsRegular(i.Type) {
+		return nil, proto.OpArgMismatchErr
+	}
+	return i, proto.OpOk
+}
+
+// putDeletedExtents records the extents removed from the inode, the data is freed by the deleted extents traveler.
+func (mp *metaPartition) putDeletedExtents(dbHandle interface{}, i *Inode, delExtents []proto.ExtentKey) (err error) {
+	if err = mp.freeExtents(dbHandle, i.Inode, delExtents); err != nil {
+		return
+	}
+	mp.uidManager.minusUidSpace(i.Uid, i.Inode, delExtents)
+	return
+}
+
+// freeExtents records the extents never referred by the inode, such as the reserved extents which
+// could not be inserted, so that the data of them is freed by the deleted extents traveler.
+func (mp *metaPartition) freeExtents(dbHandle interface{}, ino uint64, eks []proto.ExtentKey) (err error) {
+	for _, ek := range eks {
+		dek := NewDeletedExtentKey(&ek, ino, mp.AllocDeletedExtentId())
+		if err = mp.deletedExtentsTree.Put(dbHandle, dek); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (mp *metaPartition) fsmFallocate(dbHandle interface{}, req *proto.FallocateRequest) (status uint8, err error) {
+	log.LogDebugf("[fsmFallocate] mp(%v) req(%v)", mp.config.PartitionId, req)
+	i, status := mp.getRegularInode(req.Inode)
+	if status != proto.OpOk {
+		return
+	}
+	if i.ObjExtents.HasRange(req.Offset, req.Length) {
+		// the data in the blobstore is read through the holes of the extents
+		if err = mp.freeExtents(dbHandle, i.Inode, req.Extents); err != nil {
+			status = proto.OpErr
+			return
+		}
+		status = proto.OpNotPerm
+		return
+	}
+
+	if i.getVer() != mp.verSeq {
+		i.CreateVer(mp.verSeq)
+	}
+	if err = i.CreateLowerVersion(i.getVer(), mp.multiVersionList); err != nil {
+		status = proto.OpErr
+		return
+	}
+
+	oldSize := int64(i.Size)
+	var delExtents, reserved, unused []proto.ExtentKey
+	if proto.IsPreallocate(req.Mode) {
+		reserved, unused = mp.splitReservedExtents(i, req)
+		if status = mp.uidManager.addUidSpace(i.Uid, i.Inode, reserved); status != proto.OpOk {
+			reserved, unused = nil, req.Extents
+		}
+		for _, ek := range reserved {
+			ek.SetSeq(mp.verSeq)
+			i.Extents.InsertRange([]proto.ExtentKey{ek})
+		}
+	} else {
+		delExtents = i.Extents.PunchHole(req.Offset, req.Length, func(ek *proto.ExtentKey) {
+			i.insertEkRefMap(mp.config.PartitionId, ek)
+		})
+	}
+	if err = mp.freeExtents(dbHandle, i.Inode, unused); err != nil {
+		status = proto.OpErr
+		return
+	}
+	if status != proto.OpOk {
+		return
+	}
+	if req.Mode&proto.FallocKeepSize == 0 && req.Offset+req.Length > i.Size {
+		i.Size = req.Offset + req.Length
+	}
+	i.ModifyTime = req.ModifyTime
+	i.Generation++
+
+	if len(delExtents) > 0 {
+		if delExtents, err = i.RestoreExts2NextLayer(mp.config.PartitionId, delExtents, mp.verSeq, 0); err != nil {
+			panic("[RestoreExts2NextLayer] should not be error")
+		}
+		i.DecSplitExts(mp.config.PartitionId, delExtents)
+	}
+	if err = mp.inodeTree.Put(dbHandle, i); err != nil {
+		status = proto.OpErr
+		log.LogErrorf("[fsmFallocate] mp(%v) inode(%v) put error:%v", mp.config.PartitionId, i.Inode, err)
+		return
+	}
+	if err = mp.putDeletedExtents(dbHandle, i, delExtents); err != nil {
+		status = proto.OpErr
+		return
+	}
+	mp.updateUsedInfo(int64(i.Size)-oldSize, 0, i.Inode)
+	log.LogInfof("[fsmFallocate] mp(%v) inode(%v) mode(%#x) range[%v, +%v) size(%v) reserved extents(%v) unused extents(%v) delete extents(%v)",
+		mp.config.PartitionId, i.Inode, req.Mode, req.Offset, req.Length, i.Size, reserved, unused, delExtents)
+	return
+}
+
+// splitReservedExtents returns the sorted reserved extents which are still in the holes of the range, and
+// the others, whose ranges are written after the client looked for the holes, are unused and to be freed.
+func (mp *metaPartition) splitReservedExtents(i *Inode, req *proto.FallocateRequest) (reserved, unused []proto.ExtentKey) {
+	end := req.Offset
+	for _, ek := range req.Extents {
+		if ek.FileOffset < end || ek.FileOffset+uint64(ek.Size) > req.Offset+req.Length ||
+			storage.IsTinyExtent(ek.ExtentId) || len(i.Extents.CopyRange(ek.FileOffset, uint64(ek.Size))) > 0 {
+			unused = append(unused, ek)
+			continue
+		}
+		reserved = append(reserved, ek)
+		end = ek.FileOffset + uint64(ek.Size)
+	}
+	return
+}
+
+// fsmCloneExtents makes the destination range refer to the extents of the source range. The cloned extents
+// are recorded as shared, so that the client overwrites them by append and the data nodes keep the data
+// until none of the inodes refers to it.
+func (mp *metaPartition) fsmCloneExtents(dbHandle interface{}, req *proto.CloneExtentsRequest) (resp *CloneExtentsResp, err error) {
+	resp = &CloneExtentsResp{Status: proto.OpOk}
+	log.LogDebugf("[fsmCloneExtents] mp(%v) req(%v)", mp.config.PartitionId, req)
+	if mp.verSeq != 0 {
+		// the extents of snapshots are tracked by the split references of a single inode
+		resp.Status = proto.OpNotPerm
+		return
+	}
+
+	src, status := mp.getRegularInode(req.SrcInode)
+	if status != proto.OpOk {
+		resp.Status = status
+		return
+	}
+	dst := src
+	if req.DstInode != req.SrcInode {
+		if dst, status = mp.getRegularInode(req.DstInode); status != proto.OpOk {
+			resp.Status = status
+			return
+		}
+	} else if req.SrcOffset < req.DstOffset+req.Length && req.DstOffset < req.SrcOffset+req.Length {
+		resp.Status = proto.OpArgMismatchErr
+		return
+	}
+
+	if req.SrcOffset >= src.Size {
+		return
+	}
+	length := req.Length
+	if req.SrcOffset+length > src.Size {
+		length = src.Size - req.SrcOffset
+	}
+	if dst.Extents.HasCompressed(req.DstOffset, length) || src.Extents.HasCompressed(req.SrcOffset, length) {
+		// the compressed chunks could not be partly replaced or shared
+		resp.Status = proto.OpNotPerm
+		return
+	}
+	if src.ObjExtents.HasRange(req.SrcOffset, length) || dst.ObjExtents.HasRange(req.DstOffset, length) {
+		// the data in the blobstore is not shared by the extents
+		resp.Status = proto.OpNotPerm
+		return
+	}
+	eks := src.Extents.CopyRange(req.SrcOffset, length)
+	for idx := range eks {
+		if storage.IsTinyExtent(eks[idx].ExtentId) {
+			// tiny extents are shared by all the files of the data partition
+			resp.Status = proto.OpNotPerm
+			return
+		}
+		eks[idx].FileOffset = eks[idx].FileOffset - req.SrcOffset + req.DstOffset
+	}
+	if resp.Status = mp.uidManager.addUidSpace(dst.Uid, dst.Inode, eks); resp.Status != proto.OpOk {
+		return
+	}
+
+	oldSize := int64(dst.Size)
+	delExtents := dst.Extents.PunchHole(req.DstOffset, length, func(ek *proto.ExtentKey) {
+		dst.insertEkRefMap(mp.config.PartitionId, ek)
+	})
+	dst.DecSplitExts(mp.config.PartitionId, delExtents)
+	dst.Extents.InsertRange(eks)
+	if req.DstOffset+length > dst.Size {
+		dst.Size = req.DstOffset + length
+	}
+	dst.ModifyTime = req.ModifyTime
+	dst.Generation++
+	if err = mp.inodeTree.Put(dbHandle, dst); err != nil {
+		resp.Status = proto.OpErr
+		log.LogErrorf("[fsmCloneExtents] mp(%v) inode(%v) put error:%v", mp.config.PartitionId, dst.Inode, err)
+		return
+	}
+	ids := make([]uint64, 0, len(eks))
+	for _, ek := range eks {
+		mp.sharedExtents.add(ek.GenerateId(), src.Inode, dst.Inode)
+		ids = append(ids, ek.GenerateId())
+	}
+	if err = mp.persistSharedExtents(dbHandle, mp.sharedExtents, ids...); err != nil {
+		resp.Status = proto.OpErr
+		return
+	}
+	if err = mp.putDeletedExtents(dbHandle, dst, delExtents); err != nil {
+		resp.Status = proto.OpErr
+		return
+	}
+	mp.updateUsedInfo(int64(dst.Size)-oldSize, 0, dst.Inode)
+	resp.Size = length
+	log.LogInfof("[fsmCloneExtents] mp(%v) clone inode(%v) range[%v, +%v) to inode(%v) offset(%v), extents(%v) delete extents(%v)",
+		mp.config.PartitionId, src.Inode, req.SrcOffset, length, dst.Inode, req.DstOffset, eks, delExtents)
+	return
+}
+
+// fsmReleaseSharedExtents drops the inodes which no longer refer to the shared extents, and returns the
+// extents which are not referred by any inode, the data of them could be freed.
+func (mp *metaPartition) fsmReleaseSharedExtents(dbHandle interface{}, req *fsmReleaseSharedExtentsRequest) (released []uint64, err error) {
+	for _, id := range req.Extents {
+		inodes := mp.sharedExtents.get(id)
+		if len(inodes) == 0 {
+			continue
+		}
+		referred := inodes[:0]
+		for _, ino := range inodes {
+			i, err := mp.inodeTree.Get(ino)
+			if err != nil {
+				log.LogErrorf("[fsmReleaseSharedExtents] mp(%v) get inode(%v) err(%v)", mp.config.PartitionId, ino, err)
+				referred = append(referred, ino)
+				continue
+			}
+			if i != nil && !i.ShouldDelete() && inodeRefersExtent(i, &proto.ExtentKey{PartitionId: id >> 32, ExtentId: id & 0xFFFFFFFF}, false) {
+				referred = append(referred, ino)
+			}
+		}
+		mp.sharedExtents.set(id, referred)
+		if err = mp.persistSharedExtents(dbHandle, mp.sharedExtents, id); err != nil {
+			return
+		}
+		if len(referred) == 0 && !mp.sharedExtents.isDedup(id) {
+			// the extent deduplicated meanwhile is never freed as a whole
+			released = append(released, id)
+		}
+	}
+	log.LogInfof("[fsmReleaseSharedExtents] mp(%v) release shared extents(%v)", mp.config.PartitionId, released)
+	return
+}
+
+// inodeRefersExtent checks whether the inode or its snapshots refer to the extent of ek, and the
+// extent range of ek as well if overlap is set.
+func inodeRefersExtent(i *Inode, ek *proto.ExtentKey, overlap bool) (ok bool) {
+	match := func(_ int, key proto.ExtentKey) bool {
+		if key.PartitionId != ek.PartitionId || key.ExtentId != ek.ExtentId {
+			return true
+		}
+		if !overlap || (key.ExtentOffset < ek.ExtentOffset+uint64(ek.Size) && ek.ExtentOffset < key.ExtentOffset+uint64(key.Size)) {
+			ok = true
+			return false
+		}
+		return true
+	}
+	i.Extents.Range(match)
+	for layer := 0; !ok && layer < i.getLayerLen(); layer++ {
+		if ino := i.multiSnap.multiVersions[layer]; ino != nil {
+			ino.Extents.Range(match)
+		}
+	}
+	return
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFsmFallocatePreallocate(t *testing.T) {
+	mp := newMpForFsmTest(t, proto.StoreModeMem)
+	ino := NewInode(10, FileModeType)
+	ino.Extents.InsertRange([]proto.ExtentKey{{FileOffset: 4096, PartitionId: 1, ExtentId: 1025, Size: 4096}})
+	ino.Size = 8192
+	require.NoError(t, mp.inodeTree.Put(nil, ino))
+
+	// the key over the range written meanwhile is unused, and the data of it is freed
+	req := &proto.FallocateRequest{
+		Inode:  10,
+		Mode:   proto.FallocKeepSize,
+		Offset: 0,
+		Length: 16384,
+		Extents: []proto.ExtentKey{
+			{FileOffset: 0, PartitionId: 2, ExtentId: 1025, Size: 4096},
+			{FileOffset: 4096, PartitionId: 2, ExtentId: 1026, Size: 4096},
+			{FileOffset: 8192, PartitionId: 2, ExtentId: 1027, Size: 8192},
+		},
+	}
+	status, err := mp.fsmFallocate(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpOk, status)
+
+	ino, err = mp.inodeTree.Get(10)
+	require.NoError(t, err)
+	require.EqualValues(t, 8192, ino.Size)
+	eks := ino.Extents.CopyExtents()
+	require.Len(t, eks, 3)
+	require.EqualValues(t, []uint64{1025, 1025, 1027}, []uint64{eks[0].ExtentId, eks[1].ExtentId, eks[2].ExtentId})
+	require.EqualValues(t, []uint64{2, 1, 2}, []uint64{eks[0].PartitionId, eks[1].PartitionId, eks[2].PartitionId})
+	cnt, err := mp.GetDeletedExtentsRealCount()
+	require.NoError(t, err)
+	require.EqualValues(t, 1, cnt)
+
+	// the size is extended without keep size
+	req = &proto.FallocateRequest{
+		Inode:   10,
+		Offset:  16384,
+		Length:  4096,
+		Extents: []proto.ExtentKey{{FileOffset: 16384, PartitionId: 2, ExtentId: 1028, Size: 4096}},
+	}
+	status, err = mp.fsmFallocate(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpOk, status)
+	ino, err = mp.inodeTree.Get(10)
+	require.NoError(t, err)
+	require.EqualValues(t, 20480, ino.Size)
+	require.Equal(t, 4, ino.Extents.Len())
+
+	// the keys out of the range or overlapped with each other are never inserted
+	req = &proto.FallocateRequest{
+		Inode:  10,
+		Mode:   proto.FallocKeepSize,
+		Offset: 20480,
+		Length: 8192,
+		Extents: []proto.ExtentKey{
+			{FileOffset: 20480, PartitionId: 2, ExtentId: 1029, Size: 8192},
+			{FileOffset: 24576, PartitionId: 2, ExtentId: 1030, Size: 4096},
+			{FileOffset: 28672, PartitionId: 2, ExtentId: 1031, Size: 4096},
+		},
+	}
+	status, err = mp.fsmFallocate(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpOk, status)
+	ino, err = mp.inodeTree.Get(10)
+	require.NoError(t, err)
+	require.Equal(t, 5, ino.Extents.Len())
+	cnt, err = mp.GetDeletedExtentsRealCount()
+	require.NoError(t, err)
+	require.EqualValues(t, 3, cnt)
+}
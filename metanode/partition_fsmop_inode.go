@@ -792,6 +792,16 @@ func (mp *metaPartition) fsmExtentsTruncate(dbHandle interface{}, ino *Inode) (r
 		resp.Status = proto.OpArgMismatchErr
 		return
 	}
+	delObjExtents, ok := i.ObjExtents.Truncate(ino.Size)
+	if !ok {
+		// the data in the blobstore must be restored before cut
+		resp.Status = proto.OpNotPerm
+		return
+	}
+	if err = mp.putDeletedObjExtents(dbHandle, i, delObjExtents); err != nil {
+		resp.Status = proto.OpErr
+		return
+	}
 
 	doOnLastKey := func(lastKey *proto.ExtentKey) {
 		var eks []proto.ExtentKey
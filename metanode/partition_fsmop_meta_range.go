@@ -0,0 +1,152 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/binary"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// metaRangeBatch is a batch of the items of the migrating range synced from the source partition to the
+// target one, the items are replaced with the ones of the source and the deleted keys are removed.
+type metaRangeBatch struct {
+	Tree    uint8    `json:"tree"`
+	Items   [][]byte `json:"items"`
+	Deleted [][]byte `json:"deleted"`
+	Cursor  uint64   `json:"cursor"`
+}
+
+// fsmFreezeMetaRange holds off the requests of the range before the last copy of it.
+func (mp *metaPartition) fsmFreezeMetaRange(req *proto.FreezeMetaRangeRequest) (status uint8) {
+	status = proto.OpOk
+	if err := mp.checkMetaRangeMovable(req.Start, req.End); err != nil {
+		log.LogWarnf("[fsmFreezeMetaRange] mp(%v) range(%v-%v) err(%v)", mp.config.PartitionId, req.Start, req.End, err)
+		return proto.OpNotPerm
+	}
+	old := mp.config.FrozenRange
+	mp.config.FrozenRange = &proto.MetaRange{Start: req.Start, End: req.End}
+	if err := mp.PersistMetadata(); err != nil {
+		log.LogErrorf("[fsmFreezeMetaRange] mp(%v) range(%v-%v) persist err(%v)",
+			mp.config.PartitionId, req.Start, req.End, err)
+		mp.config.FrozenRange = old
+		return proto.OpDiskErr
+	}
+	mp.updateRangeState()
+	log.LogInfof("[fsmFreezeMetaRange] mp(%v) freeze range(%v-%v)", mp.config.PartitionId, req.Start, req.End)
+	return
+}
+
+// fsmSyncMetaRange applies the batch of the items copied from the source partition.
+func (mp *metaPartition) fsmSyncMetaRange(dbHandle interface{}, batch *metaRangeBatch) (status uint8, err error) {
+	status = proto.OpOk
+	for _, raw := range batch.Items {
+		switch batch.Tree {
+		case proto.MetaRangeInodeTree:
+			ino := NewInode(0, 0)
+			if err = ino.Unmarshal(raw); err != nil {
+				return proto.OpArgMismatchErr, nil
+			}
+			_, _, err = mp.inodeTree.Create(dbHandle, ino, true)
+		case proto.MetaRangeDentryTree:
+			dentry := &Dentry{}
+			if err = dentry.Unmarshal(raw); err != nil {
+				return proto.OpArgMismatchErr, nil
+			}
+			_, _, err = mp.dentryTree.Create(dbHandle, dentry, true)
+		case proto.MetaRangeExtendTree:
+			var extend *Extend
+			if extend, err = NewExtendFromBytes(raw); err != nil {
+				return proto.OpArgMismatchErr, nil
+			}
+			_, _, err = mp.extendTree.Create(dbHandle, extend, true)
+		default:
+			return proto.OpArgMismatchErr, nil
+		}
+		if err != nil {
+			return proto.OpErr, err
+		}
+	}
+
+	for _, key := range batch.Deleted {
+		switch batch.Tree {
+		case proto.MetaRangeInodeTree:
+			_, err = mp.inodeTree.Delete(dbHandle, binary.BigEndian.Uint64(key))
+		case proto.MetaRangeDentryTree:
+			dentry := &Dentry{}
+			if err = dentry.UnmarshalKey(key); err != nil {
+				return proto.OpArgMismatchErr, nil
+			}
+			_, err = mp.dentryTree.Delete(dbHandle, dentry.ParentId, dentry.Name)
+		case proto.MetaRangeExtendTree:
+			_, err = mp.extendTree.Delete(dbHandle, binary.BigEndian.Uint64(key))
+		}
+		if err != nil {
+			return proto.OpErr, err
+		}
+	}
+
+	if batch.Cursor > mp.config.Cursor && batch.Cursor <= mp.config.End {
+		mp.config.Cursor = batch.Cursor
+		mp.inodeTree.SetCursor(batch.Cursor)
+	}
+	return
+}
+
+// fsmCutoverMetaRange switches the partition to the new range once the migration is done. The source
+// partition redirects the requests of the moved range to the target, and the target takes over the
+// inodes marked deleted in the range. The frozen range is dropped in any case.
+func (mp *metaPartition) fsmCutoverMetaRange(req *proto.CutoverMetaRangeRequest) (status uint8) {
+	status = proto.OpOk
+	oldStart, oldEnd := mp.config.Start, mp.config.End
+	oldFrozen, oldMoved := mp.config.FrozenRange, mp.config.MovedRanges
+	mp.config.FrozenRange = nil
+	if !req.Abort {
+		mp.config.Start = req.Start
+		mp.config.End = req.End
+		if req.Moved != nil && mp.movedRange(req.Moved.Start) == nil {
+			mp.config.MovedRanges = append(mp.config.MovedRanges, req.Moved)
+		}
+	}
+	if err := mp.PersistMetadata(); err != nil {
+		log.LogErrorf("[fsmCutoverMetaRange] mp(%v) req(%v) persist err(%v)", mp.config.PartitionId, req, err)
+		mp.config.Start, mp.config.End = oldStart, oldEnd
+		mp.config.FrozenRange, mp.config.MovedRanges = oldFrozen, oldMoved
+		return proto.OpDiskErr
+	}
+	mp.updateRangeState()
+
+	if !req.Abort && req.Moved == nil {
+		end := req.End
+		if oldStart > req.Start {
+			end = oldStart - 1
+		}
+		mp.takeOverMarkedInodes(req.Start, end)
+	}
+	log.LogInfof("[fsmCutoverMetaRange] mp(%v) range(%v-%v) moved(%v) abort(%v)",
+		mp.config.PartitionId, mp.config.Start, mp.config.End, req.Moved, req.Abort)
+	return
+}
+
+// takeOverMarkedInodes pushes the inodes marked deleted in the range migrated in to the free list.
+func (mp *metaPartition) takeOverMarkedInodes(start, end uint64) {
+	mp.inodeTree.Range(NewInode(start, 0), inodeRangeEnd(end), func(i *Inode) (bool, error) {
+		if i.ShouldDelete() {
+			mp.freeList.Push(i.Inode)
+		}
+		return true, nil
+	})
+}
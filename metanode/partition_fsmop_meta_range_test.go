@@ -0,0 +1,173 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetaRangeReqKeys(t *testing.T) {
+	pino := uint64(5)
+	cases := []struct {
+		req  interface{}
+		inos []uint64
+	}{
+		{&proto.CreateDentryRequest{ParentID: pino, Inode: 7}, []uint64{pino}},
+		{&proto.InodeGetRequest{Inode: 7}, []uint64{7}},
+		{&proto.BatchInodeGetRequest{Inodes: []uint64{7, 8}}, []uint64{7, 8}},
+		{&proto.CloneExtentsRequest{SrcInode: 7, DstInode: 8}, []uint64{7, 8}},
+	}
+	for _, c := range cases {
+		data, err := json.Marshal(c.req)
+		require.NoError(t, err)
+		keys := &metaRangeReqKeys{}
+		require.NoError(t, json.Unmarshal(data, keys))
+		require.Equal(t, c.inos, keys.inodes())
+	}
+}
+
+func newMpForMetaRangeTest(t *testing.T, start, end uint64) (mp *metaPartition) {
+	mp = newMpForFsmTest(t, proto.StoreModeMem)
+	mp.config.Start, mp.config.End, mp.config.Cursor = start, end, start
+	mp.config.Peers = []proto.Peer{{ID: 1, Addr: "127.0.0.1:17210"}}
+	mp.freeList = newFreeList()
+	return
+}
+
+func TestFsmMetaRangeSource(t *testing.T) {
+	mp := newMpForMetaRangeTest(t, 1, 1000)
+	for _, id := range []uint64{10, 600} {
+		require.NoError(t, mp.inodeTree.Put(nil, NewInode(id, FileModeType)))
+	}
+
+	status := mp.fsmFreezeMetaRange(&proto.FreezeMetaRangeRequest{PartitionID: 10001, Start: 500, End: 1000})
+	require.Equal(t, proto.OpOk, status)
+	require.True(t, mp.isInodeFrozen(600))
+	require.False(t, mp.isInodeFrozen(10))
+
+	p := NewPacketToMetaRange(proto.OpMetaInodeGet, &proto.InodeGetRequest{PartitionID: 10001, Inode: 600})
+	release, ok := mp.acquireMetaRange(p)
+	release()
+	require.False(t, ok)
+	require.Equal(t, proto.OpAgain, p.ResultCode)
+
+	moved := &proto.MovedMetaRange{MetaRange: proto.MetaRange{Start: 500, End: 1000}, PartitionID: 10002}
+	status = mp.fsmCutoverMetaRange(&proto.CutoverMetaRangeRequest{PartitionID: 10001, Start: 1, End: 499, Moved: moved})
+	require.Equal(t, proto.OpOk, status)
+	require.EqualValues(t, 499, mp.config.End)
+	require.Nil(t, mp.config.FrozenRange)
+	require.Len(t, mp.config.MovedRanges, 1)
+	require.True(t, mp.isInodeMoved(600))
+
+	// the cutover is resent by master until it succeeds
+	status = mp.fsmCutoverMetaRange(&proto.CutoverMetaRangeRequest{PartitionID: 10001, Start: 1, End: 499, Moved: moved})
+	require.Equal(t, proto.OpOk, status)
+	require.Len(t, mp.config.MovedRanges, 1)
+
+	p = NewPacketToMetaRange(proto.OpMetaInodeGet, &proto.InodeGetRequest{PartitionID: 10001, Inode: 600})
+	release, ok = mp.acquireMetaRange(p)
+	release()
+	require.False(t, ok)
+	require.Equal(t, proto.OpMetaRangeMoved, p.ResultCode)
+	resp := &proto.MetaRangeMovedResponse{}
+	require.NoError(t, json.Unmarshal(p.Data, resp))
+	require.EqualValues(t, 10002, resp.PartitionID)
+
+	p = NewPacketToMetaRange(proto.OpMetaInodeGet, &proto.InodeGetRequest{PartitionID: 10001, Inode: 10})
+	release, ok = mp.acquireMetaRange(p)
+	release()
+	require.True(t, ok)
+}
+
+func TestFsmMetaRangeAbort(t *testing.T) {
+	mp := newMpForMetaRangeTest(t, 1, 1000)
+
+	require.Equal(t, proto.OpOk, mp.fsmFreezeMetaRange(&proto.FreezeMetaRangeRequest{Start: 500, End: 1000}))
+	require.True(t, mp.isInodeFrozen(600))
+	status := mp.fsmCutoverMetaRange(&proto.CutoverMetaRangeRequest{Start: 1, End: 1000, Abort: true})
+	require.Equal(t, proto.OpOk, status)
+	require.False(t, mp.isInodeFrozen(600))
+	require.False(t, mp.isInodeMoved(600))
+	require.EqualValues(t, 1000, mp.config.End)
+}
+
+func TestFsmMetaRangeNotMovable(t *testing.T) {
+	mp := newMpForMetaRangeTest(t, 1, 1000)
+	mp.sharedExtents.add(1<<32|100, 10, 600)
+	require.Equal(t, proto.OpNotPerm, mp.fsmFreezeMetaRange(&proto.FreezeMetaRangeRequest{Start: 500, End: 1000}))
+	require.False(t, mp.isInodeFrozen(600))
+	require.Equal(t, proto.OpOk, mp.fsmFreezeMetaRange(&proto.FreezeMetaRangeRequest{Start: 700, End: 1000}))
+	require.Equal(t, proto.OpOk, mp.fsmCutoverMetaRange(&proto.CutoverMetaRangeRequest{Start: 1, End: 1000, Abort: true}))
+
+	chunk := &dedupChunk{ExtentOffset: 0, Size: 4096, Shard: 1}
+	mp.sharedExtents.addChunk(2<<32|100, chunk, dedupRef{Inode: 800, Token: 1})
+	err := mp.checkMetaRangeMovable(700, 1000)
+	require.ErrorIs(t, err, errMetaRangeNotMovable)
+	require.NoError(t, mp.checkMetaRangeMovable(900, 1000))
+
+	rbInode := NewTxRollbackInode(NewInode(950, FileModeType), nil, proto.NewTxInodeInfo("", 950, 1), TxDelete)
+	status, err := mp.txProcessor.txResource.addTxRollbackInode(nil, rbInode)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpOk, status)
+	require.ErrorIs(t, mp.checkMetaRangeMovable(900, 1000), errMetaRangeNotMovable)
+}
+
+func TestFsmMetaRangeTarget(t *testing.T) {
+	mp := newMpForMetaRangeTest(t, 500, 1000)
+
+	ino := NewInode(600, FileModeType)
+	ino.SetDeleteMark()
+	item, err := ino.Marshal()
+	require.NoError(t, err)
+	stale := NewInode(700, FileModeType)
+	require.NoError(t, mp.inodeTree.Put(nil, stale))
+
+	batch := &metaRangeBatch{
+		Tree:    proto.MetaRangeInodeTree,
+		Items:   [][]byte{item},
+		Deleted: [][]byte{stale.MarshalKey()},
+		Cursor:  800,
+	}
+	status, err := mp.fsmSyncMetaRange(nil, batch)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpOk, status)
+	got, err := mp.inodeTree.Get(600)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	got, err = mp.inodeTree.Get(700)
+	require.NoError(t, err)
+	require.Nil(t, got)
+	require.EqualValues(t, 800, mp.config.Cursor)
+
+	dentry := &Dentry{ParentId: 600, Name: "a", Inode: 601, Type: FileModeType}
+	item, err = dentry.Marshal()
+	require.NoError(t, err)
+	status, err = mp.fsmSyncMetaRange(nil, &metaRangeBatch{Tree: proto.MetaRangeDentryTree, Items: [][]byte{item}})
+	require.NoError(t, err)
+	require.Equal(t, proto.OpOk, status)
+	gotDentry, err := mp.dentryTree.Get(600, "a")
+	require.NoError(t, err)
+	require.EqualValues(t, 601, gotDentry.Inode)
+
+	// the inodes marked deleted are taken over by the target
+	status = mp.fsmCutoverMetaRange(&proto.CutoverMetaRangeRequest{Start: 500, End: 1000})
+	require.Equal(t, proto.OpOk, status)
+	require.Equal(t, 1, mp.freeList.Len())
+	require.EqualValues(t, 600, mp.freeList.Pop())
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// getExtentsOfVersion returns the extent keys of the inode visible to the version, as ExtentsList does.
+func (mp *metaPartition) getExtentsOfVersion(ino *Inode, verSeq uint64) (eks []proto.ExtentKey) {
+	if verSeq > 0 && ino.getVer() > 0 && (verSeq < ino.getVer() || isInitSnapVer(verSeq)) {
+		rsp := &proto.GetExtentsResponse{}
+		mp.GetExtentByVer(ino, &proto.GetExtentsRequest{Inode: ino.Inode, VerSeq: verSeq}, rsp)
+		return rsp.Extents
+	}
+	ino.DoReadFunc(func() {
+		ino.Extents.Range(func(_ int, ek proto.ExtentKey) bool {
+			eks = append(eks, ek)
+			return true
+		})
+	})
+	return
+}
+
+// fsmCloneSnapshotInode creates the inode from the version of the source inode. The extents are shared
+// with the source inode as the cloned extents are, and the keys are owned by the current version, so
+// that the client overwrites them by append and the data is freed once none of the inodes refers to it.
+func (mp *metaPartition) fsmCloneSnapshotInode(dbHandle interface{}, req *proto.CloneSnapshotInodeRequest) (resp *InodeResponse, err error) {
+	resp = NewInodeResponse()
+	resp.Status = proto.OpOk
+	log.LogDebugf("[fsmCloneSnapshotInode] mp(%v) req(%v)", mp.config.PartitionId, req)
+
+	top, err := mp.inodeTree.Get(req.Inode)
+	if err != nil {
+		resp.Status = proto.OpErr
+		return
+	}
+	if top == nil {
+		resp.Status = proto.OpNotExistErr
+		return
+	}
+	src, _ := top.getInoByVer(req.VerSeq, false)
+	if src == nil || src.ShouldDelete() {
+		resp.Status = proto.OpNotExistErr
+		return
+	}
+	if proto.IsDir(src.Type) {
+		// the directories are created by the client walking the tree
+		resp.Status = proto.OpArgMismatchErr
+		return
+	}
+
+	ino := NewInode(req.NewInode, src.Type)
+	ino.Uid = src.Uid
+	ino.Gid = src.Gid
+	ino.LinkTarget = src.LinkTarget
+	ino.CreateTime = req.CreateTime
+	ino.AccessTime = req.CreateTime
+	ino.ModifyTime = src.ModifyTime
+	ino.setVer(mp.verSeq)
+
+	var eks []proto.ExtentKey
+	if proto.IsRegular(src.Type) {
+		ino.Size = src.Size
+		eks = mp.getExtentsOfVersion(top, req.VerSeq)
+		for idx := range eks {
+			eks[idx].SnapInfo = nil
+			if mp.verSeq > 0 {
+				eks[idx].SnapInfo = &proto.ExtSnapInfo{VerSeq: mp.verSeq}
+			}
+		}
+		ino.Extents = NewSortedExtentsFromEks(eks)
+		if resp.Status = mp.uidManager.addUidSpace(ino.Uid, ino.Inode, eks); resp.Status != proto.OpOk {
+			return
+		}
+	}
+
+	var ok bool
+	if _, ok, err = mp.inodeTree.Create(dbHandle, ino, false); err != nil {
+		resp.Status = proto.OpErr
+		log.LogErrorf("[fsmCloneSnapshotInode] mp(%v) inode(%v) create error:%v", mp.config.PartitionId, ino.Inode, err)
+		return
+	}
+	if !ok {
+		resp.Status = proto.OpExistErr
+		return
+	}
+	ids := make([]uint64, 0, len(eks))
+	for _, ek := range eks {
+		mp.sharedExtents.add(ek.GenerateId(), top.Inode, ino.Inode)
+		ids = append(ids, ek.GenerateId())
+	}
+	if err = mp.persistSharedExtents(dbHandle, mp.sharedExtents, ids...); err != nil {
+		resp.Status = proto.OpErr
+		return
+	}
+	resp.Msg = ino
+	log.LogInfof("[fsmCloneSnapshotInode] mp(%v) inode(%v) ver(%v) cloned to inode(%v) extents(%v)",
+		mp.config.PartitionId, req.Inode, req.VerSeq, ino.Inode, len(eks))
+	return
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// extentsCoverRange checks whether the extents of the inode cover every byte of the range [start, end).
+func extentsCoverRange(i *Inode, start, end uint64) bool {
+	i.Extents.Range(func(_ int, ek proto.ExtentKey) bool {
+		if ek.FileOffset > start {
+			return false
+		}
+		if ekEnd := ek.FileOffset + uint64(ek.Size); ekEnd > start {
+			start = ekEnd
+		}
+		return start < end
+	})
+	return start >= end
+}
+
+func objExtentsEqual(a, b []proto.ObjExtentKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if len(a[idx].Blobs) != len(b[idx].Blobs) || !a[idx].IsEquals(&b[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// putDeletedObjExtents records the object extents removed from the inode, the data is freed by the deleted
+// object extents traveler.
+func (mp *metaPartition) putDeletedObjExtents(dbHandle interface{}, i *Inode, oeks []proto.ObjExtentKey) (err error) {
+	for _, oek := range oeks {
+		doek := NewDeletedObjExtentKey(&oek, i.Inode, mp.AllocDeletedExtentId())
+		if err = mp.deletedObjExtentsTree.Put(dbHandle, doek); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// fsmTransitionExtents replaces the extents of the inode with the object extents if the inode is not modified
+// since the generation the data is read at. The extents written later take the place of the object extents
+// for the range they cover, so the reads look up the object extents only for the holes of the extents.
+// The inode transitioned already takes the same object extents only, the extents of the copy restored for
+// the reads are dropped then.
+func (mp *metaPartition) fsmTransitionExtents(dbHandle interface{}, req *proto.TransitionExtentsRequest) (status uint8, err error) {
+	log.LogDebugf("[fsmTransitionExtents] mp(%v) req(%v)", mp.config.PartitionId, req)
+	if mp.verSeq != 0 {
+		// the extents of snapshots still refer to the data
+		return proto.OpNotPerm, nil
+	}
+	i, status := mp.getRegularInode(req.Inode)
+	if status != proto.OpOk {
+		return
+	}
+	if i.Generation != req.Generation {
+		return proto.OpConflictExtentsErr, nil
+	}
+	if i.ObjExtents.Size() != 0 && !objExtentsEqual(i.ObjExtents.CopyExtents(), req.ObjExtents) {
+		return proto.OpConflictExtentsErr, nil
+	}
+	if len(req.ObjExtents) == 0 {
+		return proto.OpArgMismatchErr, nil
+	}
+	if last := req.ObjExtents[len(req.ObjExtents)-1]; last.FileOffset+last.Size != i.Size {
+		return proto.OpArgMismatchErr, nil
+	}
+
+	objExtents := NewSortedObjExtents()
+	for _, oek := range req.ObjExtents {
+		if err = objExtents.Append(oek); err != nil {
+			log.LogErrorf("[fsmTransitionExtents] mp(%v) inode(%v) append obj extent(%v) err(%v)",
+				mp.config.PartitionId, i.Inode, oek, err)
+			return proto.OpArgMismatchErr, nil
+		}
+	}
+
+	delExtents := i.Extents.PunchHole(0, i.Size, func(ek *proto.ExtentKey) {
+		i.insertEkRefMap(mp.config.PartitionId, ek)
+	})
+	i.DecSplitExts(mp.config.PartitionId, delExtents)
+	i.ObjExtents = objExtents
+	i.Generation++
+	if err = mp.inodeTree.Put(dbHandle, i); err != nil {
+		log.LogErrorf("[fsmTransitionExtents] mp(%v) inode(%v) put error:%v", mp.config.PartitionId, i.Inode, err)
+		return proto.OpErr, err
+	}
+	if err = mp.putDeletedExtents(dbHandle, i, delExtents); err != nil {
+		return proto.OpErr, err
+	}
+	log.LogInfof("[fsmTransitionExtents] mp(%v) inode(%v) obj extents(%v) delete extents(%v)",
+		mp.config.PartitionId, i.Inode, len(req.ObjExtents), delExtents)
+	return proto.OpOk, nil
+}
+
+// fsmRestoreExtents drops the object extents of the inode if the extents cover all the data of them.
+func (mp *metaPartition) fsmRestoreExtents(dbHandle interface{}, req *proto.RestoreExtentsRequest) (status uint8, err error) {
+	log.LogDebugf("[fsmRestoreExtents] mp(%v) req(%v)", mp.config.PartitionId, req)
+	i, status := mp.getRegularInode(req.Inode)
+	if status != proto.OpOk {
+		return
+	}
+	oeks := i.ObjExtents.CopyExtents()
+	if len(oeks) == 0 {
+		return proto.OpOk, nil
+	}
+	for _, oek := range oeks {
+		end := oek.FileOffset + oek.Size
+		if end > i.Size {
+			end = i.Size
+		}
+		if oek.FileOffset < end && !extentsCoverRange(i, oek.FileOffset, end) {
+			return proto.OpConflictExtentsErr, nil
+		}
+	}
+
+	i.ObjExtents = NewSortedObjExtents()
+	i.Generation++
+	if err = mp.inodeTree.Put(dbHandle, i); err != nil {
+		log.LogErrorf("[fsmRestoreExtents] mp(%v) inode(%v) put error:%v", mp.config.PartitionId, i.Inode, err)
+		return proto.OpErr, err
+	}
+	if err = mp.putDeletedObjExtents(dbHandle, i, oeks); err != nil {
+		return proto.OpErr, err
+	}
+	log.LogInfof("[fsmRestoreExtents] mp(%v) inode(%v) delete obj extents(%v)", mp.config.PartitionId, i.Inode, len(oeks))
+	return proto.OpOk, nil
+}
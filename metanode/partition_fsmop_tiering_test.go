@@ -0,0 +1,85 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFsmTransitionExtents(t *testing.T) {
+	initMp(t, proto.StoreModeMem)
+	ino := testCreateInode(t, FileModeType)
+	ino.Extents = NewSortedExtentsFromEks(buildExtents(0, 0, 100))
+	ino.Size = 1000
+	require.NoError(t, mp.inodeTree.Put(nil, ino))
+	gen := ino.Generation
+
+	oeks := []proto.ObjExtentKey{{Cid: 1, FileOffset: 0, Size: 600}, {Cid: 2, FileOffset: 600, Size: 400}}
+	req := &proto.TransitionExtentsRequest{Inode: ino.Inode, Generation: gen + 1, ObjExtents: oeks}
+	status, err := mp.fsmTransitionExtents(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpConflictExtentsErr, status)
+
+	req.Generation = gen
+	req.ObjExtents = oeks[:1]
+	status, err = mp.fsmTransitionExtents(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpArgMismatchErr, status)
+
+	req.ObjExtents = oeks
+	status, err = mp.fsmTransitionExtents(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpOk, status)
+	got, err := mp.inodeTree.Get(ino.Inode)
+	require.NoError(t, err)
+	require.Equal(t, 0, got.Extents.Len())
+	require.EqualValues(t, 1000, got.ObjExtents.Size())
+	require.Equal(t, gen+1, got.Generation)
+
+	// the copy restored for the reads is dropped with the same obj extents only
+	got.Extents = NewSortedExtentsFromEks(buildExtents(0, 0, 101))
+	require.NoError(t, mp.inodeTree.Put(nil, got))
+	req.Generation = got.Generation
+	req.ObjExtents = []proto.ObjExtentKey{{Cid: 3, FileOffset: 0, Size: 1000}}
+	status, err = mp.fsmTransitionExtents(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpConflictExtentsErr, status)
+	req.ObjExtents = oeks
+	status, err = mp.fsmTransitionExtents(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpOk, status)
+	got, err = mp.inodeTree.Get(ino.Inode)
+	require.NoError(t, err)
+	require.Equal(t, 0, got.Extents.Len())
+	require.EqualValues(t, 1000, got.ObjExtents.Size())
+
+	// the data is not restored to the extents yet
+	status, err = mp.fsmRestoreExtents(nil, &proto.RestoreExtentsRequest{Inode: ino.Inode})
+	require.NoError(t, err)
+	require.Equal(t, proto.OpConflictExtentsErr, status)
+
+	got.Extents = NewSortedExtentsFromEks(buildExtents(0, 0, 102))
+	require.NoError(t, mp.inodeTree.Put(nil, got))
+	status, err = mp.fsmRestoreExtents(nil, &proto.RestoreExtentsRequest{Inode: ino.Inode})
+	require.NoError(t, err)
+	require.Equal(t, proto.OpOk, status)
+	got, err = mp.inodeTree.Get(ino.Inode)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, got.ObjExtents.Size())
+	require.Equal(t, 1, got.Extents.Len())
+}
@@ -145,6 +145,7 @@ type MetaItemIterator struct {
 	cursor            uint64
 	treeSnap          Snapshot
 	uniqChecker       *uniqChecker
+	sharedExtents     *sharedExtents
 	verList           []*proto.VolVersionInfo
 	deletedExtentsId  uint64
 
@@ -199,6 +200,7 @@ func newMetaItemIterator(mp *metaPartition) (si *MetaItemIterator, err error) {
 		return
 	}
 	si.uniqChecker = mp.uniqChecker.clone()
+	si.sharedExtents = mp.sharedExtents.clone()
 	si.verList = mp.GetAllVerList()
 	si.deletedExtentsId = mp.GetDeletedExtentId()
 	mp.nonIdempotent.Unlock()
@@ -378,6 +380,13 @@ func newMetaItemIterator(mp *metaPartition) (si *MetaItemIterator, err error) {
 				}
 			}
 
+			if si.sharedExtents.len() != 0 {
+				produceItem(si.sharedExtents)
+				if checkClose() {
+					return
+				}
+			}
+
 			iter.treeSnap.Range(DeletedExtentsType, func(item interface{}) (bool, error) {
 				log.LogDebugf("[newMetaItemIterator] send deleted extents")
 				return produceItem(item), nil
@@ -387,6 +396,11 @@ func newMetaItemIterator(mp *metaPartition) (si *MetaItemIterator, err error) {
 				log.LogDebugf("[newMetaItemIterator] send deleted obj extents")
 				return produceItem(item), nil
 			})
+
+			iter.treeSnap.Range(FingerprintType, func(item interface{}) (bool, error) {
+				log.LogDebugf("[newMetaItemIterator] send fingerprint")
+				return produceItem(item), nil
+			})
 		}
 
 		// process extent del files
@@ -545,6 +559,14 @@ func (si *MetaItemIterator) Next() (data []byte, err error) {
 			return
 		}
 		snap = NewMetaItem(opFSMUniqCheckerSnap, nil, raw)
+	case *sharedExtents:
+		var raw []byte
+		if raw, _, err = typedItem.Marshal(); err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opFSMSharedExtentsSnap, nil, raw)
 	case *DeletedExtentKey:
 		var val []byte
 		val, err = typedItem.Marshal()
@@ -563,6 +585,15 @@ func (si *MetaItemIterator) Next() (data []byte, err error) {
 			return
 		}
 		snap = NewMetaItem(opFSMDeletedObjExtentsSnap, nil, val)
+	case *Fingerprint:
+		var val []byte
+		val, err = typedItem.Marshal()
+		if err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opFSMFingerprintSnap, nil, val)
 	default:
 		panic(fmt.Sprintf("unknown item type: %v", reflect.TypeOf(item).Name()))
 	}
@@ -0,0 +1,505 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/storage"
+	"github.com/cubefs/cubefs/util/log"
+	"github.com/cubefs/cubefs/util/timeutil"
+)
+
+func checkDedupChunks(chunks []proto.DedupChunk) error {
+	if len(chunks) == 0 {
+		return fmt.Errorf("no chunks")
+	}
+	for _, chunk := range chunks {
+		if len(chunk.Fingerprint) != proto.FingerprintSize || chunk.Size == 0 || chunk.Size > proto.MaxDedupChunkSize ||
+			chunk.FileOffset+uint64(chunk.Size) < chunk.FileOffset {
+			return fmt.Errorf("invalid chunk [%v, +%v)", chunk.FileOffset, chunk.Size)
+		}
+	}
+	return nil
+}
+
+func (mp *metaPartition) submitFingerprints(op uint32, req interface{}) (results []bool, err error) {
+	val, err := json.Marshal(req)
+	if err != nil {
+		return
+	}
+	r, err := mp.submit(op, val)
+	if err != nil {
+		return
+	}
+	resp := r.(*FingerprintsResp)
+	if resp.Status != proto.OpOk {
+		return nil, fmt.Errorf("fingerprints op(%v) status %v", op, resp.Status)
+	}
+	return resp.Results, nil
+}
+
+// DedupExtents makes the chunks of the inode refer to the data written before with the same fingerprints,
+// the chunks are acquired from the partitions of the volume the fingerprints are sharded to, and the chunks
+// not found are left to the client to write.
+func (mp *metaPartition) DedupExtents(req *proto.DedupExtentsRequest, p *Packet) (err error) {
+	if !proto.IsHot(mp.volType) {
+		err = fmt.Errorf("dedup extents is not supported by mp(%v)", mp.config.PartitionId)
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+		return
+	}
+	if err = checkDedupChunks(req.Chunks); err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	if status := mp.isOverQuota(req.Inode, true, false); status != proto.OpOk {
+		err = fmt.Errorf("dedup to inode %v is over quota", req.Inode)
+		p.PacketErrorWithBody(status, []byte(err.Error()))
+		return
+	}
+	view, err := dedupShards.get(mp.config.VolName)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	token, err := newDedupToken()
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+
+	fsmReq := &fsmDedupExtentsRequest{
+		Inode:      req.Inode,
+		ModifyTime: timeutil.GetCurrentTimeUnix(),
+		Token:      token,
+		Links:      mp.acquireDedupLinks(view.ids, req.Chunks, token),
+	}
+	resp := &DedupExtentsResp{Status: proto.OpOk, Extents: make([]*proto.ExtentKey, len(req.Chunks))}
+	if hasDedupLinks(fsmReq.Links) {
+		var val []byte
+		if val, err = json.Marshal(fsmReq); err != nil {
+			p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+			return
+		}
+		var r interface{}
+		if r, err = mp.submit(opFSMDedupExtents, val); err != nil {
+			log.LogWarnf("DedupExtents: mp(%v) inode(%v) chunks acquired with token(%v) are not recorded, err(%v)",
+				mp.config.PartitionId, req.Inode, token, err)
+			p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+			return
+		}
+		resp = r.(*DedupExtentsResp)
+	}
+	if resp.Status != proto.OpOk {
+		log.LogWarnf("DedupExtents: mp(%v) req(%v) status(%v)", mp.config.PartitionId, req, resp.Status)
+		p.PacketErrorWithBody(resp.Status, nil)
+		return
+	}
+	reply, err := json.Marshal(&proto.DedupExtentsResponse{Extents: resp.Extents})
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
+
+func hasDedupLinks(links []*dedupLink) bool {
+	for _, link := range links {
+		if link != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// acquireDedupLinks acquires the chunks from the fingerprint partitions they are sharded to, the links are
+// nil for the chunks not found.
+func (mp *metaPartition) acquireDedupLinks(shards []uint64, chunks []proto.DedupChunk, token uint64) []*dedupLink {
+	groups := make(map[uint64][]int)
+	for idx := range chunks {
+		shard := dedupShardOf(chunks[idx].Fingerprint, shards)
+		groups[shard] = append(groups[shard], idx)
+	}
+	links := make([]*dedupLink, len(chunks))
+	for shard, idxs := range groups {
+		req := &proto.AcquireFingerprintsRequest{
+			VolName:        mp.config.VolName,
+			PartitionID:    shard,
+			RefPartitionID: mp.config.PartitionId,
+			RefToken:       token,
+			Chunks:         make([]proto.DedupChunk, 0, len(idxs)),
+		}
+		for _, idx := range idxs {
+			req.Chunks = append(req.Chunks, chunks[idx])
+		}
+		resp, err := mp.acquireFromShard(shard, req)
+		if err != nil {
+			log.LogWarnf("[acquireDedupLinks] mp(%v) acquire %v chunks from mp(%v) err(%v)",
+				mp.config.PartitionId, len(idxs), shard, err)
+			continue
+		}
+		for j, ext := range resp.Extents {
+			if ext != nil {
+				links[idxs[j]] = &dedupLink{FileOffset: chunks[idxs[j]].FileOffset, Shard: shard, Extent: *ext}
+			}
+		}
+	}
+	return links
+}
+
+// chunkExtent returns the data the chunk at the file offset is written to, or nil if the chunk is not
+// written by a single key of the inode.
+func chunkExtent(i *Inode, chunk *proto.DedupChunk) (ext *proto.DedupExtent) {
+	end := chunk.FileOffset + uint64(chunk.Size)
+	i.Extents.Range(func(_ int, key proto.ExtentKey) bool {
+		if key.FileOffset > chunk.FileOffset {
+			return false
+		}
+		if key.FileOffset+uint64(key.Size) < end {
+			return true
+		}
+		if !key.IsCompressed() && !storage.IsTinyExtent(key.ExtentId) {
+			ext = &proto.DedupExtent{
+				Fingerprint:  chunk.Fingerprint,
+				PartitionId:  key.PartitionId,
+				ExtentId:     key.ExtentId,
+				ExtentOffset: key.ExtentOffset + chunk.FileOffset - key.FileOffset,
+				Size:         chunk.Size,
+			}
+		}
+		return false
+	})
+	return
+}
+
+// AddFingerprints indexes the chunks written by the inode in the partitions of the volume the fingerprints
+// are sharded to. The chunks are recorded by the partition before they are indexed, and the fingerprint
+// partitions verify the data of them on the data nodes.
+func (mp *metaPartition) AddFingerprints(req *proto.AddFingerprintsRequest, p *Packet) (err error) {
+	if !proto.IsHot(mp.volType) {
+		err = fmt.Errorf("add fingerprints is not supported by mp(%v)", mp.config.PartitionId)
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+		return
+	}
+	if err = checkDedupChunks(req.Chunks); err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	i, status := mp.getRegularInode(req.Inode)
+	if status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
+	view, err := dedupShards.get(mp.config.VolName)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	token, err := newDedupToken()
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+
+	fsmReq := &fsmAddFingerprintsRequest{Inode: req.Inode, Token: token}
+	for idx := range req.Chunks {
+		// the chunk written by several keys is not deduplicated
+		if ext := chunkExtent(i, &req.Chunks[idx]); ext != nil {
+			shard := dedupShardOf(ext.Fingerprint, view.ids)
+			fsmReq.Links = append(fsmReq.Links, &dedupLink{FileOffset: req.Chunks[idx].FileOffset, Shard: shard, Extent: *ext})
+		}
+	}
+	if len(fsmReq.Links) == 0 {
+		p.PacketOkReply()
+		return
+	}
+	recorded, err := mp.submitFingerprints(opFSMAddFingerprints, fsmReq)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+
+	groups := make(map[uint64]*proto.IndexFingerprintsRequest)
+	for idx, link := range fsmReq.Links {
+		if !recorded[idx] {
+			continue
+		}
+		indexReq, ok := groups[link.Shard]
+		if !ok {
+			indexReq = &proto.IndexFingerprintsRequest{
+				VolName:        mp.config.VolName,
+				PartitionID:    link.Shard,
+				RefPartitionID: mp.config.PartitionId,
+				RefToken:       token,
+			}
+			groups[link.Shard] = indexReq
+		}
+		indexReq.Extents = append(indexReq.Extents, link.Extent)
+	}
+	for shard, indexReq := range groups {
+		// the chunks not indexed are freed by the partition itself
+		if _, err := mp.indexToShard(shard, indexReq); err != nil {
+			log.LogWarnf("AddFingerprints: mp(%v) inode(%v) index %v chunks to mp(%v) err(%v)",
+				mp.config.PartitionId, req.Inode, len(indexReq.Extents), shard, err)
+		}
+	}
+	p.PacketOkReply()
+	return
+}
+
+// acquireFingerprints looks up the chunks in the index of the partition, the data of the chunks found is
+// verified on the data nodes before it is referred.
+func (mp *metaPartition) acquireFingerprints(req *proto.AcquireFingerprintsRequest) (resp *proto.AcquireFingerprintsResponse, err error) {
+	resp = &proto.AcquireFingerprintsResponse{Extents: make([]*proto.DedupExtent, len(req.Chunks))}
+	verified := &proto.IndexFingerprintsRequest{
+		VolName:        req.VolName,
+		PartitionID:    mp.config.PartitionId,
+		RefPartitionID: req.RefPartitionID,
+		RefToken:       req.RefToken,
+	}
+	idxs := make([]int, 0, len(req.Chunks))
+	for idx, chunk := range req.Chunks {
+		var fp *Fingerprint
+		if fp, err = mp.fingerprintTree.Get(chunk.Fingerprint); err != nil {
+			return
+		}
+		if fp == nil || fp.Size != chunk.Size || len(fp.Refs) == 0 {
+			continue
+		}
+		ext := fp.DedupExtent()
+		if !mp.verifyDedupExtent(ext) {
+			continue
+		}
+		verified.Extents = append(verified.Extents, *ext)
+		idxs = append(idxs, idx)
+	}
+	if len(idxs) == 0 {
+		return
+	}
+	acquired, err := mp.submitFingerprints(opFSMAcquireFingerprints, verified)
+	if err != nil {
+		return
+	}
+	for j, ok := range acquired {
+		if ok {
+			resp.Extents[idxs[j]] = &verified.Extents[j]
+		}
+	}
+	return
+}
+
+// indexFingerprints indexes the chunks whose data is verified on the data nodes.
+func (mp *metaPartition) indexFingerprints(req *proto.IndexFingerprintsRequest) (resp *proto.IndexFingerprintsResponse, err error) {
+	resp = &proto.IndexFingerprintsResponse{Indexed: make([]bool, len(req.Extents))}
+	verified := &proto.IndexFingerprintsRequest{
+		VolName:        req.VolName,
+		PartitionID:    mp.config.PartitionId,
+		RefPartitionID: req.RefPartitionID,
+		RefToken:       req.RefToken,
+	}
+	idxs := make([]int, 0, len(req.Extents))
+	for idx := range req.Extents {
+		if !mp.verifyDedupExtent(&req.Extents[idx]) {
+			continue
+		}
+		verified.Extents = append(verified.Extents, req.Extents[idx])
+		idxs = append(idxs, idx)
+	}
+	if len(idxs) == 0 {
+		return
+	}
+	indexed, err := mp.submitFingerprints(opFSMIndexFingerprints, verified)
+	if err != nil {
+		return
+	}
+	for j, ok := range indexed {
+		resp.Indexed[idxs[j]] = ok
+	}
+	return
+}
+
+func (mp *metaPartition) releaseFingerprints(req *proto.ReleaseFingerprintsRequest) (resp *proto.ReleaseFingerprintsResponse, err error) {
+	owned, err := mp.submitFingerprints(opFSMReleaseFingerprints, req)
+	if err != nil {
+		return
+	}
+	return &proto.ReleaseFingerprintsResponse{Owned: owned}, nil
+}
+
+func (mp *metaPartition) replyFingerprints(p *Packet, resp interface{}, err error) error {
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return err
+	}
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return err
+	}
+	p.PacketOkWithBody(reply)
+	return nil
+}
+
+// AcquireFingerprints looks up the chunks for the partition deduplicating them.
+func (mp *metaPartition) AcquireFingerprints(req *proto.AcquireFingerprintsRequest, p *Packet) (err error) {
+	if err = checkDedupChunks(req.Chunks); err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.acquireFingerprints(req)
+	return mp.replyFingerprints(p, resp, err)
+}
+
+// IndexFingerprints indexes the chunks written by the partition.
+func (mp *metaPartition) IndexFingerprints(req *proto.IndexFingerprintsRequest, p *Packet) (err error) {
+	resp, err := mp.indexFingerprints(req)
+	return mp.replyFingerprints(p, resp, err)
+}
+
+// ReleaseFingerprints drops the references of the partition to the chunks.
+func (mp *metaPartition) ReleaseFingerprints(req *proto.ReleaseFingerprintsRequest, p *Packet) (err error) {
+	resp, err := mp.releaseFingerprints(req)
+	return mp.replyFingerprints(p, resp, err)
+}
+
+// uncoveredRanges returns the ranges of ek not covered by the chunks.
+func uncoveredRanges(ek *proto.ExtentKey, chunks []*dedupChunk) (ranges []*proto.ExtentKey) {
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ExtentOffset < chunks[j].ExtentOffset })
+	cursor, end := ek.ExtentOffset, ek.ExtentOffset+uint64(ek.Size)
+	add := func(from, to uint64) {
+		if from < to {
+			ranges = append(ranges, &proto.ExtentKey{PartitionId: ek.PartitionId, ExtentId: ek.ExtentId,
+				ExtentOffset: from, Size: uint32(to - from)})
+		}
+	}
+	for _, c := range chunks {
+		if !c.overlaps(ek) {
+			continue
+		}
+		add(cursor, c.ExtentOffset)
+		if chunkEnd := c.ExtentOffset + uint64(c.Size); chunkEnd > cursor {
+			cursor = chunkEnd
+		}
+	}
+	if cursor < end {
+		add(cursor, end)
+	}
+	return
+}
+
+// freeDedupExtents filters the keys of the extents deduplicated to be freed. A chunk is released from the
+// fingerprint partition once none of the inodes refers to it, and is freed here only if the fingerprint
+// partition does not index it. The other ranges are freed once none of the inodes refers to them.
+func (mp *metaPartition) freeDedupExtents(eks []*proto.ExtentKey) (result []*proto.ExtentKey, err error) {
+	type releasing struct {
+		id    uint64
+		chunk *dedupChunk
+	}
+	type chunkKey struct {
+		id, offset, shard uint64
+	}
+	releases := make(map[uint64][]releasing)
+	released := make(map[chunkKey]bool)
+	for _, ek := range eks {
+		id := ek.GenerateId()
+		mp.sharedExtents.markFreed(id)
+		chunks := mp.sharedExtents.getChunks(id)
+		inodes := mp.sharedExtents.get(id)
+		for _, c := range chunks {
+			inodes = appendInodes(inodes, c.inodes()...)
+		}
+		for _, r := range uncoveredRanges(ek, chunks) {
+			if _, overlapped := mp.isSharedExtentReferred(inodes, r); !overlapped {
+				result = append(result, r)
+			}
+		}
+		for _, c := range chunks {
+			key := chunkKey{id: id, offset: c.ExtentOffset, shard: c.Shard}
+			if !c.overlaps(ek) || released[key] {
+				continue
+			}
+			chunkEk := c.dedupExtent(id).ExtentKey(0)
+			if _, overlapped := mp.isSharedExtentReferred(inodes, &chunkEk); overlapped {
+				continue
+			}
+			released[key] = true
+			releases[c.Shard] = append(releases[c.Shard], releasing{id: id, chunk: c})
+		}
+	}
+
+	for shard, list := range releases {
+		req := &proto.ReleaseFingerprintsRequest{
+			VolName:        mp.config.VolName,
+			PartitionID:    shard,
+			RefPartitionID: mp.config.PartitionId,
+			Releases:       make([]proto.FingerprintRelease, 0, len(list)),
+		}
+		for _, r := range list {
+			req.Releases = append(req.Releases, proto.FingerprintRelease{
+				DedupExtent: *r.chunk.dedupExtent(r.id),
+				RefTokens:   r.chunk.tokens(),
+			})
+		}
+		var resp *proto.ReleaseFingerprintsResponse
+		if resp, err = mp.releaseToShard(shard, req); err != nil {
+			log.LogErrorf("[freeDedupExtents] mp(%v) release %v chunks to mp(%v) err(%v)",
+				mp.config.PartitionId, len(list), shard, err)
+			return
+		}
+		for idx, owned := range resp.Owned {
+			if !owned {
+				ek := req.Releases[idx].ExtentKey(0)
+				result = append(result, &ek)
+			}
+		}
+	}
+	log.LogDebugf("[freeDedupExtents] mp(%v) eks(%v) free(%v)", mp.config.PartitionId, len(eks), len(result))
+	return
+}
+
+// dropDedupExtents forgets the extents deduplicated whose keys are freed once none of the inodes recorded
+// refers to them and none of the keys is still to be freed.
+func (mp *metaPartition) dropDedupExtents() {
+	ids := make([]uint64, 0)
+	for _, id := range mp.sharedExtents.freedIds() {
+		alive, pending, err := mp.isDedupExtentReferred(id)
+		if err != nil {
+			log.LogWarnf("[dropDedupExtents] mp(%v) check extent(%v) err(%v)", mp.config.PartitionId, id, err)
+			continue
+		}
+		if alive {
+			// checked again once the keys of it are freed
+			mp.sharedExtents.unmarkFreed(id)
+			continue
+		}
+		if !pending {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	val, err := json.Marshal(&fsmReleaseSharedExtentsRequest{Extents: ids})
+	if err != nil {
+		return
+	}
+	if _, err = mp.submit(opFSMDropDedupExtents, val); err != nil {
+		log.LogWarnf("[dropDedupExtents] mp(%v) drop extents(%v) err(%v)", mp.config.PartitionId, ids, err)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"fmt"
+
+	"github.com/cubefs/cubefs/proto"
+)
+
+// initExtend returns the xattrs to be stored with the inode to be created, which are the
+// ACLs inherited from the parent directory and the data key of the encrypted file.
+func (mp *metaPartition) initExtend(ino *Inode, defaultACL, fileKey []byte) (extend *Extend, err error) {
+	if extend, err = mp.inheritPosixACL(ino, defaultACL); err != nil {
+		return
+	}
+	if len(fileKey) == 0 || !proto.IsRegular(ino.Type) {
+		return
+	}
+	if _, err = proto.UnmarshalFileKey(fileKey); err != nil {
+		return nil, err
+	}
+	if extend == nil {
+		extend = NewExtend(ino.Inode)
+	}
+	extend.Put([]byte(proto.XAttrFileKey), fileKey, mp.verSeq)
+	return
+}
+
+// checkReservedXAttr rejects the changes of the xattrs only set by the meta node, the data
+// of the encrypted file could not be read any more once the data key is changed.
+func checkReservedXAttr(key string) error {
+	if key == proto.XAttrFileKey {
+		return fmt.Errorf("xattr %v is reserved", key)
+	}
+	return nil
+}
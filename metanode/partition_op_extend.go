@@ -85,6 +85,14 @@ func (mp *metaPartition) UpdateXAttr(req *proto.UpdateXAttrRequest, p *Packet) (
 }
 
 func (mp *metaPartition) SetXAttr(req *proto.SetXAttrRequest, p *Packet) (err error) {
+	if err = checkReservedXAttr(req.Key); err != nil {
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+		return
+	}
+	if err = checkPosixACLXAttr(req.Key, []byte(req.Value)); err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
 	extend := NewExtend(req.Inode)
 	extend.Put([]byte(req.Key), []byte(req.Value), mp.verSeq)
 	if _, err = mp.putExtend(opFSMSetXAttr, extend); err != nil {
@@ -98,6 +106,14 @@ func (mp *metaPartition) SetXAttr(req *proto.SetXAttrRequest, p *Packet) (err er
 func (mp *metaPartition) BatchSetXAttr(req *proto.BatchSetXAttrRequest, p *Packet) (err error) {
 	extend := NewExtend(req.Inode)
 	for key, val := range req.Attrs {
+		if err = checkReservedXAttr(key); err != nil {
+			p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+			return
+		}
+		if err = checkPosixACLXAttr(key, []byte(val)); err != nil {
+			p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+			return
+		}
 		extend.Put([]byte(key), []byte(val), mp.verSeq)
 	}
 
@@ -205,6 +221,10 @@ func (mp *metaPartition) BatchGetXAttr(req *proto.BatchGetXAttrRequest, p *Packe
 }
 
 func (mp *metaPartition) RemoveXAttr(req *proto.RemoveXAttrRequest, p *Packet) (err error) {
+	if err = checkReservedXAttr(req.Key); err != nil {
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+		return
+	}
 	extend := NewExtend(req.Inode)
 	extend.Put([]byte(req.Key), nil, req.VerSeq)
 	if _, err = mp.putExtend(opFSMRemoveXAttr, extend); err != nil {
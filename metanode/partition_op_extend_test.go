@@ -16,6 +16,7 @@ package metanode
 
 import (
 	"encoding/json"
+	"os"
 	"testing"
 
 	"github.com/cubefs/cubefs/proto"
@@ -178,3 +179,110 @@ func TestUpdateXAttr_Rocksdb(t *testing.T) {
 	mp := mockPartitionRaftForFsmExtendTest(t, mockCtrl, proto.StoreModeRocksDb)
 	testUpdateXAttr(t, mp)
 }
+
+func testInheritPosixACL(t *testing.T, mp MetaPartition) {
+	dflt := proto.PosixACLFromMode(0o750)
+	dflt.Entries = append(dflt.Entries,
+		proto.PosixACLEntry{Tag: proto.ACLUser, Perm: 7, Id: 1001},
+		proto.PosixACLEntry{Tag: proto.ACLMask, Perm: 7, Id: proto.PosixACLUndefinedID})
+	dfltData, err := dflt.Marshal()
+	require.NoError(t, err)
+	p := &Packet{}
+	req := &CreateInoReq{
+		VolName:     mp.GetBaseConfig().VolName,
+		PartitionID: mp.GetBaseConfig().PartitionId,
+		Mode:        proto.Mode(os.ModeDir | 0o777),
+		DefaultACL:  dfltData,
+	}
+	err = mp.CreateInode(req, p, "")
+	require.NoError(t, err)
+	resp := &CreateInoResp{}
+	err = json.Unmarshal(p.Data, resp)
+	require.NoError(t, err)
+	require.EqualValues(t, 0o770, resp.Info.Mode&0o777)
+	require.True(t, proto.IsDir(resp.Info.Mode))
+
+	access, _, _ := proto.InheritPosixACL(dflt, 0o777, true)
+	accessData, err := access.Marshal()
+	require.NoError(t, err)
+	checkXattrForExtendTest(t, mp, resp.Info.Inode, proto.XAttrPosixACLAccess, string(accessData))
+	checkXattrForExtendTest(t, mp, resp.Info.Inode, proto.XAttrPosixACLDefault, string(dfltData))
+
+	// malformed acl is rejected
+	setReq := &proto.SetXAttrRequest{
+		VolName:     mp.GetBaseConfig().VolName,
+		PartitionId: mp.GetBaseConfig().PartitionId,
+		Inode:       resp.Info.Inode,
+		Key:         proto.XAttrPosixACLAccess,
+		Value:       "invalid",
+	}
+	p = &Packet{}
+	err = mp.SetXAttr(setReq, p)
+	require.Error(t, err)
+	require.EqualValues(t, proto.OpArgMismatchErr, p.ResultCode)
+}
+
+func TestInheritPosixACL(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mp := mockPartitionRaftForFsmExtendTest(t, mockCtrl, proto.StoreModeMem)
+	testInheritPosixACL(t, mp)
+}
+
+func testCreateEncryptedInode(t *testing.T, mp MetaPartition) {
+	fileKey, err := (&proto.FileKey{Version: proto.FileKeyVersion, Wrapped: []byte("wrapped")}).Marshal()
+	require.NoError(t, err)
+	p := &Packet{}
+	req := &CreateInoReq{
+		VolName:     mp.GetBaseConfig().VolName,
+		PartitionID: mp.GetBaseConfig().PartitionId,
+		Mode:        FileModeType,
+		FileKey:     fileKey,
+	}
+	err = mp.CreateInode(req, p, "")
+	require.NoError(t, err)
+	resp := &CreateInoResp{}
+	err = json.Unmarshal(p.Data, resp)
+	require.NoError(t, err)
+	ino := resp.Info.Inode
+	checkXattrForExtendTest(t, mp, ino, proto.XAttrFileKey, string(fileKey))
+
+	// the file key could not be changed by the clients
+	setReq := &proto.SetXAttrRequest{
+		VolName:     mp.GetBaseConfig().VolName,
+		PartitionId: mp.GetBaseConfig().PartitionId,
+		Inode:       ino,
+		Key:         proto.XAttrFileKey,
+		Value:       "changed",
+	}
+	p = &Packet{}
+	err = mp.SetXAttr(setReq, p)
+	require.Error(t, err)
+	require.EqualValues(t, proto.OpNotPerm, p.ResultCode)
+
+	removeReq := &proto.RemoveXAttrRequest{
+		VolName:     mp.GetBaseConfig().VolName,
+		PartitionId: mp.GetBaseConfig().PartitionId,
+		Inode:       ino,
+		Key:         proto.XAttrFileKey,
+	}
+	p = &Packet{}
+	err = mp.RemoveXAttr(removeReq, p)
+	require.Error(t, err)
+	require.EqualValues(t, proto.OpNotPerm, p.ResultCode)
+	checkXattrForExtendTest(t, mp, ino, proto.XAttrFileKey, string(fileKey))
+
+	// the malformed file key is rejected
+	req.FileKey = []byte("invalid")
+	p = &Packet{}
+	err = mp.CreateInode(req, p, "")
+	require.Error(t, err)
+	require.EqualValues(t, proto.OpArgMismatchErr, p.ResultCode)
+}
+
+func TestCreateEncryptedInode(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mp := mockPartitionRaftForFsmExtendTest(t, mockCtrl, proto.StoreModeMem)
+	testCreateEncryptedInode(t, mp)
+}
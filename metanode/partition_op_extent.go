@@ -419,6 +419,7 @@ func (mp *metaPartition) ExtentsList(req *proto.GetExtentsRequest, p *Packet) (e
 				})
 			})
 		}
+		mp.markSharedExtents(resp.Extents)
 		if req.VerAll {
 			resp.LayerInfo = retMsg.Msg.getAllLayerEks()
 		}
@@ -0,0 +1,101 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+	"github.com/cubefs/cubefs/util/timeutil"
+)
+
+// Fallocate preallocates, punches or zeroes the range of the inode.
+func (mp *metaPartition) Fallocate(req *proto.FallocateRequest, p *Packet) (err error) {
+	if !proto.IsHot(mp.volType) {
+		err = fmt.Errorf("only support hot vol")
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	if err = proto.ValidateFallocate(req.Mode, req.Offset, req.Length); err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	if status := mp.isOverQuota(req.Inode, req.Mode&proto.FallocKeepSize == 0, false); status != proto.OpOk {
+		err = fmt.Errorf("fallocate inode %v is over quota", req.Inode)
+		p.PacketErrorWithBody(status, []byte(err.Error()))
+		return
+	}
+
+	req.ModifyTime = timeutil.GetCurrentTimeUnix()
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMFallocate, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+// CloneExtents clones the extent keys of the source range to the destination range, as copy_file_range
+// does, the data is shared by the inodes and copied on the later overwrite.
+func (mp *metaPartition) CloneExtents(req *proto.CloneExtentsRequest, p *Packet) (err error) {
+	if !proto.IsHot(mp.volType) || mp.HasRocksDBStore() {
+		err = fmt.Errorf("clone extents is not supported by mp(%v)", mp.config.PartitionId)
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+		return
+	}
+	if req.Length == 0 || req.SrcOffset+req.Length < req.SrcOffset || req.DstOffset+req.Length < req.DstOffset {
+		err = fmt.Errorf("invalid clone range [%v, +%v) to %v", req.SrcOffset, req.Length, req.DstOffset)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	if status := mp.isOverQuota(req.DstInode, true, false); status != proto.OpOk {
+		err = fmt.Errorf("clone to inode %v is over quota", req.DstInode)
+		p.PacketErrorWithBody(status, []byte(err.Error()))
+		return
+	}
+
+	req.ModifyTime = timeutil.GetCurrentTimeUnix()
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	r, err := mp.submit(opFSMCloneExtents, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	resp := r.(*CloneExtentsResp)
+	if resp.Status != proto.OpOk {
+		log.LogWarnf("CloneExtents: mp(%v) req(%v) status(%v)", mp.config.PartitionId, req, resp.Status)
+		p.PacketErrorWithBody(resp.Status, nil)
+		return
+	}
+	reply, err := json.Marshal(&proto.CloneExtentsResponse{Size: resp.Size})
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
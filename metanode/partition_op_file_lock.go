@@ -0,0 +1,96 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// SetFileLock acquires or releases the advisory lock, the conflicting lock is returned with OpFileLockConflict.
+func (mp *metaPartition) SetFileLock(req *proto.SetFileLockRequest, p *Packet) (err error) {
+	lock := &req.Lock
+	if err = proto.ValidateFileLock(lock); err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	if lock.Type != proto.FileLockUnlock {
+		var ino *Inode
+		if ino, err = mp.inodeTree.Get(lock.Inode); err != nil {
+			p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+			return
+		}
+		if ino == nil || ino.ShouldDelete() {
+			err = fmt.Errorf("inode %v not exist", lock.Inode)
+			p.PacketErrorWithBody(proto.OpNotExistErr, []byte(err.Error()))
+			return
+		}
+	}
+
+	conflict, again := mp.fileLocks.set(lock)
+	if again {
+		// the clients are reclaiming their locks, let the client retry later
+		p.PacketErrorWithBody(proto.OpAgain, []byte(fmt.Sprintf("mp(%v) in grace period of file locks", mp.config.PartitionId)))
+		return
+	}
+	if conflict != nil {
+		log.LogDebugf("SetFileLock: mp(%v) lock(%v) conflicts with (%v)", mp.config.PartitionId, lock, conflict)
+		reply, _ := json.Marshal(&proto.GetFileLockResponse{Conflict: conflict})
+		p.PacketErrorWithBody(proto.OpFileLockConflict, reply)
+		return
+	}
+	p.PacketOkReply()
+	return
+}
+
+// GetFileLock returns the lock which conflicts with the requested one, as F_GETLK does.
+func (mp *metaPartition) GetFileLock(req *proto.GetFileLockRequest, p *Packet) (err error) {
+	lock := &req.Lock
+	if err = proto.ValidateFileLock(lock); err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	resp := &proto.GetFileLockResponse{Conflict: mp.fileLocks.get(lock)}
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
+
+// RenewFileLocks extends the lease of the client, and returns the locks which are lost.
+func (mp *metaPartition) RenewFileLocks(req *proto.RenewFileLocksRequest, p *Packet) (err error) {
+	if req.Client == "" {
+		err = fmt.Errorf("missing client of file lock")
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	resp := &proto.RenewFileLocksResponse{Lost: mp.fileLocks.renew(req.Client, req.Locks)}
+	if len(resp.Lost) > 0 {
+		log.LogWarnf("RenewFileLocks: mp(%v) client(%v) lost locks(%v)", mp.config.PartitionId, req.Client, resp.Lost)
+	}
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
@@ -125,6 +125,11 @@ func (mp *metaPartition) CreateInode(req *CreateInoReq, p *Packet, remoteAddr st
 	ino.Gid = req.Gid
 	ino.setVer(mp.verSeq)
 	ino.LinkTarget = req.Target
+	extend, err := mp.initExtend(ino, req.DefaultACL, req.FileKey)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
 
 	val, err := ino.Marshal()
 	if err != nil {
@@ -138,6 +143,12 @@ func (mp *metaPartition) CreateInode(req *CreateInoReq, p *Packet, remoteAddr st
 	}
 
 	if resp.(uint8) == proto.OpOk {
+		if extend != nil {
+			if _, err = mp.putExtend(opFSMSetXAttr, extend); err != nil {
+				p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+				return
+			}
+		}
 		resp := &CreateInoResp{
 			Info: &proto.InodeInfo{},
 		}
@@ -178,6 +189,11 @@ func (mp *metaPartition) QuotaCreateInode(req *proto.QuotaCreateInodeRequest, p
 	ino.Uid = req.Uid
 	ino.Gid = req.Gid
 	ino.LinkTarget = req.Target
+	extend, err := mp.initExtend(ino, req.DefaultACL, req.FileKey)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
 
 	for _, quotaId := range req.QuotaIds {
 		status = mp.mqMgr.IsOverQuota(false, true, quotaId)
@@ -204,6 +220,12 @@ func (mp *metaPartition) QuotaCreateInode(req *proto.QuotaCreateInodeRequest, p
 	}
 
 	if resp.(uint8) == proto.OpOk {
+		if extend != nil {
+			if _, err = mp.putExtend(opFSMSetXAttr, extend); err != nil {
+				p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+				return
+			}
+		}
 		resp := &CreateInoResp{
 			Info: &proto.InodeInfo{},
 		}
@@ -906,6 +928,11 @@ func (mp *metaPartition) TxCreateInode(req *proto.TxCreateInodeRequest, p *Packe
 	txIno.Inode.Uid = req.Uid
 	txIno.Inode.Gid = req.Gid
 	txIno.Inode.LinkTarget = req.Target
+	extend, err := mp.initExtend(txIno.Inode, req.DefaultACL, req.FileKey)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
 
 	if log.EnableDebug() {
 		log.LogDebugf("NewTxInode: TxInode: %v", txIno)
@@ -950,6 +977,12 @@ func (mp *metaPartition) TxCreateInode(req *proto.TxCreateInodeRequest, p *Packe
 	}
 
 	if resp == proto.OpOk {
+		if extend != nil {
+			if _, err = mp.putExtend(opFSMSetXAttr, extend); err != nil {
+				p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+				return
+			}
+		}
 		quotaInfos := make(map[uint32]*proto.MetaQuotaInfo)
 		for _, quotaId := range req.QuotaIds {
 			quotaInfos[quotaId] = &proto.MetaQuotaInfo{
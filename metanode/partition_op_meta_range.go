@@ -0,0 +1,692 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// An inode range [Start, End] at the end of the range of a partition is migrated to a new partition or to
+// the next one by the leader of the target partition:
+//  1. the inode, dentry and extend trees of the range are copied from the source through the raft of the
+//     target, and copied again with the digests of the items to catch up with the source;
+//  2. the range of the source is frozen, the requests of it are held off until the cutover;
+//  3. the items differing from the source are copied at last, and the master is responded;
+//  4. the master switches the ranges of both partitions, and the source redirects the requests of the
+//     moved range to the target since then.
+// The dentries are kept with the parent inode, so the items of the dentry tree are migrated by the parent.
+//
+// The shared extents and the transactions of the partition are not migrated, a range holding the inodes
+// recorded in them is refused, or the refcounts of the extents would be split across the partitions and an
+// extent still referred by the other partition could be freed. The deleted extents are freed by the source
+// whatever the range, and the quota usage is rebuilt from the trees once the moved range is dropped.
+
+const (
+	metaRangeReadLimit      = 1000
+	metaRangeDigestLimit    = 10000
+	metaRangeReadMaxBytes   = 1 << 20
+	dropMovedRangesInterval = time.Minute
+)
+
+var (
+	errMetaRangeMigrating  = errors.New("meta range is being migrated")
+	errMetaRangeNotMovable = errors.New("meta range holds shared or deduplicated inodes or transactions")
+)
+
+var metaRangeTrees = []uint8{proto.MetaRangeInodeTree, proto.MetaRangeDentryTree, proto.MetaRangeExtendTree}
+
+// metaRangeState is the snapshot of the frozen and the moved ranges of the partition read by the requests.
+type metaRangeState struct {
+	frozen *proto.MetaRange
+	moved  []*proto.MovedMetaRange
+}
+
+func (s *metaRangeState) movedTo(ino uint64) *proto.MovedMetaRange {
+	for _, moved := range s.moved {
+		if moved.Contains(ino) {
+			return moved
+		}
+	}
+	return nil
+}
+
+// updateRangeState publishes the ranges of the config, it is called once the ranges are changed by apply.
+func (mp *metaPartition) updateRangeState() {
+	if mp.config.FrozenRange == nil && len(mp.config.MovedRanges) == 0 {
+		mp.rangeState.Store((*metaRangeState)(nil))
+		return
+	}
+	state := &metaRangeState{
+		frozen: mp.config.FrozenRange,
+		moved:  append([]*proto.MovedMetaRange(nil), mp.config.MovedRanges...),
+	}
+	mp.rangeState.Store(state)
+}
+
+func (mp *metaPartition) getRangeState() *metaRangeState {
+	state, _ := mp.rangeState.Load().(*metaRangeState)
+	return state
+}
+
+func (mp *metaPartition) isInodeFrozen(ino uint64) bool {
+	state := mp.getRangeState()
+	return state != nil && state.frozen.Contains(ino)
+}
+
+func (mp *metaPartition) isInodeMoved(ino uint64) bool {
+	state := mp.getRangeState()
+	return state != nil && state.movedTo(ino) != nil
+}
+
+func (mp *metaPartition) movedRange(start uint64) *proto.MovedMetaRange {
+	for _, moved := range mp.config.MovedRanges {
+		if moved.Start == start {
+			return moved
+		}
+	}
+	return nil
+}
+
+// metaRangeReqKeys are the inodes the request works on, the dentry requests work on the parent inode.
+type metaRangeReqKeys struct {
+	ParentID *uint64         `json:"pino"`
+	Inode    json.RawMessage `json:"ino"`
+	Inodes   []uint64        `json:"inos"`
+	SrcInode *uint64         `json:"src"`
+	DstInode *uint64         `json:"dst"`
+}
+
+func (k *metaRangeReqKeys) inodes() []uint64 {
+	if k.ParentID != nil {
+		return []uint64{*k.ParentID}
+	}
+	if k.SrcInode != nil && k.DstInode != nil {
+		return []uint64{*k.SrcInode, *k.DstInode}
+	}
+	if len(k.Inode) > 0 {
+		var ino uint64
+		if err := json.Unmarshal(k.Inode, &ino); err == nil {
+			return []uint64{ino}
+		}
+		var inos []uint64
+		if err := json.Unmarshal(k.Inode, &inos); err == nil {
+			return inos
+		}
+	}
+	return k.Inodes
+}
+
+func isMetaRangeOp(opcode uint8) bool {
+	switch opcode {
+	case proto.OpMetaReadRange, proto.OpMetaFreezeRange, proto.OpMigrateMetaRange, proto.OpCutoverMetaRange:
+		return true
+	}
+	return false
+}
+
+// acquireMetaRange checks the request against the frozen and the moved ranges. The request of the frozen
+// range is responded to try again, and the one of the moved range is redirected to the target partition.
+// The range is held until release is called, so the range is not frozen with the request in flight.
+func (mp *metaPartition) acquireMetaRange(p *Packet) (release func(), ok bool) {
+	mp.rangeLock.RLock()
+	release = mp.rangeLock.RUnlock
+	state := mp.getRangeState()
+	if state == nil {
+		return release, true
+	}
+	keys := &metaRangeReqKeys{}
+	if err := json.Unmarshal(p.Data, keys); err != nil {
+		return release, true
+	}
+	for _, ino := range keys.inodes() {
+		if state.frozen.Contains(ino) {
+			p.PacketErrorWithBody(proto.OpAgain, []byte(fmt.Sprintf("inode %v is being migrated", ino)))
+			return release, false
+		}
+		if moved := state.movedTo(ino); moved != nil {
+			body, _ := json.Marshal(&proto.MetaRangeMovedResponse{PartitionID: moved.PartitionID})
+			p.PacketErrorWithBody(proto.OpMetaRangeMoved, body)
+			return release, false
+		}
+	}
+	return release, true
+}
+
+// checkMetaRangeMovable refuses the range holding the inodes which share the extents by clone or dedup, or
+// which are in the transactions. It is called by apply as well, so it reads the states updated by raft only.
+func (mp *metaPartition) checkMetaRangeMovable(start, end uint64) (err error) {
+	r := &proto.MetaRange{Start: start, End: end}
+	se := mp.sharedExtents
+	for _, id := range se.ids() {
+		inodes := se.get(id)
+		for _, c := range se.getChunks(id) {
+			inodes = append(inodes, c.inodes()...)
+		}
+		for _, ino := range inodes {
+			if r.Contains(ino) {
+				return fmt.Errorf("%w: inode %v shares extent %v", errMetaRangeNotMovable, ino, id)
+			}
+		}
+	}
+
+	tr := mp.txProcessor.txResource
+	err = tr.txRbInodeTree.Range(nil, nil, func(i *TxRollbackInode) (bool, error) {
+		if r.Contains(i.txInodeInfo.Ino) {
+			return false, fmt.Errorf("%w: inode %v is in transaction %v", errMetaRangeNotMovable,
+				i.txInodeInfo.Ino, i.txInodeInfo.TxID)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return
+	}
+	return tr.txRbDentryTree.Range(nil, nil, func(d *TxRollbackDentry) (bool, error) {
+		if r.Contains(d.txDentryInfo.ParentId) {
+			return false, fmt.Errorf("%w: dentry %v of inode %v is in transaction %v", errMetaRangeNotMovable,
+				d.txDentryInfo.Name, d.txDentryInfo.ParentId, d.txDentryInfo.TxID)
+		}
+		return true, nil
+	})
+}
+
+func inodeRangeEnd(end uint64) *Inode {
+	if end == math.MaxUint64 {
+		return nil
+	}
+	return NewInode(end+1, 0)
+}
+
+func metaRangeKey(ino uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, ino)
+	return key
+}
+
+// rangeMetaItems walks through the items of the tree from the key of start in the range ended by the
+// inode of end, the key and the marshaled item are passed to cb.
+func (mp *metaPartition) rangeMetaItems(tree uint8, start []byte, end uint64, cb func(key, item []byte) (bool, error)) error {
+	switch tree {
+	case proto.MetaRangeInodeTree:
+		return mp.inodeTree.Range(NewInode(binary.BigEndian.Uint64(start), 0), inodeRangeEnd(end), func(i *Inode) (bool, error) {
+			item, err := i.Marshal()
+			if err != nil {
+				return false, err
+			}
+			return cb(i.MarshalKey(), item)
+		})
+	case proto.MetaRangeDentryTree:
+		startDentry := &Dentry{}
+		if err := startDentry.UnmarshalKey(start); err != nil {
+			return err
+		}
+		var endDentry *Dentry
+		if end != math.MaxUint64 {
+			endDentry = &Dentry{ParentId: end + 1}
+		}
+		return mp.dentryTree.Range(startDentry, endDentry, func(d *Dentry) (bool, error) {
+			item, err := d.Marshal()
+			if err != nil {
+				return false, err
+			}
+			return cb(d.MarshalKey(), item)
+		})
+	case proto.MetaRangeExtendTree:
+		var endExtend *Extend
+		if end != math.MaxUint64 {
+			endExtend = NewExtend(end + 1)
+		}
+		return mp.extendTree.Range(NewExtend(binary.BigEndian.Uint64(start)), endExtend, func(e *Extend) (bool, error) {
+			item, err := e.Bytes()
+			if err != nil {
+				return false, err
+			}
+			return cb(metaRangeKey(e.GetInode()), item)
+		})
+	}
+	return fmt.Errorf("unknown tree %v", tree)
+}
+
+// getMetaRangeItem returns the marshaled item of the key, nil is returned if it does not exist.
+func (mp *metaPartition) getMetaRangeItem(tree uint8, key []byte) ([]byte, error) {
+	switch tree {
+	case proto.MetaRangeInodeTree:
+		ino, err := mp.inodeTree.Get(binary.BigEndian.Uint64(key))
+		if err != nil || ino == nil {
+			return nil, err
+		}
+		return ino.Marshal()
+	case proto.MetaRangeDentryTree:
+		dentry := &Dentry{}
+		if err := dentry.UnmarshalKey(key); err != nil {
+			return nil, err
+		}
+		d, err := mp.dentryTree.Get(dentry.ParentId, dentry.Name)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		return d.Marshal()
+	case proto.MetaRangeExtendTree:
+		extend, err := mp.extendTree.Get(binary.BigEndian.Uint64(key))
+		if err != nil || extend == nil {
+			return nil, err
+		}
+		return extend.Bytes()
+	}
+	return nil, fmt.Errorf("unknown tree %v", tree)
+}
+
+// FreezeMetaRange holds off the requests of the range of the source partition before the last copy.
+func (mp *metaPartition) FreezeMetaRange(req *proto.FreezeMetaRangeRequest, p *Packet) (err error) {
+	if req.Start <= mp.config.Start || req.End != mp.config.End || req.Start > req.End {
+		err = fmt.Errorf("range %v-%v is not the end of the range %v-%v of mp(%v)",
+			req.Start, req.End, mp.config.Start, mp.config.End, mp.config.PartitionId)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	if err = mp.checkMetaRangeMovable(req.Start, req.End); err != nil {
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+		return
+	}
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+
+	// wait for the requests in flight, which are applied before the range is frozen, the range is checked
+	// again by apply for the clones and the transactions applied since then
+	mp.rangeLock.Lock()
+	defer mp.rangeLock.Unlock()
+	resp, err := mp.submit(opFSMFreezeMetaRange, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	if status := resp.(uint8); status == proto.OpNotPerm {
+		p.PacketErrorWithBody(status, []byte(errMetaRangeNotMovable.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+// ReadMetaRange reads the items of the range for the target partition.
+func (mp *metaPartition) ReadMetaRange(req *proto.ReadMetaRangeRequest, p *Packet) (err error) {
+	resp := &proto.ReadMetaRangeResponse{Cursor: mp.GetCursor()}
+	if len(req.Keys) > 0 {
+		for _, key := range req.Keys {
+			var item []byte
+			if item, err = mp.getMetaRangeItem(req.Tree, key); err != nil {
+				p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+				return
+			}
+			if item != nil {
+				resp.Keys = append(resp.Keys, key)
+				resp.Items = append(resp.Items, item)
+			}
+		}
+	} else {
+		start := req.Marker
+		if len(start) == 0 {
+			// the range not movable is refused before it is copied, it is checked again once frozen
+			if err = mp.checkMetaRangeMovable(req.Start, req.End); err != nil {
+				p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+				return
+			}
+			start = metaRangeKey(req.Start)
+		}
+		limit := req.Limit
+		if limit <= 0 {
+			limit = metaRangeReadLimit
+		}
+		var count, size int
+		err = mp.rangeMetaItems(req.Tree, start, req.End, func(key, item []byte) (bool, error) {
+			if count >= limit || size >= metaRangeReadMaxBytes {
+				resp.Next = key
+				return false, nil
+			}
+			resp.Keys = append(resp.Keys, key)
+			if req.Digest {
+				resp.Crcs = append(resp.Crcs, crc32.ChecksumIEEE(item))
+				size += len(key) + 4
+			} else {
+				resp.Items = append(resp.Items, item)
+				size += len(item)
+			}
+			count++
+			return true, nil
+		})
+		if err != nil {
+			p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+			return
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(data)
+	return
+}
+
+// MigrateMetaRange copies the range from the source partition in the background, done is called with the
+// result once the range is frozen and copied at last. The request is ignored if the range is being copied.
+func (mp *metaPartition) MigrateMetaRange(req *proto.MigrateMetaRangeRequest, done func(resp *proto.MigrateMetaRangeResponse)) (err error) {
+	if req.Start > req.End || req.SrcPartitionID == mp.config.PartitionId ||
+		(mp.config.Start != req.Start && mp.config.Start != req.End+1) {
+		return fmt.Errorf("range %v-%v can not be migrated to mp(%v) range %v-%v",
+			req.Start, req.End, mp.config.PartitionId, mp.config.Start, mp.config.End)
+	}
+	if !atomic.CompareAndSwapInt32(&mp.rangeMigrating, 0, 1) {
+		return errMetaRangeMigrating
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&mp.rangeMigrating, 0)
+		resp := &proto.MigrateMetaRangeResponse{
+			PartitionID:    mp.config.PartitionId,
+			VolName:        req.VolName,
+			SrcPartitionID: req.SrcPartitionID,
+			Start:          req.Start,
+			End:            req.End,
+			Status:         proto.TaskSucceeds,
+		}
+		start := time.Now()
+		if err := mp.migrateMetaRange(req, resp); err != nil {
+			log.LogErrorf("[MigrateMetaRange] mp(%v) migrate range(%v-%v) from mp(%v) err(%v)",
+				mp.config.PartitionId, req.Start, req.End, req.SrcPartitionID, err)
+			resp.Status = proto.TaskFailed
+			resp.Result = err.Error()
+		} else {
+			log.LogInfof("[MigrateMetaRange] mp(%v) migrated range(%v-%v) from mp(%v) inodes(%v) dentries(%v) cost(%v)",
+				mp.config.PartitionId, req.Start, req.End, req.SrcPartitionID, resp.InodeCount, resp.DentryCount, time.Since(start))
+		}
+		done(resp)
+	}()
+	return
+}
+
+func (mp *metaPartition) migrateMetaRange(req *proto.MigrateMetaRangeRequest, resp *proto.MigrateMetaRangeResponse) (err error) {
+	for _, digest := range []bool{false, true} {
+		for _, tree := range metaRangeTrees {
+			if err = mp.copyMetaRange(req, tree, digest); err != nil {
+				return
+			}
+		}
+	}
+
+	freeze := &proto.FreezeMetaRangeRequest{
+		VolName:     req.VolName,
+		PartitionID: req.SrcPartitionID,
+		Start:       req.Start,
+		End:         req.End,
+	}
+	if _, err = mp.sendToMetaRangeSource(req.SrcAddrs, proto.OpMetaFreezeRange, freeze); err != nil {
+		return
+	}
+	for _, tree := range metaRangeTrees {
+		if err = mp.copyMetaRange(req, tree, true); err != nil {
+			return
+		}
+	}
+
+	err = mp.rangeMetaItems(proto.MetaRangeInodeTree, metaRangeKey(req.Start), req.End, func(_, _ []byte) (bool, error) {
+		resp.InodeCount++
+		return true, nil
+	})
+	if err != nil {
+		return
+	}
+	return mp.rangeMetaItems(proto.MetaRangeDentryTree, metaRangeKey(req.Start), req.End, func(_, _ []byte) (bool, error) {
+		resp.DentryCount++
+		return true, nil
+	})
+}
+
+// copyMetaRange syncs the items of the tree in the range with the source partition batch by batch. Only the
+// keys and the crc of the items are read in the digest mode, and the items differing from the local ones
+// are read then.
+func (mp *metaPartition) copyMetaRange(req *proto.MigrateMetaRangeRequest, tree uint8, digest bool) (err error) {
+	limit := metaRangeReadLimit
+	if digest {
+		limit = metaRangeDigestLimit
+	}
+	marker := metaRangeKey(req.Start)
+	for len(marker) > 0 {
+		if _, isLeader := mp.IsLeader(); !isLeader {
+			return fmt.Errorf("mp(%v) is not the leader", mp.config.PartitionId)
+		}
+		readReq := &proto.ReadMetaRangeRequest{
+			VolName:     req.VolName,
+			PartitionID: req.SrcPartitionID,
+			Tree:        tree,
+			Start:       req.Start,
+			End:         req.End,
+			Marker:      marker,
+			Digest:      digest,
+			Limit:       limit,
+		}
+		var resp *proto.ReadMetaRangeResponse
+		if resp, err = mp.readMetaRange(req.SrcAddrs, readReq); err != nil {
+			return
+		}
+
+		local := make(map[string][]byte)
+		err = mp.rangeMetaItems(tree, marker, req.End, func(key, item []byte) (bool, error) {
+			if len(resp.Next) > 0 && bytes.Compare(key, resp.Next) >= 0 {
+				return false, nil
+			}
+			local[string(key)] = item
+			return true, nil
+		})
+		if err != nil {
+			return
+		}
+
+		batch := &metaRangeBatch{Tree: tree, Cursor: resp.Cursor}
+		if digest {
+			var changed [][]byte
+			for idx, key := range resp.Keys {
+				item, ok := local[string(key)]
+				delete(local, string(key))
+				if !ok || crc32.ChecksumIEEE(item) != resp.Crcs[idx] {
+					changed = append(changed, key)
+				}
+			}
+			if len(changed) > 0 {
+				readReq.Keys = changed
+				var items *proto.ReadMetaRangeResponse
+				if items, err = mp.readMetaRange(req.SrcAddrs, readReq); err != nil {
+					return
+				}
+				read := make(map[string]struct{}, len(items.Keys))
+				for _, key := range items.Keys {
+					read[string(key)] = struct{}{}
+				}
+				batch.Items = items.Items
+				// the items deleted since the digests are read
+				for _, key := range changed {
+					if _, ok := read[string(key)]; !ok {
+						batch.Deleted = append(batch.Deleted, key)
+					}
+				}
+			}
+		} else {
+			for idx, key := range resp.Keys {
+				if item, ok := local[string(key)]; !ok || !bytes.Equal(item, resp.Items[idx]) {
+					batch.Items = append(batch.Items, resp.Items[idx])
+				}
+				delete(local, string(key))
+			}
+		}
+		for key := range local {
+			batch.Deleted = append(batch.Deleted, []byte(key))
+		}
+
+		if len(batch.Items) > 0 || len(batch.Deleted) > 0 || batch.Cursor > mp.GetCursor() {
+			if err = mp.syncMetaRange(batch); err != nil {
+				return
+			}
+		}
+		marker = resp.Next
+	}
+	return
+}
+
+func (mp *metaPartition) syncMetaRange(batch *metaRangeBatch) (err error) {
+	val, err := json.Marshal(batch)
+	if err != nil {
+		return
+	}
+	resp, err := mp.submit(opFSMSyncMetaRange, val)
+	if err != nil {
+		return
+	}
+	if status := resp.(uint8); status != proto.OpOk {
+		return fmt.Errorf("sync meta range status %v", status)
+	}
+	return
+}
+
+func (mp *metaPartition) readMetaRange(addrs []string, req *proto.ReadMetaRangeRequest) (resp *proto.ReadMetaRangeResponse, err error) {
+	data, err := mp.sendToMetaRangeSource(addrs, proto.OpMetaReadRange, req)
+	if err != nil {
+		return
+	}
+	resp = &proto.ReadMetaRangeResponse{}
+	err = json.Unmarshal(data, resp)
+	return
+}
+
+// sendToMetaRangeSource sends the request to the replicas of the source partition in turn, the request is
+// proxied to the leader by the replica.
+func (mp *metaPartition) sendToMetaRangeSource(addrs []string, opcode uint8, req interface{}) (data []byte, err error) {
+	p := NewPacketToMetaRange(opcode, req)
+	if p == nil {
+		return nil, fmt.Errorf("marshal request %v", req)
+	}
+	for _, addr := range addrs {
+		if data, err = mp.sendMetaRangePacket(addr, p); err == nil {
+			return
+		}
+		log.LogWarnf("[sendToMetaRangeSource] mp(%v) send %v to %v err(%v)", mp.config.PartitionId, p.GetOpMsg(), addr, err)
+	}
+	if err == nil {
+		err = fmt.Errorf("no replica of the source partition")
+	}
+	return
+}
+
+func (mp *metaPartition) sendMetaRangePacket(addr string, p *Packet) (data []byte, err error) {
+	conn, err := mp.config.ConnPool.GetConnect(addr)
+	if err != nil {
+		return
+	}
+	defer func() {
+		mp.config.ConnPool.PutConnect(conn, err != nil)
+	}()
+	if err = p.WriteToConn(conn); err != nil {
+		return
+	}
+	resp := &Packet{}
+	if err = resp.ReadFromConnWithVer(conn, proto.ReadDeadlineTime); err != nil {
+		return
+	}
+	if resp.ResultCode != proto.OpOk {
+		return nil, fmt.Errorf("%v", resp.GetResultMsg())
+	}
+	return resp.Data[:resp.Size], nil
+}
+
+// CutoverMetaRange switches the partition to the new range, or unfreezes the range if the migration is aborted.
+func (mp *metaPartition) CutoverMetaRange(req *proto.CutoverMetaRangeRequest, p *Packet) (err error) {
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMCutoverMetaRange, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+// startDropMovedRanges drops the items of the moved ranges left in the source partition periodically.
+func (mp *metaPartition) startDropMovedRanges() {
+	go func() {
+		timer := time.NewTicker(dropMovedRangesInterval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-mp.stopC:
+				return
+			case <-timer.C:
+				if _, isLeader := mp.IsLeader(); !isLeader {
+					continue
+				}
+				state := mp.getRangeState()
+				if state == nil {
+					continue
+				}
+				for _, moved := range state.moved {
+					if err := mp.dropMovedRange(moved); err != nil {
+						log.LogWarnf("[startDropMovedRanges] mp(%v) drop moved range(%v-%v) err(%v)",
+							mp.config.PartitionId, moved.Start, moved.End, err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+func (mp *metaPartition) dropMovedRange(moved *proto.MovedMetaRange) (err error) {
+	for _, tree := range metaRangeTrees {
+		for {
+			batch := &metaRangeBatch{Tree: tree}
+			err = mp.rangeMetaItems(tree, metaRangeKey(moved.Start), moved.End, func(key, _ []byte) (bool, error) {
+				batch.Deleted = append(batch.Deleted, key)
+				return len(batch.Deleted) < metaRangeReadLimit, nil
+			})
+			if err != nil || len(batch.Deleted) == 0 {
+				break
+			}
+			if err = mp.syncMetaRange(batch); err != nil {
+				return
+			}
+			log.LogInfof("[dropMovedRange] mp(%v) drop %v items of tree(%v) in the range(%v-%v) moved to mp(%v)",
+				mp.config.PartitionId, len(batch.Deleted), tree, moved.Start, moved.End, moved.PartitionID)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// inheritPosixACL applies the default ACL of the parent directory to the inode to be
+// created. The permission bits of the inode are masked by the default ACL, and the
+// extend keeping the inherited ACLs is returned to be stored once the inode is created.
+func (mp *metaPartition) inheritPosixACL(ino *Inode, defaultACL []byte) (extend *Extend, err error) {
+	if len(defaultACL) == 0 {
+		return
+	}
+	dflt, err := proto.UnmarshalPosixACL(defaultACL)
+	if err != nil {
+		log.LogWarnf("inheritPosixACL: mp(%v) ino(%v) invalid default acl, err(%v)", mp.config.PartitionId, ino.Inode, err)
+		return
+	}
+	access, inherited, perm := proto.InheritPosixACL(dflt, ino.Type, proto.IsDir(ino.Type))
+	ino.Type = ino.Type&^0o777 | perm
+	if access == nil && inherited == nil {
+		return
+	}
+	extend = NewExtend(ino.Inode)
+	var data []byte
+	if access != nil {
+		if data, err = access.Marshal(); err != nil {
+			return nil, err
+		}
+		extend.Put([]byte(proto.XAttrPosixACLAccess), data, mp.verSeq)
+	}
+	if inherited != nil {
+		if data, err = inherited.Marshal(); err != nil {
+			return nil, err
+		}
+		extend.Put([]byte(proto.XAttrPosixACLDefault), data, mp.verSeq)
+	}
+	return
+}
+
+// checkPosixACLXAttr rejects the malformed ACLs set by the clients, so that all the
+// ACLs kept in the xattrs can be parsed by the permission checks.
+func checkPosixACLXAttr(key string, value []byte) error {
+	if !proto.IsPosixACLXAttr(key) {
+		return nil
+	}
+	_, err := proto.UnmarshalPosixACL(value)
+	return err
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+	"github.com/cubefs/cubefs/util/timeutil"
+)
+
+// getExtendOfVersion returns a copy of the extended attributes of the inode visible to the version for the
+// inode cloned. The quota of the clone is set by the directory it is linked to, so it is not copied.
+func (mp *metaPartition) getExtendOfVersion(ino, verSeq, newIno uint64) (extend *Extend) {
+	src, err := mp.extendTree.RefGet(ino)
+	if err != nil || src == nil {
+		return
+	}
+	if verSeq > 0 && src.verSeq > verSeq {
+		if ver := src.GetExtentByVersion(verSeq); ver != nil {
+			src = ver
+		}
+	}
+	extend = NewExtend(newIno)
+	src.Range(func(key, value []byte) bool {
+		if string(key) != proto.QuotaKey {
+			extend.Put(key, value, mp.verSeq)
+		}
+		return true
+	})
+	if len(extend.dataMap) == 0 {
+		return nil
+	}
+	return
+}
+
+// CloneSnapshotInode creates a writable inode from the version of the inode without copying data, the
+// client links it to the tree cloned.
+func (mp *metaPartition) CloneSnapshotInode(req *proto.CloneSnapshotInodeRequest, p *Packet) (err error) {
+	if !proto.IsHot(mp.volType) {
+		err = fmt.Errorf("clone snapshot inode is not supported by mp(%v)", mp.config.PartitionId)
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+		return
+	}
+	if req.VerSeq > mp.verSeq && !isInitSnapVer(req.VerSeq) {
+		err = fmt.Errorf("version %v is newer than the current version %v", req.VerSeq, mp.verSeq)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	inoID, err := mp.nextInodeID()
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpInodeFullErr, []byte(err.Error()))
+		return
+	}
+
+	req.NewInode = inoID
+	req.CreateTime = timeutil.GetCurrentTimeUnix()
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	r, err := mp.submit(opFSMCloneSnapshotInode, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	resp := r.(*InodeResponse)
+	if resp.Status != proto.OpOk {
+		log.LogWarnf("CloneSnapshotInode: mp(%v) req(%v) status(%v)", mp.config.PartitionId, req, resp.Status)
+		p.PacketErrorWithBody(resp.Status, nil)
+		return
+	}
+
+	// the ACLs and the key of the encrypted file go with the data
+	if extend := mp.getExtendOfVersion(req.Inode, req.VerSeq, inoID); extend != nil {
+		if _, err = mp.putExtend(opFSMSetXAttr, extend); err != nil {
+			p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+			return
+		}
+	}
+
+	reply := &proto.CloneSnapshotInodeResponse{Info: &proto.InodeInfo{}}
+	if !replyInfo(reply.Info, resp.Msg, make(map[uint32]*proto.MetaQuotaInfo, 0)) {
+		p.PacketErrorWithBody(proto.OpNotExistErr, nil)
+		return
+	}
+	data, err := json.Marshal(reply)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(data)
+	return
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const (
+	defaultSnapshotDiffLimit = 10000
+	maxSnapshotDiffLimit     = 100000
+)
+
+// isInodeModified checks whether the inode is changed between the versions. The directories are
+// changed by the dentries created and deleted, so they are never modified.
+func isInodeModified(top *Inode, fromVer, toVer uint64) bool {
+	old, _ := top.getInoByVer(fromVer, false)
+	cur, _ := top.getInoByVer(toVer, false)
+	if old == nil || cur == nil || old == cur || old.ShouldDelete() || cur.ShouldDelete() {
+		return false
+	}
+	if proto.IsDir(cur.Type) {
+		return false
+	}
+	return old.Generation != cur.Generation || old.ModifyTime != cur.ModifyTime || old.Size != cur.Size ||
+		old.Type != cur.Type || old.Uid != cur.Uid || old.Gid != cur.Gid
+}
+
+func (mp *metaPartition) diffInodes(req *proto.SnapshotDiffRequest, limit uint64) (resp *proto.SnapshotDiffResponse, err error) {
+	resp = &proto.SnapshotDiffResponse{}
+	var count uint64
+	err = mp.inodeTree.Range(NewInode(req.Start, 0), nil, func(i *Inode) (bool, error) {
+		if count >= limit {
+			resp.Next = i.Inode
+			return false, nil
+		}
+		count++
+		if isInodeModified(i, req.FromVer, req.ToVer) {
+			resp.Modified = append(resp.Modified, i.Inode)
+		}
+		return true, nil
+	})
+	return
+}
+
+func newSnapshotDiffEntry(op uint8, d *Dentry) proto.SnapshotDiffEntry {
+	return proto.SnapshotDiffEntry{
+		Op:       op,
+		ParentId: d.ParentId,
+		Name:     d.Name,
+		Inode:    d.Inode,
+		Type:     d.Type,
+	}
+}
+
+// diffDentries compares the dentries of the versions. A dentry pointing to another inode is deleted
+// and created again, and the client pairs the dentries of an inode deleted and created as renamed.
+func (mp *metaPartition) diffDentries(req *proto.SnapshotDiffRequest, limit uint64) (resp *proto.SnapshotDiffResponse, err error) {
+	resp = &proto.SnapshotDiffResponse{}
+	var (
+		count  uint64
+		parent uint64
+	)
+	err = mp.dentryTree.Range(&Dentry{ParentId: req.Start}, nil, func(d *Dentry) (bool, error) {
+		if count >= limit && d.ParentId != parent {
+			resp.Next = d.ParentId
+			return false, nil
+		}
+		count++
+		parent = d.ParentId
+
+		old := mp.getDentryByVerSeq(d, req.FromVer)
+		cur := mp.getDentryByVerSeq(d, req.ToVer)
+		if req.Scan == proto.SnapshotDiffScanLookup {
+			if cur != nil && old != nil && old.Inode == cur.Inode {
+				idx := sort.Search(len(req.Inodes), func(i int) bool { return req.Inodes[i] >= cur.Inode })
+				if idx < len(req.Inodes) && req.Inodes[idx] == cur.Inode {
+					resp.Entries = append(resp.Entries, newSnapshotDiffEntry(proto.SnapshotDiffModified, cur))
+				}
+			}
+			return true, nil
+		}
+
+		switch {
+		case old != nil && cur != nil && old.Inode == cur.Inode:
+			if proto.IsDir(cur.Type) {
+				resp.Entries = append(resp.Entries, newSnapshotDiffEntry(proto.SnapshotDiffDir, cur))
+			}
+		default:
+			if old != nil {
+				resp.Entries = append(resp.Entries, newSnapshotDiffEntry(proto.SnapshotDiffDeleted, old))
+			}
+			if cur != nil {
+				resp.Entries = append(resp.Entries, newSnapshotDiffEntry(proto.SnapshotDiffCreated, cur))
+			}
+		}
+		return true, nil
+	})
+	return
+}
+
+// SnapshotDiff scans a page of the inodes or the dentries changed between the versions.
+func (mp *metaPartition) SnapshotDiff(req *proto.SnapshotDiffRequest, p *Packet) (err error) {
+	if req.FromVer == 0 || (req.ToVer != 0 && !isInitSnapVer(req.FromVer) && req.ToVer <= req.FromVer) {
+		err = fmt.Errorf("invalid versions from %v to %v", req.FromVer, req.ToVer)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultSnapshotDiffLimit
+	} else if limit > maxSnapshotDiffLimit {
+		limit = maxSnapshotDiffLimit
+	}
+
+	var resp *proto.SnapshotDiffResponse
+	switch req.Scan {
+	case proto.SnapshotDiffScanInode:
+		resp, err = mp.diffInodes(req, limit)
+	case proto.SnapshotDiffScanDentry, proto.SnapshotDiffScanLookup:
+		resp, err = mp.diffDentries(req, limit)
+	default:
+		err = fmt.Errorf("unknown scan %v", req.Scan)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	if err != nil {
+		log.LogErrorf("SnapshotDiff: mp(%v) req(%v) err(%v)", mp.config.PartitionId, req, err)
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cubefs/cubefs/proto"
+)
+
+// checkTransitionObjExtents checks whether the object extents cover the file from the start one by one.
+func checkTransitionObjExtents(oeks []proto.ObjExtentKey) error {
+	if len(oeks) == 0 {
+		return fmt.Errorf("no obj extents")
+	}
+	var offset uint64
+	for _, oek := range oeks {
+		if oek.FileOffset != offset || oek.Size == 0 {
+			return fmt.Errorf("obj extent %v is not continuous from offset %v", oek.String(), offset)
+		}
+		offset += oek.Size
+	}
+	return nil
+}
+
+// TransitionExtents replaces the extents of the inode with the object extents the data is copied to in the
+// blobstore, the partition frees the object extents later, so the blobstore client must be available.
+func (mp *metaPartition) TransitionExtents(req *proto.TransitionExtentsRequest, p *Packet) (err error) {
+	if !proto.IsHot(mp.volType) || mp.ebsClient == nil {
+		err = fmt.Errorf("transition extents is not supported by mp(%v)", mp.config.PartitionId)
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+		return
+	}
+	if err = checkTransitionObjExtents(req.ObjExtents); err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMTransitionExtents, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+// RestoreExtents drops the object extents of the inode once the data of them is written back to the extents.
+func (mp *metaPartition) RestoreExtents(req *proto.RestoreExtentsRequest, p *Packet) (err error) {
+	if !proto.IsHot(mp.volType) {
+		err = fmt.Errorf("restore extents is not supported by mp(%v)", mp.config.PartitionId)
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(err.Error()))
+		return
+	}
+
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMRestoreExtents, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
@@ -63,6 +63,8 @@ const (
 	deletedExtentsIdFile    = "deletedExtentsId"
 	deletedExtentsFile      = "deletedExtents"
 	deletedObjExtentsFile   = "deletedObjExtents"
+	sharedExtentsFile       = "sharedExtents"
+	fingerprintFile         = "fingerprint"
 )
 
 func (mp *metaPartition) loadMetadataFromFile() (mConf *MetaPartitionConfig, err error) {
@@ -114,6 +116,9 @@ func (mp *metaPartition) loadMetadata() (err error) {
 	mp.config.RocksLogReversedTime = mConf.RocksLogReversedTime
 	mp.config.RocksLogReVersedCnt = mConf.RocksLogReVersedCnt
 	mp.config.RocksWalTTL = mConf.RocksWalTTL
+	mp.config.FrozenRange = mConf.FrozenRange
+	mp.config.MovedRanges = mConf.MovedRanges
+	mp.updateRangeState()
 
 	if mp.config.StoreMode < proto.StoreModeMem || mp.config.StoreMode > proto.StoreModeRocksDb {
 		mp.config.StoreMode = proto.StoreModeMem
@@ -866,6 +871,41 @@ func (mp *metaPartition) loadUniqChecker(rootDir string, crc uint32) (err error)
 	return
 }
 
+func (mp *metaPartition) loadSharedExtents(rootDir string, crc uint32) (err error) {
+	log.LogInfof("loadSharedExtents partition(%v) begin", mp.config.PartitionId)
+	filename := path.Join(rootDir, sharedExtentsFile)
+	if _, err = os.Stat(filename); err != nil {
+		log.LogErrorf("loadSharedExtents get file %s err(%s)", filename, err)
+		err = nil
+		return
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		log.LogErrorf("loadSharedExtents read file %s err(%s)", filename, err)
+		err = errors.NewErrorf("[loadSharedExtents] OpenFile: %v", err.Error())
+		return
+	}
+	if err = mp.sharedExtents.UnMarshal(data); err != nil {
+		log.LogErrorf("loadSharedExtents UnMarshal err(%s)", err)
+		err = errors.NewErrorf("[loadSharedExtents] Unmarshal: %v", err.Error())
+		return
+	}
+
+	crcCheck := crc32.NewIEEE()
+	if _, err = crcCheck.Write(data); err != nil {
+		log.LogErrorf("loadSharedExtents write to  crcCheck failed: %s", err)
+		return err
+	}
+	if res := crcCheck.Sum32(); res != crc {
+		log.LogErrorf("[loadSharedExtents]: check crc mismatch, expected[%d], actual[%d]", crc, res)
+		return ErrSnapshotCrcMismatch
+	}
+
+	log.LogInfof("loadSharedExtents partition(%v) complete, extents(%v)", mp.config.PartitionId, mp.sharedExtents.len())
+	return
+}
+
 func (mp *metaPartition) loadMultiVer(rootDir string, crc uint32) (err error) {
 	filename := path.Join(rootDir, verdataFile)
 	if _, err = os.Stat(filename); err != nil {
@@ -1504,6 +1544,32 @@ func (mp *metaPartition) storeUniqChecker(rootDir string, sm *storeMsg) (crc uin
 	return
 }
 
+func (mp *metaPartition) storeSharedExtents(rootDir string, sm *storeMsg) (crc uint32, err error) {
+	filename := path.Join(rootDir, sharedExtentsFile)
+	fp, err := os.OpenFile(filename, os.O_RDWR|os.O_TRUNC|os.O_APPEND|os.
+		O_CREATE, 0o755)
+	if err != nil {
+		return
+	}
+	defer func() {
+		err = fp.Sync()
+		fp.Close()
+	}()
+
+	var data []byte
+	if data, crc, err = sm.sharedExtents.Marshal(); err != nil {
+		return
+	}
+
+	if _, err = fp.Write(data); err != nil {
+		return
+	}
+
+	log.LogInfof("storeSharedExtents: store complete: PartitionID(%v) volume(%v) crc(%v)",
+		mp.config.PartitionId, mp.config.VolName, crc)
+	return
+}
+
 func (mp *metaPartition) loadDeletedExtentId(rootDir string) (err error) {
 	if mp.HasMemStore() {
 		if rootDir == "" {
@@ -1777,3 +1843,114 @@ func (mp *metaPartition) storeDeletedObjExtents(rootDir string, sm *storeMsg) (c
 	crc = sign.Sum32()
 	return
 }
+
+func (mp *metaPartition) loadFingerprint(rootDir string, crc uint32) (err error) {
+	handler, _ := mp.fingerprintTree.CreateBatchWriteHandle()
+	defer func() {
+		_ = mp.fingerprintTree.CommitAndReleaseBatchWriteHandle(handler, false)
+	}()
+	filename := path.Join(rootDir, fingerprintFile)
+	if _, err = os.Stat(filename); err != nil {
+		err = errors.NewErrorf("[loadFingerprint] Stat: %s", err.Error())
+		return
+	}
+	fp, err := os.OpenFile(filename, os.O_RDONLY, 0o644)
+	if err != nil {
+		err = errors.NewErrorf("[loadFingerprint] OpenFile: %s", err.Error())
+		return
+	}
+	defer fp.Close()
+	reader := bufio.NewReaderSize(fp, 4*1024*1024)
+	lenBuf := make([]byte, 4)
+	buff := make([]byte, 0)
+	crcCheck := crc32.NewIEEE()
+	for {
+		// first read length
+		_, err = io.ReadFull(reader, lenBuf)
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+				if res := crcCheck.Sum32(); res != crc {
+					log.LogErrorf("[loadFingerprint]: check crc mismatch, expected[%d], actual[%d]", crc, res)
+					err = ErrSnapshotCrcMismatch
+				}
+				return
+			}
+			err = errors.NewErrorf("[loadFingerprint] ReadHeader: %s", err.Error())
+			return
+		}
+		// length crc
+		if _, err = crcCheck.Write(lenBuf); err != nil {
+			return err
+		}
+
+		length := binary.BigEndian.Uint32(lenBuf)
+
+		// next read body
+		if len(buff) < int(length) {
+			buff = make([]byte, length)
+		}
+		_, err = io.ReadFull(reader, buff)
+		if err != nil {
+			err = errors.NewErrorf("[loadFingerprint] ReadBody: %s ", err.Error())
+			return
+		}
+		if _, err = crcCheck.Write(buff[:length]); err != nil {
+			return
+		}
+		fprint := &Fingerprint{}
+		err = fprint.Unmarshal(buff[:length])
+		if err != nil {
+			err = errors.NewErrorf("[loadFingerprint] Unmarshal: %s", err.Error())
+			return
+		}
+		err = mp.fingerprintTree.Put(handler, fprint)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (mp *metaPartition) storeFingerprint(rootDir string, sm *storeMsg) (crc uint32, err error) {
+	filename := path.Join(rootDir, fingerprintFile)
+	fp, err := os.OpenFile(filename, os.O_RDWR|os.O_TRUNC|os.O_APPEND|os.
+		O_CREATE, 0o755)
+	if err != nil {
+		return
+	}
+	defer func() {
+		err = fp.Sync()
+		// TODO Unhandled errors
+		fp.Close()
+	}()
+	var data []byte
+	lenBuf := make([]byte, 4)
+	sign := crc32.NewIEEE()
+	sm.snap.Range(FingerprintType, func(item interface{}) (bool, error) {
+		fprint := item.(*Fingerprint)
+
+		if data, err = fprint.Marshal(); err != nil {
+			return false, nil
+		}
+
+		// set length
+		binary.BigEndian.PutUint32(lenBuf, uint32(len(data)))
+		if _, err = fp.Write(lenBuf); err != nil {
+			return false, nil
+		}
+		if _, err = sign.Write(lenBuf); err != nil {
+			return false, nil
+		}
+		// set body
+		if _, err = fp.Write(data); err != nil {
+			return false, nil
+		}
+		if _, err = sign.Write(data); err != nil {
+			return false, nil
+		}
+		return true, nil
+	})
+	log.LogDebugf("[storeFingerprint] store fingerprint count(%v)", sm.snap.Count(FingerprintType))
+	crc = sign.Sum32()
+	return
+}
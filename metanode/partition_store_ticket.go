@@ -27,13 +27,14 @@ import (
 )
 
 type storeMsg struct {
-	command      uint32
-	snap         Snapshot
-	quotaRebuild bool
-	uidRebuild   bool
-	uniqId       uint64
-	uniqChecker  *uniqChecker
-	multiVerList []*proto.VolVersionInfo
+	command       uint32
+	snap          Snapshot
+	quotaRebuild  bool
+	uidRebuild    bool
+	uniqId        uint64
+	uniqChecker   *uniqChecker
+	sharedExtents *sharedExtents
+	multiVerList  []*proto.VolVersionInfo
 }
 
 func (mp *metaPartition) startSchedule(curIndex uint64) {
@@ -60,10 +60,11 @@ func TestMetaPartition_LoadSnapshot(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, snap)
 	msg := &storeMsg{
-		command:     1,
-		snap:        snap,
-		uniqId:      mp.GetUniqId(),
-		uniqChecker: mp.uniqChecker,
+		command:       1,
+		snap:          snap,
+		uniqId:        mp.GetUniqId(),
+		uniqChecker:   mp.uniqChecker,
+		sharedExtents: mp.sharedExtents,
 	}
 	mp.uidManager = NewUidMgr(mpC.VolName, mpC.PartitionId)
 	mp.mqMgr = NewQuotaManager(mpC.VolName, mpC.PartitionId)
@@ -116,10 +117,11 @@ func TestMetaPartition_LoadSnapshot(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, snap)
 	msg = &storeMsg{
-		command:     1,
-		snap:        snap,
-		uniqId:      mp.GetUniqId(),
-		uniqChecker: mp.uniqChecker,
+		command:       1,
+		snap:          snap,
+		uniqId:        mp.GetUniqId(),
+		uniqChecker:   mp.uniqChecker,
+		sharedExtents: mp.sharedExtents,
 	}
 	err = mp.store(msg)
 	snap.Close()
@@ -341,10 +343,11 @@ func TestLoadAndStoreMetaPartition(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, snap)
 	msg := &storeMsg{
-		command:     1,
-		snap:        snap,
-		uniqId:      mp.GetUniqId(),
-		uniqChecker: mp.uniqChecker,
+		command:       1,
+		snap:          snap,
+		uniqId:        mp.GetUniqId(),
+		uniqChecker:   mp.uniqChecker,
+		sharedExtents: mp.sharedExtents,
 	}
 	err = mp.store(msg)
 	snap.Close()
@@ -64,6 +64,8 @@ const (
 	TransactionRollbackDentryTable
 	DeletedExtentsTable
 	DeletedObjExtentsTable
+	FingerprintTable
+	SharedExtentsTable
 	MaxTable
 )
 
@@ -91,6 +93,8 @@ func getTableTypeKey(treeType TreeType) TableType {
 		return DeletedExtentsTable
 	case DeletedObjExtentsType:
 		return DeletedObjExtentsTable
+	case FingerprintType:
+		return FingerprintTable
 	default:
 	}
 	panic(ErrInvalidRocksdbTableType)
@@ -0,0 +1,618 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/storage"
+	"github.com/cubefs/cubefs/util/errors"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const sharedExtentsVersion = 2
+
+// sharedExtents records the extents whose extent keys are cloned to other inodes
+// by copy_file_range. An extent in the table is shared by the recorded inodes, the
+// range of it is not freed while any of the inodes still refers to the range, and
+// the whole extent is freed once none of the inodes refers to it. The table is
+// updated by raft only, so that it is the same on all the replicas.
+//
+// The chunks of the extents deduplicated are recorded as well, with all the inodes
+// of the partition ever referring to them through the fingerprint partitions. The
+// chunks indexed are freed by the fingerprint partitions rather than here, and the
+// extents deduplicated are never freed as a whole. An extent deduplicated is kept
+// in the table until none of the inodes recorded refers to it, so that a key of it
+// freed again is still checked against the chunks.
+type sharedExtents struct {
+	sync.RWMutex
+	extents map[uint64][]uint64      // extent id (dp<<32|extent) -> inodes
+	chunks  map[uint64][]*dedupChunk // extent id (dp<<32|extent) -> chunks deduplicated
+	freed   map[uint64]struct{}      // extents deduplicated freed on the leader, not persisted
+}
+
+// dedupChunk is a chunk of the extent indexed by the fingerprint partition Shard, or
+// not indexed at all if the fingerprint partition refused it.
+type dedupChunk struct {
+	Hash         [proto.FingerprintSize]byte
+	ExtentOffset uint64
+	Size         uint32
+	Shard        uint64
+	Refs         []dedupRef
+}
+
+// dedupRef is a reference of the inode to the chunk, with the token it is referred by in the fingerprint
+// partition.
+type dedupRef struct {
+	Inode uint64
+	Token uint64
+}
+
+// inodes returns the inodes referring to the chunk.
+func (c *dedupChunk) inodes() []uint64 {
+	inodes := make([]uint64, 0, len(c.Refs))
+	for _, ref := range c.Refs {
+		inodes = appendInodes(inodes, ref.Inode)
+	}
+	return inodes
+}
+
+// tokens returns the tokens the chunk is referred by.
+func (c *dedupChunk) tokens() []uint64 {
+	tokens := make([]uint64, 0, len(c.Refs))
+	for _, ref := range c.Refs {
+		tokens = append(tokens, ref.Token)
+	}
+	return tokens
+}
+
+func (c *dedupChunk) overlaps(ek *proto.ExtentKey) bool {
+	return c.ExtentOffset < ek.ExtentOffset+uint64(ek.Size) && ek.ExtentOffset < c.ExtentOffset+uint64(c.Size)
+}
+
+// dedupExtent returns the data of the chunk of the extent.
+func (c *dedupChunk) dedupExtent(id uint64) *proto.DedupExtent {
+	return &proto.DedupExtent{
+		Fingerprint:  append([]byte(nil), c.Hash[:]...),
+		PartitionId:  id >> 32,
+		ExtentId:     id & 0xFFFFFFFF,
+		ExtentOffset: c.ExtentOffset,
+		Size:         c.Size,
+	}
+}
+
+func (c *dedupChunk) copy() *dedupChunk {
+	other := *c
+	other.Refs = append([]dedupRef(nil), c.Refs...)
+	return &other
+}
+
+func newSharedExtents() *sharedExtents {
+	return &sharedExtents{
+		extents: make(map[uint64][]uint64),
+		chunks:  make(map[uint64][]*dedupChunk),
+		freed:   make(map[uint64]struct{}),
+	}
+}
+
+func (se *sharedExtents) clone() *sharedExtents {
+	se.RLock()
+	defer se.RUnlock()
+	extents := make(map[uint64][]uint64, len(se.extents))
+	for id, inodes := range se.extents {
+		extents[id] = append([]uint64(nil), inodes...)
+	}
+	chunks := make(map[uint64][]*dedupChunk, len(se.chunks))
+	for id, list := range se.chunks {
+		copied := make([]*dedupChunk, 0, len(list))
+		for _, c := range list {
+			copied = append(copied, c.copy())
+		}
+		chunks[id] = copied
+	}
+	return &sharedExtents{extents: extents, chunks: chunks, freed: make(map[uint64]struct{})}
+}
+
+func (se *sharedExtents) len() int {
+	se.RLock()
+	defer se.RUnlock()
+	return len(se.idsLocked())
+}
+
+func (se *sharedExtents) has(id uint64) bool {
+	se.RLock()
+	defer se.RUnlock()
+	_, ok := se.extents[id]
+	if !ok {
+		_, ok = se.chunks[id]
+	}
+	return ok
+}
+
+// get returns the inodes which share the extent, or nil if the extent is not shared.
+func (se *sharedExtents) get(id uint64) []uint64 {
+	se.RLock()
+	defer se.RUnlock()
+	return append([]uint64(nil), se.extents[id]...)
+}
+
+// add records that the inodes share the extent.
+func (se *sharedExtents) add(id uint64, inodes ...uint64) {
+	se.Lock()
+	defer se.Unlock()
+	se.extents[id] = appendInodes(se.extents[id], inodes...)
+}
+
+func (se *sharedExtents) set(id uint64, inodes []uint64) {
+	se.Lock()
+	defer se.Unlock()
+	if len(inodes) == 0 {
+		delete(se.extents, id)
+		return
+	}
+	se.extents[id] = inodes
+}
+
+// isDedup returns true if the extent has been deduplicated, the chunks of it may be referred by the
+// inodes of the other partitions.
+func (se *sharedExtents) isDedup(id uint64) bool {
+	se.RLock()
+	defer se.RUnlock()
+	_, ok := se.chunks[id]
+	return ok
+}
+
+// getChunks returns the chunks of the extent deduplicated.
+func (se *sharedExtents) getChunks(id uint64) []*dedupChunk {
+	se.RLock()
+	defer se.RUnlock()
+	chunks := make([]*dedupChunk, 0, len(se.chunks[id]))
+	for _, c := range se.chunks[id] {
+		chunks = append(chunks, c.copy())
+	}
+	return chunks
+}
+
+// addChunk records that the inode refers to the chunk of the extent through the fingerprint partition.
+func (se *sharedExtents) addChunk(id uint64, chunk *dedupChunk, ref dedupRef) {
+	se.Lock()
+	defer se.Unlock()
+	for _, c := range se.chunks[id] {
+		if c.ExtentOffset == chunk.ExtentOffset && c.Size == chunk.Size && c.Hash == chunk.Hash && c.Shard == chunk.Shard {
+			for _, r := range c.Refs {
+				if r == ref {
+					return
+				}
+			}
+			c.Refs = append(c.Refs, ref)
+			return
+		}
+	}
+	added := chunk.copy()
+	added.Refs = []dedupRef{ref}
+	se.chunks[id] = append(se.chunks[id], added)
+}
+
+// dropDedup forgets the extent deduplicated once none of the inodes of the partition refers to it.
+func (se *sharedExtents) dropDedup(id uint64) {
+	se.Lock()
+	defer se.Unlock()
+	delete(se.chunks, id)
+	delete(se.freed, id)
+}
+
+// markFreed records the extent deduplicated whose keys are freed on the leader, it is dropped later once
+// none of the inodes refers to it.
+func (se *sharedExtents) markFreed(id uint64) {
+	se.Lock()
+	defer se.Unlock()
+	se.freed[id] = struct{}{}
+}
+
+func (se *sharedExtents) unmarkFreed(id uint64) {
+	se.Lock()
+	defer se.Unlock()
+	delete(se.freed, id)
+}
+
+func (se *sharedExtents) freedIds() []uint64 {
+	se.RLock()
+	defer se.RUnlock()
+	ids := make([]uint64, 0, len(se.freed))
+	for id := range se.freed {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func appendInodes(shared []uint64, inodes ...uint64) []uint64 {
+	for _, ino := range inodes {
+		found := false
+		for _, i := range shared {
+			if i == ino {
+				found = true
+				break
+			}
+		}
+		if !found {
+			shared = append(shared, ino)
+		}
+	}
+	return shared
+}
+
+func (se *sharedExtents) ids() []uint64 {
+	se.RLock()
+	defer se.RUnlock()
+	return se.idsLocked()
+}
+
+func (se *sharedExtents) idsLocked() []uint64 {
+	ids := make([]uint64, 0, len(se.extents)+len(se.chunks))
+	for id := range se.extents {
+		ids = append(ids, id)
+	}
+	for id := range se.chunks {
+		if _, ok := se.extents[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// marshalEntryLocked writes the inodes and the chunks of the extent, a negative count of the chunks means
+// the extent is not deduplicated.
+func (se *sharedExtents) marshalEntryLocked(buffer *bytes.Buffer, id uint64) (err error) {
+	inodes := se.extents[id]
+	if err = binary.Write(buffer, binary.BigEndian, id); err != nil {
+		return
+	}
+	if err = binary.Write(buffer, binary.BigEndian, uint32(len(inodes))); err != nil {
+		return
+	}
+	if err = binary.Write(buffer, binary.BigEndian, inodes); err != nil {
+		return
+	}
+	chunks, ok := se.chunks[id]
+	if !ok {
+		return binary.Write(buffer, binary.BigEndian, int32(-1))
+	}
+	if err = binary.Write(buffer, binary.BigEndian, int32(len(chunks))); err != nil {
+		return
+	}
+	for _, c := range chunks {
+		if _, err = buffer.Write(c.Hash[:]); err != nil {
+			return
+		}
+		if err = binary.Write(buffer, binary.BigEndian, c.ExtentOffset); err != nil {
+			return
+		}
+		if err = binary.Write(buffer, binary.BigEndian, c.Size); err != nil {
+			return
+		}
+		if err = binary.Write(buffer, binary.BigEndian, c.Shard); err != nil {
+			return
+		}
+		if err = binary.Write(buffer, binary.BigEndian, uint32(len(c.Refs))); err != nil {
+			return
+		}
+		if err = binary.Write(buffer, binary.BigEndian, c.Refs); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func readInodes(buff *bytes.Buffer) (inodes []uint64, err error) {
+	var cnt uint32
+	if err = binary.Read(buff, binary.BigEndian, &cnt); err != nil {
+		return
+	}
+	if uint64(cnt)*8 > uint64(buff.Len()) {
+		err = errors.New("invalid sharedExtents inode count")
+		return
+	}
+	inodes = make([]uint64, cnt)
+	err = binary.Read(buff, binary.BigEndian, inodes)
+	return
+}
+
+func (se *sharedExtents) unmarshalEntryLocked(buff *bytes.Buffer, version int32) (err error) {
+	var id uint64
+	if err = binary.Read(buff, binary.BigEndian, &id); err != nil {
+		return
+	}
+	inodes, err := readInodes(buff)
+	if err != nil {
+		return
+	}
+	if len(inodes) > 0 {
+		se.extents[id] = inodes
+	}
+	if version < 2 {
+		return
+	}
+	var cnt int32
+	if err = binary.Read(buff, binary.BigEndian, &cnt); err != nil || cnt < 0 {
+		return
+	}
+	chunks := make([]*dedupChunk, 0, cnt)
+	for i := int32(0); i < cnt; i++ {
+		c := &dedupChunk{}
+		if _, err = io.ReadFull(buff, c.Hash[:]); err != nil {
+			return
+		}
+		if err = binary.Read(buff, binary.BigEndian, &c.ExtentOffset); err != nil {
+			return
+		}
+		if err = binary.Read(buff, binary.BigEndian, &c.Size); err != nil {
+			return
+		}
+		if err = binary.Read(buff, binary.BigEndian, &c.Shard); err != nil {
+			return
+		}
+		var refCnt uint32
+		if err = binary.Read(buff, binary.BigEndian, &refCnt); err != nil {
+			return
+		}
+		if uint64(refCnt)*16 > uint64(buff.Len()) {
+			return errors.New("invalid sharedExtents chunk reference count")
+		}
+		c.Refs = make([]dedupRef, refCnt)
+		if err = binary.Read(buff, binary.BigEndian, c.Refs); err != nil {
+			return
+		}
+		chunks = append(chunks, c)
+	}
+	se.chunks[id] = chunks
+	return
+}
+
+func (se *sharedExtents) Marshal() (buf []byte, crc uint32, err error) {
+	se.RLock()
+	ids := se.idsLocked()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	buffer := bytes.NewBuffer(make([]byte, 0, 4+len(ids)*28))
+	if err = binary.Write(buffer, binary.BigEndian, int32(sharedExtentsVersion)); err != nil {
+		se.RUnlock()
+		return
+	}
+	for _, id := range ids {
+		if err = se.marshalEntryLocked(buffer, id); err != nil {
+			break
+		}
+	}
+	se.RUnlock()
+	if err != nil {
+		return
+	}
+
+	sign := crc32.NewIEEE()
+	if _, err = sign.Write(buffer.Bytes()); err != nil {
+		return
+	}
+	crc = sign.Sum32()
+	buf = buffer.Bytes()
+	return
+}
+
+func (se *sharedExtents) UnMarshal(data []byte) (err error) {
+	if len(data) < 4 {
+		err = errors.New("invalid sharedExtents file length")
+		log.LogErrorf("sharedExtents UnMarshal err(%v)", err)
+		return
+	}
+
+	buff := bytes.NewBuffer(data)
+	var version int32
+	if err = binary.Read(buff, binary.BigEndian, &version); err != nil {
+		log.LogErrorf("sharedExtents unmarshal read version err(%v)", err)
+		return
+	}
+
+	se.Lock()
+	defer se.Unlock()
+	for buff.Len() != 0 {
+		if err = se.unmarshalEntryLocked(buff, version); err != nil {
+			log.LogErrorf("sharedExtents unmarshal version(%v) err(%v)", version, err)
+			return
+		}
+	}
+	return
+}
+
+// checkSharedExtents filters the extent keys to be freed. The range of a shared extent is
+// not freed if any inode still refers to it, and the whole extent is freed once none of
+// the inodes refers to it, except that only the ranges of a tiny extent are freed. The
+// keys of the extents deduplicated are checked by freeDedupExtents.
+func (mp *metaPartition) checkSharedExtents(eks []*proto.ExtentKey) (result []*proto.ExtentKey, err error) {
+	if mp.sharedExtents.len() == 0 {
+		return eks, nil
+	}
+
+	result = make([]*proto.ExtentKey, 0, len(eks))
+	unreferred := make(map[uint64][]*proto.ExtentKey)
+	ids := make([]uint64, 0)
+	dedup := make([]*proto.ExtentKey, 0)
+	for _, ek := range eks {
+		id := ek.GenerateId()
+		if mp.sharedExtents.isDedup(id) {
+			dedup = append(dedup, ek)
+			continue
+		}
+		inodes := mp.sharedExtents.get(id)
+		if len(inodes) == 0 {
+			result = append(result, ek)
+			continue
+		}
+		referred, overlapped := mp.isSharedExtentReferred(inodes, ek)
+		if overlapped {
+			log.LogDebugf("[checkSharedExtents] mp(%v) skip ek(%v) referred by inodes(%v)", mp.config.PartitionId, ek, inodes)
+			continue
+		}
+		if referred {
+			result = append(result, ek)
+			continue
+		}
+		if _, ok := unreferred[id]; !ok {
+			ids = append(ids, id)
+		}
+		unreferred[id] = append(unreferred[id], ek)
+	}
+	if len(dedup) > 0 {
+		var freed []*proto.ExtentKey
+		if freed, err = mp.freeDedupExtents(dedup); err != nil {
+			log.LogErrorf("[checkSharedExtents] mp(%v) free dedup extents err(%v)", mp.config.PartitionId, err)
+			return
+		}
+		result = append(result, freed...)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	val, err := json.Marshal(&fsmReleaseSharedExtentsRequest{Extents: ids})
+	if err != nil {
+		return
+	}
+	resp, err := mp.submit(opFSMReleaseSharedExtents, val)
+	if err != nil {
+		log.LogErrorf("[checkSharedExtents] mp(%v) release shared extents(%v) err(%v)", mp.config.PartitionId, ids, err)
+		return
+	}
+	for _, id := range resp.([]uint64) {
+		ek := unreferred[id][0]
+		if storage.IsTinyExtent(ek.ExtentId) {
+			// tiny extents are shared by all the files of the data partition, only the ranges are freed
+			result = append(result, unreferred[id]...)
+			continue
+		}
+		// size 0 deletes the whole extent
+		result = append(result, &proto.ExtentKey{PartitionId: ek.PartitionId, ExtentId: ek.ExtentId})
+	}
+	return
+}
+
+// isSharedExtentReferred checks whether the alive inodes refer to the extent of ek, and
+// the range of ek as well.
+func (mp *metaPartition) isSharedExtentReferred(inodes []uint64, ek *proto.ExtentKey) (referred, overlapped bool) {
+	for _, ino := range inodes {
+		i, err := mp.inodeTree.Get(ino)
+		if err != nil {
+			log.LogErrorf("[isSharedExtentReferred] mp(%v) get inode(%v) err(%v)", mp.config.PartitionId, ino, err)
+			return true, true
+		}
+		if i == nil || i.ShouldDelete() {
+			continue
+		}
+		if inodeRefersExtent(i, ek, true) {
+			return true, true
+		}
+		if !referred {
+			referred = inodeRefersExtent(i, ek, false)
+		}
+	}
+	return
+}
+
+// markSharedExtents marks the shared extent keys in the list to the client, so that the
+// client writes the new data of them by append rather than overwrite.
+func (mp *metaPartition) markSharedExtents(eks []proto.ExtentKey) {
+	if mp.sharedExtents.len() == 0 {
+		return
+	}
+	for idx := range eks {
+		ek := &eks[idx]
+		if !mp.sharedExtents.has(ek.GenerateId()) {
+			continue
+		}
+		snapInfo := &proto.ExtSnapInfo{}
+		if ek.SnapInfo != nil {
+			*snapInfo = *ek.SnapInfo
+		}
+		snapInfo.Shared = true
+		ek.SnapInfo = snapInfo
+	}
+}
+
+func sharedExtentsEncodingKey(id uint64) []byte {
+	buff := &bytes.Buffer{}
+	buff.WriteByte(byte(SharedExtentsTable))
+	_ = binary.Write(buff, binary.BigEndian, id)
+	return buff.Bytes()
+}
+
+// persistSharedExtents writes the inodes and the chunks of the shared extents to rocksdb in the rocksdb store
+// mode, the table is stored by the snapshot files in the memory store mode.
+func (mp *metaPartition) persistSharedExtents(dbHandle interface{}, se *sharedExtents, ids ...uint64) (err error) {
+	if !mp.HasRocksDBStore() {
+		return
+	}
+	tree := mp.inodeTree.(*InodeRocks).RocksTree
+	for _, id := range ids {
+		if !se.has(id) {
+			if err = tree.DelItemToBatch(dbHandle, sharedExtentsEncodingKey(id)); err != nil {
+				return
+			}
+			continue
+		}
+		buff := &bytes.Buffer{}
+		se.RLock()
+		err = se.marshalEntryLocked(buff, id)
+		se.RUnlock()
+		if err != nil {
+			return
+		}
+		if err = tree.Update(dbHandle, sharedExtentsEncodingKey(id), buff.Bytes()); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// loadSharedExtentsFromRocksDb loads the shared extents persisted by persistSharedExtents.
+func (mp *metaPartition) loadSharedExtentsFromRocksDb() (err error) {
+	tree := mp.inodeTree.(*InodeRocks).RocksTree
+	err = tree.Range([]byte{byte(SharedExtentsTable)}, []byte{byte(SharedExtentsTable) + 1}, func(v []byte) (bool, error) {
+		mp.sharedExtents.Lock()
+		defer mp.sharedExtents.Unlock()
+		if err := mp.sharedExtents.unmarshalEntryLocked(bytes.NewBuffer(v), sharedExtentsVersion); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		log.LogErrorf("[loadSharedExtentsFromRocksDb] mp(%v) err(%v)", mp.config.PartitionId, err)
+		return
+	}
+	log.LogDebugf("[loadSharedExtentsFromRocksDb] mp(%v) load shared extents(%v)", mp.config.PartitionId, mp.sharedExtents.len())
+	return
+}
+
+func (mp *metaPartition) clearSharedExtents(handle interface{}) (err error) {
+	if !mp.HasRocksDBStore() {
+		return
+	}
+	tree := mp.inodeTree.(*InodeRocks).RocksTree
+	return tree.DelRangeToBatch(handle, []byte{byte(SharedExtentsTable)}, []byte{byte(SharedExtentsTable + 1)})
+}
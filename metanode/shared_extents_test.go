@@ -0,0 +1,86 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedExtentsMarshal(t *testing.T) {
+	se := newSharedExtents()
+	se.add(1<<32|100, 10, 11)
+	se.add(1<<32|100, 11, 12)
+	se.add(2<<32|200, 20)
+	require.Equal(t, []uint64{10, 11, 12}, se.get(1<<32|100))
+
+	data, crc, err := se.Marshal()
+	require.NoError(t, err)
+	require.NotZero(t, crc)
+
+	loaded := newSharedExtents()
+	require.NoError(t, loaded.UnMarshal(data))
+	require.Equal(t, se.extents, loaded.extents)
+
+	loaded.set(2<<32|200, nil)
+	require.False(t, loaded.has(2<<32|200))
+	require.Equal(t, 1, loaded.len())
+	require.Error(t, loaded.UnMarshal(data[:len(data)-4]))
+}
+
+func TestSharedExtentsMarshalChunks(t *testing.T) {
+	se := newSharedExtents()
+	chunk := &dedupChunk{ExtentOffset: 4096, Size: 65536, Shard: 3}
+	chunk.Hash[0] = 1
+	se.add(1<<32|100, 10)
+	se.addChunk(1<<32|100, chunk, dedupRef{Inode: 10, Token: 7})
+	se.addChunk(1<<32|100, chunk, dedupRef{Inode: 11, Token: 8})
+	se.addChunk(1<<32|100, chunk, dedupRef{Inode: 11, Token: 8})
+	se.addChunk(2<<32|200, chunk, dedupRef{Inode: 20, Token: 9})
+	require.True(t, se.isDedup(1<<32|100))
+	require.False(t, se.isDedup(3<<32|300))
+	chunks := se.getChunks(1<<32 | 100)
+	require.Len(t, chunks, 1)
+	require.Equal(t, []uint64{10, 11}, chunks[0].inodes())
+	require.Equal(t, []uint64{7, 8}, chunks[0].tokens())
+
+	data, _, err := se.Marshal()
+	require.NoError(t, err)
+	loaded := newSharedExtents()
+	require.NoError(t, loaded.UnMarshal(data))
+	require.Equal(t, se.extents, loaded.extents)
+	require.Equal(t, se.chunks, loaded.chunks)
+	require.Equal(t, 2, loaded.len())
+
+	// the extent deduplicated is still recorded once the inodes no longer share it
+	loaded.set(2<<32|200, nil)
+	require.True(t, loaded.has(2<<32|200))
+	loaded.dropDedup(2<<32 | 200)
+	require.False(t, loaded.has(2<<32|200))
+}
+
+func TestInodeRefersExtent(t *testing.T) {
+	ino := NewInode(10, proto.Mode(0o644))
+	ino.Extents.eks = append(ino.Extents.eks, proto.ExtentKey{FileOffset: 0, Size: 1000, PartitionId: 1, ExtentId: 100, ExtentOffset: 1000})
+
+	ek := &proto.ExtentKey{PartitionId: 1, ExtentId: 100, ExtentOffset: 0, Size: 1000}
+	require.True(t, inodeRefersExtent(ino, ek, false))
+	require.False(t, inodeRefersExtent(ino, ek, true))
+	ek.Size = 1001
+	require.True(t, inodeRefersExtent(ino, ek, true))
+	require.False(t, inodeRefersExtent(ino, &proto.ExtentKey{PartitionId: 1, ExtentId: 101}, false))
+}
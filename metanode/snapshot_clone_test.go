@@ -0,0 +1,57 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFsmCloneSnapshotInode(t *testing.T) {
+	initMp(t, proto.StoreModeMem)
+	src := testCreateInode(t, FileModeType)
+	src.Extents = NewSortedExtentsFromEks(buildExtents(0, 0, 100))
+	src.Size = 1000
+	require.NoError(t, mp.inodeTree.Put(nil, src))
+
+	newIno, err := mp.nextInodeID()
+	require.NoError(t, err)
+	req := &proto.CloneSnapshotInodeRequest{Inode: src.Inode, NewInode: newIno, CreateTime: 100}
+	resp, err := mp.fsmCloneSnapshotInode(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpOk, resp.Status)
+
+	ino, err := mp.inodeTree.Get(req.NewInode)
+	require.NoError(t, err)
+	require.NotNil(t, ino)
+	require.Equal(t, src.Size, ino.Size)
+	require.Equal(t, int64(100), ino.CreateTime)
+	require.Equal(t, 1, ino.Extents.Len())
+	require.True(t, isExtEqual(src.Extents.eks[0], ino.Extents.eks[0]))
+	require.ElementsMatch(t, []uint64{src.Inode, ino.Inode}, mp.sharedExtents.get(src.Extents.eks[0].GenerateId()))
+
+	resp, err = mp.fsmCloneSnapshotInode(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, proto.OpExistErr, resp.Status)
+
+	dir := testCreateInode(t, DirModeType)
+	newIno, err = mp.nextInodeID()
+	require.NoError(t, err)
+	resp, err = mp.fsmCloneSnapshotInode(nil, &proto.CloneSnapshotInodeRequest{Inode: dir.Inode, NewInode: newIno})
+	require.NoError(t, err)
+	require.Equal(t, proto.OpArgMismatchErr, resp.Status)
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsInodeModified(t *testing.T) {
+	old := NewInode(10, FileModeType)
+	old.setVer(10)
+	old.Size = 100
+
+	top := NewInode(10, FileModeType)
+	top.setVer(20)
+	top.Size = 200
+	top.Generation = old.Generation + 1
+	top.multiSnap = NewMultiSnap(20)
+	top.multiSnap.multiVersions = InodeBatch{old}
+
+	require.True(t, isInodeModified(top, 10, 0))
+	require.True(t, isInodeModified(top, 15, 20))
+	require.False(t, isInodeModified(top, 20, 0))
+
+	dir := NewInode(11, DirModeType)
+	dir.setVer(20)
+	dir.multiSnap = NewMultiSnap(20)
+	dir.multiSnap.multiVersions = InodeBatch{NewInode(11, DirModeType)}
+	dir.multiSnap.multiVersions[0].setVer(10)
+	require.False(t, isInodeModified(dir, 10, 0))
+}
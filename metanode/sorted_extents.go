@@ -2,7 +2,10 @@ package metanode
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/cubefs/cubefs/proto"
@@ -86,6 +89,106 @@ func (se *SortedExtents) UnmarshalBinary(data []byte, v3 bool) (err error, split
 	return
 }
 
+// MarshalCompression marshals the frame index of the compressed keys by the index of the keys,
+// which is kept apart to leave the binary format of the keys unchanged. Nil is returned if none
+// of the keys is compressed.
+func (se *SortedExtents) MarshalCompression() (data []byte) {
+	se.RLock()
+	defer se.RUnlock()
+
+	for idx, ek := range se.eks {
+		if !ek.IsCompressed() {
+			continue
+		}
+		item := make([]byte, compressionItemHeaderSize+4*len(ek.Compression.Frames))
+		binary.BigEndian.PutUint32(item[0:4], uint32(idx))
+		item[4] = ek.Compression.Codec
+		binary.BigEndian.PutUint32(item[5:9], uint32(len(ek.Compression.Frames)))
+		for i, frame := range ek.Compression.Frames {
+			binary.BigEndian.PutUint32(item[compressionItemHeaderSize+4*i:], frame)
+		}
+		data = append(data, item...)
+	}
+	return
+}
+
+func (se *SortedExtents) UnmarshalCompression(data []byte) (err error) {
+	se.Lock()
+	defer se.Unlock()
+
+	for off := 0; off < len(data); {
+		if len(data)-off < compressionItemHeaderSize {
+			return fmt.Errorf("invalid compression data length %v at %v", len(data), off)
+		}
+		idx := int(binary.BigEndian.Uint32(data[off : off+4]))
+		if idx >= len(se.eks) {
+			return fmt.Errorf("compression of key %v out of %v keys", idx, len(se.eks))
+		}
+		codec := data[off+4]
+		count := int(binary.BigEndian.Uint32(data[off+5 : off+9]))
+		off += compressionItemHeaderSize
+		if count > (len(data)-off)/4 {
+			return fmt.Errorf("compression of key %v with %v frames out of data length %v", idx, count, len(data))
+		}
+		frames := make([]uint32, count)
+		for i := range frames {
+			frames[i] = binary.BigEndian.Uint32(data[off : off+4])
+			off += 4
+		}
+		se.eks[idx].Compression = &proto.ExtCompression{Codec: codec, Frames: frames}
+	}
+	return
+}
+
+// compressionItemHeaderSize is the size of the index, codec and frame count of a compressed key.
+const compressionItemHeaderSize = 9
+
+// MarshalCipherNonce marshals the nonces of the keys of the encrypted file by the index of the keys,
+// which are kept apart like the compression. Nil is returned if none of the keys has a nonce.
+func (se *SortedExtents) MarshalCipherNonce() (data []byte) {
+	se.RLock()
+	defer se.RUnlock()
+
+	for idx, ek := range se.eks {
+		if ek.CipherNonce != 0 {
+			data = appendCipherNonce(data, idx, ek.CipherNonce)
+		}
+	}
+	return
+}
+
+func (se *SortedExtents) UnmarshalCipherNonce(data []byte) (err error) {
+	se.Lock()
+	defer se.Unlock()
+
+	return rangeCipherNonce(data, len(se.eks), func(idx int, nonce uint64) {
+		se.eks[idx].CipherNonce = nonce
+	})
+}
+
+const cipherNonceItemSize = 12
+
+func appendCipherNonce(data []byte, idx int, nonce uint64) []byte {
+	item := make([]byte, cipherNonceItemSize)
+	binary.BigEndian.PutUint32(item[0:4], uint32(idx))
+	binary.BigEndian.PutUint64(item[4:12], nonce)
+	return append(data, item...)
+}
+
+func rangeCipherNonce(data []byte, count int, f func(idx int, nonce uint64)) error {
+	if len(data)%cipherNonceItemSize != 0 {
+		return fmt.Errorf("invalid cipher nonce data length %v", len(data))
+	}
+	for off := 0; off < len(data); off += cipherNonceItemSize {
+		idx := int(binary.BigEndian.Uint32(data[off : off+4]))
+		if idx >= count {
+			return fmt.Errorf("cipher nonce of key %v out of %v keys", idx, count)
+		}
+		f(idx, binary.BigEndian.Uint64(data[off+4:off+cipherNonceItemSize]))
+	}
+	return nil
+}
+
 func (se *SortedExtents) Append(ek proto.ExtentKey) (deleteExtents []proto.ExtentKey) {
 	endOffset := ek.FileOffset + uint64(ek.Size)
 
@@ -96,6 +199,13 @@ func (se *SortedExtents) Append(ek proto.ExtentKey) (deleteExtents []proto.Exten
 		se.eks = append(se.eks, ek)
 		return
 	}
+	if ek.IsCompressed() {
+		var splitExtents []proto.ExtentKey
+		se.eks, splitExtents = splitCompressed(se.eks, ek.FileOffset, compressedChunkEnd(&ek))
+		defer func() {
+			deleteExtents = append(splitExtents, deleteExtents...)
+		}()
+	}
 	lastKey := se.eks[len(se.eks)-1]
 	if lastKey.FileOffset+uint64(lastKey.Size) <= ek.FileOffset {
 		se.eks = append(se.eks, ek)
@@ -134,13 +244,64 @@ func (se *SortedExtents) Append(ek proto.ExtentKey) (deleteExtents []proto.Exten
 	// check if ek and key are the same extent file with size extented
 	deleteExtents = make([]proto.ExtentKey, 0, len(invalidExtents))
 	for _, key := range invalidExtents {
-		if key.PartitionId != ek.PartitionId || key.ExtentId != ek.ExtentId {
+		if key.PartitionId != ek.PartitionId || key.ExtentId != ek.ExtentId ||
+			(key.IsCompressed() && key.ExtentOffset != ek.ExtentOffset) {
 			deleteExtents = append(deleteExtents, key)
 		}
 	}
 	return
 }
 
+// compressedChunkEnd returns the end of the last chunk of the compressed key.
+func compressedChunkEnd(ek *proto.ExtentKey) uint64 {
+	return ek.FileOffset + uint64(len(ek.Compression.Frames))*proto.CompressChunkSize
+}
+
+// splitCompressed cuts the compressed keys across the boundaries of the range [start, end) at the
+// chunks, the parts in the range are returned to be deleted and the others are kept. The range starts
+// at a chunk and ends at a chunk or the end of the file, as a compressed key does. The keys are copied,
+// so the keys given are left unchanged.
+func splitCompressed(eks []proto.ExtentKey, start, end uint64) (kept, deleteExtents []proto.ExtentKey) {
+	kept = make([]proto.ExtentKey, 0, len(eks)+1)
+	for _, key := range eks {
+		keyEnd := key.FileOffset + uint64(key.Size)
+		if !key.IsCompressed() || keyEnd <= start || key.FileOffset >= end || (key.FileOffset >= start && keyEnd <= end) {
+			kept = append(kept, key)
+			continue
+		}
+		from, to := key.FileOffset, keyEnd
+		if from < start {
+			kept = append(kept, key.CompressedSubKey(from, start))
+			from = start
+		}
+		if to > end {
+			to = end
+		}
+		deleteExtents = append(deleteExtents, key.CompressedSubKey(from, to))
+		if to < keyEnd {
+			kept = append(kept, key.CompressedSubKey(to, keyEnd))
+		}
+		log.LogDebugf("splitCompressed: key %v cut at [%v, %v)", key, start, end)
+	}
+	return
+}
+
+// HasCompressed returns true if any compressed chunk is in the range [offset, offset+size).
+func (se *SortedExtents) HasCompressed(offset, size uint64) bool {
+	se.RLock()
+	defer se.RUnlock()
+
+	for _, key := range se.eks {
+		if key.FileOffset >= offset+size {
+			break
+		}
+		if key.IsCompressed() && key.FileOffset+uint64(key.Size) > offset {
+			return true
+		}
+	}
+	return false
+}
+
 func storeEkSplit(mpId uint64, inodeID uint64, ekRef *sync.Map, ek *proto.ExtentKey) (id uint64) {
 	if ekRef == nil {
 		log.LogErrorf("[storeEkSplit] mpId [%v] inodeID %v ekRef nil", mpId, inodeID)
@@ -209,6 +370,11 @@ func (se *SortedExtents) SplitWithCheck(mpId uint64, inodeID uint64, ekSplit pro
 	}
 
 	key := &se.eks[startIndex-1]
+	if key.IsCompressed() {
+		status = proto.OpArgMismatchErr
+		log.LogErrorf("SplitWithCheck. mpId [%v] inode[%v] compressed key [%v] could not be split, request [%v]", mpId, inodeID, key, ekSplit)
+		return
+	}
 	if !storage.IsTinyExtent(key.ExtentId) && (key.PartitionId != ekSplit.PartitionId || key.ExtentId != ekSplit.ExtentId) {
 		status = proto.OpArgMismatchErr
 		log.LogErrorf("SplitWithCheck. mpId [%v] inode[%v]  key found with mismatch extent info [%v] request [%v]", mpId, inodeID, key, ekSplit)
@@ -312,6 +478,7 @@ func (se *SortedExtents) SplitWithCheck(mpId uint64, inodeID uint64, ekSplit pro
 			ExtentId:     key.ExtentId,
 			ExtentOffset: key.ExtentOffset + uint64(key.Size) + uint64(ekSplit.Size),
 			Size:         keySize - key.Size - ekSplit.Size,
+			CipherNonce:  key.CipherNonce,
 			// crc
 			SnapInfo: &proto.ExtSnapInfo{
 				VerSeq:  key.GetSeq(),
@@ -336,6 +503,10 @@ func (se *SortedExtents) CheckAndAddRef(lastKey *proto.ExtentKey, currEk *proto.
 	if !lastKey.IsSameExtent(currEk) {
 		return
 	}
+	// NOTE: the frames of compressed chunks are freed alone
+	if lastKey.IsCompressed() || currEk.IsCompressed() {
+		return
+	}
 	log.LogDebugf("action[AppendWithCheck.CheckAndAddRef] ek [%v],lastKey %v", currEk, lastKey)
 	// NOTE: in case of
 	// +---------+--------+
@@ -419,6 +590,19 @@ func (se *SortedExtents) AppendWithCheck(inodeID uint64, ek proto.ExtentKey, add
 		return
 	}
 
+	// NOTE: the chunks of the compressed keys replaced are deleted, and the
+	// keys are restored if the discards of the client mismatch
+	var splitExtents []proto.ExtentKey
+	if ek.IsCompressed() {
+		origin := se.eks
+		se.eks, splitExtents = splitCompressed(se.eks, ek.FileOffset, compressedChunkEnd(&ek))
+		defer func() {
+			if status != proto.OpOk {
+				se.eks = origin
+			}
+		}()
+	}
+
 	var startIndex, endIndex int
 	invalidExtents := make([]proto.ExtentKey, 0)
 	for idx, key := range se.eks {
@@ -440,12 +624,18 @@ func (se *SortedExtents) AppendWithCheck(inodeID uint64, ek proto.ExtentKey, add
 	}
 
 	// check if ek and key are the same extent file with size extented
-	deleteExtents = make([]proto.ExtentKey, 0, len(invalidExtents))
+	deleteExtents = make([]proto.ExtentKey, 0, len(invalidExtents)+len(splitExtents))
+	deleteExtents = append(deleteExtents, splitExtents...)
 	for _, key := range invalidExtents {
 		if key.PartitionId != ek.PartitionId || key.ExtentId != ek.ExtentId || key.ExtentOffset != ek.ExtentOffset {
 			deleteExtents = append(deleteExtents, key)
 		}
 	}
+	if len(splitExtents) > 0 {
+		sort.SliceStable(deleteExtents, func(i, j int) bool {
+			return deleteExtents[i].FileOffset < deleteExtents[j].FileOffset
+		})
+	}
 
 	log.LogDebugf("action[AppendWithCheck] invalidExtents(%v) deleteExtents(%v) discardExtents(%v)", invalidExtents, deleteExtents, clientDiscardExts)
 	if clientDiscardExts != nil {
@@ -510,6 +700,17 @@ func (se *SortedExtents) Truncate(offset uint64, doOnLastKey func(*proto.ExtentK
 			if doOnLastKey != nil {
 				doOnLastKey(&proto.ExtentKey{Size: uint32(lastKey.FileOffset + uint64(lastKey.Size) - offset)})
 			}
+			if lastKey.IsCompressed() {
+				// the chunks after the offset are deleted, and the frame of the chunk
+				// across the offset is kept until the whole chunk is deleted
+				keyEnd := lastKey.FileOffset + uint64(lastKey.Size)
+				kept := lastKey.CompressedSubKey(lastKey.FileOffset, offset)
+				if chunkEnd := compressedChunkEnd(&kept); chunkEnd < keyEnd {
+					deleteExtents = append([]proto.ExtentKey{lastKey.CompressedSubKey(chunkEnd, keyEnd)}, deleteExtents...)
+				}
+				*lastKey = kept
+				return
+			}
 			rsKey := &proto.ExtentKey{}
 			*rsKey = *lastKey
 			lastKey.Size = uint32(offset - lastKey.FileOffset)
@@ -531,6 +732,154 @@ func (se *SortedExtents) Truncate(offset uint64, doOnLastKey func(*proto.ExtentK
 	return
 }
 
+// PunchHole removes the extent keys in the range [offset, offset+size), the keys across
+// the boundaries are split and the parts out of the range are kept, except the compressed
+// keys which are split at the chunks.
+func (se *SortedExtents) PunchHole(offset, size uint64, insertRefMap func(ek *proto.ExtentKey)) (deleteExtents []proto.ExtentKey) {
+	end := offset + size
+
+	se.Lock()
+	defer se.Unlock()
+
+	eks := make([]proto.ExtentKey, 0, len(se.eks))
+	for _, key := range se.eks {
+		keyEnd := key.FileOffset + uint64(key.Size)
+		if keyEnd <= offset || key.FileOffset >= end {
+			eks = append(eks, key)
+			continue
+		}
+		if key.FileOffset >= offset && keyEnd <= end {
+			deleteExtents = append(deleteExtents, key)
+			continue
+		}
+		if key.IsCompressed() {
+			// NOTE: the chunks in the range are deleted, the parts of the chunks
+			// across the boundaries are zeroed by the client before, and the
+			// chunks are kept as a whole
+			chunk := uint64(proto.CompressChunkSize)
+			from, to := key.FileOffset, keyEnd
+			if offset > from {
+				from += (offset - from + chunk - 1) / chunk * chunk
+			}
+			if end < to {
+				to = key.FileOffset + (end-key.FileOffset)/chunk*chunk
+			}
+			if from >= to {
+				eks = append(eks, key)
+				continue
+			}
+			if from > key.FileOffset {
+				eks = append(eks, key.CompressedSubKey(key.FileOffset, from))
+			}
+			deleteExtents = append(deleteExtents, key.CompressedSubKey(from, to))
+			if to < keyEnd {
+				eks = append(eks, key.CompressedSubKey(to, keyEnd))
+			}
+			continue
+		}
+
+		// NOTE: the key is across the boundary, split it into
+		// +------+--------+-------+
+		// | left | delete | right |
+		// +------+--------+-------+
+		// the first kept part takes over the reference of a split key
+		inherit := key.IsSplit()
+		addRef := func(ek *proto.ExtentKey) {
+			if inherit {
+				inherit = false
+				return
+			}
+			insertRefMap(ek)
+		}
+		delStart, delEnd := key.FileOffset, keyEnd
+		if key.FileOffset < offset {
+			left := subExtentKey(&key, key.FileOffset, offset)
+			addRef(&left)
+			eks = append(eks, left)
+			delStart = offset
+		}
+		if keyEnd > end {
+			delEnd = end
+			right := subExtentKey(&key, end, keyEnd)
+			addRef(&right)
+			eks = append(eks, right)
+		}
+		delKey := subExtentKey(&key, delStart, delEnd)
+		insertRefMap(&delKey)
+		deleteExtents = append(deleteExtents, delKey)
+		log.LogDebugf("SortedExtents.PunchHole split key %v at [%v, %v)", key, offset, end)
+	}
+	se.eks = eks
+	return
+}
+
+// CopyRange returns the extent keys in the range [offset, offset+size), the keys
+// across the boundaries are cut off. The snapshot info is not copied.
+func (se *SortedExtents) CopyRange(offset, size uint64) (eks []proto.ExtentKey) {
+	end := offset + size
+
+	se.RLock()
+	defer se.RUnlock()
+
+	for _, key := range se.eks {
+		keyEnd := key.FileOffset + uint64(key.Size)
+		if keyEnd <= offset {
+			continue
+		}
+		if key.FileOffset >= end {
+			break
+		}
+		from, to := key.FileOffset, keyEnd
+		if from < offset {
+			from = offset
+		}
+		if to > end {
+			to = end
+		}
+		ek := subExtentKey(&key, from, to)
+		ek.SnapInfo = nil
+		eks = append(eks, ek)
+	}
+	return
+}
+
+// InsertRange inserts the sorted extent keys into a hole, which must not overlap
+// with the existing keys.
+func (se *SortedExtents) InsertRange(eks []proto.ExtentKey) {
+	if len(eks) == 0 {
+		return
+	}
+
+	se.Lock()
+	defer se.Unlock()
+
+	idx := len(se.eks)
+	for i, key := range se.eks {
+		if key.FileOffset >= eks[0].FileOffset {
+			idx = i
+			break
+		}
+	}
+	merged := make([]proto.ExtentKey, 0, len(se.eks)+len(eks))
+	merged = append(merged, se.eks[:idx]...)
+	merged = append(merged, eks...)
+	merged = append(merged, se.eks[idx:]...)
+	se.eks = merged
+}
+
+// subExtentKey returns the part [from, to) of the file range of key.
+func subExtentKey(key *proto.ExtentKey, from, to uint64) (ek proto.ExtentKey) {
+	ek = *key
+	if key.SnapInfo != nil {
+		snap := *key.SnapInfo
+		ek.SnapInfo = &snap
+	}
+	ek.FileOffset = from
+	ek.ExtentOffset = key.ExtentOffset + (from - key.FileOffset)
+	ek.Size = uint32(to - from)
+	return
+}
+
 func (se *SortedExtents) insert(ek proto.ExtentKey, startIdx int) {
 	se.eks = append(se.eks, ek)
 	size := len(se.eks)
@@ -151,3 +151,164 @@ func TestSortedMarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestPunchHole(t *testing.T) {
+	se := NewSortedExtents()
+	se.eks = append(se.eks, proto.ExtentKey{FileOffset: 0, Size: 1000, PartitionId: 1, ExtentId: 1})
+	se.eks = append(se.eks, proto.ExtentKey{FileOffset: 1000, Size: 1000, PartitionId: 1, ExtentId: 2})
+	se.eks = append(se.eks, proto.ExtentKey{FileOffset: 2000, Size: 1000, PartitionId: 1, ExtentId: 3})
+
+	refs := 0
+	delExtents := se.PunchHole(500, 2000, func(ek *proto.ExtentKey) { refs++ })
+	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
+	if len(se.eks) != 2 || len(delExtents) != 3 || refs != 4 {
+		t.Fatalf("eks %v del %v refs %v", se.eks, delExtents, refs)
+	}
+	left, right := se.eks[0], se.eks[1]
+	if left.ExtentId != 1 || left.Size != 500 || right.ExtentId != 3 || right.FileOffset != 2500 ||
+		right.ExtentOffset != 500 || right.Size != 500 {
+		t.Fatalf("eks %v", se.eks)
+	}
+	if delExtents[0].ExtentOffset != 500 || delExtents[0].Size != 500 || delExtents[1].ExtentId != 2 ||
+		delExtents[2].ExtentId != 3 || delExtents[2].Size != 500 {
+		t.Fatalf("del %v", delExtents)
+	}
+
+	// punch in the middle of a key
+	se = NewSortedExtents()
+	se.eks = append(se.eks, proto.ExtentKey{FileOffset: 0, Size: 3000, PartitionId: 1, ExtentId: 1})
+	delExtents = se.PunchHole(1000, 1000, func(ek *proto.ExtentKey) {})
+	if len(se.eks) != 2 || se.eks[1].FileOffset != 2000 || se.eks[1].ExtentOffset != 2000 ||
+		len(delExtents) != 1 || delExtents[0].ExtentOffset != 1000 || delExtents[0].Size != 1000 {
+		t.Fatalf("eks %v del %v", se.eks, delExtents)
+	}
+}
+
+func TestCopyAndInsertRange(t *testing.T) {
+	se := NewSortedExtents()
+	se.eks = append(se.eks, proto.ExtentKey{FileOffset: 0, Size: 1000, PartitionId: 1, ExtentId: 1})
+	se.eks = append(se.eks, proto.ExtentKey{FileOffset: 1000, Size: 1000, PartitionId: 1, ExtentId: 2,
+		SnapInfo: &proto.ExtSnapInfo{VerSeq: 1, IsSplit: true}})
+
+	eks := se.CopyRange(500, 1000)
+	if len(eks) != 2 || eks[0].ExtentOffset != 500 || eks[0].Size != 500 || eks[1].Size != 500 || eks[1].SnapInfo != nil {
+		t.Fatalf("eks %v", eks)
+	}
+
+	dst := NewSortedExtents()
+	dst.eks = append(dst.eks, proto.ExtentKey{FileOffset: 0, Size: 100, PartitionId: 1, ExtentId: 3})
+	dst.eks = append(dst.eks, proto.ExtentKey{FileOffset: 5000, Size: 100, PartitionId: 1, ExtentId: 4})
+	for idx := range eks {
+		eks[idx].FileOffset += 500
+	}
+	dst.InsertRange(eks)
+	if len(dst.eks) != 4 || dst.eks[1].FileOffset != 1000 || dst.eks[2].FileOffset != 1500 || dst.eks[3].ExtentId != 4 {
+		t.Fatalf("eks %v", dst.eks)
+	}
+}
+
+func TestCompressedExtents(t *testing.T) {
+	chunk := uint64(proto.CompressChunkSize)
+	compressed := func(off uint64, size uint32, extOff uint64, frames ...uint32) proto.ExtentKey {
+		return proto.ExtentKey{FileOffset: off, Size: size, PartitionId: 1, ExtentId: 1025, ExtentOffset: extOff,
+			Compression: &proto.ExtCompression{Codec: 1, Frames: frames}}
+	}
+
+	se := NewSortedExtents()
+	se.eks = append(se.eks, compressed(0, uint32(3*chunk), 0, 4000, 5000, 6000|proto.CompressFrameRaw))
+	se.eks = append(se.eks, proto.ExtentKey{FileOffset: 3 * chunk, Size: 1000, PartitionId: 1, ExtentId: 1026})
+	if !se.HasCompressed(chunk-1, 1) || se.HasCompressed(3*chunk, 100) {
+		t.Fatalf("eks %v", se.eks)
+	}
+
+	// the frame index is marshaled apart from the keys
+	data, err := se.MarshalBinary(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	se2 := NewSortedExtents()
+	if err, _ = se2.UnmarshalBinary(data, true); err != nil {
+		t.Fatal(err)
+	}
+	if err = se2.UnmarshalCompression(se.MarshalCompression()); err != nil {
+		t.Fatal(err)
+	}
+	for idx := range se.eks {
+		if !se.eks[idx].Equals(&se2.eks[idx]) {
+			t.Fatalf("eks %v unmarshal %v", se.eks, se2.eks)
+		}
+	}
+	if err = se2.UnmarshalCompression(se.MarshalCompression()[:12]); err == nil {
+		t.Fatalf("short frame index unmarshaled")
+	}
+
+	// the chunk rewritten is cut from the key, and the frame of it is deleted
+	delExtents := se.Append(compressed(chunk, uint32(chunk), 15000, 3000))
+	if len(se.eks) != 4 || len(delExtents) != 1 || delExtents[0].ExtentOffset != 4000 ||
+		NewDeletedExtentKey(&delExtents[0], 1, 1).ExtentKey.Size != 5000 {
+		t.Fatalf("eks %v del %v", se.eks, delExtents)
+	}
+	if se.eks[0].Size != uint32(chunk) || se.eks[2].ExtentOffset != 9000 || se.eks[2].FileOffset != 2*chunk {
+		t.Fatalf("eks %v", se.eks)
+	}
+
+	// the key extended keeps the frames of it
+	delExtents = se.Append(compressed(chunk, uint32(2*chunk), 15000, 3000, 2000))
+	if len(se.eks) != 3 || len(delExtents) != 1 || delExtents[0].ExtentOffset != 9000 {
+		t.Fatalf("eks %v del %v", se.eks, delExtents)
+	}
+
+	// the chunks in the hole are deleted, and the chunks across it are kept whole
+	delExtents = se.PunchHole(chunk/2, 2*chunk, func(ek *proto.ExtentKey) {})
+	if len(se.eks) != 3 || len(delExtents) != 1 || delExtents[0].ExtentOffset != 15000 || delExtents[0].Size != uint32(chunk) {
+		t.Fatalf("eks %v del %v", se.eks, delExtents)
+	}
+	if se.eks[0].Size != uint32(chunk) || se.eks[1].FileOffset != 2*chunk || se.eks[1].ExtentOffset != 18000 ||
+		len(se.eks[1].Compression.Frames) != 1 {
+		t.Fatalf("eks %v", se.eks)
+	}
+
+	// the frame of the last chunk is kept by truncate
+	delExtents = se.Truncate(2*chunk+100, nil, nil)
+	if len(se.eks) != 2 || se.eks[1].Size != 100 || len(se.eks[1].Compression.Frames) != 1 ||
+		len(delExtents) != 1 || delExtents[0].ExtentId != 1026 {
+		t.Fatalf("eks %v del %v", se.eks, delExtents)
+	}
+
+	// the chunks after the one truncated are deleted
+	se = NewSortedExtents()
+	se.eks = append(se.eks, compressed(0, uint32(3*chunk), 0, 4000, 5000, 6000))
+	delExtents = se.Truncate(chunk+10, nil, nil)
+	if len(se.eks) != 1 || se.eks[0].Size != uint32(chunk+10) || len(se.eks[0].Compression.Frames) != 2 ||
+		len(delExtents) != 1 || delExtents[0].ExtentOffset != 9000 || delExtents[0].StorageKey().Size != 6000 {
+		t.Fatalf("eks %v del %v", se.eks, delExtents)
+	}
+}
+
+func TestAppendCompressedWithCheck(t *testing.T) {
+	chunk := uint64(proto.CompressChunkSize)
+	origin := proto.ExtentKey{FileOffset: 0, Size: uint32(3 * chunk), PartitionId: 1, ExtentId: 1025,
+		Compression: &proto.ExtCompression{Codec: 1, Frames: []uint32{4000, 5000, 6000}}}
+	ek := proto.ExtentKey{FileOffset: chunk, Size: uint32(chunk), PartitionId: 1, ExtentId: 1025, ExtentOffset: 15000,
+		Compression: &proto.ExtCompression{Codec: 1, Frames: []uint32{3000}}}
+
+	// the keys are left unchanged if the discards of the client mismatch
+	se := NewSortedExtents()
+	se.eks = append(se.eks, origin)
+	_, status := se.AppendWithCheck(1, ek, func(*proto.ExtentKey) {}, nil)
+	if status != proto.OpConflictExtentsErr || len(se.eks) != 1 || !se.eks[0].Equals(&origin) {
+		t.Fatalf("status %v eks %v", status, se.eks)
+	}
+
+	discards := []proto.ExtentKey{origin.CompressedSubKey(chunk, 2*chunk)}
+	delExtents, status := se.AppendWithCheck(1, ek, func(*proto.ExtentKey) {}, discards)
+	if status != proto.OpOk || len(se.eks) != 3 || len(delExtents) != 1 || delExtents[0].ExtentOffset != 4000 {
+		t.Fatalf("status %v eks %v del %v", status, se.eks, delExtents)
+	}
+
+	// the request retried is done once
+	delExtents, status = se.AppendWithCheck(1, ek, func(*proto.ExtentKey) {}, discards)
+	if status != proto.OpOk || len(se.eks) != 3 || len(delExtents) != 0 {
+		t.Fatalf("status %v eks %v del %v", status, se.eks, delExtents)
+	}
+}
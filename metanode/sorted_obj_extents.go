@@ -67,6 +67,28 @@ func (se *SortedObjExtents) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// MarshalCipherNonce marshals the nonces of the keys of the encrypted file like the SortedExtents.
+func (se *SortedObjExtents) MarshalCipherNonce() (data []byte) {
+	se.RLock()
+	defer se.RUnlock()
+
+	for idx, ek := range se.eks {
+		if ek.CipherNonce != 0 {
+			data = appendCipherNonce(data, idx, ek.CipherNonce)
+		}
+	}
+	return
+}
+
+func (se *SortedObjExtents) UnmarshalCipherNonce(data []byte) (err error) {
+	se.Lock()
+	defer se.Unlock()
+
+	return rangeCipherNonce(data, len(se.eks), func(idx int, nonce uint64) {
+		se.eks[idx].CipherNonce = nonce
+	})
+}
+
 // Append will return error if the objextentkey exist overlap.
 func (se *SortedObjExtents) Append(ek proto.ObjExtentKey) (err error) {
 	se.Lock()
@@ -177,3 +199,34 @@ func (se *SortedObjExtents) Find(oek *proto.ObjExtentKey) (extent proto.ObjExten
 	}
 	return
 }
+
+// HasRange checks whether a key overlaps the range [offset, offset+size).
+func (se *SortedObjExtents) HasRange(offset, size uint64) bool {
+	se.RLock()
+	defer se.RUnlock()
+
+	for _, ek := range se.eks {
+		if ek.FileOffset < offset+size && offset < ek.FileOffset+ek.Size {
+			return true
+		}
+	}
+	return false
+}
+
+// Truncate removes the keys beyond the size and returns them, false is returned and nothing is removed if
+// a key crosses the size, the key could not be cut since the data of it is kept as a whole in the blobstore.
+func (se *SortedObjExtents) Truncate(size uint64) (deleted []proto.ObjExtentKey, ok bool) {
+	se.Lock()
+	defer se.Unlock()
+
+	idx := len(se.eks)
+	for idx > 0 && se.eks[idx-1].FileOffset >= size {
+		idx--
+	}
+	if idx > 0 && se.eks[idx-1].FileOffset+se.eks[idx-1].Size > size {
+		return nil, false
+	}
+	deleted = append(deleted, se.eks[idx:]...)
+	se.eks = se.eks[:idx]
+	return deleted, true
+}
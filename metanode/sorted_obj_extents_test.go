@@ -96,3 +96,22 @@ func TestSortedObjExtentsMarshal(t *testing.T) {
 		require.EqualValues(t, e1.Size, e2.Size)
 	}
 }
+
+func TestSortedObjExtentsTruncate(t *testing.T) {
+	se := NewSortedObjExtents()
+	for i := uint64(0); i < 3; i++ {
+		require.NoError(t, se.Append(proto.ObjExtentKey{Cid: i, FileOffset: i * 1000, Size: 1000}))
+	}
+	require.True(t, se.HasRange(2500, 10))
+	require.False(t, se.HasRange(3000, 10))
+
+	_, ok := se.Truncate(1500)
+	require.False(t, ok)
+	require.EqualValues(t, 3000, se.Size())
+
+	deleted, ok := se.Truncate(1000)
+	require.True(t, ok)
+	require.Len(t, deleted, 2)
+	require.EqualValues(t, 1000, se.Size())
+	require.False(t, se.HasRange(1000, 10))
+}
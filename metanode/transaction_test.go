@@ -66,6 +66,7 @@ func newMetaPartition(PartitionId uint64, manager *metadataManager, storeMode pr
 		vol:            NewVol(),
 		manager:        manager,
 		rocksdbManager: NewPerDiskRocksdbManager(0, 0, 0, 0, 0),
+		sharedExtents:  newSharedExtents(),
 	}
 	err := mp.rocksdbManager.Register(metaConf.RocksDBDir)
 	if err != nil {
@@ -0,0 +1,72 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+)
+
+const (
+	ModuleName = "nfsNode"
+
+	// String type configuration item, the port to serve both the NFS and the MOUNT programs.
+	// Mount with 'mount -t nfs -o vers=3,proto=tcp,port=2049,mountport=2049,nolock <host>:/<volume> <dir>'.
+	configListen = proto.ListenPort
+	// Array type configuration item, the addresses of the masters.
+	configMasterAddr = proto.MasterAddr
+	// Array type configuration item, the volumes to export, each an object of the name and the owner
+	// of the volume, such as {"volName": "ltptest", "owner": "ltptest"}. Nothing is exported if not set.
+	configExports       = "exports"
+	configExportVolName = "volName"
+	configExportOwner   = "owner"
+	// Bool type configuration item, maps the root user of the NFS clients to the anonymous user, true by default.
+	configRootSquash = "rootSquash"
+	// Bool type configuration item, maps all users of the NFS clients to the anonymous user.
+	configAllSquash = "allSquash"
+	// Int type configuration items, the anonymous user for squash and AUTH_NONE requests.
+	configAnonUid = "anonUid"
+	configAnonGid = "anonGid"
+	// Bool type configuration item, rejects the writes of the users and the dirs over the quotas.
+	configEnableQuota = "enableQuota"
+)
+
+const (
+	defaultListen  = "2049"
+	defaultAnonUid = 65534
+	defaultAnonGid = 65534
+
+	maxRecordSize      = 4 * 1024 * 1024
+	maxConnCalls       = 64
+	maxReadSize        = 1024 * 1024
+	maxWriteSize       = 1024 * 1024
+	prefDirSize        = 64 * 1024
+	readDirLimit       = 1024
+	maxNameLen         = 255
+	maxPathLen         = 4096
+	streamIdleTimeout  = 30 * time.Second
+	ipCheckExpiration  = time.Minute
+	maxCachedCookies   = 1000000
+	maxCachedParents   = 1000000
+	volumeCheckTimeout = 5 * time.Second
+)
+
+var (
+	// Regular expression used to verify the configuration of the service listening port.
+	// A valid service listening port configuration is a string containing only numbers.
+	regexpListen = regexp.MustCompile(`^(\d)+$`)
+)
@@ -0,0 +1,53 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+)
+
+// The file handles are made up of the version, the hash of the volume name and the inode
+// id, so they only depend on the volume and the inode and stay valid across the restarts
+// and among all the gateways.
+const (
+	fileHandleVersion = 1
+	fileHandleLen     = 17
+	maxFileHandleLen  = 64
+)
+
+var errBadHandle = errors.New("bad file handle")
+
+func volumeHash(name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return h.Sum64()
+}
+
+func encodeFileHandle(volHash, ino uint64) []byte {
+	fh := make([]byte, fileHandleLen)
+	fh[0] = fileHandleVersion
+	binary.BigEndian.PutUint64(fh[1:], volHash)
+	binary.BigEndian.PutUint64(fh[9:], ino)
+	return fh
+}
+
+func decodeFileHandle(fh []byte) (volHash, ino uint64, err error) {
+	if len(fh) != fileHandleLen || fh[0] != fileHandleVersion {
+		return 0, 0, errBadHandle
+	}
+	return binary.BigEndian.Uint64(fh[1:]), binary.BigEndian.Uint64(fh[9:]), nil
+}
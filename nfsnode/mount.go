@@ -0,0 +1,162 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// MOUNT version 3 of RFC 1813.
+const (
+	mountProgram = 100005
+	mountVersion = 3
+
+	mountProcNull    = 0
+	mountProcMnt     = 1
+	mountProcDump    = 2
+	mountProcUmnt    = 3
+	mountProcUmntAll = 4
+	mountProcExport  = 5
+
+	mountOK             = 0
+	mountErrPerm        = 1
+	mountErrNoEnt       = 2
+	mountErrIO          = 5
+	mountErrAccess      = 13
+	mountErrNotDir      = 20
+	mountErrInval       = 22
+	mountErrNameTooLong = 63
+	mountErrServerFault = 10006
+
+	maxMountPathLen = 1024
+)
+
+type mountServer struct {
+	node *NfsNode
+}
+
+func (s *mountServer) versions() (low, high uint32) {
+	return mountVersion, mountVersion
+}
+
+func (s *mountServer) handle(call *rpcCall, reply *xdrEncoder) uint32 {
+	switch call.Proc {
+	case mountProcNull:
+		return rpcAcceptSuccess
+	case mountProcMnt:
+		return s.mnt(call, reply)
+	case mountProcDump:
+		return s.dump(call, reply)
+	case mountProcUmnt:
+		dir := call.Args.String(maxMountPathLen)
+		if call.Args.Err() != nil {
+			return rpcAcceptGarbageArgs
+		}
+		s.node.removeMount(call.Remote, path.Clean(dir))
+		return rpcAcceptSuccess
+	case mountProcUmntAll:
+		s.node.removeMount(call.Remote, "")
+		return rpcAcceptSuccess
+	case mountProcExport:
+		return s.export(call, reply)
+	default:
+		return rpcAcceptProcUnavail
+	}
+}
+
+// mnt returns the file handle of the dir, which is the volume name followed by the
+// optional path in the volume.
+func (s *mountServer) mnt(call *rpcCall, reply *xdrEncoder) uint32 {
+	dir := call.Args.String(maxMountPathLen)
+	if call.Args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	dir = path.Clean("/" + dir)
+	fh, stat := s.lookupExport(call, dir)
+	log.LogInfof("mnt: remote(%v) dir(%v) stat(%v)", call.Remote, dir, stat)
+	reply.Uint32(stat)
+	if stat != mountOK {
+		return rpcAcceptSuccess
+	}
+	s.node.addMount(call.Remote, dir)
+	reply.Opaque(fh)
+	reply.Uint32(1)
+	reply.Uint32(rpcAuthSys)
+	return rpcAcceptSuccess
+}
+
+func (s *mountServer) lookupExport(call *rpcCall, dir string) (fh []byte, stat uint32) {
+	parts := strings.Split(strings.TrimPrefix(dir, "/"), "/")
+	if parts[0] == "" {
+		return nil, mountErrNoEnt
+	}
+	v, err := s.node.getVolume(parts[0])
+	if err != nil {
+		return nil, mountErrNoEnt
+	}
+	if !v.checkIP(remoteIP(call.Remote)) {
+		return nil, mountErrAccess
+	}
+	ino := proto.RootIno
+	for _, name := range parts[1:] {
+		if len(name) > maxNameLen {
+			return nil, mountErrNameTooLong
+		}
+		child, mode, err := v.mw.Lookup_ll(ino, name)
+		if err == syscall.ENOENT {
+			return nil, mountErrNoEnt
+		}
+		if err != nil {
+			log.LogErrorf("lookupExport: lookup failed: volume(%v) parent(%v) name(%v) err(%v)", v.name, ino, name, err)
+			return nil, mountErrIO
+		}
+		if !os.FileMode(mode).IsDir() {
+			return nil, mountErrNotDir
+		}
+		v.setParent(child, ino)
+		ino = child
+	}
+	return encodeFileHandle(v.hash, ino), mountOK
+}
+
+func (s *mountServer) dump(call *rpcCall, reply *xdrEncoder) uint32 {
+	s.node.mountLock.Lock()
+	defer s.node.mountLock.Unlock()
+	for host, dirs := range s.node.mounts {
+		for dir := range dirs {
+			reply.Bool(true)
+			reply.String(host)
+			reply.String(dir)
+		}
+	}
+	reply.Bool(false)
+	return rpcAcceptSuccess
+}
+
+func (s *mountServer) export(call *rpcCall, reply *xdrEncoder) uint32 {
+	for _, name := range s.node.exportList() {
+		reply.Bool(true)
+		reply.String("/" + name)
+		reply.Bool(false) // no groups
+	}
+	reply.Bool(false)
+	return rpcAcceptSuccess
+}
@@ -0,0 +1,1340 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// NFS version 3 of RFC 1813.
+const (
+	nfsProgram = 100003
+	nfsVersion = 3
+
+	nfsProcNull        = 0
+	nfsProcGetAttr     = 1
+	nfsProcSetAttr     = 2
+	nfsProcLookup      = 3
+	nfsProcAccess      = 4
+	nfsProcReadLink    = 5
+	nfsProcRead        = 6
+	nfsProcWrite       = 7
+	nfsProcCreate      = 8
+	nfsProcMkdir       = 9
+	nfsProcSymlink     = 10
+	nfsProcMknod       = 11
+	nfsProcRemove      = 12
+	nfsProcRmdir       = 13
+	nfsProcRename      = 14
+	nfsProcLink        = 15
+	nfsProcReadDir     = 16
+	nfsProcReadDirPlus = 17
+	nfsProcFsStat      = 18
+	nfsProcFsInfo      = 19
+	nfsProcPathConf    = 20
+	nfsProcCommit      = 21
+)
+
+// nfsstat3
+const (
+	nfs3OK             = 0
+	nfs3ErrPerm        = 1
+	nfs3ErrNoEnt       = 2
+	nfs3ErrIO          = 5
+	nfs3ErrAccess      = 13
+	nfs3ErrExist       = 17
+	nfs3ErrXDev        = 18
+	nfs3ErrNotDir      = 20
+	nfs3ErrIsDir       = 21
+	nfs3ErrInval       = 22
+	nfs3ErrFBig        = 27
+	nfs3ErrNoSpc       = 28
+	nfs3ErrROFS        = 30
+	nfs3ErrMLink       = 31
+	nfs3ErrNameTooLong = 63
+	nfs3ErrNotEmpty    = 66
+	nfs3ErrDQuot       = 69
+	nfs3ErrStale       = 70
+	nfs3ErrBadHandle   = 10001
+	nfs3ErrNotSync     = 10002
+	nfs3ErrBadCookie   = 10003
+	nfs3ErrNotSupp     = 10004
+	nfs3ErrTooSmall    = 10005
+	nfs3ErrServerFault = 10006
+	nfs3ErrBadType     = 10007
+)
+
+// ftype3
+const (
+	nf3Reg  = 1
+	nf3Dir  = 2
+	nf3Blk  = 3
+	nf3Chr  = 4
+	nf3Lnk  = 5
+	nf3Sock = 6
+	nf3Fifo = 7
+)
+
+const (
+	access3Read    = 0x01
+	access3Lookup  = 0x02
+	access3Modify  = 0x04
+	access3Extend  = 0x08
+	access3Delete  = 0x10
+	access3Execute = 0x20
+
+	stableUnstable = 0
+	stableDataSync = 1
+	stableFileSync = 2
+
+	createUnchecked = 0
+	createGuarded   = 1
+	createExclusive = 2
+
+	timeDontChange    = 0
+	timeServer        = 1
+	timeClient        = 2
+	createVerfLen     = 8
+	cookieVerfLen     = 8
+	fsf3Link          = 0x01
+	fsf3Symlink       = 0x02
+	fsf3Homogeneous   = 0x08
+	fsf3CanSetTime    = 0x10
+	fsBlockSize       = 4096
+	fattr3Len         = 84
+	entryOverhead     = 24 // value_follows, fileid, name length and cookie
+	entryPlusOverhead = entryOverhead + 4 + fattr3Len + 8 + fileHandleLen + 3
+	readDirOverhead   = 4 + 4 + fattr3Len + cookieVerfLen + 8
+	maxLinkCount      = math.MaxUint32
+	maxFileSize       = math.MaxInt64
+	fsStatTotalFiles  = math.MaxUint32
+	unixModePermMask  = 0o7777
+)
+
+var (
+	errBadCookie = errors.New("bad readdir cookie")
+	errTooSmall  = errors.New("readdir buffer too small")
+)
+
+type nfsServer struct {
+	node *NfsNode
+}
+
+type nfsRequest struct {
+	call  *rpcCall
+	args  *xdrDecoder
+	reply *xdrEncoder
+	cred  *rpcCredential
+}
+
+func (s *nfsServer) versions() (low, high uint32) {
+	return nfsVersion, nfsVersion
+}
+
+func (s *nfsServer) handle(call *rpcCall, reply *xdrEncoder) uint32 {
+	r := &nfsRequest{call: call, args: call.Args, reply: reply, cred: s.node.credential(call)}
+	start := time.Now()
+	var stat uint32
+	switch call.Proc {
+	case nfsProcNull:
+		stat = rpcAcceptSuccess
+	case nfsProcGetAttr:
+		stat = s.getAttr(r)
+	case nfsProcSetAttr:
+		stat = s.setAttr(r)
+	case nfsProcLookup:
+		stat = s.lookup(r)
+	case nfsProcAccess:
+		stat = s.access(r)
+	case nfsProcReadLink:
+		stat = s.readLink(r)
+	case nfsProcRead:
+		stat = s.read(r)
+	case nfsProcWrite:
+		stat = s.write(r)
+	case nfsProcCreate:
+		stat = s.create(r)
+	case nfsProcMkdir:
+		stat = s.mkdir(r)
+	case nfsProcSymlink:
+		stat = s.symlink(r)
+	case nfsProcMknod:
+		stat = s.mknod(r)
+	case nfsProcRemove:
+		stat = s.remove(r, false)
+	case nfsProcRmdir:
+		stat = s.remove(r, true)
+	case nfsProcRename:
+		stat = s.rename(r)
+	case nfsProcLink:
+		stat = s.link(r)
+	case nfsProcReadDir:
+		stat = s.readDir(r, false)
+	case nfsProcReadDirPlus:
+		stat = s.readDir(r, true)
+	case nfsProcFsStat:
+		stat = s.fsStat(r)
+	case nfsProcFsInfo:
+		stat = s.fsInfo(r)
+	case nfsProcPathConf:
+		stat = s.pathConf(r)
+	case nfsProcCommit:
+		stat = s.commit(r)
+	default:
+		stat = rpcAcceptProcUnavail
+	}
+	log.LogDebugf("TRACE nfs: proc(%v) remote(%v) uid(%v) stat(%v) (%v)ns", call.Proc, call.Remote,
+		r.cred.Uid, stat, time.Since(start).Nanoseconds())
+	return stat
+}
+
+func nfsStatus(err error) uint32 {
+	switch err {
+	case nil:
+		return nfs3OK
+	case errBadHandle:
+		return nfs3ErrBadHandle
+	case errStale:
+		return nfs3ErrStale
+	}
+	errno, ok := err.(syscall.Errno)
+	if !ok {
+		return nfs3ErrIO
+	}
+	switch errno {
+	case syscall.EPERM:
+		return nfs3ErrPerm
+	case syscall.ENOENT:
+		return nfs3ErrNoEnt
+	case syscall.EACCES:
+		return nfs3ErrAccess
+	case syscall.EEXIST:
+		return nfs3ErrExist
+	case syscall.EXDEV:
+		return nfs3ErrXDev
+	case syscall.ENOTDIR:
+		return nfs3ErrNotDir
+	case syscall.EISDIR:
+		return nfs3ErrIsDir
+	case syscall.EINVAL:
+		return nfs3ErrInval
+	case syscall.EFBIG:
+		return nfs3ErrFBig
+	case syscall.ENOSPC:
+		return nfs3ErrNoSpc
+	case syscall.EROFS:
+		return nfs3ErrROFS
+	case syscall.EMLINK:
+		return nfs3ErrMLink
+	case syscall.ENAMETOOLONG:
+		return nfs3ErrNameTooLong
+	case syscall.ENOTEMPTY:
+		return nfs3ErrNotEmpty
+	case syscall.EDQUOT:
+		return nfs3ErrDQuot
+	case syscall.ENOTSUP:
+		return nfs3ErrNotSupp
+	default:
+		return nfs3ErrIO
+	}
+}
+
+func nfsType(mode uint32) uint32 {
+	m := os.FileMode(mode)
+	switch {
+	case m.IsDir():
+		return nf3Dir
+	case m&os.ModeSymlink != 0:
+		return nf3Lnk
+	case m&os.ModeNamedPipe != 0:
+		return nf3Fifo
+	case m&os.ModeSocket != 0:
+		return nf3Sock
+	case m&os.ModeCharDevice != 0:
+		return nf3Chr
+	case m&os.ModeDevice != 0:
+		return nf3Blk
+	default:
+		return nf3Reg
+	}
+}
+
+// unixMode converts the permission bits of the inode to the mode of NFS.
+func unixMode(mode uint32) uint32 {
+	m := os.FileMode(mode)
+	unix := uint32(m.Perm())
+	if m&os.ModeSetuid != 0 {
+		unix |= syscall.S_ISUID
+	}
+	if m&os.ModeSetgid != 0 {
+		unix |= syscall.S_ISGID
+	}
+	if m&os.ModeSticky != 0 {
+		unix |= syscall.S_ISVTX
+	}
+	return unix
+}
+
+// inodeMode replaces the permission bits of the inode by the mode of NFS.
+func inodeMode(mode, unix uint32) uint32 {
+	m := os.FileMode(mode) &^ (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	m |= os.FileMode(unix) & os.ModePerm
+	if unix&syscall.S_ISUID != 0 {
+		m |= os.ModeSetuid
+	}
+	if unix&syscall.S_ISGID != 0 {
+		m |= os.ModeSetgid
+	}
+	if unix&syscall.S_ISVTX != 0 {
+		m |= os.ModeSticky
+	}
+	return proto.Mode(m)
+}
+
+func writeTime(e *xdrEncoder, t time.Time) {
+	e.Uint32(uint32(t.Unix()))
+	e.Uint32(uint32(t.Nanosecond()))
+}
+
+func writeFattr(e *xdrEncoder, v *Volume, info *proto.InodeInfo) {
+	e.Uint32(nfsType(info.Mode))
+	e.Uint32(unixMode(info.Mode))
+	e.Uint32(info.Nlink)
+	e.Uint32(info.Uid)
+	e.Uint32(info.Gid)
+	e.Uint64(info.Size)
+	e.Uint64(info.Size)
+	e.Uint32(0) // rdev
+	e.Uint32(0)
+	e.Uint64(v.hash)
+	e.Uint64(info.Inode)
+	writeTime(e, info.AccessTime)
+	writeTime(e, info.ModifyTime)
+	writeTime(e, info.CreateTime)
+}
+
+func writePostOpAttr(e *xdrEncoder, v *Volume, info *proto.InodeInfo) {
+	if v == nil || info == nil {
+		e.Bool(false)
+		return
+	}
+	e.Bool(true)
+	writeFattr(e, v, info)
+}
+
+// writeWcc writes the attributes before the operation and the refreshed ones after.
+func writeWcc(e *xdrEncoder, v *Volume, pre *proto.InodeInfo) {
+	if v == nil || pre == nil {
+		e.Bool(false)
+		e.Bool(false)
+		return
+	}
+	e.Bool(true)
+	e.Uint64(pre.Size)
+	writeTime(e, pre.ModifyTime)
+	writeTime(e, pre.CreateTime)
+	post, _ := v.getAttr(pre.Inode)
+	writePostOpAttr(e, v, post)
+}
+
+func writePostOpFh(e *xdrEncoder, v *Volume, ino uint64) {
+	e.Bool(true)
+	e.Opaque(encodeFileHandle(v.hash, ino))
+}
+
+type sattr3 struct {
+	setMode  bool
+	mode     uint32
+	setUid   bool
+	uid      uint32
+	setGid   bool
+	gid      uint32
+	setSize  bool
+	size     uint64
+	atimeHow uint32
+	atime    uint32
+	mtimeHow uint32
+	mtime    uint32
+}
+
+func decodeSattr(d *xdrDecoder) (s sattr3) {
+	if s.setMode = d.Bool(); s.setMode {
+		s.mode = d.Uint32()
+	}
+	if s.setUid = d.Bool(); s.setUid {
+		s.uid = d.Uint32()
+	}
+	if s.setGid = d.Bool(); s.setGid {
+		s.gid = d.Uint32()
+	}
+	if s.setSize = d.Bool(); s.setSize {
+		s.size = d.Uint64()
+	}
+	if s.atimeHow = d.Uint32(); s.atimeHow == timeClient {
+		s.atime = d.Uint32()
+		d.Uint32()
+	}
+	if s.mtimeHow = d.Uint32(); s.mtimeHow == timeClient {
+		s.mtime = d.Uint32()
+		d.Uint32()
+	}
+	return
+}
+
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return syscall.EINVAL
+	}
+	if len(name) > maxNameLen {
+		return syscall.ENAMETOOLONG
+	}
+	return nil
+}
+
+func isOwner(info *proto.InodeInfo, cred *rpcCredential) bool {
+	return cred.Uid == 0 || cred.Uid == info.Uid
+}
+
+func inGroups(gid uint32, cred *rpcCredential) bool {
+	for _, g := range cred.Gids {
+		if g == gid {
+			return true
+		}
+	}
+	return false
+}
+
+// applySattr sets the attributes of the inode after the permissions are checked the
+// same way as the local filesystems.
+func (s *nfsServer) applySattr(v *Volume, info *proto.InodeInfo, attr sattr3, cred *rpcCredential) (err error) {
+	var valid, mode, uid, gid uint32
+	var atime, mtime int64
+	now := time.Now().Unix()
+	if attr.setMode {
+		if !isOwner(info, cred) {
+			return syscall.EPERM
+		}
+		mode = inodeMode(info.Mode, attr.mode&unixModePermMask)
+		valid |= proto.AttrMode
+	}
+	if attr.setUid && attr.uid != info.Uid {
+		if cred.Uid != 0 {
+			return syscall.EPERM
+		}
+		uid = attr.uid
+		valid |= proto.AttrUid
+	}
+	if attr.setGid && attr.gid != info.Gid {
+		if cred.Uid != 0 && (cred.Uid != info.Uid || !inGroups(attr.gid, cred)) {
+			return syscall.EPERM
+		}
+		gid = attr.gid
+		valid |= proto.AttrGid
+	}
+	if attr.atimeHow != timeDontChange || attr.mtimeHow != timeDontChange {
+		toClientTime := attr.atimeHow == timeClient || attr.mtimeHow == timeClient
+		if !isOwner(info, cred) {
+			if toClientTime {
+				return syscall.EPERM
+			}
+			if err = v.checkPermission(info, cred, proto.ACLWrite); err != nil {
+				return
+			}
+		}
+		switch attr.atimeHow {
+		case timeServer:
+			atime = now
+			valid |= proto.AttrAccessTime
+		case timeClient:
+			atime = int64(attr.atime)
+			valid |= proto.AttrAccessTime
+		}
+		switch attr.mtimeHow {
+		case timeServer:
+			mtime = now
+			valid |= proto.AttrModifyTime
+		case timeClient:
+			mtime = int64(attr.mtime)
+			valid |= proto.AttrModifyTime
+		}
+	}
+	if attr.setSize {
+		if proto.IsDir(info.Mode) {
+			return syscall.EISDIR
+		}
+		if !proto.IsRegular(info.Mode) {
+			return syscall.EINVAL
+		}
+		if err = v.checkPermission(info, cred, proto.ACLWrite); err != nil {
+			return
+		}
+		if err = v.openStream(info.Inode); err != nil {
+			return
+		}
+		if err = v.ec.Truncate(v.mw, 0, info.Inode, int(attr.size), ""); err != nil {
+			log.LogErrorf("applySattr: truncate failed: volume(%v) ino(%v) size(%v) err(%v)",
+				v.name, info.Inode, attr.size, err)
+			return
+		}
+	}
+	if valid == 0 {
+		return
+	}
+	if err = v.mw.Setattr(info.Inode, valid, mode, uid, gid, atime, mtime); err != nil {
+		return
+	}
+	if valid&proto.AttrMode != 0 {
+		err = v.mw.ChmodPosixACL_ll(info.Inode, mode)
+	}
+	return
+}
+
+func (s *nfsServer) getAttr(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	r.reply.Uint32(nfsStatus(err))
+	if err == nil {
+		writeFattr(r.reply, v, info)
+	}
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) setAttr(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	attr := decodeSattr(r.args)
+	guard := r.args.Bool()
+	var guardCtime uint32
+	if guard {
+		guardCtime = r.args.Uint32()
+		r.args.Uint32()
+	}
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	if err == nil && guard && uint32(info.CreateTime.Unix()) != guardCtime {
+		r.reply.Uint32(nfs3ErrNotSync)
+		writeWcc(r.reply, v, info)
+		return rpcAcceptSuccess
+	}
+	if err == nil {
+		err = s.applySattr(v, info, attr, r.cred)
+	}
+	r.reply.Uint32(nfsStatus(err))
+	writeWcc(r.reply, v, info)
+	return rpcAcceptSuccess
+}
+
+// lookupChild looks up the entry in the directory after checking the search permission.
+func (s *nfsServer) lookupChild(v *Volume, dir *proto.InodeInfo, name string, cred *rpcCredential) (info *proto.InodeInfo, err error) {
+	if !proto.IsDir(dir.Mode) {
+		return nil, syscall.ENOTDIR
+	}
+	if err = v.checkPermission(dir, cred, proto.ACLExecute); err != nil {
+		return
+	}
+	var ino uint64
+	switch name {
+	case ".":
+		ino = dir.Inode
+	case "..":
+		ino = v.getParent(dir.Inode)
+	default:
+		if len(name) > maxNameLen {
+			return nil, syscall.ENAMETOOLONG
+		}
+		if ino, _, err = v.mw.Lookup_ll(dir.Inode, name); err != nil {
+			return
+		}
+	}
+	if info, err = v.getAttr(ino); err != nil {
+		return
+	}
+	if proto.IsDir(info.Mode) && name != "." && name != ".." {
+		v.setParent(info.Inode, dir.Inode)
+	}
+	return
+}
+
+func (s *nfsServer) lookup(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	name := r.args.String(maxPathLen)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, dir, err := s.node.resolve(r.call, fh)
+	var info *proto.InodeInfo
+	if err == nil {
+		info, err = s.lookupChild(v, dir, name, r.cred)
+	}
+	r.reply.Uint32(nfsStatus(err))
+	if err != nil {
+		writePostOpAttr(r.reply, v, dir)
+		return rpcAcceptSuccess
+	}
+	r.reply.Opaque(encodeFileHandle(v.hash, info.Inode))
+	writePostOpAttr(r.reply, v, info)
+	writePostOpAttr(r.reply, v, dir)
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) access(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	want := r.args.Uint32()
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	var granted uint16
+	if err == nil {
+		granted, err = v.grantedPermissions(info, r.cred)
+	}
+	r.reply.Uint32(nfsStatus(err))
+	writePostOpAttr(r.reply, v, info)
+	if err != nil {
+		return rpcAcceptSuccess
+	}
+	var result uint32
+	if granted&proto.ACLRead != 0 {
+		result |= access3Read
+	}
+	if granted&proto.ACLWrite != 0 {
+		result |= access3Modify | access3Extend
+		if proto.IsDir(info.Mode) {
+			result |= access3Delete
+		}
+	}
+	if granted&proto.ACLExecute != 0 {
+		if proto.IsDir(info.Mode) {
+			result |= access3Lookup
+		} else {
+			result |= access3Execute
+		}
+	}
+	r.reply.Uint32(result & want)
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) readLink(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	if err == nil && !proto.IsSymlink(info.Mode) {
+		err = syscall.EINVAL
+	}
+	r.reply.Uint32(nfsStatus(err))
+	writePostOpAttr(r.reply, v, info)
+	if err == nil {
+		r.reply.String(string(info.Target))
+	}
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) read(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	offset := r.args.Uint64()
+	count := r.args.Uint32()
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	if err == nil {
+		if proto.IsDir(info.Mode) {
+			err = syscall.EISDIR
+		} else if !proto.IsRegular(info.Mode) {
+			err = syscall.EINVAL
+		} else if !isOwner(info, r.cred) {
+			// the files being executed are read without the read permission
+			if err = v.checkPermission(info, r.cred, proto.ACLRead); err != nil {
+				err = v.checkPermission(info, r.cred, proto.ACLExecute)
+			}
+		}
+	}
+	var data []byte
+	if err == nil && offset < info.Size {
+		if count > maxReadSize {
+			count = maxReadSize
+		}
+		if remain := info.Size - offset; uint64(count) > remain {
+			count = uint32(remain)
+		}
+		if err = v.openStream(info.Inode); err == nil {
+			data = make([]byte, count)
+			var n int
+			n, err = v.ec.Read(info.Inode, data, int(offset), int(count))
+			if err == io.EOF {
+				err = nil
+			}
+			if err != nil {
+				log.LogErrorf("read: volume(%v) ino(%v) offset(%v) count(%v) err(%v)",
+					v.name, info.Inode, offset, count, err)
+			}
+			data = data[:n]
+		}
+	}
+	r.reply.Uint32(nfsStatus(err))
+	writePostOpAttr(r.reply, v, info)
+	if err != nil {
+		return rpcAcceptSuccess
+	}
+	r.reply.Uint32(uint32(len(data)))
+	r.reply.Bool(offset+uint64(len(data)) >= info.Size)
+	r.reply.Opaque(data)
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) write(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	offset := r.args.Uint64()
+	r.args.Uint32() // count, the same as the length of data
+	stable := r.args.Uint32()
+	data := r.args.Opaque(maxWriteSize)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	if err == nil {
+		if proto.IsDir(info.Mode) {
+			err = syscall.EISDIR
+		} else if !proto.IsRegular(info.Mode) {
+			err = syscall.EINVAL
+		} else {
+			err = v.checkPermission(info, r.cred, proto.ACLWrite)
+		}
+	}
+	if err == nil {
+		err = v.openStream(info.Inode)
+	}
+	if err == nil {
+		checkFunc := func() error {
+			if !v.mw.EnableQuota {
+				return nil
+			}
+			if v.ec.UidIsLimited(r.cred.Uid) {
+				return syscall.EDQUOT
+			}
+			var quotaIds []uint32
+			for quotaId := range info.QuotaInfos {
+				quotaIds = append(quotaIds, quotaId)
+			}
+			if v.mw.IsQuotaLimited(quotaIds) {
+				return syscall.EDQUOT
+			}
+			return nil
+		}
+		if _, err = v.ec.Write(info.Inode, int(offset), data, 0, checkFunc); err != nil {
+			log.LogErrorf("write: volume(%v) ino(%v) offset(%v) size(%v) err(%v)",
+				v.name, info.Inode, offset, len(data), err)
+		} else if stable != stableUnstable {
+			err = v.flushStream(info.Inode)
+			stable = stableFileSync
+		}
+	}
+	r.reply.Uint32(nfsStatus(err))
+	writeWcc(r.reply, v, info)
+	if err != nil {
+		return rpcAcceptSuccess
+	}
+	r.reply.Uint32(uint32(len(data)))
+	r.reply.Uint32(stable)
+	r.reply.FixedOpaque(s.node.writeVerf)
+	return rpcAcceptSuccess
+}
+
+// prepareCreate resolves the directory to create the entry in and checks the permissions.
+func (s *nfsServer) prepareCreate(r *nfsRequest, fh []byte, name string) (v *Volume, dir *proto.InodeInfo, err error) {
+	if v, dir, err = s.node.resolve(r.call, fh); err != nil {
+		return
+	}
+	if !proto.IsDir(dir.Mode) {
+		return v, dir, syscall.ENOTDIR
+	}
+	if err = checkName(name); err != nil {
+		return
+	}
+	if err = v.checkPermission(dir, r.cred, proto.ACLWrite|proto.ACLExecute); err != nil {
+		return
+	}
+	if v.mw.EnableQuota && v.ec.UidIsLimited(r.cred.Uid) {
+		err = syscall.EDQUOT
+	}
+	return
+}
+
+// createEntry creates the entry owned by the caller, the group is inherited from the
+// directory with the setgid bit.
+func (s *nfsServer) createEntry(v *Volume, dir *proto.InodeInfo, name string, mode uint32, target []byte, cred *rpcCredential) (*proto.InodeInfo, error) {
+	gid := cred.Gid
+	if os.FileMode(dir.Mode)&os.ModeSetgid != 0 {
+		gid = dir.Gid
+		if proto.IsDir(mode) {
+			mode |= uint32(os.ModeSetgid)
+		}
+	}
+	info, err := v.mw.Create_ll(dir.Inode, name, mode, cred.Uid, gid, target, "")
+	if err != nil {
+		return nil, err
+	}
+	if proto.IsDir(info.Mode) {
+		v.setParent(info.Inode, dir.Inode)
+	}
+	return info, nil
+}
+
+func (s *nfsServer) writeCreateReply(r *nfsRequest, v *Volume, dir, info *proto.InodeInfo, err error) uint32 {
+	r.reply.Uint32(nfsStatus(err))
+	if err == nil {
+		writePostOpFh(r.reply, v, info.Inode)
+		writePostOpAttr(r.reply, v, info)
+	}
+	writeWcc(r.reply, v, dir)
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) create(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	name := r.args.String(maxPathLen)
+	how := r.args.Uint32()
+	var attr sattr3
+	var verf []byte
+	switch how {
+	case createUnchecked, createGuarded:
+		attr = decodeSattr(r.args)
+	case createExclusive:
+		verf = r.args.FixedOpaque(createVerfLen)
+	default:
+		return rpcAcceptGarbageArgs
+	}
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+
+	v, dir, err := s.prepareCreate(r, fh, name)
+	if err != nil {
+		return s.writeCreateReply(r, v, dir, nil, err)
+	}
+	var info *proto.InodeInfo
+	if ino, _, lookupErr := v.mw.Lookup_ll(dir.Inode, name); lookupErr == nil {
+		// the file exists, which is opened by UNCHECKED or the retry of EXCLUSIVE
+		if info, err = v.getAttr(ino); err == nil {
+			switch how {
+			case createGuarded:
+				err = syscall.EEXIST
+			case createExclusive:
+				if !matchCreateVerf(info, verf) {
+					err = syscall.EEXIST
+				}
+			default:
+				if proto.IsDir(info.Mode) {
+					err = syscall.EISDIR
+				} else if attr.setSize {
+					err = s.applySattr(v, info, sattr3{setSize: true, size: attr.size}, r.cred)
+				}
+			}
+		}
+		if err == nil {
+			info, err = v.getAttr(ino)
+		}
+		return s.writeCreateReply(r, v, dir, info, err)
+	} else if lookupErr != syscall.ENOENT {
+		return s.writeCreateReply(r, v, dir, nil, lookupErr)
+	}
+
+	perm := uint32(0o644)
+	if attr.setMode {
+		perm = attr.mode & unixModePermMask
+	}
+	if info, err = s.createEntry(v, dir, name, inodeMode(0, perm), nil, r.cred); err == nil {
+		if how == createExclusive {
+			// keep the verifier in the times, which are set by the following SETATTR of the client
+			atime, mtime := createVerfTimes(verf)
+			err = v.mw.Setattr(info.Inode, proto.AttrAccessTime|proto.AttrModifyTime, 0, 0, 0, atime, mtime)
+		} else {
+			attr.setMode = false
+			err = s.applySattr(v, info, attr, r.cred)
+		}
+	}
+	if err == nil {
+		info, err = v.getAttr(info.Inode)
+	}
+	return s.writeCreateReply(r, v, dir, info, err)
+}
+
+func createVerfTimes(verf []byte) (atime, mtime int64) {
+	d := newXDRDecoder(verf)
+	return int64(d.Uint32()), int64(d.Uint32())
+}
+
+func matchCreateVerf(info *proto.InodeInfo, verf []byte) bool {
+	atime, mtime := createVerfTimes(verf)
+	return info.AccessTime.Unix() == atime && info.ModifyTime.Unix() == mtime
+}
+
+func (s *nfsServer) mkdir(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	name := r.args.String(maxPathLen)
+	attr := decodeSattr(r.args)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, dir, err := s.prepareCreate(r, fh, name)
+	var info *proto.InodeInfo
+	if err == nil {
+		perm := uint32(0o755)
+		if attr.setMode {
+			perm = attr.mode & unixModePermMask
+		}
+		info, err = s.createEntry(v, dir, name, inodeMode(uint32(os.ModeDir), perm), nil, r.cred)
+	}
+	return s.writeCreateReply(r, v, dir, info, err)
+}
+
+func (s *nfsServer) symlink(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	name := r.args.String(maxPathLen)
+	decodeSattr(r.args)
+	target := r.args.String(maxPathLen)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, dir, err := s.prepareCreate(r, fh, name)
+	var info *proto.InodeInfo
+	if err == nil {
+		info, err = s.createEntry(v, dir, name, proto.Mode(os.ModeSymlink|os.ModePerm), []byte(target), r.cred)
+	}
+	return s.writeCreateReply(r, v, dir, info, err)
+}
+
+func (s *nfsServer) mknod(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	name := r.args.String(maxPathLen)
+	ftype := r.args.Uint32()
+	var attr sattr3
+	switch ftype {
+	case nf3Chr, nf3Blk:
+		attr = decodeSattr(r.args)
+		r.args.Uint32() // specdata3
+		r.args.Uint32()
+	case nf3Sock, nf3Fifo:
+		attr = decodeSattr(r.args)
+	}
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, dir, err := s.prepareCreate(r, fh, name)
+	if err != nil {
+		return s.writeCreateReply(r, v, dir, nil, err)
+	}
+	var mode os.FileMode
+	switch ftype {
+	case nf3Sock:
+		mode = os.ModeSocket
+	case nf3Fifo:
+		mode = os.ModeNamedPipe
+	case nf3Chr, nf3Blk:
+		// the device numbers can not be kept by the inodes
+		return s.writeCreateReply(r, v, dir, nil, syscall.ENOTSUP)
+	default:
+		r.reply.Uint32(nfs3ErrBadType)
+		writeWcc(r.reply, v, dir)
+		return rpcAcceptSuccess
+	}
+	perm := uint32(0o644)
+	if attr.setMode {
+		perm = attr.mode & unixModePermMask
+	}
+	info, err := s.createEntry(v, dir, name, inodeMode(proto.Mode(mode), perm), nil, r.cred)
+	return s.writeCreateReply(r, v, dir, info, err)
+}
+
+// checkSticky checks whether the caller is allowed to remove or rename the entry in the
+// directory with the sticky bit.
+func (s *nfsServer) checkSticky(v *Volume, dir *proto.InodeInfo, name string, cred *rpcCredential) error {
+	if os.FileMode(dir.Mode)&os.ModeSticky == 0 || isOwner(dir, cred) {
+		return nil
+	}
+	ino, _, err := v.mw.Lookup_ll(dir.Inode, name)
+	if err != nil {
+		return err
+	}
+	info, err := v.getAttr(ino)
+	if err != nil {
+		return err
+	}
+	if info.Uid != cred.Uid {
+		return syscall.EACCES
+	}
+	return nil
+}
+
+// prepareRemove resolves the directory to remove the entry from and checks the permissions.
+func (s *nfsServer) prepareRemove(r *nfsRequest, fh []byte, name string) (v *Volume, dir *proto.InodeInfo, err error) {
+	if v, dir, err = s.node.resolve(r.call, fh); err != nil {
+		return
+	}
+	if !proto.IsDir(dir.Mode) {
+		return v, dir, syscall.ENOTDIR
+	}
+	if err = checkName(name); err != nil {
+		return
+	}
+	if err = v.checkPermission(dir, r.cred, proto.ACLWrite|proto.ACLExecute); err != nil {
+		return
+	}
+	err = s.checkSticky(v, dir, name, r.cred)
+	return
+}
+
+func (s *nfsServer) remove(r *nfsRequest, isDir bool) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	name := r.args.String(maxPathLen)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, dir, err := s.prepareRemove(r, fh, name)
+	if err == nil {
+		var mode uint32
+		if _, mode, err = v.mw.Lookup_ll(dir.Inode, name); err == nil {
+			if proto.IsDir(mode) && !isDir {
+				err = syscall.EISDIR
+			} else if !proto.IsDir(mode) && isDir {
+				err = syscall.ENOTDIR
+			}
+		}
+	}
+	if err == nil {
+		var info *proto.InodeInfo
+		if info, err = v.mw.Delete_ll(dir.Inode, name, isDir, ""); err == nil && info != nil && !isDir {
+			v.evictStream(info.Inode)
+			_ = v.mw.Evict(info.Inode, "")
+		}
+	}
+	r.reply.Uint32(nfsStatus(err))
+	writeWcc(r.reply, v, dir)
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) rename(r *nfsRequest) uint32 {
+	fromFh := r.args.Opaque(maxFileHandleLen)
+	fromName := r.args.String(maxPathLen)
+	toFh := r.args.Opaque(maxFileHandleLen)
+	toName := r.args.String(maxPathLen)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	fromVol, fromDir, err := s.prepareRemove(r, fromFh, fromName)
+	var toVol *Volume
+	var toDir *proto.InodeInfo
+	if err == nil {
+		if toVol, toDir, err = s.prepareCreate(r, toFh, toName); err == nil && toVol != fromVol {
+			err = syscall.EXDEV
+		}
+	}
+	if err == nil {
+		if err = s.checkSticky(toVol, toDir, toName, r.cred); err == syscall.ENOENT {
+			err = nil
+		}
+	}
+	if err == nil {
+		err = fromVol.mw.Rename_ll(fromDir.Inode, fromName, toDir.Inode, toName, "", "", true)
+	}
+	r.reply.Uint32(nfsStatus(err))
+	writeWcc(r.reply, fromVol, fromDir)
+	writeWcc(r.reply, toVol, toDir)
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) link(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	dirFh := r.args.Opaque(maxFileHandleLen)
+	name := r.args.String(maxPathLen)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	var dirVol *Volume
+	var dir *proto.InodeInfo
+	if err == nil {
+		if dirVol, dir, err = s.prepareCreate(r, dirFh, name); err == nil && dirVol != v {
+			err = syscall.EXDEV
+		}
+	}
+	if err == nil && proto.IsDir(info.Mode) {
+		err = syscall.EISDIR
+	}
+	if err == nil {
+		_, err = v.mw.Link(dir.Inode, name, info.Inode, "")
+	}
+	r.reply.Uint32(nfsStatus(err))
+	if v != nil && info != nil {
+		info, _ = v.getAttr(info.Inode)
+	}
+	writePostOpAttr(r.reply, v, info)
+	writeWcc(r.reply, dirVol, dir)
+	return rpcAcceptSuccess
+}
+
+type dirEntry struct {
+	name   string
+	ino    uint64
+	cookie uint64
+}
+
+// listDir lists the entries of the directory after the cookie until next returns false.
+// The cookies of the entries are the hashes of the names mapped back by the volume, so
+// the listing continues correctly even if the directory is changed in between.
+func (v *Volume) listDir(dir, cookie uint64, next func(e dirEntry) bool) (eof bool, err error) {
+	from := ""
+	switch cookie {
+	case 0:
+		if !next(dirEntry{name: ".", ino: dir, cookie: 1}) {
+			return false, nil
+		}
+		fallthrough
+	case 1:
+		if !next(dirEntry{name: "..", ino: v.getParent(dir), cookie: 2}) {
+			return false, nil
+		}
+	case 2:
+	default:
+		var ok bool
+		if from, ok = v.getCookie(dir, cookie); !ok {
+			return false, errBadCookie
+		}
+	}
+	for {
+		children, err := v.mw.ReadDirLimit_ll(dir, from, readDirLimit)
+		if err != nil {
+			return false, err
+		}
+		fetched := len(children)
+		if from != "" && fetched > 0 && children[0].Name == from {
+			children = children[1:]
+		}
+		for _, child := range children {
+			if !next(dirEntry{name: child.Name, ino: child.Inode, cookie: v.putCookie(dir, child.Name)}) {
+				return false, nil
+			}
+		}
+		if fetched < readDirLimit {
+			return true, nil
+		}
+		from = children[len(children)-1].Name
+	}
+}
+
+func (s *nfsServer) readDir(r *nfsRequest, plus bool) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	cookie := r.args.Uint64()
+	r.args.FixedOpaque(cookieVerfLen)
+	dirCount := r.args.Uint32()
+	maxCount := dirCount
+	if plus {
+		maxCount = r.args.Uint32()
+	}
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, dir, err := s.node.resolve(r.call, fh)
+	if err == nil {
+		if !proto.IsDir(dir.Mode) {
+			err = syscall.ENOTDIR
+		} else {
+			err = v.checkPermission(dir, r.cred, proto.ACLRead)
+		}
+	}
+
+	var (
+		entries  []dirEntry
+		size     = readDirOverhead
+		dirSize  = 0
+		eof      bool
+		tooSmall bool
+	)
+	if err == nil {
+		eof, err = v.listDir(dir.Inode, cookie, func(e dirEntry) bool {
+			entrySize := entryOverhead + len(e.name) + xdrPad(len(e.name))
+			dirEntrySize := entrySize
+			if plus {
+				entrySize = entryPlusOverhead + len(e.name) + xdrPad(len(e.name))
+			}
+			if size+entrySize > int(maxCount) || (plus && dirSize+dirEntrySize > int(dirCount)) {
+				tooSmall = len(entries) == 0
+				return false
+			}
+			size += entrySize
+			dirSize += dirEntrySize
+			entries = append(entries, e)
+			return true
+		})
+	}
+	if err == nil && tooSmall {
+		err = errTooSmall
+	}
+	r.reply.Uint32(readDirStatus(err))
+	writePostOpAttr(r.reply, v, dir)
+	if err != nil {
+		return rpcAcceptSuccess
+	}
+	r.reply.FixedOpaque(make([]byte, cookieVerfLen))
+
+	var attrs map[uint64]*proto.InodeInfo
+	if plus {
+		inodes := make([]uint64, 0, len(entries))
+		for _, e := range entries {
+			inodes = append(inodes, e.ino)
+		}
+		attrs = make(map[uint64]*proto.InodeInfo, len(entries))
+		for _, info := range v.mw.BatchInodeGet(inodes) {
+			attrs[info.Inode] = info
+		}
+	}
+	for _, e := range entries {
+		r.reply.Bool(true)
+		r.reply.Uint64(e.ino)
+		r.reply.String(e.name)
+		r.reply.Uint64(e.cookie)
+		if plus {
+			info := attrs[e.ino]
+			writePostOpAttr(r.reply, v, info)
+			if info != nil && e.name != "." && e.name != ".." && proto.IsDir(info.Mode) {
+				v.setParent(info.Inode, dir.Inode)
+			}
+			writePostOpFh(r.reply, v, e.ino)
+		}
+	}
+	r.reply.Bool(false)
+	r.reply.Bool(eof)
+	return rpcAcceptSuccess
+}
+
+func readDirStatus(err error) uint32 {
+	switch err {
+	case errBadCookie:
+		return nfs3ErrBadCookie
+	case errTooSmall:
+		return nfs3ErrTooSmall
+	default:
+		return nfsStatus(err)
+	}
+}
+
+func (s *nfsServer) fsStat(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	r.reply.Uint32(nfsStatus(err))
+	writePostOpAttr(r.reply, v, info)
+	if err != nil {
+		return rpcAcceptSuccess
+	}
+	total, used, inodeCount := v.mw.Statfs()
+	var free uint64
+	if total > used {
+		free = total - used
+	}
+	var freeFiles uint64
+	if inodeCount < fsStatTotalFiles {
+		freeFiles = fsStatTotalFiles - inodeCount
+	}
+	r.reply.Uint64(total)
+	r.reply.Uint64(free)
+	r.reply.Uint64(free)
+	r.reply.Uint64(fsStatTotalFiles)
+	r.reply.Uint64(freeFiles)
+	r.reply.Uint64(freeFiles)
+	r.reply.Uint32(0) // invarsec
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) fsInfo(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	r.reply.Uint32(nfsStatus(err))
+	writePostOpAttr(r.reply, v, info)
+	if err != nil {
+		return rpcAcceptSuccess
+	}
+	r.reply.Uint32(maxReadSize)
+	r.reply.Uint32(maxReadSize)
+	r.reply.Uint32(fsBlockSize)
+	r.reply.Uint32(maxWriteSize)
+	r.reply.Uint32(maxWriteSize)
+	r.reply.Uint32(fsBlockSize)
+	r.reply.Uint32(prefDirSize)
+	r.reply.Uint64(maxFileSize)
+	r.reply.Uint32(1) // time delta of seconds
+	r.reply.Uint32(0)
+	r.reply.Uint32(fsf3Link | fsf3Symlink | fsf3Homogeneous | fsf3CanSetTime)
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) pathConf(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	r.reply.Uint32(nfsStatus(err))
+	writePostOpAttr(r.reply, v, info)
+	if err != nil {
+		return rpcAcceptSuccess
+	}
+	r.reply.Uint32(maxLinkCount)
+	r.reply.Uint32(maxNameLen)
+	r.reply.Bool(true)  // no_trunc
+	r.reply.Bool(true)  // chown_restricted
+	r.reply.Bool(false) // case_insensitive
+	r.reply.Bool(true)  // case_preserving
+	return rpcAcceptSuccess
+}
+
+func (s *nfsServer) commit(r *nfsRequest) uint32 {
+	fh := r.args.Opaque(maxFileHandleLen)
+	r.args.Uint64()
+	r.args.Uint32()
+	if r.args.Err() != nil {
+		return rpcAcceptGarbageArgs
+	}
+	v, info, err := s.node.resolve(r.call, fh)
+	if err == nil {
+		err = v.flushStream(info.Inode)
+	}
+	r.reply.Uint32(nfsStatus(err))
+	writeWcc(r.reply, v, info)
+	if err == nil {
+		r.reply.FixedOpaque(s.node.writeVerf)
+	}
+	return rpcAcceptSuccess
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNfsMode(t *testing.T) {
+	mode := proto.Mode(os.ModeDir | os.ModeSetgid | 0o750)
+	require.EqualValues(t, nf3Dir, nfsType(mode))
+	require.EqualValues(t, 0o2750, unixMode(mode))
+
+	mode = inodeMode(mode, 0o1700)
+	require.True(t, proto.IsDir(mode))
+	require.EqualValues(t, 0o1700, unixMode(mode))
+
+	require.EqualValues(t, nf3Lnk, nfsType(proto.Mode(os.ModeSymlink|0o777)))
+	require.EqualValues(t, nf3Fifo, nfsType(proto.Mode(os.ModeNamedPipe)))
+	require.EqualValues(t, nf3Reg, nfsType(inodeMode(0, 0o644)))
+}
+
+func TestNfsStatus(t *testing.T) {
+	require.EqualValues(t, nfs3OK, nfsStatus(nil))
+	require.EqualValues(t, nfs3ErrStale, nfsStatus(errStale))
+	require.EqualValues(t, nfs3ErrNotEmpty, nfsStatus(syscall.ENOTEMPTY))
+	require.EqualValues(t, nfs3ErrDQuot, nfsStatus(syscall.EDQUOT))
+	require.EqualValues(t, nfs3ErrIO, nfsStatus(os.ErrClosed))
+	require.EqualValues(t, nfs3ErrBadCookie, readDirStatus(errBadCookie))
+}
+
+func TestNameCookie(t *testing.T) {
+	for _, name := range []string{"", "a", "file", "dir"} {
+		require.Greater(t, nameCookie(name), uint64(2))
+	}
+	require.NoError(t, checkName("file"))
+	require.Equal(t, syscall.EINVAL, checkName(".."))
+	require.Equal(t, syscall.EINVAL, checkName("a/b"))
+}
+
+func TestCredential(t *testing.T) {
+	n := &NfsNode{rootSquash: true, anonUid: defaultAnonUid, anonGid: defaultAnonGid}
+	cred := n.credential(&rpcCall{Cred: rpcCredential{Flavor: rpcAuthSys, Uid: 0, Gid: 0}})
+	require.EqualValues(t, defaultAnonUid, cred.Uid)
+
+	cred = n.credential(&rpcCall{Cred: rpcCredential{Flavor: rpcAuthSys, Uid: 1000, Gid: 100, Gids: []uint32{200}}})
+	require.EqualValues(t, 1000, cred.Uid)
+	require.Equal(t, []uint32{100, 200}, cred.Gids)
+
+	cred = n.credential(&rpcCall{Cred: rpcCredential{Flavor: rpcAuthNone}})
+	require.EqualValues(t, defaultAnonUid, cred.Uid)
+}
+
+func TestParseExports(t *testing.T) {
+	exports, err := parseExports(nil)
+	require.NoError(t, err)
+	require.Empty(t, exports)
+
+	exports, err = parseExports([]interface{}{map[string]interface{}{"volName": "ltptest", "owner": "ltptest"}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"ltptest": "ltptest"}, exports)
+
+	// the owner is required to mount the volume
+	_, err = parseExports([]interface{}{map[string]interface{}{"volName": "ltptest"}})
+	require.Error(t, err)
+	_, err = parseExports([]interface{}{"ltptest"})
+	require.Error(t, err)
+}
@@ -0,0 +1,305 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// ONC RPC of RFC 5531 over TCP with the record marking.
+const (
+	rpcVersion = 2
+
+	rpcMsgCall  = 0
+	rpcMsgReply = 1
+
+	rpcReplyAccepted = 0
+	rpcReplyDenied   = 1
+
+	rpcAcceptSuccess      = 0
+	rpcAcceptProgUnavail  = 1
+	rpcAcceptProgMismatch = 2
+	rpcAcceptProcUnavail  = 3
+	rpcAcceptGarbageArgs  = 4
+	rpcAcceptSystemErr    = 5
+
+	rpcRejectMismatch  = 0
+	rpcRejectAuthError = 1
+
+	rpcAuthNone = 0
+	rpcAuthSys  = 1
+
+	rpcAuthBadCred = 1
+	rpcAuthTooWeak = 5
+
+	rpcLastFragment = 1 << 31
+	maxAuthBodyLen  = 400
+	maxAuthSysGids  = 16
+)
+
+// rpcCredential is the caller of the AUTH_SYS credential.
+type rpcCredential struct {
+	Flavor  uint32
+	Machine string
+	Uid     uint32
+	Gid     uint32
+	Gids    []uint32
+}
+
+type rpcCall struct {
+	Xid    uint32
+	Prog   uint32
+	Vers   uint32
+	Proc   uint32
+	Cred   rpcCredential
+	Args   *xdrDecoder
+	Remote net.Addr
+}
+
+// rpcProgram serves the procedures of an RPC program. The results are encoded to the
+// reply if rpcAcceptSuccess is returned.
+type rpcProgram interface {
+	versions() (low, high uint32)
+	handle(call *rpcCall, reply *xdrEncoder) (acceptStat uint32)
+}
+
+type rpcServer struct {
+	programs map[uint32]rpcProgram
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	connLock sync.Mutex
+	wg       sync.WaitGroup
+}
+
+func newRPCServer() *rpcServer {
+	return &rpcServer{
+		programs: make(map[uint32]rpcProgram),
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+func (s *rpcServer) register(prog uint32, p rpcProgram) {
+	s.programs[prog] = p
+}
+
+func (s *rpcServer) serve(l net.Listener) {
+	s.listener = l
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				log.LogInfof("rpcServer: stop accepting connections: err(%v)", err)
+				return
+			}
+			s.connLock.Lock()
+			s.conns[conn] = struct{}{}
+			s.connLock.Unlock()
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serveConn(conn)
+				s.connLock.Lock()
+				delete(s.conns, conn)
+				s.connLock.Unlock()
+			}()
+		}
+	}()
+}
+
+func (s *rpcServer) stop() {
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	s.connLock.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.connLock.Unlock()
+	s.wg.Wait()
+}
+
+func (s *rpcServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	var (
+		reader    = bufio.NewReader(conn)
+		writeLock sync.Mutex
+		pending   sync.WaitGroup
+		limit     = make(chan struct{}, maxConnCalls)
+	)
+	defer pending.Wait()
+	for {
+		record, err := readRecord(reader)
+		if err != nil {
+			if err != io.EOF {
+				log.LogWarnf("serveConn: read record failed: remote(%v) err(%v)", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		limit <- struct{}{}
+		pending.Add(1)
+		go func() {
+			defer func() {
+				<-limit
+				pending.Done()
+			}()
+			reply := s.dispatch(record, conn.RemoteAddr())
+			if reply == nil {
+				return
+			}
+			writeLock.Lock()
+			defer writeLock.Unlock()
+			if err := writeRecord(conn, reply); err != nil {
+				log.LogWarnf("serveConn: write record failed: remote(%v) err(%v)", conn.RemoteAddr(), err)
+				_ = conn.Close()
+			}
+		}()
+	}
+}
+
+// dispatch decodes the call and serves it by the registered program. Nil is returned for
+// the malformed messages which are not replied.
+func (s *rpcServer) dispatch(record []byte, remote net.Addr) []byte {
+	d := newXDRDecoder(record)
+	call := &rpcCall{Args: d, Remote: remote}
+	call.Xid = d.Uint32()
+	msgType := d.Uint32()
+	rpcVers := d.Uint32()
+	call.Prog = d.Uint32()
+	call.Vers = d.Uint32()
+	call.Proc = d.Uint32()
+	call.Cred.Flavor = d.Uint32()
+	credBody := d.Opaque(maxAuthBodyLen)
+	d.Uint32()
+	d.Opaque(maxAuthBodyLen)
+	if d.Err() != nil || msgType != rpcMsgCall {
+		log.LogWarnf("dispatch: malformed call: remote(%v) xid(%v) type(%v) err(%v)", remote, call.Xid, msgType, d.Err())
+		return nil
+	}
+
+	reply := &xdrEncoder{}
+	reply.Uint32(call.Xid)
+	reply.Uint32(rpcMsgReply)
+	if rpcVers != rpcVersion {
+		reply.Uint32(rpcReplyDenied)
+		reply.Uint32(rpcRejectMismatch)
+		reply.Uint32(rpcVersion)
+		reply.Uint32(rpcVersion)
+		return reply.Bytes()
+	}
+	switch call.Cred.Flavor {
+	case rpcAuthNone:
+	case rpcAuthSys:
+		if err := parseAuthSys(credBody, &call.Cred); err != nil {
+			log.LogWarnf("dispatch: invalid auth sys credential: remote(%v) xid(%v) err(%v)", remote, call.Xid, err)
+			reply.Uint32(rpcReplyDenied)
+			reply.Uint32(rpcRejectAuthError)
+			reply.Uint32(rpcAuthBadCred)
+			return reply.Bytes()
+		}
+	default:
+		reply.Uint32(rpcReplyDenied)
+		reply.Uint32(rpcRejectAuthError)
+		reply.Uint32(rpcAuthTooWeak)
+		return reply.Bytes()
+	}
+
+	reply.Uint32(rpcReplyAccepted)
+	reply.Uint32(rpcAuthNone)
+	reply.Uint32(0)
+	prog, ok := s.programs[call.Prog]
+	if !ok {
+		reply.Uint32(rpcAcceptProgUnavail)
+		return reply.Bytes()
+	}
+	if low, high := prog.versions(); call.Vers < low || call.Vers > high {
+		reply.Uint32(rpcAcceptProgMismatch)
+		reply.Uint32(low)
+		reply.Uint32(high)
+		return reply.Bytes()
+	}
+	results := &xdrEncoder{}
+	stat := s.handle(prog, call, results)
+	reply.Uint32(stat)
+	if stat == rpcAcceptSuccess {
+		reply.Write(results.Bytes())
+	}
+	return reply.Bytes()
+}
+
+func (s *rpcServer) handle(prog rpcProgram, call *rpcCall, results *xdrEncoder) (stat uint32) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.LogErrorf("handle: panic: prog(%v) vers(%v) proc(%v) remote(%v) r(%v)",
+				call.Prog, call.Vers, call.Proc, call.Remote, r)
+			stat = rpcAcceptSystemErr
+		}
+	}()
+	return prog.handle(call, results)
+}
+
+func parseAuthSys(body []byte, cred *rpcCredential) error {
+	d := newXDRDecoder(body)
+	d.Uint32() // stamp
+	cred.Machine = d.String(255)
+	cred.Uid = d.Uint32()
+	cred.Gid = d.Uint32()
+	n := d.Uint32()
+	if n > maxAuthSysGids {
+		return fmt.Errorf("too many gids %v", n)
+	}
+	for i := uint32(0); i < n; i++ {
+		cred.Gids = append(cred.Gids, d.Uint32())
+	}
+	return d.Err()
+}
+
+func readRecord(r io.Reader) (record []byte, err error) {
+	var header [4]byte
+	for {
+		if _, err = io.ReadFull(r, header[:]); err != nil {
+			return
+		}
+		marker := binary.BigEndian.Uint32(header[:])
+		size := int(marker &^ rpcLastFragment)
+		if len(record)+size > maxRecordSize {
+			return nil, fmt.Errorf("record too large %v", len(record)+size)
+		}
+		fragment := make([]byte, size)
+		if _, err = io.ReadFull(r, fragment); err != nil {
+			return
+		}
+		record = append(record, fragment...)
+		if marker&rpcLastFragment != 0 {
+			return
+		}
+	}
+}
+
+func writeRecord(w io.Writer, record []byte) error {
+	buf := make([]byte, 4+len(record))
+	binary.BigEndian.PutUint32(buf, rpcLastFragment|uint32(len(record)))
+	copy(buf[4:], record)
+	_, err := w.Write(buf)
+	return err
+}
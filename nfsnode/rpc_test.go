@@ -0,0 +1,152 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestXDRCodec(t *testing.T) {
+	e := &xdrEncoder{}
+	e.Uint32(7)
+	e.Uint64(1 << 40)
+	e.Bool(true)
+	e.String("abcde")
+	e.FixedOpaque([]byte{1, 2})
+	require.Equal(t, 0, e.Len()%4)
+
+	d := newXDRDecoder(e.Bytes())
+	require.EqualValues(t, 7, d.Uint32())
+	require.EqualValues(t, 1<<40, d.Uint64())
+	require.True(t, d.Bool())
+	require.Equal(t, "abcde", d.String(16))
+	require.Equal(t, []byte{1, 2}, d.FixedOpaque(2))
+	require.NoError(t, d.Err())
+
+	d.Uint32()
+	require.Equal(t, errXDRShort, d.Err())
+
+	// longer than the limit
+	d = newXDRDecoder(e.Bytes()[16:])
+	d.String(4)
+	require.Error(t, d.Err())
+}
+
+func TestFileHandle(t *testing.T) {
+	hash := volumeHash("ltptest")
+	fh := encodeFileHandle(hash, 1024)
+	require.Len(t, fh, fileHandleLen)
+	volHash, ino, err := decodeFileHandle(fh)
+	require.NoError(t, err)
+	require.Equal(t, hash, volHash)
+	require.EqualValues(t, 1024, ino)
+
+	_, _, err = decodeFileHandle(fh[:8])
+	require.Equal(t, errBadHandle, err)
+	require.Equal(t, fh, encodeFileHandle(volumeHash("ltptest"), 1024))
+}
+
+type testProgram struct{}
+
+func (p *testProgram) versions() (low, high uint32) {
+	return 3, 3
+}
+
+func (p *testProgram) handle(call *rpcCall, reply *xdrEncoder) uint32 {
+	if call.Proc != 0 {
+		return rpcAcceptProcUnavail
+	}
+	reply.Uint32(call.Cred.Uid)
+	reply.Uint32(uint32(len(call.Cred.Gids)))
+	return rpcAcceptSuccess
+}
+
+func encodeCall(xid, prog, vers, proc uint32, cred *rpcCredential) []byte {
+	e := &xdrEncoder{}
+	e.Uint32(xid)
+	e.Uint32(rpcMsgCall)
+	e.Uint32(rpcVersion)
+	e.Uint32(prog)
+	e.Uint32(vers)
+	e.Uint32(proc)
+	if cred == nil {
+		e.Uint32(rpcAuthNone)
+		e.Opaque(nil)
+	} else {
+		body := &xdrEncoder{}
+		body.Uint32(0)
+		body.String(cred.Machine)
+		body.Uint32(cred.Uid)
+		body.Uint32(cred.Gid)
+		body.Uint32(uint32(len(cred.Gids)))
+		for _, gid := range cred.Gids {
+			body.Uint32(gid)
+		}
+		e.Uint32(rpcAuthSys)
+		e.Opaque(body.Bytes())
+	}
+	e.Uint32(rpcAuthNone)
+	e.Opaque(nil)
+	return e.Bytes()
+}
+
+func TestRPCServer(t *testing.T) {
+	s := newRPCServer()
+	s.register(nfsProgram, &testProgram{})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s.serve(l)
+	defer s.stop()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	call := func(record []byte) *xdrDecoder {
+		require.NoError(t, writeRecord(conn, record))
+		reply, err := readRecord(conn)
+		require.NoError(t, err)
+		d := newXDRDecoder(reply)
+		d.Uint32() // xid
+		require.EqualValues(t, rpcMsgReply, d.Uint32())
+		return d
+	}
+	accepted := func(d *xdrDecoder) uint32 {
+		require.EqualValues(t, rpcReplyAccepted, d.Uint32())
+		d.Uint32()
+		d.Opaque(maxAuthBodyLen)
+		return d.Uint32()
+	}
+
+	cred := &rpcCredential{Machine: "client", Uid: 1000, Gid: 100, Gids: []uint32{100, 200}}
+	d := call(encodeCall(1, nfsProgram, 3, 0, cred))
+	require.EqualValues(t, rpcAcceptSuccess, accepted(d))
+	require.EqualValues(t, 1000, d.Uint32())
+	require.EqualValues(t, 2, d.Uint32())
+
+	// NFSv4 is not served
+	d = call(encodeCall(2, nfsProgram, 4, 0, nil))
+	require.EqualValues(t, rpcAcceptProgMismatch, accepted(d))
+	require.EqualValues(t, 3, d.Uint32())
+	require.EqualValues(t, 3, d.Uint32())
+
+	d = call(encodeCall(3, mountProgram, 3, 0, nil))
+	require.EqualValues(t, rpcAcceptProgUnavail, accepted(d))
+
+	d = call(encodeCall(4, nfsProgram, 3, 1, nil))
+	require.EqualValues(t, rpcAcceptProcUnavail, accepted(d))
+}
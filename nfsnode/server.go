@@ -0,0 +1,302 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/cubefs/cubefs/cmd/common"
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/config"
+	"github.com/cubefs/cubefs/util/exporter"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+var errStale = errors.New("stale file handle")
+
+// NfsNode is the gateway exporting the volumes by NFSv3, with the MOUNT program served
+// on the same port. NFSv4 is not served, the clients requesting it get PROG_MISMATCH and
+// mount with vers=3. Only the volumes configured are exported, each mounted with the
+// owner validated by the master. The users of the clients are trusted by the AUTH_SYS
+// credentials with the root squashed by default, and checked by the permission bits and
+// the POSIX ACLs, while the clients are checked by the IP ACLs of the volumes.
+type NfsNode struct {
+	listen      string
+	masters     []string
+	exports     map[string]string // owners of the volumes exported
+	rootSquash  bool
+	allSquash   bool
+	anonUid     uint32
+	anonGid     uint32
+	enableQuota bool
+	writeVerf   []byte // changes at every start so that the clients resend the uncommitted data
+
+	volLock sync.RWMutex
+	volumes map[uint64]*Volume
+
+	mountLock sync.Mutex
+	mounts    map[string]map[string]struct{} // dirs mounted by each client
+
+	rpc     *rpcServer
+	control common.Control
+}
+
+func NewServer() *NfsNode {
+	return &NfsNode{}
+}
+
+func (n *NfsNode) Start(cfg *config.Config) (err error) {
+	runtime.GOMAXPROCS(runtime.NumCPU())
+	return n.control.Start(n, cfg, doStart)
+}
+
+func (n *NfsNode) Shutdown() {
+	n.control.Shutdown(n, doShutdown)
+}
+
+func (n *NfsNode) Sync() {
+	n.control.Sync()
+}
+
+func doStart(s common.Server, cfg *config.Config) (err error) {
+	n, ok := s.(*NfsNode)
+	if !ok {
+		return errors.New("Invalid node Type!")
+	}
+	if err = n.parseConfig(cfg); err != nil {
+		return
+	}
+	n.volumes = make(map[uint64]*Volume)
+	n.mounts = make(map[string]map[string]struct{})
+	n.writeVerf = make([]byte, 8)
+	if _, err = rand.Read(n.writeVerf); err != nil {
+		return
+	}
+
+	var listener net.Listener
+	if listener, err = net.Listen("tcp", ":"+n.listen); err != nil {
+		log.LogErrorf("doStart: listen failed: port(%v) err(%v)", n.listen, err)
+		return
+	}
+	n.rpc = newRPCServer()
+	n.rpc.register(nfsProgram, &nfsServer{node: n})
+	n.rpc.register(mountProgram, &mountServer{node: n})
+	n.rpc.serve(listener)
+
+	exporter.Init(ModuleName, cfg)
+	log.LogInfo("nfsnode start successfully")
+	return
+}
+
+func doShutdown(s common.Server) {
+	n, ok := s.(*NfsNode)
+	if !ok {
+		return
+	}
+	if n.rpc != nil {
+		n.rpc.stop()
+	}
+	n.volLock.Lock()
+	for _, v := range n.volumes {
+		v.Close()
+	}
+	n.volumes = make(map[uint64]*Volume)
+	n.volLock.Unlock()
+}
+
+func (n *NfsNode) parseConfig(cfg *config.Config) (err error) {
+	// parse listen
+	listen := cfg.GetString(configListen)
+	if len(listen) == 0 {
+		listen = defaultListen
+	}
+	if match := regexpListen.MatchString(listen); !match {
+		err = errors.New("invalid listen configuration")
+		return
+	}
+	n.listen = listen
+	log.LogInfof("loadConfig: setup config: %v(%v)", configListen, listen)
+
+	// parse master config
+	masters := cfg.GetStringSlice(configMasterAddr)
+	if len(masters) == 0 {
+		return config.NewIllegalConfigError(configMasterAddr)
+	}
+	n.masters = masters
+	log.LogInfof("loadConfig: setup config: %v(%v)", configMasterAddr, strings.Join(masters, ","))
+
+	// parse exports
+	if n.exports, err = parseExports(cfg.GetSlice(configExports)); err != nil {
+		return
+	}
+	if len(n.exports) == 0 {
+		log.LogWarnf("loadConfig: no volume exported, set %v to export the volumes", configExports)
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configExports, n.exportList())
+
+	// parse squash
+	n.rootSquash = cfg.GetBoolWithDefault(configRootSquash, true)
+	n.allSquash = cfg.GetBool(configAllSquash)
+	n.anonUid = uint32(cfg.GetInt64WithDefault(configAnonUid, defaultAnonUid))
+	n.anonGid = uint32(cfg.GetInt64WithDefault(configAnonGid, defaultAnonGid))
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v) %v(%v) %v(%v)", configRootSquash, n.rootSquash,
+		configAllSquash, n.allSquash, configAnonUid, n.anonUid, configAnonGid, n.anonGid)
+
+	n.enableQuota = cfg.GetBool(configEnableQuota)
+	log.LogInfof("loadConfig: setup config: %v(%v)", configEnableQuota, n.enableQuota)
+	return
+}
+
+// parseExports parses the volumes exported, each with the owner to mount it.
+func parseExports(items []interface{}) (exports map[string]string, err error) {
+	exports = make(map[string]string)
+	for _, item := range items {
+		export, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %v configuration: %v", configExports, item)
+		}
+		name, _ := export[configExportVolName].(string)
+		owner, _ := export[configExportOwner].(string)
+		if name == "" || owner == "" {
+			return nil, fmt.Errorf("invalid %v configuration: %v and %v are required: %v",
+				configExports, configExportVolName, configExportOwner, item)
+		}
+		exports[name] = owner
+	}
+	return
+}
+
+// exportList returns the names of the volumes exported.
+func (n *NfsNode) exportList() (names []string) {
+	for name := range n.exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+func (n *NfsNode) getVolume(name string) (v *Volume, err error) {
+	owner, ok := n.exports[name]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	hash := volumeHash(name)
+	n.volLock.RLock()
+	v, ok = n.volumes[hash]
+	n.volLock.RUnlock()
+	if ok {
+		return
+	}
+
+	n.volLock.Lock()
+	defer n.volLock.Unlock()
+	if v, ok = n.volumes[hash]; ok {
+		return
+	}
+	if v, err = NewVolume(name, owner, n.masters, n.enableQuota); err != nil {
+		return nil, syscall.ENOENT
+	}
+	n.volumes[hash] = v
+	log.LogInfof("getVolume: volume(%v) loaded", name)
+	return
+}
+
+// getVolumeByHash finds the volume of the file handle, which may be issued before the
+// restart of the gateway or by another gateway.
+func (n *NfsNode) getVolumeByHash(hash uint64) (v *Volume, err error) {
+	n.volLock.RLock()
+	v, ok := n.volumes[hash]
+	n.volLock.RUnlock()
+	if ok {
+		return
+	}
+	for _, name := range n.exportList() {
+		if volumeHash(name) == hash {
+			return n.getVolume(name)
+		}
+	}
+	return nil, errStale
+}
+
+// resolve returns the volume and the inode of the file handle if the client is allowed.
+func (n *NfsNode) resolve(call *rpcCall, fh []byte) (v *Volume, info *proto.InodeInfo, err error) {
+	hash, ino, err := decodeFileHandle(fh)
+	if err != nil {
+		return
+	}
+	if v, err = n.getVolumeByHash(hash); err != nil {
+		return
+	}
+	if !v.checkIP(remoteIP(call.Remote)) {
+		return nil, nil, syscall.EACCES
+	}
+	if info, err = v.getAttr(ino); err == syscall.ENOENT {
+		err = errStale
+	}
+	return
+}
+
+// credential returns the user of the call with the squash applied.
+func (n *NfsNode) credential(call *rpcCall) *rpcCredential {
+	cred := call.Cred
+	if cred.Flavor != rpcAuthSys || n.allSquash || (n.rootSquash && cred.Uid == 0) {
+		return &rpcCredential{Flavor: cred.Flavor, Uid: n.anonUid, Gid: n.anonGid, Gids: []uint32{n.anonGid}}
+	}
+	cred.Gids = append([]uint32{cred.Gid}, cred.Gids...)
+	return &cred
+}
+
+func (n *NfsNode) addMount(remote net.Addr, dir string) {
+	n.mountLock.Lock()
+	defer n.mountLock.Unlock()
+	host := remoteIP(remote)
+	if n.mounts[host] == nil {
+		n.mounts[host] = make(map[string]struct{})
+	}
+	n.mounts[host][dir] = struct{}{}
+}
+
+func (n *NfsNode) removeMount(remote net.Addr, dir string) {
+	n.mountLock.Lock()
+	defer n.mountLock.Unlock()
+	host := remoteIP(remote)
+	if dir == "" {
+		delete(n.mounts, host)
+		return
+	}
+	delete(n.mounts[host], dir)
+	if len(n.mounts[host]) == 0 {
+		delete(n.mounts, host)
+	}
+}
+
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,295 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/stream"
+	"github.com/cubefs/cubefs/sdk/master"
+	"github.com/cubefs/cubefs/sdk/meta"
+	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+type dirCookie struct {
+	dir    uint64
+	cookie uint64
+}
+
+type ipCheck struct {
+	allowed bool
+	expire  time.Time
+}
+
+// Volume is an exported volume accessed by the meta wrapper and the extent client.
+// NFS is stateless, so the streams of the files are opened on demand and closed after
+// being idle for a while, and the data written unstably is flushed by COMMIT.
+type Volume struct {
+	name string
+	hash uint64
+	mw   *meta.MetaWrapper
+	ec   *stream.ExtentClient
+	mc   *master.MasterClient
+
+	streamLock sync.Mutex
+	streams    map[uint64]time.Time
+
+	cacheLock sync.Mutex
+	parents   map[uint64]uint64    // parents of the directories looked up, for '..'
+	cookies   map[dirCookie]string // names of the readdir cookies
+
+	ipLock   sync.Mutex
+	ipChecks map[string]*ipCheck
+
+	closeC chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewVolume mounts the volume with the owner, which is validated by the master.
+func NewVolume(name, owner string, masters []string, enableQuota bool) (v *Volume, err error) {
+	metaConfig := &meta.MetaConfig{
+		Volume:        name,
+		Owner:         owner,
+		Masters:       masters,
+		Authenticate:  false,
+		ValidateOwner: true,
+	}
+	var mw *meta.MetaWrapper
+	if mw, err = meta.NewMetaWrapper(metaConfig); err != nil {
+		log.LogErrorf("NewVolume: new meta wrapper failed: volume(%v) err(%v)", name, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = mw.Close()
+		}
+	}()
+	mw.EnableQuota = enableQuota
+
+	extentConfig := &stream.ExtentConfig{
+		Volume:            name,
+		Masters:           masters,
+		FollowerRead:      true,
+		OnAppendExtentKey: mw.AppendExtentKey,
+		OnSplitExtentKey:  mw.SplitExtentKey,
+		OnGetExtents:      mw.GetExtents,
+		OnTruncate:        mw.Truncate,
+		OnLoadFileCipher:  mw.LoadFileCipher,
+	}
+	var ec *stream.ExtentClient
+	if ec, err = stream.NewExtentClient(extentConfig); err != nil {
+		log.LogErrorf("NewVolume: new extent client failed: volume(%v) err(%v)", name, err)
+		return
+	}
+
+	v = &Volume{
+		name:     name,
+		hash:     volumeHash(name),
+		mw:       mw,
+		ec:       ec,
+		mc:       master.NewMasterClient(masters, false),
+		streams:  make(map[uint64]time.Time),
+		parents:  make(map[uint64]uint64),
+		cookies:  make(map[dirCookie]string),
+		ipChecks: make(map[string]*ipCheck),
+		closeC:   make(chan struct{}),
+	}
+	v.wg.Add(1)
+	go v.closeIdleStreams()
+	return v, nil
+}
+
+func (v *Volume) Name() string {
+	return v.name
+}
+
+func (v *Volume) Close() {
+	close(v.closeC)
+	v.wg.Wait()
+	v.streamLock.Lock()
+	for ino := range v.streams {
+		v.closeStream(ino)
+	}
+	v.streams = make(map[uint64]time.Time)
+	v.streamLock.Unlock()
+	_ = v.ec.Close()
+	_ = v.mw.Close()
+}
+
+// getAttr returns the inode with the size of the data written but not flushed yet.
+func (v *Volume) getAttr(ino uint64) (info *proto.InodeInfo, err error) {
+	if info, err = v.mw.InodeGet_ll(ino); err != nil {
+		return
+	}
+	if size, _, valid := v.ec.FileSize(ino); valid && uint64(size) > info.Size {
+		info.Size = uint64(size)
+	}
+	return
+}
+
+func (v *Volume) openStream(ino uint64) (err error) {
+	v.streamLock.Lock()
+	defer v.streamLock.Unlock()
+	if _, ok := v.streams[ino]; !ok {
+		if err = v.ec.OpenStream(ino); err != nil {
+			log.LogErrorf("openStream: volume(%v) ino(%v) err(%v)", v.name, ino, err)
+			return
+		}
+	}
+	v.streams[ino] = time.Now()
+	return
+}
+
+func (v *Volume) flushStream(ino uint64) (err error) {
+	v.streamLock.Lock()
+	defer v.streamLock.Unlock()
+	if _, ok := v.streams[ino]; !ok {
+		return
+	}
+	if err = v.ec.Flush(ino); err != nil {
+		log.LogErrorf("flushStream: volume(%v) ino(%v) err(%v)", v.name, ino, err)
+	}
+	return
+}
+
+func (v *Volume) evictStream(ino uint64) {
+	v.streamLock.Lock()
+	defer v.streamLock.Unlock()
+	if _, ok := v.streams[ino]; ok {
+		v.closeStream(ino)
+		delete(v.streams, ino)
+	}
+}
+
+func (v *Volume) closeStream(ino uint64) {
+	if err := v.ec.CloseStream(ino); err != nil {
+		log.LogWarnf("closeStream: volume(%v) ino(%v) err(%v)", v.name, ino, err)
+	}
+	if err := v.ec.EvictStream(ino); err != nil {
+		log.LogWarnf("closeStream: evict volume(%v) ino(%v) err(%v)", v.name, ino, err)
+	}
+}
+
+func (v *Volume) closeIdleStreams() {
+	defer v.wg.Done()
+	ticker := time.NewTicker(streamIdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-v.closeC:
+			return
+		case now := <-ticker.C:
+			v.streamLock.Lock()
+			for ino, lastAccess := range v.streams {
+				if now.Sub(lastAccess) > streamIdleTimeout {
+					v.closeStream(ino)
+					delete(v.streams, ino)
+				}
+			}
+			v.streamLock.Unlock()
+		}
+	}
+}
+
+func (v *Volume) setParent(dir, parent uint64) {
+	v.cacheLock.Lock()
+	defer v.cacheLock.Unlock()
+	if len(v.parents) >= maxCachedParents {
+		v.parents = make(map[uint64]uint64)
+	}
+	v.parents[dir] = parent
+}
+
+func (v *Volume) getParent(dir uint64) uint64 {
+	v.cacheLock.Lock()
+	defer v.cacheLock.Unlock()
+	if parent, ok := v.parents[dir]; ok {
+		return parent
+	}
+	return proto.RootIno
+}
+
+func (v *Volume) putCookie(dir uint64, name string) (cookie uint64) {
+	cookie = nameCookie(name)
+	v.cacheLock.Lock()
+	defer v.cacheLock.Unlock()
+	if len(v.cookies) >= maxCachedCookies {
+		v.cookies = make(map[dirCookie]string)
+	}
+	v.cookies[dirCookie{dir: dir, cookie: cookie}] = name
+	return
+}
+
+func (v *Volume) getCookie(dir, cookie uint64) (name string, ok bool) {
+	v.cacheLock.Lock()
+	defer v.cacheLock.Unlock()
+	name, ok = v.cookies[dirCookie{dir: dir, cookie: cookie}]
+	return
+}
+
+// nameCookie returns the readdir cookie of the entry, the cookies 0, 1 and 2 are kept for
+// the beginning, '.' and '..' of the directory.
+func nameCookie(name string) uint64 {
+	cookie := volumeHash(name) >> 1
+	if cookie <= 2 {
+		cookie += 3
+	}
+	return cookie
+}
+
+// checkIP checks whether the client is allowed by the IP ACL of the volume, the same
+// as the fuse clients at mount.
+func (v *Volume) checkIP(ip string) bool {
+	v.ipLock.Lock()
+	check, ok := v.ipChecks[ip]
+	v.ipLock.Unlock()
+	if ok && time.Now().Before(check.expire) {
+		return check.allowed
+	}
+	check = &ipCheck{expire: time.Now().Add(ipCheckExpiration)}
+	info, err := v.mc.UserAPI().AclOperation(v.name, ip, util.AclCheckIP)
+	if err != nil {
+		log.LogWarnf("checkIP: volume(%v) ip(%v) err(%v)", v.name, ip, err)
+	}
+	check.allowed = err == nil && info.OK
+	v.ipLock.Lock()
+	v.ipChecks[ip] = check
+	v.ipLock.Unlock()
+	return check.allowed
+}
+
+// grantedPermissions returns the permissions of the user on the inode by the access ACL
+// and the permission bits.
+func (v *Volume) grantedPermissions(info *proto.InodeInfo, cred *rpcCredential) (granted uint16, err error) {
+	var acl *proto.PosixACL
+	if v.mw.PosixACLEnabled() {
+		if acl, err = v.mw.GetPosixACL_ll(info.Inode, proto.XAttrPosixACLAccess); err != nil {
+			return
+		}
+	}
+	for _, perm := range []uint16{proto.ACLRead, proto.ACLWrite, proto.ACLExecute} {
+		if proto.CheckPosixPermission(info.Mode, info.Uid, info.Gid, cred.Uid, cred.Gids, perm, acl) {
+			granted |= perm
+		}
+	}
+	return
+}
+
+func (v *Volume) checkPermission(info *proto.InodeInfo, cred *rpcCredential, want uint16) error {
+	return v.mw.CheckPermission_ll(info, cred.Uid, cred.Gids, want)
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var errXDRShort = errors.New("xdr: short buffer")
+
+// xdrDecoder decodes the XDR data of RFC 4506. The first error is kept and all the
+// following reads return zero values, so the caller only checks the error once.
+type xdrDecoder struct {
+	buf []byte
+	off int
+	err error
+}
+
+func newXDRDecoder(buf []byte) *xdrDecoder {
+	return &xdrDecoder{buf: buf}
+}
+
+func (d *xdrDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.off+n > len(d.buf) {
+		d.err = errXDRShort
+		return nil
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *xdrDecoder) Uint32() uint32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (d *xdrDecoder) Uint64() uint64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (d *xdrDecoder) Bool() bool {
+	return d.Uint32() != 0
+}
+
+// FixedOpaque reads the opaque data of the fixed length with the padding.
+func (d *xdrDecoder) FixedOpaque(n int) []byte {
+	b := d.next(n)
+	if b == nil {
+		return nil
+	}
+	d.next(xdrPad(n))
+	return b
+}
+
+// Opaque reads the opaque data of variable length no longer than max.
+func (d *xdrDecoder) Opaque(max int) []byte {
+	n := d.Uint32()
+	if d.err == nil && int(n) > max {
+		d.err = errXDRShort
+		return nil
+	}
+	return d.FixedOpaque(int(n))
+}
+
+func (d *xdrDecoder) String(max int) string {
+	return string(d.Opaque(max))
+}
+
+func (d *xdrDecoder) Err() error {
+	return d.err
+}
+
+// xdrEncoder encodes the XDR data of RFC 4506.
+type xdrEncoder struct {
+	bytes.Buffer
+}
+
+func (e *xdrEncoder) Uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.Write(b[:])
+}
+
+func (e *xdrEncoder) Uint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.Write(b[:])
+}
+
+func (e *xdrEncoder) Bool(v bool) {
+	if v {
+		e.Uint32(1)
+	} else {
+		e.Uint32(0)
+	}
+}
+
+func (e *xdrEncoder) FixedOpaque(b []byte) {
+	e.Write(b)
+	var pad [3]byte
+	e.Write(pad[:xdrPad(len(b))])
+}
+
+func (e *xdrEncoder) Opaque(b []byte) {
+	e.Uint32(uint32(len(b)))
+	e.FixedOpaque(b)
+}
+
+func (e *xdrEncoder) String(s string) {
+	e.Opaque([]byte(s))
+}
+
+func xdrPad(n int) int {
+	return (4 - n%4) % 4
+}
@@ -98,6 +98,18 @@ func (o *ObjectNode) putBucketACLHandler(w http.ResponseWriter, r *http.Request)
 			GetRequestID(r), param.bucket, err)
 		return
 	}
+	var blocked bool
+	if blocked, err = isPublicAclBlocked(vol, acl); err != nil {
+		log.LogErrorf("putBucketACLHandler: load public access block fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.bucket, err)
+		return
+	}
+	if blocked {
+		log.LogWarnf("putBucketACLHandler: public acl is blocked: requestID(%v) volume(%v) acl(%+v)",
+			GetRequestID(r), param.bucket, acl)
+		erc = PublicAclBlocked
+		return
+	}
 	if err = putBucketACL(vol, acl); err != nil {
 		log.LogErrorf("putBucketACLHandler: put acl fail: requestID(%v) volume(%v) acl(%+v) err(%v)",
 			GetRequestID(r), param.bucket, acl, err)
@@ -125,7 +137,7 @@ func (o *ObjectNode) getObjectACLHandler(w http.ResponseWriter, r *http.Request)
 		erc = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		erc = InvalidKey
 		return
 	}
@@ -176,7 +188,7 @@ func (o *ObjectNode) putObjectACLHandler(w http.ResponseWriter, r *http.Request)
 		erc = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		erc = InvalidKey
 		return
 	}
@@ -212,6 +224,18 @@ func (o *ObjectNode) putObjectACLHandler(w http.ResponseWriter, r *http.Request)
 			GetRequestID(r), param.bucket, param.object, err)
 		return
 	}
+	var blocked bool
+	if blocked, err = isPublicAclBlocked(vol, acl); err != nil {
+		log.LogErrorf("putObjectACLHandler: load public access block fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), param.bucket, param.object, err)
+		return
+	}
+	if blocked {
+		log.LogWarnf("putObjectACLHandler: public acl is blocked: requestID(%v) volume(%v) path(%v) acl(%+v)",
+			GetRequestID(r), param.bucket, param.object, acl)
+		erc = PublicAclBlocked
+		return
+	}
 	if oldAcl != nil {
 		originalOwner := oldAcl.GetOwner()
 		if oldAcl.IsEmpty() {
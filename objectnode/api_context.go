@@ -34,6 +34,7 @@ const (
 	ContextKeyRequester     = "requester"
 	ContextKeyOwner         = "owner"
 	ContextKeyAccessKey     = "access_key"
+	ContextKeyWebsite       = "website"
 )
 
 func SetRequestID(r *http.Request, requestID string) {
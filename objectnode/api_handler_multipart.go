@@ -53,7 +53,7 @@ func (o *ObjectNode) createMultipleUploadHandler(w http.ResponseWriter, r *http.
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -115,6 +115,16 @@ func (o *ObjectNode) createMultipleUploadHandler(w http.ResponseWriter, r *http.
 			GetRequestID(r), acl, err)
 		return
 	}
+	var blocked bool
+	if blocked, err = isPublicAclBlocked(vol, acl); err != nil {
+		log.LogErrorf("createMultipleUploadHandler: load public access block fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if blocked {
+		errorCode = PublicAclBlocked
+		return
+	}
 	opt := &PutFileOption{
 		MIMEType:     contentType,
 		Disposition:  contentDisposition,
@@ -124,6 +134,11 @@ func (o *ObjectNode) createMultipleUploadHandler(w http.ResponseWriter, r *http.
 		Expires:      expires,
 		ACL:          acl,
 	}
+	if opt.Encryption, errorCode, err = newObjectEncryption(r, vol); errorCode != nil || err != nil {
+		log.LogErrorf("createMultipleUploadHandler: resolve encryption fail: requestID(%v) volume(%v) path(%v) errorCode(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), errorCode, err)
+		return
+	}
 
 	var uploadID string
 	if uploadID, err = vol.InitMultipart(param.Object(), opt); err != nil {
@@ -132,6 +147,7 @@ func (o *ObjectNode) createMultipleUploadHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	setSSEResponseHeaders(w, opt.Encryption)
 	initResult := InitMultipartResult{
 		Bucket:   param.Bucket(),
 		Key:      param.Object(),
@@ -184,7 +200,7 @@ func (o *ObjectNode) uploadPartHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -245,9 +261,17 @@ func (o *ObjectNode) uploadPartHandler(w http.ResponseWriter, r *http.Request) {
 		reader = r.Body
 	}
 
+	// parts are encrypted with the data key of the multipart upload
+	var encryption *ObjectEncryption
+	if encryption, errorCode, err = loadMultipartEncryption(r, vol, param.Object(), uploadId); errorCode != nil || err != nil {
+		log.LogErrorf("uploadPartHandler: load encryption fail: requestID(%v) volume(%v) path(%v) uploadId(%v) errorCode(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), uploadId, errorCode, err)
+		return
+	}
+
 	// Write Part
 	start := time.Now()
-	fsFileInfo, err := vol.WritePart(param.Object(), uploadId, partNumberInt, reader)
+	fsFileInfo, err := vol.WritePart(param.Object(), uploadId, partNumberInt, reader, encryption)
 	span.AppendTrackLog("part.w", start, err)
 	if err != nil {
 		log.LogErrorf("uploadPartHandler: write part fail: requestID(%v) volume(%v) path(%v) uploadId(%v) part(%v) err(%v)",
@@ -266,6 +290,7 @@ func (o *ObjectNode) uploadPartHandler(w http.ResponseWriter, r *http.Request) {
 
 	// write header to response
 	w.Header()[ETag] = []string{"\"" + fsFileInfo.ETag + "\""}
+	setSSEResponseHeaders(w, encryption)
 	return
 }
 
@@ -303,7 +328,7 @@ func (o *ObjectNode) uploadPartCopyHandler(w http.ResponseWriter, r *http.Reques
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -348,10 +373,26 @@ func (o *ObjectNode) uploadPartCopyHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if !srcFileInfo.Restore.Readable(srcFileInfo.StorageClass) {
+		errorCode = InvalidObjectState
+		return
+	}
+
 	errorCode = CheckConditionInHeader(r, srcFileInfo)
 	if errorCode != nil {
 		return
 	}
+	if errorCode, err = unsealObjectKey(r, srcFileInfo.Encryption, true); errorCode != nil || err != nil {
+		log.LogErrorf("uploadPartCopyHandler: unseal source object key fail: requestId(%v) srcVol(%v) path(%v) errorCode(%v) err(%v)",
+			GetRequestID(r), srcBucket, srcObject, errorCode, err)
+		return
+	}
+	var encryption *ObjectEncryption
+	if encryption, errorCode, err = loadMultipartEncryption(r, vol, param.Object(), uploadId); errorCode != nil || err != nil {
+		log.LogErrorf("uploadPartCopyHandler: load encryption fail: requestID(%v) volume(%v) path(%v) uploadId(%v) errorCode(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), uploadId, errorCode, err)
+		return
+	}
 
 	// step4: extract range params
 	copyRange := r.Header.Get(XAmzCopySourceRange)
@@ -359,10 +400,6 @@ func (o *ObjectNode) uploadPartCopyHandler(w http.ResponseWriter, r *http.Reques
 	if errorCode != nil {
 		return
 	}
-	size, err := safeConvertInt64ToUint64(srcFileInfo.Size)
-	if err != nil {
-		return
-	}
 	fb, err := safeConvertInt64ToUint64(firstByte)
 	if err != nil {
 		return
@@ -373,7 +410,7 @@ func (o *ObjectNode) uploadPartCopyHandler(w http.ResponseWriter, r *http.Reques
 	}
 	reader, writer := io.Pipe()
 	go func() {
-		err = srcVol.readFile(srcFileInfo.Inode, size, srcObject, writer, fb, cl)
+		err = srcVol.readObject(srcFileInfo, srcObject, writer, fb, cl)
 		if err != nil {
 			log.LogErrorf("uploadPartCopyHandler: read srcObj err(%v): requestId(%v) srcVol(%v) path(%v)",
 				err, GetRequestID(r), srcBucket, srcObject)
@@ -389,7 +426,7 @@ func (o *ObjectNode) uploadPartCopyHandler(w http.ResponseWriter, r *http.Reques
 		rd = reader
 	}
 	start = time.Now()
-	fsFileInfo, err := vol.WritePart(param.Object(), uploadId, partNumberInt, rd)
+	fsFileInfo, err := vol.WritePart(param.Object(), uploadId, partNumberInt, rd, encryption)
 	span.AppendTrackLog("part.w", start, err)
 	if err != nil {
 		log.LogErrorf("uploadPartCopyHandler: write part fail: requestID(%v) volume(%v) path(%v) uploadId(%v) part(%v) err(%v)",
@@ -410,6 +447,7 @@ func (o *ObjectNode) uploadPartCopyHandler(w http.ResponseWriter, r *http.Reques
 
 	Etag := "\"" + fsFileInfo.ETag + "\""
 	w.Header()[ETag] = []string{Etag}
+	setSSEResponseHeaders(w, encryption)
 	response := NewS3CopyPartResult(Etag, fsFileInfo.CreateTime.UTC().Format(time.RFC3339)).String()
 
 	writeSuccessResponseXML(w, []byte(response))
@@ -486,7 +524,7 @@ func (o *ObjectNode) listPartsHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -644,7 +682,7 @@ func (o *ObjectNode) completeMultipartUploadHandler(w http.ResponseWriter, r *ht
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -755,6 +793,14 @@ func (o *ObjectNode) completeMultipartUploadHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
+	if fsFileInfo.VersionId != "" {
+		w.Header()[XAmzVersionId] = []string{fsFileInfo.VersionId}
+	}
+	setSSEResponseHeaders(w, fsFileInfo.Encryption)
+	if err = o.replicateObjectEvent(r, vol, param.Object(), fsFileInfo.VersionId, false); err != nil {
+		return
+	}
+	o.notifyObjectEvent(r, vol, EventObjectCreatedCompleteMultipart, param.Object(), fsFileInfo)
 	completeResult := CompleteMultipartResult{
 		Bucket: param.Bucket(),
 		Key:    param.Object(),
@@ -792,7 +838,7 @@ func (o *ObjectNode) abortMultipartUploadHandler(w http.ResponseWriter, r *http.
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -845,6 +891,10 @@ func (o *ObjectNode) listMultipartUploadsHandler(w http.ResponseWriter, r *http.
 	delimiter := param.GetVar(ParamPartDelimiter)
 	maxUploads := param.GetVar(ParamPartMaxUploads)
 	uploadIdMarker := param.GetVar(ParamUploadIdMarker)
+	if isVersionsKey(prefix) || isVersionsKey(keyMarker) {
+		errorCode = InvalidArgument
+		return
+	}
 
 	var maxUploadsInt uint64
 	if maxUploads == "" {
@@ -58,7 +58,7 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -153,18 +153,35 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 	responseContentType := r.URL.Query().Get(ParamResponseContentType)
 	responseContentDisposition := r.URL.Query().Get(ParamResponseContentDisposition)
 
+	versionId := r.URL.Query().Get(ParamVersionId)
 	// get object meta
 	start := time.Now()
-	fileInfo, xattr, err := vol.ObjectMeta(param.Object())
+	fileInfo, xattr, err := vol.ObjectVersionMeta(param.Object(), versionId)
 	span.AppendTrackLog("meta.r", start, err)
 	if err != nil {
-		log.LogErrorf("getObjectHandler: get file meta fail: requestId(%v) volume(%v) path(%v) err(%v)",
-			GetRequestID(r), vol.Name(), param.Object(), err)
+		log.LogErrorf("getObjectHandler: get file meta fail: requestId(%v) volume(%v) path(%v) versionId(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), versionId, err)
 		if err == syscall.ENOENT {
 			errorCode = NoSuchKey
 		}
 		return
 	}
+	if fileInfo.VersionId != "" {
+		w.Header()[XAmzVersionId] = []string{fileInfo.VersionId}
+	}
+	if fileInfo.ReplicationStatus != "" {
+		w.Header()[XAmzReplicationStatus] = []string{fileInfo.ReplicationStatus}
+	}
+	if fileInfo.DeleteMarker {
+		w.Header()[XAmzDeleteMarker] = []string{"true"}
+		errorCode = MethodNotAllowed
+		return
+	}
+	setStorageClassResponseHeaders(w, fileInfo)
+	if !fileInfo.Restore.Readable(fileInfo.StorageClass) {
+		errorCode = InvalidObjectState
+		return
+	}
 
 	// header condition check
 	errorCode = CheckConditionInHeader(r, fileInfo)
@@ -172,6 +189,14 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the data key of encrypted object is required to read the object
+	if errorCode, err = unsealObjectKey(r, fileInfo.Encryption, false); errorCode != nil || err != nil {
+		log.LogErrorf("getObjectHandler: unseal object key fail: requestID(%v) volume(%v) path(%v) errorCode(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), errorCode, err)
+		return
+	}
+	setSSEResponseHeaders(w, fileInfo.Encryption)
+
 	// validate and fix range
 	if isRangeRead && rangeUpper > uint64(fileInfo.Size)-1 {
 		rangeUpper = uint64(fileInfo.Size) - 1
@@ -219,9 +244,12 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set(Expires, fileInfo.Expires)
 	}
 	if len(fileInfo.RetainUntilDate) > 0 {
-		w.Header().Set(XAmzObjectLockMode, ComplianceMode)
+		w.Header().Set(XAmzObjectLockMode, fileInfo.LockMode)
 		w.Header().Set(XAmzObjectLockRetainUntilDate, fileInfo.RetainUntilDate)
 	}
+	if len(fileInfo.LegalHold) > 0 {
+		w.Header().Set(XAmzObjectLockLegalHold, fileInfo.LegalHold)
+	}
 
 	// check request is whether contain param : partNumber
 	partNumber := r.URL.Query().Get(ParamPartNumber)
@@ -271,7 +299,6 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 	// get object content
 	offset := rangeLower
 	size, err := safeConvertInt64ToUint64(fileInfo.Size)
-	fileSize := size
 	if err != nil {
 		return
 	}
@@ -290,7 +317,7 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 
 	// read file
 	start = time.Now()
-	err = vol.readFile(fileInfo.Inode, fileSize, param.Object(), writer, offset, size)
+	err = vol.readObject(fileInfo, param.Object(), writer, offset, size)
 	span.AppendTrackLog("file.r", start, err)
 	if err != nil {
 		log.LogErrorf("getObjectHandler: read file fail: requestID(%v) volume(%v) path(%v) offset(%v) size(%v) err(%v)",
@@ -379,7 +406,7 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -398,18 +425,31 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rateLimit.ReleaseLimitResource(vol.owner, param.apiName)
 
+	versionId := r.URL.Query().Get(ParamVersionId)
 	// get object meta
 	start := time.Now()
-	fileInfo, _, err := vol.ObjectMeta(param.Object())
+	fileInfo, _, err := vol.ObjectVersionMeta(param.Object(), versionId)
 	span.AppendTrackLog("meta.r", start, err)
 	if err != nil {
-		log.LogErrorf("headObjectHandler: get file meta fail: requestId(%v) volume(%v) path(%v) err(%v)",
-			GetRequestID(r), vol.Name(), param.Object(), err)
+		log.LogErrorf("headObjectHandler: get file meta fail: requestId(%v) volume(%v) path(%v) versionId(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), versionId, err)
 		if err == syscall.ENOENT {
 			errorCode = NoSuchKey
 		}
 		return
 	}
+	if fileInfo.VersionId != "" {
+		w.Header()[XAmzVersionId] = []string{fileInfo.VersionId}
+	}
+	if fileInfo.ReplicationStatus != "" {
+		w.Header()[XAmzReplicationStatus] = []string{fileInfo.ReplicationStatus}
+	}
+	if fileInfo.DeleteMarker {
+		w.Header()[XAmzDeleteMarker] = []string{"true"}
+		errorCode = MethodNotAllowed
+		return
+	}
+	setStorageClassResponseHeaders(w, fileInfo)
 
 	// parse request header
 	match := r.Header.Get(IfMatch)
@@ -470,6 +510,14 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// the customer key is required to get the meta of SSE-C object
+	if errorCode, err = unsealObjectKey(r, fileInfo.Encryption, false); errorCode != nil || err != nil {
+		log.LogErrorf("headObjectHandler: unseal object key fail: requestID(%v) volume(%v) path(%v) errorCode(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), errorCode, err)
+		return
+	}
+	setSSEResponseHeaders(w, fileInfo.Encryption)
+
 	// set response header
 	w.Header().Set(AcceptRanges, ValueAcceptRanges)
 	w.Header().Set(LastModified, formatTimeRFC1123(fileInfo.ModifyTime))
@@ -489,9 +537,12 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set(Expires, fileInfo.Expires)
 	}
 	if len(fileInfo.RetainUntilDate) > 0 {
-		w.Header().Set(XAmzObjectLockMode, ComplianceMode)
+		w.Header().Set(XAmzObjectLockMode, fileInfo.LockMode)
 		w.Header().Set(XAmzObjectLockRetainUntilDate, fileInfo.RetainUntilDate)
 	}
+	if len(fileInfo.LegalHold) > 0 {
+		w.Header().Set(XAmzObjectLockLegalHold, fileInfo.LegalHold)
+	}
 
 	// check request is whether contain param : partNumber
 	partNumber := r.URL.Query().Get(ParamPartNumber)
@@ -632,6 +683,10 @@ func (o *ObjectNode) deleteObjectsHandler(w http.ResponseWriter, r *http.Request
 	objectKeys := make([]string, 0, len(deleteReq.Objects))
 	start := time.Now()
 	for _, object := range deleteReq.Objects {
+		if isVersionsKey(object.Key) {
+			deletedErrors = append(deletedErrors, Error{Key: object.Key, Code: InvalidKey.ErrorCode, Message: InvalidKey.ErrorMessage})
+			continue
+		}
 		result := POLICY_UNKNOW
 		if policy != nil && !policy.IsEmpty() {
 			conditionCheck := map[string]string{
@@ -658,7 +713,9 @@ func (o *ObjectNode) deleteObjectsHandler(w http.ResponseWriter, r *http.Request
 		if err = rateLimit.AcquireLimitResource(vol.owner, DELETE_OBJECT); err != nil {
 			return
 		}
-		if err1 := vol.DeletePath(object.Key); err1 != nil {
+		bypassGovernance := o.allowBypassGovernance(r, param, object.Key)
+		versionId, deleteMarker, err1 := vol.DeleteObject(object.Key, object.VersionId, bypassGovernance)
+		if err1 != nil {
 			log.LogErrorf("deleteObjectsHandler: delete object failed: requestID(%v) volume(%v) path(%v) err(%v)",
 				GetRequestID(r), vol.Name(), object.Key, err1)
 			if !strings.Contains(err1.Error(), AccessDenied.ErrorMessage) {
@@ -667,7 +724,26 @@ func (o *ObjectNode) deleteObjectsHandler(w http.ResponseWriter, r *http.Request
 				deletedErrors = append(deletedErrors, Error{Key: object.Key, Code: "AccessDenied", Message: err1.Error()})
 			}
 		} else {
-			deletedObjects = append(deletedObjects, Deleted{Key: object.Key})
+			deleted := Deleted{Key: object.Key, VersionId: object.VersionId}
+			if deleteMarker {
+				deleted.DeleteMarker = "true"
+				if object.VersionId == "" {
+					deleted.DeleteMarkerVersionId = versionId
+				}
+			}
+			if deleteMarker && object.VersionId == "" {
+				if err1 = o.replicateObjectEvent(r, vol, object.Key, versionId, true); err1 != nil {
+					deletedErrors = append(deletedErrors, Error{Key: object.Key, Code: "InternalError", Message: err1.Error()})
+					rateLimit.ReleaseLimitResource(vol.owner, param.apiName)
+					continue
+				}
+			}
+			deletedObjects = append(deletedObjects, deleted)
+			event := EventObjectRemovedDelete
+			if deleteMarker {
+				event = EventObjectRemovedDeleteMarkerCreated
+			}
+			o.notifyObjectEvent(r, vol, event, object.Key, &FSFileInfo{VersionId: versionId})
 		}
 		rateLimit.ReleaseLimitResource(vol.owner, param.apiName)
 	}
@@ -709,6 +785,9 @@ func extractSrcBucketKey(r *http.Request) (srcBucketId, srcKey, versionId string
 	if srcBucketId == "" || srcKey == "" {
 		return "", "", "", InvalidArgument
 	}
+	if isVersionsKey(srcKey) {
+		return "", "", "", InvalidKey
+	}
 	return
 }
 
@@ -730,7 +809,7 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -779,7 +858,7 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// parse x-amz-copy-source header
-	sourceBucket, sourceObject, _, err := extractSrcBucketKey(r)
+	sourceBucket, sourceObject, sourceVersionId, err := extractSrcBucketKey(r)
 	if err != nil {
 		log.LogErrorf("copyObjectHandler: copySource(%v) argument invalid: requestID(%v) volume(%v) err(%v)",
 			r.Header.Get(XAmzCopySource), GetRequestID(r), param.Bucket(), err)
@@ -799,6 +878,16 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 			GetRequestID(r), param.Bucket(), acl, err)
 		return
 	}
+	blocked, err := isPublicAclBlocked(vol, acl)
+	if err != nil {
+		log.LogErrorf("copyObjectHandler: load public access block fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		return
+	}
+	if blocked {
+		errorCode = PublicAclBlocked
+		return
+	}
 
 	// get src object meta
 	var sourceVol *Volume
@@ -808,13 +897,26 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// resolve the path of the specified source version
+	sourcePath := sourceObject
+	if sourceVersionId != "" {
+		if sourcePath, err = sourceVol.ObjectVersionPath(sourceObject, sourceVersionId); err != nil {
+			log.LogErrorf("copyObjectHandler: get source version fail: requestID(%v) srcVolume(%v) srcObject(%v) versionId(%v) err(%v)",
+				GetRequestID(r), sourceBucket, sourceObject, sourceVersionId, err)
+			if err == MethodNotAllowed {
+				err = InvalidArgument
+			}
+			return
+		}
+	}
+
 	// get object meta
 	start := time.Now()
-	fileInfo, _, err := sourceVol.ObjectMeta(sourceObject)
+	fileInfo, sourceXAttr, err := sourceVol.ObjectMeta(sourcePath)
 	span.AppendTrackLog("meta.r", start, err)
 	if err != nil {
 		log.LogErrorf("copyObjectHandler: get object meta fail: requestID(%v) srcVolume(%v) srcObject(%v) err(%v)",
-			GetRequestID(r), sourceBucket, sourceObject, err)
+			GetRequestID(r), sourceBucket, sourcePath, err)
 		if err == syscall.ENOENT {
 			errorCode = NoSuchKey
 		}
@@ -828,6 +930,10 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = EntityTooLarge
 		return
 	}
+	if !fileInfo.Restore.Readable(fileInfo.StorageClass) {
+		errorCode = InvalidObjectState
+		return
+	}
 
 	// get header
 	copyMatch := r.Header.Get(XAmzCopySourceIfMatch)
@@ -882,6 +988,27 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 			GetRequestID(r), param.Bucket(), err)
 		return
 	}
+	retention, legalHold, err := parseObjectLockHeaders(r, objetLock)
+	if err != nil {
+		log.LogErrorf("copyObjectHandler: parse object lock headers fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	// encrypted source requires its data key, and the target may be encrypted differently from the source
+	if errorCode, err = unsealObjectKey(r, fileInfo.Encryption, true); errorCode != nil || err != nil {
+		log.LogErrorf("copyObjectHandler: unseal source object key fail: requestID(%v) srcVolume(%v) srcObject(%v) errorCode(%v) err(%v)",
+			GetRequestID(r), sourceBucket, sourcePath, errorCode, err)
+		return
+	}
+	var encryption *ObjectEncryption
+	if !fileInfo.Mode.IsDir() {
+		if encryption, errorCode, err = newObjectEncryption(r, vol); errorCode != nil || err != nil {
+			log.LogErrorf("copyObjectHandler: resolve encryption fail: requestID(%v) volume(%v) errorCode(%v) err(%v)",
+				GetRequestID(r), param.Bucket(), errorCode, err)
+			return
+		}
+	}
 
 	// parse user-defined metadata
 	metadata := ParseUserDefinedMetadata(r.Header)
@@ -895,9 +1022,32 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		Expires:      expires,
 		ACL:          acl,
 		ObjectLock:   objetLock,
+		Retention:    retention,
+		LegalHold:    legalHold,
 	}
+	var fsFileInfo *FSFileInfo
 	start = time.Now()
-	fsFileInfo, err := vol.CopyFile(sourceVol, sourceObject, param.Object(), metadataDirective, opt)
+	if fileInfo.Encryption == nil && encryption == nil {
+		fsFileInfo, err = vol.CopyFile(sourceVol, sourcePath, param.Object(), metadataDirective, opt)
+	} else {
+		// the data of encrypted object can not be copied directly, it is decrypted and rewritten
+		if metadataDirective == MetadataDirectiveCopy {
+			opt.MIMEType = fileInfo.MIMEType
+			opt.Disposition = fileInfo.Disposition
+			opt.Metadata = fileInfo.Metadata
+			opt.CacheControl = fileInfo.CacheControl
+			opt.Expires = fileInfo.Expires
+			if raw := string(sourceXAttr.Get(XAttrKeyOSSTagging)); raw != "" {
+				if opt.Tagging, err = ParseTagging(raw); err != nil {
+					log.LogErrorf("copyObjectHandler: parse source tagging fail: requestID(%v) srcVolume(%v) srcObject(%v) err(%v)",
+						GetRequestID(r), sourceBucket, sourcePath, err)
+					return
+				}
+			}
+		}
+		opt.Encryption = encryption
+		fsFileInfo, err = vol.CopyObjectData(sourceVol, fileInfo, param.Object(), opt)
+	}
 	span.AppendTrackLog("file.c", start, err)
 	if err != nil && err != syscall.EINVAL && err != syscall.EFBIG {
 		log.LogErrorf("copyObjectHandler: Volume copy file fail: requestID(%v) Volume(%v) source(%v) target(%v) err(%v)",
@@ -917,6 +1067,17 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if sourceVersionId != "" {
+		w.Header()[XAmzCopySourceVersionId] = []string{sourceVersionId}
+	}
+	if fsFileInfo.VersionId != "" {
+		w.Header()[XAmzVersionId] = []string{fsFileInfo.VersionId}
+	}
+	setSSEResponseHeaders(w, encryption)
+	if err = o.replicateObjectEvent(r, vol, param.Object(), fsFileInfo.VersionId, false); err != nil {
+		return
+	}
+	o.notifyObjectEvent(r, vol, EventObjectCreatedCopy, param.Object(), fsFileInfo)
 	copyResult := CopyResult{
 		ETag:         "\"" + fsFileInfo.ETag + "\"",
 		LastModified: formatTimeISO(fsFileInfo.ModifyTime),
@@ -969,6 +1130,10 @@ func (o *ObjectNode) getBucketV1Handler(w http.ResponseWriter, r *http.Request)
 	maxKeys := r.URL.Query().Get(ParamMaxKeys)
 	delimiter := r.URL.Query().Get(ParamPartDelimiter)
 	encodingType := r.URL.Query().Get(ParamEncodingType)
+	if isVersionsKey(prefix) || isVersionsKey(marker) {
+		errorCode = InvalidArgument
+		return
+	}
 
 	var maxKeysInt uint64
 	if maxKeys != "" {
@@ -1035,7 +1200,7 @@ func (o *ObjectNode) getBucketV1Handler(w http.ResponseWriter, r *http.Request)
 			LastModified: formatTimeISO(file.ModifyTime),
 			ETag:         wrapUnescapedQuot(file.ETag),
 			Size:         int(file.Size),
-			StorageClass: StorageClassStandard,
+			StorageClass: file.ObjectStorageClass(),
 			Owner:        bucketOwner,
 		}
 		contents = append(contents, content)
@@ -1111,6 +1276,10 @@ func (o *ObjectNode) getBucketV2Handler(w http.ResponseWriter, r *http.Request)
 	fetchOwner := r.URL.Query().Get(ParamFetchOwner)
 	startAfter := r.URL.Query().Get(ParamStartAfter)
 	encodingType := r.URL.Query().Get(ParamEncodingType)
+	if isVersionsKey(prefix) || isVersionsKey(startAfter) {
+		errorCode = InvalidArgument
+		return
+	}
 
 	var maxKeysInt uint64
 	if maxKeys != "" {
@@ -1202,7 +1371,7 @@ func (o *ObjectNode) getBucketV2Handler(w http.ResponseWriter, r *http.Request)
 				LastModified: formatTimeISO(file.ModifyTime),
 				ETag:         wrapUnescapedQuot(file.ETag),
 				Size:         int(file.Size),
-				StorageClass: StorageClassStandard,
+				StorageClass: file.ObjectStorageClass(),
 				Owner:        bucketOwner,
 			}
 			contents = append(contents, content)
@@ -1258,7 +1427,7 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -1295,6 +1464,12 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = NoContentMd5HeaderErr
 		return
 	}
+	retention, legalHold, err := parseObjectLockHeaders(r, objetLock)
+	if err != nil {
+		log.LogErrorf("putObjectHandler: parse object lock headers fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
 
 	// QPS and Concurrency Limit
 	rateLimit := o.AcquireRateLimiter()
@@ -1333,6 +1508,16 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 			GetRequestID(r), vol.Name(), param.Object(), acl, err)
 		return
 	}
+	blocked, err := isPublicAclBlocked(vol, acl)
+	if err != nil {
+		log.LogErrorf("putObjectHandler: load public access block fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		return
+	}
+	if blocked {
+		errorCode = PublicAclBlocked
+		return
+	}
 
 	// Verify ContentLength
 	length := GetContentLength(r)
@@ -1377,6 +1562,14 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		reader = r.Body
 	}
 
+	// Server-side encryption
+	var encryption *ObjectEncryption
+	if encryption, errorCode, err = newObjectEncryption(r, vol); errorCode != nil || err != nil {
+		log.LogErrorf("putObjectHandler: resolve encryption fail: requestID(%v) volume(%v) path(%v) errorCode(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), errorCode, err)
+		return
+	}
+
 	// Put Object
 	opt := &PutFileOption{
 		MIMEType:     contentType,
@@ -1387,6 +1580,9 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		Expires:      expires,
 		ACL:          acl,
 		ObjectLock:   objetLock,
+		Retention:    retention,
+		LegalHold:    legalHold,
+		Encryption:   encryption,
 	}
 	start := time.Now()
 	fsFileInfo, err := vol.PutObject(param.Object(), reader, opt)
@@ -1408,6 +1604,15 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 
 	// set response header
 	w.Header()[ETag] = []string{wrapUnescapedQuot(fsFileInfo.ETag)}
+	if fsFileInfo.VersionId != "" {
+		w.Header()[XAmzVersionId] = []string{fsFileInfo.VersionId}
+	}
+	setSSEResponseHeaders(w, fsFileInfo.Encryption)
+
+	if err = o.replicateObjectEvent(r, vol, param.Object(), fsFileInfo.VersionId, false); err != nil {
+		return
+	}
+	o.notifyObjectEvent(r, vol, EventObjectCreatedPut, param.Object(), fsFileInfo)
 	return
 }
 
@@ -1499,6 +1704,10 @@ func (o *ObjectNode) postObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode.ErrorMessage = fmt.Sprintf("%s (%s)", errorCode.ErrorMessage, "Invalid utf8 string or the key is too long")
 		return
 	}
+	if isVersionsKey(key) {
+		errorCode = InvalidKey
+		return
+	}
 
 	var aclInfo *AccessControlPolicy
 	if acl := formReq.MultipartFormValue("acl"); acl != "" {
@@ -1606,6 +1815,14 @@ func (o *ObjectNode) postObjectHandler(w http.ResponseWriter, r *http.Request) {
 		reader = f
 	}
 
+	// server-side encryption
+	var encryption *ObjectEncryption
+	if encryption, errorCode, err = newObjectEncryption(r, vol); errorCode != nil || err != nil {
+		log.LogErrorf("postObjectHandler: resolve encryption fail: requestID(%v) volume(%v) path(%v) errorCode(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, errorCode, err)
+		return
+	}
+
 	// put object
 	putOpt := &PutFileOption{
 		MIMEType:     contentType,
@@ -1616,6 +1833,7 @@ func (o *ObjectNode) postObjectHandler(w http.ResponseWriter, r *http.Request) {
 		Expires:      expires,
 		ACL:          aclInfo,
 		ObjectLock:   objetLock,
+		Encryption:   encryption,
 	}
 	start := time.Now()
 	fsFileInfo, err := vol.PutObject(key, reader, putOpt)
@@ -1638,6 +1856,12 @@ func (o *ObjectNode) postObjectHandler(w http.ResponseWriter, r *http.Request) {
 	// set response header
 	etag := wrapUnescapedQuot(fsFileInfo.ETag)
 	w.Header()[ETag] = []string{etag}
+	setSSEResponseHeaders(w, fsFileInfo.Encryption)
+
+	if err = o.replicateObjectEvent(r, vol, key, fsFileInfo.VersionId, false); err != nil {
+		return
+	}
+	o.notifyObjectEvent(r, vol, EventObjectCreatedPost, key, fsFileInfo)
 
 	// return response depending on success_action_xxx parameter
 	if successRedirectURL != nil {
@@ -1696,7 +1920,7 @@ func (o *ObjectNode) deleteObjectHandler(w http.ResponseWriter, r *http.Request)
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -1715,13 +1939,15 @@ func (o *ObjectNode) deleteObjectHandler(w http.ResponseWriter, r *http.Request)
 	}
 	defer rateLimit.ReleaseLimitResource(vol.owner, param.apiName)
 
+	versionId := r.URL.Query().Get(ParamVersionId)
+
 	// Audit deletion
-	log.LogInfof("Audit: delete object: requestID(%v) remote(%v) volume(%v) path(%v)",
-		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object())
+	log.LogInfof("Audit: delete object: requestID(%v) remote(%v) volume(%v) path(%v) versionId(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object(), versionId)
 
 	// Delete file
 	start := time.Now()
-	err = vol.DeletePath(param.Object())
+	versionId, deleteMarker, err := vol.DeleteObject(param.Object(), versionId, o.allowBypassGovernance(r, param, param.Object()))
 	span.AppendTrackLog("file.d", start, err)
 	if err != nil {
 		log.LogErrorf("deleteObjectHandler: Volume delete file fail: "+
@@ -1731,6 +1957,24 @@ func (o *ObjectNode) deleteObjectHandler(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
+	if versionId != "" {
+		w.Header()[XAmzVersionId] = []string{versionId}
+	}
+	if deleteMarker {
+		w.Header()[XAmzDeleteMarker] = []string{"true"}
+	}
+
+	// deletion of a specified version is never replicated
+	if deleteMarker && r.URL.Query().Get(ParamVersionId) == "" {
+		if err = o.replicateObjectEvent(r, vol, param.Object(), versionId, true); err != nil {
+			return
+		}
+	}
+	event := EventObjectRemovedDelete
+	if deleteMarker {
+		event = EventObjectRemovedDeleteMarkerCreated
+	}
+	o.notifyObjectEvent(r, vol, event, param.Object(), &FSFileInfo{VersionId: versionId})
 
 	w.WriteHeader(http.StatusNoContent)
 	return
@@ -1754,7 +1998,7 @@ func (o *ObjectNode) getObjectTaggingHandler(w http.ResponseWriter, r *http.Requ
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -1816,7 +2060,7 @@ func (o *ObjectNode) putObjectTaggingHandler(w http.ResponseWriter, r *http.Requ
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -1894,7 +2138,7 @@ func (o *ObjectNode) deleteObjectTaggingHandler(w http.ResponseWriter, r *http.R
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -1942,7 +2186,7 @@ func (o *ObjectNode) putObjectXAttrHandler(w http.ResponseWriter, r *http.Reques
 		errorCode = InvalidBucketName
 		return
 	}
-	if len(param.Object()) == 0 {
+	if len(param.Object()) == 0 || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -2019,7 +2263,7 @@ func (o *ObjectNode) getObjectXAttrHandler(w http.ResponseWriter, r *http.Reques
 		errorCode = InvalidBucketName
 		return
 	}
-	if len(param.Object()) == 0 {
+	if len(param.Object()) == 0 || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -2089,7 +2333,7 @@ func (o *ObjectNode) deleteObjectXAttrHandler(w http.ResponseWriter, r *http.Req
 		errorCode = InvalidBucketName
 		return
 	}
-	if len(param.Object()) == 0 {
+	if len(param.Object()) == 0 || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -2145,7 +2389,7 @@ func (o *ObjectNode) listObjectXAttrs(w http.ResponseWriter, r *http.Request) {
 		errorCode = InvalidBucketName
 		return
 	}
-	if len(param.Object()) == 0 {
+	if len(param.Object()) == 0 || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -2208,7 +2452,7 @@ func (o *ObjectNode) getObjectRetentionHandler(w http.ResponseWriter, r *http.Re
 		errorCode = InvalidBucketName
 		return
 	}
-	if param.Object() == "" {
+	if param.Object() == "" || isVersionsKey(param.Object()) {
 		errorCode = InvalidKey
 		return
 	}
@@ -2218,33 +2462,27 @@ func (o *ObjectNode) getObjectRetentionHandler(w http.ResponseWriter, r *http.Re
 			GetRequestID(r), err)
 		return
 	}
+	versionId := r.URL.Query().Get(ParamVersionId)
 
-	// get object meta
+	// get object lock state
 	start := time.Now()
-	_, xattrs, err := vol.ObjectMeta(param.Object())
+	_, state, err := vol.objectVersionLockState(param.Object(), versionId)
 	span.AppendTrackLog("meta.r", start, err)
 	if err != nil {
-		log.LogErrorf("getObjectRetentionHandler: get file meta fail: requestId(%v) volume(%v) path(%v) err(%v)",
-			GetRequestID(r), vol.Name(), param.Object(), err)
+		log.LogErrorf("getObjectRetentionHandler: get object lock state fail: requestId(%v) volume(%v) path(%v) versionId(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), versionId, err)
 		if err == syscall.ENOENT {
 			errorCode = NoSuchKey
 		}
 		return
 	}
-	retainUntilDate := string(xattrs.Get(XAttrKeyOSSLock))
-	if retainUntilDate == "" {
+	if !state.hasRetention() {
 		errorCode = NoSuchObjectLockConfiguration
 		return
 	}
-	retainUntilDateInt64, err := strconv.ParseInt(retainUntilDate, 10, 64)
-	if err != nil {
-		log.LogErrorf("getObjectRetentionHandler: parse retainUntilDate fail: requestId(%v) volume(%v) path(%v) err(%v)",
-			GetRequestID(r), vol.Name(), param.Object(), err)
-		return
-	}
 	var objectRetention ObjectRetention
-	objectRetention.Mode = ComplianceMode
-	objectRetention.RetainUntilDate = RetentionDate{Time: time.Unix(0, retainUntilDateInt64).UTC()}
+	objectRetention.Mode = state.mode
+	objectRetention.RetainUntilDate = RetentionDate{Time: state.retainUntilDate}
 	b, err := xml.Marshal(objectRetention)
 	if err != nil {
 		log.LogErrorf("getObjectRetentionHandler: xml marshal fail: requestId(%v) volume(%v) result(%v) err(%v)",
@@ -2256,6 +2494,259 @@ func (o *ObjectNode) getObjectRetentionHandler(w http.ResponseWriter, r *http.Re
 	return
 }
 
+// PutObjectRetention
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectRetention.html
+func (o *ObjectNode) putObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+
+	span := trace.SpanFromContextSafe(r.Context())
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	if param.Object() == "" || isVersionsKey(param.Object()) {
+		errorCode = InvalidKey
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putObjectRetentionHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	// QPS and Concurrency Limit
+	rateLimit := o.AcquireRateLimiter()
+	if err = rateLimit.AcquireLimitResource(vol.owner, param.apiName); err != nil {
+		return
+	}
+	defer rateLimit.ReleaseLimitResource(vol.owner, param.apiName)
+
+	objectLock, err := vol.metaLoader.loadObjectLock()
+	if err != nil {
+		log.LogErrorf("putObjectRetentionHandler: load volume objectLock fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if objectLock == nil {
+		errorCode = ObjectLockConfigurationMissing
+		return
+	}
+
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(r.Body, MaxObjectLockSize+1)); err != nil {
+		log.LogErrorf("putObjectRetentionHandler: read request body fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		return
+	}
+	if len(body) > MaxObjectLockSize {
+		errorCode = EntityTooLarge
+		return
+	}
+	var retention *ObjectRetention
+	if retention, err = ParseObjectRetentionFromXML(body); err != nil {
+		log.LogErrorf("putObjectRetentionHandler: parse retention fail: requestID(%v) volume(%v) path(%v) retention(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), string(body), err)
+		return
+	}
+
+	versionId := r.URL.Query().Get(ParamVersionId)
+	bypassGovernance := o.allowBypassGovernance(r, param, param.Object())
+	start := time.Now()
+	err = vol.PutObjectRetention(param.Object(), versionId, retention, bypassGovernance)
+	span.AppendTrackLog("xattr.w", start, err)
+	if err != nil {
+		log.LogErrorf("putObjectRetentionHandler: put retention fail: requestID(%v) volume(%v) path(%v) versionId(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), versionId, err)
+		if err == syscall.ENOENT {
+			errorCode = NoSuchKey
+		}
+		return
+	}
+
+	return
+}
+
+// GetObjectLegalHold
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectLegalHold.html
+func (o *ObjectNode) getObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+
+	span := trace.SpanFromContextSafe(r.Context())
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	if param.Object() == "" || isVersionsKey(param.Object()) {
+		errorCode = InvalidKey
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getObjectLegalHoldHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	versionId := r.URL.Query().Get(ParamVersionId)
+
+	start := time.Now()
+	_, state, err := vol.objectVersionLockState(param.Object(), versionId)
+	span.AppendTrackLog("meta.r", start, err)
+	if err != nil {
+		log.LogErrorf("getObjectLegalHoldHandler: get object lock state fail: requestID(%v) volume(%v) path(%v) versionId(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), versionId, err)
+		if err == syscall.ENOENT {
+			errorCode = NoSuchKey
+		}
+		return
+	}
+	legalHold := ObjectLegalHold{Status: LegalHoldOff}
+	if state.legalHold {
+		legalHold.Status = LegalHoldOn
+	}
+	b, err := xml.Marshal(legalHold)
+	if err != nil {
+		log.LogErrorf("getObjectLegalHoldHandler: xml marshal fail: requestID(%v) volume(%v) result(%v) err(%v)",
+			GetRequestID(r), vol.Name(), legalHold, err)
+		return
+	}
+
+	writeSuccessResponseXML(w, b)
+	return
+}
+
+// PutObjectLegalHold
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectLegalHold.html
+func (o *ObjectNode) putObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+
+	span := trace.SpanFromContextSafe(r.Context())
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	if param.Object() == "" || isVersionsKey(param.Object()) {
+		errorCode = InvalidKey
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putObjectLegalHoldHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	// QPS and Concurrency Limit
+	rateLimit := o.AcquireRateLimiter()
+	if err = rateLimit.AcquireLimitResource(vol.owner, param.apiName); err != nil {
+		return
+	}
+	defer rateLimit.ReleaseLimitResource(vol.owner, param.apiName)
+
+	objectLock, err := vol.metaLoader.loadObjectLock()
+	if err != nil {
+		log.LogErrorf("putObjectLegalHoldHandler: load volume objectLock fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if objectLock == nil {
+		errorCode = ObjectLockConfigurationMissing
+		return
+	}
+
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(r.Body, MaxObjectLockSize+1)); err != nil {
+		log.LogErrorf("putObjectLegalHoldHandler: read request body fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		return
+	}
+	if len(body) > MaxObjectLockSize {
+		errorCode = EntityTooLarge
+		return
+	}
+	var legalHold *ObjectLegalHold
+	if legalHold, err = ParseObjectLegalHoldFromXML(body); err != nil {
+		log.LogErrorf("putObjectLegalHoldHandler: parse legal hold fail: requestID(%v) volume(%v) path(%v) legalHold(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), string(body), err)
+		return
+	}
+
+	versionId := r.URL.Query().Get(ParamVersionId)
+	start := time.Now()
+	err = vol.PutObjectLegalHold(param.Object(), versionId, legalHold.Status)
+	span.AppendTrackLog("xattr.w", start, err)
+	if err != nil {
+		log.LogErrorf("putObjectLegalHoldHandler: put legal hold fail: requestID(%v) volume(%v) path(%v) versionId(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), versionId, err)
+		if err == syscall.ENOENT {
+			errorCode = NoSuchKey
+		}
+		return
+	}
+
+	return
+}
+
+// allowBypassGovernance checks whether the request bypasses the retention of the object in GOVERNANCE mode,
+// which is allowed for the root and admin users, and the users granted s3:BypassGovernanceRetention
+// explicitly by the bucket policy. Neither the owner nor the write permission of the bucket implies it.
+func (o *ObjectNode) allowBypassGovernance(r *http.Request, param *RequestParam, key string) bool {
+	if !strings.EqualFold(r.Header.Get(XAmzBypassGovernanceRetention), "true") || isAnonymous(param.AccessKey()) {
+		return false
+	}
+	userInfo, err := o.getUserInfoByAccessKey(param.AccessKey())
+	if err != nil {
+		log.LogWarnf("allowBypassGovernance: load user info fail: requestID(%v) accessKey(%v) err(%v)",
+			GetRequestID(r), param.AccessKey(), err)
+		return false
+	}
+	if userInfo.UserType == proto.UserTypeRoot || userInfo.UserType == proto.UserTypeAdmin {
+		return true
+	}
+	vol, _, policy, err := o.loadBucketMeta(param.Bucket())
+	if err != nil {
+		log.LogWarnf("allowBypassGovernance: load bucket metadata fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return false
+	}
+	if policy == nil || policy.IsEmpty() {
+		return false
+	}
+	bypassParam := *param
+	bypassParam.apiName = BYPASS_GOVERNANCE
+	conditionCheck := map[string]string{
+		SOURCEIP: param.sourceIP,
+		REFERER:  r.Referer(),
+		KEYNAME:  key,
+		HOST:     r.Host,
+	}
+	return policy.IsAllowed(&bypassParam, userInfo.UserID, vol.owner, conditionCheck) == POLICY_ALLOW
+}
+
 func parsePartInfo(partNumber uint64, fileSize uint64) (uint64, uint64, uint64, uint64) {
 	var partSize uint64
 	var partCount uint64
@@ -63,6 +63,12 @@ func (w *ResponseStater) WriteHeader(code int) {
 	w.hasWroteHeader = true
 }
 
+func (w *ResponseStater) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func (w *ResponseStater) ExtraHeader() http.Header {
 	h := make(http.Header)
 	if eh, ok := w.ResponseWriter.(auditlog.ResponseExtraHeader); ok {
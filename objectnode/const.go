@@ -14,7 +14,11 @@
 
 package objectnode
 
-import "os"
+import (
+	"os"
+
+	"github.com/cubefs/cubefs/proto"
+)
 
 const (
 	MaxRetry = 3
@@ -85,6 +89,21 @@ const (
 	XAmzSecurityToken               = "X-Amz-Security-Token" // #nosec G101
 	XAmzObjectLockMode              = "X-Amz-Object-Lock-Mode"
 	XAmzObjectLockRetainUntilDate   = "X-Amz-Object-Lock-Retain-Until-Date"
+	XAmzObjectLockLegalHold         = "X-Amz-Object-Lock-Legal-Hold"
+	XAmzBypassGovernanceRetention   = "X-Amz-Bypass-Governance-Retention"
+	XAmzVersionId                   = "x-amz-version-id"
+	XAmzDeleteMarker                = "x-amz-delete-marker"
+	XAmzReplicationStatus           = "x-amz-replication-status"
+	XAmzCopySourceVersionId         = "x-amz-copy-source-version-id"
+	XAmzRestore                     = "x-amz-restore"
+
+	XAmzServerSideEncryption                            = "x-amz-server-side-encryption"
+	XAmzServerSideEncryptionCustomerAlgorithm           = "x-amz-server-side-encryption-customer-algorithm"
+	XAmzServerSideEncryptionCustomerKey                 = "x-amz-server-side-encryption-customer-key"
+	XAmzServerSideEncryptionCustomerKeyMD5              = "x-amz-server-side-encryption-customer-key-MD5"
+	XAmzCopySourceServerSideEncryptionCustomerAlgorithm = "x-amz-copy-source-server-side-encryption-customer-algorithm"
+	XAmzCopySourceServerSideEncryptionCustomerKey       = "x-amz-copy-source-server-side-encryption-customer-key"
+	XAmzCopySourceServerSideEncryptionCustomerKeyMD5    = "x-amz-copy-source-server-side-encryption-customer-key-MD5"
 
 	HeaderNameXAmzDecodedContentLength = "x-amz-decoded-content-length"
 )
@@ -116,6 +135,9 @@ const (
 	ParamStartAfter = "start-after"
 	ParamKey        = "key"
 
+	ParamVersionId       = "versionId"
+	ParamVersionIdMarker = "version-id-marker"
+
 	ParamMaxParts       = "max-parts"
 	ParamUploadIdMarker = "upload-id-marker"
 	ParamPartNoMarker   = "part-number-marker"
@@ -139,21 +161,39 @@ const (
 
 const (
 	StorageClassStandard = "STANDARD"
+	// StorageClassGlacier is the storage class of the objects transitioned to the blobstore.
+	StorageClassGlacier = proto.S3StorageClassGlacier
 )
 
 // XAttr keys for ObjectNode compatible feature
 const (
-	XAttrKeyOSSPrefix       = "oss:"
-	XAttrKeyOSSETag         = "oss:etag"
-	XAttrKeyOSSTagging      = "oss:tagging"
-	XAttrKeyOSSPolicy       = "oss:policy"
-	XAttrKeyOSSACL          = "oss:acl"
-	XAttrKeyOSSMIME         = "oss:mime"
-	XAttrKeyOSSDISPOSITION  = "oss:disposition"
-	XAttrKeyOSSCORS         = "oss:cors"
-	XAttrKeyOSSLock         = "oss:lock"
-	XAttrKeyOSSCacheControl = "oss:cache"
-	XAttrKeyOSSExpires      = "oss:expires"
+	XAttrKeyOSSPrefix            = "oss:"
+	XAttrKeyOSSETag              = "oss:etag"
+	XAttrKeyOSSTagging           = "oss:tagging"
+	XAttrKeyOSSPolicy            = "oss:policy"
+	XAttrKeyOSSACL               = "oss:acl"
+	XAttrKeyOSSMIME              = "oss:mime"
+	XAttrKeyOSSDISPOSITION       = "oss:disposition"
+	XAttrKeyOSSCORS              = "oss:cors"
+	XAttrKeyOSSLock              = "oss:lock"
+	XAttrKeyOSSLockMode          = "oss:lock-mode"
+	XAttrKeyOSSLegalHold         = "oss:legal-hold"
+	XAttrKeyOSSCacheControl      = "oss:cache"
+	XAttrKeyOSSExpires           = "oss:expires"
+	XAttrKeyOSSVersioning        = "oss:versioning"
+	XAttrKeyOSSVersionId         = "oss:version-id"
+	XAttrKeyOSSDeleteMarker      = proto.S3XAttrKeyDeleteMarker
+	XAttrKeyOSSReplication       = "oss:replication"
+	XAttrKeyOSSReplStatus        = "oss:replication-status"
+	XAttrKeyOSSEncryption        = "oss:encryption"
+	XAttrKeyOSSSSE               = "oss:sse"
+	XAttrKeyOSSNotification      = "oss:notification"
+	XAttrKeyOSSWebsite           = "oss:website"
+	XAttrKeyOSSPublicAccessBlock = "oss:public-access-block"
+	XAttrKeyOSSStorageClass      = proto.S3XAttrKeyStorageClass
+	XAttrKeyOSSRestoreExpiry     = proto.S3XAttrKeyRestoreExpiry
+	XAttrKeyOSSRestoreOngoing    = "oss:restore-ongoing"
+	XAttrKeyOSSNoncurrentTime    = proto.S3XAttrKeyNoncurrentTime
 
 	// Deprecated
 	XAttrKeyOSSETagDeprecated = "oss:tag"
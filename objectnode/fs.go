@@ -34,6 +34,34 @@ type FSFileInfo struct {
 	Expires         string
 	Metadata        map[string]string `graphql:"-"` // User-defined metadata
 	RetainUntilDate string
+	LockMode        string
+	LegalHold       string
+	VersionId       string
+	DeleteMarker    bool
+	// ReplicationStatus is one of PENDING, COMPLETED, FAILED and REPLICA, empty if not replicated.
+	ReplicationStatus string
+	// Encryption is the server-side encryption envelope, nil if the object is not encrypted.
+	Encryption *ObjectEncryption `graphql:"-"`
+	// StorageClass is GLACIER once the data is transitioned to the blobstore, empty for STANDARD.
+	StorageClass string
+	// Restore is the state of the copy restored for the reads of the object transitioned, nil if not restored.
+	Restore *ObjectRestore `graphql:"-"`
+}
+
+// ObjectStorageClass returns the storage class of the object.
+func (i *FSFileInfo) ObjectStorageClass() string {
+	if i.StorageClass == "" {
+		return StorageClassStandard
+	}
+	return i.StorageClass
+}
+
+// ObjectVersionId returns the version ID of the object. Objects without a version ID are null versions.
+func (i *FSFileInfo) ObjectVersionId() string {
+	if i.VersionId == "" {
+		return NullVersionId
+	}
+	return i.VersionId
 }
 
 type Prefixes []string
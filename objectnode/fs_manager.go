@@ -40,6 +40,17 @@ type VolumeLoader struct {
 	closeOnce  sync.Once
 	closeCh    chan struct{}
 	metaStrict bool
+	encryption *VolumeEncryptionConfig
+}
+
+// VolumeEncryptionConfig is the identity in authnode used to get the master keys of the
+// encrypted volumes, the files of which are encrypted and decrypted by the ObjectNode.
+type VolumeEncryptionConfig struct {
+	ClientID    string   `json:"clientId"`
+	ClientKey   string   `json:"clientKey"`
+	AuthNodes   []string `json:"authNodes"`
+	EnableHTTPS bool     `json:"enableHTTPS"`
+	CertFile    string   `json:"certFile"`
 }
 
 func (loader *VolumeLoader) blacklistCleanup() {
@@ -128,6 +139,7 @@ func (loader *VolumeLoader) loadVolumeWithoutBlacklist(volName string) (*Volume,
 			Store:            loader.store,
 			OnAsyncTaskError: onAsyncTaskError,
 			MetaStrict:       loader.metaStrict,
+			Encryption:       loader.encryption,
 		}
 		if volume, err = NewVolume(config); err != nil {
 			if err != proto.ErrVolNotExists {
@@ -193,6 +205,7 @@ func (loader *VolumeLoader) loadVolume(volName string) (*Volume, error) {
 			Store:            loader.store,
 			OnAsyncTaskError: onAsyncTaskError,
 			MetaStrict:       loader.metaStrict,
+			Encryption:       loader.encryption,
 		}
 		if volume, err = NewVolume(config); err != nil {
 			log.LogDebugf("loadVolume: new volume fail, add to blacklist: volume(%v) err(%v)", volName, err)
@@ -231,13 +244,14 @@ func (loader *VolumeLoader) Close() {
 	})
 }
 
-func NewVolumeLoader(masters []string, store Store, strict bool) *VolumeLoader {
+func NewVolumeLoader(masters []string, store Store, strict bool, encryption *VolumeEncryptionConfig) *VolumeLoader {
 	loader := &VolumeLoader{
 		masters:    masters,
 		store:      store,
 		volumes:    make(map[string]*Volume),
 		closeCh:    make(chan struct{}),
 		metaStrict: strict,
+		encryption: encryption,
 	}
 	go loader.blacklistCleanup()
 	return loader
@@ -249,6 +263,7 @@ type VolumeManager struct {
 	loaders    [volumeLoaderNum]*VolumeLoader
 	store      Store
 	metaStrict bool
+	encryption *VolumeEncryptionConfig
 	closeOnce  sync.Once
 	closeCh    chan struct{}
 }
@@ -284,15 +299,16 @@ func (m *VolumeManager) init() {
 		vm: m,
 	}
 	for i := 0; i < len(m.loaders); i++ {
-		m.loaders[i] = NewVolumeLoader(m.masters, m.store, m.metaStrict)
+		m.loaders[i] = NewVolumeLoader(m.masters, m.store, m.metaStrict, m.encryption)
 	}
 }
 
-func NewVolumeManager(masters []string, strict bool) *VolumeManager {
+func NewVolumeManager(masters []string, strict bool, encryption *VolumeEncryptionConfig) *VolumeManager {
 	manager := &VolumeManager{
 		masters:    masters,
 		closeCh:    make(chan struct{}),
 		metaStrict: strict,
+		encryption: encryption,
 	}
 	manager.init()
 	return manager
@@ -39,6 +39,7 @@ import (
 	"github.com/cubefs/cubefs/sdk/master"
 	"github.com/cubefs/cubefs/sdk/meta"
 	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/auth"
 	"github.com/cubefs/cubefs/util/buf"
 	"github.com/cubefs/cubefs/util/exporter"
 	"github.com/cubefs/cubefs/util/log"
@@ -81,6 +82,10 @@ type VolumeConfig struct {
 
 	// Get OSSMeta from the MetaNode every time if it is set true.
 	MetaStrict bool
+
+	// Identity in authnode to access the encrypted volume.
+	// This is a optional configuration item.
+	Encryption *VolumeEncryptionConfig
 }
 
 type PutFileOption struct {
@@ -92,6 +97,13 @@ type PutFileOption struct {
 	CacheControl string
 	Expires      string
 	ObjectLock   *ObjectLockConfig
+	// Retention and LegalHold are specified by the request, they take effect only if ObjectLock is set.
+	Retention *ObjectRetention
+	LegalHold string
+	// ReplicationStatus is set to REPLICA on objects written by replication.
+	ReplicationStatus string
+	// Encryption is the server-side encryption envelope with the data key, nil if not encrypted.
+	Encryption *ObjectEncryption
 }
 
 type ListFilesV1Option struct {
@@ -109,6 +121,27 @@ type ListFilesV1Result struct {
 	CommonPrefixes []string
 }
 
+type ListObjectVersionsOption struct {
+	Prefix          string
+	Delimiter       string
+	KeyMarker       string
+	VersionIdMarker string
+	MaxKeys         uint64
+}
+
+type ObjectVersionInfo struct {
+	*FSFileInfo
+	IsLatest bool
+}
+
+type ListObjectVersionsResult struct {
+	Versions            []*ObjectVersionInfo
+	CommonPrefixes      []string
+	NextKeyMarker       string
+	NextVersionIdMarker string
+	IsTruncated         bool
+}
+
 type ListFilesV2Option struct {
 	Delimiter  string
 	MaxKeys    uint64
@@ -198,6 +231,42 @@ func (v *Volume) loadOSSMeta() {
 		return
 	}
 	v.metaLoader.storeObjectLock(objectlock)
+
+	var versioning *VersioningConfiguration
+	if versioning, err = v.loadVersioning(); err != nil {
+		return
+	}
+	v.metaLoader.storeVersioning(versioning)
+
+	var replication *ReplicationConfiguration
+	if replication, err = v.loadReplication(); err != nil {
+		return
+	}
+	v.metaLoader.storeReplication(replication)
+
+	var encryption *ServerSideEncryptionConfiguration
+	if encryption, err = v.loadBucketEncryption(); err != nil {
+		return
+	}
+	v.metaLoader.storeEncryption(encryption)
+
+	var notification *NotificationConfiguration
+	if notification, err = v.loadNotification(); err != nil {
+		return
+	}
+	v.metaLoader.storeNotification(notification)
+
+	var website *WebsiteConfiguration
+	if website, err = v.loadBucketWebsite(); err != nil {
+		return
+	}
+	v.metaLoader.storeWebsite(website)
+
+	var publicAccessBlock *PublicAccessBlockConfiguration
+	if publicAccessBlock, err = v.loadBucketPublicAccessBlock(); err != nil {
+		return
+	}
+	v.metaLoader.storePublicAccessBlock(publicAccessBlock)
 	v.metaLoader.setSynced()
 }
 
@@ -277,6 +346,96 @@ func (v *Volume) loadObjectLock() (configuration *ObjectLockConfig, err error) {
 	return configuration, nil
 }
 
+func (v *Volume) loadReplication() (configuration *ReplicationConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSReplication); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	configuration = &ReplicationConfiguration{}
+	if err = json.Unmarshal(raw, configuration); err != nil {
+		return
+	}
+	return configuration, nil
+}
+
+func (v *Volume) loadBucketEncryption() (configuration *ServerSideEncryptionConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSEncryption); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	configuration = &ServerSideEncryptionConfiguration{}
+	if err = json.Unmarshal(raw, configuration); err != nil {
+		return
+	}
+	return configuration, nil
+}
+
+func (v *Volume) loadNotification() (configuration *NotificationConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSNotification); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	configuration = &NotificationConfiguration{}
+	if err = json.Unmarshal(raw, configuration); err != nil {
+		return
+	}
+	return configuration, nil
+}
+
+func (v *Volume) loadBucketWebsite() (configuration *WebsiteConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSWebsite); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	configuration = &WebsiteConfiguration{}
+	if err = json.Unmarshal(raw, configuration); err != nil {
+		return
+	}
+	return configuration, nil
+}
+
+func (v *Volume) loadBucketPublicAccessBlock() (configuration *PublicAccessBlockConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSPublicAccessBlock); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	configuration = &PublicAccessBlockConfiguration{}
+	if err = json.Unmarshal(raw, configuration); err != nil {
+		return
+	}
+	return configuration, nil
+}
+
+func (v *Volume) loadVersioning() (configuration *VersioningConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSVersioning); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	configuration = &VersioningConfiguration{}
+	if err = json.Unmarshal(raw, configuration); err != nil {
+		return
+	}
+	return configuration, nil
+}
+
 func (v *Volume) getInodeFromPath(path string) (inode uint64, err error) {
 	if path == "/" {
 		return volumeRootInode, nil
@@ -673,7 +832,7 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 
 	// check whether existing object is protected by object lock
 	if oldInode != 0 && opt != nil && opt.ObjectLock != nil {
-		err = isObjectLocked(v, oldInode, lastPathItem.Name, path)
+		err = isObjectLocked(v, oldInode, lastPathItem.Name, path, false)
 		if err != nil {
 			return
 		}
@@ -712,14 +871,27 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 		}
 	}()
 
+	var (
+		encryption *ObjectEncryption
+		dataHash   hash.Hash
+		plain      *ssePlainReader
+	)
+	if opt != nil {
+		encryption = opt.Encryption
+	}
+	if reader, dataHash, plain, err = encryptReader(reader, encryption, 0, md5Hash); err != nil {
+		log.LogErrorf("PutObject: init encryption fail: volume(%v) path(%v) err(%v)", v.name, path, err)
+		return
+	}
+
 	if proto.IsCold(v.volType) {
-		if _, err = v.ebsWrite(invisibleTempDataInode.Inode, reader, md5Hash); err != nil {
+		if _, err = v.ebsWrite(invisibleTempDataInode.Inode, reader, dataHash); err != nil {
 			log.LogErrorf("PutObject: ebs write fail: volume(%v) path(%v) inode(%v) err(%v)",
 				v.name, path, invisibleTempDataInode.Inode, err)
 			return
 		}
 	} else {
-		if _, err = v.streamWrite(invisibleTempDataInode.Inode, reader, md5Hash); err != nil {
+		if _, err = v.streamWrite(invisibleTempDataInode.Inode, reader, dataHash); err != nil {
 			log.LogErrorf("PutObject: stream write fail: volume(%v) path(%v) inode(%v) err(%v)",
 				v.name, path, invisibleTempDataInode.Inode, err)
 			return
@@ -771,8 +943,23 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 	if opt != nil && opt.ACL != nil {
 		attr.XAttrs[XAttrKeyOSSACL] = opt.ACL.Encode()
 	}
-	if opt != nil && opt.ObjectLock != nil && opt.ObjectLock.ToRetention() != nil {
-		attr.XAttrs[XAttrKeyOSSLock] = formatRetentionDateStr(finalInode.ModifyTime, opt.ObjectLock.ToRetention())
+	setObjectLockXAttrs(attr.XAttrs, finalInode.ModifyTime, opt)
+	var versionId string
+	if versionId, err = v.versionIdForWrite(); err != nil {
+		return
+	}
+	if versionId != "" {
+		attr.XAttrs[XAttrKeyOSSVersionId] = versionId
+	}
+	if opt != nil && opt.ReplicationStatus != "" {
+		attr.XAttrs[XAttrKeyOSSReplStatus] = opt.ReplicationStatus
+	}
+	size := int64(finalInode.Size)
+	if encryption != nil {
+		encryption.Size = int64(plain.size)
+		encryption.Parts = nil
+		attr.XAttrs[XAttrKeyOSSSSE] = encryption.Encode()
+		size = encryption.Size
 	}
 
 	// If user-defined metadata have been specified, use extend attributes for storage.
@@ -795,12 +982,14 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 	// create file info
 	fsInfo = &FSFileInfo{
 		Path:       path,
-		Size:       int64(finalInode.Size),
+		Size:       size,
 		Mode:       os.FileMode(finalInode.Mode),
 		CreateTime: finalInode.CreateTime,
 		ModifyTime: finalInode.ModifyTime,
 		ETag:       etagValue.ETag(),
 		Inode:      finalInode.Inode,
+		VersionId:  versionId,
+		Encryption: encryption,
 	}
 
 	// apply new inode to dentry
@@ -819,14 +1008,20 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 }
 
 func (v *Volume) applyInodeToDEntry(parentId uint64, name string, inode uint64, isCompleteMultipart bool, fullPath string) (err error) {
+	var existInode uint64
 	var existMode uint32
-	_, existMode, err = v.mw.Lookup_ll(parentId, name) // exist object inode
+	existInode, existMode, err = v.mw.Lookup_ll(parentId, name) // exist object inode
 	if err != nil && err != syscall.ENOENT {
 		log.LogErrorf("applyInodeToDEntry: meta lookup fail: parentID(%v) name(%v) err(%v)", parentId, name, err)
 		return
 	}
 
 	if err == syscall.ENOENT {
+		if err = v.keepNoncurrentVersion(fullPath, 0); err != nil {
+			log.LogErrorf("applyInodeToDEntry: keep noncurrent version fail: parentID(%v) name(%v) err(%v)",
+				parentId, name, err)
+			return
+		}
 		if err = v.applyInodeToNewDentry(parentId, name, inode, fullPath); err != nil {
 			log.LogErrorf("applyInodeToDEntry: apply inode to new dentry fail: parentID(%v) name(%v) inode(%v) err(%v)",
 				parentId, name, inode, err)
@@ -841,9 +1036,14 @@ func (v *Volume) applyInodeToDEntry(parentId uint64, name string, inode uint64,
 			err = syscall.EINVAL
 			return
 		}
-		// current implementation doesn't support object versioning, so uploading a object with a key already existed in bucket
-		// is implemented with replacing the old one instead.
+		// uploading a object with a key already existed in bucket is implemented with replacing the old one,
+		// and the old one is kept as a noncurrent version before replacing if versioning is configured.
 		// refer: https://docs.aws.amazon.com/AmazonS3/latest/userguide/upload-objects.html
+		if err = v.keepNoncurrentVersion(fullPath, existInode); err != nil {
+			log.LogErrorf("applyInodeToDEntry: keep noncurrent version fail: parentID(%v) name(%v) inode(%v) err(%v)",
+				parentId, name, existInode, err)
+			return
+		}
 		if err = v.applyInodeToExistDentry(parentId, name, inode, isCompleteMultipart, fullPath); err != nil {
 			log.LogErrorf("applyInodeToDEntry: apply inode to exist dentry fail: parentID(%v) name(%v) inode(%v) err(%v)",
 				parentId, name, inode, err)
@@ -861,6 +1061,11 @@ func (v *Volume) applyInodeToDEntry(parentId uint64, name string, inode uint64,
 // This method will only returns internal system errors.
 // This method will not return syscall.ENOENT error
 func (v *Volume) DeletePath(path string) (err error) {
+	return v.deletePath(path, false)
+}
+
+// deletePath deletes the path, the object retained in GOVERNANCE mode is deleted if bypassGovernance is true.
+func (v *Volume) deletePath(path string, bypassGovernance bool) (err error) {
 	defer func() {
 		// Audit behavior
 		log.LogInfof("Audit: DeletePath: volume(%v) path(%v), err(%v)", v.name, path, err)
@@ -897,11 +1102,16 @@ func (v *Volume) DeletePath(path string) (err error) {
 		log.LogErrorf("DeletePath: load volume objetLock: volume(%v) err(%v)", v.name, err)
 		return
 	}
+	if objetLock != nil && !mode.IsDir() {
+		if err = isObjectLocked(v, ino, name, path, bypassGovernance); err != nil {
+			return
+		}
+	}
 	log.LogInfof("DeletePath: delete: volume(%v) path(%v) inode(%v)", v.name, path, ino)
 
 	// delete dentry with condition when objectlock is open
 	if objetLock != nil {
-		_, err = v.mw.DeleteWithCond_ll(parent, ino, name, mode.IsDir(), path)
+		_, err = v.deleteWithCond(parent, ino, name, mode.IsDir(), path, bypassGovernance)
 	} else {
 		_, err = v.mw.Delete_ll(parent, name, mode.IsDir(), path)
 	}
@@ -926,6 +1136,15 @@ func (v *Volume) DeletePath(path string) (err error) {
 	return
 }
 
+// deleteWithCond deletes the dentry only if it still points to the inode, which is refused by the
+// meta wrapper if the inode is locked.
+func (v *Volume) deleteWithCond(parent, ino uint64, name string, isDir bool, path string, bypassGovernance bool) (*proto.InodeInfo, error) {
+	if bypassGovernance {
+		return v.mw.DeleteWithCondBypassGovernance_ll(parent, ino, name, isDir, path)
+	}
+	return v.mw.DeleteWithCond_ll(parent, ino, name, isDir, path)
+}
+
 func (v *Volume) InitMultipart(path string, opt *PutFileOption) (multipartID string, err error) {
 	defer func() {
 		log.LogInfof("Audit: InitMultipart: volume(%v) path(%v) multipartID(%v) err(%v)", v.name, path, multipartID, err)
@@ -963,6 +1182,10 @@ func (v *Volume) InitMultipart(path string, opt *PutFileOption) (multipartID str
 	if opt != nil && opt.ACL != nil {
 		extend[XAttrKeyOSSACL] = opt.ACL.Encode()
 	}
+	// If encryption have been specified, the parts are encrypted with the same data key.
+	if opt != nil && opt.Encryption != nil {
+		extend[XAttrKeyOSSSSE] = opt.Encryption.Encode()
+	}
 
 	if v.mw.EnableQuota {
 		var parentId uint64
@@ -986,7 +1209,7 @@ func (v *Volume) InitMultipart(path string, opt *PutFileOption) (multipartID str
 	return multipartID, nil
 }
 
-func (v *Volume) WritePart(path string, multipartId string, partId uint16, reader io.Reader) (*FSFileInfo, error) {
+func (v *Volume) WritePart(path string, multipartId string, partId uint16, reader io.Reader, enc *ObjectEncryption) (*FSFileInfo, error) {
 	var exist bool
 	var err error
 	defer func() {
@@ -1031,10 +1254,17 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 	}()
 
 	var (
-		size    uint64
-		etag    string
-		md5Hash = md5.New()
+		size     uint64
+		etag     string
+		md5Hash  = md5.New()
+		dataHash hash.Hash
+		plain    *ssePlainReader
 	)
+	if reader, dataHash, plain, err = encryptReader(reader, enc, partId, md5Hash); err != nil {
+		log.LogErrorf("WritePart: init encryption fail: volume(%v) path(%v) multipartID(%v) partID(%v) err(%v)",
+			v.name, path, multipartId, partId, err)
+		return nil, err
+	}
 	if err = v.ec.OpenStream(tempInodeInfo.Inode); err != nil {
 		log.LogErrorf("WritePart: data open stream fail: volume(%v) path(%v) multipartID(%v) partID(%v) inode(%v) err(%v)",
 			v.name, path, multipartId, partId, tempInodeInfo.Inode, err)
@@ -1047,14 +1277,14 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 		}
 	}()
 	if proto.IsCold(v.volType) {
-		if size, err = v.ebsWrite(tempInodeInfo.Inode, reader, md5Hash); err != nil {
+		if size, err = v.ebsWrite(tempInodeInfo.Inode, reader, dataHash); err != nil {
 			log.LogErrorf("WritePart: ebs write fail: volume(%v) inode(%v) multipartID(%v) partID(%v) err(%v)",
 				v.name, tempInodeInfo.Inode, multipartId, partId, err)
 			return nil, err
 		}
 	} else {
 		// Write data to data node
-		if size, err = v.streamWrite(tempInodeInfo.Inode, reader, md5Hash); err != nil {
+		if size, err = v.streamWrite(tempInodeInfo.Inode, reader, dataHash); err != nil {
 			log.LogErrorf("WritePart: stream write fail: volume(%v) inode(%v) multipartID(%v) partID(%v) err(%v)",
 				v.name, tempInodeInfo.Inode, multipartId, partId, err)
 			return nil, err
@@ -1068,6 +1298,10 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 
 	// compute file md5
 	etag = hex.EncodeToString(md5Hash.Sum(nil))
+	// the size of encrypted part is recorded in plaintext
+	if plain != nil {
+		size = plain.size
+	}
 
 	// update temp file inode to meta with session, overwrite existing part can result in exist == true
 	oldInode, exist, err = v.mw.AddMultipartPart_ll(path, multipartId, partId, size, etag, tempInodeInfo)
@@ -1163,7 +1397,7 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 		return
 	}
 	if oldInode != 0 && objectLock != nil {
-		err = isObjectLocked(v, oldInode, filename, path)
+		err = isObjectLocked(v, oldInode, filename, path, false)
 		if err != nil {
 			return
 		}
@@ -1215,6 +1449,13 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 				v.name, path, multipartID, completeInodeInfo.Inode, err)
 			return
 		}
+	} else if v.mw.EncryptionEnabled() {
+		// the parts are encrypted with their own keys, so the data is copied instead of the extent keys
+		if size, err = v.copyPartsData(completeInodeInfo.Inode, parts, path); err != nil {
+			log.LogErrorf("CompleteMultipart: copy parts data fail: volume(%v) path(%v) multipartID(%v) inode(%v) err(%v)",
+				v.name, path, multipartID, completeInodeInfo.Inode, err)
+			return
+		}
 	} else {
 		completeExtentKeys := make([]proto.ExtentKey, 0)
 		for _, part := range parts {
@@ -1281,8 +1522,28 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 			attrs[key] = value
 		}
 	}
-	if objectLock != nil && objectLock.ToRetention() != nil {
-		attrs[XAttrKeyOSSLock] = formatRetentionDateStr(finalInode.ModifyTime, objectLock.ToRetention())
+	setObjectLockXAttrs(attrs, finalInode.ModifyTime, &PutFileOption{ObjectLock: objectLock})
+	// parts of encrypted object are encrypted separately, record them to locate the plaintext
+	var encryption *ObjectEncryption
+	if encryption, err = parseObjectEncryption(extend[XAttrKeyOSSSSE]); err != nil {
+		log.LogErrorf("CompleteMultipart: parse encryption fail: volume(%v) multipartID(%v) err(%v)",
+			v.name, multipartID, err)
+		return
+	}
+	if encryption != nil {
+		encryption.Size = int64(size)
+		encryption.Parts = make([]SSEPart, 0, len(parts))
+		for _, part := range parts {
+			encryption.Parts = append(encryption.Parts, SSEPart{Number: part.ID, Size: int64(part.Size)})
+		}
+		attrs[XAttrKeyOSSSSE] = encryption.Encode()
+	}
+	var versionId string
+	if versionId, err = v.versionIdForWrite(); err != nil {
+		return
+	}
+	if versionId != "" {
+		attrs[XAttrKeyOSSVersionId] = versionId
 	}
 	if err = v.mw.BatchSetXAttr_ll(finalInode.Inode, attrs); err != nil {
 		log.LogErrorf("CompleteMultipart: store multipart extend fail: volume(%v) multipartID(%v) inode(%v) "+
@@ -1341,11 +1602,52 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 		ModifyTime: time.Now(),
 		ETag:       etagValue.ETag(),
 		Inode:      finalInode.Inode,
+		VersionId:  versionId,
+		Encryption: encryption,
 	}
 
 	return fInfo, nil
 }
 
+// inodeWriter writes the data sequentially into the inode with the extent client.
+type inodeWriter struct {
+	v      *Volume
+	inode  uint64
+	offset int
+}
+
+func (w *inodeWriter) Write(p []byte) (n int, err error) {
+	n, err = w.v.ec.Write(w.inode, w.offset, p, 0, nil)
+	w.offset += n
+	return
+}
+
+// copyPartsData copies the data of the parts into the inode in order and returns the size.
+func (v *Volume) copyPartsData(inode uint64, parts []*proto.MultipartPartInfo, path string) (size uint64, err error) {
+	if err = v.ec.OpenStream(inode); err != nil {
+		log.LogErrorf("copyPartsData: data open stream fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+		return
+	}
+	defer func() {
+		if closeErr := v.ec.CloseStream(inode); closeErr != nil {
+			log.LogWarnf("copyPartsData: data close stream fail: volume(%v) inode(%v) err(%v)", v.name, inode, closeErr)
+		}
+	}()
+	writer := &inodeWriter{v: v, inode: inode}
+	for _, part := range parts {
+		if err = v.readFile(part.Inode, part.Size, path, writer, 0, part.Size); err != nil {
+			log.LogErrorf("copyPartsData: copy part fail: volume(%v) inode(%v) partID(%v) partInode(%v) err(%v)",
+				v.name, inode, part.ID, part.Inode, err)
+			return
+		}
+		size += part.Size
+	}
+	if err = v.ec.Flush(inode); err != nil {
+		log.LogErrorf("copyPartsData: data flush fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+	}
+	return
+}
+
 func (v *Volume) ebsWrite(inode uint64, reader io.Reader, h hash.Hash) (size uint64, err error) {
 	ctx := context.Background()
 	size, err = v.getEbsWriter(inode).WriteFromReader(ctx, reader, h)
@@ -1510,7 +1812,7 @@ func (v *Volume) loadUserDefinedMetadata(inode uint64) (metadata map[string]stri
 	}
 	xattrKeys := make([]string, 0)
 	for _, storedXAttrKey := range storedXAttrKeys {
-		if !strings.HasPrefix(storedXAttrKey, "oss:") {
+		if !strings.HasPrefix(storedXAttrKey, "oss:") && storedXAttrKey != proto.XAttrFileKey {
 			xattrKeys = append(xattrKeys, storedXAttrKey)
 		}
 	}
@@ -1764,6 +2066,25 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, xattr *proto.XAttrIn
 			retainUntilDate = time.Unix(0, retainUntilDateInt64).UTC().Format(ISO8601Layout)
 		}
 	}
+	var lockMode string
+	if retainUntilDate != "" {
+		if lockMode = string(xattr.Get(XAttrKeyOSSLockMode)); lockMode == "" {
+			lockMode = ComplianceMode
+		}
+	}
+
+	// The size of encrypted object is the size of plaintext.
+	var encryption *ObjectEncryption
+	size := int64(inoInfo.Size)
+	if !mode.IsDir() {
+		if encryption, err = parseObjectEncryption(string(xattr.Get(XAttrKeyOSSSSE))); err != nil {
+			log.LogErrorf("ObjectMeta: parse encryption fail: volume(%v) path(%v) err(%v)", v.name, path, err)
+			return
+		}
+		if encryption != nil {
+			size = encryption.Size
+		}
+	}
 
 	// Validating ETag value.
 	if !mode.IsDir() && (!etagValue.Valid() || etagValue.TS.Before(inoInfo.ModifyTime)) {
@@ -1772,19 +2093,27 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, xattr *proto.XAttrIn
 	}
 
 	info = &FSFileInfo{
-		Path:            path,
-		Size:            int64(inoInfo.Size),
-		Mode:            os.FileMode(inoInfo.Mode),
-		CreateTime:      inoInfo.CreateTime,
-		ModifyTime:      inoInfo.ModifyTime,
-		ETag:            etagValue.ETag(),
-		Inode:           inoInfo.Inode,
-		MIMEType:        mimeType,
-		Disposition:     disposition,
-		CacheControl:    cacheControl,
-		Expires:         expires,
-		Metadata:        metadata,
-		RetainUntilDate: retainUntilDate,
+		Path:              path,
+		Size:              size,
+		Mode:              os.FileMode(inoInfo.Mode),
+		CreateTime:        inoInfo.CreateTime,
+		ModifyTime:        inoInfo.ModifyTime,
+		ETag:              etagValue.ETag(),
+		Inode:             inoInfo.Inode,
+		MIMEType:          mimeType,
+		Disposition:       disposition,
+		CacheControl:      cacheControl,
+		Expires:           expires,
+		Metadata:          metadata,
+		RetainUntilDate:   retainUntilDate,
+		LockMode:          lockMode,
+		LegalHold:         string(xattr.Get(XAttrKeyOSSLegalHold)),
+		VersionId:         string(xattr.Get(XAttrKeyOSSVersionId)),
+		DeleteMarker:      string(xattr.Get(XAttrKeyOSSDeleteMarker)) == "true",
+		ReplicationStatus: string(xattr.Get(XAttrKeyOSSReplStatus)),
+		Encryption:        encryption,
+		StorageClass:      string(xattr.Get(XAttrKeyOSSStorageClass)),
+		Restore:           parseObjectRestore(xattr),
 	}
 	return
 }
@@ -2299,7 +2628,7 @@ readDir:
 		currentPath, parentId, fromName, maxKeys, readLimit, children)
 
 	for _, child := range children {
-		if child.Name == lastKey {
+		if child.Name == lastKey || isVersionsDir(dirs, child.Name) {
 			continue
 		}
 		path := strings.Join(append(dirs, child.Name), pathSep)
@@ -2412,7 +2741,8 @@ func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo) (err error) {
 	}
 
 	// Get MD5 information in batches, then update to fileInfos
-	keys := []string{XAttrKeyOSSETag, XAttrKeyOSSETagDeprecated}
+	keys := []string{XAttrKeyOSSETag, XAttrKeyOSSETagDeprecated, XAttrKeyOSSVersionId, XAttrKeyOSSDeleteMarker, XAttrKeyOSSSSE,
+		XAttrKeyOSSStorageClass}
 	xattrs, err := v.mw.BatchGetXAttr(inodes, keys)
 	if err != nil {
 		log.LogErrorf("supplyListFileInfo: batch get xattr fail, inodes(%v), err(%v)", inodes, err)
@@ -2439,6 +2769,15 @@ func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo) (err error) {
 			if len(rawETag) > 0 {
 				etagValue = ParseETagValue(rawETag)
 			}
+			if versionId := string(xattr.Get(XAttrKeyOSSVersionId)); len(versionId) > 0 {
+				fileInfo.VersionId = versionId
+			}
+			fileInfo.DeleteMarker = string(xattr.Get(XAttrKeyOSSDeleteMarker)) == "true"
+			fileInfo.StorageClass = string(xattr.Get(XAttrKeyOSSStorageClass))
+			if enc, _ := parseObjectEncryption(string(xattr.Get(XAttrKeyOSSSSE))); enc != nil {
+				fileInfo.Size = enc.Size
+				fileInfo.Encryption = enc
+			}
 		}
 		if !etagValue.Valid() || etagValue.TS.Before(fileInfo.ModifyTime) {
 			// The ETag is invalid or outdated then generate a new ETag and make update.
@@ -2622,7 +2961,7 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		} else {
 			// check whether target object is protected by object lock
 			if opt != nil && opt.ObjectLock != nil {
-				err = isObjectLocked(v, sInode, sName, sourcePath)
+				err = isObjectLocked(v, sInode, sName, sourcePath, false)
 				if err != nil {
 					return
 				}
@@ -2650,9 +2989,7 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 			if opt != nil && opt.ACL != nil {
 				attr.XAttrs[XAttrKeyOSSACL] = opt.ACL.Encode()
 			}
-			if opt != nil && opt.ObjectLock != nil && opt.ObjectLock.ToRetention() != nil {
-				attr.XAttrs[XAttrKeyOSSLock] = formatRetentionDateStr(time.Now(), opt.ObjectLock.ToRetention())
-			}
+			setObjectLockXAttrs(attr.XAttrs, time.Now(), opt)
 			// If user-defined metadata have been specified, use extend attributes for storage.
 			if opt != nil && len(opt.Metadata) > 0 {
 				for name, value := range opt.Metadata {
@@ -2741,7 +3078,7 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 
 	// check whether existing object is protected by object lock
 	if oldtInode != 0 && opt != nil && opt.ObjectLock != nil {
-		err = isObjectLocked(v, oldtInode, tLastName, targetPath)
+		err = isObjectLocked(v, oldtInode, tLastName, targetPath, false)
 		if err != nil {
 			return
 		}
@@ -2872,6 +3209,10 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		},
 	}
 	targetAttr.XAttrs[XAttrKeyOSSETag] = etagValue.Encode()
+	var versionId string
+	if versionId, err = v.versionIdForWrite(); err != nil {
+		return
+	}
 
 	// copy source file metadata to write target file metadata
 	if metaDirective != MetadataDirectiveReplace {
@@ -2880,17 +3221,19 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 			return
 		}
 		for key, val := range xattr.XAttrs {
-			if key == XAttrKeyOSSETag {
+			if key == XAttrKeyOSSETag || key == XAttrKeyOSSVersionId || key == XAttrKeyOSSDeleteMarker || isObjectLockXAttr(key) ||
+				isLifecycleXAttr(key) || key == proto.XAttrFileKey {
 				continue
 			}
 			targetAttr.XAttrs[key] = val
 		}
+		if versionId != "" {
+			targetAttr.XAttrs[XAttrKeyOSSVersionId] = versionId
+		}
 		if opt != nil && opt.ACL != nil {
 			targetAttr.XAttrs[XAttrKeyOSSACL] = opt.ACL.Encode()
 		}
-		if opt != nil && opt.ObjectLock != nil && opt.ObjectLock.ToRetention() != nil {
-			targetAttr.XAttrs[XAttrKeyOSSLock] = formatRetentionDateStr(tInodeInfo.ModifyTime, opt.ObjectLock.ToRetention())
-		}
+		setObjectLockXAttrs(targetAttr.XAttrs, tInodeInfo.ModifyTime, opt)
 		if err = v.mw.BatchSetXAttr_ll(tInodeInfo.Inode, targetAttr.XAttrs); err != nil {
 			log.LogErrorf("CopyFile: set target xattr fail: volume(%v) target path(%v) inode(%v) xattr (%v)err(%v)",
 				v.name, targetPath, tInodeInfo.Inode, xattr, err)
@@ -2917,8 +3260,9 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		if opt != nil && opt.ACL != nil {
 			targetAttr.XAttrs[XAttrKeyOSSACL] = opt.ACL.Encode()
 		}
-		if opt != nil && opt.ObjectLock != nil && opt.ObjectLock.ToRetention() != nil {
-			targetAttr.XAttrs[XAttrKeyOSSLock] = formatRetentionDateStr(tInodeInfo.ModifyTime, opt.ObjectLock.ToRetention())
+		setObjectLockXAttrs(targetAttr.XAttrs, tInodeInfo.ModifyTime, opt)
+		if versionId != "" {
+			targetAttr.XAttrs[XAttrKeyOSSVersionId] = versionId
 		}
 
 		// If user-defined metadata have been specified, use extend attributes for storage.
@@ -2951,6 +3295,7 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		CreateTime: tInodeInfo.CreateTime,
 		ETag:       md5Value,
 		Inode:      tInodeInfo.Inode,
+		VersionId:  versionId,
 	}
 
 	// apply new inode to dentry
@@ -2988,6 +3333,15 @@ func NewVolume(config *VolumeConfig) (*Volume, error) {
 			config.OnAsyncTaskError.OnError(err)
 		},
 	}
+	if config.Encryption != nil {
+		metaConfig.Owner = config.Encryption.ClientID
+		metaConfig.TicketMess = auth.TicketMess{
+			ClientKey:   config.Encryption.ClientKey,
+			TicketHosts: config.Encryption.AuthNodes,
+			EnableHTTPS: config.Encryption.EnableHTTPS,
+			CertFile:    config.Encryption.CertFile,
+		}
+	}
 
 	var metaWrapper *meta.MetaWrapper
 	if metaWrapper, err = meta.NewMetaWrapper(metaConfig); err != nil {
@@ -3021,6 +3375,7 @@ func NewVolume(config *VolumeConfig) (*Volume, error) {
 		OnSplitExtentKey:  metaWrapper.SplitExtentKey,
 		OnGetExtents:      metaWrapper.GetExtents,
 		OnTruncate:        metaWrapper.Truncate,
+		OnLoadFileCipher:  metaWrapper.LoadFileCipher,
 	}
 	if proto.IsCold(volumeInfo.VolType) {
 		if blockCache != nil {
@@ -3030,6 +3385,10 @@ func NewVolume(config *VolumeConfig) (*Volume, error) {
 			extentConfig.OnEvictBcache = blockCache.Evict
 		}
 		log.LogDebugf("%v is cold volume", config.Volume)
+	} else if ebsClient != nil {
+		// the data of the objects transitioned by the lifecycle is read from the blobstore
+		extentConfig.OnGetObjExtents = metaWrapper.GetObjExtents
+		extentConfig.OnReadObjExtent = ebsClient.Read
 	}
 	var extentClient *stream.ExtentClient
 	if extentClient, err = stream.NewExtentClient(extentConfig); err != nil {
@@ -26,10 +26,22 @@ type ossMetaLoader interface {
 	loadACL() (p *AccessControlPolicy, err error)
 	loadCORS() (cors *CORSConfiguration, err error)
 	loadObjectLock() (config *ObjectLockConfig, err error)
+	loadVersioning() (config *VersioningConfiguration, err error)
+	loadReplication() (config *ReplicationConfiguration, err error)
+	loadEncryption() (config *ServerSideEncryptionConfiguration, err error)
+	loadNotification() (config *NotificationConfiguration, err error)
+	loadWebsite() (config *WebsiteConfiguration, err error)
+	loadPublicAccessBlock() (config *PublicAccessBlockConfiguration, err error)
 	storePolicy(p *Policy)
 	storeACL(p *AccessControlPolicy)
 	storeCORS(cors *CORSConfiguration)
 	storeObjectLock(config *ObjectLockConfig)
+	storeVersioning(config *VersioningConfiguration)
+	storeReplication(config *ReplicationConfiguration)
+	storeEncryption(config *ServerSideEncryptionConfiguration)
+	storeNotification(config *NotificationConfiguration)
+	storeWebsite(config *WebsiteConfiguration)
+	storePublicAccessBlock(config *PublicAccessBlockConfiguration)
 	setSynced()
 }
 
@@ -50,10 +62,22 @@ type OSSMeta struct {
 	acl        *AccessControlPolicy
 	corsConfig *CORSConfiguration
 	lockConfig *ObjectLockConfig
+	versioning *VersioningConfiguration
+	replConfig *ReplicationConfiguration
+	encConfig  *ServerSideEncryptionConfiguration
+	notifyConf *NotificationConfiguration
+	website    *WebsiteConfiguration
+	pabConfig  *PublicAccessBlockConfiguration
 	policyLock sync.RWMutex
 	aclLock    sync.RWMutex
 	corsLock   sync.RWMutex
 	objectLock sync.RWMutex
+	verLock    sync.RWMutex
+	replLock   sync.RWMutex
+	encLock    sync.RWMutex
+	notifyLock sync.RWMutex
+	siteLock   sync.RWMutex
+	pabLock    sync.RWMutex
 }
 
 func (c *cacheMetaLoader) loadPolicy() (p *Policy, err error) {
@@ -156,6 +180,156 @@ func (c *cacheMetaLoader) storeObjectLock(config *ObjectLockConfig) {
 	return
 }
 
+func (c *cacheMetaLoader) loadVersioning() (config *VersioningConfiguration, err error) {
+	c.om.verLock.RLock()
+	config = c.om.versioning
+	c.om.verLock.RUnlock()
+	if config == nil && atomic.LoadInt32(c.synced) == 0 {
+		ret, err, _ := c.sf.Do(XAttrKeyOSSVersioning, func() (interface{}, error) {
+			vc, err := c.sml.loadVersioning()
+			return vc, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		config = ret.(*VersioningConfiguration)
+		c.storeVersioning(config)
+	}
+	return
+}
+
+func (c *cacheMetaLoader) storeVersioning(config *VersioningConfiguration) {
+	c.om.verLock.Lock()
+	c.om.versioning = config
+	c.om.verLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadReplication() (config *ReplicationConfiguration, err error) {
+	c.om.replLock.RLock()
+	config = c.om.replConfig
+	c.om.replLock.RUnlock()
+	if config == nil && atomic.LoadInt32(c.synced) == 0 {
+		ret, err, _ := c.sf.Do(XAttrKeyOSSReplication, func() (interface{}, error) {
+			rc, err := c.sml.loadReplication()
+			return rc, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		config = ret.(*ReplicationConfiguration)
+		c.storeReplication(config)
+	}
+	return
+}
+
+func (c *cacheMetaLoader) storeReplication(config *ReplicationConfiguration) {
+	c.om.replLock.Lock()
+	c.om.replConfig = config
+	c.om.replLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadEncryption() (config *ServerSideEncryptionConfiguration, err error) {
+	c.om.encLock.RLock()
+	config = c.om.encConfig
+	c.om.encLock.RUnlock()
+	if config == nil && atomic.LoadInt32(c.synced) == 0 {
+		ret, err, _ := c.sf.Do(XAttrKeyOSSEncryption, func() (interface{}, error) {
+			ec, err := c.sml.loadEncryption()
+			return ec, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		config = ret.(*ServerSideEncryptionConfiguration)
+		c.storeEncryption(config)
+	}
+	return
+}
+
+func (c *cacheMetaLoader) storeEncryption(config *ServerSideEncryptionConfiguration) {
+	c.om.encLock.Lock()
+	c.om.encConfig = config
+	c.om.encLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadNotification() (config *NotificationConfiguration, err error) {
+	c.om.notifyLock.RLock()
+	config = c.om.notifyConf
+	c.om.notifyLock.RUnlock()
+	if config == nil && atomic.LoadInt32(c.synced) == 0 {
+		ret, err, _ := c.sf.Do(XAttrKeyOSSNotification, func() (interface{}, error) {
+			nc, err := c.sml.loadNotification()
+			return nc, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		config = ret.(*NotificationConfiguration)
+		c.storeNotification(config)
+	}
+	return
+}
+
+func (c *cacheMetaLoader) storeNotification(config *NotificationConfiguration) {
+	c.om.notifyLock.Lock()
+	c.om.notifyConf = config
+	c.om.notifyLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadWebsite() (config *WebsiteConfiguration, err error) {
+	c.om.siteLock.RLock()
+	config = c.om.website
+	c.om.siteLock.RUnlock()
+	if config == nil && atomic.LoadInt32(c.synced) == 0 {
+		ret, err, _ := c.sf.Do(XAttrKeyOSSWebsite, func() (interface{}, error) {
+			wc, err := c.sml.loadWebsite()
+			return wc, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		config = ret.(*WebsiteConfiguration)
+		c.storeWebsite(config)
+	}
+	return
+}
+
+func (c *cacheMetaLoader) storeWebsite(config *WebsiteConfiguration) {
+	c.om.siteLock.Lock()
+	c.om.website = config
+	c.om.siteLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadPublicAccessBlock() (config *PublicAccessBlockConfiguration, err error) {
+	c.om.pabLock.RLock()
+	config = c.om.pabConfig
+	c.om.pabLock.RUnlock()
+	if config == nil && atomic.LoadInt32(c.synced) == 0 {
+		ret, err, _ := c.sf.Do(XAttrKeyOSSPublicAccessBlock, func() (interface{}, error) {
+			pc, err := c.sml.loadPublicAccessBlock()
+			return pc, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		config = ret.(*PublicAccessBlockConfiguration)
+		c.storePublicAccessBlock(config)
+	}
+	return
+}
+
+func (c *cacheMetaLoader) storePublicAccessBlock(config *PublicAccessBlockConfiguration) {
+	c.om.pabLock.Lock()
+	c.om.pabConfig = config
+	c.om.pabLock.Unlock()
+	return
+}
+
 func (c *cacheMetaLoader) setSynced() {
 	atomic.StoreInt32(c.synced, 1)
 }
@@ -192,6 +366,54 @@ func (s *strictMetaLoader) storeObjectLock(cors *ObjectLockConfig) {
 	// do nothing
 }
 
+func (s *strictMetaLoader) loadVersioning() (config *VersioningConfiguration, err error) {
+	return s.v.loadVersioning()
+}
+
+func (s *strictMetaLoader) storeVersioning(config *VersioningConfiguration) {
+	// do nothing
+}
+
+func (s *strictMetaLoader) loadReplication() (config *ReplicationConfiguration, err error) {
+	return s.v.loadReplication()
+}
+
+func (s *strictMetaLoader) storeReplication(config *ReplicationConfiguration) {
+	// do nothing
+}
+
+func (s *strictMetaLoader) loadEncryption() (config *ServerSideEncryptionConfiguration, err error) {
+	return s.v.loadBucketEncryption()
+}
+
+func (s *strictMetaLoader) storeEncryption(config *ServerSideEncryptionConfiguration) {
+	// do nothing
+}
+
+func (s *strictMetaLoader) loadNotification() (config *NotificationConfiguration, err error) {
+	return s.v.loadNotification()
+}
+
+func (s *strictMetaLoader) storeNotification(config *NotificationConfiguration) {
+	// do nothing
+}
+
+func (s *strictMetaLoader) loadWebsite() (config *WebsiteConfiguration, err error) {
+	return s.v.loadBucketWebsite()
+}
+
+func (s *strictMetaLoader) storeWebsite(config *WebsiteConfiguration) {
+	// do nothing
+}
+
+func (s *strictMetaLoader) loadPublicAccessBlock() (config *PublicAccessBlockConfiguration, err error) {
+	return s.v.loadBucketPublicAccessBlock()
+}
+
+func (s *strictMetaLoader) storePublicAccessBlock(config *PublicAccessBlockConfiguration) {
+	// do nothing
+}
+
 func (s *strictMetaLoader) setSynced() {
 	// do nothing
 }
@@ -0,0 +1,83 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/blobstore"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// RestoreObject makes the copy of the data of the object transitioned to the blobstore for the reads until
+// the expiry. The copy is made asynchronously if it does not exist, and true is returned then, the expiry of
+// the copy is extended otherwise.
+func (v *Volume) RestoreObject(info *FSFileInfo, expiry time.Time) (accepted bool, err error) {
+	defer func() {
+		log.LogInfof("Audit: RestoreObject: volume(%v) path(%v) inode(%v) expiry(%v) accepted(%v) err(%v)",
+			v.name, info.Path, info.Inode, expiry, accepted, err)
+	}()
+	if info.Restore != nil {
+		err = v.mw.XAttrSet_ll(info.Inode, []byte(XAttrKeyOSSRestoreExpiry), []byte(strconv.FormatInt(expiry.Unix(), 10)))
+		deleteAttrCache(info.Inode, v.name)
+		return
+	}
+
+	attrs := map[string]string{
+		XAttrKeyOSSRestoreExpiry:  strconv.FormatInt(expiry.Unix(), 10),
+		XAttrKeyOSSRestoreOngoing: "true",
+	}
+	if err = v.mw.BatchSetXAttr_ll(info.Inode, attrs); err != nil {
+		log.LogErrorf("RestoreObject: set restore state fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, info.Path, info.Inode, err)
+		return
+	}
+	deleteAttrCache(info.Inode, v.name)
+	go v.restoreObjectCopy(info)
+	return true, nil
+}
+
+func (v *Volume) restoreObjectCopy(info *FSFileInfo) {
+	var err error
+	defer func() {
+		if err == nil {
+			err = v.mw.XAttrDel_ll(info.Inode, XAttrKeyOSSRestoreOngoing)
+		} else {
+			// the restore could be requested again
+			_ = v.mw.XAttrDel_ll(info.Inode, XAttrKeyOSSRestoreExpiry)
+			_ = v.mw.XAttrDel_ll(info.Inode, XAttrKeyOSSRestoreOngoing)
+		}
+		deleteAttrCache(info.Inode, v.name)
+		if err != nil {
+			log.LogErrorf("restoreObjectCopy: volume(%v) path(%v) inode(%v) err(%v)", v.name, info.Path, info.Inode, err)
+		}
+	}()
+
+	var inoInfo *proto.InodeInfo
+	if inoInfo, err = v.mw.InodeGet_ll(info.Inode); err != nil {
+		return
+	}
+	if _, err = blobstore.RestoreCopy(v.ec, v.mw, info.Inode); err != nil {
+		return
+	}
+	// the object is not modified by the copy, which is written to the extents of the inode
+	if setErr := v.mw.Setattr(info.Inode, proto.AttrModifyTime|proto.AttrAccessTime, 0, 0, 0,
+		inoInfo.AccessTime.Unix(), inoInfo.ModifyTime.Unix()); setErr != nil {
+		log.LogWarnf("restoreObjectCopy: reset times fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, info.Path, info.Inode, setErr)
+	}
+}
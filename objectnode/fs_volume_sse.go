@@ -0,0 +1,86 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/md5"
+	"hash"
+	"io"
+	"syscall"
+)
+
+// readObject reads the plaintext of the object in range [offset, offset+size), the data of encrypted
+// objects is decrypted with the data key of the encryption envelope of the object.
+func (v *Volume) readObject(info *FSFileInfo, path string, writer io.Writer, offset, size uint64) error {
+	if info.Encryption == nil {
+		return v.readFile(info.Inode, uint64(info.Size), path, writer, offset, size)
+	}
+	if offset >= uint64(info.Size) {
+		return nil
+	}
+	if offset+size > uint64(info.Size) {
+		size = uint64(info.Size) - offset
+	}
+	encSize := info.Encryption.EncryptedSize()
+	return sseReadRange(info.Encryption, writer, offset, size, func(w io.Writer, off, n uint64) error {
+		return v.readFile(info.Inode, encSize, path, w, off, n)
+	})
+}
+
+// ssePlainReader computes the MD5 hash and size of the plaintext which is read before encryption.
+type ssePlainReader struct {
+	reader io.Reader
+	hash   hash.Hash
+	size   uint64
+}
+
+func (r *ssePlainReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	if n > 0 {
+		r.hash.Write(p[:n])
+		r.size += uint64(n)
+	}
+	return
+}
+
+// encryptReader wraps the reader of the plaintext with encryption if the envelope is specified. The returned
+// hash is the one to be passed to the data writers, since the MD5 of the encrypted data is meaningless.
+func encryptReader(reader io.Reader, enc *ObjectEncryption, part uint16, md5Hash hash.Hash) (
+	io.Reader, hash.Hash, *ssePlainReader, error) {
+	if enc == nil {
+		return reader, md5Hash, nil, nil
+	}
+	plain := &ssePlainReader{reader: reader, hash: md5Hash}
+	encReader, err := newSSEEncryptReader(plain, enc.dataKey, part)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return encReader, md5.New(), plain, nil
+}
+
+// CopyObjectData copies the object by reading the plaintext of the source and writing it as a new object
+// with opt, which is used when the data of the source or the target is encrypted and can not be copied directly.
+func (v *Volume) CopyObjectData(sv *Volume, sourceInfo *FSFileInfo, targetPath string, opt *PutFileOption) (info *FSFileInfo, err error) {
+	if sourceInfo.Size > MaxCopyObjectSize {
+		return nil, syscall.EFBIG
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(sv.readObject(sourceInfo, sourceInfo.Path, writer, 0, uint64(sourceInfo.Size)))
+	}()
+	info, err = v.PutObject(targetPath, reader, opt)
+	reader.CloseWithError(err)
+	return
+}
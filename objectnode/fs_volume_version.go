@@ -0,0 +1,693 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const versionsReadDirLimit = 1000
+
+// versionIdForWrite returns the version ID assigned to a newly written object according to
+// the versioning state of the bucket. It returns an empty ID if versioning has never been configured.
+func (v *Volume) versionIdForWrite() (versionId string, err error) {
+	var versioning *VersioningConfiguration
+	if versioning, err = v.metaLoader.loadVersioning(); err != nil {
+		log.LogErrorf("versionIdForWrite: load versioning fail: volume(%v) err(%v)", v.name, err)
+		return
+	}
+	switch {
+	case versioning.IsEnabled():
+		versionId = newVersionId()
+	case versioning.IsSuspended():
+		versionId = NullVersionId
+	}
+	return
+}
+
+// keepNoncurrentVersion is called before the current object of the path is replaced by a new one.
+// The replaced object is kept in the versions directory unless it is a null version and versioning
+// is suspended, in which case it is replaced like an unversioned bucket does.
+func (v *Volume) keepNoncurrentVersion(path string, existInode uint64) (err error) {
+	var versioning *VersioningConfiguration
+	if versioning, err = v.metaLoader.loadVersioning(); err != nil {
+		log.LogErrorf("keepNoncurrentVersion: load versioning fail: volume(%v) path(%v) err(%v)", v.name, path, err)
+		return
+	}
+	if !versioning.IsConfigured() {
+		return
+	}
+	if versioning.IsSuspended() {
+		// the new object is the null version, so any noncurrent null version is replaced
+		if err = v.removeVersionEntry(path, NullVersionId); err != nil {
+			return
+		}
+	}
+	if existInode != 0 {
+		_, err = v.archiveObjectVersion(path, existInode, versioning)
+	}
+	return
+}
+
+// archiveObjectVersion links the current object inode into the versions directory.
+// It returns false if the inode is not archived because it is a null version and versioning is suspended.
+func (v *Volume) archiveObjectVersion(path string, inode uint64, versioning *VersioningConfiguration) (archived bool, err error) {
+	var xattr *proto.XAttrInfo
+	if xattr, err = v.mw.XAttrGet_ll(inode, XAttrKeyOSSVersionId); err != nil {
+		log.LogErrorf("archiveObjectVersion: get version id fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, path, inode, err)
+		return
+	}
+	versionId := string(xattr.Get(XAttrKeyOSSVersionId))
+	if versionId == "" {
+		versionId = NullVersionId
+	}
+	if versioning.IsSuspended() && versionId == NullVersionId {
+		return false, nil
+	}
+
+	entryPath := versionEntryName(versionsPath(path), versionId)
+	var parentId uint64
+	if parentId, err = v.recursiveMakeDirectory(entryPath); err != nil {
+		log.LogErrorf("archiveObjectVersion: recursive make directory fail: volume(%v) path(%v) err(%v)",
+			v.name, entryPath, err)
+		return
+	}
+	pathItems := NewPathIterator(entryPath).ToSlice()
+	name := pathItems[len(pathItems)-1].Name
+	if err = v.removeVersionEntry(path, versionId); err != nil {
+		return
+	}
+	if _, err = v.mw.InodeLink_ll(inode, entryPath); err != nil {
+		log.LogErrorf("archiveObjectVersion: link inode fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, entryPath, inode, err)
+		return
+	}
+	if err = v.mw.DentryCreate_ll(parentId, name, inode, DefaultFileMode, entryPath); err != nil {
+		log.LogErrorf("archiveObjectVersion: dentry create fail: volume(%v) path(%v) parentID(%v) inode(%v) err(%v)",
+			v.name, entryPath, parentId, inode, err)
+		_, _ = v.mw.InodeUnlink_ll(inode, entryPath)
+		return
+	}
+	updateDentryCache(parentId, inode, DefaultFileMode, name, v.name)
+	// the noncurrent version is expired by the lifecycle since the time, which is set by the lcnode once it
+	// finds the version without the time
+	noncurrentTime := strconv.FormatInt(time.Now().Unix(), 10)
+	if setErr := v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSNoncurrentTime), []byte(noncurrentTime)); setErr != nil {
+		log.LogWarnf("archiveObjectVersion: set noncurrent time fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, entryPath, inode, setErr)
+	}
+	deleteAttrCache(inode, v.name)
+	log.LogDebugf("archiveObjectVersion: archive version: volume(%v) path(%v) versionId(%v) inode(%v)",
+		v.name, path, versionId, inode)
+	return true, nil
+}
+
+// removeVersionEntry permanently removes the noncurrent version of the object from the versions directory.
+// It returns success if the version does not exist.
+func (v *Volume) removeVersionEntry(path, versionId string) (err error) {
+	entryPath := versionEntryName(versionsPath(path), versionId)
+	var parent, ino uint64
+	var name string
+	if parent, ino, name, _, err = v.recursiveLookupTarget(entryPath, true); err != nil {
+		if err == syscall.ENOENT {
+			err = nil
+		}
+		return
+	}
+	if _, err = v.mw.Delete_ll(parent, name, false, entryPath); err != nil {
+		if err == syscall.ENOENT {
+			err = nil
+		}
+		return
+	}
+	deleteDentryCache(parent, name, v.name)
+	if err = v.mw.Evict(ino, entryPath); err != nil {
+		log.LogWarnf("removeVersionEntry: evict inode fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, entryPath, ino, err)
+	}
+	return nil
+}
+
+// DeleteObject deletes the object of the path according to the versioning state of the bucket.
+// Without a version ID, a delete marker is inserted as the latest version if versioning is configured.
+// With a version ID, the specified version is permanently removed.
+func (v *Volume) DeleteObject(path, versionId string, bypassGovernance bool) (resultVersionId string, deleteMarker bool, err error) {
+	defer func() {
+		// Audit behavior
+		log.LogInfof("Audit: DeleteObject: volume(%v) path(%v) versionId(%v) bypassGovernance(%v) err(%v)",
+			v.name, path, versionId, bypassGovernance, err)
+	}()
+	if versionId != "" {
+		deleteMarker, err = v.deleteObjectVersion(path, versionId, bypassGovernance)
+		return versionId, deleteMarker, err
+	}
+	var versioning *VersioningConfiguration
+	if versioning, err = v.metaLoader.loadVersioning(); err != nil {
+		log.LogErrorf("DeleteObject: load versioning fail: volume(%v) err(%v)", v.name, err)
+		return
+	}
+	if !versioning.IsConfigured() || strings.HasSuffix(path, pathSep) {
+		err = v.deletePath(path, bypassGovernance)
+		return
+	}
+	return v.putDeleteMarker(path, versioning, bypassGovernance)
+}
+
+func (v *Volume) putDeleteMarker(path string, versioning *VersioningConfiguration, bypassGovernance bool) (versionId string, deleteMarker bool, err error) {
+	parent, ino, name, mode, err := v.recursiveLookupTarget(path, false)
+	if err != nil && err != syscall.ENOENT {
+		return
+	}
+	if err == nil {
+		if mode.IsDir() {
+			err = v.DeletePath(path)
+			return
+		}
+		var archived bool
+		if archived, err = v.archiveObjectVersion(path, ino, versioning); err != nil {
+			return
+		}
+		if !archived {
+			// the null version is removed permanently
+			var objectLock *ObjectLockConfig
+			if objectLock, err = v.metaLoader.loadObjectLock(); err != nil {
+				return
+			}
+			if objectLock != nil {
+				if err = isObjectLocked(v, ino, name, path, bypassGovernance); err != nil {
+					return
+				}
+			}
+		}
+		if _, err = v.deleteWithCond(parent, ino, name, false, path, bypassGovernance); err != nil && err != syscall.ENOENT {
+			log.LogErrorf("putDeleteMarker: delete current dentry fail: volume(%v) path(%v) inode(%v) err(%v)",
+				v.name, path, ino, err)
+			return
+		}
+		deleteDentryCache(parent, name, v.name)
+		deleteAttrCache(ino, v.name)
+		if !archived {
+			if evictErr := v.ec.EvictStream(ino); evictErr != nil {
+				log.LogWarnf("putDeleteMarker: evict stream fail: volume(%v) path(%v) inode(%v) err(%v)",
+					v.name, path, ino, evictErr)
+			}
+		}
+		if evictErr := v.mw.Evict(ino, path); evictErr != nil {
+			log.LogWarnf("putDeleteMarker: evict inode fail: volume(%v) path(%v) inode(%v) err(%v)",
+				v.name, path, ino, evictErr)
+		}
+	}
+	err = nil
+
+	if versioning.IsSuspended() {
+		versionId = NullVersionId
+		if err = v.removeVersionEntry(path, NullVersionId); err != nil {
+			return
+		}
+	} else {
+		versionId = newVersionId()
+	}
+
+	entryPath := versionEntryName(versionsPath(path), versionId)
+	var parentId uint64
+	if parentId, err = v.recursiveMakeDirectory(entryPath); err != nil {
+		log.LogErrorf("putDeleteMarker: recursive make directory fail: volume(%v) path(%v) err(%v)",
+			v.name, entryPath, err)
+		return
+	}
+	pathItems := NewPathIterator(entryPath).ToSlice()
+	markerName := pathItems[len(pathItems)-1].Name
+
+	var markerInode *proto.InodeInfo
+	if markerInode, err = v.mw.InodeCreate_ll(parentId, DefaultFileMode, 0, 0, nil, make([]uint64, 0), entryPath); err != nil {
+		log.LogErrorf("putDeleteMarker: inode create fail: volume(%v) path(%v) err(%v)", v.name, entryPath, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			_, _ = v.mw.InodeUnlink_ll(markerInode.Inode, entryPath)
+			_ = v.mw.Evict(markerInode.Inode, entryPath)
+		}
+	}()
+	etagValue := ETagValue{
+		Value:   EmptyContentMD5String,
+		PartNum: 0,
+		TS:      markerInode.ModifyTime,
+	}
+	attrs := map[string]string{
+		XAttrKeyOSSETag:         etagValue.Encode(),
+		XAttrKeyOSSVersionId:    versionId,
+		XAttrKeyOSSDeleteMarker: "true",
+	}
+	if err = v.mw.BatchSetXAttr_ll(markerInode.Inode, attrs); err != nil {
+		log.LogErrorf("putDeleteMarker: BatchSetXAttr_ll fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, entryPath, markerInode.Inode, err)
+		return
+	}
+	if err = v.mw.DentryCreate_ll(parentId, markerName, markerInode.Inode, DefaultFileMode, entryPath); err != nil {
+		log.LogErrorf("putDeleteMarker: dentry create fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, entryPath, markerInode.Inode, err)
+		return
+	}
+	updateDentryCache(parentId, markerInode.Inode, DefaultFileMode, markerName, v.name)
+	return versionId, true, nil
+}
+
+// deleteObjectVersion permanently removes the specified version of the object. If the current version
+// is removed, the latest noncurrent version becomes the current one unless it is a delete marker.
+func (v *Volume) deleteObjectVersion(path, versionId string, bypassGovernance bool) (deleteMarker bool, err error) {
+	objectLock, err := v.metaLoader.loadObjectLock()
+	if err != nil {
+		log.LogErrorf("deleteObjectVersion: load volume objetLock: volume(%v) err(%v)", v.name, err)
+		return
+	}
+
+	parent, ino, name, mode, err := v.recursiveLookupTarget(path, false)
+	if err != nil && err != syscall.ENOENT {
+		return
+	}
+	hasCurrent := err == nil && !mode.IsDir()
+	if hasCurrent {
+		var xattr *proto.XAttrInfo
+		if xattr, err = v.mw.XAttrGet_ll(ino, XAttrKeyOSSVersionId); err != nil {
+			return
+		}
+		currentVersionId := string(xattr.Get(XAttrKeyOSSVersionId))
+		if currentVersionId == "" {
+			currentVersionId = NullVersionId
+		}
+		if currentVersionId == versionId {
+			if objectLock != nil {
+				if err = isObjectLocked(v, ino, name, path, bypassGovernance); err != nil {
+					return
+				}
+			}
+			if _, err = v.deleteWithCond(parent, ino, name, false, path, bypassGovernance); err != nil {
+				if err == syscall.ENOENT {
+					err = nil
+				}
+				return
+			}
+			deleteDentryCache(parent, name, v.name)
+			deleteAttrCache(ino, v.name)
+			v.evictObjectInode(path, ino)
+			err = v.promoteLatestVersion(path)
+			return
+		}
+	}
+
+	entryPath := versionEntryName(versionsPath(path), versionId)
+	var entryParent, entryIno uint64
+	var entryName string
+	if entryParent, entryIno, entryName, _, err = v.recursiveLookupTarget(entryPath, false); err != nil {
+		if err == syscall.ENOENT {
+			err = nil
+		}
+		return
+	}
+	var xattr *proto.XAttrInfo
+	if xattr, err = v.mw.XAttrGet_ll(entryIno, XAttrKeyOSSDeleteMarker); err != nil {
+		return
+	}
+	deleteMarker = string(xattr.Get(XAttrKeyOSSDeleteMarker)) == "true"
+	if !deleteMarker && objectLock != nil {
+		if err = isObjectLocked(v, entryIno, entryName, entryPath, bypassGovernance); err != nil {
+			return
+		}
+	}
+	if _, err = v.deleteWithCond(entryParent, entryIno, entryName, false, entryPath, bypassGovernance); err != nil {
+		if err == syscall.ENOENT {
+			err = nil
+		}
+		return
+	}
+	deleteDentryCache(entryParent, entryName, v.name)
+	deleteAttrCache(entryIno, v.name)
+	v.evictObjectInode(entryPath, entryIno)
+	if !hasCurrent {
+		err = v.promoteLatestVersion(path)
+	}
+	return
+}
+
+func (v *Volume) evictObjectInode(path string, ino uint64) {
+	if err := v.ec.EvictStream(ino); err != nil {
+		log.LogWarnf("evictObjectInode: evict stream fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, path, ino, err)
+	}
+	if err := v.mw.Evict(ino, path); err != nil {
+		log.LogWarnf("evictObjectInode: evict inode fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, path, ino, err)
+	}
+}
+
+// promoteLatestVersion moves the latest noncurrent version back to the object path if it is not a delete marker.
+func (v *Volume) promoteLatestVersion(path string) (err error) {
+	var versions []*FSFileInfo
+	var parentId uint64
+	if versions, parentId, err = v.listNoncurrentVersions(path); err != nil || len(versions) == 0 {
+		return
+	}
+	latest := versions[0]
+	if latest.DeleteMarker {
+		return
+	}
+	var curParentId uint64
+	if curParentId, err = v.recursiveMakeDirectory(path); err != nil {
+		log.LogErrorf("promoteLatestVersion: recursive make directory fail: volume(%v) path(%v) err(%v)",
+			v.name, path, err)
+		return
+	}
+	pathItems := NewPathIterator(path).ToSlice()
+	name := pathItems[len(pathItems)-1].Name
+	entryPath := versionEntryName(versionsPath(path), latest.VersionId)
+	entryName := versionEntryName(name, latest.VersionId)
+	if err = v.mw.Rename_ll(parentId, entryName, curParentId, name, entryPath, path, false); err != nil {
+		log.LogErrorf("promoteLatestVersion: rename fail: volume(%v) src(%v) dst(%v) err(%v)",
+			v.name, entryPath, path, err)
+		return
+	}
+	deleteDentryCache(parentId, entryName, v.name)
+	updateDentryCache(curParentId, latest.Inode, DefaultFileMode, name, v.name)
+	log.LogDebugf("promoteLatestVersion: volume(%v) path(%v) versionId(%v) inode(%v)",
+		v.name, path, latest.VersionId, latest.Inode)
+	return
+}
+
+// listNoncurrentVersions returns the noncurrent versions of the object sorted from newest to oldest,
+// along with the inode of the directory which holds them.
+func (v *Volume) listNoncurrentVersions(path string) (versions []*FSFileInfo, parentId uint64, err error) {
+	entryDir, name := splitObjectPath(versionsPath(path))
+	if _, parentId, _, _, err = v.recursiveLookupTarget(entryDir+pathSep, false); err != nil {
+		if err == syscall.ENOENT {
+			err = nil
+		}
+		return
+	}
+	objectDir, _ := splitObjectPath(path)
+	fromName := name + versionNameSep
+	for {
+		var children []proto.Dentry
+		if children, err = v.mw.ReadDirLimit_ll(parentId, fromName, versionsReadDirLimit); err != nil {
+			if err == syscall.ENOENT {
+				err = nil
+			}
+			return
+		}
+		var matched int
+		for _, child := range children {
+			if child.Name == fromName || !strings.HasPrefix(child.Name, name+versionNameSep) {
+				continue
+			}
+			matched++
+			key, versionId, ok := parseVersionEntryName(child.Name)
+			if !ok || key != name || os.FileMode(child.Type).IsDir() {
+				continue
+			}
+			versions = append(versions, &FSFileInfo{
+				Path:      joinObjectPath(objectDir, key),
+				Inode:     child.Inode,
+				VersionId: versionId,
+			})
+		}
+		if len(children) < versionsReadDirLimit || matched == 0 {
+			break
+		}
+		fromName = children[len(children)-1].Name
+	}
+	if err = v.supplyListFileInfo(versions); err != nil {
+		return
+	}
+	sortVersionsNewestFirst(versions)
+	return
+}
+
+// splitObjectPath splits the path into the parent directory and the last name.
+func splitObjectPath(path string) (dir, name string) {
+	path = strings.TrimPrefix(path, pathSep)
+	if idx := strings.LastIndex(path, pathSep); idx >= 0 {
+		return path[:idx], path[idx+1:]
+	}
+	return "", path
+}
+
+func joinObjectPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + pathSep + name
+}
+
+// sortVersionsNewestFirst sorts versions of the same object from newest to oldest.
+// Version IDs generated by newVersionId sort in creation order as well.
+func sortVersionsNewestFirst(versions []*FSFileInfo) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		if !versions[i].ModifyTime.Equal(versions[j].ModifyTime) {
+			return versions[i].ModifyTime.After(versions[j].ModifyTime)
+		}
+		return versions[i].VersionId > versions[j].VersionId
+	})
+}
+
+// ObjectVersionMeta returns the meta of the specified version of the object.
+// It returns the meta of the current object if the version ID is empty.
+func (v *Volume) ObjectVersionMeta(path, versionId string) (info *FSFileInfo, xattr *proto.XAttrInfo, err error) {
+	if versionId == "" {
+		return v.ObjectMeta(path)
+	}
+	info, xattr, err = v.ObjectMeta(path)
+	if err != nil && err != syscall.ENOENT {
+		return
+	}
+	if err == nil && !info.Mode.IsDir() && info.ObjectVersionId() == versionId {
+		return
+	}
+	if info, xattr, err = v.ObjectMeta(versionEntryName(versionsPath(path), versionId)); err != nil {
+		if err == syscall.ENOENT {
+			err = NoSuchVersion
+		}
+		return
+	}
+	info.Path = path
+	return
+}
+
+// ObjectVersionPath returns the path at which the specified version of the object can be read.
+func (v *Volume) ObjectVersionPath(path, versionId string) (versionPath string, err error) {
+	var info *FSFileInfo
+	if info, _, err = v.ObjectVersionMeta(path, versionId); err != nil {
+		return
+	}
+	if info.DeleteMarker {
+		return "", MethodNotAllowed
+	}
+	if versionId == "" || info.Inode == 0 {
+		return path, nil
+	}
+	var ino uint64
+	if _, ino, _, _, err = v.recursiveLookupTarget(path, false); err == nil && ino == info.Inode {
+		return path, nil
+	}
+	return versionEntryName(versionsPath(path), versionId), nil
+}
+
+// ListObjectVersions lists both the current and the noncurrent versions of objects in the bucket.
+func (v *Volume) ListObjectVersions(opt *ListObjectVersionsOption) (result *ListObjectVersionsResult, err error) {
+	result = &ListObjectVersionsResult{}
+
+	// current objects, one more for upper bound detection
+	var currents []*FSFileInfo
+	if currents, _, _, err = v.listFilesV1(opt.Prefix, opt.KeyMarker, "", opt.MaxKeys+1, true); err != nil {
+		log.LogErrorf("ListObjectVersions: list current objects fail: volume(%v) prefix(%v) keyMarker(%v) err(%v)",
+			v.name, opt.Prefix, opt.KeyMarker, err)
+		return nil, err
+	}
+	var upperKey string
+	if uint64(len(currents)) > opt.MaxKeys {
+		upperKey = currents[len(currents)-1].Path
+	}
+
+	var noncurrents []*FSFileInfo
+	if noncurrents, err = v.scanNoncurrentVersions(opt.Prefix); err != nil {
+		log.LogErrorf("ListObjectVersions: scan noncurrent versions fail: volume(%v) prefix(%v) err(%v)",
+			v.name, opt.Prefix, err)
+		return nil, err
+	}
+	if err = v.supplyListFileInfo(noncurrents); err != nil {
+		return nil, err
+	}
+
+	// group versions by key, the current object always is the latest one
+	inRange := func(key string) bool {
+		return (opt.KeyMarker == "" || key >= opt.KeyMarker) && (upperKey == "" || key <= upperKey)
+	}
+	versionMap := make(map[string][]*FSFileInfo)
+	keys := make([]string, 0)
+	for _, info := range currents {
+		if info.Mode.IsDir() || !inRange(info.Path) {
+			continue
+		}
+		keys = append(keys, info.Path)
+		versionMap[info.Path] = []*FSFileInfo{info}
+	}
+	noncurrentMap := make(map[string][]*FSFileInfo)
+	for _, info := range noncurrents {
+		if !inRange(info.Path) {
+			continue
+		}
+		if _, ok := versionMap[info.Path]; !ok {
+			if _, ok = noncurrentMap[info.Path]; !ok {
+				keys = append(keys, info.Path)
+			}
+		}
+		noncurrentMap[info.Path] = append(noncurrentMap[info.Path], info)
+	}
+	for key, versions := range noncurrentMap {
+		sortVersionsNewestFirst(versions)
+		versionMap[key] = append(versionMap[key], versions...)
+	}
+	sort.Strings(keys)
+
+	var (
+		count       uint64
+		prefixMap   = PrefixMap(make(map[string]struct{}))
+		lastKey     string
+		lastVersion string
+		afterMarker bool
+	)
+	for _, key := range keys {
+		if key == opt.KeyMarker {
+			if opt.VersionIdMarker == "" {
+				continue
+			}
+			afterMarker = false
+		} else {
+			afterMarker = true
+		}
+
+		if opt.Delimiter != "" {
+			nonPrefixPart := strings.TrimPrefix(key, opt.Prefix)
+			if idx := strings.Index(nonPrefixPart, opt.Delimiter); idx >= 0 {
+				commonPrefix := opt.Prefix + nonPrefixPart[:idx] + opt.Delimiter
+				if strings.HasPrefix(opt.KeyMarker, commonPrefix) || prefixMap.contain(commonPrefix) {
+					continue
+				}
+				if count >= opt.MaxKeys {
+					result.IsTruncated = true
+					break
+				}
+				prefixMap.AddPrefix(commonPrefix)
+				lastKey, lastVersion = commonPrefix, ""
+				count++
+				continue
+			}
+		}
+
+		for i, info := range versionMap[key] {
+			versionId := info.ObjectVersionId()
+			if !afterMarker {
+				afterMarker = versionId == opt.VersionIdMarker
+				continue
+			}
+			if count >= opt.MaxKeys {
+				result.IsTruncated = true
+				break
+			}
+			info.VersionId = versionId
+			result.Versions = append(result.Versions, &ObjectVersionInfo{
+				FSFileInfo: info,
+				IsLatest:   i == 0,
+			})
+			lastKey, lastVersion = key, versionId
+			count++
+		}
+		if result.IsTruncated {
+			break
+		}
+	}
+	if upperKey != "" {
+		result.IsTruncated = true
+	}
+	if result.IsTruncated {
+		result.NextKeyMarker = lastKey
+		result.NextVersionIdMarker = lastVersion
+	}
+	result.CommonPrefixes = prefixMap.Prefixes()
+	return
+}
+
+// scanNoncurrentVersions walks the versions directory and collects the noncurrent versions
+// and delete markers of objects with the specified prefix.
+func (v *Volume) scanNoncurrentVersions(prefix string) (infos []*FSFileInfo, err error) {
+	var ino uint64
+	var mode uint32
+	if ino, mode, err = v.mw.Lookup_ll(rootIno, VersionsDirName); err != nil {
+		if err == syscall.ENOENT {
+			err = nil
+		}
+		return
+	}
+	if !os.FileMode(mode).IsDir() {
+		return
+	}
+	err = v.recursiveScanVersions(ino, "", prefix, &infos)
+	return
+}
+
+func (v *Volume) recursiveScanVersions(parentId uint64, dir, prefix string, infos *[]*FSFileInfo) (err error) {
+	var children []proto.Dentry
+	if children, err = v.mw.ReadDir_ll(parentId); err != nil {
+		if err == syscall.ENOENT {
+			err = nil
+		}
+		return
+	}
+	for _, child := range children {
+		if os.FileMode(child.Type).IsDir() {
+			subDir := joinObjectPath(dir, child.Name) + pathSep
+			// only descend into directories which may contain keys with the prefix
+			if !strings.HasPrefix(subDir, prefix) && !strings.HasPrefix(prefix, subDir) {
+				continue
+			}
+			if err = v.recursiveScanVersions(child.Inode, strings.TrimSuffix(subDir, pathSep), prefix, infos); err != nil {
+				return
+			}
+			continue
+		}
+		key, versionId, ok := parseVersionEntryName(child.Name)
+		if !ok {
+			continue
+		}
+		path := joinObjectPath(dir, key)
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		*infos = append(*infos, &FSFileInfo{
+			Path:      path,
+			Inode:     child.Inode,
+			VersionId: versionId,
+		})
+	}
+	return
+}
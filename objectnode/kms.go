@@ -0,0 +1,181 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cubefs/cubefs/util/cryptoutil"
+)
+
+const (
+	KMSProviderLocal = "local"
+
+	sseDataKeySize = 32 // AES-256
+)
+
+var (
+	ErrKMSKeyNotFound   = errors.New("kms: master key not found")
+	ErrKMSInvalidSealed = errors.New("kms: invalid sealed key")
+)
+
+// KMS is the key management service used by server-side encryption. It generates the data keys
+// of objects and seals them with the master keys it manages.
+type KMS interface {
+	Name() string
+	// DefaultKeyID returns the ID of the master key used when no key is specified.
+	DefaultKeyID() string
+	// GenerateDataKey returns a new data key and its sealed form under the specified master key.
+	GenerateDataKey(keyID string) (key, sealed []byte, err error)
+	// UnsealDataKey returns the data key sealed by GenerateDataKey.
+	UnsealDataKey(keyID string, sealed []byte) (key []byte, err error)
+}
+
+type KMSConfig struct {
+	Provider string `json:"provider"`
+	// Key file of the local provider, which is a JSON file like:
+	//	{
+	//		"defaultKeyId": "key1",
+	//		"keys": {
+	//			"key1": "<base64 encoded 256-bit key>"
+	//		}
+	//	}
+	KeyFile string `json:"keyFile"`
+}
+
+func NewKMS(conf KMSConfig) (KMS, error) {
+	switch conf.Provider {
+	case KMSProviderLocal, "":
+		return NewLocalKMS(conf.KeyFile)
+	default:
+		return nil, fmt.Errorf("kms: unknown provider '%v'", conf.Provider)
+	}
+}
+
+type localKeyFile struct {
+	DefaultKeyID string            `json:"defaultKeyId"`
+	Keys         map[string]string `json:"keys"`
+}
+
+// LocalKMS is a KMS whose master keys are loaded from a local file. All the ObjectNodes
+// of the cluster must be configured with the same key file.
+type LocalKMS struct {
+	defaultKeyID string
+	keys         map[string]cipher.AEAD
+}
+
+func NewLocalKMS(keyFile string) (*LocalKMS, error) {
+	if keyFile == "" {
+		return nil, errors.New("kms: key file is required")
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	var kf localKeyFile
+	if err = json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("kms: parse key file fail: %v", err)
+	}
+	if len(kf.Keys) == 0 {
+		return nil, errors.New("kms: no master key found in key file")
+	}
+	if _, ok := kf.Keys[kf.DefaultKeyID]; !ok {
+		return nil, fmt.Errorf("kms: default key '%v' not found", kf.DefaultKeyID)
+	}
+	k := &LocalKMS{
+		defaultKeyID: kf.DefaultKeyID,
+		keys:         make(map[string]cipher.AEAD, len(kf.Keys)),
+	}
+	for id, encoded := range kf.Keys {
+		key, err := cryptoutil.Base64Decode(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("kms: decode key '%v' fail: %v", id, err)
+		}
+		if len(key) != sseDataKeySize {
+			return nil, fmt.Errorf("kms: key '%v' must be 256 bits", id)
+		}
+		if k.keys[id], err = newGCM(key); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func (k *LocalKMS) Name() string {
+	return KMSProviderLocal
+}
+
+func (k *LocalKMS) DefaultKeyID() string {
+	return k.defaultKeyID
+}
+
+func (k *LocalKMS) GenerateDataKey(keyID string) (key, sealed []byte, err error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, nil, ErrKMSKeyNotFound
+	}
+	key = make([]byte, sseDataKeySize)
+	if _, err = io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, err
+	}
+	if sealed, err = sealKey(aead, key, []byte(keyID)); err != nil {
+		return nil, nil, err
+	}
+	return key, sealed, nil
+}
+
+func (k *LocalKMS) UnsealDataKey(keyID string, sealed []byte) (key []byte, err error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, ErrKMSKeyNotFound
+	}
+	return unsealKey(aead, sealed, []byte(keyID))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealKey encrypts the key with the AEAD, the random nonce is prepended to the sealed key.
+func sealKey(aead cipher.AEAD, key, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(key)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, key, additional), nil
+}
+
+func unsealKey(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrKMSInvalidSealed
+	}
+	nonce := sealed[:aead.NonceSize()]
+	key, err := aead.Open(nil, nonce, sealed[aead.NonceSize():], additional)
+	if err != nil {
+		return nil, ErrKMSInvalidSealed
+	}
+	return key, nil
+}
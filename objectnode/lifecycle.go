@@ -39,6 +39,11 @@ var (
 	LifeCycleErrSameRuleID       = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Rule ID must be unique. Found same ID for more than one rule.", StatusCode: http.StatusBadRequest}
 	LifeCycleErrDateType         = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "'Date' must be at midnight GMT.", StatusCode: http.StatusBadRequest}
 	LifeCycleErrDaysType         = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "'Days' for Expiration action must be a positive integer.", StatusCode: http.StatusBadRequest}
+	LifeCycleErrTransitionDays   = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "'Days' for Transition action must be a nonnegative integer.", StatusCode: http.StatusBadRequest}
+	LifeCycleErrExpirationDays   = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "'Days' in the Expiration action must be greater than 'Days' in the Transition action.", StatusCode: http.StatusBadRequest}
+	LifeCycleErrStorageClass     = &ErrorCode{ErrorCode: "InvalidStorageClass", ErrorMessage: "The storage class you specified is not valid.", StatusCode: http.StatusBadRequest}
+	LifeCycleErrNoncurrentDays   = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "'NoncurrentDays' for NoncurrentVersionExpiration action must be a positive integer.", StatusCode: http.StatusBadRequest}
+	LifeCycleErrInitiationDays   = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "'DaysAfterInitiation' for AbortIncompleteMultipartUpload action must be a positive integer.", StatusCode: http.StatusBadRequest}
 	LifeCycleErrMalformedXML     = &ErrorCode{ErrorCode: "MalformedXML", ErrorMessage: "The XML you provided was not well-formed or did not validate against our published schema.", StatusCode: http.StatusBadRequest}
 	NoSuchLifecycleConfiguration = &ErrorCode{ErrorCode: "NoSuchLifecycleConfiguration", ErrorMessage: "The lifecycle configuration does not exist.", StatusCode: http.StatusNotFound}
 )
@@ -50,11 +55,14 @@ type LifeCycle struct {
 }
 
 type Rule struct {
-	XMLName xml.Name    `xml:"Rule"`
-	Expire  *Expiration `xml:"Expiration"`
-	Filter  *Filter     `xml:"Filter"`
-	ID      string      `xml:"ID"`
-	Status  string      `xml:"Status"`
+	XMLName                        xml.Name                        `xml:"Rule"`
+	Expire                         *Expiration                     `xml:"Expiration"`
+	Transition                     *Transition                     `xml:"Transition"`
+	NoncurrentVersionExpire        *NoncurrentVersionExpiration    `xml:"NoncurrentVersionExpiration"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload"`
+	Filter                         *Filter                         `xml:"Filter"`
+	ID                             string                          `xml:"ID"`
+	Status                         string                          `xml:"Status"`
 }
 
 type Expiration struct {
@@ -63,6 +71,24 @@ type Expiration struct {
 	Days    *int       `xml:"Days,omitempty"`
 }
 
+// Transition moves the data of the objects to the blobstore, which is the storage class GLACIER.
+type Transition struct {
+	XMLName      xml.Name   `xml:"Transition"`
+	Date         *time.Time `xml:"Date,omitempty"`
+	Days         *int       `xml:"Days,omitempty"`
+	StorageClass string     `xml:"StorageClass"`
+}
+
+type NoncurrentVersionExpiration struct {
+	XMLName        xml.Name `xml:"NoncurrentVersionExpiration"`
+	NoncurrentDays int      `xml:"NoncurrentDays"`
+}
+
+type AbortIncompleteMultipartUpload struct {
+	XMLName             xml.Name `xml:"AbortIncompleteMultipartUpload"`
+	DaysAfterInitiation int      `xml:"DaysAfterInitiation"`
+}
+
 type Filter struct {
 	XMLName xml.Name `xml:"Filter"`
 	Prefix  string   `xml:"Prefix,omitempty"`
@@ -98,6 +124,15 @@ func (l *LifeCycle) Validate() (bool, *ErrorCode) {
 	return true, nil
 }
 
+func (l *LifeCycle) hasTransition() bool {
+	for _, rule := range l.Rules {
+		if rule.Transition != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *Rule) valid() *ErrorCode {
 	if len(r.ID) == 0 {
 		return LifeCycleErrMissingRuleID
@@ -109,12 +144,28 @@ func (r *Rule) valid() *ErrorCode {
 		return LifeCycleErrMalformedXML
 	}
 
-	if r.Expire == nil {
+	if r.Expire == nil && r.Transition == nil && r.NoncurrentVersionExpire == nil && r.AbortIncompleteMultipartUpload == nil {
 		return LifeCycleErrMissingActions
 	}
 
-	if err := r.Expire.validExpiration(); err != nil {
-		return err
+	if r.Expire != nil {
+		if err := r.Expire.validExpiration(); err != nil {
+			return err
+		}
+	}
+	if r.Transition != nil {
+		if err := r.Transition.validTransition(); err != nil {
+			return err
+		}
+		if r.Expire != nil && r.Expire.Days != nil && r.Transition.Days != nil && *r.Expire.Days <= *r.Transition.Days {
+			return LifeCycleErrExpirationDays
+		}
+	}
+	if r.NoncurrentVersionExpire != nil && r.NoncurrentVersionExpire.NoncurrentDays <= 0 {
+		return LifeCycleErrNoncurrentDays
+	}
+	if r.AbortIncompleteMultipartUpload != nil && r.AbortIncompleteMultipartUpload.DaysAfterInitiation <= 0 {
+		return LifeCycleErrInitiationDays
 	}
 
 	return nil
@@ -144,3 +195,23 @@ func (e *Expiration) validExpiration() *ErrorCode {
 
 	return nil
 }
+
+func (t *Transition) validTransition() *ErrorCode {
+	if t.StorageClass != StorageClassGlacier {
+		return LifeCycleErrStorageClass
+	}
+	// Date and Days cannot be set at the same time, and one of them is required
+	if (t.Date != nil) == (t.Days != nil) {
+		return LifeCycleErrMalformedXML
+	}
+	if t.Date != nil {
+		date := t.Date.In(time.UTC)
+		if !(date.Hour() == 0 && date.Minute() == 0 && date.Second() == 0 && date.Nanosecond() == 0) {
+			return LifeCycleErrDateType
+		}
+	} else if *t.Days < 0 {
+		return LifeCycleErrTransitionDays
+	}
+
+	return nil
+}
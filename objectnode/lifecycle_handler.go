@@ -65,6 +65,26 @@ func (o *ObjectNode) getBucketLifecycleConfigurationHandler(w http.ResponseWrite
 				rule.Expire.Days = &lc.Expire.Days
 			}
 		}
+		if lc.Transition != nil {
+			rule.Transition = &Transition{
+				StorageClass: StorageClassGlacier,
+			}
+			if lc.Transition.Date != nil {
+				rule.Transition.Date = lc.Transition.Date
+			} else {
+				rule.Transition.Days = &lc.Transition.Days
+			}
+		}
+		if lc.NoncurrentVersionExpire != nil {
+			rule.NoncurrentVersionExpire = &NoncurrentVersionExpiration{
+				NoncurrentDays: lc.NoncurrentVersionExpire.NoncurrentDays,
+			}
+		}
+		if lc.AbortIncompleteMultipartUpload != nil {
+			rule.AbortIncompleteMultipartUpload = &AbortIncompleteMultipartUpload{
+				DaysAfterInitiation: lc.AbortIncompleteMultipartUpload.DaysAfterInitiation,
+			}
+		}
 		if lc.Filter != nil {
 			rule.Filter = &Filter{
 				Prefix: lc.Filter.Prefix,
@@ -99,7 +119,8 @@ func (o *ObjectNode) putBucketLifecycleConfigurationHandler(w http.ResponseWrite
 		errorCode = InvalidBucketName
 		return
 	}
-	if _, err = o.vm.Volume(param.Bucket()); err != nil {
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
 		errorCode = NoSuchBucket
 		return
 	}
@@ -131,6 +152,12 @@ func (o *ObjectNode) putBucketLifecycleConfigurationHandler(w http.ResponseWrite
 		log.LogErrorf("putBucketLifecycle failed: validate err: requestID(%v) lifeCycle(%v) err(%v)", GetRequestID(r), lifeCycle, errorCode)
 		return
 	}
+	// the data of the cold volume is kept in the blobstore already
+	if !proto.IsHot(vol.volType) && lifeCycle.hasTransition() {
+		log.LogErrorf("putBucketLifecycle failed: transition of cold volume: requestID(%v) volume(%v)", GetRequestID(r), param.Bucket())
+		errorCode = LifeCycleErrStorageClass
+		return
+	}
 
 	req := proto.LcConfiguration{
 		VolName: param.Bucket(),
@@ -151,6 +178,25 @@ func (o *ObjectNode) putBucketLifecycleConfigurationHandler(w http.ResponseWrite
 				rule.Expire.Days = *lr.Expire.Days
 			}
 		}
+		if lr.Transition != nil {
+			rule.Transition = &proto.TransitionConfig{}
+			if lr.Transition.Date != nil {
+				rule.Transition.Date = lr.Transition.Date
+			}
+			if lr.Transition.Days != nil {
+				rule.Transition.Days = *lr.Transition.Days
+			}
+		}
+		if lr.NoncurrentVersionExpire != nil {
+			rule.NoncurrentVersionExpire = &proto.NoncurrentVersionExpirationConfig{
+				NoncurrentDays: lr.NoncurrentVersionExpire.NoncurrentDays,
+			}
+		}
+		if lr.AbortIncompleteMultipartUpload != nil {
+			rule.AbortIncompleteMultipartUpload = &proto.AbortIncompleteMultipartUploadConfig{
+				DaysAfterInitiation: lr.AbortIncompleteMultipartUpload.DaysAfterInitiation,
+			}
+		}
 		if lr.Filter != nil {
 			rule.Filter = &proto.FilterConfig{
 				Prefix: lr.Filter.Prefix,
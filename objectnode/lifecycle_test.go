@@ -124,3 +124,87 @@ func TestLifecycleConfiguration(t *testing.T) {
 	_, err = l1.Validate()
 	require.Equal(t, err, LifeCycleErrMissingRules)
 }
+
+func TestLifecycleTransitionRules(t *testing.T) {
+	LifecycleXml := `
+<LifecycleConfiguration>
+    <Rule>
+        <Filter>
+           <Prefix>logs/</Prefix>
+        </Filter>
+        <ID>id1</ID>
+        <Status>Enabled</Status>
+        <Transition>
+           <Days>30</Days>
+           <StorageClass>GLACIER</StorageClass>
+        </Transition>
+        <Expiration>
+           <Days>365</Days>
+        </Expiration>
+        <NoncurrentVersionExpiration>
+           <NoncurrentDays>7</NoncurrentDays>
+        </NoncurrentVersionExpiration>
+        <AbortIncompleteMultipartUpload>
+           <DaysAfterInitiation>3</DaysAfterInitiation>
+        </AbortIncompleteMultipartUpload>
+    </Rule>
+</LifecycleConfiguration>
+`
+
+	l1 := NewLifeCycle()
+	err := xml.Unmarshal([]byte(LifecycleXml), l1)
+	require.NoError(t, err)
+	ok, _ := l1.Validate()
+	require.True(t, ok)
+	require.True(t, l1.hasTransition())
+	rule := l1.Rules[0]
+	require.Equal(t, 30, *rule.Transition.Days)
+	require.Equal(t, 7, rule.NoncurrentVersionExpire.NoncurrentDays)
+	require.Equal(t, 3, rule.AbortIncompleteMultipartUpload.DaysAfterInitiation)
+
+	// expiration is not later than transition
+	day := 30
+	rule.Expire.Days = &day
+	_, err = l1.Validate()
+	require.Equal(t, err, LifeCycleErrExpirationDays)
+	rule.Expire = nil
+
+	// invalid storage class
+	rule.Transition.StorageClass = "STANDARD_IA"
+	_, err = l1.Validate()
+	require.Equal(t, err, LifeCycleErrStorageClass)
+	rule.Transition.StorageClass = StorageClassGlacier
+
+	// days < 0
+	day = -1
+	rule.Transition.Days = &day
+	_, err = l1.Validate()
+	require.Equal(t, err, LifeCycleErrTransitionDays)
+
+	// days and date
+	day = 0
+	now := time.Now().In(time.UTC)
+	ti := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	rule.Transition.Date = &ti
+	_, err = l1.Validate()
+	require.Equal(t, err, LifeCycleErrMalformedXML)
+	rule.Transition.Days = nil
+	ok, _ = l1.Validate()
+	require.True(t, ok)
+
+	rule.NoncurrentVersionExpire.NoncurrentDays = 0
+	_, err = l1.Validate()
+	require.Equal(t, err, LifeCycleErrNoncurrentDays)
+	rule.NoncurrentVersionExpire.NoncurrentDays = 1
+
+	rule.AbortIncompleteMultipartUpload.DaysAfterInitiation = 0
+	_, err = l1.Validate()
+	require.Equal(t, err, LifeCycleErrInitiationDays)
+
+	// the rule of the noncurrent versions only
+	rule.Transition = nil
+	rule.AbortIncompleteMultipartUpload = nil
+	ok, _ = l1.Validate()
+	require.True(t, ok)
+	require.False(t, l1.hasTransition())
+}
@@ -0,0 +1,341 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/EventNotifications.html
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Event types of bucket notifications.
+	EventObjectCreatedAll                 = "s3:ObjectCreated:*"
+	EventObjectCreatedPut                 = "s3:ObjectCreated:Put"
+	EventObjectCreatedPost                = "s3:ObjectCreated:Post"
+	EventObjectCreatedCopy                = "s3:ObjectCreated:Copy"
+	EventObjectCreatedCompleteMultipart   = "s3:ObjectCreated:CompleteMultipartUpload"
+	EventObjectRemovedAll                 = "s3:ObjectRemoved:*"
+	EventObjectRemovedDelete              = "s3:ObjectRemoved:Delete"
+	EventObjectRemovedDeleteMarkerCreated = "s3:ObjectRemoved:DeleteMarkerCreated"
+
+	NotificationFilterPrefix = "prefix"
+	NotificationFilterSuffix = "suffix"
+
+	// Targets of notifications are configured by the administrator and referenced in bucket
+	// notification configurations by ARNs like 'arn:cubefs:sqs::<id>:webhook'.
+	NotificationARNPrefix    = "arn:cubefs:sqs::"
+	NotificationTypeWebhook  = "webhook"
+	NotificationTypeKafka    = "kafka"
+	notificationEventVersion = "2.1"
+	notificationEventSource  = "cubefs:s3"
+	notificationSchema       = "1.0"
+
+	MaxNotificationSize    = 1 << 20 // 1MB
+	MaxNotificationConfigs = 100
+)
+
+var notificationEvents = map[string]struct{}{
+	EventObjectCreatedAll:                 {},
+	EventObjectCreatedPut:                 {},
+	EventObjectCreatedPost:                {},
+	EventObjectCreatedCopy:                {},
+	EventObjectCreatedCompleteMultipart:   {},
+	EventObjectRemovedAll:                 {},
+	EventObjectRemovedDelete:              {},
+	EventObjectRemovedDeleteMarkerCreated: {},
+}
+
+var (
+	NotificationNotConfigured    = &ErrorCode{ErrorCode: "NotImplemented", ErrorMessage: "Bucket notification is not configured on this server.", StatusCode: http.StatusNotImplemented}
+	NotificationErrEvent         = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The event is not supported for notifications.", StatusCode: http.StatusBadRequest}
+	NotificationErrMissingEvent  = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "At least one event must be specified in a notification configuration.", StatusCode: http.StatusBadRequest}
+	NotificationErrDestination   = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Unable to validate the following destination configurations.", StatusCode: http.StatusBadRequest}
+	NotificationErrFilterName    = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The filter rule name must be either prefix or suffix.", StatusCode: http.StatusBadRequest}
+	NotificationErrFilterRepeat  = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Cannot specify more than one prefix or suffix rule in a filter.", StatusCode: http.StatusBadRequest}
+	NotificationErrSameID        = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Configurations must have unique ids.", StatusCode: http.StatusBadRequest}
+	NotificationErrTooManyConfig = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Notification configurations should not exceed allowed limit of 100.", StatusCode: http.StatusBadRequest}
+	NotificationErrUnsupported   = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Only queue and topic destinations are supported.", StatusCode: http.StatusBadRequest}
+)
+
+type NotificationConfiguration struct {
+	XMLName             xml.Name              `xml:"NotificationConfiguration" json:"-"`
+	XMLNS               string                `xml:"xmlns,attr,omitempty" json:"-"`
+	QueueConfigurations []*NotificationConfig `xml:"QueueConfiguration" json:"queue_configurations,omitempty"`
+	TopicConfigurations []*NotificationConfig `xml:"TopicConfiguration" json:"topic_configurations,omitempty"`
+	// Lambda functions are not supported, it is only used to reject such configurations.
+	CloudFunctionConfigurations []struct{} `xml:"CloudFunctionConfiguration" json:"-"`
+}
+
+// NotificationConfig is a queue or topic configuration, only one of Queue and Topic is set.
+type NotificationConfig struct {
+	ID     string              `xml:"Id,omitempty" json:"id,omitempty"`
+	Filter *NotificationFilter `xml:"Filter,omitempty" json:"filter,omitempty"`
+	Queue  string              `xml:"Queue,omitempty" json:"queue,omitempty"`
+	Topic  string              `xml:"Topic,omitempty" json:"topic,omitempty"`
+	Events []string            `xml:"Event" json:"events"`
+}
+
+type NotificationFilter struct {
+	S3Key NotificationS3Key `xml:"S3Key" json:"s3key"`
+}
+
+type NotificationS3Key struct {
+	FilterRules []NotificationFilterRule `xml:"FilterRule" json:"filter_rules,omitempty"`
+}
+
+type NotificationFilterRule struct {
+	Name  string `xml:"Name" json:"name"`
+	Value string `xml:"Value" json:"value"`
+}
+
+// ParseNotificationConfig parses the notification configuration, hasTarget reports whether
+// the ARN of a destination is a target configured on this server.
+func ParseNotificationConfig(data []byte, hasTarget func(arn string) bool) (*NotificationConfiguration, *ErrorCode) {
+	config := &NotificationConfiguration{}
+	if err := xml.Unmarshal(data, config); err != nil {
+		return nil, MalformedXML
+	}
+	if errCode := config.Validate(hasTarget); errCode != nil {
+		return nil, errCode
+	}
+	return config, nil
+}
+
+func (c *NotificationConfiguration) Validate(hasTarget func(arn string) bool) *ErrorCode {
+	if len(c.CloudFunctionConfigurations) > 0 {
+		return NotificationErrUnsupported
+	}
+	if len(c.QueueConfigurations)+len(c.TopicConfigurations) > MaxNotificationConfigs {
+		return NotificationErrTooManyConfig
+	}
+	ids := make(map[string]struct{})
+	for i, nc := range c.configs() {
+		if len(nc.ID) > MaxIdLength {
+			return InvalidArgument
+		}
+		if nc.ID != "" {
+			if _, ok := ids[nc.ID]; ok {
+				return NotificationErrSameID
+			}
+			ids[nc.ID] = struct{}{}
+		}
+		isQueue := i < len(c.QueueConfigurations)
+		if (isQueue && (nc.Queue == "" || nc.Topic != "")) || (!isQueue && (nc.Topic == "" || nc.Queue != "")) {
+			return MalformedXML
+		}
+		if !hasTarget(nc.TargetARN()) {
+			return NotificationErrDestination
+		}
+		if len(nc.Events) == 0 {
+			return NotificationErrMissingEvent
+		}
+		for _, event := range nc.Events {
+			if _, ok := notificationEvents[event]; !ok {
+				return NotificationErrEvent
+			}
+		}
+		if errCode := nc.Filter.validate(); errCode != nil {
+			return errCode
+		}
+	}
+	return nil
+}
+
+func (c *NotificationConfiguration) configs() []*NotificationConfig {
+	configs := make([]*NotificationConfig, 0, len(c.QueueConfigurations)+len(c.TopicConfigurations))
+	configs = append(configs, c.QueueConfigurations...)
+	return append(configs, c.TopicConfigurations...)
+}
+
+// Match returns the configurations which subscribe the event of the key.
+func (c *NotificationConfiguration) Match(event, key string) (matched []*NotificationConfig) {
+	if c == nil {
+		return nil
+	}
+	for _, nc := range c.configs() {
+		if nc.matchEvent(event) && nc.Filter.matchKey(key) {
+			matched = append(matched, nc)
+		}
+	}
+	return
+}
+
+func (c *NotificationConfig) TargetARN() string {
+	if c.Queue != "" {
+		return c.Queue
+	}
+	return c.Topic
+}
+
+func (c *NotificationConfig) matchEvent(event string) bool {
+	for _, e := range c.Events {
+		if e == event || (strings.HasSuffix(e, "*") && strings.HasPrefix(event, strings.TrimSuffix(e, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *NotificationFilter) validate() *ErrorCode {
+	if f == nil {
+		return nil
+	}
+	var hasPrefix, hasSuffix bool
+	for _, rule := range f.S3Key.FilterRules {
+		switch strings.ToLower(rule.Name) {
+		case NotificationFilterPrefix:
+			if hasPrefix {
+				return NotificationErrFilterRepeat
+			}
+			hasPrefix = true
+		case NotificationFilterSuffix:
+			if hasSuffix {
+				return NotificationErrFilterRepeat
+			}
+			hasSuffix = true
+		default:
+			return NotificationErrFilterName
+		}
+	}
+	return nil
+}
+
+func (f *NotificationFilter) matchKey(key string) bool {
+	if f == nil {
+		return true
+	}
+	for _, rule := range f.S3Key.FilterRules {
+		switch strings.ToLower(rule.Name) {
+		case NotificationFilterPrefix:
+			if !strings.HasPrefix(key, rule.Value) {
+				return false
+			}
+		case NotificationFilterSuffix:
+			if !strings.HasSuffix(key, rule.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NotificationTargetARN returns the ARN of the notification target configured on this server.
+func NotificationTargetARN(targetType, id string) string {
+	return NotificationARNPrefix + id + ":" + targetType
+}
+
+// NotificationEvent is the message delivered to the targets, in the format of AWS S3 event notifications.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-content-structure.html
+type NotificationEvent struct {
+	Records []*NotificationRecord `json:"Records"`
+}
+
+type NotificationRecord struct {
+	EventVersion      string            `json:"eventVersion"`
+	EventSource       string            `json:"eventSource"`
+	AwsRegion         string            `json:"awsRegion"`
+	EventTime         string            `json:"eventTime"`
+	EventName         string            `json:"eventName"`
+	UserIdentity      NotificationUser  `json:"userIdentity"`
+	RequestParameters map[string]string `json:"requestParameters"`
+	ResponseElements  map[string]string `json:"responseElements"`
+	S3                NotificationS3    `json:"s3"`
+}
+
+type NotificationUser struct {
+	PrincipalID string `json:"principalId"`
+}
+
+type NotificationS3 struct {
+	SchemaVersion   string             `json:"s3SchemaVersion"`
+	ConfigurationID string             `json:"configurationId"`
+	Bucket          NotificationBucket `json:"bucket"`
+	Object          NotificationObject `json:"object"`
+}
+
+type NotificationBucket struct {
+	Name          string           `json:"name"`
+	OwnerIdentity NotificationUser `json:"ownerIdentity"`
+	ARN           string           `json:"arn"`
+}
+
+type NotificationObject struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size,omitempty"`
+	ETag      string `json:"eTag,omitempty"`
+	VersionID string `json:"versionId,omitempty"`
+	Sequencer string `json:"sequencer"`
+}
+
+// NotificationEventInfo describes the object which the event occurs on.
+type NotificationEventInfo struct {
+	Event     string
+	Region    string
+	Bucket    string
+	Owner     string
+	Key       string
+	Size      int64
+	ETag      string
+	VersionID string
+	Requester string
+	RequestID string
+	SourceIP  string
+	Time      time.Time
+}
+
+// newNotificationEvent builds the event of the configuration, the key of object is URL encoded like AWS.
+func newNotificationEvent(info *NotificationEventInfo, configID string) *NotificationEvent {
+	record := &NotificationRecord{
+		EventVersion:      notificationEventVersion,
+		EventSource:       notificationEventSource,
+		AwsRegion:         info.Region,
+		EventTime:         info.Time.UTC().Format(ISO8601Layout),
+		EventName:         strings.TrimPrefix(info.Event, "s3:"),
+		UserIdentity:      NotificationUser{PrincipalID: info.Requester},
+		RequestParameters: map[string]string{"sourceIPAddress": info.SourceIP},
+		ResponseElements:  map[string]string{XAmzRequestId: info.RequestID},
+		S3: NotificationS3{
+			SchemaVersion:   notificationSchema,
+			ConfigurationID: configID,
+			Bucket: NotificationBucket{
+				Name:          info.Bucket,
+				OwnerIdentity: NotificationUser{PrincipalID: info.Owner},
+				ARN:           ReplicationBucketARNPrefix + info.Bucket,
+			},
+			Object: NotificationObject{
+				Key:       url.QueryEscape(info.Key),
+				Size:      info.Size,
+				ETag:      info.ETag,
+				VersionID: info.VersionID,
+				Sequencer: strings.ToUpper(strconv.FormatInt(info.Time.UnixNano(), 16)),
+			},
+		},
+	}
+	return &NotificationEvent{Records: []*NotificationRecord{record}}
+}
+
+func storeBucketNotification(bytes []byte, vol *Volume) (err error) {
+	return vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSNotification, bytes)
+}
+
+func deleteBucketNotification(vol *Volume) (err error) {
+	return vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSNotification)
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// Put bucket notification configuration
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketNotificationConfiguration.html
+func (o *ObjectNode) putBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putBucketNotificationHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(r.Body, MaxNotificationSize+1)); err != nil {
+		log.LogErrorf("putBucketNotificationHandler: read request body fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if len(body) > MaxNotificationSize {
+		errorCode = EntityTooLarge
+		return
+	}
+	var config *NotificationConfiguration
+	if config, errorCode = ParseNotificationConfig(body, o.hasNotificationTarget); errorCode != nil {
+		log.LogErrorf("putBucketNotificationHandler: parse notification config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), errorCode)
+		if errorCode == NotificationErrDestination && o.notifier == nil {
+			errorCode = NotificationNotConfigured
+		}
+		return
+	}
+
+	// an empty configuration turns off the notifications of the bucket
+	if len(config.configs()) == 0 {
+		if err = deleteBucketNotification(vol); err != nil {
+			log.LogErrorf("putBucketNotificationHandler: delete notification config fail: requestID(%v) volume(%v) err(%v)",
+				GetRequestID(r), vol.Name(), err)
+			return
+		}
+		vol.metaLoader.storeNotification(nil)
+		log.LogInfof("Audit: delete bucket notification: requestID(%v) volume(%v)", GetRequestID(r), vol.Name())
+		return
+	}
+
+	if body, err = json.Marshal(config); err != nil {
+		log.LogErrorf("putBucketNotificationHandler: json marshal notification config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+	if err = storeBucketNotification(body, vol); err != nil {
+		log.LogErrorf("putBucketNotificationHandler: store notification config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), err)
+		return
+	}
+	vol.metaLoader.storeNotification(config)
+
+	log.LogInfof("Audit: put bucket notification: requestID(%v) volume(%v) config(%v)",
+		GetRequestID(r), vol.Name(), string(body))
+	return
+}
+
+// Get bucket notification configuration
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketNotificationConfiguration.html
+func (o *ObjectNode) getBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getBucketNotificationHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var config *NotificationConfiguration
+	if config, err = vol.metaLoader.loadNotification(); err != nil {
+		log.LogErrorf("getBucketNotificationHandler: load notification fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	// an empty configuration is returned if the notification is not configured
+	if config == nil {
+		config = &NotificationConfiguration{}
+	}
+	config.XMLNS = XMLNS
+
+	var data []byte
+	if data, err = MarshalXMLEntity(config); err != nil {
+		log.LogErrorf("getBucketNotificationHandler: xml marshal fail: requestID(%v) volume(%v) config(%+v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+
+	writeSuccessResponseXML(w, data)
+	return
+}
+
+func (o *ObjectNode) hasNotificationTarget(arn string) bool {
+	return o.notifier != nil && o.notifier.HasTarget(arn)
+}
+
+// notifyObjectEvent sends the event of the object to the notification targets subscribed by the bucket,
+// fileInfo is nil for the objects which have been removed.
+func (o *ObjectNode) notifyObjectEvent(r *http.Request, vol *Volume, event, key string, fileInfo *FSFileInfo) {
+	if o.notifier == nil {
+		return
+	}
+	param := ParseRequestParam(r)
+	info := &NotificationEventInfo{
+		Event:     event,
+		Region:    o.region,
+		Bucket:    vol.Name(),
+		Owner:     vol.Owner(),
+		Key:       key,
+		Requester: param.Requester(),
+		RequestID: GetRequestID(r),
+		SourceIP:  getRequestIP(r),
+		Time:      time.Now(),
+	}
+	if fileInfo != nil {
+		info.Size = fileInfo.Size
+		info.ETag = fileInfo.ETag
+		info.VersionID = fileInfo.VersionId
+	}
+	if err := o.notifier.Notify(vol, info); err != nil {
+		log.LogErrorf("notifyObjectEvent: notify fail: requestID(%v) volume(%v) event(%v) key(%v) err(%v)",
+			info.RequestID, vol.Name(), event, key, err)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNotificationConfig(t *testing.T) {
+	webhookARN := NotificationTargetARN(NotificationTypeWebhook, "app1")
+	kafkaARN := NotificationTargetARN(NotificationTypeKafka, "app2")
+	hasTarget := func(arn string) bool {
+		return arn == webhookARN || arn == kafkaARN
+	}
+	tests := []struct {
+		value       string
+		expectedErr *ErrorCode
+	}{
+		{
+			value: `<NotificationConfiguration>
+						<QueueConfiguration>
+							<Id>images</Id>
+							<Queue>arn:cubefs:sqs::app1:webhook</Queue>
+							<Event>s3:ObjectCreated:*</Event>
+							<Filter><S3Key>
+								<FilterRule><Name>prefix</Name><Value>images/</Value></FilterRule>
+								<FilterRule><Name>Suffix</Name><Value>.jpg</Value></FilterRule>
+							</S3Key></Filter>
+						</QueueConfiguration>
+						<TopicConfiguration>
+							<Topic>arn:cubefs:sqs::app2:kafka</Topic>
+							<Event>s3:ObjectRemoved:Delete</Event>
+						</TopicConfiguration>
+					</NotificationConfiguration>`,
+		},
+		{
+			value: `<NotificationConfiguration></NotificationConfiguration>`,
+		},
+		{
+			value: `<NotificationConfiguration><QueueConfiguration>
+						<Queue>arn:cubefs:sqs::app3:webhook</Queue><Event>s3:ObjectCreated:*</Event>
+					</QueueConfiguration></NotificationConfiguration>`,
+			expectedErr: NotificationErrDestination,
+		},
+		{
+			value: `<NotificationConfiguration><QueueConfiguration>
+						<Queue>arn:cubefs:sqs::app1:webhook</Queue><Event>s3:ObjectRestore:*</Event>
+					</QueueConfiguration></NotificationConfiguration>`,
+			expectedErr: NotificationErrEvent,
+		},
+		{
+			value: `<NotificationConfiguration><QueueConfiguration>
+						<Queue>arn:cubefs:sqs::app1:webhook</Queue>
+					</QueueConfiguration></NotificationConfiguration>`,
+			expectedErr: NotificationErrMissingEvent,
+		},
+		{
+			value: `<NotificationConfiguration><QueueConfiguration>
+						<Topic>arn:cubefs:sqs::app2:kafka</Topic><Event>s3:ObjectCreated:*</Event>
+					</QueueConfiguration></NotificationConfiguration>`,
+			expectedErr: MalformedXML,
+		},
+		{
+			value: `<NotificationConfiguration><QueueConfiguration>
+						<Queue>arn:cubefs:sqs::app1:webhook</Queue><Event>s3:ObjectCreated:*</Event>
+						<Filter><S3Key><FilterRule><Name>regex</Name><Value>.*</Value></FilterRule></S3Key></Filter>
+					</QueueConfiguration></NotificationConfiguration>`,
+			expectedErr: NotificationErrFilterName,
+		},
+		{
+			value: `<NotificationConfiguration><QueueConfiguration>
+						<Queue>arn:cubefs:sqs::app1:webhook</Queue><Event>s3:ObjectCreated:*</Event>
+						<Filter><S3Key>
+							<FilterRule><Name>prefix</Name><Value>a</Value></FilterRule>
+							<FilterRule><Name>prefix</Name><Value>b</Value></FilterRule>
+						</S3Key></Filter>
+					</QueueConfiguration></NotificationConfiguration>`,
+			expectedErr: NotificationErrFilterRepeat,
+		},
+		{
+			value: `<NotificationConfiguration>
+						<QueueConfiguration><Id>1</Id><Queue>arn:cubefs:sqs::app1:webhook</Queue><Event>s3:ObjectCreated:*</Event></QueueConfiguration>
+						<TopicConfiguration><Id>1</Id><Topic>arn:cubefs:sqs::app2:kafka</Topic><Event>s3:ObjectCreated:*</Event></TopicConfiguration>
+					</NotificationConfiguration>`,
+			expectedErr: NotificationErrSameID,
+		},
+		{
+			value: `<NotificationConfiguration><CloudFunctionConfiguration>
+						<CloudFunction>arn:aws:lambda:us-west-2:35667example:function:CreateThumbnail</CloudFunction>
+						<Event>s3:ObjectCreated:*</Event>
+					</CloudFunctionConfiguration></NotificationConfiguration>`,
+			expectedErr: NotificationErrUnsupported,
+		},
+	}
+	for i, tt := range tests {
+		_, errCode := ParseNotificationConfig([]byte(tt.value), hasTarget)
+		require.Equal(t, tt.expectedErr, errCode, "case %v", i)
+	}
+}
+
+func TestNotificationMatch(t *testing.T) {
+	config := &NotificationConfiguration{
+		QueueConfigurations: []*NotificationConfig{
+			{
+				ID:     "images",
+				Queue:  "arn:cubefs:sqs::app1:webhook",
+				Events: []string{EventObjectCreatedAll},
+				Filter: &NotificationFilter{S3Key: NotificationS3Key{FilterRules: []NotificationFilterRule{
+					{Name: NotificationFilterPrefix, Value: "images/"},
+					{Name: NotificationFilterSuffix, Value: ".jpg"},
+				}}},
+			},
+		},
+		TopicConfigurations: []*NotificationConfig{
+			{
+				ID:     "removed",
+				Topic:  "arn:cubefs:sqs::app2:kafka",
+				Events: []string{EventObjectRemovedDelete},
+			},
+		},
+	}
+	matchedIDs := func(event, key string) (ids []string) {
+		for _, nc := range config.Match(event, key) {
+			ids = append(ids, nc.ID)
+		}
+		return
+	}
+	require.Equal(t, []string{"images"}, matchedIDs(EventObjectCreatedPut, "images/a.jpg"))
+	require.Equal(t, []string{"images"}, matchedIDs(EventObjectCreatedCompleteMultipart, "images/b/c.jpg"))
+	require.Nil(t, matchedIDs(EventObjectCreatedPut, "images/a.png"))
+	require.Nil(t, matchedIDs(EventObjectCreatedPut, "docs/a.jpg"))
+	require.Equal(t, []string{"removed"}, matchedIDs(EventObjectRemovedDelete, "images/a.jpg"))
+	require.Nil(t, matchedIDs(EventObjectRemovedDeleteMarkerCreated, "images/a.jpg"))
+
+	var nilConfig *NotificationConfiguration
+	require.Nil(t, nilConfig.Match(EventObjectCreatedPut, "a"))
+}
+
+func TestNotificationEvent(t *testing.T) {
+	now := time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+	event := newNotificationEvent(&NotificationEventInfo{
+		Event:     EventObjectCreatedPut,
+		Region:    "cfs_dev",
+		Bucket:    "bucket",
+		Owner:     "owner",
+		Key:       "images/a b.jpg",
+		Size:      1024,
+		ETag:      "etag",
+		VersionID: "v1",
+		Requester: "user",
+		RequestID: "request",
+		SourceIP:  "127.0.0.1",
+		Time:      now,
+	}, "images")
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	decoded := struct {
+		Records []struct {
+			EventName string `json:"eventName"`
+			EventTime string `json:"eventTime"`
+			AwsRegion string `json:"awsRegion"`
+			S3        struct {
+				ConfigurationID string `json:"configurationId"`
+				Bucket          struct {
+					Name string `json:"name"`
+					ARN  string `json:"arn"`
+				} `json:"bucket"`
+				Object struct {
+					Key       string `json:"key"`
+					Size      int64  `json:"size"`
+					VersionID string `json:"versionId"`
+				} `json:"object"`
+			} `json:"s3"`
+		} `json:"Records"`
+	}{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Len(t, decoded.Records, 1)
+	record := decoded.Records[0]
+	require.Equal(t, "ObjectCreated:Put", record.EventName)
+	require.Equal(t, "2023-05-01T08:00:00.000Z", record.EventTime)
+	require.Equal(t, "cfs_dev", record.AwsRegion)
+	require.Equal(t, "images", record.S3.ConfigurationID)
+	require.Equal(t, "bucket", record.S3.Bucket.Name)
+	require.Equal(t, "arn:aws:s3:::bucket", record.S3.Bucket.ARN)
+	require.Equal(t, "images%2Fa+b.jpg", record.S3.Object.Key)
+	require.Equal(t, int64(1024), record.S3.Object.Size)
+	require.Equal(t, "v1", record.S3.Object.VersionID)
+}
+
+func TestNotifierDelivery(t *testing.T) {
+	var (
+		failures int32 = 1
+		received       = make(chan []byte, 10)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		received <- data
+	}))
+	defer server.Close()
+
+	dir, err := os.MkdirTemp("", "notification-spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := NotificationServerConfig{
+		SpoolDir:         dir,
+		RetryIntervalSec: 1,
+		Webhook: map[string]WebhookNotificationConfig{
+			"app1": {Enable: true, WebhookConfig: WebhookConfig{Endpoint: server.URL}},
+			"app2": {Enable: false, WebhookConfig: WebhookConfig{Endpoint: server.URL}},
+		},
+	}
+	n, err := NewNotifier(conf)
+	require.NoError(t, err)
+	target := NotificationTargetARN(NotificationTypeWebhook, "app1")
+	require.True(t, n.HasTarget(target))
+	require.False(t, n.HasTarget(NotificationTargetARN(NotificationTypeWebhook, "app2")))
+
+	// the task is persisted before the notifier starts, and delivered after a failed attempt
+	task := &NotificationTask{
+		Target: target,
+		Event: newNotificationEvent(&NotificationEventInfo{
+			Event: EventObjectCreatedPut, Bucket: "bucket", Key: "a.jpg", Time: time.Now(),
+		}, "images"),
+		CreateTime: time.Now().Unix(),
+	}
+	require.NoError(t, n.spool.save(task))
+	n.Start()
+	defer n.Close()
+
+	select {
+	case data := <-received:
+		event := &NotificationEvent{}
+		require.NoError(t, json.Unmarshal(data, event))
+		require.Equal(t, "ObjectCreated:Put", event.Records[0].EventName)
+	case <-time.After(10 * time.Second):
+		t.Fatal("event is not delivered")
+	}
+	require.Eventually(t, func() bool {
+		tasks, err := n.spool.load(func() spoolTask { return &NotificationTask{} })
+		return err == nil && len(tasks) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestNotificationServerConfig(t *testing.T) {
+	conf := NotificationServerConfig{}
+	require.Error(t, conf.fixConfig())
+
+	conf.SpoolDir = "spool"
+	require.NoError(t, conf.fixConfig())
+	require.Equal(t, defaultNotificationWorkers, conf.Workers)
+	require.Equal(t, defaultNotificationMaxRetry, conf.MaxRetry)
+
+	_, err := NewNotifier(NotificationServerConfig{
+		SpoolDir: os.TempDir(),
+		Webhook:  map[string]WebhookNotificationConfig{"app": {Enable: true, WebhookConfig: WebhookConfig{Endpoint: "ftp://a"}}},
+	})
+	require.Error(t, err)
+}
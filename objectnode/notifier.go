@@ -0,0 +1,408 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const (
+	defaultNotificationWorkers       = 4
+	defaultNotificationQueueSize     = 10000
+	defaultNotificationMaxRetry      = 20
+	defaultNotificationRetryInterval = 10
+	maxNotificationRetryBackoff      = time.Hour
+	notificationWebhookTimeout       = 3 * time.Second
+)
+
+var NotificationWebhookUserAgent = "Golang cubefs/objectnode notification webhook"
+
+// NotificationServerConfig is the configuration of the bucket notifier of ObjectNode.
+type NotificationServerConfig struct {
+	// Directory in which the undelivered events are persisted, so that they survive restarts.
+	SpoolDir         string `json:"spoolDir"`
+	Workers          int    `json:"workers"`
+	QueueSize        int    `json:"queueSize"`
+	MaxRetry         int    `json:"maxRetry"`
+	RetryIntervalSec int    `json:"retryIntervalSec"`
+	// The key of map is a unique identifier of target, which is referenced by the ARN
+	// 'arn:cubefs:sqs::<id>:webhook' or 'arn:cubefs:sqs::<id>:kafka' in bucket notification configurations.
+	Webhook map[string]WebhookNotificationConfig `json:"webhook,omitempty"`
+	Kafka   map[string]KafkaNotificationConfig   `json:"kafka,omitempty"`
+}
+
+type WebhookNotificationConfig struct {
+	Enable bool `json:"enable"`
+
+	WebhookConfig
+}
+
+type KafkaNotificationConfig struct {
+	Enable bool `json:"enable"`
+
+	KafkaConfig
+}
+
+func (c *NotificationServerConfig) fixConfig() error {
+	if c.SpoolDir == "" {
+		return errors.New("spoolDir is required")
+	}
+	if c.Workers <= 0 {
+		c.Workers = defaultNotificationWorkers
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultNotificationQueueSize
+	}
+	if c.MaxRetry <= 0 {
+		c.MaxRetry = defaultNotificationMaxRetry
+	}
+	if c.RetryIntervalSec <= 0 {
+		c.RetryIntervalSec = defaultNotificationRetryInterval
+	}
+	return nil
+}
+
+// notificationTarget delivers the events to an endpoint, key is the bucket and key of the object.
+type notificationTarget interface {
+	Send(key string, data []byte) error
+	Close() error
+}
+
+type webhookTarget struct {
+	client *http.Client
+	conf   WebhookConfig
+}
+
+func newWebhookTarget(conf WebhookConfig) (*webhookTarget, error) {
+	if err := conf.FixConfig(); err != nil {
+		return nil, err
+	}
+	client, err := conf.BuildClient()
+	if err != nil {
+		return nil, err
+	}
+	return &webhookTarget{client: client, conf: conf}, nil
+}
+
+func (t *webhookTarget) Send(key string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.conf.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(ContentType, ValueContentTypeJSON)
+	req.Header.Set(UserAgent, NotificationWebhookUserAgent)
+	if t.conf.Authorization != "" {
+		req.Header.Set(Authorization, t.conf.Authorization)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationWebhookTimeout)
+	defer cancel()
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if resp != nil && resp.Body != nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if err != nil || resp.StatusCode/100 == 2 {
+		return err
+	}
+
+	return fmt.Errorf("%s returns '%s' statuscode", t.conf.Endpoint, resp.Status)
+}
+
+func (t *webhookTarget) Close() error {
+	return nil
+}
+
+type kafkaTarget struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+func newKafkaTarget(conf KafkaConfig) (*kafkaTarget, error) {
+	if err := conf.FixConfig(); err != nil {
+		return nil, err
+	}
+	producer, err := conf.BuildSyncProducer()
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaTarget{producer: producer, topic: conf.Topic}, nil
+}
+
+func (t *kafkaTarget) Send(key string, data []byte) error {
+	// events of the same object are sent to the same partition to keep them in order
+	_, _, err := t.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: t.topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(data),
+	})
+	return err
+}
+
+func (t *kafkaTarget) Close() error {
+	return t.producer.Close()
+}
+
+// NotificationTask is an event to be delivered to the target.
+type NotificationTask struct {
+	Target     string             `json:"target"`
+	Event      *NotificationEvent `json:"event"`
+	Retries    int                `json:"retries,omitempty"`
+	NextRetry  int64              `json:"nextRetry,omitempty"`
+	CreateTime int64              `json:"createTime"`
+}
+
+// ID returns a unique name of the task, the same event is only delivered once to each target.
+func (t *NotificationTask) ID() string {
+	var parts []string
+	for _, record := range t.Event.Records {
+		parts = append(parts, record.EventName, record.S3.ConfigurationID, record.S3.Bucket.Name,
+			record.S3.Object.Key, record.S3.Object.VersionID, record.S3.Object.Sequencer)
+	}
+	sum := md5.Sum([]byte(t.Target + "\n" + strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *NotificationTask) messageKey() string {
+	if len(t.Event.Records) == 0 {
+		return ""
+	}
+	return t.Event.Records[0].S3.Bucket.Name + pathSep + t.Event.Records[0].S3.Object.Key
+}
+
+// Notifier delivers the events of buckets to the targets subscribed by the notification configurations.
+// Events are persisted in the spool before delivery and removed after being acknowledged by the target,
+// so that each event is delivered at least once.
+type Notifier struct {
+	conf     NotificationServerConfig
+	spool    *taskSpool
+	targets  map[string]notificationTarget
+	queue    chan *NotificationTask
+	inflight sync.Map
+	stopC    chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewNotifier(conf NotificationServerConfig) (*Notifier, error) {
+	if err := conf.fixConfig(); err != nil {
+		return nil, err
+	}
+	spool, err := newTaskSpool("notificationSpool", conf.SpoolDir)
+	if err != nil {
+		return nil, err
+	}
+	n := &Notifier{
+		conf:    conf,
+		spool:   spool,
+		targets: make(map[string]notificationTarget),
+		queue:   make(chan *NotificationTask, conf.QueueSize),
+		stopC:   make(chan struct{}),
+	}
+	if err = n.buildTargets(); err != nil {
+		n.closeTargets()
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *Notifier) buildTargets() error {
+	for id, tc := range n.conf.Webhook {
+		if !tc.Enable {
+			continue
+		}
+		target, err := newWebhookTarget(tc.WebhookConfig)
+		if err != nil {
+			return fmt.Errorf("webhook target '%v': %v", id, err)
+		}
+		n.targets[NotificationTargetARN(NotificationTypeWebhook, id)] = target
+	}
+	for id, tc := range n.conf.Kafka {
+		if !tc.Enable {
+			continue
+		}
+		target, err := newKafkaTarget(tc.KafkaConfig)
+		if err != nil {
+			return fmt.Errorf("kafka target '%v': %v", id, err)
+		}
+		n.targets[NotificationTargetARN(NotificationTypeKafka, id)] = target
+	}
+	return nil
+}
+
+// HasTarget reports whether the target of the ARN is configured.
+func (n *Notifier) HasTarget(arn string) bool {
+	_, ok := n.targets[arn]
+	return ok
+}
+
+// Notify persists the event for each configuration of the bucket which subscribes it, and then queues
+// them for delivery. Events which fail to be persisted are lost, so the errors are returned to callers.
+func (n *Notifier) Notify(vol *Volume, info *NotificationEventInfo) error {
+	config, err := vol.metaLoader.loadNotification()
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, nc := range config.Match(info.Event, info.Key) {
+		task := &NotificationTask{
+			Target:     nc.TargetARN(),
+			Event:      newNotificationEvent(info, nc.ID),
+			CreateTime: now,
+		}
+		if err = n.spool.save(task); err != nil {
+			log.LogErrorf("Notifier: save task fail: target(%v) event(%v) bucket(%v) key(%v) err(%v)",
+				task.Target, info.Event, info.Bucket, info.Key, err)
+			return err
+		}
+		n.enqueue(task)
+	}
+	return nil
+}
+
+func (n *Notifier) Close() error {
+	n.stopOnce.Do(func() {
+		close(n.stopC)
+	})
+	n.wg.Wait()
+	n.closeTargets()
+	return nil
+}
+
+func (n *Notifier) closeTargets() {
+	for arn, target := range n.targets {
+		if err := target.Close(); err != nil {
+			log.LogWarnf("Notifier: close target fail: target(%v) err(%v)", arn, err)
+		}
+	}
+}
+
+// Start starts the delivery workers and reloads the events persisted in the spool.
+func (n *Notifier) Start() {
+	for i := 0; i < n.conf.Workers; i++ {
+		n.wg.Add(1)
+		go n.worker()
+	}
+	n.wg.Add(1)
+	go n.scheduleRetry()
+	n.rescan()
+}
+
+func (n *Notifier) enqueue(task *NotificationTask) {
+	id := task.ID()
+	if _, loaded := n.inflight.LoadOrStore(id, struct{}{}); loaded {
+		return
+	}
+	select {
+	case n.queue <- task:
+	default:
+		// the task is kept in the spool and will be picked up by the next rescan
+		n.inflight.Delete(id)
+		log.LogWarnf("Notifier: queue is full: target(%v) task(%v)", task.Target, id)
+	}
+}
+
+func (n *Notifier) rescan() {
+	tasks, err := n.spool.load(func() spoolTask { return &NotificationTask{} })
+	if err != nil {
+		log.LogErrorf("Notifier: load spool fail: dir(%v) err(%v)", n.conf.SpoolDir, err)
+		return
+	}
+	now := time.Now().Unix()
+	for _, task := range tasks {
+		if t := task.(*NotificationTask); t.Event != nil && t.NextRetry <= now {
+			n.enqueue(t)
+		}
+	}
+}
+
+func (n *Notifier) scheduleRetry() {
+	defer n.wg.Done()
+	ticker := time.NewTicker(time.Duration(n.conf.RetryIntervalSec) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stopC:
+			return
+		case <-ticker.C:
+			n.rescan()
+		}
+	}
+}
+
+func (n *Notifier) worker() {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.stopC:
+			return
+		case task := <-n.queue:
+			n.process(task)
+			n.inflight.Delete(task.ID())
+		}
+	}
+}
+
+func (n *Notifier) process(task *NotificationTask) {
+	target, ok := n.targets[task.Target]
+	if !ok {
+		// the target has been removed from the configuration of this server
+		log.LogWarnf("Notifier: drop task of unknown target: target(%v) task(%v)", task.Target, task.ID())
+		_ = n.spool.remove(task)
+		return
+	}
+	data, err := json.Marshal(task.Event)
+	if err == nil {
+		err = target.Send(task.messageKey(), data)
+	}
+	if err == nil {
+		if err = n.spool.remove(task); err != nil {
+			log.LogErrorf("Notifier: remove task fail: target(%v) task(%v) err(%v)", task.Target, task.ID(), err)
+		}
+		log.LogDebugf("Notifier: deliver success: target(%v) event(%s)", task.Target, data)
+		return
+	}
+
+	task.Retries++
+	log.LogWarnf("Notifier: deliver fail: target(%v) task(%v) retries(%v) err(%v)", task.Target, task.ID(), task.Retries, err)
+	if task.Retries >= n.conf.MaxRetry {
+		log.LogErrorf("Notifier: drop event after %v retries: target(%v) event(%s) err(%v)", task.Retries, task.Target, data, err)
+		_ = n.spool.remove(task)
+		return
+	}
+	task.NextRetry = time.Now().Add(n.retryBackoff(task.Retries)).Unix()
+	if err = n.spool.save(task); err != nil {
+		log.LogErrorf("Notifier: save task fail: target(%v) task(%v) err(%v)", task.Target, task.ID(), err)
+	}
+}
+
+func (n *Notifier) retryBackoff(retries int) time.Duration {
+	return retryBackoff(time.Duration(n.conf.RetryIntervalSec)*time.Second, maxNotificationRetryBackoff, retries)
+}
@@ -17,9 +17,12 @@ package objectnode
 import (
 	"encoding/xml"
 	"errors"
+	"net/http"
 	"strconv"
+	"syscall"
 	"time"
 
+	"github.com/cubefs/cubefs/proto"
 	"github.com/cubefs/cubefs/util/log"
 )
 
@@ -36,8 +39,12 @@ var (
 
 const (
 	ComplianceMode = "COMPLIANCE"
+	GovernanceMode = "GOVERNANCE"
 	Enabled        = "Enabled"
 
+	LegalHoldOn  = "ON"
+	LegalHoldOff = "OFF"
+
 	MaxObjectLockSize     = 1 << 12 // 16KB
 	maximumRetentionDays  = 70 * 365
 	maximumRetentionYears = 70
@@ -125,7 +132,7 @@ func ParseObjectLockConfigFromXML(data []byte) (*ObjectLockConfig, error) {
 // check valid of DefaultRetention
 func (d DefaultRetention) isValid() error {
 	switch d.Mode {
-	case ComplianceMode:
+	case ComplianceMode, GovernanceMode:
 	default:
 		return InvalidModeErr
 	}
@@ -175,28 +182,162 @@ func (r RetentionDate) MarshalXML(e *xml.Encoder, startElement xml.StartElement)
 	return e.EncodeElement(r.Format(ISO8601Layout), startElement)
 }
 
+// ParseObjectRetentionFromXML parses the retention of the object, the empty retention is used to
+// remove the retention in GOVERNANCE mode.
+func ParseObjectRetentionFromXML(data []byte) (*ObjectRetention, error) {
+	retention := ObjectRetention{}
+	if err := xml.Unmarshal(data, &retention); err != nil {
+		return nil, MalformedXML
+	}
+	if retention.Mode == "" && retention.RetainUntilDate.IsZero() {
+		return &retention, nil
+	}
+	if err := checkObjectRetention(retention.Mode, retention.RetainUntilDate.Time); err != nil {
+		return nil, err
+	}
+	return &retention, nil
+}
+
+func checkObjectRetention(mode string, retainUntilDate time.Time) error {
+	if mode != ComplianceMode && mode != GovernanceMode {
+		return InvalidRetentionMode
+	}
+	if retainUntilDate.IsZero() {
+		return MissingRetainUntilDate
+	}
+	if !retainUntilDate.After(time.Now()) {
+		return PastRetainUntilDate
+	}
+	return nil
+}
+
+type ObjectLegalHold struct {
+	XMLNS   string   `xml:"xmlns,attr,omitempty"`
+	XMLName xml.Name `xml:"LegalHold"`
+	Status  string   `xml:"Status"`
+}
+
+func ParseObjectLegalHoldFromXML(data []byte) (*ObjectLegalHold, error) {
+	legalHold := ObjectLegalHold{}
+	if err := xml.Unmarshal(data, &legalHold); err != nil {
+		return nil, MalformedXML
+	}
+	if legalHold.Status != LegalHoldOn && legalHold.Status != LegalHoldOff {
+		return nil, InvalidLegalHoldStatus
+	}
+	return &legalHold, nil
+}
+
+// parseObjectLockHeaders parses the retention and legal hold specified by the headers of the
+// request which writes the object. They are allowed only if the bucket has object lock enabled.
+func parseObjectLockHeaders(r *http.Request, objectLock *ObjectLockConfig) (retention *ObjectRetention, legalHold string, err error) {
+	mode := r.Header.Get(XAmzObjectLockMode)
+	date := r.Header.Get(XAmzObjectLockRetainUntilDate)
+	legalHold = r.Header.Get(XAmzObjectLockLegalHold)
+	if mode == "" && date == "" && legalHold == "" {
+		return
+	}
+	if objectLock == nil {
+		err = ObjectLockConfigurationMissing
+		return
+	}
+	if mode != "" || date != "" {
+		if mode == "" || date == "" {
+			err = InvalidObjectLockHeaders
+			return
+		}
+		var retainUntilDate time.Time
+		if retainUntilDate, err = time.Parse(time.RFC3339, date); err != nil {
+			err = InvalidRetainUntilDate
+			return
+		}
+		if err = checkObjectRetention(mode, retainUntilDate); err != nil {
+			return
+		}
+		retention = &ObjectRetention{Mode: mode, RetainUntilDate: RetentionDate{Time: retainUntilDate}}
+	}
+	if legalHold != "" && legalHold != LegalHoldOn && legalHold != LegalHoldOff {
+		err = InvalidLegalHoldStatus
+	}
+	return
+}
+
 func storeObjectLock(bytes []byte, vol *Volume) (err error) {
 	return vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSLock, bytes)
 }
 
-func isObjectLocked(v *Volume, inode uint64, name, path string) error {
-	xattrInfo, err := v.mw.XAttrGet_ll(inode, XAttrKeyOSSLock)
+// objectLockState is the retention and legal hold of an object version.
+type objectLockState struct {
+	mode            string
+	retainUntilDate time.Time
+	legalHold       bool
+}
+
+func parseObjectLockState(xattr *proto.XAttrInfo) (state objectLockState, err error) {
+	state.legalHold = string(xattr.Get(XAttrKeyOSSLegalHold)) == LegalHoldOn
+	if raw := string(xattr.Get(XAttrKeyOSSLock)); raw != "" {
+		var retainUntilDate int64
+		if retainUntilDate, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return
+		}
+		state.retainUntilDate = time.Unix(0, retainUntilDate).UTC()
+		// the retention is set in COMPLIANCE mode before GOVERNANCE mode is supported
+		if state.mode = string(xattr.Get(XAttrKeyOSSLockMode)); state.mode == "" {
+			state.mode = ComplianceMode
+		}
+	}
+	return
+}
+
+func (s objectLockState) hasRetention() bool {
+	return !s.retainUntilDate.IsZero()
+}
+
+// retained reports whether the retention of the object has not expired.
+func (s objectLockState) retained() bool {
+	return s.retainUntilDate.After(time.Now())
+}
+
+// checkDelete returns AccessDenied if the object is protected from being deleted or overwritten.
+func (s objectLockState) checkDelete(bypassGovernance bool) error {
+	if s.legalHold {
+		return AccessDenied
+	}
+	if s.retained() && !(s.mode == GovernanceMode && bypassGovernance) {
+		return AccessDenied
+	}
+	return nil
+}
+
+// checkRetentionUpdate checks whether the retention is allowed to be replaced by the new one. The retention
+// in COMPLIANCE mode can only be extended, and the retention in GOVERNANCE mode can be shortened, removed or
+// changed to other mode only if it is bypassed.
+func (s objectLockState) checkRetentionUpdate(retention *ObjectRetention, bypassGovernance bool) error {
+	if !s.retained() {
+		return nil
+	}
+	extended := retention.Mode == s.mode && !retention.RetainUntilDate.Before(s.retainUntilDate)
+	if extended || (s.mode == GovernanceMode && bypassGovernance) {
+		return nil
+	}
+	return AccessDenied
+}
+
+func isObjectLocked(v *Volume, inode uint64, name, path string, bypassGovernance bool) error {
+	xattrInfo, err := v.mw.XAttrGetAll_ll(inode)
 	if err != nil {
 		log.LogErrorf("isObjectLocked: check ObjectLock err(%v) volume(%v) path(%v) name(%v)",
 			err, v.name, path, name)
 		return err
 	}
-	retainUntilDate := xattrInfo.Get(XAttrKeyOSSLock)
-	if len(retainUntilDate) > 0 {
-		retainUntilDateInt64, err := strconv.ParseInt(string(retainUntilDate), 10, 64)
-		if err != nil {
-			return err
-		}
-		if retainUntilDateInt64 > time.Now().UnixNano() {
-			log.LogWarnf("isObjectLocked: object is locked, retainUntilDate(%v) volume(%v) path(%v) name(%v)",
-				retainUntilDateInt64, v.name, path, name)
-			return AccessDenied
-		}
+	state, err := parseObjectLockState(xattrInfo)
+	if err != nil {
+		return err
+	}
+	if err = state.checkDelete(bypassGovernance); err != nil {
+		log.LogWarnf("isObjectLocked: object is locked, mode(%v) retainUntilDate(%v) legalHold(%v) volume(%v) path(%v) name(%v)",
+			state.mode, state.retainUntilDate, state.legalHold, v.name, path, name)
+		return err
 	}
 	return nil
 }
@@ -205,3 +346,113 @@ func formatRetentionDateStr(modifyTime time.Time, retention *Retention) string {
 	retentionDateUnixNano := modifyTime.Add(retention.Duration).UnixNano()
 	return strconv.FormatInt(retentionDateUnixNano, 10)
 }
+
+// setObjectLockXAttrs sets the retention and legal hold of the new object. The retention specified
+// by the request takes precedence over the default retention of the bucket.
+func setObjectLockXAttrs(attrs map[string]string, modifyTime time.Time, opt *PutFileOption) {
+	if opt == nil || opt.ObjectLock == nil {
+		return
+	}
+	if opt.Retention != nil {
+		attrs[XAttrKeyOSSLock] = strconv.FormatInt(opt.Retention.RetainUntilDate.UnixNano(), 10)
+		attrs[XAttrKeyOSSLockMode] = opt.Retention.Mode
+	} else if retention := opt.ObjectLock.ToRetention(); retention != nil {
+		attrs[XAttrKeyOSSLock] = formatRetentionDateStr(modifyTime, retention)
+		attrs[XAttrKeyOSSLockMode] = retention.Mode
+	}
+	if opt.LegalHold == LegalHoldOn {
+		attrs[XAttrKeyOSSLegalHold] = LegalHoldOn
+	}
+}
+
+// isObjectLockXAttr reports whether the key is the retention or legal hold of the object,
+// which is not copied from the source object.
+func isObjectLockXAttr(key string) bool {
+	return key == XAttrKeyOSSLock || key == XAttrKeyOSSLockMode || key == XAttrKeyOSSLegalHold
+}
+
+// objectVersionLockState returns the inode and the lock state of the specified version of the object.
+func (v *Volume) objectVersionLockState(path, versionId string) (inode uint64, state objectLockState, err error) {
+	var info *FSFileInfo
+	if info, _, err = v.ObjectVersionMeta(path, versionId); err != nil {
+		return
+	}
+	if info.DeleteMarker {
+		err = MethodNotAllowed
+		return
+	}
+	if info.Mode.IsDir() {
+		err = syscall.ENOENT
+		return
+	}
+	// the xattrs in cache may be outdated, the lock state is always loaded from the meta node
+	var xattr *proto.XAttrInfo
+	if xattr, err = v.mw.XAttrGetAll_ll(info.Inode); err != nil {
+		log.LogErrorf("objectVersionLockState: get xattr fail: volume(%v) path(%v) versionId(%v) inode(%v) err(%v)",
+			v.name, path, versionId, info.Inode, err)
+		return
+	}
+	if state, err = parseObjectLockState(xattr); err != nil {
+		log.LogErrorf("objectVersionLockState: parse lock state fail: volume(%v) path(%v) versionId(%v) inode(%v) err(%v)",
+			v.name, path, versionId, info.Inode, err)
+		return
+	}
+	return info.Inode, state, nil
+}
+
+// PutObjectRetention sets the retention of the specified version of the object,
+// and the retention is removed if the mode is empty.
+func (v *Volume) PutObjectRetention(path, versionId string, retention *ObjectRetention, bypassGovernance bool) (err error) {
+	defer func() {
+		log.LogInfof("Audit: PutObjectRetention: volume(%v) path(%v) versionId(%v) mode(%v) retainUntilDate(%v) bypassGovernance(%v) err(%v)",
+			v.name, path, versionId, retention.Mode, retention.RetainUntilDate, bypassGovernance, err)
+	}()
+	inode, state, err := v.objectVersionLockState(path, versionId)
+	if err != nil {
+		return
+	}
+	if err = state.checkRetentionUpdate(retention, bypassGovernance); err != nil {
+		return
+	}
+	if retention.Mode == "" {
+		for _, key := range []string{XAttrKeyOSSLock, XAttrKeyOSSLockMode} {
+			if err = v.mw.XAttrDel_ll(inode, key); err != nil {
+				log.LogErrorf("PutObjectRetention: delete xattr fail: volume(%v) path(%v) inode(%v) key(%v) err(%v)",
+					v.name, path, inode, key, err)
+				return
+			}
+		}
+		deleteAttrCache(inode, v.name)
+		return
+	}
+	attrs := map[string]string{
+		XAttrKeyOSSLock:     strconv.FormatInt(retention.RetainUntilDate.UnixNano(), 10),
+		XAttrKeyOSSLockMode: retention.Mode,
+	}
+	return v.storeObjectLockXAttrs(path, inode, attrs)
+}
+
+// PutObjectLegalHold turns on or off the legal hold of the specified version of the object.
+func (v *Volume) PutObjectLegalHold(path, versionId, status string) (err error) {
+	defer func() {
+		log.LogInfof("Audit: PutObjectLegalHold: volume(%v) path(%v) versionId(%v) status(%v) err(%v)",
+			v.name, path, versionId, status, err)
+	}()
+	inode, _, err := v.objectVersionLockState(path, versionId)
+	if err != nil {
+		return
+	}
+	return v.storeObjectLockXAttrs(path, inode, map[string]string{XAttrKeyOSSLegalHold: status})
+}
+
+func (v *Volume) storeObjectLockXAttrs(path string, inode uint64, attrs map[string]string) (err error) {
+	if err = v.mw.BatchSetXAttr_ll(inode, attrs); err != nil {
+		log.LogErrorf("storeObjectLockXAttrs: set xattr fail: volume(%v) path(%v) inode(%v) attrs(%v) err(%v)",
+			v.name, path, inode, attrs, err)
+		return
+	}
+	if objMetaCache != nil {
+		objMetaCache.MergeAttr(v.name, &AttrItem{XAttrInfo: proto.XAttrInfo{Inode: inode, XAttrs: attrs}})
+	}
+	return
+}
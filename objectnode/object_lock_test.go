@@ -16,10 +16,12 @@ package objectnode
 
 import (
 	"encoding/xml"
+	"net/http"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/cubefs/cubefs/proto"
 	"github.com/stretchr/testify/require"
 )
 
@@ -217,3 +219,132 @@ func TestMarshalXML(t *testing.T) {
 	_, err := xml.Marshal(objectRetention)
 	require.NoError(t, err)
 }
+
+func TestParseObjectRetention(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(ISO8601Layout)
+	past := time.Now().Add(-time.Hour).UTC().Format(ISO8601Layout)
+	tests := []struct {
+		value       string
+		expectedErr error
+	}{
+		{value: `<Retention><Mode>GOVERNANCE</Mode><RetainUntilDate>` + future + `</RetainUntilDate></Retention>`},
+		{value: `<Retention><Mode>COMPLIANCE</Mode><RetainUntilDate>` + future + `</RetainUntilDate></Retention>`},
+		{value: `<Retention></Retention>`},
+		{value: `<Retention><Mode>governance</Mode><RetainUntilDate>` + future + `</RetainUntilDate></Retention>`, expectedErr: InvalidRetentionMode},
+		{value: `<Retention><Mode>GOVERNANCE</Mode></Retention>`, expectedErr: MissingRetainUntilDate},
+		{value: `<Retention><Mode>COMPLIANCE</Mode><RetainUntilDate>` + past + `</RetainUntilDate></Retention>`, expectedErr: PastRetainUntilDate},
+		{value: `<Retention><Mode>COMPLIANCE</Mode><RetainUntilDate>tomorrow</RetainUntilDate></Retention>`, expectedErr: MalformedXML},
+	}
+	for i, tt := range tests {
+		_, err := ParseObjectRetentionFromXML([]byte(tt.value))
+		require.Equal(t, tt.expectedErr, err, "case %v", i)
+	}
+
+	_, err := ParseObjectLegalHoldFromXML([]byte(`<LegalHold><Status>ON</Status></LegalHold>`))
+	require.NoError(t, err)
+	_, err = ParseObjectLegalHoldFromXML([]byte(`<LegalHold><Status>on</Status></LegalHold>`))
+	require.Equal(t, InvalidLegalHoldStatus, err)
+}
+
+func TestParseObjectLockHeaders(t *testing.T) {
+	objectLock := &ObjectLockConfig{ObjectLockEnabled: Enabled}
+	future := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	request := func(headers map[string]string) *http.Request {
+		r, _ := http.NewRequest(http.MethodPut, "/bucket/key", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		return r
+	}
+
+	retention, legalHold, err := parseObjectLockHeaders(request(map[string]string{
+		XAmzObjectLockMode:            GovernanceMode,
+		XAmzObjectLockRetainUntilDate: future.Format(time.RFC3339),
+		XAmzObjectLockLegalHold:       LegalHoldOn,
+	}), objectLock)
+	require.NoError(t, err)
+	require.Equal(t, GovernanceMode, retention.Mode)
+	require.True(t, future.Equal(retention.RetainUntilDate.Time))
+	require.Equal(t, LegalHoldOn, legalHold)
+
+	retention, legalHold, err = parseObjectLockHeaders(request(nil), nil)
+	require.NoError(t, err)
+	require.Nil(t, retention)
+	require.Empty(t, legalHold)
+
+	tests := []struct {
+		headers     map[string]string
+		objectLock  *ObjectLockConfig
+		expectedErr error
+	}{
+		{headers: map[string]string{XAmzObjectLockLegalHold: LegalHoldOn}, expectedErr: ObjectLockConfigurationMissing},
+		{headers: map[string]string{XAmzObjectLockMode: ComplianceMode}, objectLock: objectLock, expectedErr: InvalidObjectLockHeaders},
+		{headers: map[string]string{XAmzObjectLockMode: ComplianceMode, XAmzObjectLockRetainUntilDate: "2030"}, objectLock: objectLock, expectedErr: InvalidRetainUntilDate},
+		{headers: map[string]string{XAmzObjectLockLegalHold: "YES"}, objectLock: objectLock, expectedErr: InvalidLegalHoldStatus},
+	}
+	for i, tt := range tests {
+		_, _, err = parseObjectLockHeaders(request(tt.headers), tt.objectLock)
+		require.Equal(t, tt.expectedErr, err, "case %v", i)
+	}
+}
+
+func TestObjectLockState(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	xattr := func(attrs map[string]string) *proto.XAttrInfo {
+		return &proto.XAttrInfo{XAttrs: attrs}
+	}
+	retainUntil := strconv.FormatInt(future.UnixNano(), 10)
+
+	// the retention without mode is set before GOVERNANCE mode is supported
+	state, err := parseObjectLockState(xattr(map[string]string{XAttrKeyOSSLock: retainUntil}))
+	require.NoError(t, err)
+	require.Equal(t, ComplianceMode, state.mode)
+	require.Equal(t, AccessDenied, state.checkDelete(true))
+
+	state, err = parseObjectLockState(xattr(map[string]string{XAttrKeyOSSLock: retainUntil, XAttrKeyOSSLockMode: GovernanceMode}))
+	require.NoError(t, err)
+	require.Equal(t, AccessDenied, state.checkDelete(false))
+	require.NoError(t, state.checkDelete(true))
+
+	state, err = parseObjectLockState(xattr(map[string]string{XAttrKeyOSSLegalHold: LegalHoldOn}))
+	require.NoError(t, err)
+	require.False(t, state.hasRetention())
+	require.Equal(t, AccessDenied, state.checkDelete(true))
+
+	state, err = parseObjectLockState(xattr(map[string]string{XAttrKeyOSSLock: strconv.FormatInt(time.Now().Add(-time.Hour).UnixNano(), 10)}))
+	require.NoError(t, err)
+	require.NoError(t, state.checkDelete(false))
+
+	_, err = parseObjectLockState(xattr(map[string]string{XAttrKeyOSSLock: "invalid"}))
+	require.Error(t, err)
+}
+
+func TestCheckRetentionUpdate(t *testing.T) {
+	now := time.Now()
+	compliance := objectLockState{mode: ComplianceMode, retainUntilDate: now.Add(time.Hour)}
+	governance := objectLockState{mode: GovernanceMode, retainUntilDate: now.Add(time.Hour)}
+	retention := func(mode string, d time.Duration) *ObjectRetention {
+		return &ObjectRetention{Mode: mode, RetainUntilDate: RetentionDate{Time: now.Add(d)}}
+	}
+	tests := []struct {
+		state            objectLockState
+		retention        *ObjectRetention
+		bypassGovernance bool
+		expectedErr      error
+	}{
+		{state: objectLockState{}, retention: retention(ComplianceMode, time.Minute)},
+		{state: compliance, retention: retention(ComplianceMode, 2*time.Hour)},
+		{state: compliance, retention: retention(ComplianceMode, time.Minute), bypassGovernance: true, expectedErr: AccessDenied},
+		{state: compliance, retention: retention(GovernanceMode, 2*time.Hour), bypassGovernance: true, expectedErr: AccessDenied},
+		{state: compliance, retention: &ObjectRetention{}, bypassGovernance: true, expectedErr: AccessDenied},
+		{state: governance, retention: retention(GovernanceMode, 2*time.Hour)},
+		{state: governance, retention: retention(ComplianceMode, 2*time.Hour), expectedErr: AccessDenied},
+		{state: governance, retention: retention(GovernanceMode, time.Minute), expectedErr: AccessDenied},
+		{state: governance, retention: retention(GovernanceMode, time.Minute), bypassGovernance: true},
+		{state: governance, retention: &ObjectRetention{}, bypassGovernance: true},
+	}
+	for i, tt := range tests {
+		err := tt.state.checkRetentionUpdate(tt.retention, tt.bypassGovernance)
+		require.Equal(t, tt.expectedErr, err, "case %v", i)
+	}
+}
@@ -286,6 +286,11 @@ func (o *ObjectNode) policyCheck(f http.HandlerFunc) http.HandlerFunc {
 					return
 				}
 				err = nil
+				if acl, _, err = applyPublicAccessBlock(vol, acl, nil); err != nil {
+					log.LogErrorf("acl check: load public access block fail: requestID(%v) volume(%v) err(%v)",
+						GetRequestID(r), param.Bucket(), err)
+					return
+				}
 			}
 			if acl == nil && !isOwner {
 				allowed = false
@@ -322,6 +327,7 @@ func (o *ObjectNode) loadBucketMeta(bucket string) (vol *Volume, acl *AccessCont
 	if policy, err = vol.metaLoader.loadPolicy(); err != nil {
 		return
 	}
+	acl, policy, err = applyPublicAccessBlock(vol, acl, policy)
 	return
 }
 
@@ -369,6 +375,11 @@ func (o *ObjectNode) allowedBySrcBucketPolicy(param *RequestParam, reqUid string
 		return
 	}
 	err = nil
+	if acl, _, err = applyPublicAccessBlock(vol, acl, nil); err != nil {
+		log.LogErrorf("srcBucket acl check: load public access block fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(paramCopy.r), srcBucketId, err)
+		return
+	}
 	if acl == nil && !isOwner {
 		log.LogWarnf("srcBucket acl check: empty acl disallows: requestID(%v) reqUid(%v) ownerUid(%v) volume(%v) action(%v)",
 			GetRequestID(paramCopy.r), reqUid, vol.owner, srcBucketId, paramCopy.Action())
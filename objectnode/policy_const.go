@@ -40,7 +40,7 @@ const (
 
 // if more s3 api is supported by policy, need extend bucketApiList, objectApiList
 var bucketApiList = SliceString{LIST_OBJECTS, LIST_OBJECTS_V2, HEAD_BUCKET, DELETE_BUCKET, LIST_MULTIPART_UPLOADS, GET_BUCKET_LOCATION, GET_OBJECT_LOCK_CFG, PUT_OBJECT_LOCK_CFG}
-var objectApiList = SliceString{GET_OBJECT, HEAD_OBJECT, DELETE_OBJECT, PUT_OBJECT, POST_OBJECT, INITIALE_MULTIPART_UPLOAD, UPLOAD_PART, UPLOAD_PART_COPY, COMPLETE_MULTIPART_UPLOAD, COPY_OBJECT, ABORT_MULTIPART_UPLOAD, LIST_PARTS, BATCH_DELETE, GET_OBJECT_RETENTION}
+var objectApiList = SliceString{GET_OBJECT, HEAD_OBJECT, SELECT_OBJECT_CONTENT, DELETE_OBJECT, PUT_OBJECT, POST_OBJECT, INITIALE_MULTIPART_UPLOAD, UPLOAD_PART, UPLOAD_PART_COPY, COMPLETE_MULTIPART_UPLOAD, COPY_OBJECT, ABORT_MULTIPART_UPLOAD, LIST_PARTS, BATCH_DELETE, GET_OBJECT_RETENTION, PUT_OBJECT_RETENTION, GET_OBJECT_LEGAL_HOLD, PUT_OBJECT_LEGAL_HOLD, BYPASS_GOVERNANCE}
 
 type SliceString []string
 
@@ -53,6 +53,10 @@ const (
 	ACTION_ABORT_MULTIPART_UPLOAD      = "abortmultipartupload"
 	ACTION_LIST_MULTIPART_UPLOAD_PARTS = "listmultipartuploadparts"
 	ACTION_GET_OBJECT_RETENTION        = "getobjectretention"
+	ACTION_PUT_OBJECT_RETENTION        = "putobjectretention"
+	ACTION_GET_OBJECT_LEGAL_HOLD       = "getobjectlegalhold"
+	ACTION_PUT_OBJECT_LEGAL_HOLD       = "putobjectlegalhold"
+	ACTION_BYPASS_GOVERNANCE           = "bypassgovernanceretention"
 
 	// bucket level
 	ACTION_LIST_BUCKET                   = "listbucket"
@@ -69,7 +73,7 @@ const (
 // action => api list, this should be consistent with bucketApiList&&objectApiList
 var S3ActionToApis = map[string]SliceString{
 	ACTION_PUT_OBJECT:                    {PUT_OBJECT, POST_OBJECT, COPY_OBJECT, INITIALE_MULTIPART_UPLOAD, UPLOAD_PART, UPLOAD_PART_COPY, COMPLETE_MULTIPART_UPLOAD},
-	ACTION_GET_OBJECT:                    {GET_OBJECT, HEAD_OBJECT},
+	ACTION_GET_OBJECT:                    {GET_OBJECT, HEAD_OBJECT, SELECT_OBJECT_CONTENT},
 	ACTION_DELETE_OBJECT:                 {DELETE_OBJECT, BATCH_DELETE},
 	ACTION_ABORT_MULTIPART_UPLOAD:        {ABORT_MULTIPART_UPLOAD},
 	ACTION_LIST_BUCKET:                   {LIST_OBJECTS, LIST_OBJECTS_V2, HEAD_BUCKET},
@@ -80,6 +84,10 @@ var S3ActionToApis = map[string]SliceString{
 	ACTION_GET_OBJECT_LOCK_CFG:           {GET_OBJECT_LOCK_CFG},
 	ACTION_PUT_OBJECT_LOCK_CFG:           {PUT_OBJECT_LOCK_CFG},
 	ACTION_GET_OBJECT_RETENTION:          {GET_OBJECT_RETENTION},
+	ACTION_PUT_OBJECT_RETENTION:          {PUT_OBJECT_RETENTION},
+	ACTION_GET_OBJECT_LEGAL_HOLD:         {GET_OBJECT_LEGAL_HOLD},
+	ACTION_PUT_OBJECT_LEGAL_HOLD:         {PUT_OBJECT_LEGAL_HOLD},
+	ACTION_BYPASS_GOVERNANCE:             {BYPASS_GOVERNANCE},
 }
 
 var allowAnonymousActions = SliceString{ACTION_GET_OBJECT}
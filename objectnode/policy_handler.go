@@ -65,6 +65,50 @@ func (o *ObjectNode) getBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 	return
 }
 
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketPolicyStatus.html
+func (o *ObjectNode) getBucketPolicyStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		ec  *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getBucketPolicyStatusHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	var policy *Policy
+	if policy, err = vol.metaLoader.loadPolicy(); err != nil {
+		log.LogErrorf("getBucketPolicyStatusHandler: load volume policy fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if policy == nil {
+		ec = NoSuchBucketPolicy
+		return
+	}
+
+	status := &PolicyStatus{XMLNS: XMLNS, IsPublic: policy.IsPublic()}
+	var data []byte
+	if data, err = MarshalXMLEntity(status); err != nil {
+		log.LogErrorf("getBucketPolicyStatusHandler: xml marshal fail: requestID(%v) volume(%v) status(%+v) err(%v)",
+			GetRequestID(r), vol.Name(), status, err)
+		return
+	}
+
+	writeSuccessResponseXML(w, data)
+	return
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketPolicy.html
 func (o *ObjectNode) putBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
 	var (
@@ -113,6 +157,18 @@ func (o *ObjectNode) putBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 			GetRequestID(r), policy, vol.name, err)
 		return
 	}
+	var blocked bool
+	if blocked, err = isPublicPolicyBlocked(vol, policy); err != nil {
+		log.LogErrorf("putBucketPolicyHandler: load public access block fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.name, err)
+		return
+	}
+	if blocked {
+		log.LogWarnf("putBucketPolicyHandler: public policy is blocked: requestID(%v) volume(%v) policy(%v)",
+			GetRequestID(r), vol.name, string(policyRaw))
+		ec = PublicPolicyBlocked
+		return
+	}
 	if err = storeBucketPolicy(vol, policyRaw); err != nil {
 		log.LogErrorf("putBucketPolicyHandler: store policy fail: requestID(%v) err(%v)", GetRequestID(r), err)
 		return
@@ -157,6 +157,18 @@ func TestActionMatch_Multiaction(t *testing.T) {
 	require.False(t, result)
 }
 
+func TestActionMatch_BypassGovernance(t *testing.T) {
+	s := &Statement{}
+	err := json.Unmarshal([]byte(`["s3:PutObject","s3:DeleteObject","s3:PutObjectRetention"]`), &s.Action)
+	require.NoError(t, err)
+	require.False(t, s.matchAction(BYPASS_GOVERNANCE))
+
+	err = json.Unmarshal([]byte(`"s3:BypassGovernanceRetention"`), &s.Action)
+	require.NoError(t, err)
+	require.True(t, s.matchAction(BYPASS_GOVERNANCE))
+	require.False(t, s.matchAction(DELETE_OBJECT))
+}
+
 func TestIpMatch_Whitelist(t *testing.T) {
 	ipcondition := `{"IpAddress": {"aws:SourceIp": ["1.2.3.4/24", "1.1.1.1","fe80::45e:9d4c:20ca:20f7/64"] }}`
 	s := &Statement{}
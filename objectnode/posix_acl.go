@@ -0,0 +1,133 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// PosixIdentity is the POSIX user which the S3 user acts as on the volumes with POSIX ACL enabled.
+type PosixIdentity struct {
+	Uid  uint32   `json:"uid"`
+	Gids []uint32 `json:"gids"`
+}
+
+// posixPermissionOfAction returns the permissions wanted by the action, which are checked
+// on the object itself or on the directory the object is created in or deleted from.
+func posixPermissionOfAction(action proto.Action) (want uint16, onParent, ok bool) {
+	switch action {
+	case proto.OSSGetObjectAction, proto.OSSHeadObjectAction:
+		return proto.ACLRead, false, true
+	case proto.OSSPutObjectAction, proto.OSSCopyObjectAction, proto.OSSDeleteObjectAction,
+		proto.OSSCompleteMultipartUploadAction:
+		return proto.ACLWrite | proto.ACLExecute, true, true
+	default:
+		return 0, false, false
+	}
+}
+
+// checkPosixPermission checks the wanted permissions of the identity on the object, or on
+// the nearest existing directory of the object if onParent. Missing objects are left to the
+// handlers to report.
+func (v *Volume) checkPosixPermission(path string, id *PosixIdentity, want uint16, onParent bool) (err error) {
+	dirs, filename := splitPath(strings.TrimPrefix(path, pathSep))
+	if !onParent {
+		dirs = append(dirs, filename)
+	}
+	ino := volumeRootInode
+	for i, name := range dirs {
+		if name == "" {
+			continue
+		}
+		var child uint64
+		var mode uint32
+		if child, mode, err = v.mw.Lookup_ll(ino, name); err == syscall.ENOENT {
+			if !onParent {
+				return nil
+			}
+			break
+		}
+		if err != nil {
+			return
+		}
+		if i < len(dirs)-1 && !os.FileMode(mode).IsDir() {
+			if !onParent {
+				return nil
+			}
+			break
+		}
+		ino = child
+	}
+	info, err := v.mw.InodeGet_ll(ino)
+	if err != nil {
+		return
+	}
+	return v.mw.CheckPermission_ll(info, id.Uid, id.Gids, want)
+}
+
+// posixACLCheckMiddleware checks the object operations of the S3 users configured with POSIX
+// identities by the ACLs and permission bits, the same way as the POSIX clients of the volume.
+func (o *ObjectNode) posixACLCheckMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if len(o.posixIdentities) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			param := ParseRequestParam(r)
+			want, onParent, ok := posixPermissionOfAction(param.Action())
+			if !ok || param.Bucket() == "" || param.Object() == "" || isAnonymous(param.AccessKey()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			userInfo, err := o.getUserInfoByAccessKey(param.AccessKey())
+			if err != nil {
+				o.errorResponse(w, r, err, nil)
+				return
+			}
+			id, ok := o.posixIdentities[userInfo.UserID]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			vol, err := o.getVol(param.Bucket())
+			if err != nil {
+				o.errorResponse(w, r, err, nil)
+				return
+			}
+			if !vol.mw.PosixACLEnabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err = vol.checkPosixPermission(param.Object(), id, want, onParent); err != nil {
+				log.LogWarnf("posixACLCheckMiddleware: permission check fail: requestID(%v) volume(%v) path(%v) "+
+					"userID(%v) uid(%v) want(%v) err(%v)", GetRequestID(r), param.Bucket(), param.Object(),
+					userInfo.UserID, id.Uid, want, err)
+				if err == syscall.EACCES {
+					o.errorResponse(w, r, nil, AccessDenied)
+				} else {
+					o.errorResponse(w, r, err, nil)
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+}
@@ -0,0 +1,46 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPosixPermissionOfAction(t *testing.T) {
+	want, onParent, ok := posixPermissionOfAction(proto.OSSGetObjectAction)
+	require.True(t, ok)
+	require.False(t, onParent)
+	require.Equal(t, proto.ACLRead, want)
+
+	want, onParent, ok = posixPermissionOfAction(proto.OSSDeleteObjectAction)
+	require.True(t, ok)
+	require.True(t, onParent)
+	require.Equal(t, proto.ACLWrite|proto.ACLExecute, want)
+
+	_, _, ok = posixPermissionOfAction(proto.OSSListObjectsAction)
+	require.False(t, ok)
+}
+
+func TestParsePosixIdentities(t *testing.T) {
+	var identities map[string]*PosixIdentity
+	raw := map[string]interface{}{"user1": map[string]interface{}{"uid": 1001, "gids": []int{1001, 2000}}}
+	require.NoError(t, ParseJSONEntity(raw, &identities))
+	require.EqualValues(t, 1001, identities["user1"].Uid)
+	require.Equal(t, []uint32{1001, 2000}, identities["user1"].Gids)
+}
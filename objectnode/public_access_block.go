@@ -0,0 +1,204 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/access-control-block-public-access.html
+
+import (
+	"encoding/xml"
+	"net/http"
+)
+
+const (
+	MaxPublicAccessBlockSize = 1 << 10 // 1KB
+)
+
+var (
+	NoSuchPublicAccessBlockConfiguration = &ErrorCode{ErrorCode: "NoSuchPublicAccessBlockConfiguration", ErrorMessage: "The public access block configuration was not found.", StatusCode: http.StatusNotFound}
+	PublicAclBlocked                     = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "Public ACLs are blocked by the BlockPublicAcls setting of the bucket.", StatusCode: http.StatusForbidden}
+	PublicPolicyBlocked                  = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "Public policies are blocked by the BlockPublicPolicy setting of the bucket.", StatusCode: http.StatusForbidden}
+)
+
+type PublicAccessBlockConfiguration struct {
+	XMLName               xml.Name `xml:"PublicAccessBlockConfiguration" json:"-"`
+	XMLNS                 string   `xml:"xmlns,attr,omitempty" json:"-"`
+	BlockPublicAcls       bool     `xml:"BlockPublicAcls" json:"block_public_acls"`
+	IgnorePublicAcls      bool     `xml:"IgnorePublicAcls" json:"ignore_public_acls"`
+	BlockPublicPolicy     bool     `xml:"BlockPublicPolicy" json:"block_public_policy"`
+	RestrictPublicBuckets bool     `xml:"RestrictPublicBuckets" json:"restrict_public_buckets"`
+}
+
+type PolicyStatus struct {
+	XMLName  xml.Name `xml:"PolicyStatus"`
+	XMLNS    string   `xml:"xmlns,attr,omitempty"`
+	IsPublic bool     `xml:"IsPublic"`
+}
+
+func ParsePublicAccessBlockConfig(data []byte) (*PublicAccessBlockConfiguration, *ErrorCode) {
+	config := &PublicAccessBlockConfiguration{}
+	if err := xml.Unmarshal(data, config); err != nil {
+		return nil, MalformedXML
+	}
+	return config, nil
+}
+
+func storeBucketPublicAccessBlock(bytes []byte, vol *Volume) (err error) {
+	return vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSPublicAccessBlock, bytes)
+}
+
+func deleteBucketPublicAccessBlock(vol *Volume) (err error) {
+	return vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSPublicAccessBlock)
+}
+
+// isPublicAclBlocked reports whether the acl to be set is rejected by the BlockPublicAcls setting of the bucket.
+func isPublicAclBlocked(vol *Volume, acl *AccessControlPolicy) (bool, error) {
+	if acl == nil || !acl.IsPublic() {
+		return false, nil
+	}
+	config, err := vol.metaLoader.loadPublicAccessBlock()
+	if err != nil {
+		return false, err
+	}
+	return config != nil && config.BlockPublicAcls, nil
+}
+
+// isPublicPolicyBlocked reports whether the policy to be set is rejected by the BlockPublicPolicy setting of the bucket.
+func isPublicPolicyBlocked(vol *Volume, policy *Policy) (bool, error) {
+	if policy == nil || !policy.IsPublic() {
+		return false, nil
+	}
+	config, err := vol.metaLoader.loadPublicAccessBlock()
+	if err != nil {
+		return false, err
+	}
+	return config != nil && config.BlockPublicPolicy, nil
+}
+
+// applyPublicAccessBlock returns the acl and policy which take effect on the access check, the public
+// grants and statements are dropped under the IgnorePublicAcls and RestrictPublicBuckets settings.
+func applyPublicAccessBlock(vol *Volume, acl *AccessControlPolicy, policy *Policy) (*AccessControlPolicy, *Policy, error) {
+	config, err := vol.metaLoader.loadPublicAccessBlock()
+	if err != nil || config == nil {
+		return acl, policy, err
+	}
+	if config.IgnorePublicAcls && acl != nil {
+		acl = acl.WithoutPublicGrants()
+	}
+	if config.RestrictPublicBuckets && policy != nil {
+		policy = policy.WithoutPublicStatements()
+	}
+	return acl, policy, nil
+}
+
+// IsPublic reports whether the acl grants any permission to the AllUsers or AuthenticatedUsers group.
+func (acp *AccessControlPolicy) IsPublic() bool {
+	for _, g := range acp.Acl.Grants {
+		if g.isPublic() {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutPublicGrants returns a copy of the acl with the public grants removed, the acl is returned
+// as is if there is no public grant.
+func (acp *AccessControlPolicy) WithoutPublicGrants() *AccessControlPolicy {
+	if !acp.IsPublic() {
+		return acp
+	}
+	filtered := &AccessControlPolicy{Xmlns: acp.Xmlns, Owner: acp.Owner}
+	for _, g := range acp.Acl.Grants {
+		if !g.isPublic() {
+			filtered.Acl.Grants = append(filtered.Acl.Grants, g)
+		}
+	}
+	return filtered
+}
+
+func (g *Grant) isPublic() bool {
+	return g.Grantee.Type == TypeGroup && (g.Grantee.URI == GroupAllUser || g.Grantee.URI == GroupAuthenticated)
+}
+
+// IsPublic reports whether the policy grants access to everyone, that is any statement allows
+// the wildcard principal without a condition restricting the source to fixed values.
+func (p *Policy) IsPublic() bool {
+	for _, s := range p.Statements {
+		if s.isPublic() {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutPublicStatements returns a copy of the policy with the public statements removed, the
+// policy is returned as is if there is no public statement.
+func (p *Policy) WithoutPublicStatements() *Policy {
+	if !p.IsPublic() {
+		return p
+	}
+	filtered := &Policy{Version: p.Version, Id: p.Id}
+	for _, s := range p.Statements {
+		if !s.isPublic() {
+			filtered.Statements = append(filtered.Statements, s)
+		}
+	}
+	return filtered
+}
+
+func (s *Statement) isPublic() bool {
+	if s.effect() != POLICY_ALLOW || !s.hasAnyPrincipal() {
+		return false
+	}
+	// the referer and host can be forged by the client, only the source ip restricts the access
+	for _, op := range s.Condition {
+		if ipOp, ok := op.(*ipAddressOp); ok && !ipOp.matchAnyIP() {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAnyPrincipal reports whether the principal of statement contains the wildcard,
+// "Principal":"*" or "Principal":{"AWS":"*"} or "Principal":{"AWS":["*"]}.
+func (s *Statement) hasAnyPrincipal() bool {
+	switch p := s.Principal.(type) {
+	case string:
+		return PrincipalElementType(p) == Principal_Any
+	case map[string]interface{}:
+		switch p1 := p[S3_PRINCIPAL_PREFIX].(type) {
+		case string:
+			return PrincipalElementType(p1) == Principal_Any
+		case []interface{}:
+			for _, p2 := range p1 {
+				if p3, ok := p2.(string); ok && PrincipalElementType(p3) == Principal_Any {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchAnyIP reports whether any network of the operation contains all the addresses, like "0.0.0.0/0".
+func (op *ipAddressOp) matchAnyIP() bool {
+	for _, infos := range op.m {
+		for _, info := range infos {
+			if ones, _ := info.Net.Mask.Size(); ones == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
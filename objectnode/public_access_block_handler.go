@@ -0,0 +1,160 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// Put public access block
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutPublicAccessBlock.html
+func (o *ObjectNode) putPublicAccessBlockHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putPublicAccessBlockHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(r.Body, MaxPublicAccessBlockSize+1)); err != nil {
+		log.LogErrorf("putPublicAccessBlockHandler: read request body fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if len(body) > MaxPublicAccessBlockSize {
+		errorCode = EntityTooLarge
+		return
+	}
+	var config *PublicAccessBlockConfiguration
+	if config, errorCode = ParsePublicAccessBlockConfig(body); errorCode != nil {
+		log.LogErrorf("putPublicAccessBlockHandler: parse public access block fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), errorCode)
+		return
+	}
+	if body, err = json.Marshal(config); err != nil {
+		log.LogErrorf("putPublicAccessBlockHandler: json marshal public access block fail: requestID(%v) volume(%v) config(%+v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+	if err = storeBucketPublicAccessBlock(body, vol); err != nil {
+		log.LogErrorf("putPublicAccessBlockHandler: store public access block fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), err)
+		return
+	}
+	vol.metaLoader.storePublicAccessBlock(config)
+
+	log.LogInfof("Audit: put public access block: requestID(%v) volume(%v) config(%v)",
+		GetRequestID(r), vol.Name(), string(body))
+	return
+}
+
+// Get public access block
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetPublicAccessBlock.html
+func (o *ObjectNode) getPublicAccessBlockHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getPublicAccessBlockHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var config *PublicAccessBlockConfiguration
+	if config, err = vol.metaLoader.loadPublicAccessBlock(); err != nil {
+		log.LogErrorf("getPublicAccessBlockHandler: load public access block fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if config == nil {
+		errorCode = NoSuchPublicAccessBlockConfiguration
+		return
+	}
+	output := *config
+	output.XMLNS = XMLNS
+
+	var data []byte
+	if data, err = MarshalXMLEntity(&output); err != nil {
+		log.LogErrorf("getPublicAccessBlockHandler: xml marshal fail: requestID(%v) volume(%v) config(%+v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+
+	writeSuccessResponseXML(w, data)
+	return
+}
+
+// Delete public access block
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeletePublicAccessBlock.html
+func (o *ObjectNode) deletePublicAccessBlockHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("deletePublicAccessBlockHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	if err = deleteBucketPublicAccessBlock(vol); err != nil {
+		log.LogErrorf("deletePublicAccessBlockHandler: delete public access block fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	vol.metaLoader.storePublicAccessBlock(nil)
+
+	log.LogInfof("Audit: delete public access block: requestID(%v) volume(%v)", GetRequestID(r), vol.Name())
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
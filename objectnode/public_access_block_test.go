@@ -0,0 +1,101 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePublicAccessBlockConfig(t *testing.T) {
+	config, errCode := ParsePublicAccessBlockConfig([]byte(`<PublicAccessBlockConfiguration>
+			<BlockPublicAcls>true</BlockPublicAcls>
+			<RestrictPublicBuckets>true</RestrictPublicBuckets>
+		</PublicAccessBlockConfiguration>`))
+	require.Nil(t, errCode)
+	require.True(t, config.BlockPublicAcls)
+	require.False(t, config.IgnorePublicAcls)
+	require.False(t, config.BlockPublicPolicy)
+	require.True(t, config.RestrictPublicBuckets)
+
+	_, errCode = ParsePublicAccessBlockConfig([]byte(`<PublicAccessBlockConfiguration><BlockPublicAcls>yes</BlockPublicAcls></PublicAccessBlockConfiguration>`))
+	require.Equal(t, MalformedXML, errCode)
+}
+
+func TestPolicyIsPublic(t *testing.T) {
+	statement := func(effect, principal, condition string) string {
+		s := `{"Effect":"` + effect + `","Principal":` + principal +
+			`,"Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket/*"`
+		if condition != "" {
+			s += `,"Condition":` + condition
+		}
+		return `{"Version":"2012-10-17","Statement":[` + s + `}]}`
+	}
+	tests := []struct {
+		policy   string
+		expected bool
+	}{
+		{policy: statement("Allow", `"*"`, ""), expected: true},
+		{policy: statement("Allow", `{"AWS":"*"}`, ""), expected: true},
+		{policy: statement("Allow", `{"AWS":["1001","*"]}`, ""), expected: true},
+		{policy: statement("Deny", `"*"`, ""), expected: false},
+		{policy: statement("Allow", `{"AWS":["1001","1002"]}`, ""), expected: false},
+		{policy: statement("Allow", `"*"`, `{"IpAddress":{"aws:SourceIp":"10.0.0.0/8"}}`), expected: false},
+		{policy: statement("Allow", `"*"`, `{"IpAddress":{"aws:SourceIp":["10.0.0.0/8","0.0.0.0/0"]}}`), expected: true},
+		{policy: statement("Allow", `"*"`, `{"NotIpAddress":{"aws:SourceIp":"10.0.0.0/8"}}`), expected: true},
+		{policy: statement("Allow", `"*"`, `{"StringLike":{"aws:Referer":"http://example.com/*"}}`), expected: true},
+	}
+	for _, tt := range tests {
+		policy, err := ParsePolicy([]byte(tt.policy))
+		require.NoError(t, err, tt.policy)
+		require.Equal(t, tt.expected, policy.IsPublic(), tt.policy)
+	}
+
+	policy, err := ParsePolicy([]byte(`{"Version":"2012-10-17","Statement":[
+		{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket/*"},
+		{"Effect":"Deny","Principal":"*","Action":"s3:DeleteObject","Resource":"arn:aws:s3:::bucket/*"},
+		{"Effect":"Allow","Principal":{"AWS":"1001"},"Action":"s3:PutObject","Resource":"arn:aws:s3:::bucket/*"}]}`))
+	require.NoError(t, err)
+	require.True(t, policy.IsPublic())
+	filtered := policy.WithoutPublicStatements()
+	require.False(t, filtered.IsPublic())
+	require.Len(t, filtered.Statements, 2)
+	require.Len(t, policy.Statements, 3)
+	require.Equal(t, filtered, filtered.WithoutPublicStatements())
+}
+
+func TestAclIsPublic(t *testing.T) {
+	acl := &AccessControlPolicy{}
+	acl.SetPrivate("1001")
+	require.False(t, acl.IsPublic())
+	require.Equal(t, acl, acl.WithoutPublicGrants())
+
+	acl = &AccessControlPolicy{}
+	acl.SetPublicReadWrite("1001")
+	require.True(t, acl.IsPublic())
+	filtered := acl.WithoutPublicGrants()
+	require.False(t, filtered.IsPublic())
+	require.Len(t, filtered.Acl.Grants, 1)
+	require.Len(t, acl.Acl.Grants, 3)
+	require.True(t, filtered.IsAllowed("1001", proto.OSSGetObjectAction))
+	require.False(t, filtered.IsAllowed(AnonymousUser, proto.OSSGetObjectAction))
+
+	acl = &AccessControlPolicy{}
+	acl.SetAuthenticatedRead("1001")
+	require.True(t, acl.IsPublic())
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/replication.html
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+)
+
+const (
+	ReplicationStatusEnabled  = "Enabled"
+	ReplicationStatusDisabled = "Disabled"
+
+	// Values of the replication status of objects, which is returned by header 'x-amz-replication-status'.
+	ReplicationPending   = "PENDING"
+	ReplicationCompleted = "COMPLETED"
+	ReplicationFailed    = "FAILED"
+	ReplicationReplica   = "REPLICA"
+
+	ReplicationBucketARNPrefix = "arn:aws:s3:::"
+
+	MaxReplicationSize  = 1 << 20 // 1MB
+	MaxReplicationRules = 1000
+)
+
+var (
+	NoSuchReplicationConfiguration = &ErrorCode{ErrorCode: "ReplicationConfigurationNotFoundError", ErrorMessage: "The replication configuration was not found.", StatusCode: http.StatusNotFound}
+	ReplicationErrVersioning       = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "Versioning must be 'Enabled' on the bucket to apply a replication configuration.", StatusCode: http.StatusBadRequest}
+	ReplicationErrMissingRules     = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "No replication rules found in request.", StatusCode: http.StatusBadRequest}
+	ReplicationErrTooManyRules     = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Rules number should not exceed allowed limit of 1000.", StatusCode: http.StatusBadRequest}
+	ReplicationErrSameRuleID       = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Rule ID must be unique. Found same ID for more than one rule.", StatusCode: http.StatusBadRequest}
+	ReplicationErrTooLongRuleID    = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "ID length should not exceed allowed limit of 255.", StatusCode: http.StatusBadRequest}
+	ReplicationErrSamePriority     = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Found duplicate priority among rules.", StatusCode: http.StatusBadRequest}
+	ReplicationErrDestination      = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Invalid destination bucket ARN.", StatusCode: http.StatusBadRequest}
+	ReplicationErrSameBucket       = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "Destination bucket cannot be the same as the source bucket.", StatusCode: http.StatusBadRequest}
+)
+
+type ReplicationConfiguration struct {
+	XMLName xml.Name           `xml:"ReplicationConfiguration" json:"-"`
+	XMLNS   string             `xml:"xmlns,attr,omitempty" json:"-"`
+	Role    string             `xml:"Role,omitempty" json:"role,omitempty"`
+	Rules   []*ReplicationRule `xml:"Rule" json:"rules"`
+}
+
+type ReplicationRule struct {
+	ID                      string                   `xml:"ID,omitempty" json:"id,omitempty"`
+	Priority                int                      `xml:"Priority,omitempty" json:"priority,omitempty"`
+	Status                  string                   `xml:"Status" json:"status"`
+	Prefix                  string                   `xml:"Prefix,omitempty" json:"prefix,omitempty"` // deprecated by filter
+	Filter                  *ReplicationFilter       `xml:"Filter,omitempty" json:"filter,omitempty"`
+	Destination             *ReplicationDestination  `xml:"Destination" json:"destination"`
+	DeleteMarkerReplication *DeleteMarkerReplication `xml:"DeleteMarkerReplication,omitempty" json:"delete_marker_replication,omitempty"`
+}
+
+type ReplicationFilter struct {
+	Prefix string `xml:"Prefix,omitempty" json:"prefix,omitempty"`
+}
+
+type ReplicationDestination struct {
+	Bucket       string `xml:"Bucket" json:"bucket"`
+	StorageClass string `xml:"StorageClass,omitempty" json:"storage_class,omitempty"`
+}
+
+type DeleteMarkerReplication struct {
+	Status string `xml:"Status" json:"status"`
+}
+
+func ParseReplicationConfig(data []byte, bucket string) (*ReplicationConfiguration, *ErrorCode) {
+	config := &ReplicationConfiguration{}
+	if err := xml.Unmarshal(data, config); err != nil {
+		return nil, MalformedXML
+	}
+	if errCode := config.Validate(bucket); errCode != nil {
+		return nil, errCode
+	}
+	return config, nil
+}
+
+func (c *ReplicationConfiguration) Validate(bucket string) *ErrorCode {
+	if len(c.Rules) == 0 {
+		return ReplicationErrMissingRules
+	}
+	if len(c.Rules) > MaxReplicationRules {
+		return ReplicationErrTooManyRules
+	}
+	ids := make(map[string]struct{})
+	priorities := make(map[int]struct{})
+	for _, rule := range c.Rules {
+		if len(rule.ID) > MaxIdLength {
+			return ReplicationErrTooLongRuleID
+		}
+		if rule.ID != "" {
+			if _, ok := ids[rule.ID]; ok {
+				return ReplicationErrSameRuleID
+			}
+			ids[rule.ID] = struct{}{}
+		}
+		if _, ok := priorities[rule.Priority]; ok && len(c.Rules) > 1 {
+			return ReplicationErrSamePriority
+		}
+		priorities[rule.Priority] = struct{}{}
+		if rule.Status != ReplicationStatusEnabled && rule.Status != ReplicationStatusDisabled {
+			return MalformedXML
+		}
+		if rule.DeleteMarkerReplication != nil {
+			status := rule.DeleteMarkerReplication.Status
+			if status != ReplicationStatusEnabled && status != ReplicationStatusDisabled {
+				return MalformedXML
+			}
+		}
+		if rule.Destination == nil {
+			return MalformedXML
+		}
+		target := rule.Destination.TargetBucket()
+		if target == "" {
+			return ReplicationErrDestination
+		}
+		if target == bucket {
+			return ReplicationErrSameBucket
+		}
+	}
+	return nil
+}
+
+// Match returns the enabled rule with the highest priority which applies to the key.
+func (c *ReplicationConfiguration) Match(key string) *ReplicationRule {
+	if c == nil {
+		return nil
+	}
+	var matched *ReplicationRule
+	for _, rule := range c.Rules {
+		if rule.Status != ReplicationStatusEnabled || !strings.HasPrefix(key, rule.KeyPrefix()) {
+			continue
+		}
+		if matched == nil || rule.Priority > matched.Priority {
+			matched = rule
+		}
+	}
+	return matched
+}
+
+func (r *ReplicationRule) KeyPrefix() string {
+	if r.Filter != nil {
+		return r.Filter.Prefix
+	}
+	return r.Prefix
+}
+
+func (r *ReplicationRule) ReplicateDeleteMarker() bool {
+	return r.DeleteMarkerReplication != nil && r.DeleteMarkerReplication.Status == ReplicationStatusEnabled
+}
+
+// TargetBucket returns the name of the destination bucket, which is specified as
+// a bucket ARN like 'arn:aws:s3:::bucket' or just the bucket name.
+func (d *ReplicationDestination) TargetBucket() string {
+	bucket := strings.TrimPrefix(d.Bucket, ReplicationBucketARNPrefix)
+	if strings.ContainsAny(bucket, ":/") {
+		return ""
+	}
+	return bucket
+}
+
+func storeBucketReplication(bytes []byte, vol *Volume) (err error) {
+	return vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSReplication, bytes)
+}
+
+func deleteBucketReplication(vol *Volume) (err error) {
+	return vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSReplication)
+}
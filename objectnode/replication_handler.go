@@ -0,0 +1,201 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// Put bucket replication
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketReplication.html
+func (o *ObjectNode) putBucketReplicationHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putBucketReplicationHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(r.Body, MaxReplicationSize+1)); err != nil {
+		log.LogErrorf("putBucketReplicationHandler: read request body fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if len(body) > MaxReplicationSize {
+		errorCode = EntityTooLarge
+		return
+	}
+	var config *ReplicationConfiguration
+	if config, errorCode = ParseReplicationConfig(body, vol.Name()); errorCode != nil {
+		log.LogErrorf("putBucketReplicationHandler: parse replication config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), errorCode)
+		return
+	}
+
+	// replication requires versioning to be enabled on the source bucket
+	var versioning *VersioningConfiguration
+	if versioning, err = vol.metaLoader.loadVersioning(); err != nil {
+		log.LogErrorf("putBucketReplicationHandler: load versioning fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if !versioning.IsEnabled() {
+		errorCode = ReplicationErrVersioning
+		return
+	}
+
+	// the destination must be either a remote target or an existing bucket on this cluster
+	for _, rule := range config.Rules {
+		target := rule.Destination.TargetBucket()
+		if o.replicator != nil && o.replicator.hasRemoteTarget(target) {
+			continue
+		}
+		if _, err = o.getVol(target); err != nil {
+			log.LogErrorf("putBucketReplicationHandler: load destination volume fail: requestID(%v) volume(%v) target(%v) err(%v)",
+				GetRequestID(r), vol.Name(), target, err)
+			err = nil
+			errorCode = NewError("InvalidRequest", "Destination bucket must exist.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if body, err = json.Marshal(config); err != nil {
+		log.LogErrorf("putBucketReplicationHandler: json marshal replication config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+	if err = storeBucketReplication(body, vol); err != nil {
+		log.LogErrorf("putBucketReplicationHandler: store replication config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), err)
+		return
+	}
+	vol.metaLoader.storeReplication(config)
+
+	log.LogInfof("Audit: put bucket replication: requestID(%v) volume(%v) config(%v)",
+		GetRequestID(r), vol.Name(), string(body))
+	return
+}
+
+// Get bucket replication
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketReplication.html
+func (o *ObjectNode) getBucketReplicationHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getBucketReplicationHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var config *ReplicationConfiguration
+	if config, err = vol.metaLoader.loadReplication(); err != nil {
+		log.LogErrorf("getBucketReplicationHandler: load replication fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if config == nil || len(config.Rules) == 0 {
+		errorCode = NoSuchReplicationConfiguration
+		return
+	}
+	config.XMLNS = XMLNS
+
+	var data []byte
+	if data, err = MarshalXMLEntity(config); err != nil {
+		log.LogErrorf("getBucketReplicationHandler: xml marshal fail: requestID(%v) volume(%v) config(%+v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+
+	writeSuccessResponseXML(w, data)
+	return
+}
+
+// Delete bucket replication
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketReplication.html
+func (o *ObjectNode) deleteBucketReplicationHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("deleteBucketReplicationHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	if err = deleteBucketReplication(vol); err != nil {
+		log.LogErrorf("deleteBucketReplicationHandler: delete replication config fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	vol.metaLoader.storeReplication(nil)
+
+	log.LogInfof("Audit: delete bucket replication: requestID(%v) volume(%v)", GetRequestID(r), vol.Name())
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
+
+// replicateObjectEvent persists the replication task of the object write before it is acknowledged, the
+// client gets an error and retries the write if the task can not be persisted.
+func (o *ObjectNode) replicateObjectEvent(r *http.Request, vol *Volume, key, versionId string, isDelete bool) error {
+	if o.replicator == nil {
+		return nil
+	}
+	if err := o.replicator.Submit(vol, key, versionId, isDelete); err != nil {
+		log.LogErrorf("replicateObjectEvent: submit task fail: requestID(%v) volume(%v) key(%v) versionId(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, versionId, err)
+		return err
+	}
+	return nil
+}
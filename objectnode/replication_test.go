@@ -0,0 +1,181 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReplicationConfig(t *testing.T) {
+	tests := []struct {
+		value       string
+		expectedErr *ErrorCode
+	}{
+		{
+			value: `<ReplicationConfiguration>
+						<Role>arn:aws:iam::account:role/replication</Role>
+						<Rule>
+							<ID>r1</ID>
+							<Priority>1</Priority>
+							<Status>Enabled</Status>
+							<Filter><Prefix>logs/</Prefix></Filter>
+							<Destination><Bucket>arn:aws:s3:::dst</Bucket></Destination>
+							<DeleteMarkerReplication><Status>Enabled</Status></DeleteMarkerReplication>
+						</Rule>
+					</ReplicationConfiguration>`,
+		},
+		{
+			value: `<ReplicationConfiguration>
+						<Rule><Status>Enabled</Status><Destination><Bucket>dst</Bucket></Destination></Rule>
+					</ReplicationConfiguration>`,
+		},
+		{
+			value:       `<ReplicationConfiguration></ReplicationConfiguration>`,
+			expectedErr: ReplicationErrMissingRules,
+		},
+		{
+			value: `<ReplicationConfiguration>
+						<Rule><Status>enabled</Status><Destination><Bucket>dst</Bucket></Destination></Rule>
+					</ReplicationConfiguration>`,
+			expectedErr: MalformedXML,
+		},
+		{
+			value: `<ReplicationConfiguration>
+						<Rule><Status>Enabled</Status></Rule>
+					</ReplicationConfiguration>`,
+			expectedErr: MalformedXML,
+		},
+		{
+			value: `<ReplicationConfiguration>
+						<Rule><Status>Enabled</Status><Destination><Bucket>arn:aws:s3:::src</Bucket></Destination></Rule>
+					</ReplicationConfiguration>`,
+			expectedErr: ReplicationErrSameBucket,
+		},
+		{
+			value: `<ReplicationConfiguration>
+						<Rule><Status>Enabled</Status><Destination><Bucket>arn:aws:s3:us:dst</Bucket></Destination></Rule>
+					</ReplicationConfiguration>`,
+			expectedErr: ReplicationErrDestination,
+		},
+		{
+			value: `<ReplicationConfiguration>
+						<Rule><ID>a</ID><Priority>1</Priority><Status>Enabled</Status><Destination><Bucket>dst</Bucket></Destination></Rule>
+						<Rule><ID>a</ID><Priority>2</Priority><Status>Enabled</Status><Destination><Bucket>dst</Bucket></Destination></Rule>
+					</ReplicationConfiguration>`,
+			expectedErr: ReplicationErrSameRuleID,
+		},
+		{
+			value: `<ReplicationConfiguration>
+						<Rule><ID>a</ID><Priority>1</Priority><Status>Enabled</Status><Destination><Bucket>dst</Bucket></Destination></Rule>
+						<Rule><ID>b</ID><Priority>1</Priority><Status>Enabled</Status><Destination><Bucket>dst</Bucket></Destination></Rule>
+					</ReplicationConfiguration>`,
+			expectedErr: ReplicationErrSamePriority,
+		},
+	}
+	for _, tt := range tests {
+		config, errCode := ParseReplicationConfig([]byte(tt.value), "src")
+		if tt.expectedErr != nil {
+			require.Equal(t, tt.expectedErr, errCode)
+			continue
+		}
+		require.Nil(t, errCode)
+		require.Equal(t, "dst", config.Rules[0].Destination.TargetBucket())
+	}
+}
+
+func TestReplicationConfigMatch(t *testing.T) {
+	config := &ReplicationConfiguration{
+		Rules: []*ReplicationRule{
+			{ID: "all", Priority: 1, Status: ReplicationStatusEnabled, Destination: &ReplicationDestination{Bucket: "a"}},
+			{ID: "logs", Priority: 2, Status: ReplicationStatusEnabled, Filter: &ReplicationFilter{Prefix: "logs/"},
+				Destination:             &ReplicationDestination{Bucket: "b"},
+				DeleteMarkerReplication: &DeleteMarkerReplication{Status: ReplicationStatusEnabled}},
+			{ID: "tmp", Priority: 3, Status: ReplicationStatusDisabled, Prefix: "logs/tmp/",
+				Destination: &ReplicationDestination{Bucket: "c"}},
+		},
+	}
+	require.Equal(t, "all", config.Match("data/1").ID)
+	require.Equal(t, "logs", config.Match("logs/1").ID)
+	require.Equal(t, "logs", config.Match("logs/tmp/1").ID)
+	require.True(t, config.Match("logs/1").ReplicateDeleteMarker())
+	require.False(t, config.Match("data/1").ReplicateDeleteMarker())
+
+	var nilConfig *ReplicationConfiguration
+	require.Nil(t, nilConfig.Match("data/1"))
+}
+
+func TestReplicationSpool(t *testing.T) {
+	dir, err := os.MkdirTemp("", "replication-spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	spool, err := newReplicationSpool(dir)
+	require.NoError(t, err)
+	put := &ReplicationTask{Bucket: "src", Key: "a/b", VersionId: "1", Target: "dst", CreateTime: time.Now().Unix()}
+	del := &ReplicationTask{Bucket: "src", Key: "a/b", VersionId: "2", Target: "dst", Delete: true}
+	require.NotEqual(t, put.ID(), del.ID())
+	require.NoError(t, spool.save(put))
+	require.NoError(t, spool.save(del))
+
+	put.Retries = 3
+	require.NoError(t, spool.save(put))
+	tasks, err := spool.load()
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	for _, task := range tasks {
+		if task.Delete {
+			require.Equal(t, *del, *task)
+		} else {
+			require.Equal(t, *put, *task)
+		}
+	}
+
+	require.NoError(t, spool.remove(put))
+	require.NoError(t, spool.remove(put))
+	tasks, err = spool.load()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	require.True(t, tasks[0].Delete)
+}
+
+func TestReplicationRetryBackoff(t *testing.T) {
+	conf := ReplicationConfig{SpoolDir: "spool"}
+	require.NoError(t, conf.fixConfig())
+	r := &Replicator{conf: conf}
+	interval := time.Duration(defaultReplicationRetryInterval) * time.Second
+	require.Equal(t, interval, r.retryBackoff(1))
+	require.Equal(t, 4*interval, r.retryBackoff(3))
+	require.Equal(t, maxReplicationRetryBackoff, r.retryBackoff(100))
+
+	require.Error(t, (&ReplicationConfig{}).fixConfig())
+}
+
+func TestReplicationConfigRedacted(t *testing.T) {
+	conf := ReplicationConfig{
+		SpoolDir: "spool",
+		Targets: map[string]ReplicationTarget{
+			"remote": {Endpoint: "s3.example.com", Bucket: "dst", AccessKey: "ak-secret", SecretKey: "sk-secret"},
+		},
+	}
+	require.NoError(t, conf.fixConfig())
+	redacted := conf.redacted()
+	require.Contains(t, redacted, "remote: s3.example.com/dst")
+	require.NotContains(t, redacted, "ak-secret")
+	require.NotContains(t, redacted, "sk-secret")
+}
@@ -0,0 +1,540 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const (
+	defaultReplicationWorkers       = 4
+	defaultReplicationQueueSize     = 10000
+	defaultReplicationMaxRetry      = 10
+	defaultReplicationRetryInterval = 10
+	maxReplicationRetryBackoff      = time.Hour
+)
+
+var errReplicationSSEC = errors.New("objects encrypted with customer keys can not be replicated")
+
+// ReplicationConfig is the configuration of the bucket replicator of ObjectNode.
+type ReplicationConfig struct {
+	// Directory in which the pending replication tasks are persisted, so that they survive restarts.
+	SpoolDir         string `json:"spoolDir"`
+	Workers          int    `json:"workers"`
+	QueueSize        int    `json:"queueSize"`
+	MaxRetry         int    `json:"maxRetry"`
+	RetryIntervalSec int    `json:"retryIntervalSec"`
+	// The key of map is the destination bucket name used in replication rules.
+	// Buckets not listed here are treated as buckets of the local cluster.
+	Targets map[string]ReplicationTarget `json:"targets,omitempty"`
+}
+
+// ReplicationTarget is a bucket on a remote S3 compatible endpoint.
+type ReplicationTarget struct {
+	Endpoint   string `json:"endpoint"`
+	Region     string `json:"region"`
+	AccessKey  string `json:"accessKey"`
+	SecretKey  string `json:"secretKey"`
+	Bucket     string `json:"bucket"` // remote bucket name, same as the key of targets if empty
+	DisableSSL bool   `json:"disableSSL"`
+}
+
+func (c *ReplicationConfig) fixConfig() error {
+	if c.SpoolDir == "" {
+		return errors.New("spoolDir is required")
+	}
+	if c.Workers <= 0 {
+		c.Workers = defaultReplicationWorkers
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultReplicationQueueSize
+	}
+	if c.MaxRetry <= 0 {
+		c.MaxRetry = defaultReplicationMaxRetry
+	}
+	if c.RetryIntervalSec <= 0 {
+		c.RetryIntervalSec = defaultReplicationRetryInterval
+	}
+	for name, target := range c.Targets {
+		if target.Endpoint == "" {
+			return fmt.Errorf("endpoint of replication target '%v' is required", name)
+		}
+	}
+	return nil
+}
+
+// redacted returns the config without the credentials of the targets, which is safe to be logged.
+func (c *ReplicationConfig) redacted() string {
+	targets := make([]string, 0, len(c.Targets))
+	for name, target := range c.Targets {
+		targets = append(targets, fmt.Sprintf("%v: %v/%v", name, target.Endpoint, target.Bucket))
+	}
+	sort.Strings(targets)
+	return fmt.Sprintf("spoolDir: %v, workers: %v, targets: [%v]", c.SpoolDir, c.Workers, strings.Join(targets, ", "))
+}
+
+// ReplicationTask describes an object version or a delete marker to be replicated to the destination bucket.
+type ReplicationTask struct {
+	Bucket     string `json:"bucket"`
+	Key        string `json:"key"`
+	VersionId  string `json:"versionId"`
+	Target     string `json:"target"`
+	Delete     bool   `json:"delete,omitempty"`
+	Retries    int    `json:"retries,omitempty"`
+	NextRetry  int64  `json:"nextRetry,omitempty"`
+	CreateTime int64  `json:"createTime"`
+}
+
+// ID returns a unique name of the task, the same object version is only replicated once.
+func (t *ReplicationTask) ID() string {
+	op := "put"
+	if t.Delete {
+		op = "delete"
+	}
+	sum := md5.Sum([]byte(strings.Join([]string{t.Bucket, t.Key, t.VersionId, t.Target, op}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// replicationSpool persists the replication tasks as files in the spool directory.
+type replicationSpool struct {
+	*taskSpool
+}
+
+func newReplicationSpool(dir string) (*replicationSpool, error) {
+	spool, err := newTaskSpool("replicationSpool", dir)
+	if err != nil {
+		return nil, err
+	}
+	return &replicationSpool{taskSpool: spool}, nil
+}
+
+func (s *replicationSpool) load() (tasks []*ReplicationTask, err error) {
+	loaded, err := s.taskSpool.load(func() spoolTask { return &ReplicationTask{} })
+	if err != nil {
+		return
+	}
+	for _, task := range loaded {
+		tasks = append(tasks, task.(*ReplicationTask))
+	}
+	return tasks, nil
+}
+
+// Replicator replicates objects to the destination buckets asynchronously. The tasks are submitted
+// by the object write handlers and persisted in the spool before the writes are acknowledged.
+type Replicator struct {
+	conf     ReplicationConfig
+	getVol   func(bucket string) (*Volume, error)
+	spool    *replicationSpool
+	remotes  map[string]*s3.S3
+	queue    chan *ReplicationTask
+	inflight sync.Map
+	stopC    chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewReplicator(conf ReplicationConfig, getVol func(bucket string) (*Volume, error)) (*Replicator, error) {
+	if err := conf.fixConfig(); err != nil {
+		return nil, err
+	}
+	spool, err := newReplicationSpool(conf.SpoolDir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replicator{
+		conf:    conf,
+		getVol:  getVol,
+		spool:   spool,
+		remotes: make(map[string]*s3.S3),
+		queue:   make(chan *ReplicationTask, conf.QueueSize),
+		stopC:   make(chan struct{}),
+	}
+	for name, target := range conf.Targets {
+		sess, err := session.NewSession()
+		if err != nil {
+			return nil, err
+		}
+		ac := aws.NewConfig()
+		ac.Endpoint = aws.String(target.Endpoint)
+		ac.DisableSSL = aws.Bool(target.DisableSSL)
+		ac.Region = aws.String(target.Region)
+		ac.Credentials = credentials.NewStaticCredentials(target.AccessKey, target.SecretKey, "")
+		ac.S3ForcePathStyle = aws.Bool(true)
+		r.remotes[name] = s3.New(sess, ac)
+	}
+	return r, nil
+}
+
+// Submit persists the replication task of the object version written, or of the delete marker created,
+// if it matches a replication rule of the bucket. It is called by the handlers before the response is
+// written, so an object write acknowledged to the client is never lost by a crash of ObjectNode.
+func (r *Replicator) Submit(vol *Volume, key, versionId string, isDelete bool) error {
+	if key == "" {
+		return nil
+	}
+	config, err := vol.metaLoader.loadReplication()
+	if err != nil {
+		return err
+	}
+	rule := config.Match(key)
+	if rule == nil || (isDelete && !rule.ReplicateDeleteMarker()) {
+		return nil
+	}
+
+	task := &ReplicationTask{
+		Bucket:     vol.Name(),
+		Key:        key,
+		VersionId:  versionId,
+		Target:     rule.Destination.TargetBucket(),
+		Delete:     isDelete,
+		CreateTime: time.Now().Unix(),
+	}
+	if !isDelete {
+		r.setStatus(vol, task, ReplicationPending)
+	}
+	if err = r.spool.save(task); err != nil {
+		log.LogErrorf("Replicator: save task fail: task(%+v) err(%v)", task, err)
+		return err
+	}
+	r.enqueue(task)
+	return nil
+}
+
+func (r *Replicator) Close() error {
+	r.stopOnce.Do(func() {
+		close(r.stopC)
+	})
+	r.wg.Wait()
+	return nil
+}
+
+// Start starts the replication workers and reloads the tasks persisted in the spool.
+func (r *Replicator) Start() {
+	for i := 0; i < r.conf.Workers; i++ {
+		r.wg.Add(1)
+		go r.worker()
+	}
+	r.wg.Add(1)
+	go r.scheduleRetry()
+	r.rescan()
+}
+
+func (r *Replicator) hasRemoteTarget(bucket string) bool {
+	_, ok := r.remotes[bucket]
+	return ok
+}
+
+func (r *Replicator) enqueue(task *ReplicationTask) {
+	id := task.ID()
+	if _, loaded := r.inflight.LoadOrStore(id, struct{}{}); loaded {
+		return
+	}
+	select {
+	case r.queue <- task:
+	default:
+		// the task is kept in the spool and will be picked up by the next rescan
+		r.inflight.Delete(id)
+		log.LogWarnf("Replicator: queue is full: task(%+v)", task)
+	}
+}
+
+func (r *Replicator) rescan() {
+	tasks, err := r.spool.load()
+	if err != nil {
+		log.LogErrorf("Replicator: load spool fail: dir(%v) err(%v)", r.conf.SpoolDir, err)
+		return
+	}
+	now := time.Now().Unix()
+	for _, task := range tasks {
+		if task.NextRetry <= now {
+			r.enqueue(task)
+		}
+	}
+}
+
+func (r *Replicator) scheduleRetry() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(time.Duration(r.conf.RetryIntervalSec) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stopC:
+			return
+		case <-ticker.C:
+			r.rescan()
+		}
+	}
+}
+
+func (r *Replicator) worker() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.stopC:
+			return
+		case task := <-r.queue:
+			r.process(task)
+			r.inflight.Delete(task.ID())
+		}
+	}
+}
+
+func (r *Replicator) process(task *ReplicationTask) {
+	srcVol, err := r.getVol(task.Bucket)
+	if err != nil {
+		if err == NoSuchBucket {
+			log.LogWarnf("Replicator: drop task of deleted bucket: task(%+v)", task)
+			_ = r.spool.remove(task)
+			return
+		}
+		log.LogErrorf("Replicator: load source volume fail: task(%+v) err(%v)", task, err)
+		return
+	}
+	if task.Delete {
+		err = r.replicateDelete(task)
+	} else {
+		err = r.replicateObject(srcVol, task)
+	}
+	if err == nil {
+		if !task.Delete {
+			r.setStatus(srcVol, task, ReplicationCompleted)
+		}
+		if err = r.spool.remove(task); err != nil {
+			log.LogErrorf("Replicator: remove task fail: task(%+v) err(%v)", task, err)
+		}
+		log.LogDebugf("Replicator: replicate success: task(%+v)", task)
+		return
+	}
+	if err == errReplicationSSEC {
+		r.setStatus(srcVol, task, ReplicationFailed)
+		log.LogWarnf("Replicator: drop task of SSE-C object: task(%+v)", task)
+		_ = r.spool.remove(task)
+		return
+	}
+	if err == syscall.ENOENT || err == NoSuchVersion {
+		// the source object version has gone, nothing to replicate
+		log.LogWarnf("Replicator: drop task: task(%+v) err(%v)", task, err)
+		_ = r.spool.remove(task)
+		return
+	}
+
+	task.Retries++
+	log.LogWarnf("Replicator: replicate fail: task(%+v) err(%v)", task, err)
+	if task.Retries >= r.conf.MaxRetry {
+		if !task.Delete {
+			r.setStatus(srcVol, task, ReplicationFailed)
+		}
+		log.LogErrorf("Replicator: replicate fail after %v retries: task(%+v) err(%v)", task.Retries, task, err)
+		_ = r.spool.remove(task)
+		return
+	}
+	task.NextRetry = time.Now().Add(r.retryBackoff(task.Retries)).Unix()
+	if err = r.spool.save(task); err != nil {
+		log.LogErrorf("Replicator: save task fail: task(%+v) err(%v)", task, err)
+	}
+}
+
+func (r *Replicator) retryBackoff(retries int) time.Duration {
+	return retryBackoff(time.Duration(r.conf.RetryIntervalSec)*time.Second, maxReplicationRetryBackoff, retries)
+}
+
+// setStatus records the replication status in the xattr of the replicated object version.
+func (r *Replicator) setStatus(vol *Volume, task *ReplicationTask, status string) {
+	info, _, err := vol.ObjectVersionMeta(task.Key, task.VersionId)
+	if err != nil || info.Inode == 0 {
+		log.LogWarnf("Replicator: get object meta fail: task(%+v) status(%v) err(%v)", task, status, err)
+		return
+	}
+	if err = vol.mw.XAttrSet_ll(info.Inode, []byte(XAttrKeyOSSReplStatus), []byte(status)); err != nil {
+		log.LogErrorf("Replicator: set replication status fail: task(%+v) inode(%v) status(%v) err(%v)",
+			task, info.Inode, status, err)
+		return
+	}
+	updateAttrCache(info.Inode, XAttrKeyOSSReplStatus, status, vol.name)
+}
+
+func (r *Replicator) replicateObject(srcVol *Volume, task *ReplicationTask) (err error) {
+	var (
+		info  *FSFileInfo
+		xattr *proto.XAttrInfo
+	)
+	if info, xattr, err = srcVol.ObjectVersionMeta(task.Key, task.VersionId); err != nil {
+		return
+	}
+	if info.DeleteMarker {
+		return NoSuchVersion
+	}
+	if err = r.unsealSource(info); err != nil {
+		return
+	}
+	var tagging *Tagging
+	if xattr != nil {
+		if raw := string(xattr.Get(XAttrKeyOSSTagging)); raw != "" {
+			if tagging, err = ParseTagging(raw); err != nil {
+				return
+			}
+		}
+	}
+
+	if client, ok := r.remotes[task.Target]; ok {
+		return r.putRemoteObject(client, srcVol, task, info, tagging)
+	}
+
+	var dstVol *Volume
+	if dstVol, err = r.getVol(task.Target); err != nil {
+		return
+	}
+	opt := &PutFileOption{
+		MIMEType:          info.MIMEType,
+		Disposition:       info.Disposition,
+		Tagging:           tagging,
+		Metadata:          info.Metadata,
+		CacheControl:      info.CacheControl,
+		Expires:           info.Expires,
+		ReplicationStatus: ReplicationReplica,
+	}
+	if info.Mode.IsDir() {
+		opt.MIMEType = ValueContentTypeDirectory
+		_, err = dstVol.PutObject(task.Key, bytes.NewReader(nil), opt)
+		return
+	}
+	// the replica is encrypted if the source is encrypted or the destination bucket requires encryption
+	var dstEncryption *ServerSideEncryptionConfiguration
+	if dstEncryption, err = dstVol.metaLoader.loadEncryption(); err != nil {
+		return
+	}
+	if info.Encryption != nil || dstEncryption.DefaultAlgorithm() != "" {
+		if opt.Encryption, err = newSSES3Encryption(); err != nil {
+			return
+		}
+	}
+	_, err = dstVol.CopyObjectData(srcVol, info, task.Key, opt)
+	return
+}
+
+// unsealSource unseals the data key of the encrypted source object. Objects encrypted with customer keys
+// can not be replicated since the keys are never kept by the server.
+func (r *Replicator) unsealSource(info *FSFileInfo) (err error) {
+	if info.Encryption == nil {
+		return nil
+	}
+	if info.Encryption.Type == SSETypeC {
+		return errReplicationSSEC
+	}
+	if sseKMS == nil {
+		return SSENotConfigured
+	}
+	info.Encryption.dataKey, err = sseKMS.UnsealDataKey(info.Encryption.KeyID, info.Encryption.SealedKey)
+	return
+}
+
+func (r *Replicator) putRemoteObject(client *s3.S3, srcVol *Volume, task *ReplicationTask, info *FSFileInfo, tagging *Tagging) (err error) {
+	// the data is staged in a local file since the aws sdk requires a seekable body
+	var file *os.File
+	if file, err = os.CreateTemp(r.conf.SpoolDir, task.ID()+spoolTmpSuffix); err != nil {
+		return
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+	if !info.Mode.IsDir() && info.Size > 0 {
+		if err = srcVol.readObject(info, task.Key, file, 0, uint64(info.Size)); err != nil {
+			return
+		}
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return
+		}
+	}
+
+	key := task.Key
+	if info.Mode.IsDir() && !strings.HasSuffix(key, pathSep) {
+		key += pathSep
+	}
+	input := &s3.PutObjectInput{
+		Bucket:   aws.String(r.remoteBucket(task.Target)),
+		Key:      aws.String(key),
+		Body:     file,
+		Metadata: make(map[string]*string, len(info.Metadata)),
+	}
+	if info.MIMEType != "" {
+		input.ContentType = aws.String(info.MIMEType)
+	}
+	if info.Disposition != "" {
+		input.ContentDisposition = aws.String(info.Disposition)
+	}
+	if info.CacheControl != "" {
+		input.CacheControl = aws.String(info.CacheControl)
+	}
+	if tagging != nil && len(tagging.TagSet) > 0 {
+		input.Tagging = aws.String(tagging.Encode())
+	}
+	if info.Encryption != nil {
+		input.ServerSideEncryption = aws.String(SSEAlgorithmAES256)
+	}
+	for k, v := range info.Metadata {
+		input.Metadata[k] = aws.String(v)
+	}
+	_, err = client.PutObject(input)
+	return
+}
+
+func (r *Replicator) replicateDelete(task *ReplicationTask) (err error) {
+	if client, ok := r.remotes[task.Target]; ok {
+		_, err = client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: aws.String(r.remoteBucket(task.Target)),
+			Key:    aws.String(task.Key),
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			err = nil
+		}
+		return
+	}
+	var dstVol *Volume
+	if dstVol, err = r.getVol(task.Target); err != nil {
+		return
+	}
+	if _, _, err = dstVol.DeleteObject(task.Key, "", false); err == syscall.ENOENT {
+		err = nil
+	}
+	return
+}
+
+func (r *Replicator) remoteBucket(target string) string {
+	if bucket := r.conf.Targets[target].Bucket; bucket != "" {
+		return bucket
+	}
+	return target
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/restoring-objects.html
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+)
+
+const (
+	MaxRestoreRequestSize = 1 << 10 // 1KB
+	MaxRestoreDays        = 30000
+)
+
+var (
+	InvalidObjectState       = &ErrorCode{"InvalidObjectState", "The operation is not valid for the object's storage class.", http.StatusForbidden}
+	RestoreAlreadyInProgress = &ErrorCode{"RestoreAlreadyInProgress", "Object restore is already in progress.", http.StatusConflict}
+	InvalidRestoreDays       = &ErrorCode{"InvalidArgument", "'Days' for RestoreRequest must be a positive integer.", http.StatusBadRequest}
+)
+
+// RestoreRequest restores the copy of the object transitioned to the storage class GLACIER for the days,
+// the tier of the job is ignored since the copy is made from the blobstore directly.
+type RestoreRequest struct {
+	XMLName xml.Name `xml:"RestoreRequest"`
+	Days    int      `xml:"Days"`
+	Type    string   `xml:"Type,omitempty"`
+}
+
+func ParseRestoreRequest(body []byte) (*RestoreRequest, *ErrorCode) {
+	req := &RestoreRequest{}
+	if err := xml.Unmarshal(body, req); err != nil {
+		return nil, MalformedXML
+	}
+	// restore for SELECT is not supported
+	if req.Type != "" {
+		return nil, MalformedXML
+	}
+	if req.Days <= 0 || req.Days > MaxRestoreDays {
+		return nil, InvalidRestoreDays
+	}
+	return req, nil
+}
+
+// ObjectRestore is the state of the copy restored for the reads of the object transitioned, the copy is
+// dropped by the lifecycle transition of the lcnode once it expires.
+type ObjectRestore struct {
+	Ongoing bool
+	Expiry  time.Time
+}
+
+func parseObjectRestore(xattr *proto.XAttrInfo) *ObjectRestore {
+	expiry, err := strconv.ParseInt(string(xattr.Get(XAttrKeyOSSRestoreExpiry)), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &ObjectRestore{
+		Ongoing: string(xattr.Get(XAttrKeyOSSRestoreOngoing)) == "true",
+		Expiry:  time.Unix(expiry, 0),
+	}
+}
+
+// HeaderValue returns the value of the header x-amz-restore.
+func (r *ObjectRestore) HeaderValue() string {
+	if r.Ongoing {
+		return `ongoing-request="true"`
+	}
+	return fmt.Sprintf(`ongoing-request="false", expiry-date="%s"`, formatTimeRFC1123(r.Expiry))
+}
+
+// Readable checks whether the data of the object with the storage class could be read.
+func (r *ObjectRestore) Readable(storageClass string) bool {
+	if storageClass != StorageClassGlacier {
+		return true
+	}
+	return r != nil && !r.Ongoing
+}
+
+// restoreExpiry returns the time the copy restored for the days expires at, which is rounded up to the
+// midnight UTC of the next day.
+func restoreExpiry(now time.Time, days int) time.Time {
+	now = now.UTC().AddDate(0, 0, days+1)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// isLifecycleXAttr checks whether the xattr is the lifecycle state of the object, which is not copied
+// with the object.
+func isLifecycleXAttr(key string) bool {
+	return key == XAttrKeyOSSStorageClass || key == XAttrKeyOSSRestoreExpiry || key == XAttrKeyOSSRestoreOngoing ||
+		key == XAttrKeyOSSNoncurrentTime
+}
+
+// setStorageClassResponseHeaders sets the headers of the storage class and the restore state of the object,
+// the storage class STANDARD is omitted.
+func setStorageClassResponseHeaders(w http.ResponseWriter, info *FSFileInfo) {
+	if info.StorageClass != "" {
+		w.Header()[XAmzStorageClass] = []string{info.StorageClass}
+	}
+	if info.Restore != nil {
+		w.Header()[XAmzRestore] = []string{info.Restore.HeaderValue()}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRestoreRequest(t *testing.T) {
+	req, errCode := ParseRestoreRequest([]byte(`<RestoreRequest><Days>2</Days></RestoreRequest>`))
+	require.Nil(t, errCode)
+	require.Equal(t, 2, req.Days)
+
+	_, errCode = ParseRestoreRequest([]byte(`<RestoreRequest><Days>0</Days></RestoreRequest>`))
+	require.Equal(t, InvalidRestoreDays, errCode)
+	_, errCode = ParseRestoreRequest([]byte(`<RestoreRequest><Days>2</Days><Type>SELECT</Type></RestoreRequest>`))
+	require.Equal(t, MalformedXML, errCode)
+	_, errCode = ParseRestoreRequest([]byte(`<RestoreRequest><Days>2</Days>`))
+	require.Equal(t, MalformedXML, errCode)
+}
+
+func TestObjectRestore(t *testing.T) {
+	now := time.Date(2023, 5, 1, 15, 4, 5, 0, time.UTC)
+	expiry := restoreExpiry(now, 2)
+	require.Equal(t, time.Date(2023, 5, 4, 0, 0, 0, 0, time.UTC), expiry)
+
+	xattr := &proto.XAttrInfo{XAttrs: map[string]string{}}
+	require.Nil(t, parseObjectRestore(xattr))
+	var restore *ObjectRestore
+	require.True(t, restore.Readable(""))
+	require.False(t, restore.Readable(StorageClassGlacier))
+
+	xattr.XAttrs[XAttrKeyOSSRestoreExpiry] = strconv.FormatInt(expiry.Unix(), 10)
+	xattr.XAttrs[XAttrKeyOSSRestoreOngoing] = "true"
+	restore = parseObjectRestore(xattr)
+	require.True(t, restore.Ongoing)
+	require.Equal(t, `ongoing-request="true"`, restore.HeaderValue())
+	require.False(t, restore.Readable(StorageClassGlacier))
+
+	delete(xattr.XAttrs, XAttrKeyOSSRestoreOngoing)
+	restore = parseObjectRestore(xattr)
+	require.True(t, expiry.Equal(restore.Expiry))
+	require.Equal(t, `ongoing-request="false", expiry-date="Thu, 04 May 2023 00:00:00 GMT"`, restore.HeaderValue())
+	require.True(t, restore.Readable(StorageClassGlacier))
+}
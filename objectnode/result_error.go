@@ -88,7 +88,18 @@ var (
 	NoSuchObjectLockConfiguration       = &ErrorCode{"NoSuchObjectLockConfiguration", "The specified object does not have a ObjectLock configuration", http.StatusNotFound}
 	NoContentMd5HeaderErr               = &ErrorCode{"NoContentMd5Header", "Content-MD5 HTTP header is required for Upload Object/Part requests with Object Lock parameters", http.StatusBadRequest}
 	ObjectLockConfigurationNotFound     = &ErrorCode{"ObjectLockConfigurationNotFoundError", "Object Lock configuration does not exist for this bucket", http.StatusNotFound}
+	ObjectLockConfigurationMissing      = &ErrorCode{"InvalidRequest", "Bucket is missing Object Lock Configuration", http.StatusBadRequest}
+	NoSuchObjectLegalHold               = &ErrorCode{"NoSuchObjectLockConfiguration", "The specified object does not have a legal hold configuration", http.StatusNotFound}
+	InvalidRetentionMode                = &ErrorCode{"InvalidArgument", "Unknown wormMode directive.", http.StatusBadRequest}
+	MissingRetainUntilDate              = &ErrorCode{"InvalidArgument", "RetainUntilDate must be specified with the retention mode.", http.StatusBadRequest}
+	InvalidRetainUntilDate              = &ErrorCode{"InvalidArgument", "The retain until date must be provided in ISO 8601 format.", http.StatusBadRequest}
+	PastRetainUntilDate                 = &ErrorCode{"InvalidArgument", "The retain until date must be in the future!", http.StatusBadRequest}
+	InvalidObjectLockHeaders            = &ErrorCode{"InvalidArgument", "x-amz-object-lock-retain-until-date and x-amz-object-lock-mode must both be supplied.", http.StatusBadRequest}
+	InvalidLegalHoldStatus              = &ErrorCode{"InvalidArgument", "Legal Hold must be either of 'ON' or 'OFF'.", http.StatusBadRequest}
 	TooManyRequests                     = &ErrorCode{"TooManyRequests", "too many requests, please retry later", http.StatusTooManyRequests}
+	NoSuchVersion                       = &ErrorCode{"NoSuchVersion", "The specified version does not exist.", http.StatusNotFound}
+	MethodNotAllowed                    = &ErrorCode{"MethodNotAllowed", "The specified method is not allowed against this resource.", http.StatusMethodNotAllowed}
+	InvalidBucketState                  = &ErrorCode{"InvalidBucketState", "The request is not valid with the current state of the bucket.", http.StatusConflict}
 	MalformedPOSTRequest                = &ErrorCode{ErrorCode: "MalformedPOSTRequest", ErrorMessage: "The body of your POST request is not well-formed multipart/form-data.", StatusCode: http.StatusBadRequest}
 )
 
@@ -24,6 +24,23 @@ import (
 
 // register api routers
 func (o *ObjectNode) registerApiRouters(router *mux.Router) {
+	// Static website
+	// API reference: https://docs.aws.amazon.com/AmazonS3/latest/userguide/WebsiteHosting.html
+	// Notes: the website endpoints must be registered before the bucket routers which match any host.
+	for _, d := range o.websiteDomains {
+		for _, host := range []string{"{website:.+}." + d, "{website:.+}." + d + ":{port:[0-9]+}"} {
+			wRouter := router.Host(host).Subrouter()
+			wRouter.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetObjectAction)).
+				Methods(http.MethodGet).
+				Path("/{object:.*}").
+				HandlerFunc(o.websiteHandler)
+			wRouter.NewRoute().Name(ActionToUniqueRouteName(proto.OSSHeadObjectAction)).
+				Methods(http.MethodHead).
+				Path("/{object:.*}").
+				HandlerFunc(o.websiteHandler)
+		}
+	}
+
 	var bucketRouters []*mux.Router
 	bRouter := router.PathPrefix("/").Subrouter()
 	for _, d := range o.domains {
@@ -96,12 +113,11 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Get object legal hold
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectLegalHold.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetObjectLegalHoldAction)).
 			Methods(http.MethodGet).
 			Path("/{object:.+}").
 			Queries("legal-hold", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getObjectLegalHoldHandler)
 
 		// Get object retention
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectRetention.html
@@ -157,11 +173,10 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Get bucket policy status
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketPolicyStatus.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketPolicyStatusAction)).
 			Methods(http.MethodGet).
 			Queries("policyStatus", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketPolicyStatusHandler)
 
 		// Get bucket acl
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketAcl.html
@@ -179,11 +194,10 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Get bucket encryption
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketEncryption.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketEncryptionAction)).
 			Methods(http.MethodGet).
 			Queries("encryption", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketEncryptionHandler)
 
 		// Get bucket cors
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketCors.html
@@ -195,19 +209,17 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Get bucket website
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketWebsite.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketWebsiteAction)).
 			Methods(http.MethodGet).
 			Queries("website", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketWebsiteHandler)
 
 		// Get public access block
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetPublicAccessBlock.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetPublicAccessBlockAction)).
 			Methods(http.MethodGet).
 			Queries("publicAccessBlock", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getPublicAccessBlockHandler)
 
 		// Get bucket request payment
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketRequestPayment.html
@@ -219,11 +231,17 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Get bucket replication
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketReplication.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketReplicationAction)).
 			Methods(http.MethodGet).
 			Queries("replication", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketReplicationHandler)
+
+		// Get bucket notification
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketNotificationConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketNotificationAction)).
+			Methods(http.MethodGet).
+			Queries("notification", "").
+			HandlerFunc(o.getBucketNotificationHandler)
 
 		// Get bucket lifecycle
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLifecycle.html
@@ -242,19 +260,17 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Get bucket versioning
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketVersioning.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketVersioningAction)).
 			Methods(http.MethodGet).
 			Queries("versioning", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketVersioningHandler)
 
 		// List object versions
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectVersions.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSListObjectVersionsAction)).
 			Methods(http.MethodGet).
 			Queries("versions", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.listObjectVersionsHandler)
 
 		// List objects version 1
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjects.html
@@ -280,14 +296,21 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 			Queries("uploadId", "{uploadId:.*}").
 			HandlerFunc(o.completeMultipartUploadHandler)
 
+		// Select object content
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSSelectObjectContentAction)).
+			Methods(http.MethodPost).
+			Path("/{object:.+}").
+			Queries("select", "", "select-type", "2").
+			HandlerFunc(o.selectObjectContentHandler)
+
 		// Restore object
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_RestoreObject.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSRestoreObjectAction)).
 			Methods(http.MethodPost).
 			Path("/{object:.+}").
 			Queries("restore", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.restoreObjectHandler)
 
 		// Delete objects (multiple objects)
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObjects.html
@@ -362,21 +385,19 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Put object legal hold
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectLegalHold.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutObjectLegalHoldAction)).
 			Methods(http.MethodPut).
 			Path("/{object:.+}").
 			Queries("legal-hold", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putObjectLegalHoldHandler)
 
 		// Put object retention
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectRetention.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutObjectRetentionAction)).
 			Methods(http.MethodPut).
 			Path("/{object:.+}").
 			Queries("retention", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putObjectRetentionHandler)
 
 		// Put object
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObject.html
@@ -408,11 +429,10 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Put bucket encryption
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketEncryption.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketEncryptionAction)).
 			Methods(http.MethodPut).
 			Queries("encryption", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketEncryptionHandler)
 
 		// Put bucket cors
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketCors.html
@@ -424,19 +444,17 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Put bucket website
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketWebsite.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketWebsiteAction)).
 			Methods(http.MethodPut).
 			Queries("website", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketWebsiteHandler)
 
 		// Put public access block
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutPublicAccessBlock.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutPublicAccessBlockAction)).
 			Methods(http.MethodPut).
 			Queries("publicAccessBlock", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putPublicAccessBlockHandler)
 
 		// Put bucket request payment
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketRequestPayment.html
@@ -448,11 +466,17 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Put bucket replication
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketReplication.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketReplicationAction)).
 			Methods(http.MethodPut).
 			Queries("replication", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketReplicationHandler)
+
+		// Put bucket notification
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketNotificationConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketNotificationAction)).
+			Methods(http.MethodPut).
+			Queries("notification", "").
+			HandlerFunc(o.putBucketNotificationHandler)
 
 		// Put bucket lifecycle
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketLifecycle.html
@@ -471,11 +495,10 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Put bucket versioning
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketVersioning.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketVersioningAction)).
 			Methods(http.MethodPut).
 			Queries("versioning", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketVersioningHandler)
 
 		// Create bucket
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateBucket.html
@@ -532,11 +555,10 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Delete bucket encryption
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketEncryption.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeleteBucketEncryptionAction)).
 			Methods(http.MethodDelete).
 			Queries("encryption", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.deleteBucketEncryptionHandler)
 
 		// Delete bucket cors
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketCors.html
@@ -548,27 +570,24 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Delete bucket website
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketWebsite.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeleteBucketWebsiteAction)).
 			Methods(http.MethodDelete).
 			Queries("website", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.deleteBucketWebsiteHandler)
 
 		// Delete public access block
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeletePublicAccessBlock.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeletePublicAccessBlockAction)).
 			Methods(http.MethodDelete).
 			Queries("publicAccessBlock", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.deletePublicAccessBlockHandler)
 
 		// Delete bucket replication
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketReplication.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeleteBucketReplicationAction)).
 			Methods(http.MethodDelete).
 			Queries("replication", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.deleteBucketReplicationHandler)
 
 		// Delete bucket lifecycle
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketLifecycle.html
@@ -32,6 +32,7 @@ const (
 	DELETE_BUCKET_REPLICATION  = "DeleteBucketReplication"    // api:  Delete /?replication  , host=<bucket>.domain
 	DELETE_BUCKET_TAGGING      = "DeleteBucketTagging"        // api:  Delete /?tagging  , host=<bucket>.domain
 	DELETE_BUCKET_WEBSITE      = "DeleteBucketWebsite"        // api:  Delete /?website  , host=<bucket>.domain
+	DELETE_PUBLIC_ACCESS_BLOCK = "DeletePublicAccessBlock"    // api:  Delete /?publicAccessBlock  , host=<bucket>.domain
 	LIST_OBJECTS               = "ListObjects"                // api:  Get /  ,  host=<bucket>.domain ,  GetBucket version1
 	LIST_OBJECTS_V2            = "ListObjectsV2"              // api:  Get /?list-type=2, host=<bucket>.domain, GetBucket Version2
 	GET_BUCKET_ACCELERATE      = "GetBucketAccelerate"        // api:  GET /<bucketname>?accelerate
@@ -78,7 +79,12 @@ const (
 	GET_OBJECT_ACL             = "GetObjectAcl"               // api:  Get /<bucketname>/<objname>?acl   , host=<bucket>.domain
 	GET_OBJECT_TAGGING         = "GetObjectTagging"           // api:  Get /<bucketname>/<objname>?tagging   , host=<bucket>.domain
 	GET_OBJECT_RETENTION       = "GetObjectRetention"         // api:  Get /<bucketname>/<objname>?retention, host=<bucket>.domain
+	PUT_OBJECT_RETENTION       = "PutObjectRetention"         // api:  Put /<bucketname>/<objname>?retention, host=<bucket>.domain
+	GET_OBJECT_LEGAL_HOLD      = "GetObjectLegalHold"         // api:  Get /<bucketname>/<objname>?legal-hold, host=<bucket>.domain
+	PUT_OBJECT_LEGAL_HOLD      = "PutObjectLegalHold"         // api:  Put /<bucketname>/<objname>?legal-hold, host=<bucket>.domain
+	BYPASS_GOVERNANCE          = "BypassGovernanceRetention"  // permission: header["x-amz-bypass-governance-retention"]
 	HEAD_OBJECT                = "HeadObject"                 // api:  HEAD /<ObjectName> , host=<bucket>.domain
+	SELECT_OBJECT_CONTENT      = "SelectObjectContent"        // api:  POST /<ObjectName>?select&select-type=2 , host=<bucket>.domain
 	OPTIONS_OBJECT             = "OptionsObject"              // api:  OPTIONS /<ObjectName>, host=<bucket>.domain
 	POST_OBJECT                = "PostObject"                 // api:  Post /  , host=<bucket>.domain
 	PUT_OBJECT                 = "PutObject"                  // api:  Put  /<objname>,  host=<bucket>.domain
@@ -0,0 +1,788 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxSelectRequestSize    = 1 << 20   // 1MB
+	MaxSelectExpressionSize = 256 << 10 // 256KB
+	MaxSelectRecordSize     = 1 << 20   // 1MB
+
+	SelectExpressionTypeSQL = "SQL"
+
+	SelectCompressionNone  = "NONE"
+	SelectCompressionGzip  = "GZIP"
+	SelectCompressionBzip2 = "BZIP2"
+
+	SelectFileHeaderUse    = "USE"
+	SelectFileHeaderIgnore = "IGNORE"
+	SelectFileHeaderNone   = "NONE"
+
+	SelectJSONTypeDocument = "DOCUMENT"
+	SelectJSONTypeLines    = "LINES"
+
+	SelectQuoteFieldsAlways   = "ALWAYS"
+	SelectQuoteFieldsAsNeeded = "ASNEEDED"
+)
+
+var (
+	SelectErrExpressionType            = &ErrorCode{ErrorCode: "InvalidExpressionType", ErrorMessage: "The ExpressionType is invalid. Only SQL expressions are supported.", StatusCode: http.StatusBadRequest}
+	SelectErrExpressionTooLong         = &ErrorCode{ErrorCode: "ExpressionTooLong", ErrorMessage: "The SQL expression is too long: The maximum byte-length for the SQL expression is 256 KB.", StatusCode: http.StatusBadRequest}
+	SelectErrMissingExpression         = &ErrorCode{ErrorCode: "MissingRequiredParameter", ErrorMessage: "The SelectRequest entity is missing a required parameter Expression.", StatusCode: http.StatusBadRequest}
+	SelectErrInputSerialization        = &ErrorCode{ErrorCode: "InvalidRequestParameter", ErrorMessage: "The InputSerialization must specify exactly one of CSV or JSON.", StatusCode: http.StatusBadRequest}
+	SelectErrOutputSerialization       = &ErrorCode{ErrorCode: "InvalidRequestParameter", ErrorMessage: "The OutputSerialization must specify exactly one of CSV or JSON.", StatusCode: http.StatusBadRequest}
+	SelectErrCompressionFormat         = &ErrorCode{ErrorCode: "InvalidCompressionFormat", ErrorMessage: "The file is not in a supported compression format. Only GZIP and BZIP2 are supported.", StatusCode: http.StatusBadRequest}
+	SelectErrFileHeaderInfo            = &ErrorCode{ErrorCode: "InvalidFileHeaderInfo", ErrorMessage: "The FileHeaderInfo is invalid. Only NONE, USE, and IGNORE are supported.", StatusCode: http.StatusBadRequest}
+	SelectErrJSONType                  = &ErrorCode{ErrorCode: "InvalidJsonType", ErrorMessage: "The JsonType is invalid. Only DOCUMENT and LINES are supported.", StatusCode: http.StatusBadRequest}
+	SelectErrQuoteFields               = &ErrorCode{ErrorCode: "InvalidQuoteFields", ErrorMessage: "The QuoteFields is invalid. Only ALWAYS and ASNEEDED are supported.", StatusCode: http.StatusBadRequest}
+	SelectErrDelimiter                 = &ErrorCode{ErrorCode: "InvalidRequestParameter", ErrorMessage: "The delimiters and quote characters must be a single character.", StatusCode: http.StatusBadRequest}
+	SelectErrUnsupportedParquet        = &ErrorCode{ErrorCode: "NotImplemented", ErrorMessage: "The Parquet input is not supported.", StatusCode: http.StatusNotImplemented}
+	SelectErrUnsupportedScanRange      = &ErrorCode{ErrorCode: "NotImplemented", ErrorMessage: "The ScanRange is not supported.", StatusCode: http.StatusNotImplemented}
+	SelectErrParse                     = &ErrorCode{ErrorCode: "ParseUnexpectedToken", ErrorMessage: "The SQL expression contains an unexpected token.", StatusCode: http.StatusBadRequest}
+	SelectErrUnsupportedFunction       = &ErrorCode{ErrorCode: "UnsupportedFunction", ErrorMessage: "Encountered an unsupported SQL function.", StatusCode: http.StatusBadRequest}
+	SelectErrUnsupportedSQLStructure   = &ErrorCode{ErrorCode: "UnsupportedSqlStructure", ErrorMessage: "Encountered an unsupported SQL structure.", StatusCode: http.StatusBadRequest}
+	SelectErrEvaluatorInvalidArguments = &ErrorCode{ErrorCode: "EvaluatorInvalidArguments", ErrorMessage: "Incorrect number of arguments in the function call in the SQL expression.", StatusCode: http.StatusBadRequest}
+	SelectErrInvalidCast               = &ErrorCode{ErrorCode: "InvalidCast", ErrorMessage: "Attempt to convert from one data type to another using CAST failed in the SQL expression.", StatusCode: http.StatusBadRequest}
+	SelectErrInvalidDataType           = &ErrorCode{ErrorCode: "InvalidDataType", ErrorMessage: "The SQL expression contains an invalid data type.", StatusCode: http.StatusBadRequest}
+	SelectErrInvalidColumnIndex        = &ErrorCode{ErrorCode: "InvalidColumnIndex", ErrorMessage: "The column index is invalid.", StatusCode: http.StatusBadRequest}
+	SelectErrBindingDoesNotExist       = &ErrorCode{ErrorCode: "EvaluatorBindingDoesNotExist", ErrorMessage: "A column name or a path provided does not exist in the SQL expression.", StatusCode: http.StatusBadRequest}
+	SelectErrCSVParsing                = &ErrorCode{ErrorCode: "CSVParsingError", ErrorMessage: "Encountered an error parsing the CSV file.", StatusCode: http.StatusBadRequest}
+	SelectErrJSONParsing               = &ErrorCode{ErrorCode: "JSONParsingError", ErrorMessage: "Encountered an error parsing the JSON file.", StatusCode: http.StatusBadRequest}
+	SelectErrOverMaxRecordSize         = &ErrorCode{ErrorCode: "OverMaxRecordSize", ErrorMessage: "The length of a record in the input or result is greater than maxCharsPerRecord of 1 MB.", StatusCode: http.StatusBadRequest}
+)
+
+// selectError returns a copy of the error code with the detailed message.
+func selectError(ec *ErrorCode, format string, args ...interface{}) *ErrorCode {
+	err := ec.Copy()
+	err.ErrorMessage = fmt.Sprintf(format, args...)
+	return err
+}
+
+type SelectObjectContentRequest struct {
+	XMLName             xml.Name                  `xml:"SelectObjectContentRequest"`
+	Expression          string                    `xml:"Expression"`
+	ExpressionType      string                    `xml:"ExpressionType"`
+	RequestProgress     *SelectRequestProgress    `xml:"RequestProgress,omitempty"`
+	InputSerialization  SelectInputSerialization  `xml:"InputSerialization"`
+	OutputSerialization SelectOutputSerialization `xml:"OutputSerialization"`
+	ScanRange           *SelectScanRange          `xml:"ScanRange,omitempty"`
+
+	query *selectQuery
+}
+
+type SelectRequestProgress struct {
+	Enabled bool `xml:"Enabled"`
+}
+
+type SelectInputSerialization struct {
+	CompressionType string           `xml:"CompressionType,omitempty"`
+	CSV             *SelectCSVInput  `xml:"CSV,omitempty"`
+	JSON            *SelectJSONInput `xml:"JSON,omitempty"`
+	Parquet         *struct{}        `xml:"Parquet,omitempty"`
+}
+
+type SelectCSVInput struct {
+	AllowQuotedRecordDelimiter bool   `xml:"AllowQuotedRecordDelimiter,omitempty"`
+	Comments                   string `xml:"Comments,omitempty"`
+	FieldDelimiter             string `xml:"FieldDelimiter,omitempty"`
+	FileHeaderInfo             string `xml:"FileHeaderInfo,omitempty"`
+	QuoteCharacter             string `xml:"QuoteCharacter,omitempty"`
+	QuoteEscapeCharacter       string `xml:"QuoteEscapeCharacter,omitempty"`
+	RecordDelimiter            string `xml:"RecordDelimiter,omitempty"`
+}
+
+type SelectJSONInput struct {
+	Type string `xml:"Type,omitempty"`
+}
+
+type SelectOutputSerialization struct {
+	CSV  *SelectCSVOutput  `xml:"CSV,omitempty"`
+	JSON *SelectJSONOutput `xml:"JSON,omitempty"`
+}
+
+type SelectCSVOutput struct {
+	FieldDelimiter       string `xml:"FieldDelimiter,omitempty"`
+	QuoteCharacter       string `xml:"QuoteCharacter,omitempty"`
+	QuoteEscapeCharacter string `xml:"QuoteEscapeCharacter,omitempty"`
+	QuoteFields          string `xml:"QuoteFields,omitempty"`
+	RecordDelimiter      string `xml:"RecordDelimiter,omitempty"`
+}
+
+type SelectJSONOutput struct {
+	RecordDelimiter string `xml:"RecordDelimiter,omitempty"`
+}
+
+type SelectScanRange struct {
+	Start *int64 `xml:"Start,omitempty"`
+	End   *int64 `xml:"End,omitempty"`
+}
+
+type SelectStats struct {
+	BytesScanned   int64 `xml:"BytesScanned"`
+	BytesProcessed int64 `xml:"BytesProcessed"`
+	BytesReturned  int64 `xml:"BytesReturned"`
+}
+
+type SelectStatsMessage struct {
+	XMLName xml.Name `xml:"Stats"`
+	SelectStats
+}
+
+type SelectProgressMessage struct {
+	XMLName xml.Name `xml:"Progress"`
+	SelectStats
+}
+
+func ParseSelectRequest(data []byte) (*SelectObjectContentRequest, error) {
+	req := &SelectObjectContentRequest{}
+	if err := xml.Unmarshal(data, req); err != nil {
+		return nil, MalformedXML
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	query, err := parseSelectSQL(req.Expression)
+	if err != nil {
+		return nil, err
+	}
+	req.query = query
+	return req, nil
+}
+
+func (req *SelectObjectContentRequest) Validate() error {
+	if req.Expression == "" {
+		return SelectErrMissingExpression
+	}
+	if len(req.Expression) > MaxSelectExpressionSize {
+		return SelectErrExpressionTooLong
+	}
+	if !strings.EqualFold(req.ExpressionType, SelectExpressionTypeSQL) {
+		return SelectErrExpressionType
+	}
+	if req.ScanRange != nil {
+		return SelectErrUnsupportedScanRange
+	}
+
+	input := &req.InputSerialization
+	switch strings.ToUpper(input.CompressionType) {
+	case "", SelectCompressionNone, SelectCompressionGzip, SelectCompressionBzip2:
+	default:
+		return SelectErrCompressionFormat
+	}
+	if input.Parquet != nil {
+		return SelectErrUnsupportedParquet
+	}
+	if (input.CSV == nil) == (input.JSON == nil) {
+		return SelectErrInputSerialization
+	}
+	if csv := input.CSV; csv != nil {
+		switch strings.ToUpper(csv.FileHeaderInfo) {
+		case "", SelectFileHeaderUse, SelectFileHeaderIgnore, SelectFileHeaderNone:
+		default:
+			return SelectErrFileHeaderInfo
+		}
+		for _, c := range []string{csv.Comments, csv.FieldDelimiter, csv.QuoteCharacter, csv.QuoteEscapeCharacter} {
+			if utf8.RuneCountInString(c) > 1 {
+				return SelectErrDelimiter
+			}
+		}
+		if utf8.RuneCountInString(csv.RecordDelimiter) > 1 && csv.RecordDelimiter != "\r\n" {
+			return SelectErrDelimiter
+		}
+	}
+	if input.JSON != nil {
+		switch strings.ToUpper(input.JSON.Type) {
+		case "", SelectJSONTypeDocument, SelectJSONTypeLines:
+		default:
+			return SelectErrJSONType
+		}
+	}
+
+	output := &req.OutputSerialization
+	if (output.CSV == nil) == (output.JSON == nil) {
+		return SelectErrOutputSerialization
+	}
+	if csv := output.CSV; csv != nil {
+		switch strings.ToUpper(csv.QuoteFields) {
+		case "", SelectQuoteFieldsAlways, SelectQuoteFieldsAsNeeded:
+		default:
+			return SelectErrQuoteFields
+		}
+		for _, c := range []string{csv.FieldDelimiter, csv.QuoteCharacter, csv.QuoteEscapeCharacter} {
+			if utf8.RuneCountInString(c) > 1 {
+				return SelectErrDelimiter
+			}
+		}
+	}
+	return nil
+}
+
+func (req *SelectObjectContentRequest) progressEnabled() bool {
+	return req.RequestProgress != nil && req.RequestProgress.Enabled
+}
+
+// selectCountingReader counts the bytes read from the underlying reader.
+type selectCountingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *selectCountingReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.n += int64(n)
+	return
+}
+
+// decompressSelectInput returns the reader of the uncompressed content of the object.
+func decompressSelectInput(compression string, r io.Reader) (io.Reader, error) {
+	switch strings.ToUpper(compression) {
+	case SelectCompressionGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, selectError(SelectErrCompressionFormat, "The GZIP stream is invalid: %v", err)
+		}
+		return zr, nil
+	case SelectCompressionBzip2:
+		return bzip2.NewReader(r), nil
+	default:
+		return r, nil
+	}
+}
+
+// selectRecordReader reads the records of the object, io.EOF is returned at the end of the object.
+type selectRecordReader interface {
+	Read() (selectRecord, error)
+}
+
+func newSelectRecordReader(input *SelectInputSerialization, query *selectQuery, r io.Reader) (selectRecordReader, error) {
+	if input.CSV != nil {
+		return newSelectCSVReader(input.CSV, r)
+	}
+	return newSelectJSONReader(query, r), nil
+}
+
+func firstRune(s string, def rune) rune {
+	if s == "" {
+		return def
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+type selectCSVHeader struct {
+	names []string
+}
+
+// selectCSVRecord is a record of CSV, the fields are referred by the positions like _1 or the names of the header.
+type selectCSVRecord struct {
+	header *selectCSVHeader // nil if the header is not used
+	fields []string
+}
+
+func (r *selectCSVRecord) Get(path []selectPathElem) (interface{}, error) {
+	if len(path) != 1 || path[0].isIndex() {
+		return nil, selectError(SelectErrBindingDoesNotExist, "nested path is not supported by CSV")
+	}
+	elem := path[0]
+	if !elem.quoted && strings.HasPrefix(elem.name, "_") {
+		if index, err := strconv.Atoi(elem.name[1:]); err == nil {
+			if index < 1 {
+				return nil, selectError(SelectErrInvalidColumnIndex, "invalid column index %v", elem.name)
+			}
+			if index > len(r.fields) {
+				return nil, nil
+			}
+			return r.fields[index-1], nil
+		}
+	}
+	if r.header != nil {
+		for i, name := range r.header.names {
+			if elem.match(name) {
+				if i >= len(r.fields) {
+					return nil, nil
+				}
+				return r.fields[i], nil
+			}
+		}
+	}
+	return nil, selectError(SelectErrBindingDoesNotExist, "column %v does not exist", elem.name)
+}
+
+func (r *selectCSVRecord) Columns() ([]string, []interface{}) {
+	names := make([]string, len(r.fields))
+	values := make([]interface{}, len(r.fields))
+	for i, field := range r.fields {
+		if r.header != nil && i < len(r.header.names) {
+			names[i] = r.header.names[i]
+		} else {
+			names[i] = "_" + strconv.Itoa(i+1)
+		}
+		values[i] = field
+	}
+	return names, values
+}
+
+// selectCSVReader parses the CSV records with the configured delimiters and quote characters.
+type selectCSVReader struct {
+	reader         *bufio.Reader
+	fieldDelimiter rune
+	recordDelim    rune
+	crlf           bool
+	quote          rune
+	escape         rune
+	comment        rune
+	header         *selectCSVHeader
+}
+
+func newSelectCSVReader(conf *SelectCSVInput, r io.Reader) (*selectCSVReader, error) {
+	reader := &selectCSVReader{
+		reader:         bufio.NewReader(r),
+		fieldDelimiter: firstRune(conf.FieldDelimiter, ','),
+		recordDelim:    firstRune(conf.RecordDelimiter, '\n'),
+		quote:          firstRune(conf.QuoteCharacter, '"'),
+		escape:         firstRune(conf.QuoteEscapeCharacter, '"'),
+		comment:        firstRune(conf.Comments, 0),
+	}
+	if conf.RecordDelimiter == "" || conf.RecordDelimiter == "\n" || conf.RecordDelimiter == "\r\n" {
+		reader.recordDelim, reader.crlf = '\n', true
+	}
+	switch strings.ToUpper(conf.FileHeaderInfo) {
+	case SelectFileHeaderUse, SelectFileHeaderIgnore:
+		fields, err := reader.readFields()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if strings.ToUpper(conf.FileHeaderInfo) == SelectFileHeaderUse {
+			reader.header = &selectCSVHeader{names: fields}
+		}
+	}
+	return reader, nil
+}
+
+func (r *selectCSVReader) Read() (selectRecord, error) {
+	fields, err := r.readFields()
+	if err != nil {
+		return nil, err
+	}
+	return &selectCSVRecord{header: r.header, fields: fields}, nil
+}
+
+// readFields reads the fields of the next record, the empty lines and the comment lines are skipped.
+func (r *selectCSVReader) readFields() ([]string, error) {
+	for {
+		fields, empty, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if !empty {
+			return fields, nil
+		}
+	}
+}
+
+func (r *selectCSVReader) readLine() (fields []string, empty bool, err error) {
+	var (
+		field     strings.Builder
+		size      int
+		read      bool
+		inQuote   bool
+		quoted    bool
+		isComment bool
+	)
+	finishField := func() {
+		fields = append(fields, field.String())
+		field.Reset()
+		quoted = false
+	}
+	for {
+		c, n, err := r.reader.ReadRune()
+		if err == io.EOF {
+			if !read {
+				return nil, false, io.EOF
+			}
+			if inQuote {
+				return nil, false, selectError(SelectErrCSVParsing, "unterminated quoted field")
+			}
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if size += n; size > MaxSelectRecordSize {
+			return nil, false, SelectErrOverMaxRecordSize
+		}
+		if !read && r.comment != 0 && c == r.comment {
+			isComment = true
+		}
+		read = true
+		if isComment {
+			if c == r.recordDelim {
+				return nil, true, nil
+			}
+			continue
+		}
+		if inQuote {
+			switch {
+			case c == r.escape && r.escape != r.quote:
+				next, _, err := r.reader.ReadRune()
+				if err != nil {
+					return nil, false, selectError(SelectErrCSVParsing, "unterminated quoted field")
+				}
+				if next != r.quote && next != r.escape {
+					field.WriteRune(c)
+				}
+				field.WriteRune(next)
+			case c == r.quote:
+				next, _, err := r.reader.ReadRune()
+				if err == nil && next == r.quote {
+					field.WriteRune(c)
+					continue
+				}
+				if err == nil {
+					_ = r.reader.UnreadRune()
+				}
+				inQuote = false
+			default:
+				field.WriteRune(c)
+			}
+			continue
+		}
+		// CRLF is regarded as the record delimiter as well as LF
+		if c == '\r' && r.crlf {
+			if next, _, err := r.reader.ReadRune(); err == nil {
+				if next == '\n' {
+					c = next
+				} else {
+					_ = r.reader.UnreadRune()
+				}
+			}
+		}
+		switch {
+		case c == r.quote && field.Len() == 0 && !quoted:
+			inQuote, quoted = true, true
+		case c == r.fieldDelimiter:
+			finishField()
+		case c == r.recordDelim:
+			if len(fields) == 0 && field.Len() == 0 && !quoted {
+				return nil, true, nil
+			}
+			finishField()
+			return fields, false, nil
+		default:
+			field.WriteRune(c)
+		}
+	}
+	if isComment {
+		return nil, true, nil
+	}
+	if len(fields) == 0 && !quoted && field.Len() == 0 {
+		return nil, true, nil
+	}
+	finishField()
+	return fields, false, nil
+}
+
+// selectJSONObject is the object of JSON, which keeps the order of the keys.
+type selectJSONObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *selectJSONObject) get(key string, elem selectPathElem) (interface{}, bool) {
+	if v, ok := o.values[key]; ok {
+		return v, true
+	}
+	if elem.quoted {
+		return nil, false
+	}
+	for _, k := range o.keys {
+		if elem.match(k) {
+			return o.values[k], true
+		}
+	}
+	return nil, false
+}
+
+func (o *selectJSONObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := marshalSelectJSON(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func marshalSelectJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// decodeSelectJSON decodes the next JSON value, the numbers are decoded as int64 or float64.
+func decodeSelectJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := &selectJSONObject{values: make(map[string]interface{})}
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, noSelectEOF(err)
+				}
+				key, _ := keyToken.(string)
+				value, err := decodeSelectJSON(decoder)
+				if err != nil {
+					return nil, noSelectEOF(err)
+				}
+				if _, ok := obj.values[key]; !ok {
+					obj.keys = append(obj.keys, key)
+				}
+				obj.values[key] = value
+			}
+			_, err = decoder.Token()
+			return obj, noSelectEOF(err)
+		case '[':
+			array := make([]interface{}, 0)
+			for decoder.More() {
+				value, err := decodeSelectJSON(decoder)
+				if err != nil {
+					return nil, noSelectEOF(err)
+				}
+				array = append(array, value)
+			}
+			_, err = decoder.Token()
+			return array, noSelectEOF(err)
+		default:
+			return nil, selectError(SelectErrJSONParsing, "unexpected delimiter %v", t)
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	default:
+		return t, nil
+	}
+}
+
+// noSelectEOF converts EOF to ErrUnexpectedEOF, the EOF in the middle of a value is not the end of input.
+func noSelectEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// getSelectJSONPath returns the value of the path in the JSON value, nil is returned if it does not exist.
+func getSelectJSONPath(v interface{}, path []selectPathElem) interface{} {
+	for _, elem := range path {
+		switch t := v.(type) {
+		case *selectJSONObject:
+			if elem.isIndex() {
+				return nil
+			}
+			var ok bool
+			if v, ok = t.get(elem.name, elem); !ok {
+				return nil
+			}
+		case []interface{}:
+			if !elem.isIndex() || elem.index >= len(t) {
+				return nil
+			}
+			v = t[elem.index]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+type selectJSONRecord struct {
+	value interface{}
+}
+
+func (r *selectJSONRecord) Get(path []selectPathElem) (interface{}, error) {
+	return getSelectJSONPath(r.value, path), nil
+}
+
+func (r *selectJSONRecord) Columns() ([]string, []interface{}) {
+	if obj, ok := r.value.(*selectJSONObject); ok {
+		values := make([]interface{}, len(obj.keys))
+		for i, key := range obj.keys {
+			values[i] = obj.values[key]
+		}
+		return obj.keys, values
+	}
+	return []string{"_1"}, []interface{}{r.value}
+}
+
+// selectJSONReader reads the JSON values of the document or lines. The values which are specified
+// by the path following 'S3Object[*]' are the records, and the elements of the arrays are iterated.
+type selectJSONReader struct {
+	decoder  *json.Decoder
+	wildcard bool
+	path     []selectPathElem
+	pending  []interface{}
+}
+
+func newSelectJSONReader(query *selectQuery, r io.Reader) *selectJSONReader {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return &selectJSONReader{decoder: decoder, wildcard: query.wildcard, path: query.fromPath}
+}
+
+func (r *selectJSONReader) Read() (selectRecord, error) {
+	for len(r.pending) == 0 {
+		value, err := decodeSelectJSON(r.decoder)
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			if _, ok := err.(*ErrorCode); ok {
+				return nil, err
+			}
+			return nil, selectError(SelectErrJSONParsing, "invalid JSON: %v", err)
+		}
+		value = getSelectJSONPath(value, r.path)
+		if array, ok := value.([]interface{}); ok && r.wildcard {
+			r.pending = array
+		} else if value != nil {
+			r.pending = []interface{}{value}
+		}
+	}
+	value := r.pending[0]
+	r.pending = r.pending[1:]
+	return &selectJSONRecord{value: value}, nil
+}
+
+// selectRecordWriter serializes the output records.
+type selectRecordWriter interface {
+	Write(buf *bytes.Buffer, names []string, values []interface{}) error
+}
+
+func newSelectRecordWriter(output *SelectOutputSerialization) selectRecordWriter {
+	if csv := output.CSV; csv != nil {
+		recordDelimiter := csv.RecordDelimiter
+		if recordDelimiter == "" {
+			recordDelimiter = "\n"
+		}
+		return &selectCSVWriter{
+			fieldDelimiter:  firstRune(csv.FieldDelimiter, ','),
+			recordDelimiter: recordDelimiter,
+			quote:           firstRune(csv.QuoteCharacter, '"'),
+			escape:          firstRune(csv.QuoteEscapeCharacter, '"'),
+			always:          strings.ToUpper(csv.QuoteFields) == SelectQuoteFieldsAlways,
+		}
+	}
+	recordDelimiter := output.JSON.RecordDelimiter
+	if recordDelimiter == "" {
+		recordDelimiter = "\n"
+	}
+	return &selectJSONWriter{recordDelimiter: recordDelimiter}
+}
+
+type selectCSVWriter struct {
+	fieldDelimiter  rune
+	recordDelimiter string
+	quote           rune
+	escape          rune
+	always          bool
+}
+
+func (w *selectCSVWriter) Write(buf *bytes.Buffer, names []string, values []interface{}) error {
+	for i, v := range values {
+		if i > 0 {
+			buf.WriteRune(w.fieldDelimiter)
+		}
+		s := formatSelectValue(v)
+		if !w.always && !strings.ContainsRune(s, w.fieldDelimiter) && !strings.ContainsRune(s, w.quote) &&
+			!strings.Contains(s, w.recordDelimiter) && !strings.ContainsAny(s, "\r\n") {
+			buf.WriteString(s)
+			continue
+		}
+		buf.WriteRune(w.quote)
+		for _, c := range s {
+			if c == w.quote || c == w.escape && w.escape != w.quote {
+				buf.WriteRune(w.escape)
+			}
+			buf.WriteRune(c)
+		}
+		buf.WriteRune(w.quote)
+	}
+	buf.WriteString(w.recordDelimiter)
+	return nil
+}
+
+type selectJSONWriter struct {
+	recordDelimiter string
+}
+
+func (w *selectJSONWriter) Write(buf *bytes.Buffer, names []string, values []interface{}) error {
+	obj := &selectJSONObject{keys: append([]string(nil), names...), values: make(map[string]interface{}, len(names))}
+	keys := make(map[string]bool, len(names))
+	for i, name := range names {
+		// the duplicated names are renamed by the positions
+		if keys[name] {
+			name = "_" + strconv.Itoa(i+1)
+			obj.keys[i] = name
+		}
+		keys[name] = true
+		obj.values[name] = values[i]
+	}
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	buf.WriteString(w.recordDelimiter)
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"time"
+)
+
+// The response of SelectObjectContent is a stream of the event messages.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTSelectObjectAppendix.html
+const (
+	selectEventHeaderMessageType  = ":message-type"
+	selectEventHeaderEventType    = ":event-type"
+	selectEventHeaderContentType  = ":content-type"
+	selectEventHeaderErrorCode    = ":error-code"
+	selectEventHeaderErrorMessage = ":error-message"
+
+	selectMessageTypeEvent = "event"
+	selectMessageTypeError = "error"
+
+	selectEventRecords  = "Records"
+	selectEventStats    = "Stats"
+	selectEventProgress = "Progress"
+	selectEventCont     = "Cont"
+	selectEventEnd      = "End"
+
+	selectHeaderValueTypeString = 7
+
+	// the size of the records payload which is sent in a message
+	selectRecordsMessageSize = 128 << 10
+	// the interval of the continuation or progress messages which keep the connection alive
+	selectKeepAliveInterval = 5 * time.Second
+)
+
+type selectEventHeader struct {
+	name  string
+	value string
+}
+
+// encodeSelectEventMessage encodes the message with the prelude, headers, payload and CRC checksums.
+func encodeSelectEventMessage(headers []selectEventHeader, payload []byte) []byte {
+	headersLen := 0
+	for _, h := range headers {
+		headersLen += 1 + len(h.name) + 1 + 2 + len(h.value)
+	}
+	totalLen := 4 + 4 + 4 + headersLen + len(payload) + 4
+	msg := make([]byte, totalLen)
+	binary.BigEndian.PutUint32(msg[0:], uint32(totalLen))
+	binary.BigEndian.PutUint32(msg[4:], uint32(headersLen))
+	binary.BigEndian.PutUint32(msg[8:], crc32.ChecksumIEEE(msg[:8]))
+	off := 12
+	for _, h := range headers {
+		msg[off] = byte(len(h.name))
+		off++
+		off += copy(msg[off:], h.name)
+		msg[off] = selectHeaderValueTypeString
+		off++
+		binary.BigEndian.PutUint16(msg[off:], uint16(len(h.value)))
+		off += 2
+		off += copy(msg[off:], h.value)
+	}
+	off += copy(msg[off:], payload)
+	binary.BigEndian.PutUint32(msg[off:], crc32.ChecksumIEEE(msg[:off]))
+	return msg
+}
+
+// selectEventWriter writes the event messages to the response.
+type selectEventWriter struct {
+	w        io.Writer
+	lastSent time.Time
+}
+
+func newSelectEventWriter(w io.Writer) *selectEventWriter {
+	return &selectEventWriter{w: w, lastSent: time.Now()}
+}
+
+func (ew *selectEventWriter) write(headers []selectEventHeader, payload []byte) error {
+	if _, err := ew.w.Write(encodeSelectEventMessage(headers, payload)); err != nil {
+		return err
+	}
+	if flusher, ok := ew.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	ew.lastSent = time.Now()
+	return nil
+}
+
+func (ew *selectEventWriter) writeEvent(eventType, contentType string, payload []byte) error {
+	headers := []selectEventHeader{
+		{name: selectEventHeaderMessageType, value: selectMessageTypeEvent},
+		{name: selectEventHeaderEventType, value: eventType},
+	}
+	if contentType != "" {
+		headers = append(headers, selectEventHeader{name: selectEventHeaderContentType, value: contentType})
+	}
+	return ew.write(headers, payload)
+}
+
+func (ew *selectEventWriter) writeRecords(payload []byte) error {
+	return ew.writeEvent(selectEventRecords, ValueContentTypeStream, payload)
+}
+
+func (ew *selectEventWriter) writeStats(stats SelectStats) error {
+	data, err := MarshalXMLEntity(&SelectStatsMessage{SelectStats: stats})
+	if err != nil {
+		return err
+	}
+	return ew.writeEvent(selectEventStats, ValueContentTypeXML, data)
+}
+
+func (ew *selectEventWriter) writeProgress(stats SelectStats) error {
+	data, err := MarshalXMLEntity(&SelectProgressMessage{SelectStats: stats})
+	if err != nil {
+		return err
+	}
+	return ew.writeEvent(selectEventProgress, ValueContentTypeXML, data)
+}
+
+func (ew *selectEventWriter) writeCont() error {
+	return ew.writeEvent(selectEventCont, "", nil)
+}
+
+func (ew *selectEventWriter) writeEnd() error {
+	return ew.writeEvent(selectEventEnd, "", nil)
+}
+
+func (ew *selectEventWriter) writeError(ec *ErrorCode) error {
+	return ew.write([]selectEventHeader{
+		{name: selectEventHeaderErrorCode, value: ec.ErrorCode},
+		{name: selectEventHeaderErrorMessage, value: ec.ErrorMessage},
+		{name: selectEventHeaderMessageType, value: selectMessageTypeError},
+	}, nil)
+}
+
+// idle reports whether no message has been sent for the keep alive interval.
+func (ew *selectEventWriter) idle() bool {
+	return time.Since(ew.lastSent) >= selectKeepAliveInterval
+}
@@ -0,0 +1,234 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/cubefs/cubefs/blobstore/common/trace"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// Select object content
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+func (o *ObjectNode) selectObjectContentHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+
+	span := trace.SpanFromContextSafe(r.Context())
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	if param.Object() == "" || isVersionsKey(param.Object()) {
+		errorCode = InvalidKey
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("selectObjectContentHandler: load volume fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), param.Object(), err)
+		return
+	}
+
+	// QPS and Concurrency Limit
+	rateLimit := o.AcquireRateLimiter()
+	if err = rateLimit.AcquireLimitResource(vol.owner, param.apiName); err != nil {
+		return
+	}
+	defer rateLimit.ReleaseLimitResource(vol.owner, param.apiName)
+
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(r.Body, MaxSelectRequestSize+1)); err != nil {
+		log.LogErrorf("selectObjectContentHandler: read request body fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		return
+	}
+	if len(body) > MaxSelectRequestSize {
+		errorCode = EntityTooLarge
+		return
+	}
+	var req *SelectObjectContentRequest
+	if req, err = ParseSelectRequest(body); err != nil {
+		log.LogErrorf("selectObjectContentHandler: parse select request fail: requestID(%v) volume(%v) path(%v) request(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), string(body), err)
+		return
+	}
+
+	start := time.Now()
+	fileInfo, _, err := vol.ObjectMeta(param.Object())
+	span.AppendTrackLog("meta.r", start, err)
+	if err != nil {
+		log.LogErrorf("selectObjectContentHandler: get file meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		if err == syscall.ENOENT {
+			errorCode = NoSuchKey
+		}
+		return
+	}
+	if fileInfo.DeleteMarker || fileInfo.Mode.IsDir() {
+		errorCode = NoSuchKey
+		return
+	}
+
+	// the data key of encrypted object is required to read the object
+	if errorCode, err = unsealObjectKey(r, fileInfo.Encryption, false); errorCode != nil || err != nil {
+		log.LogErrorf("selectObjectContentHandler: unseal object key fail: requestID(%v) volume(%v) path(%v) errorCode(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), errorCode, err)
+		return
+	}
+
+	// the content is streamed from the volume through the pipe
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		readErr := vol.readObject(fileInfo, param.Object(), pw, 0, uint64(fileInfo.Size))
+		_ = pw.CloseWithError(readErr)
+	}()
+
+	// the errors occur after the response header is sent are returned as error messages
+	w.Header().Set(ContentType, ValueContentTypeStream)
+	w.WriteHeader(http.StatusOK)
+	ew := newSelectEventWriter(w)
+	start = time.Now()
+	selectErr := runSelectQuery(ew, req, pr)
+	span.AppendTrackLog("select", start, selectErr)
+	if selectErr != nil {
+		log.LogErrorf("selectObjectContentHandler: select fail: requestID(%v) volume(%v) path(%v) expression(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), req.Expression, selectErr)
+		ec, ok := selectErr.(*ErrorCode)
+		if !ok {
+			ec = InternalErrorCode(selectErr)
+		}
+		if err = ew.writeError(ec); err != nil {
+			log.LogWarnf("selectObjectContentHandler: write error message fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), vol.Name(), param.Object(), err)
+		}
+		err = nil
+	}
+	return
+}
+
+// runSelectQuery evaluates the query over the records read from r, and writes the results,
+// progress and stats as event messages.
+func runSelectQuery(ew *selectEventWriter, req *SelectObjectContentRequest, r io.Reader) error {
+	scanned := &selectCountingReader{reader: r}
+	input, err := decompressSelectInput(req.InputSerialization.CompressionType, scanned)
+	if err != nil {
+		return err
+	}
+	processed := &selectCountingReader{reader: input}
+	reader, err := newSelectRecordReader(&req.InputSerialization, req.query, processed)
+	if err != nil {
+		return err
+	}
+	writer := newSelectRecordWriter(&req.OutputSerialization)
+
+	var (
+		query    = req.query
+		buf      bytes.Buffer
+		returned int64
+		count    int64
+	)
+	stats := func() SelectStats {
+		return SelectStats{BytesScanned: scanned.n, BytesProcessed: processed.n, BytesReturned: returned}
+	}
+	write := func(names []string, values []interface{}) error {
+		if err := writer.Write(&buf, names, values); err != nil {
+			return err
+		}
+		count++
+		if buf.Len() < selectRecordsMessageSize {
+			return nil
+		}
+		returned += int64(buf.Len())
+		err := ew.writeRecords(buf.Bytes())
+		buf.Reset()
+		return err
+	}
+
+	for query.isAggregate() || query.limit < 0 || count < query.limit {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		matched, err := query.match(record)
+		if err != nil {
+			return err
+		}
+		if matched && query.isAggregate() {
+			if err = query.accumulate(record); err != nil {
+				return err
+			}
+		} else if matched {
+			names, values, err := query.project(record)
+			if err != nil {
+				return err
+			}
+			if err = write(names, values); err != nil {
+				return err
+			}
+		}
+		// keep the connection alive while scanning the records which are not matched
+		if ew.idle() {
+			if req.progressEnabled() {
+				err = ew.writeProgress(stats())
+			} else {
+				err = ew.writeCont()
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if query.isAggregate() && query.limit != 0 {
+		names, values, err := query.project(nil)
+		if err != nil {
+			return err
+		}
+		if err = write(names, values); err != nil {
+			return err
+		}
+	}
+	if buf.Len() > 0 {
+		returned += int64(buf.Len())
+		if err = ew.writeRecords(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if req.progressEnabled() {
+		if err = ew.writeProgress(stats()); err != nil {
+			return err
+		}
+	}
+	if err = ew.writeStats(stats()); err != nil {
+		return err
+	}
+	return ew.writeEnd()
+}
@@ -0,0 +1,1537 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// The SQL subset of S3 Select which is supported:
+//
+//	SELECT * | expr [[AS] alias], ...
+//	FROM S3Object[[*][.path]] [[AS] alias]
+//	[WHERE condition]
+//	[LIMIT number]
+//
+// Expressions are composed of the literals (numbers, 'strings', TRUE, FALSE, NULL), the columns
+// (_1, name, s.name, s."Quoted Name", s.a.b[0]), the operators (OR, AND, NOT, =, !=, <>, <, <=,
+// >, >=, +, -, *, /, %, ||, [NOT] LIKE ... [ESCAPE ...], [NOT] BETWEEN ... AND ..., [NOT] IN (...),
+// IS [NOT] NULL), the scalar functions (LOWER, UPPER, CHAR_LENGTH, CHARACTER_LENGTH, TRIM,
+// SUBSTRING, COALESCE, NULLIF, CAST) and the aggregate functions (COUNT, SUM, AVG, MIN, MAX).
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlTokenEOF sqlTokenKind = iota
+	sqlTokenIdent
+	sqlTokenQuotedIdent
+	sqlTokenString
+	sqlTokenNumber
+	sqlTokenOperator
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	pos  int
+}
+
+var sqlKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "LIMIT": true, "AS": true, "AND": true, "OR": true,
+	"NOT": true, "LIKE": true, "ESCAPE": true, "BETWEEN": true, "IN": true, "IS": true, "NULL": true,
+	"TRUE": true, "FALSE": true, "CAST": true, "MISSING": true,
+}
+
+func lexSelectSQL(sql string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			kind := sqlTokenString
+			if c == '"' {
+				kind = sqlTokenQuotedIdent
+			}
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(sql); j++ {
+				if sql[j] == c {
+					// the quote is escaped by doubling it
+					if j+1 < len(sql) && sql[j+1] == c {
+						sb.WriteByte(c)
+						j++
+						continue
+					}
+					break
+				}
+				sb.WriteByte(sql[j])
+			}
+			if j >= len(sql) {
+				return nil, selectError(SelectErrParse, "unterminated quoted text at position %v", i)
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: sb.String(), pos: i})
+			i = j + 1
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9':
+			j := i
+			for j < len(sql) && (sql[j] >= '0' && sql[j] <= '9' || sql[j] == '.') {
+				j++
+			}
+			if j < len(sql) && (sql[j] == 'e' || sql[j] == 'E') {
+				j++
+				if j < len(sql) && (sql[j] == '+' || sql[j] == '-') {
+					j++
+				}
+				for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+					j++
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenNumber, text: sql[i:j], pos: i})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(sql) && (sql[j] == '_' || sql[j] >= 'a' && sql[j] <= 'z' || sql[j] >= 'A' && sql[j] <= 'Z' ||
+				sql[j] >= '0' && sql[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenIdent, text: sql[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			if i+1 < len(sql) {
+				switch two := sql[i : i+2]; two {
+				case "<=", ">=", "<>", "!=", "||":
+					op = two
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("*,().[]=<>+-/%", rune(c)) {
+					return nil, selectError(SelectErrParse, "unexpected character %q at position %v", c, i)
+				}
+				op = string(c)
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, sqlToken{kind: sqlTokenEOF, pos: len(sql)}), nil
+}
+
+// selectPathElem is an element of the column path, which is either a name or an array index.
+type selectPathElem struct {
+	name   string
+	quoted bool
+	index  int
+}
+
+func (e selectPathElem) isIndex() bool {
+	return e.index >= 0
+}
+
+// match reports whether the name of the element matches key, names are case-insensitive unless quoted.
+func (e selectPathElem) match(key string) bool {
+	if e.quoted {
+		return e.name == key
+	}
+	return strings.EqualFold(e.name, key)
+}
+
+// selectRecord is a row of the object which is queried.
+type selectRecord interface {
+	// Get returns the value of the column specified by the path, nil is returned for the missing values.
+	Get(path []selectPathElem) (interface{}, error)
+	// Columns returns the names and values of all the columns of the record.
+	Columns() ([]string, []interface{})
+}
+
+type selectExpr interface {
+	eval(record selectRecord) (interface{}, error)
+}
+
+type selectProjection struct {
+	expr  selectExpr
+	alias string
+}
+
+type selectQuery struct {
+	projections []*selectProjection // empty for 'SELECT *'
+	alias       string
+	wildcard    bool             // 'S3Object[*]'
+	fromPath    []selectPathElem // the path of the records in the JSON documents, like 'S3Object[*].a.b'
+	where       selectExpr
+	limit       int64 // -1 if unlimited
+	aggregates  []*sqlAggregateExpr
+}
+
+func (q *selectQuery) isAggregate() bool {
+	return len(q.aggregates) > 0
+}
+
+// match reports whether the record satisfies the WHERE clause.
+func (q *selectQuery) match(record selectRecord) (bool, error) {
+	if q.where == nil {
+		return true, nil
+	}
+	v, err := q.where.eval(record)
+	if err != nil {
+		return false, err
+	}
+	return selectTruth(v)
+}
+
+// accumulate adds the record to the aggregate functions.
+func (q *selectQuery) accumulate(record selectRecord) error {
+	for _, agg := range q.aggregates {
+		if err := agg.accumulate(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// project returns the names and values of the output columns, the record is nil for aggregate queries.
+func (q *selectQuery) project(record selectRecord) ([]string, []interface{}, error) {
+	if len(q.projections) == 0 {
+		names, values := record.Columns()
+		return names, values, nil
+	}
+	names := make([]string, len(q.projections))
+	values := make([]interface{}, len(q.projections))
+	for i, p := range q.projections {
+		v, err := p.expr.eval(record)
+		if err != nil {
+			return nil, nil, err
+		}
+		names[i], values[i] = p.alias, v
+		if names[i] == "" {
+			if col, ok := p.expr.(*sqlColumnExpr); ok && len(col.path) > 0 && !col.path[len(col.path)-1].isIndex() {
+				names[i] = col.path[len(col.path)-1].name
+			} else {
+				names[i] = "_" + strconv.Itoa(i+1)
+			}
+		}
+	}
+	return names, values, nil
+}
+
+type sqlParser struct {
+	tokens      []sqlToken
+	pos         int
+	inWhere     bool
+	inAggregate bool
+	columns     []*sqlColumnExpr
+	aggregates  []*sqlAggregateExpr
+}
+
+func parseSelectSQL(sql string) (*selectQuery, error) {
+	tokens, err := lexSelectSQL(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{tokens: tokens}
+	return p.parseQuery()
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	t := p.tokens[p.pos]
+	if t.kind != sqlTokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// backup moves back to the token t which is returned by next.
+func (p *sqlParser) backup(t sqlToken) {
+	if t.kind != sqlTokenEOF {
+		p.pos--
+	}
+}
+
+func (p *sqlParser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == sqlTokenIdent && strings.EqualFold(t.text, keyword)
+}
+
+func (p *sqlParser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.unexpected("expected " + keyword)
+	}
+	return nil
+}
+
+func (p *sqlParser) isOperator(op string) bool {
+	t := p.peek()
+	return t.kind == sqlTokenOperator && t.text == op
+}
+
+func (p *sqlParser) acceptOperator(op string) bool {
+	if p.isOperator(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) expectOperator(op string) error {
+	if !p.acceptOperator(op) {
+		return p.unexpected("expected '" + op + "'")
+	}
+	return nil
+}
+
+func (p *sqlParser) unexpected(expected string) error {
+	t := p.peek()
+	if t.kind == sqlTokenEOF {
+		return selectError(SelectErrParse, "unexpected end of the expression, %v", expected)
+	}
+	return selectError(SelectErrParse, "unexpected token %q at position %v, %v", t.text, t.pos, expected)
+}
+
+// parseAlias parses the optional alias which follows the projection or the FROM clause.
+func (p *sqlParser) parseAlias() (string, error) {
+	explicit := p.acceptKeyword("AS")
+	t := p.peek()
+	if t.kind == sqlTokenQuotedIdent || t.kind == sqlTokenIdent && !sqlKeywords[strings.ToUpper(t.text)] {
+		p.pos++
+		return t.text, nil
+	}
+	if explicit {
+		return "", p.unexpected("expected alias")
+	}
+	return "", nil
+}
+
+func (p *sqlParser) parseQuery() (*selectQuery, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	q := &selectQuery{limit: -1}
+	if !p.acceptOperator("*") {
+		aggregated, plain := 0, 0
+		for {
+			columns, aggregates := len(p.columns), len(p.aggregates)
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			proj := &selectProjection{expr: expr}
+			if proj.alias, err = p.parseAlias(); err != nil {
+				return nil, err
+			}
+			q.projections = append(q.projections, proj)
+			if len(p.aggregates) > aggregates {
+				aggregated++
+				// the columns must be the arguments of the aggregate functions
+				for _, col := range p.columns[columns:] {
+					if !col.aggregated {
+						return nil, selectError(SelectErrUnsupportedSQLStructure, "column is not in the aggregate function")
+					}
+				}
+			} else {
+				plain++
+			}
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+		if aggregated > 0 && plain > 0 {
+			return nil, selectError(SelectErrUnsupportedSQLStructure, "aggregate and non-aggregate projections cannot be mixed")
+		}
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	if t := p.next(); t.kind != sqlTokenIdent || !strings.EqualFold(t.text, "S3Object") {
+		p.backup(t)
+		return nil, p.unexpected("expected S3Object")
+	}
+	if p.acceptOperator("[") {
+		if err := p.expectOperator("*"); err != nil {
+			return nil, err
+		}
+		if err := p.expectOperator("]"); err != nil {
+			return nil, err
+		}
+		q.wildcard = true
+		for p.acceptOperator(".") {
+			t := p.next()
+			if t.kind != sqlTokenIdent && t.kind != sqlTokenQuotedIdent {
+				p.backup(t)
+				return nil, p.unexpected("expected path")
+			}
+			q.fromPath = append(q.fromPath, selectPathElem{name: t.text, quoted: t.kind == sqlTokenQuotedIdent, index: -1})
+		}
+	}
+	var err error
+	if q.alias, err = p.parseAlias(); err != nil {
+		return nil, err
+	}
+	if p.acceptKeyword("WHERE") {
+		p.inWhere = true
+		if q.where, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		p.inWhere = false
+	}
+	if p.acceptKeyword("LIMIT") {
+		t := p.next()
+		if t.kind != sqlTokenNumber {
+			p.backup(t)
+			return nil, p.unexpected("expected number")
+		}
+		if q.limit, err = strconv.ParseInt(t.text, 10, 64); err != nil || q.limit < 0 {
+			return nil, selectError(SelectErrParse, "invalid limit %v", t.text)
+		}
+	}
+	if p.peek().kind != sqlTokenEOF {
+		return nil, p.unexpected("expected end of the expression")
+	}
+
+	// the alias of the table is removed from the columns
+	tableName := q.alias
+	if tableName == "" {
+		tableName = "S3Object"
+	}
+	for _, col := range p.columns {
+		if len(col.path) > 1 && !col.path[0].isIndex() && col.path[0].match(tableName) {
+			col.path = col.path[1:]
+		}
+	}
+	q.aggregates = p.aggregates
+	return q, nil
+}
+
+func (p *sqlParser) parseExpr() (selectExpr, error) {
+	return p.parseOr()
+}
+
+func (p *sqlParser) parseOr() (selectExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlLogicalExpr{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAnd() (selectExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlLogicalExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseNot() (selectExpr, error) {
+	if p.acceptKeyword("NOT") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlNotExpr{x: x}, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *sqlParser) parsePredicate() (selectExpr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == sqlTokenOperator {
+		switch t.text {
+		case "=", "!=", "<>", "<", "<=", ">", ">=":
+			p.pos++
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return &sqlCompareExpr{op: t.text, left: left, right: right}, nil
+		}
+	}
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if !p.acceptKeyword("NULL") && !p.acceptKeyword("MISSING") {
+			return nil, p.unexpected("expected NULL")
+		}
+		return &sqlIsNullExpr{x: left, not: not}, nil
+	}
+	not := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("LIKE"):
+		pattern, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		expr := &sqlLikeExpr{x: left, pattern: pattern, not: not}
+		if p.acceptKeyword("ESCAPE") {
+			if expr.escape, err = p.parseAdditive(); err != nil {
+				return nil, err
+			}
+		}
+		return expr, nil
+	case p.acceptKeyword("BETWEEN"):
+		lower, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		upper, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlBetweenExpr{x: left, lower: lower, upper: upper, not: not}, nil
+	case p.acceptKeyword("IN"):
+		if err = p.expectOperator("("); err != nil {
+			return nil, err
+		}
+		expr := &sqlInExpr{x: left, not: not}
+		for {
+			item, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			expr.list = append(expr.list, item)
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+		if err = p.expectOperator(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	if not {
+		return nil, p.unexpected("expected LIKE, BETWEEN or IN")
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAdditive() (selectExpr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+") || p.isOperator("-") || p.isOperator("||") {
+		op := p.next().text
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		if op == "||" {
+			left = &sqlConcatExpr{left: left, right: right}
+		} else {
+			left = &sqlArithExpr{op: op, left: left, right: right}
+		}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseMultiplicative() (selectExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("*") || p.isOperator("/") || p.isOperator("%") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlArithExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseUnary() (selectExpr, error) {
+	if p.acceptOperator("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlArithExpr{op: "-", left: &sqlLiteralExpr{value: int64(0)}, right: x}, nil
+	}
+	if p.acceptOperator("+") {
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *sqlParser) parsePrimary() (selectExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case sqlTokenNumber:
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return &sqlLiteralExpr{value: i}, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, selectError(SelectErrParse, "invalid number %v at position %v", t.text, t.pos)
+		}
+		return &sqlLiteralExpr{value: f}, nil
+	case sqlTokenString:
+		return &sqlLiteralExpr{value: t.text}, nil
+	case sqlTokenOperator:
+		if t.text == "(" {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectOperator(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	case sqlTokenQuotedIdent:
+		return p.parseColumn(t)
+	case sqlTokenIdent:
+		switch strings.ToUpper(t.text) {
+		case "TRUE":
+			return &sqlLiteralExpr{value: true}, nil
+		case "FALSE":
+			return &sqlLiteralExpr{value: false}, nil
+		case "NULL", "MISSING":
+			return &sqlLiteralExpr{}, nil
+		case "CAST":
+			return p.parseCast()
+		}
+		if p.isOperator("(") {
+			return p.parseFunction(t)
+		}
+		if sqlKeywords[strings.ToUpper(t.text)] {
+			break
+		}
+		return p.parseColumn(t)
+	}
+	p.backup(t)
+	return nil, p.unexpected("expected expression")
+}
+
+func (p *sqlParser) parseColumn(t sqlToken) (selectExpr, error) {
+	col := &sqlColumnExpr{
+		path:       []selectPathElem{{name: t.text, quoted: t.kind == sqlTokenQuotedIdent, index: -1}},
+		aggregated: p.inAggregate,
+	}
+	for {
+		if p.acceptOperator(".") {
+			t := p.next()
+			if t.kind != sqlTokenIdent && t.kind != sqlTokenQuotedIdent {
+				p.backup(t)
+				return nil, p.unexpected("expected column name")
+			}
+			col.path = append(col.path, selectPathElem{name: t.text, quoted: t.kind == sqlTokenQuotedIdent, index: -1})
+			continue
+		}
+		if p.acceptOperator("[") {
+			t := p.next()
+			index, err := strconv.Atoi(t.text)
+			if t.kind != sqlTokenNumber || err != nil || index < 0 {
+				p.backup(t)
+				return nil, p.unexpected("expected array index")
+			}
+			if err = p.expectOperator("]"); err != nil {
+				return nil, err
+			}
+			col.path = append(col.path, selectPathElem{index: index})
+			continue
+		}
+		break
+	}
+	p.columns = append(p.columns, col)
+	return col, nil
+}
+
+func (p *sqlParser) parseCast() (selectExpr, error) {
+	if err := p.expectOperator("("); err != nil {
+		return nil, err
+	}
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expectKeyword("AS"); err != nil {
+		return nil, err
+	}
+	t := p.next()
+	typ := strings.ToUpper(t.text)
+	switch typ {
+	case "INT", "INTEGER", "FLOAT", "DECIMAL", "NUMERIC", "STRING", "VARCHAR", "CHAR", "BOOL", "BOOLEAN":
+	default:
+		p.backup(t)
+		return nil, p.unexpected("expected INT, FLOAT, DECIMAL, STRING or BOOL")
+	}
+	if err = p.expectOperator(")"); err != nil {
+		return nil, err
+	}
+	return &sqlCastExpr{x: x, typ: typ}, nil
+}
+
+func (p *sqlParser) parseFunction(t sqlToken) (selectExpr, error) {
+	name := strings.ToUpper(t.text)
+	p.pos++ // (
+	switch name {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+		if p.inWhere {
+			return nil, selectError(SelectErrUnsupportedSQLStructure, "aggregate function %v is not allowed in WHERE clause", name)
+		}
+		if p.inAggregate {
+			return nil, selectError(SelectErrUnsupportedSQLStructure, "aggregate function %v cannot be nested", name)
+		}
+		agg := &sqlAggregateExpr{fn: name}
+		if name == "COUNT" && p.acceptOperator("*") {
+			// COUNT(*) counts all the records
+		} else {
+			p.inAggregate = true
+			x, err := p.parseExpr()
+			p.inAggregate = false
+			if err != nil {
+				return nil, err
+			}
+			agg.x = x
+		}
+		if err := p.expectOperator(")"); err != nil {
+			return nil, err
+		}
+		p.aggregates = append(p.aggregates, agg)
+		return agg, nil
+	case "TRIM":
+		expr := &sqlTrimExpr{mode: "BOTH"}
+		for _, mode := range []string{"LEADING", "TRAILING", "BOTH"} {
+			if p.acceptKeyword(mode) {
+				expr.mode = mode
+				break
+			}
+		}
+		if !p.acceptKeyword("FROM") {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if p.acceptKeyword("FROM") {
+				expr.chars = x
+				if x, err = p.parseExpr(); err != nil {
+					return nil, err
+				}
+			}
+			expr.x = x
+		} else {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			expr.x = x
+		}
+		if err := p.expectOperator(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case "SUBSTRING":
+		expr := &sqlSubstringExpr{}
+		var err error
+		if expr.x, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("FROM") {
+			if err = p.expectOperator(","); err != nil {
+				return nil, err
+			}
+		}
+		if expr.start, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		if p.acceptKeyword("FOR") || p.acceptOperator(",") {
+			if expr.length, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		if err = p.expectOperator(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	fn, ok := sqlScalarFunctions[name]
+	if !ok {
+		return nil, selectError(SelectErrUnsupportedFunction, "function %v is not supported", t.text)
+	}
+	expr := &sqlFuncExpr{name: name, fn: fn}
+	if !p.acceptOperator(")") {
+		for {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			expr.args = append(expr.args, x)
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+		if err := p.expectOperator(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(expr.args) < fn.minArgs || fn.maxArgs >= 0 && len(expr.args) > fn.maxArgs {
+		return nil, selectError(SelectErrEvaluatorInvalidArguments, "invalid number of arguments for function %v", name)
+	}
+	return expr, nil
+}
+
+type sqlLiteralExpr struct {
+	value interface{}
+}
+
+func (e *sqlLiteralExpr) eval(selectRecord) (interface{}, error) {
+	return e.value, nil
+}
+
+type sqlColumnExpr struct {
+	path       []selectPathElem
+	aggregated bool // the column is the argument of an aggregate function
+}
+
+func (e *sqlColumnExpr) eval(record selectRecord) (interface{}, error) {
+	return record.Get(e.path)
+}
+
+type sqlLogicalExpr struct {
+	or          bool
+	left, right selectExpr
+}
+
+// eval implements the three-valued logic, nil represents the unknown value.
+func (e *sqlLogicalExpr) eval(record selectRecord) (interface{}, error) {
+	lv, err := e.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	l, err := selectBool(lv)
+	if err != nil {
+		return nil, err
+	}
+	if l != nil && *l == e.or {
+		return e.or, nil
+	}
+	rv, err := e.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	r, err := selectBool(rv)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil && *r == e.or {
+		return e.or, nil
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	return !e.or, nil
+}
+
+type sqlNotExpr struct {
+	x selectExpr
+}
+
+func (e *sqlNotExpr) eval(record selectRecord) (interface{}, error) {
+	v, err := e.x.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	b, err := selectBool(v)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return !*b, nil
+}
+
+type sqlCompareExpr struct {
+	op          string
+	left, right selectExpr
+}
+
+func (e *sqlCompareExpr) eval(record selectRecord) (interface{}, error) {
+	l, err := e.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	c, err := compareSelectValues(l, r)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "=":
+		return c == 0, nil
+	case "!=", "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+type sqlIsNullExpr struct {
+	x   selectExpr
+	not bool
+}
+
+func (e *sqlIsNullExpr) eval(record selectRecord) (interface{}, error) {
+	v, err := e.x.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	return (v == nil) != e.not, nil
+}
+
+type sqlLikeExpr struct {
+	x, pattern, escape selectExpr
+	not                bool
+}
+
+func (e *sqlLikeExpr) eval(record selectRecord) (interface{}, error) {
+	v, err := e.x.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	pv, err := e.pattern.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil || pv == nil {
+		return nil, nil
+	}
+	var escape rune
+	if e.escape != nil {
+		ev, err := e.escape.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		es := formatSelectValue(ev)
+		if utf8.RuneCountInString(es) != 1 {
+			return nil, selectError(SelectErrEvaluatorInvalidArguments, "escape of LIKE must be a single character")
+		}
+		escape, _ = utf8.DecodeRuneInString(es)
+	}
+	matched, err := selectLike([]rune(formatSelectValue(v)), []rune(formatSelectValue(pv)), escape)
+	if err != nil {
+		return nil, err
+	}
+	return matched != e.not, nil
+}
+
+// selectLike matches s against the LIKE pattern, '%' matches any sequence and '_' matches any character.
+func selectLike(s, pattern []rune, escape rune) (bool, error) {
+	for len(pattern) > 0 {
+		c := pattern[0]
+		switch {
+		case escape != 0 && c == escape:
+			if len(pattern) < 2 {
+				return false, selectError(SelectErrEvaluatorInvalidArguments, "pattern of LIKE ends with the escape character")
+			}
+			if len(s) == 0 || s[0] != pattern[1] {
+				return false, nil
+			}
+			s, pattern = s[1:], pattern[2:]
+		case c == '%':
+			for len(pattern) > 0 && pattern[0] == '%' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true, nil
+			}
+			for i := 0; i <= len(s); i++ {
+				matched, err := selectLike(s[i:], pattern, escape)
+				if err != nil || matched {
+					return matched, err
+				}
+			}
+			return false, nil
+		case c == '_':
+			if len(s) == 0 {
+				return false, nil
+			}
+			s, pattern = s[1:], pattern[1:]
+		default:
+			if len(s) == 0 || s[0] != c {
+				return false, nil
+			}
+			s, pattern = s[1:], pattern[1:]
+		}
+	}
+	return len(s) == 0, nil
+}
+
+type sqlBetweenExpr struct {
+	x, lower, upper selectExpr
+	not             bool
+}
+
+func (e *sqlBetweenExpr) eval(record selectRecord) (interface{}, error) {
+	var values [3]interface{}
+	for i, x := range []selectExpr{e.x, e.lower, e.upper} {
+		v, err := x.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nil, nil
+		}
+		values[i] = v
+	}
+	c1, err := compareSelectValues(values[0], values[1])
+	if err != nil {
+		return nil, err
+	}
+	c2, err := compareSelectValues(values[0], values[2])
+	if err != nil {
+		return nil, err
+	}
+	return (c1 >= 0 && c2 <= 0) != e.not, nil
+}
+
+type sqlInExpr struct {
+	x    selectExpr
+	list []selectExpr
+	not  bool
+}
+
+func (e *sqlInExpr) eval(record selectRecord) (interface{}, error) {
+	v, err := e.x.eval(record)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	for _, item := range e.list {
+		iv, err := item.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		if iv == nil {
+			continue
+		}
+		if c, err := compareSelectValues(v, iv); err == nil && c == 0 {
+			return !e.not, nil
+		}
+	}
+	return e.not, nil
+}
+
+type sqlArithExpr struct {
+	op          string
+	left, right selectExpr
+}
+
+func (e *sqlArithExpr) eval(record selectRecord) (interface{}, error) {
+	lv, err := e.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	rv, err := e.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if lv == nil || rv == nil {
+		return nil, nil
+	}
+	l, err := selectNumber(lv)
+	if err != nil {
+		return nil, err
+	}
+	r, err := selectNumber(rv)
+	if err != nil {
+		return nil, err
+	}
+	li, lok := l.(int64)
+	ri, rok := r.(int64)
+	if lok && rok {
+		switch e.op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		}
+		if ri == 0 {
+			return nil, selectError(SelectErrEvaluatorInvalidArguments, "division by zero")
+		}
+		if e.op == "/" {
+			return li / ri, nil
+		}
+		return li % ri, nil
+	}
+	lf, rf := selectFloat(l), selectFloat(r)
+	switch e.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	}
+	if rf == 0 {
+		return nil, selectError(SelectErrEvaluatorInvalidArguments, "division by zero")
+	}
+	if e.op == "/" {
+		return lf / rf, nil
+	}
+	return math.Mod(lf, rf), nil
+}
+
+type sqlConcatExpr struct {
+	left, right selectExpr
+}
+
+func (e *sqlConcatExpr) eval(record selectRecord) (interface{}, error) {
+	l, err := e.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	return formatSelectValue(l) + formatSelectValue(r), nil
+}
+
+type sqlCastExpr struct {
+	x   selectExpr
+	typ string
+}
+
+func (e *sqlCastExpr) eval(record selectRecord) (interface{}, error) {
+	v, err := e.x.eval(record)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	switch e.typ {
+	case "INT", "INTEGER":
+		n, err := selectNumber(v)
+		if err != nil {
+			return nil, selectError(SelectErrInvalidCast, "cannot cast %v to INT", formatSelectValue(v))
+		}
+		if f, ok := n.(float64); ok {
+			return int64(f), nil
+		}
+		return n, nil
+	case "FLOAT", "DECIMAL", "NUMERIC":
+		n, err := selectNumber(v)
+		if err != nil {
+			return nil, selectError(SelectErrInvalidCast, "cannot cast %v to %v", formatSelectValue(v), e.typ)
+		}
+		return selectFloat(n), nil
+	case "BOOL", "BOOLEAN":
+		b, err := selectBool(v)
+		if err != nil {
+			return nil, selectError(SelectErrInvalidCast, "cannot cast %v to BOOL", formatSelectValue(v))
+		}
+		return *b, nil
+	default:
+		return formatSelectValue(v), nil
+	}
+}
+
+type sqlTrimExpr struct {
+	mode     string
+	chars, x selectExpr
+}
+
+func (e *sqlTrimExpr) eval(record selectRecord) (interface{}, error) {
+	v, err := e.x.eval(record)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	cutset := " "
+	if e.chars != nil {
+		cv, err := e.chars.eval(record)
+		if err != nil || cv == nil {
+			return nil, err
+		}
+		cutset = formatSelectValue(cv)
+	}
+	s := formatSelectValue(v)
+	switch e.mode {
+	case "LEADING":
+		return strings.TrimLeft(s, cutset), nil
+	case "TRAILING":
+		return strings.TrimRight(s, cutset), nil
+	default:
+		return strings.Trim(s, cutset), nil
+	}
+}
+
+type sqlSubstringExpr struct {
+	x, start, length selectExpr
+}
+
+func (e *sqlSubstringExpr) eval(record selectRecord) (interface{}, error) {
+	v, err := e.x.eval(record)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	runes := []rune(formatSelectValue(v))
+	start, err := evalSelectInt(e.start, record)
+	if err != nil || start == nil {
+		return nil, err
+	}
+	// the position is 1-based, and the characters before the first one are counted in the length
+	from, to := *start, int64(len(runes))+1
+	if e.length != nil {
+		length, err := evalSelectInt(e.length, record)
+		if err != nil || length == nil {
+			return nil, err
+		}
+		if *length < 0 {
+			return nil, selectError(SelectErrEvaluatorInvalidArguments, "negative length of SUBSTRING")
+		}
+		if from+*length < to {
+			to = from + *length
+		}
+	}
+	if from < 1 {
+		from = 1
+	}
+	if from >= to {
+		return "", nil
+	}
+	return string(runes[from-1 : to-1]), nil
+}
+
+func evalSelectInt(x selectExpr, record selectRecord) (*int64, error) {
+	v, err := x.eval(record)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	n, err := selectNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	i, ok := n.(int64)
+	if !ok {
+		i = int64(n.(float64))
+	}
+	return &i, nil
+}
+
+type sqlScalarFunction struct {
+	minArgs, maxArgs int // maxArgs is -1 if unlimited
+	call             func(args []interface{}) (interface{}, error)
+}
+
+var sqlScalarFunctions = map[string]*sqlScalarFunction{
+	"LOWER": {minArgs: 1, maxArgs: 1, call: func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return strings.ToLower(formatSelectValue(args[0])), nil
+	}},
+	"UPPER": {minArgs: 1, maxArgs: 1, call: func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return strings.ToUpper(formatSelectValue(args[0])), nil
+	}},
+	"CHAR_LENGTH":      {minArgs: 1, maxArgs: 1, call: selectCharLength},
+	"CHARACTER_LENGTH": {minArgs: 1, maxArgs: 1, call: selectCharLength},
+	"COALESCE": {minArgs: 1, maxArgs: -1, call: func(args []interface{}) (interface{}, error) {
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	}},
+	"NULLIF": {minArgs: 2, maxArgs: 2, call: func(args []interface{}) (interface{}, error) {
+		if args[0] == nil || args[1] == nil {
+			return args[0], nil
+		}
+		if c, err := compareSelectValues(args[0], args[1]); err == nil && c == 0 {
+			return nil, nil
+		}
+		return args[0], nil
+	}},
+}
+
+func selectCharLength(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	return int64(utf8.RuneCountInString(formatSelectValue(args[0]))), nil
+}
+
+type sqlFuncExpr struct {
+	name string
+	fn   *sqlScalarFunction
+	args []selectExpr
+}
+
+func (e *sqlFuncExpr) eval(record selectRecord) (interface{}, error) {
+	args := make([]interface{}, len(e.args))
+	for i, arg := range e.args {
+		v, err := arg.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return e.fn.call(args)
+}
+
+type sqlAggregateExpr struct {
+	fn       string
+	x        selectExpr // nil for COUNT(*)
+	count    int64
+	sumInt   int64
+	sumFloat float64
+	isFloat  bool
+	value    interface{} // MIN or MAX
+}
+
+func (e *sqlAggregateExpr) accumulate(record selectRecord) error {
+	if e.x == nil {
+		e.count++
+		return nil
+	}
+	v, err := e.x.eval(record)
+	if err != nil || v == nil {
+		return err
+	}
+	e.count++
+	switch e.fn {
+	case "SUM", "AVG":
+		n, err := selectNumber(v)
+		if err != nil {
+			return err
+		}
+		if i, ok := n.(int64); ok && !e.isFloat {
+			e.sumInt += i
+			return nil
+		}
+		if !e.isFloat {
+			e.isFloat, e.sumFloat = true, float64(e.sumInt)
+		}
+		e.sumFloat += selectFloat(n)
+	case "MIN", "MAX":
+		if e.value == nil {
+			e.value = v
+			return nil
+		}
+		c, err := compareSelectValues(v, e.value)
+		if err != nil {
+			return err
+		}
+		if e.fn == "MIN" && c < 0 || e.fn == "MAX" && c > 0 {
+			e.value = v
+		}
+	}
+	return nil
+}
+
+// eval returns the result of the aggregate function after all the records are accumulated.
+func (e *sqlAggregateExpr) eval(selectRecord) (interface{}, error) {
+	switch e.fn {
+	case "COUNT":
+		return e.count, nil
+	case "SUM", "AVG":
+		if e.count == 0 {
+			return nil, nil
+		}
+		sum := e.sumFloat
+		if !e.isFloat {
+			if e.fn == "SUM" {
+				return e.sumInt, nil
+			}
+			sum = float64(e.sumInt)
+		}
+		if e.fn == "SUM" {
+			return sum, nil
+		}
+		return sum / float64(e.count), nil
+	default:
+		return e.value, nil
+	}
+}
+
+// selectNumber converts the value to int64 or float64.
+func selectNumber(v interface{}) (interface{}, error) {
+	switch n := v.(type) {
+	case int64, float64:
+		return n, nil
+	case string:
+		s := strings.TrimSpace(n)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, selectError(SelectErrInvalidDataType, "%v is not a number", formatSelectValue(v))
+}
+
+func selectFloat(n interface{}) float64 {
+	if i, ok := n.(int64); ok {
+		return float64(i)
+	}
+	return n.(float64)
+}
+
+// selectBool converts the value to bool, nil is returned for the unknown value.
+func selectBool(v interface{}) (*bool, error) {
+	switch b := v.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return &b, nil
+	case string:
+		if parsed, err := strconv.ParseBool(strings.TrimSpace(b)); err == nil {
+			return &parsed, nil
+		}
+	}
+	return nil, selectError(SelectErrInvalidDataType, "%v is not a boolean", formatSelectValue(v))
+}
+
+// selectTruth reports whether the condition is true, the unknown value is regarded as false.
+func selectTruth(v interface{}) (bool, error) {
+	b, err := selectBool(v)
+	if err != nil || b == nil {
+		return false, err
+	}
+	return *b, nil
+}
+
+// compareSelectValues compares the non-nil values. The values of CSV are strings, which are compared as
+// numbers or booleans if they are compared to numbers or booleans.
+func compareSelectValues(a, b interface{}) (int, error) {
+	switch av := a.(type) {
+	case int64, float64:
+		bn, err := selectNumber(b)
+		if err != nil {
+			if _, ok := b.(string); ok {
+				return strings.Compare(formatSelectValue(a), b.(string)), nil
+			}
+			return 0, err
+		}
+		return compareSelectNumbers(av, bn), nil
+	case string:
+		switch b.(type) {
+		case string:
+			return strings.Compare(av, b.(string)), nil
+		case int64, float64, bool:
+			c, err := compareSelectValues(b, a)
+			return -c, err
+		}
+	case bool:
+		bb, err := selectBool(b)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case av == *bb:
+			return 0, nil
+		case !av:
+			return -1, nil
+		default:
+			return 1, nil
+		}
+	}
+	return 0, selectError(SelectErrInvalidDataType, "cannot compare %v with %v", formatSelectValue(a), formatSelectValue(b))
+}
+
+func compareSelectNumbers(a, b interface{}) int {
+	ai, aok := a.(int64)
+	bi, bok := b.(int64)
+	if aok && bok {
+		switch {
+		case ai < bi:
+			return -1
+		case ai > bi:
+			return 1
+		}
+		return 0
+	}
+	af, bf := selectFloat(a), selectFloat(b)
+	switch {
+	case af < bf:
+		return -1
+	case af > bf:
+		return 1
+	}
+	return 0
+}
+
+// formatSelectValue formats the value as string, the objects and arrays of JSON are formatted as JSON.
+func formatSelectValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		data, _ := marshalSelectJSON(t)
+		return string(data)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSelectSQL(t *testing.T) {
+	tests := []struct {
+		sql     string
+		errCode string
+	}{
+		{sql: "SELECT * FROM S3Object"},
+		{sql: "select s._1, s._2 as name from s3object s where s._3 > 10 limit 5"},
+		{sql: `SELECT s."Full Name" FROM S3Object[*].records s WHERE s.age BETWEEN 10 AND 20`},
+		{sql: "SELECT COUNT(*), SUM(CAST(_2 AS INT)), AVG(_2) FROM S3Object WHERE _1 LIKE 'a%'"},
+		{sql: "SELECT UPPER(TRIM(LEADING 'x' FROM _1)), SUBSTRING(_2 FROM 2 FOR 3) FROM S3Object"},
+		{sql: "SELECT * FROM S3Object WHERE _1 IN ('a', 'b') AND _2 IS NOT NULL OR NOT _3 = ''"},
+		{sql: "SELECT", errCode: "ParseUnexpectedToken"},
+		{sql: "SELECT * FROM table", errCode: "ParseUnexpectedToken"},
+		{sql: "SELECT * FROM S3Object WHERE _1 = 'a", errCode: "ParseUnexpectedToken"},
+		{sql: "SELECT * FROM S3Object LIMIT a", errCode: "ParseUnexpectedToken"},
+		{sql: "SELECT _1, COUNT(*) FROM S3Object", errCode: "UnsupportedSqlStructure"},
+		{sql: "SELECT SUM(_1) + _2 FROM S3Object", errCode: "UnsupportedSqlStructure"},
+		{sql: "SELECT * FROM S3Object WHERE COUNT(*) > 1", errCode: "UnsupportedSqlStructure"},
+		{sql: "SELECT SUM(COUNT(*)) FROM S3Object", errCode: "UnsupportedSqlStructure"},
+		{sql: "SELECT UTCNOW() FROM S3Object", errCode: "UnsupportedFunction"},
+		{sql: "SELECT LOWER(_1, _2) FROM S3Object", errCode: "EvaluatorInvalidArguments"},
+	}
+	for _, tt := range tests {
+		_, err := parseSelectSQL(tt.sql)
+		if tt.errCode == "" {
+			require.NoError(t, err, tt.sql)
+			continue
+		}
+		require.Error(t, err, tt.sql)
+		require.Equal(t, tt.errCode, err.(*ErrorCode).ErrorCode, tt.sql)
+	}
+}
+
+func evalSelectQuery(t *testing.T, sql string, records []selectRecord) [][]interface{} {
+	query, err := parseSelectSQL(sql)
+	require.NoError(t, err, sql)
+	var rows [][]interface{}
+	for _, record := range records {
+		if query.limit >= 0 && int64(len(rows)) >= query.limit {
+			break
+		}
+		matched, err := query.match(record)
+		require.NoError(t, err, sql)
+		if !matched {
+			continue
+		}
+		if query.isAggregate() {
+			require.NoError(t, query.accumulate(record), sql)
+			continue
+		}
+		_, values, err := query.project(record)
+		require.NoError(t, err, sql)
+		rows = append(rows, values)
+	}
+	if query.isAggregate() {
+		_, values, err := query.project(nil)
+		require.NoError(t, err, sql)
+		rows = append(rows, values)
+	}
+	return rows
+}
+
+func TestSelectSQLOverCSV(t *testing.T) {
+	header := &selectCSVHeader{names: []string{"name", "age", "city"}}
+	records := []selectRecord{
+		&selectCSVRecord{header: header, fields: []string{"alice", "30", "Beijing"}},
+		&selectCSVRecord{header: header, fields: []string{"bob", "25", "Shanghai"}},
+		&selectCSVRecord{header: header, fields: []string{"carol", "35", ""}},
+		&selectCSVRecord{header: header, fields: []string{"dave", "8.5", "Beijing"}},
+	}
+	tests := []struct {
+		sql      string
+		expected [][]interface{}
+	}{
+		{
+			sql:      "SELECT name FROM S3Object WHERE age > 26",
+			expected: [][]interface{}{{"alice"}, {"carol"}},
+		},
+		{
+			sql:      "SELECT s._1, s.CITY FROM S3Object s WHERE s.city = 'Beijing' LIMIT 1",
+			expected: [][]interface{}{{"alice", "Beijing"}},
+		},
+		{
+			sql:      "SELECT UPPER(name) || '-' || city FROM S3Object WHERE name LIKE '_o%'",
+			expected: [][]interface{}{{"BOB-Shanghai"}},
+		},
+		{
+			sql:      "SELECT name FROM S3Object WHERE CAST(age AS FLOAT) BETWEEN 8 AND 26 AND city IN ('Shanghai', 'Beijing')",
+			expected: [][]interface{}{{"bob"}, {"dave"}},
+		},
+		{
+			sql:      "SELECT COUNT(*), SUM(CAST(age AS INT)), MAX(age), MIN(name), AVG(age) FROM S3Object WHERE city <> ''",
+			expected: [][]interface{}{{int64(3), int64(63), "8.5", "alice", (30 + 25 + 8.5) / 3}},
+		},
+		{
+			sql:      "SELECT CHAR_LENGTH(name), SUBSTRING(name, 2, 3), NULLIF(city, ''), age * 2 FROM S3Object WHERE name = 'carol'",
+			expected: [][]interface{}{{int64(5), "aro", nil, int64(70)}},
+		},
+		{
+			sql:      "SELECT COUNT(city) FROM S3Object WHERE NOT (age < 30)",
+			expected: [][]interface{}{{int64(2)}},
+		},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, evalSelectQuery(t, tt.sql, records), tt.sql)
+	}
+
+	query, err := parseSelectSQL("SELECT unknown FROM S3Object")
+	require.NoError(t, err)
+	_, _, err = query.project(records[0])
+	require.Equal(t, SelectErrBindingDoesNotExist.ErrorCode, err.(*ErrorCode).ErrorCode)
+}
+
+func TestSelectSQLOverJSON(t *testing.T) {
+	data := `{"name": "alice", "age": 30, "tags": ["a", "b"], "address": {"city": "Beijing"}}
+			 {"name": "bob", "age": 25.5, "tags": [], "address": {"city": "Shanghai"}}
+			 {"name": "carol", "active": true}`
+	reader := newSelectJSONReader(&selectQuery{}, strings.NewReader(data))
+	var records []selectRecord
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			break
+		}
+		records = append(records, record)
+	}
+	require.Len(t, records, 3)
+
+	tests := []struct {
+		sql      string
+		expected [][]interface{}
+	}{
+		{
+			sql:      "SELECT s.name, s.address.city, s.tags[1] FROM S3Object[*] s WHERE s.age >= 25",
+			expected: [][]interface{}{{"alice", "Beijing", "b"}, {"bob", "Shanghai", nil}},
+		},
+		{
+			sql:      "SELECT name FROM S3Object WHERE age IS NULL AND active = TRUE",
+			expected: [][]interface{}{{"carol"}},
+		},
+		{
+			sql:      "SELECT SUM(age), COUNT(age), COUNT(*) FROM S3Object",
+			expected: [][]interface{}{{55.5, int64(2), int64(3)}},
+		},
+		{
+			sql:      "SELECT COALESCE(s.address.city, 'unknown') AS city FROM S3Object s",
+			expected: [][]interface{}{{"Beijing"}, {"Shanghai"}, {"unknown"}},
+		},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, evalSelectQuery(t, tt.sql, records), tt.sql)
+	}
+
+	// the objects keep the order of the keys
+	_, values := records[0].Columns()
+	data2, err := json.Marshal(values[3])
+	require.NoError(t, err)
+	require.Equal(t, `{"city":"Beijing"}`, string(data2))
+	require.Equal(t, `{"name":"alice","age":30,"tags":["a","b"],"address":{"city":"Beijing"}}`, formatSelectValue(records[0].(*selectJSONRecord).value))
+}
+
+func TestSelectLike(t *testing.T) {
+	tests := []struct {
+		s, pattern string
+		escape     rune
+		expected   bool
+	}{
+		{"abc", "abc", 0, true},
+		{"abc", "a%", 0, true},
+		{"abc", "%c", 0, true},
+		{"abc", "a_c", 0, true},
+		{"abc", "a_", 0, false},
+		{"abc", "%%b%", 0, true},
+		{"a%c", `a\%c`, '\\', true},
+		{"abc", `a\%c`, '\\', false},
+		{"", "%", 0, true},
+	}
+	for _, tt := range tests {
+		matched, err := selectLike([]rune(tt.s), []rune(tt.pattern), tt.escape)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, matched, "%v LIKE %v", tt.s, tt.pattern)
+	}
+}
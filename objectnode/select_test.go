@@ -0,0 +1,211 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSelectRequest(t *testing.T) {
+	request := func(expression, input, output string) string {
+		return `<SelectObjectContentRequest>
+					<Expression>` + expression + `</Expression>
+					<ExpressionType>SQL</ExpressionType>
+					<InputSerialization>` + input + `</InputSerialization>
+					<OutputSerialization>` + output + `</OutputSerialization>
+				</SelectObjectContentRequest>`
+	}
+	tests := []struct {
+		value       string
+		expectedErr *ErrorCode
+	}{
+		{value: request("SELECT * FROM S3Object", "<CSV><FileHeaderInfo>USE</FileHeaderInfo></CSV>", "<JSON/>")},
+		{value: request("SELECT * FROM S3Object", "<CompressionType>GZIP</CompressionType><JSON><Type>LINES</Type></JSON>", "<CSV/>")},
+		{value: request("", "<CSV/>", "<CSV/>"), expectedErr: SelectErrMissingExpression},
+		{value: request("SELECT * FROM S3Object", "<CompressionType>ZIP</CompressionType><CSV/>", "<CSV/>"), expectedErr: SelectErrCompressionFormat},
+		{value: request("SELECT * FROM S3Object", "<CSV/><JSON/>", "<CSV/>"), expectedErr: SelectErrInputSerialization},
+		{value: request("SELECT * FROM S3Object", "<CSV/>", ""), expectedErr: SelectErrOutputSerialization},
+		{value: request("SELECT * FROM S3Object", "<CSV><FileHeaderInfo>FIRST</FileHeaderInfo></CSV>", "<CSV/>"), expectedErr: SelectErrFileHeaderInfo},
+		{value: request("SELECT * FROM S3Object", "<CSV><FieldDelimiter>::</FieldDelimiter></CSV>", "<CSV/>"), expectedErr: SelectErrDelimiter},
+		{value: request("SELECT * FROM S3Object", "<JSON><Type>ARRAY</Type></JSON>", "<CSV/>"), expectedErr: SelectErrJSONType},
+		{value: request("SELECT * FROM S3Object", "<Parquet/>", "<CSV/>"), expectedErr: SelectErrUnsupportedParquet},
+		{value: request("SELECT * FROM S3Object", "<CSV/>", "<CSV><QuoteFields>NEVER</QuoteFields></CSV>"), expectedErr: SelectErrQuoteFields},
+		{value: `<SelectObjectContentRequest>`, expectedErr: MalformedXML},
+	}
+	for i, tt := range tests {
+		_, err := ParseSelectRequest([]byte(tt.value))
+		if tt.expectedErr == nil {
+			require.NoError(t, err, "case %v", i)
+			continue
+		}
+		require.Equal(t, tt.expectedErr, err, "case %v", i)
+	}
+
+	_, err := ParseSelectRequest([]byte(request("SELECT * FROM", "<CSV/>", "<CSV/>")))
+	require.Equal(t, SelectErrParse.ErrorCode, err.(*ErrorCode).ErrorCode)
+}
+
+func TestSelectCSVReader(t *testing.T) {
+	readAll := func(conf *SelectCSVInput, data string) (header []string, rows [][]string) {
+		reader, err := newSelectCSVReader(conf, strings.NewReader(data))
+		require.NoError(t, err)
+		if reader.header != nil {
+			header = reader.header.names
+		}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			require.NoError(t, err)
+			rows = append(rows, record.(*selectCSVRecord).fields)
+		}
+	}
+
+	header, rows := readAll(&SelectCSVInput{FileHeaderInfo: SelectFileHeaderUse},
+		"name,comment\r\nalice,\"hello, \"\"world\"\"\"\r\n\r\nbob,\"multi\nline\"\ncarol,")
+	require.Equal(t, []string{"name", "comment"}, header)
+	require.Equal(t, [][]string{{"alice", `hello, "world"`}, {"bob", "multi\nline"}, {"carol", ""}}, rows)
+
+	header, rows = readAll(&SelectCSVInput{
+		FileHeaderInfo:       SelectFileHeaderIgnore,
+		Comments:             "#",
+		FieldDelimiter:       "|",
+		RecordDelimiter:      ";",
+		QuoteCharacter:       "'",
+		QuoteEscapeCharacter: "\\",
+	}, `a|b;#comment;1|'x\'y|z';2|3`)
+	require.Nil(t, header)
+	require.Equal(t, [][]string{{"1", "x'y|z"}, {"2", "3"}}, rows)
+
+	reader, err := newSelectCSVReader(&SelectCSVInput{}, strings.NewReader(`a,"b`))
+	require.NoError(t, err)
+	_, err = reader.Read()
+	require.Equal(t, SelectErrCSVParsing.ErrorCode, err.(*ErrorCode).ErrorCode)
+}
+
+func TestSelectRecordWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer := newSelectRecordWriter(&SelectOutputSerialization{CSV: &SelectCSVOutput{}})
+	require.NoError(t, writer.Write(&buf, []string{"a", "b", "c"}, []interface{}{"x,y", int64(1), nil}))
+	require.NoError(t, writer.Write(&buf, []string{"a"}, []interface{}{`say "hi"`}))
+	require.Equal(t, "\"x,y\",1,\n\"say \"\"hi\"\"\"\n", buf.String())
+
+	buf.Reset()
+	writer = newSelectRecordWriter(&SelectOutputSerialization{CSV: &SelectCSVOutput{QuoteFields: SelectQuoteFieldsAlways, RecordDelimiter: "\r\n"}})
+	require.NoError(t, writer.Write(&buf, []string{"a", "b"}, []interface{}{"x", 1.5}))
+	require.Equal(t, "\"x\",\"1.5\"\r\n", buf.String())
+
+	buf.Reset()
+	writer = newSelectRecordWriter(&SelectOutputSerialization{JSON: &SelectJSONOutput{RecordDelimiter: ","}})
+	require.NoError(t, writer.Write(&buf, []string{"b", "a", "b"}, []interface{}{"<x>", true, nil}))
+	require.Equal(t, `{"b":"<x>","a":true,"_3":null},`, buf.String())
+}
+
+type selectTestMessage struct {
+	headers map[string]string
+	payload []byte
+}
+
+// decodeSelectTestMessages decodes the event messages and verifies the checksums.
+func decodeSelectTestMessages(t *testing.T, data []byte) (messages []selectTestMessage) {
+	for len(data) > 0 {
+		require.True(t, len(data) >= 16)
+		totalLen := binary.BigEndian.Uint32(data[0:])
+		headersLen := binary.BigEndian.Uint32(data[4:])
+		require.Equal(t, crc32.ChecksumIEEE(data[:8]), binary.BigEndian.Uint32(data[8:]))
+		msg := data[:totalLen]
+		require.Equal(t, crc32.ChecksumIEEE(msg[:totalLen-4]), binary.BigEndian.Uint32(msg[totalLen-4:]))
+
+		m := selectTestMessage{headers: make(map[string]string)}
+		headers := msg[12 : 12+headersLen]
+		for len(headers) > 0 {
+			nameLen := int(headers[0])
+			name := string(headers[1 : 1+nameLen])
+			require.Equal(t, byte(selectHeaderValueTypeString), headers[1+nameLen])
+			valueLen := int(binary.BigEndian.Uint16(headers[2+nameLen:]))
+			m.headers[name] = string(headers[4+nameLen : 4+nameLen+valueLen])
+			headers = headers[4+nameLen+valueLen:]
+		}
+		m.payload = msg[12+headersLen : totalLen-4]
+		messages = append(messages, m)
+		data = data[totalLen:]
+	}
+	return
+}
+
+func TestRunSelectQuery(t *testing.T) {
+	var content bytes.Buffer
+	zw := gzip.NewWriter(&content)
+	data := "name,age\nalice,30\nbob,25\ncarol,35\n"
+	_, err := zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	compressedSize := int64(content.Len())
+
+	req, err := ParseSelectRequest([]byte(`<SelectObjectContentRequest>
+		<Expression>SELECT s.name FROM S3Object s WHERE CAST(s.age AS INT) &gt;= 30</Expression>
+		<ExpressionType>SQL</ExpressionType>
+		<RequestProgress><Enabled>true</Enabled></RequestProgress>
+		<InputSerialization><CompressionType>GZIP</CompressionType><CSV><FileHeaderInfo>USE</FileHeaderInfo></CSV></InputSerialization>
+		<OutputSerialization><JSON/></OutputSerialization>
+	</SelectObjectContentRequest>`))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, runSelectQuery(newSelectEventWriter(&out), req, &content))
+	messages := decodeSelectTestMessages(t, out.Bytes())
+	require.Len(t, messages, 4)
+
+	require.Equal(t, selectEventRecords, messages[0].headers[selectEventHeaderEventType])
+	require.Equal(t, selectMessageTypeEvent, messages[0].headers[selectEventHeaderMessageType])
+	require.Equal(t, "{\"name\":\"alice\"}\n{\"name\":\"carol\"}\n", string(messages[0].payload))
+	require.Equal(t, selectEventProgress, messages[1].headers[selectEventHeaderEventType])
+	require.Equal(t, selectEventStats, messages[2].headers[selectEventHeaderEventType])
+	stats := &SelectStatsMessage{}
+	require.NoError(t, UnmarshalXMLEntity(messages[2].payload, stats))
+	require.Equal(t, compressedSize, stats.BytesScanned)
+	require.Equal(t, int64(len(data)), stats.BytesProcessed)
+	require.Equal(t, int64(len(messages[0].payload)), stats.BytesReturned)
+	require.Equal(t, selectEventEnd, messages[3].headers[selectEventHeaderEventType])
+
+	// the errors of the records are returned by the query
+	countRequest := []byte(`<SelectObjectContentRequest>
+		<Expression>SELECT COUNT(*) FROM S3Object</Expression>
+		<ExpressionType>SQL</ExpressionType>
+		<InputSerialization><JSON><Type>LINES</Type></JSON></InputSerialization>
+		<OutputSerialization><CSV/></OutputSerialization>
+	</SelectObjectContentRequest>`)
+	req, err = ParseSelectRequest(countRequest)
+	require.NoError(t, err)
+	out.Reset()
+	err = runSelectQuery(newSelectEventWriter(&out), req, strings.NewReader(`{"a":1}{"a":`))
+	require.Equal(t, SelectErrJSONParsing.ErrorCode, err.(*ErrorCode).ErrorCode)
+
+	req, err = ParseSelectRequest(countRequest)
+	require.NoError(t, err)
+	out.Reset()
+	require.NoError(t, runSelectQuery(newSelectEventWriter(&out), req, strings.NewReader("{\"a\":1}\n{\"a\":2}\n")))
+	messages = decodeSelectTestMessages(t, out.Bytes())
+	require.Equal(t, "2\n", string(messages[0].payload))
+}
@@ -93,6 +93,17 @@ const (
 	// The configuration in the example will allow ObjectNode to automatically resolve "* .object.cube.io".
 	configDomains = "domains"
 
+	// String array configuration item, used to configure the website endpoints of the buckets. The requests
+	// sent to "<bucket>.<websiteDomain>" are served as static website of the bucket with the index document,
+	// error document and routing rules configured by PutBucketWebsite.
+	// Example:
+	//		{
+	//			"websiteDomains": [
+	//				"website.cube.io"
+	//			]
+	//		}
+	configWebsiteDomains = "websiteDomains"
+
 	disabledActions               = "disabledActions"
 	configSignatureIgnoredActions = "signatureIgnoredActions"
 
@@ -128,6 +139,94 @@ const (
 	// 		}
 	configAuditLog = "auditLog"
 
+	// Map type configuration item, used to configure the bucket replication of ObjectNode. Replication tasks
+	// are persisted in spoolDir. Destination buckets listed in targets are replicated to remote S3 endpoints,
+	// the others are treated as buckets of this cluster. For detailed parameters, see the ReplicationConfig
+	// structure.
+	// Example:
+	//		{
+	//			"replication": {
+	//				"spoolDir": "/cfs/objectnode/replication",
+	//				"workers": 4,
+	//				"maxRetry": 10,
+	//				"targets": {
+	//					"backup-bucket": {
+	//						"endpoint": "s3.backup.cube.io",
+	//						"region": "cfs_backup",
+	//						"accessKey": "...",
+	//						"secretKey": "..."
+	//					}
+	//				}
+	//			}
+	//		}
+	configReplication = "replication"
+
+	// Map type configuration item, used to configure the KMS of server-side encryption. Objects can only be
+	// encrypted with SSE-S3 when KMS is configured. The key file of the local provider must be the same on
+	// all ObjectNodes, see the KMSConfig structure for its format.
+	// Example:
+	//		{
+	//			"kms": {
+	//				"provider": "local",
+	//				"keyFile": "/cfs/objectnode/kms_keys.json"
+	//			}
+	//		}
+	configKMS = "kms"
+
+	// Map type configuration item, used to map the S3 users to the POSIX identities. Object operations
+	// of the mapped users are checked by the POSIX ACLs and permission bits on the volumes with POSIX
+	// ACL enabled, while the other users are not affected.
+	// Example:
+	//		{
+	//			"posixIdentities": {
+	//				"user1": {
+	//					"uid": 1001,
+	//					"gids": [1001, 2000]
+	//				}
+	//			}
+	//		}
+	configPosixIdentities = "posixIdentities"
+
+	// Map type configuration item, used to configure the targets of bucket notifications. Targets are
+	// referenced in bucket notification configurations by ARNs like 'arn:cubefs:sqs::<id>:webhook', and
+	// undelivered events are persisted in spoolDir. For detailed parameters, see the NotificationServerConfig
+	// structure.
+	// Example:
+	//		{
+	//			"notification": {
+	//				"spoolDir": "/cfs/objectnode/notification",
+	//				"maxRetry": 20,
+	//				"webhook": {
+	//					"app1": {
+	//						"enable": true,
+	//						"endpoint": "http://app1.cube.io/events"
+	//					}
+	//				},
+	//				"kafka": {
+	//					"app2": {
+	//						"enable": true,
+	//						"topic": "bucket_events",
+	//						"brokers": "192.168.80.130:9095,192.168.80.131:9095"
+	//					}
+	//				}
+	//			}
+	//		}
+	configNotification = "notification"
+
+	// Map type configuration item, used to configure the identity in authnode to get the master keys of
+	// the volumes with encryption enabled. The file data of the encrypted volumes is encrypted and
+	// decrypted by the ObjectNode, and the client must be allowed to access the volumes by authnode.
+	// Example:
+	//		{
+	//			"volumeEncryption": {
+	//				"clientId": "objectnode",
+	//				"clientKey": "...",
+	//				"authNodes": ["192.168.0.11:8080", "192.168.0.12:8080"],
+	//				"enableHTTPS": false
+	//			}
+	//		}
+	configVolumeEncryption = "volumeEncryption"
+
 	// ObjMetaCache takes each path hierarchy of the path-like S3 object key as the cache key,
 	// and map it to the corresponding posix-compatible inode
 	// when enabled, the maxDentryCacheNum must at least be the minimum of defaultMaxDentryCacheNum
@@ -175,6 +274,7 @@ var (
 	objMetaCache     *ObjMetaCache
 	blockCache       *bcache.BcacheClient
 	ebsClient        *blobstore.BlobStoreClient
+	sseKMS           KMS
 	writeThreads     = 4
 	readThreads      = 4
 	enableBlockcache bool
@@ -194,6 +294,8 @@ type ObjectNode struct {
 
 	localAuditHandler rpc.ProgressHandler
 	externalAudit     *ExternalAudit
+	replicator        *Replicator
+	notifier          *Notifier
 
 	closes []func() // close other resources after http server closed
 
@@ -205,6 +307,10 @@ type ObjectNode struct {
 	rateLimit               RateLimiter
 	limitMutex              sync.RWMutex
 	disableCreateBucketByS3 bool
+
+	websiteDomains []string // website endpoints of the buckets
+
+	posixIdentities map[string]*PosixIdentity // POSIX identities of the S3 users
 }
 
 func (o *ObjectNode) Start(cfg *config.Config) (err error) {
@@ -240,6 +346,10 @@ func (o *ObjectNode) loadConfig(cfg *config.Config) (err error) {
 	}
 	log.LogInfof("loadConfig: setup config: %v(%v)", configDomains, domains)
 
+	// parse website domain
+	o.websiteDomains = cfg.GetStringSlice(configWebsiteDomains)
+	log.LogInfof("loadConfig: setup config: %v(%v)", configWebsiteDomains, o.websiteDomains)
+
 	// parse master config
 	masters := cfg.GetStringSlice(configMasterAddr)
 	if len(masters) == 0 {
@@ -291,10 +401,73 @@ func (o *ObjectNode) loadConfig(cfg *config.Config) (err error) {
 	log.LogInfof("loadConfig: strict: %v", strict)
 	o.disableCreateBucketByS3 = cfg.GetBool(disableCreateBucketByS3)
 
+	// parse volume encryption config
+	var encryption *VolumeEncryptionConfig
+	if rawEncryption := cfg.GetValue(configVolumeEncryption); rawEncryption != nil {
+		encryption = new(VolumeEncryptionConfig)
+		if err = ParseJSONEntity(rawEncryption, encryption); err != nil {
+			err = fmt.Errorf("invalid %v configuration: %v", configVolumeEncryption, err)
+			return
+		}
+		if encryption.ClientID == "" || len(encryption.AuthNodes) == 0 {
+			err = fmt.Errorf("invalid %v configuration: clientId and authNodes are required", configVolumeEncryption)
+			return
+		}
+		log.LogInfof("loadConfig: setup config: %v(clientId: %v, authNodes: %v)", configVolumeEncryption,
+			encryption.ClientID, encryption.AuthNodes)
+	}
+
 	o.mc = master.NewMasterClient(masters, false)
-	o.vm = NewVolumeManager(masters, strict)
+	o.vm = NewVolumeManager(masters, strict, encryption)
 	o.userStore = NewUserInfoStore(masters, strict)
 
+	// parse replication config
+	if rawReplication := cfg.GetValue(configReplication); rawReplication != nil {
+		if err = o.setReplication(rawReplication); err != nil {
+			err = fmt.Errorf("invalid %v configuration: %v", configReplication, err)
+			return
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v)", configReplication, o.replicator.conf.redacted())
+	}
+
+	// parse kms config
+	if rawKMS := cfg.GetValue(configKMS); rawKMS != nil {
+		var kmsConf KMSConfig
+		if err = ParseJSONEntity(rawKMS, &kmsConf); err != nil {
+			err = fmt.Errorf("invalid %v configuration: %v", configKMS, err)
+			return
+		}
+		if sseKMS, err = NewKMS(kmsConf); err != nil {
+			err = fmt.Errorf("invalid %v configuration: %v", configKMS, err)
+			return
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v)", configKMS, rawKMS)
+	}
+
+	// parse posix identities config
+	if rawIdentities := cfg.GetValue(configPosixIdentities); rawIdentities != nil {
+		if err = ParseJSONEntity(rawIdentities, &o.posixIdentities); err != nil {
+			err = fmt.Errorf("invalid %v configuration: %v", configPosixIdentities, err)
+			return
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v)", configPosixIdentities, rawIdentities)
+	}
+
+	// parse notification config
+	if rawNotification := cfg.GetValue(configNotification); rawNotification != nil {
+		var notifyConf NotificationServerConfig
+		if err = ParseJSONEntity(rawNotification, &notifyConf); err != nil {
+			err = fmt.Errorf("invalid %v configuration: %v", configNotification, err)
+			return
+		}
+		if o.notifier, err = NewNotifier(notifyConf); err != nil {
+			err = fmt.Errorf("invalid %v configuration: %v", configNotification, err)
+			return
+		}
+		o.closes = append(o.closes, func() { o.notifier.Close() })
+		log.LogInfof("loadConfig: setup config: %v(%v)", configNotification, rawNotification)
+	}
+
 	// parse inode cache
 	cacheEnable := cfg.GetBool(configObjMetaCache)
 	if cacheEnable {
@@ -370,6 +543,21 @@ func (o *ObjectNode) setAuditLog(raw interface{}) error {
 	return nil
 }
 
+func (o *ObjectNode) setReplication(raw interface{}) error {
+	var conf ReplicationConfig
+	if err := ParseJSONEntity(raw, &conf); err != nil {
+		return err
+	}
+	replicator, err := NewReplicator(conf, o.getVol)
+	if err != nil {
+		return err
+	}
+	o.closes = append(o.closes, func() { replicator.Close() })
+	o.replicator = replicator
+
+	return nil
+}
+
 func handleStart(s common.Server, cfg *config.Config) (err error) {
 	o, ok := s.(*ObjectNode)
 	if !ok {
@@ -416,6 +604,16 @@ func handleStart(s common.Server, cfg *config.Config) (err error) {
 		o.limitMutex.Unlock()
 	}
 
+	// start replication workers
+	if o.replicator != nil {
+		o.replicator.Start()
+	}
+
+	// start notification workers
+	if o.notifier != nil {
+		o.notifier.Start()
+	}
+
 	// start rest api
 	if err = o.startMuxRestAPI(); err != nil {
 		log.LogInfof("handleStart: start rest api fail: err(%v)", err)
@@ -461,6 +659,7 @@ func (o *ObjectNode) startMuxRestAPI() (err error) {
 		o.authMiddleware,
 		o.corsMiddleware,
 		o.policyCheckMiddleware,
+		o.posixACLCheckMiddleware,
 		o.contentMiddleware,
 	)
 
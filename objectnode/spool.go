@@ -0,0 +1,111 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const (
+	spoolTaskSuffix = ".task"
+	spoolTmpSuffix  = ".tmp"
+)
+
+type spoolTask interface {
+	// ID returns the unique name of the task, tasks with the same ID overwrite each other.
+	ID() string
+}
+
+// taskSpool persists the pending tasks of asynchronous workers as files in a local directory,
+// so that they survive restarts.
+type taskSpool struct {
+	name string
+	dir  string
+}
+
+func newTaskSpool(name, dir string) (*taskSpool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &taskSpool{name: name, dir: dir}, nil
+}
+
+func (s *taskSpool) taskPath(id string) string {
+	return filepath.Join(s.dir, id+spoolTaskSuffix)
+}
+
+func (s *taskSpool) save(task spoolTask) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	path := s.taskPath(task.ID())
+	tmpPath := path + spoolTmpSuffix
+	if err = os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (s *taskSpool) remove(task spoolTask) error {
+	if err := os.Remove(s.taskPath(task.ID())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// load unmarshals the persisted tasks into the ones returned by newTask, broken tasks are skipped.
+func (s *taskSpool) load(newTask func() spoolTask) (tasks []spoolTask, err error) {
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(s.dir); err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), spoolTaskSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			log.LogWarnf("%v: read task fail: file(%v) err(%v)", s.name, entry.Name(), err)
+			continue
+		}
+		task := newTask()
+		if err = json.Unmarshal(data, task); err != nil {
+			log.LogWarnf("%v: unmarshal task fail: file(%v) err(%v)", s.name, entry.Name(), err)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// retryBackoff returns the delay before the next retry of a spooled task, which doubles the
+// interval on each retry up to max.
+func retryBackoff(interval, max time.Duration, retries int) time.Duration {
+	backoff := interval
+	for i := 1; i < retries && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff
+}
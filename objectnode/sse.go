@@ -0,0 +1,361 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/serv-side-encryption.html
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+)
+
+const (
+	SSEAlgorithmAES256 = "AES256"
+	SSEAlgorithmKMS    = "aws:kms"
+
+	// Types of server-side encryption of objects
+	SSETypeS3 = "SSE-S3"
+	SSETypeC  = "SSE-C"
+
+	MaxEncryptionSize = 1 << 16 // 64KB
+
+	// Object data is split into chunks which are encrypted with AES-GCM separately, so that
+	// ranges of the object can be read without decrypting the whole object. Each encrypted chunk
+	// is stored as nonce | ciphertext | tag.
+	sseChunkSize    = 64 << 10
+	sseNonceSize    = 12
+	sseTagSize      = 16
+	sseChunkOverlay = sseNonceSize + sseTagSize
+	sseEncChunkSize = sseChunkSize + sseChunkOverlay
+)
+
+var (
+	NoSuchEncryptionConfiguration = &ErrorCode{ErrorCode: "ServerSideEncryptionConfigurationNotFoundError", ErrorMessage: "The server side encryption configuration was not found.", StatusCode: http.StatusNotFound}
+	SSENotConfigured              = &ErrorCode{ErrorCode: "NotImplemented", ErrorMessage: "Server side encryption is not configured on this server.", StatusCode: http.StatusNotImplemented}
+	SSEInvalidAlgorithm           = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The encryption method specified is not supported.", StatusCode: http.StatusBadRequest}
+	SSEInvalidCustomerKey         = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The secret key was invalid for the specified algorithm.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyMD5Mismatch     = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The calculated MD5 hash of the key did not match the hash that was provided.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyRequired        = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyNotExpected     = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The encryption parameters are not applicable to this object.", StatusCode: http.StatusBadRequest}
+	SSEConflictHeaders            = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Server side encryption specified with both SSE-C and SSE-S3 headers.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyIncorrect       = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "The provided customer key does not match the key of the object.", StatusCode: http.StatusForbidden}
+
+	errSSECorrupted = errors.New("sse: encrypted data is corrupted")
+)
+
+// ServerSideEncryptionConfiguration is the default encryption of the bucket.
+type ServerSideEncryptionConfiguration struct {
+	XMLName xml.Name   `xml:"ServerSideEncryptionConfiguration" json:"-"`
+	XMLNS   string     `xml:"xmlns,attr,omitempty" json:"-"`
+	Rules   []*SSERule `xml:"Rule" json:"rules"`
+}
+
+type SSERule struct {
+	ApplySSEByDefault *SSEByDefault `xml:"ApplyServerSideEncryptionByDefault" json:"apply_sse_by_default"`
+	BucketKeyEnabled  bool          `xml:"BucketKeyEnabled,omitempty" json:"bucket_key_enabled,omitempty"`
+}
+
+type SSEByDefault struct {
+	SSEAlgorithm   string `xml:"SSEAlgorithm" json:"sse_algorithm"`
+	KMSMasterKeyID string `xml:"KMSMasterKeyID,omitempty" json:"kms_master_key_id,omitempty"`
+}
+
+func ParseEncryptionConfig(data []byte) (*ServerSideEncryptionConfiguration, *ErrorCode) {
+	config := &ServerSideEncryptionConfiguration{}
+	if err := xml.Unmarshal(data, config); err != nil {
+		return nil, MalformedXML
+	}
+	if len(config.Rules) != 1 || config.Rules[0].ApplySSEByDefault == nil {
+		return nil, MalformedXML
+	}
+	byDefault := config.Rules[0].ApplySSEByDefault
+	if byDefault.SSEAlgorithm != SSEAlgorithmAES256 || byDefault.KMSMasterKeyID != "" {
+		return nil, SSEInvalidAlgorithm
+	}
+	return config, nil
+}
+
+// DefaultAlgorithm returns the algorithm used to encrypt objects which are written without encryption headers.
+func (c *ServerSideEncryptionConfiguration) DefaultAlgorithm() string {
+	if c == nil || len(c.Rules) == 0 || c.Rules[0].ApplySSEByDefault == nil {
+		return ""
+	}
+	return c.Rules[0].ApplySSEByDefault.SSEAlgorithm
+}
+
+func storeBucketEncryption(bytes []byte, vol *Volume) (err error) {
+	return vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSEncryption, bytes)
+}
+
+func deleteBucketEncryption(vol *Volume) (err error) {
+	return vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSEncryption)
+}
+
+// ObjectEncryption is the encryption envelope of an object, which is stored in the xattr of the object.
+// The data key is never stored in plaintext: it is sealed by the KMS for SSE-S3, and sealed by the key
+// provided by the client for SSE-C.
+type ObjectEncryption struct {
+	Type      string    `json:"type"`
+	KeyID     string    `json:"keyId,omitempty"`  // master key of KMS, SSE-S3 only
+	SealedKey []byte    `json:"sealedKey"`        // sealed data key
+	KeyMD5    string    `json:"keyMd5,omitempty"` // base64 encoded MD5 of the customer key, SSE-C only
+	Size      int64     `json:"size"`             // plaintext size of the object
+	Parts     []SSEPart `json:"parts,omitempty"`  // parts of multipart object, nil for object put at once
+
+	dataKey []byte
+}
+
+// SSEPart is a part of the encrypted multipart object, each part is encrypted separately.
+type SSEPart struct {
+	Number uint16 `json:"number"`
+	Size   int64  `json:"size"` // plaintext size of the part
+}
+
+// newSSES3Encryption returns a new SSE-S3 envelope whose data key is generated by the KMS.
+func newSSES3Encryption() (enc *ObjectEncryption, err error) {
+	if sseKMS == nil {
+		return nil, SSENotConfigured
+	}
+	enc = &ObjectEncryption{Type: SSETypeS3, KeyID: sseKMS.DefaultKeyID()}
+	if enc.dataKey, enc.SealedKey, err = sseKMS.GenerateDataKey(enc.KeyID); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+func parseObjectEncryption(raw string) (*ObjectEncryption, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	enc := &ObjectEncryption{}
+	if err := json.Unmarshal([]byte(raw), enc); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+func (e *ObjectEncryption) Encode() string {
+	data, _ := json.Marshal(e)
+	return string(data)
+}
+
+// Algorithm returns the algorithm returned by response headers.
+func (e *ObjectEncryption) Algorithm() string {
+	return SSEAlgorithmAES256
+}
+
+// segments returns the separately encrypted segments of the object.
+func (e *ObjectEncryption) segments() []SSEPart {
+	if len(e.Parts) > 0 {
+		return e.Parts
+	}
+	return []SSEPart{{Number: 0, Size: e.Size}}
+}
+
+// EncryptedSize returns the size of the encrypted data stored in the inode.
+func (e *ObjectEncryption) EncryptedSize() uint64 {
+	var size uint64
+	for _, seg := range e.segments() {
+		size += sseEncryptedSize(uint64(seg.Size))
+	}
+	return size
+}
+
+func sseEncryptedSize(size uint64) uint64 {
+	chunks := (size + sseChunkSize - 1) / sseChunkSize
+	return size + chunks*sseChunkOverlay
+}
+
+func sseChunkAdditional(part uint16, chunk uint64) []byte {
+	ad := make([]byte, 10)
+	binary.BigEndian.PutUint16(ad, part)
+	binary.BigEndian.PutUint64(ad[2:], chunk)
+	return ad
+}
+
+// sseEncryptReader encrypts the data read from the source reader chunk by chunk.
+type sseEncryptReader struct {
+	src   io.Reader
+	aead  cipher.AEAD
+	part  uint16
+	chunk uint64
+	plain []byte
+	buf   []byte // encrypted chunk not yet read
+	err   error
+}
+
+func newSSEEncryptReader(src io.Reader, key []byte, part uint16) (*sseEncryptReader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &sseEncryptReader{
+		src:   src,
+		aead:  aead,
+		part:  part,
+		plain: make([]byte, sseChunkSize),
+	}, nil
+}
+
+func (r *sseEncryptReader) Read(p []byte) (n int, err error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		var m int
+		m, r.err = io.ReadFull(r.src, r.plain)
+		if r.err == io.ErrUnexpectedEOF {
+			r.err = io.EOF
+		}
+		if m == 0 {
+			continue
+		}
+		out := make([]byte, sseNonceSize, sseEncChunkSize)
+		if _, err = io.ReadFull(rand.Reader, out); err != nil {
+			return 0, err
+		}
+		r.buf = r.aead.Seal(out, out, r.plain[:m], sseChunkAdditional(r.part, r.chunk))
+		r.chunk++
+	}
+	n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// sseDecryptWriter decrypts the encrypted chunks of a segment written to it, skips the leading
+// plaintext which is not requested and writes the remaining to the destination writer.
+type sseDecryptWriter struct {
+	dst       io.Writer
+	aead      cipher.AEAD
+	part      uint16
+	chunk     uint64
+	skip      uint64
+	remaining uint64
+	buf       []byte
+}
+
+func (w *sseDecryptWriter) Write(p []byte) (n int, err error) {
+	n = len(p)
+	for len(p) > 0 {
+		m := sseEncChunkSize - len(w.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		w.buf = append(w.buf, p[:m]...)
+		p = p[m:]
+		if len(w.buf) == sseEncChunkSize {
+			if err = w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush decrypts the buffered chunk, the last chunk of the segment may be smaller than the others.
+func (w *sseDecryptWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if len(w.buf) <= sseChunkOverlay {
+		return errSSECorrupted
+	}
+	plain, err := w.aead.Open(w.buf[sseNonceSize:sseNonceSize], w.buf[:sseNonceSize], w.buf[sseNonceSize:],
+		sseChunkAdditional(w.part, w.chunk))
+	if err != nil {
+		return errSSECorrupted
+	}
+	w.buf = w.buf[:0]
+	w.chunk++
+	if w.skip >= uint64(len(plain)) {
+		w.skip -= uint64(len(plain))
+		return nil
+	}
+	plain = plain[w.skip:]
+	w.skip = 0
+	if uint64(len(plain)) > w.remaining {
+		plain = plain[:w.remaining]
+	}
+	w.remaining -= uint64(len(plain))
+	if len(plain) == 0 {
+		return nil
+	}
+	_, err = w.dst.Write(plain)
+	return err
+}
+
+// sseReadRange reads the plaintext in range [offset, offset+size) of the encrypted object. The function
+// read is used to read the encrypted data of the object in the specified range.
+func sseReadRange(enc *ObjectEncryption, writer io.Writer, offset, size uint64,
+	read func(writer io.Writer, offset, size uint64) error) (err error) {
+	aead, err := newGCM(enc.dataKey)
+	if err != nil {
+		return err
+	}
+	var plainStart, encStart uint64
+	end := offset + size
+	for _, seg := range enc.segments() {
+		segSize := uint64(seg.Size)
+		segEncSize := sseEncryptedSize(segSize)
+		plainEnd := plainStart + segSize
+		if plainEnd > offset && plainStart < end && segSize > 0 {
+			lower := offset
+			if lower < plainStart {
+				lower = plainStart
+			}
+			upper := end
+			if upper > plainEnd {
+				upper = plainEnd
+			}
+			firstChunk := (lower - plainStart) / sseChunkSize
+			lastChunk := (upper - plainStart - 1) / sseChunkSize
+			readOffset := encStart + firstChunk*sseEncChunkSize
+			readEnd := encStart + (lastChunk+1)*sseEncChunkSize
+			if readEnd > encStart+segEncSize {
+				readEnd = encStart + segEncSize
+			}
+			w := &sseDecryptWriter{
+				dst:       writer,
+				aead:      aead,
+				part:      seg.Number,
+				chunk:     firstChunk,
+				skip:      lower - plainStart - firstChunk*sseChunkSize,
+				remaining: upper - lower,
+				buf:       make([]byte, 0, sseEncChunkSize),
+			}
+			if err = read(w, readOffset, readEnd-readOffset); err != nil {
+				return err
+			}
+			if err = w.flush(); err != nil {
+				return err
+			}
+			if w.remaining > 0 {
+				return errSSECorrupted
+			}
+		}
+		plainStart = plainEnd
+		encStart += segEncSize
+		if plainStart >= end {
+			break
+		}
+	}
+	return nil
+}
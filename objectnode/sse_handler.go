@@ -0,0 +1,327 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"syscall"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// Put bucket encryption
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketEncryption.html
+func (o *ObjectNode) putBucketEncryptionHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putBucketEncryptionHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	if sseKMS == nil {
+		errorCode = SSENotConfigured
+		return
+	}
+
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(r.Body, MaxEncryptionSize+1)); err != nil {
+		log.LogErrorf("putBucketEncryptionHandler: read request body fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if len(body) > MaxEncryptionSize {
+		errorCode = EntityTooLarge
+		return
+	}
+	var config *ServerSideEncryptionConfiguration
+	if config, errorCode = ParseEncryptionConfig(body); errorCode != nil {
+		log.LogErrorf("putBucketEncryptionHandler: parse encryption config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), errorCode)
+		return
+	}
+	if body, err = json.Marshal(config); err != nil {
+		log.LogErrorf("putBucketEncryptionHandler: json marshal encryption config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+	if err = storeBucketEncryption(body, vol); err != nil {
+		log.LogErrorf("putBucketEncryptionHandler: store encryption config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), err)
+		return
+	}
+	vol.metaLoader.storeEncryption(config)
+
+	log.LogInfof("Audit: put bucket encryption: requestID(%v) volume(%v) config(%v)",
+		GetRequestID(r), vol.Name(), string(body))
+	return
+}
+
+// Get bucket encryption
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketEncryption.html
+func (o *ObjectNode) getBucketEncryptionHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getBucketEncryptionHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var config *ServerSideEncryptionConfiguration
+	if config, err = vol.metaLoader.loadEncryption(); err != nil {
+		log.LogErrorf("getBucketEncryptionHandler: load encryption fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if config.DefaultAlgorithm() == "" {
+		errorCode = NoSuchEncryptionConfiguration
+		return
+	}
+	config.XMLNS = XMLNS
+
+	var data []byte
+	if data, err = MarshalXMLEntity(config); err != nil {
+		log.LogErrorf("getBucketEncryptionHandler: xml marshal fail: requestID(%v) volume(%v) config(%+v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+
+	writeSuccessResponseXML(w, data)
+	return
+}
+
+// Delete bucket encryption
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketEncryption.html
+func (o *ObjectNode) deleteBucketEncryptionHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("deleteBucketEncryptionHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	if err = deleteBucketEncryption(vol); err != nil {
+		log.LogErrorf("deleteBucketEncryptionHandler: delete encryption config fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	vol.metaLoader.storeEncryption(nil)
+
+	log.LogInfof("Audit: delete bucket encryption: requestID(%v) volume(%v)", GetRequestID(r), vol.Name())
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
+
+// parseSSECustomerKey parses the SSE-C headers of the request, the headers of the copy source are parsed
+// if copySource is true. It returns nil key if no customer key is specified.
+func parseSSECustomerKey(r *http.Request, copySource bool) (key []byte, keyMD5 string, errorCode *ErrorCode) {
+	algorithmHeader, keyHeader, keyMD5Header := XAmzServerSideEncryptionCustomerAlgorithm,
+		XAmzServerSideEncryptionCustomerKey, XAmzServerSideEncryptionCustomerKeyMD5
+	if copySource {
+		algorithmHeader, keyHeader, keyMD5Header = XAmzCopySourceServerSideEncryptionCustomerAlgorithm,
+			XAmzCopySourceServerSideEncryptionCustomerKey, XAmzCopySourceServerSideEncryptionCustomerKeyMD5
+	}
+	algorithm, encodedKey, keyMD5 := r.Header.Get(algorithmHeader), r.Header.Get(keyHeader), r.Header.Get(keyMD5Header)
+	if algorithm == "" && encodedKey == "" && keyMD5 == "" {
+		return
+	}
+	if algorithm != SSEAlgorithmAES256 {
+		errorCode = SSEInvalidAlgorithm
+		return
+	}
+	var err error
+	if key, err = base64.StdEncoding.DecodeString(encodedKey); err != nil || len(key) != sseDataKeySize {
+		errorCode = SSEInvalidCustomerKey
+		return
+	}
+	if keyMD5 != GetMD5(key) {
+		errorCode = SSECustomerKeyMD5Mismatch
+		return
+	}
+	return
+}
+
+// newObjectEncryption returns the encryption envelope of the object to be written, which is specified by the
+// request headers or the default encryption of the bucket. It returns nil if the object is not to be encrypted.
+func newObjectEncryption(r *http.Request, vol *Volume) (enc *ObjectEncryption, errorCode *ErrorCode, err error) {
+	var (
+		customerKey []byte
+		keyMD5      string
+	)
+	if customerKey, keyMD5, errorCode = parseSSECustomerKey(r, false); errorCode != nil {
+		return
+	}
+	algorithm := r.Header.Get(XAmzServerSideEncryption)
+	if customerKey != nil {
+		if algorithm != "" {
+			errorCode = SSEConflictHeaders
+			return
+		}
+		enc = &ObjectEncryption{Type: SSETypeC, KeyMD5: keyMD5, dataKey: make([]byte, sseDataKeySize)}
+		if _, err = io.ReadFull(rand.Reader, enc.dataKey); err != nil {
+			return nil, nil, err
+		}
+		enc.SealedKey, err = sealSSECustomerDataKey(customerKey, enc.dataKey)
+		return
+	}
+
+	if algorithm == "" {
+		var config *ServerSideEncryptionConfiguration
+		if config, err = vol.metaLoader.loadEncryption(); err != nil {
+			return
+		}
+		if algorithm = config.DefaultAlgorithm(); algorithm == "" {
+			return
+		}
+	}
+	if algorithm != SSEAlgorithmAES256 {
+		errorCode = SSEInvalidAlgorithm
+		return
+	}
+	if sseKMS == nil {
+		errorCode = SSENotConfigured
+		return
+	}
+	enc, err = newSSES3Encryption()
+	return
+}
+
+// unsealObjectKey unseals the data key of the encrypted object, with the customer key of the request for SSE-C
+// and with the KMS for SSE-S3. The headers of the copy source are used if copySource is true.
+func unsealObjectKey(r *http.Request, enc *ObjectEncryption, copySource bool) (errorCode *ErrorCode, err error) {
+	var (
+		customerKey []byte
+		keyMD5      string
+	)
+	if customerKey, keyMD5, errorCode = parseSSECustomerKey(r, copySource); errorCode != nil {
+		return
+	}
+	if enc == nil {
+		if customerKey != nil {
+			errorCode = SSECustomerKeyNotExpected
+		}
+		return
+	}
+	switch enc.Type {
+	case SSETypeC:
+		if customerKey == nil {
+			errorCode = SSECustomerKeyRequired
+			return
+		}
+		if keyMD5 != enc.KeyMD5 {
+			errorCode = SSECustomerKeyIncorrect
+			return
+		}
+		if enc.dataKey, err = unsealSSECustomerDataKey(customerKey, enc.SealedKey); err != nil {
+			errorCode = SSECustomerKeyIncorrect
+			err = nil
+		}
+	default:
+		if sseKMS == nil {
+			errorCode = SSENotConfigured
+			return
+		}
+		enc.dataKey, err = sseKMS.UnsealDataKey(enc.KeyID, enc.SealedKey)
+	}
+	return
+}
+
+// loadMultipartEncryption returns the encryption envelope of the multipart upload with the data key unsealed,
+// all parts of the upload are encrypted with the same data key.
+func loadMultipartEncryption(r *http.Request, vol *Volume, path, uploadId string) (enc *ObjectEncryption, errorCode *ErrorCode, err error) {
+	var info *proto.MultipartInfo
+	if info, err = vol.mw.GetMultipart_ll(path, uploadId); err != nil {
+		if err == syscall.ENOENT {
+			return nil, NoSuchUpload, nil
+		}
+		return
+	}
+	if enc, err = parseObjectEncryption(info.Extend[XAttrKeyOSSSSE]); err != nil {
+		return
+	}
+	errorCode, err = unsealObjectKey(r, enc, false)
+	return
+}
+
+func sealSSECustomerDataKey(customerKey, dataKey []byte) ([]byte, error) {
+	aead, err := newGCM(customerKey)
+	if err != nil {
+		return nil, err
+	}
+	return sealKey(aead, dataKey, []byte(SSETypeC))
+}
+
+func unsealSSECustomerDataKey(customerKey, sealed []byte) ([]byte, error) {
+	aead, err := newGCM(customerKey)
+	if err != nil {
+		return nil, err
+	}
+	return unsealKey(aead, sealed, []byte(SSETypeC))
+}
+
+// setSSEResponseHeaders sets the encryption headers of the response for the encrypted object.
+func setSSEResponseHeaders(w http.ResponseWriter, enc *ObjectEncryption) {
+	if enc == nil {
+		return
+	}
+	if enc.Type == SSETypeC {
+		w.Header()[XAmzServerSideEncryptionCustomerAlgorithm] = []string{enc.Algorithm()}
+		w.Header()[XAmzServerSideEncryptionCustomerKeyMD5] = []string{enc.KeyMD5}
+		return
+	}
+	w.Header()[XAmzServerSideEncryption] = []string{enc.Algorithm()}
+}
@@ -0,0 +1,217 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEncryptionConfig(t *testing.T) {
+	tests := []struct {
+		value       string
+		expectedErr *ErrorCode
+	}{
+		{
+			value: `<ServerSideEncryptionConfiguration>
+						<Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>AES256</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule>
+					</ServerSideEncryptionConfiguration>`,
+		},
+		{
+			value: `<ServerSideEncryptionConfiguration>
+						<Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>aws:kms</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule>
+					</ServerSideEncryptionConfiguration>`,
+			expectedErr: SSEInvalidAlgorithm,
+		},
+		{
+			value:       `<ServerSideEncryptionConfiguration></ServerSideEncryptionConfiguration>`,
+			expectedErr: MalformedXML,
+		},
+		{
+			value:       `<ServerSideEncryptionConfiguration><Rule></Rule></ServerSideEncryptionConfiguration>`,
+			expectedErr: MalformedXML,
+		},
+	}
+	for _, tt := range tests {
+		config, errCode := ParseEncryptionConfig([]byte(tt.value))
+		if tt.expectedErr != nil {
+			require.Equal(t, tt.expectedErr, errCode)
+			continue
+		}
+		require.Nil(t, errCode)
+		require.Equal(t, SSEAlgorithmAES256, config.DefaultAlgorithm())
+	}
+
+	var nilConfig *ServerSideEncryptionConfiguration
+	require.Equal(t, "", nilConfig.DefaultAlgorithm())
+}
+
+func TestLocalKMS(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kms")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	masterKey := make([]byte, sseDataKeySize)
+	_, err = rand.Read(masterKey)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "keys.json")
+	content := fmt.Sprintf(`{"defaultKeyId":"k1","keys":{"k1":"%v"}}`, base64.StdEncoding.EncodeToString(masterKey))
+	require.NoError(t, os.WriteFile(keyFile, []byte(content), 0o600))
+
+	kms, err := NewKMS(KMSConfig{Provider: KMSProviderLocal, KeyFile: keyFile})
+	require.NoError(t, err)
+	require.Equal(t, "k1", kms.DefaultKeyID())
+
+	key, sealed, err := kms.GenerateDataKey("k1")
+	require.NoError(t, err)
+	require.Len(t, key, sseDataKeySize)
+	unsealed, err := kms.UnsealDataKey("k1", sealed)
+	require.NoError(t, err)
+	require.Equal(t, key, unsealed)
+
+	sealed[len(sealed)-1] ^= 0xff
+	_, err = kms.UnsealDataKey("k1", sealed)
+	require.Equal(t, ErrKMSInvalidSealed, err)
+	_, _, err = kms.GenerateDataKey("k2")
+	require.Equal(t, ErrKMSKeyNotFound, err)
+
+	_, err = NewKMS(KMSConfig{Provider: "vault"})
+	require.Error(t, err)
+}
+
+func encryptSegments(t *testing.T, enc *ObjectEncryption, plain []byte) []byte {
+	var (
+		encrypted bytes.Buffer
+		offset    int64
+	)
+	for _, seg := range enc.segments() {
+		reader, err := newSSEEncryptReader(bytes.NewReader(plain[offset:offset+seg.Size]), enc.dataKey, seg.Number)
+		require.NoError(t, err)
+		_, err = io.Copy(&encrypted, reader)
+		require.NoError(t, err)
+		offset += seg.Size
+	}
+	require.Equal(t, enc.EncryptedSize(), uint64(encrypted.Len()))
+	return encrypted.Bytes()
+}
+
+func TestSSEReadRange(t *testing.T) {
+	dataKey := make([]byte, sseDataKeySize)
+	_, err := rand.Read(dataKey)
+	require.NoError(t, err)
+
+	tests := []*ObjectEncryption{
+		{Size: 0},
+		{Size: 100},
+		{Size: sseChunkSize},
+		{Size: 3*sseChunkSize + 17},
+		{Size: 5*sseChunkSize + 3, Parts: []SSEPart{{Number: 1, Size: 2*sseChunkSize + 1}, {Number: 2, Size: 2}, {Number: 4, Size: 3 * sseChunkSize}}},
+	}
+	for _, enc := range tests {
+		enc.dataKey = dataKey
+		plain := make([]byte, enc.Size)
+		_, err = rand.Read(plain)
+		require.NoError(t, err)
+		encrypted := encryptSegments(t, enc, plain)
+		read := func(w io.Writer, offset, size uint64) error {
+			_, err := w.Write(encrypted[offset : offset+size])
+			return err
+		}
+
+		ranges := [][2]uint64{{0, uint64(enc.Size)}, {1, 10}, {sseChunkSize - 1, 2}, {sseChunkSize, sseChunkSize}, {uint64(enc.Size) / 3, uint64(enc.Size) / 2}}
+		for _, rg := range ranges {
+			offset, size := rg[0], rg[1]
+			if size == 0 || offset+size > uint64(enc.Size) {
+				continue
+			}
+			var out bytes.Buffer
+			require.NoError(t, sseReadRange(enc, &out, offset, size, read))
+			require.Equal(t, plain[offset:offset+size], out.Bytes(), "size(%v) offset(%v) n(%v)", enc.Size, offset, size)
+		}
+	}
+}
+
+func TestSSEDetectTampering(t *testing.T) {
+	enc := &ObjectEncryption{Size: 2*sseChunkSize + 1, dataKey: make([]byte, sseDataKeySize)}
+	plain := make([]byte, enc.Size)
+	encrypted := encryptSegments(t, enc, plain)
+	encrypted[sseEncChunkSize+sseNonceSize] ^= 0x01
+	read := func(w io.Writer, offset, size uint64) error {
+		_, err := w.Write(encrypted[offset : offset+size])
+		return err
+	}
+	require.NoError(t, sseReadRange(enc, io.Discard, 0, sseChunkSize, read))
+	require.Equal(t, errSSECorrupted, sseReadRange(enc, io.Discard, 0, uint64(enc.Size), read))
+
+	// chunks can not be reordered between parts
+	enc = &ObjectEncryption{Size: 2, Parts: []SSEPart{{Number: 1, Size: 1}, {Number: 2, Size: 1}}, dataKey: enc.dataKey}
+	encrypted = encryptSegments(t, enc, []byte{1, 2})
+	swapped := append(append([]byte{}, encrypted[len(encrypted)/2:]...), encrypted[:len(encrypted)/2]...)
+	read = func(w io.Writer, offset, size uint64) error {
+		_, err := w.Write(swapped[offset : offset+size])
+		return err
+	}
+	require.Equal(t, errSSECorrupted, sseReadRange(enc, io.Discard, 0, 2, read))
+}
+
+func TestSSECustomerKey(t *testing.T) {
+	customerKey := make([]byte, sseDataKeySize)
+	_, err := rand.Read(customerKey)
+	require.NoError(t, err)
+
+	r, err := http.NewRequest("PUT", "http://s3.cubefs.com/bucket/key", nil)
+	require.NoError(t, err)
+	key, _, errCode := parseSSECustomerKey(r, false)
+	require.Nil(t, errCode)
+	require.Nil(t, key)
+
+	r.Header.Set(XAmzServerSideEncryptionCustomerAlgorithm, SSEAlgorithmAES256)
+	r.Header.Set(XAmzServerSideEncryptionCustomerKey, base64.StdEncoding.EncodeToString(customerKey))
+	r.Header.Set(XAmzServerSideEncryptionCustomerKeyMD5, GetMD5(customerKey))
+	enc, errCode, err := newObjectEncryption(r, nil)
+	require.NoError(t, err)
+	require.Nil(t, errCode)
+	require.Equal(t, SSETypeC, enc.Type)
+	dataKey := enc.dataKey
+
+	enc.dataKey = nil
+	errCode, err = unsealObjectKey(r, enc, false)
+	require.NoError(t, err)
+	require.Nil(t, errCode)
+	require.Equal(t, dataKey, enc.dataKey)
+
+	// copy source headers are used for the source object
+	errCode, _ = unsealObjectKey(r, enc, true)
+	require.Equal(t, SSECustomerKeyRequired, errCode)
+
+	otherKey := make([]byte, sseDataKeySize)
+	r.Header.Set(XAmzServerSideEncryptionCustomerKey, base64.StdEncoding.EncodeToString(otherKey))
+	r.Header.Set(XAmzServerSideEncryptionCustomerKeyMD5, GetMD5(otherKey))
+	errCode, _ = unsealObjectKey(r, enc, false)
+	require.Equal(t, SSECustomerKeyIncorrect, errCode)
+
+	r.Header.Set(XAmzServerSideEncryptionCustomerKeyMD5, GetMD5(customerKey))
+	_, errCode, _ = newObjectEncryption(r, nil)
+	require.Equal(t, SSECustomerKeyMD5Mismatch, errCode)
+}
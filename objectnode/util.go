@@ -19,7 +19,6 @@ import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
-	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
 	"fmt"
@@ -405,21 +404,3 @@ func GetRootCAs(file ...string) (*x509.CertPool, error) {
 
 	return rootCAs, nil
 }
-
-// NewTLSConfig creates a new tls.Config object for configuring TLS settings.
-// clientCert: file path to the client's certificate.
-// clientKey: file path to the client's private key.
-func NewTLSConfig(clientCert, clientKey string) (*tls.Config, error) {
-	tlsConfig := tls.Config{
-		MinVersion: tls.VersionTLS12,
-	}
-	if clientCert != "" && clientKey != "" {
-		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
-		if err != nil {
-			return &tlsConfig, err
-		}
-		tlsConfig.Certificates = []tls.Certificate{cert}
-	}
-
-	return &tlsConfig, nil
-}
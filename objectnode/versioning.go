@@ -0,0 +1,155 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/Versioning.html
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+)
+
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+
+	// NullVersionId is the version ID of objects which were written while versioning
+	// was never enabled or was suspended.
+	NullVersionId = "null"
+
+	// VersionsDirName is the hidden directory under the volume root in which noncurrent
+	// object versions and delete markers are kept. It mirrors the directory layout of
+	// the bucket and each entry is named as "<object name>#<version id>".
+	VersionsDirName = proto.S3VersionsDirName
+	versionNameSep  = "#"
+
+	MaxVersioningSize = 1 << 10 // 1KB
+)
+
+type VersioningConfiguration struct {
+	XMLNS     string    `xml:"xmlns,attr,omitempty" json:"-"`
+	XMLName   *xml.Name `xml:"VersioningConfiguration" json:"-"`
+	Status    string    `xml:"Status,omitempty" json:"status,omitempty"`
+	MfaDelete string    `xml:"MfaDelete,omitempty" json:"-"`
+}
+
+func (c *VersioningConfiguration) IsEnabled() bool {
+	return c != nil && c.Status == VersioningEnabled
+}
+
+func (c *VersioningConfiguration) IsSuspended() bool {
+	return c != nil && c.Status == VersioningSuspended
+}
+
+// check whether versioning has ever been enabled on the bucket
+func (c *VersioningConfiguration) IsConfigured() bool {
+	return c.IsEnabled() || c.IsSuspended()
+}
+
+func ParseVersioningConfig(data []byte) (*VersioningConfiguration, *ErrorCode) {
+	config := &VersioningConfiguration{}
+	if err := xml.Unmarshal(data, config); err != nil {
+		return nil, MalformedXML
+	}
+	switch config.Status {
+	case VersioningEnabled, VersioningSuspended:
+	default:
+		return nil, MalformedXML
+	}
+	if config.MfaDelete == VersioningEnabled {
+		return nil, NewError("InvalidArgument", "MFA delete is not supported.", 400)
+	}
+	return config, nil
+}
+
+// newVersionId returns a version ID which sorts in creation order.
+func newVersionId() string {
+	return fmt.Sprintf("%016x%08x", time.Now().UnixNano(), rand.Uint32())
+}
+
+// versionEntryName returns the dentry name of a noncurrent version in the versions directory.
+func versionEntryName(name, versionId string) string {
+	return name + versionNameSep + versionId
+}
+
+// parseVersionEntryName splits a dentry name of the versions directory into object name and version ID.
+func parseVersionEntryName(entry string) (name, versionId string, ok bool) {
+	idx := strings.LastIndex(entry, versionNameSep)
+	if idx <= 0 || idx == len(entry)-1 {
+		return "", "", false
+	}
+	return entry[:idx], entry[idx+1:], true
+}
+
+// versionsPath returns the path under the versions directory for the specified object path.
+func versionsPath(path string) string {
+	return VersionsDirName + pathSep + strings.TrimPrefix(path, pathSep)
+}
+
+// isVersionsDir checks whether the dentry is the hidden versions directory of the volume root.
+func isVersionsDir(dirs []string, name string) bool {
+	return len(dirs) == 0 && name == VersionsDirName
+}
+
+// isVersionsKey checks whether the key or the prefix is under the hidden versions directory, which is never
+// accessed by the requests of the users, or the noncurrent versions could be changed bypassing versioning
+// and object lock.
+func isVersionsKey(key string) bool {
+	return strings.SplitN(strings.TrimPrefix(key, pathSep), pathSep, 2)[0] == VersionsDirName
+}
+
+type ObjectVersion struct {
+	XMLName      xml.Name     `xml:"Version"`
+	Key          string       `xml:"Key"`
+	VersionId    string       `xml:"VersionId"`
+	IsLatest     bool         `xml:"IsLatest"`
+	LastModified string       `xml:"LastModified"`
+	ETag         string       `xml:"ETag"`
+	Size         int64        `xml:"Size"`
+	StorageClass string       `xml:"StorageClass"`
+	Owner        *BucketOwner `xml:"Owner,omitempty"`
+}
+
+type DeleteMarkerEntry struct {
+	XMLName      xml.Name     `xml:"DeleteMarker"`
+	Key          string       `xml:"Key"`
+	VersionId    string       `xml:"VersionId"`
+	IsLatest     bool         `xml:"IsLatest"`
+	LastModified string       `xml:"LastModified"`
+	Owner        *BucketOwner `xml:"Owner,omitempty"`
+}
+
+type ListVersionsResult struct {
+	XMLName             xml.Name             `xml:"ListVersionsResult"`
+	XMLNS               string               `xml:"xmlns,attr,omitempty"`
+	Name                string               `xml:"Name"`
+	Prefix              string               `xml:"Prefix"`
+	KeyMarker           string               `xml:"KeyMarker"`
+	VersionIdMarker     string               `xml:"VersionIdMarker"`
+	NextKeyMarker       string               `xml:"NextKeyMarker,omitempty"`
+	NextVersionIdMarker string               `xml:"NextVersionIdMarker,omitempty"`
+	MaxKeys             uint64               `xml:"MaxKeys"`
+	Delimiter           string               `xml:"Delimiter,omitempty"`
+	EncodingType        string               `xml:"EncodingType,omitempty"`
+	IsTruncated         bool                 `xml:"IsTruncated"`
+	Versions            []*ObjectVersion     `xml:"Version"`
+	DeleteMarkers       []*DeleteMarkerEntry `xml:"DeleteMarker"`
+	CommonPrefixes      []*CommonPrefix      `xml:"CommonPrefixes"`
+}
@@ -0,0 +1,281 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// Put bucket versioning
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketVersioning.html
+func (o *ObjectNode) putBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putBucketVersioningHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(r.Body, MaxVersioningSize+1)); err != nil {
+		log.LogErrorf("putBucketVersioningHandler: read request body fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if len(body) > MaxVersioningSize {
+		errorCode = EntityTooLarge
+		return
+	}
+	var config *VersioningConfiguration
+	if config, errorCode = ParseVersioningConfig(body); errorCode != nil {
+		log.LogErrorf("putBucketVersioningHandler: parse versioning config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), errorCode)
+		return
+	}
+
+	// versioning can not be suspended once object lock is enabled
+	if config.IsSuspended() {
+		var objectLock *ObjectLockConfig
+		if objectLock, err = vol.metaLoader.loadObjectLock(); err != nil {
+			log.LogErrorf("putBucketVersioningHandler: load object lock fail: requestID(%v) volume(%v) err(%v)",
+				GetRequestID(r), vol.Name(), err)
+			return
+		}
+		if objectLock != nil {
+			errorCode = InvalidBucketState
+			return
+		}
+	}
+
+	if body, err = json.Marshal(config); err != nil {
+		log.LogErrorf("putBucketVersioningHandler: json marshal versioning config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSVersioning, body); err != nil {
+		log.LogErrorf("putBucketVersioningHandler: store versioning config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), err)
+		return
+	}
+	vol.metaLoader.storeVersioning(config)
+
+	log.LogInfof("Audit: put bucket versioning: requestID(%v) volume(%v) status(%v)",
+		GetRequestID(r), vol.Name(), config.Status)
+	return
+}
+
+// Get bucket versioning
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketVersioning.html
+func (o *ObjectNode) getBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getBucketVersioningHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var config *VersioningConfiguration
+	if config, err = vol.metaLoader.loadVersioning(); err != nil {
+		log.LogErrorf("getBucketVersioningHandler: load versioning fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	// an empty configuration is returned if versioning has never been enabled on the bucket
+	result := &VersioningConfiguration{XMLNS: XMLNS}
+	if config != nil {
+		result.Status = config.Status
+	}
+
+	var data []byte
+	if data, err = MarshalXMLEntity(result); err != nil {
+		log.LogErrorf("getBucketVersioningHandler: xml marshal fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), result, err)
+		return
+	}
+
+	writeSuccessResponseXML(w, data)
+	return
+}
+
+// List object versions
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectVersions.html
+func (o *ObjectNode) listObjectVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("listObjectVersionsHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	// QPS and Concurrency Limit
+	rateLimit := o.AcquireRateLimiter()
+	if err = rateLimit.AcquireLimitResource(vol.owner, param.apiName); err != nil {
+		return
+	}
+	defer rateLimit.ReleaseLimitResource(vol.owner, param.apiName)
+
+	// get options
+	prefix := r.URL.Query().Get(ParamPrefix)
+	delimiter := r.URL.Query().Get(ParamPartDelimiter)
+	keyMarker := r.URL.Query().Get(ParamKeyMarker)
+	versionIdMarker := r.URL.Query().Get(ParamVersionIdMarker)
+	maxKeys := r.URL.Query().Get(ParamMaxKeys)
+	encodingType := r.URL.Query().Get(ParamEncodingType)
+	if isVersionsKey(prefix) || isVersionsKey(keyMarker) {
+		errorCode = InvalidArgument
+		return
+	}
+
+	var maxKeysInt uint64
+	if maxKeys != "" {
+		if maxKeysInt, err = strconv.ParseUint(maxKeys, 10, 16); err != nil {
+			log.LogErrorf("listObjectVersionsHandler: parse max keys fail: requestID(%v) volume(%v) maxKeys(%v) err(%v)",
+				GetRequestID(r), vol.Name(), maxKeys, err)
+			errorCode = InvalidArgument
+			return
+		}
+		if maxKeysInt > MaxKeys {
+			maxKeysInt = MaxKeys
+		}
+	} else {
+		maxKeysInt = uint64(MaxKeys)
+	}
+	if encodingType != "" && encodingType != "url" {
+		errorCode = InvalidArgument
+		return
+	}
+	if versionIdMarker != "" && keyMarker == "" {
+		errorCode = NewError("InvalidArgument", "A version-id marker cannot be specified without a key marker.", http.StatusBadRequest)
+		return
+	}
+	if keyMarker != "" && prefix != "" && !strings.HasPrefix(keyMarker, prefix) {
+		errorCode = InvalidArgument
+		return
+	}
+
+	option := &ListObjectVersionsOption{
+		Prefix:          prefix,
+		Delimiter:       delimiter,
+		KeyMarker:       keyMarker,
+		VersionIdMarker: versionIdMarker,
+		MaxKeys:         maxKeysInt,
+	}
+	var result *ListObjectVersionsResult
+	if result, err = vol.ListObjectVersions(option); err != nil {
+		log.LogErrorf("listObjectVersionsHandler: list object versions fail: requestID(%v) volume(%v) option(%+v) err(%v)",
+			GetRequestID(r), vol.Name(), option, err)
+		return
+	}
+
+	bucketOwner := NewBucketOwner(vol)
+	listResult := &ListVersionsResult{
+		XMLNS:               XMLNS,
+		Name:                param.Bucket(),
+		Prefix:              encodeKey(prefix, encodingType),
+		KeyMarker:           encodeKey(keyMarker, encodingType),
+		VersionIdMarker:     versionIdMarker,
+		NextKeyMarker:       encodeKey(result.NextKeyMarker, encodingType),
+		NextVersionIdMarker: result.NextVersionIdMarker,
+		MaxKeys:             maxKeysInt,
+		Delimiter:           encodeKey(delimiter, encodingType),
+		EncodingType:        encodingType,
+		IsTruncated:         result.IsTruncated,
+		Versions:            make([]*ObjectVersion, 0),
+		DeleteMarkers:       make([]*DeleteMarkerEntry, 0),
+		CommonPrefixes:      make([]*CommonPrefix, 0),
+	}
+	for _, version := range result.Versions {
+		if version.DeleteMarker {
+			listResult.DeleteMarkers = append(listResult.DeleteMarkers, &DeleteMarkerEntry{
+				Key:          encodeKey(version.Path, encodingType),
+				VersionId:    version.VersionId,
+				IsLatest:     version.IsLatest,
+				LastModified: formatTimeISO(version.ModifyTime),
+				Owner:        bucketOwner,
+			})
+			continue
+		}
+		listResult.Versions = append(listResult.Versions, &ObjectVersion{
+			Key:          encodeKey(version.Path, encodingType),
+			VersionId:    version.VersionId,
+			IsLatest:     version.IsLatest,
+			LastModified: formatTimeISO(version.ModifyTime),
+			ETag:         wrapUnescapedQuot(version.ETag),
+			Size:         version.Size,
+			StorageClass: version.ObjectStorageClass(),
+			Owner:        bucketOwner,
+		})
+	}
+	for _, prefix := range result.CommonPrefixes {
+		listResult.CommonPrefixes = append(listResult.CommonPrefixes, &CommonPrefix{
+			Prefix: encodeKey(prefix, encodingType),
+		})
+	}
+
+	var data []byte
+	if data, err = MarshalXMLEntity(listResult); err != nil {
+		log.LogErrorf("listObjectVersionsHandler: xml marshal result fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+
+	writeSuccessResponseXML(w, data)
+	return
+}
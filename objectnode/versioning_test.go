@@ -0,0 +1,199 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersioningConfig(t *testing.T) {
+	tests := []struct {
+		value       string
+		status      string
+		expectedErr *ErrorCode
+	}{
+		{
+			value: `<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+						<Status>Enabled</Status>
+					</VersioningConfiguration>`,
+			status: VersioningEnabled,
+		},
+		{
+			value: `<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+						<Status>Suspended</Status>
+					</VersioningConfiguration>`,
+			status: VersioningSuspended,
+		},
+		{
+			value: `<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+						<Status>enabled</Status>
+					</VersioningConfiguration>`,
+			expectedErr: MalformedXML,
+		},
+		{
+			value:       `<VersioningConfiguration></VersioningConfiguration>`,
+			expectedErr: MalformedXML,
+		},
+		{
+			value:       `<VersioningConfiguration><Status>Enabled</Status>`,
+			expectedErr: MalformedXML,
+		},
+	}
+	for _, tt := range tests {
+		config, errCode := ParseVersioningConfig([]byte(tt.value))
+		if tt.expectedErr != nil {
+			require.Equal(t, tt.expectedErr, errCode)
+			continue
+		}
+		require.Nil(t, errCode)
+		require.Equal(t, tt.status, config.Status)
+		require.True(t, config.IsConfigured())
+	}
+
+	_, errCode := ParseVersioningConfig([]byte(`<VersioningConfiguration>
+		<Status>Enabled</Status><MfaDelete>Enabled</MfaDelete></VersioningConfiguration>`))
+	require.NotNil(t, errCode)
+	require.Equal(t, "InvalidArgument", errCode.ErrorCode)
+
+	var nilConfig *VersioningConfiguration
+	require.False(t, nilConfig.IsConfigured())
+}
+
+func TestVersionEntryName(t *testing.T) {
+	tests := []struct {
+		name      string
+		versionId string
+	}{
+		{name: "a.txt", versionId: NullVersionId},
+		{name: "a#b.txt", versionId: newVersionId()},
+		{name: "#", versionId: "1"},
+	}
+	for _, tt := range tests {
+		entry := versionEntryName(tt.name, tt.versionId)
+		name, versionId, ok := parseVersionEntryName(entry)
+		require.True(t, ok)
+		require.Equal(t, tt.name, name)
+		require.Equal(t, tt.versionId, versionId)
+	}
+
+	for _, entry := range []string{"abc", "#abc", "abc#"} {
+		_, _, ok := parseVersionEntryName(entry)
+		require.False(t, ok)
+	}
+
+	require.Equal(t, VersionsDirName+"/a/b.txt", versionsPath("a/b.txt"))
+	require.Equal(t, VersionsDirName+"/a/b.txt", versionsPath("/a/b.txt"))
+	require.True(t, isVersionsDir(nil, VersionsDirName))
+	require.False(t, isVersionsDir([]string{"a"}, VersionsDirName))
+}
+
+func TestIsVersionsKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected bool
+	}{
+		{VersionsDirName, true},
+		{VersionsDirName + "/", true},
+		{"/" + VersionsDirName + "/a.txt#" + NullVersionId, true},
+		{VersionsDirName + "/a/b.txt#0001", true},
+		{"", false},
+		{"a/" + VersionsDirName + "/b.txt", false},
+		{VersionsDirName + "x/a.txt", false},
+		{".cfs_s3", false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, isVersionsKey(tt.key), tt.key)
+	}
+}
+
+func TestVersionsKeyRejected(t *testing.T) {
+	o := &ObjectNode{}
+	key := versionsPath("a.txt") + versionNameSep + NullVersionId
+	tests := []struct {
+		method  string
+		action  proto.Action
+		handler http.HandlerFunc
+	}{
+		{http.MethodGet, proto.OSSGetObjectAction, o.getObjectHandler},
+		{http.MethodHead, proto.OSSHeadObjectAction, o.headObjectHandler},
+		{http.MethodPut, proto.OSSPutObjectAction, o.putObjectHandler},
+		{http.MethodPut, proto.OSSCopyObjectAction, o.copyObjectHandler},
+		{http.MethodDelete, proto.OSSDeleteObjectAction, o.deleteObjectHandler},
+		{http.MethodPost, proto.OSSCreateMultipartUploadAction, o.createMultipleUploadHandler},
+		{http.MethodPut, proto.OSSUploadPartAction, o.uploadPartHandler},
+		{http.MethodPost, proto.OSSCompleteMultipartUploadAction, o.completeMultipartUploadHandler},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "http://s3.cubefs.com/bucket/"+key, nil)
+		r = mux.SetURLVars(r, map[string]string{
+			ContextKeyBucket: "bucket", ContextKeyObject: key, ParamUploadId: "1", ParamPartNumber: "1",
+		})
+		SetRequestAction(r, tt.action)
+		w := httptest.NewRecorder()
+		tt.handler(w, r)
+		require.Equal(t, InvalidKey.StatusCode, w.Code, tt.action)
+		require.Contains(t, w.Body.String(), InvalidKey.ErrorCode, tt.action)
+	}
+
+	r := httptest.NewRequest(http.MethodPut, "http://s3.cubefs.com/bucket/a.txt", nil)
+	r.Header.Set(XAmzCopySource, "/bucket/"+key)
+	_, _, _, err := extractSrcBucketKey(r)
+	require.Equal(t, InvalidKey, err)
+	r.Header.Set(XAmzCopySource, "/bucket/a.txt?versionId="+NullVersionId)
+	_, srcKey, versionId, err := extractSrcBucketKey(r)
+	require.NoError(t, err)
+	require.Equal(t, "a.txt", srcKey)
+	require.Equal(t, NullVersionId, versionId)
+}
+
+func TestNewVersionIdOrder(t *testing.T) {
+	prev := newVersionId()
+	for i := 0; i < 10; i++ {
+		time.Sleep(time.Microsecond)
+		cur := newVersionId()
+		require.Less(t, prev, cur)
+		prev = cur
+	}
+}
+
+func TestSortVersionsNewestFirst(t *testing.T) {
+	now := time.Now()
+	versions := []*FSFileInfo{
+		{Path: "a", VersionId: "1", ModifyTime: now.Add(-time.Hour)},
+		{Path: "a", VersionId: "3", ModifyTime: now},
+		{Path: "a", VersionId: "2", ModifyTime: now},
+	}
+	sortVersionsNewestFirst(versions)
+	require.Equal(t, "3", versions[0].VersionId)
+	require.Equal(t, "2", versions[1].VersionId)
+	require.Equal(t, "1", versions[2].VersionId)
+
+	require.Equal(t, NullVersionId, (&FSFileInfo{}).ObjectVersionId())
+
+	dir, name := splitObjectPath("/a/b/c.txt")
+	require.Equal(t, "a/b", dir)
+	require.Equal(t, "c.txt", name)
+	require.Equal(t, "a/b/c.txt", joinObjectPath(dir, name))
+	dir, name = splitObjectPath("c.txt")
+	require.Equal(t, "", dir)
+	require.Equal(t, "c.txt", joinObjectPath(dir, name))
+}
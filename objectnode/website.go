@@ -0,0 +1,285 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/WebsiteHosting.html
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxWebsiteSize         = 1 << 20 // 1MB
+	MaxWebsiteRoutingRules = 50
+
+	WebsiteProtocolHTTP  = "http"
+	WebsiteProtocolHTTPS = "https"
+
+	defaultWebsiteRedirectCode = http.StatusMovedPermanently
+)
+
+var (
+	NoSuchWebsiteConfiguration = &ErrorCode{ErrorCode: "NoSuchWebsiteConfiguration", ErrorMessage: "The specified bucket does not have a website configuration.", StatusCode: http.StatusNotFound}
+	WebsiteErrIndexDocument    = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The IndexDocument Suffix is not well formed.", StatusCode: http.StatusBadRequest}
+	WebsiteErrErrorDocument    = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The ErrorDocument Key is not well formed.", StatusCode: http.StatusBadRequest}
+	WebsiteErrRedirectAll      = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "RedirectAllRequestsTo cannot be provided in conjunction with other Routing Rules.", StatusCode: http.StatusBadRequest}
+	WebsiteErrHostName         = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "A host name must be provided to redirect all requests.", StatusCode: http.StatusBadRequest}
+	WebsiteErrProtocol         = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Invalid protocol, protocol can be http or https.", StatusCode: http.StatusBadRequest}
+	WebsiteErrTooManyRules     = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Routing rules should not exceed allowed limit of 50.", StatusCode: http.StatusBadRequest}
+	WebsiteErrReplaceKey       = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "You can only define ReplaceKeyPrefix or ReplaceKey but not both.", StatusCode: http.StatusBadRequest}
+	WebsiteErrRedirectCode     = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The provided HTTP redirect code is not valid. It should be a 3XX code.", StatusCode: http.StatusBadRequest}
+	WebsiteErrErrorCode        = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The provided HTTP error code is not valid. Valid codes are 4XX or 5XX.", StatusCode: http.StatusBadRequest}
+	WebsiteErrMissingRedirect  = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Routing rule must contain a redirect.", StatusCode: http.StatusBadRequest}
+)
+
+type WebsiteConfiguration struct {
+	XMLName               xml.Name               `xml:"WebsiteConfiguration" json:"-"`
+	XMLNS                 string                 `xml:"xmlns,attr,omitempty" json:"-"`
+	RedirectAllRequestsTo *RedirectAllRequestsTo `xml:"RedirectAllRequestsTo,omitempty" json:"redirect_all_requests_to,omitempty"`
+	IndexDocument         *IndexDocument         `xml:"IndexDocument,omitempty" json:"index_document,omitempty"`
+	ErrorDocument         *ErrorDocument         `xml:"ErrorDocument,omitempty" json:"error_document,omitempty"`
+	RoutingRules          []*RoutingRule         `xml:"RoutingRules>RoutingRule,omitempty" json:"routing_rules,omitempty"`
+}
+
+type RedirectAllRequestsTo struct {
+	HostName string `xml:"HostName" json:"host_name"`
+	Protocol string `xml:"Protocol,omitempty" json:"protocol,omitempty"`
+}
+
+type IndexDocument struct {
+	Suffix string `xml:"Suffix" json:"suffix"`
+}
+
+type ErrorDocument struct {
+	Key string `xml:"Key" json:"key"`
+}
+
+type RoutingRule struct {
+	Condition *RoutingCondition `xml:"Condition,omitempty" json:"condition,omitempty"`
+	Redirect  *RoutingRedirect  `xml:"Redirect" json:"redirect"`
+}
+
+type RoutingCondition struct {
+	KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty" json:"key_prefix_equals,omitempty"`
+	HttpErrorCodeReturnedEquals string `xml:"HttpErrorCodeReturnedEquals,omitempty" json:"http_error_code_returned_equals,omitempty"`
+}
+
+type RoutingRedirect struct {
+	HostName             string `xml:"HostName,omitempty" json:"host_name,omitempty"`
+	HttpRedirectCode     string `xml:"HttpRedirectCode,omitempty" json:"http_redirect_code,omitempty"`
+	Protocol             string `xml:"Protocol,omitempty" json:"protocol,omitempty"`
+	ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty" json:"replace_key_prefix_with,omitempty"`
+	ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty" json:"replace_key_with,omitempty"`
+}
+
+func ParseWebsiteConfig(data []byte) (*WebsiteConfiguration, *ErrorCode) {
+	config := &WebsiteConfiguration{}
+	if err := xml.Unmarshal(data, config); err != nil {
+		return nil, MalformedXML
+	}
+	if errCode := config.Validate(); errCode != nil {
+		return nil, errCode
+	}
+	return config, nil
+}
+
+func (c *WebsiteConfiguration) Validate() *ErrorCode {
+	if c.RedirectAllRequestsTo != nil {
+		if c.IndexDocument != nil || c.ErrorDocument != nil || len(c.RoutingRules) > 0 {
+			return WebsiteErrRedirectAll
+		}
+		if c.RedirectAllRequestsTo.HostName == "" {
+			return WebsiteErrHostName
+		}
+		return validateWebsiteProtocol(c.RedirectAllRequestsTo.Protocol)
+	}
+	if c.IndexDocument == nil || c.IndexDocument.Suffix == "" || strings.Contains(c.IndexDocument.Suffix, pathSep) {
+		return WebsiteErrIndexDocument
+	}
+	if c.ErrorDocument != nil && (c.ErrorDocument.Key == "" || strings.HasSuffix(c.ErrorDocument.Key, pathSep)) {
+		return WebsiteErrErrorDocument
+	}
+	if len(c.RoutingRules) > MaxWebsiteRoutingRules {
+		return WebsiteErrTooManyRules
+	}
+	for _, rule := range c.RoutingRules {
+		if rule.Redirect == nil {
+			return WebsiteErrMissingRedirect
+		}
+		if rule.Redirect.ReplaceKeyPrefixWith != "" && rule.Redirect.ReplaceKeyWith != "" {
+			return WebsiteErrReplaceKey
+		}
+		if code := rule.Redirect.HttpRedirectCode; code != "" {
+			if c, err := strconv.Atoi(code); err != nil || c/100 != 3 {
+				return WebsiteErrRedirectCode
+			}
+		}
+		if errCode := validateWebsiteProtocol(rule.Redirect.Protocol); errCode != nil {
+			return errCode
+		}
+		if rule.Condition != nil && rule.Condition.HttpErrorCodeReturnedEquals != "" {
+			if c, err := strconv.Atoi(rule.Condition.HttpErrorCodeReturnedEquals); err != nil || c < 400 || c >= 600 {
+				return WebsiteErrErrorCode
+			}
+		}
+	}
+	return nil
+}
+
+func validateWebsiteProtocol(protocol string) *ErrorCode {
+	switch protocol {
+	case "", WebsiteProtocolHTTP, WebsiteProtocolHTTPS:
+		return nil
+	default:
+		return WebsiteErrProtocol
+	}
+}
+
+// IndexKey returns the key of the object to be served for the requested key, the index
+// document is served for the root and keys like directories which end with a slash.
+func (c *WebsiteConfiguration) IndexKey(key string) string {
+	if key == "" || strings.HasSuffix(key, pathSep) {
+		return key + c.IndexDocument.Suffix
+	}
+	return key
+}
+
+// MatchRoutingRule returns the first rule applies to the key. Rules with an error code condition only
+// apply to the responses with the same status code, statusCode is 0 before the object is served.
+func (c *WebsiteConfiguration) MatchRoutingRule(key string, statusCode int) *RoutingRule {
+	for _, rule := range c.RoutingRules {
+		var prefix, errorCode string
+		if rule.Condition != nil {
+			prefix, errorCode = rule.Condition.KeyPrefixEquals, rule.Condition.HttpErrorCodeReturnedEquals
+		}
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if (statusCode == 0 && errorCode == "") || (statusCode != 0 && errorCode == strconv.Itoa(statusCode)) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// RedirectURL returns the location and status code of the redirect for the key requested by r.
+func (rule *RoutingRule) RedirectURL(r *http.Request, key string) (location string, statusCode int) {
+	redirect := rule.Redirect
+	protocol, host := redirect.Protocol, redirect.HostName
+	if protocol == "" {
+		protocol = requestProtocol(r)
+	}
+	if host == "" {
+		host = r.Host
+	}
+	switch {
+	case redirect.ReplaceKeyWith != "":
+		key = redirect.ReplaceKeyWith
+	case redirect.ReplaceKeyPrefixWith != "":
+		var prefix string
+		if rule.Condition != nil {
+			prefix = rule.Condition.KeyPrefixEquals
+		}
+		key = redirect.ReplaceKeyPrefixWith + strings.TrimPrefix(key, prefix)
+	}
+	statusCode = defaultWebsiteRedirectCode
+	if redirect.HttpRedirectCode != "" {
+		statusCode, _ = strconv.Atoi(redirect.HttpRedirectCode)
+	}
+	return protocol + "://" + host + pathSep + key, statusCode
+}
+
+func requestProtocol(r *http.Request) string {
+	if r.TLS != nil {
+		return WebsiteProtocolHTTPS
+	}
+	return WebsiteProtocolHTTP
+}
+
+// websiteResponseWriter buffers the error response of the object handlers, so that the error
+// document or redirect rules of the website can be applied instead. If statusCode is specified,
+// it replaces the status code of the successful response, which is used to serve the error document.
+type websiteResponseWriter struct {
+	http.ResponseWriter
+	header      http.Header
+	statusCode  int
+	override    int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newWebsiteResponseWriter(w http.ResponseWriter, override int) *websiteResponseWriter {
+	return &websiteResponseWriter{ResponseWriter: w, header: make(http.Header), override: override}
+}
+
+func (w *websiteResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *websiteResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.statusCode = statusCode
+	if w.failed() {
+		return
+	}
+	if w.override != 0 && statusCode/100 == 2 {
+		statusCode = w.override
+	}
+	w.copyHeader()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *websiteResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.failed() {
+		return w.body.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// failed reports whether the response is an error, which is buffered and not sent yet.
+func (w *websiteResponseWriter) failed() bool {
+	return w.statusCode >= http.StatusBadRequest
+}
+
+// flush sends the buffered error response.
+func (w *websiteResponseWriter) flush() {
+	w.copyHeader()
+	w.ResponseWriter.WriteHeader(w.statusCode)
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
+
+func (w *websiteResponseWriter) copyHeader() {
+	for k, v := range w.header {
+		w.ResponseWriter.Header()[k] = v
+	}
+}
+
+func storeBucketWebsite(bytes []byte, vol *Volume) (err error) {
+	return vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSWebsite, bytes)
+}
+
+func deleteBucketWebsite(vol *Volume) (err error) {
+	return vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSWebsite)
+}
@@ -0,0 +1,253 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/cubefs/cubefs/util/log"
+
+	"github.com/gorilla/mux"
+)
+
+// Put bucket website
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketWebsite.html
+func (o *ObjectNode) putBucketWebsiteHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("putBucketWebsiteHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(r.Body, MaxWebsiteSize+1)); err != nil {
+		log.LogErrorf("putBucketWebsiteHandler: read request body fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if len(body) > MaxWebsiteSize {
+		errorCode = EntityTooLarge
+		return
+	}
+	var config *WebsiteConfiguration
+	if config, errorCode = ParseWebsiteConfig(body); errorCode != nil {
+		log.LogErrorf("putBucketWebsiteHandler: parse website config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), errorCode)
+		return
+	}
+	if body, err = json.Marshal(config); err != nil {
+		log.LogErrorf("putBucketWebsiteHandler: json marshal website config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+	if err = storeBucketWebsite(body, vol); err != nil {
+		log.LogErrorf("putBucketWebsiteHandler: store website config fail: requestID(%v) volume(%v) config(%v) err(%v)",
+			GetRequestID(r), vol.Name(), string(body), err)
+		return
+	}
+	vol.metaLoader.storeWebsite(config)
+
+	log.LogInfof("Audit: put bucket website: requestID(%v) volume(%v) config(%v)",
+		GetRequestID(r), vol.Name(), string(body))
+	return
+}
+
+// Get bucket website
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketWebsite.html
+func (o *ObjectNode) getBucketWebsiteHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("getBucketWebsiteHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+
+	var config *WebsiteConfiguration
+	if config, err = vol.metaLoader.loadWebsite(); err != nil {
+		log.LogErrorf("getBucketWebsiteHandler: load website fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if config == nil {
+		errorCode = NoSuchWebsiteConfiguration
+		return
+	}
+	config.XMLNS = XMLNS
+
+	var data []byte
+	if data, err = MarshalXMLEntity(config); err != nil {
+		log.LogErrorf("getBucketWebsiteHandler: xml marshal fail: requestID(%v) volume(%v) config(%+v) err(%v)",
+			GetRequestID(r), vol.Name(), config, err)
+		return
+	}
+
+	writeSuccessResponseXML(w, data)
+	return
+}
+
+// Delete bucket website
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketWebsite.html
+func (o *ObjectNode) deleteBucketWebsiteHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	param := ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("deleteBucketWebsiteHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	if err = deleteBucketWebsite(vol); err != nil {
+		log.LogErrorf("deleteBucketWebsiteHandler: delete website config fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	vol.metaLoader.storeWebsite(nil)
+
+	log.LogInfof("Audit: delete bucket website: requestID(%v) volume(%v)", GetRequestID(r), vol.Name())
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
+
+// websiteHandler serves the static website of the bucket on the website endpoints. The bucket is parsed
+// from the host as 'website' var, so that the policy check middleware is skipped for the request and the
+// access of the anonymous user is checked against the object which is actually served.
+func (o *ObjectNode) websiteHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+	defer func() {
+		o.errorResponse(w, r, err, errorCode)
+	}()
+
+	vars := mux.Vars(r)
+	bucket := vars[ContextKeyWebsite]
+	vars[ContextKeyBucket] = bucket
+	var vol *Volume
+	if vol, err = o.getVol(bucket); err != nil {
+		log.LogErrorf("websiteHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), bucket, err)
+		return
+	}
+	var config *WebsiteConfiguration
+	if config, err = vol.metaLoader.loadWebsite(); err != nil {
+		log.LogErrorf("websiteHandler: load website fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if config == nil {
+		errorCode = NoSuchWebsiteConfiguration
+		return
+	}
+
+	if redirect := config.RedirectAllRequestsTo; redirect != nil {
+		protocol := redirect.Protocol
+		if protocol == "" {
+			protocol = requestProtocol(r)
+		}
+		websiteRedirect(w, protocol+"://"+redirect.HostName+r.URL.RequestURI(), http.StatusMovedPermanently)
+		return
+	}
+
+	key := vars[ContextKeyObject]
+	if rule := config.MatchRoutingRule(key, 0); rule != nil {
+		location, statusCode := rule.RedirectURL(r, key)
+		websiteRedirect(w, location, statusCode)
+		return
+	}
+
+	ww := newWebsiteResponseWriter(w, 0)
+	o.serveWebsiteObject(ww, r, config.IndexKey(key))
+	if !ww.failed() {
+		return
+	}
+	// redirect to the directory if its index document exists, like '/docs' to '/docs/'
+	if ww.statusCode == http.StatusNotFound && key != "" && config.IndexKey(key) == key {
+		if info, _, err := vol.ObjectMeta(config.IndexKey(key + pathSep)); err == nil && info != nil {
+			websiteRedirect(w, pathSep+key+pathSep, http.StatusFound)
+			return
+		}
+	}
+	if rule := config.MatchRoutingRule(key, ww.statusCode); rule != nil {
+		location, statusCode := rule.RedirectURL(r, key)
+		websiteRedirect(w, location, statusCode)
+		return
+	}
+	if config.ErrorDocument != nil {
+		ew := newWebsiteResponseWriter(w, ww.statusCode)
+		o.serveWebsiteObject(ew, r, config.ErrorDocument.Key)
+		if !ew.failed() {
+			return
+		}
+		log.LogWarnf("websiteHandler: serve error document fail: requestID(%v) volume(%v) key(%v) status(%v)",
+			GetRequestID(r), vol.Name(), config.ErrorDocument.Key, ew.statusCode)
+	}
+	ww.flush()
+}
+
+// serveWebsiteObject serves the object with the policy and ACL checks of the object.
+func (o *ObjectNode) serveWebsiteObject(w http.ResponseWriter, r *http.Request, key string) {
+	mux.Vars(r)[ContextKeyObject] = key
+	handler := o.getObjectHandler
+	if r.Method == http.MethodHead {
+		handler = o.headObjectHandler
+	}
+	o.policyCheck(handler).ServeHTTP(w, r)
+}
+
+func websiteRedirect(w http.ResponseWriter, location string, statusCode int) {
+	w.Header().Set(Location, location)
+	w.WriteHeader(statusCode)
+}
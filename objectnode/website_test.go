@@ -0,0 +1,164 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWebsiteConfig(t *testing.T) {
+	tests := []struct {
+		value       string
+		expectedErr *ErrorCode
+	}{
+		{
+			value: `<WebsiteConfiguration>
+						<IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+						<ErrorDocument><Key>error.html</Key></ErrorDocument>
+						<RoutingRules><RoutingRule>
+							<Condition><KeyPrefixEquals>docs/</KeyPrefixEquals></Condition>
+							<Redirect><ReplaceKeyPrefixWith>documents/</ReplaceKeyPrefixWith></Redirect>
+						</RoutingRule></RoutingRules>
+					</WebsiteConfiguration>`,
+		},
+		{
+			value: `<WebsiteConfiguration>
+						<RedirectAllRequestsTo><HostName>example.com</HostName><Protocol>https</Protocol></RedirectAllRequestsTo>
+					</WebsiteConfiguration>`,
+		},
+		{
+			value:       `<WebsiteConfiguration><ErrorDocument><Key>error.html</Key></ErrorDocument></WebsiteConfiguration>`,
+			expectedErr: WebsiteErrIndexDocument,
+		},
+		{
+			value:       `<WebsiteConfiguration><IndexDocument><Suffix>a/index.html</Suffix></IndexDocument></WebsiteConfiguration>`,
+			expectedErr: WebsiteErrIndexDocument,
+		},
+		{
+			value: `<WebsiteConfiguration>
+						<RedirectAllRequestsTo><HostName>example.com</HostName></RedirectAllRequestsTo>
+						<IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+					</WebsiteConfiguration>`,
+			expectedErr: WebsiteErrRedirectAll,
+		},
+		{
+			value: `<WebsiteConfiguration>
+						<RedirectAllRequestsTo><HostName>example.com</HostName><Protocol>ftp</Protocol></RedirectAllRequestsTo>
+					</WebsiteConfiguration>`,
+			expectedErr: WebsiteErrProtocol,
+		},
+		{
+			value: `<WebsiteConfiguration>
+						<IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+						<RoutingRules><RoutingRule>
+							<Redirect><ReplaceKeyPrefixWith>a</ReplaceKeyPrefixWith><ReplaceKeyWith>b</ReplaceKeyWith></Redirect>
+						</RoutingRule></RoutingRules>
+					</WebsiteConfiguration>`,
+			expectedErr: WebsiteErrReplaceKey,
+		},
+		{
+			value: `<WebsiteConfiguration>
+						<IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+						<RoutingRules><RoutingRule>
+							<Redirect><HttpRedirectCode>200</HttpRedirectCode></Redirect>
+						</RoutingRule></RoutingRules>
+					</WebsiteConfiguration>`,
+			expectedErr: WebsiteErrRedirectCode,
+		},
+		{
+			value: `<WebsiteConfiguration>
+						<IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+						<RoutingRules><RoutingRule>
+							<Condition><HttpErrorCodeReturnedEquals>302</HttpErrorCodeReturnedEquals></Condition>
+							<Redirect><HostName>example.com</HostName></Redirect>
+						</RoutingRule></RoutingRules>
+					</WebsiteConfiguration>`,
+			expectedErr: WebsiteErrErrorCode,
+		},
+		{
+			value: `<WebsiteConfiguration>
+						<IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+						<RoutingRules><RoutingRule><Condition><KeyPrefixEquals>a</KeyPrefixEquals></Condition></RoutingRule></RoutingRules>
+					</WebsiteConfiguration>`,
+			expectedErr: WebsiteErrMissingRedirect,
+		},
+	}
+	for i, tt := range tests {
+		_, errCode := ParseWebsiteConfig([]byte(tt.value))
+		require.Equal(t, tt.expectedErr, errCode, "case %v", i)
+	}
+}
+
+func TestWebsiteRouting(t *testing.T) {
+	config := &WebsiteConfiguration{
+		IndexDocument: &IndexDocument{Suffix: "index.html"},
+		RoutingRules: []*RoutingRule{
+			{
+				Condition: &RoutingCondition{KeyPrefixEquals: "docs/"},
+				Redirect:  &RoutingRedirect{ReplaceKeyPrefixWith: "documents/"},
+			},
+			{
+				Condition: &RoutingCondition{HttpErrorCodeReturnedEquals: "404"},
+				Redirect:  &RoutingRedirect{HostName: "example.com", Protocol: WebsiteProtocolHTTPS, ReplaceKeyWith: "404.html", HttpRedirectCode: "302"},
+			},
+		},
+	}
+	require.Equal(t, "index.html", config.IndexKey(""))
+	require.Equal(t, "a/index.html", config.IndexKey("a/"))
+	require.Equal(t, "a/b.html", config.IndexKey("a/b.html"))
+
+	r := httptest.NewRequest(http.MethodGet, "http://bucket.website.cube.io/docs/a.html", nil)
+	rule := config.MatchRoutingRule("docs/a.html", 0)
+	require.NotNil(t, rule)
+	location, code := rule.RedirectURL(r, "docs/a.html")
+	require.Equal(t, "http://bucket.website.cube.io/documents/a.html", location)
+	require.Equal(t, http.StatusMovedPermanently, code)
+
+	require.Nil(t, config.MatchRoutingRule("images/a.jpg", 0))
+	require.Nil(t, config.MatchRoutingRule("images/a.jpg", http.StatusForbidden))
+	rule = config.MatchRoutingRule("images/a.jpg", http.StatusNotFound)
+	require.NotNil(t, rule)
+	location, code = rule.RedirectURL(r, "images/a.jpg")
+	require.Equal(t, "https://example.com/404.html", location)
+	require.Equal(t, http.StatusFound, code)
+}
+
+func TestWebsiteResponseWriter(t *testing.T) {
+	// the error response is buffered until flushed
+	recorder := httptest.NewRecorder()
+	w := newWebsiteResponseWriter(recorder, 0)
+	w.Header().Set(ContentType, ValueContentTypeXML)
+	w.WriteHeader(http.StatusNotFound)
+	_, _ = w.Write([]byte("not found"))
+	require.True(t, w.failed())
+	require.Empty(t, recorder.Body.String())
+	require.Empty(t, recorder.Header().Get(ContentType))
+	w.flush()
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Equal(t, ValueContentTypeXML, recorder.Header().Get(ContentType))
+	require.Equal(t, "not found", recorder.Body.String())
+
+	// the successful response of the error document is sent with the original status code
+	recorder = httptest.NewRecorder()
+	w = newWebsiteResponseWriter(recorder, http.StatusNotFound)
+	_, _ = w.Write([]byte("error document"))
+	require.False(t, w.failed())
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Equal(t, "error document", recorder.Body.String())
+}
@@ -169,6 +169,7 @@ const (
 	AdminDecommissionMetaPartition     = "/metaPartition/decommission"
 	AdminChangeMetaPartitionLeader     = "/metaPartition/changeleader"
 	AdminBalanceMetaPartitionLeader    = "/metaPartition/balanceLeader"
+	AdminMigrateMetaRange              = "/metaPartition/migrateRange"
 	AdminAddMetaReplica                = "/metaReplica/add"
 	AdminDeleteMetaReplica             = "/metaReplica/delete"
 	AdminPutDataPartitions             = "/dataPartitions/set"
@@ -188,6 +189,21 @@ const (
 
 	AddLcNode = "/lcNode/add"
 
+	// async volume replication APIs
+	AdminSetReplication      = "/replication/set"
+	AdminGetReplication      = "/replication/get"
+	AdminListReplication     = "/replication/list"
+	AdminPauseReplication    = "/replication/pause"
+	AdminResumeReplication   = "/replication/resume"
+	AdminFailoverReplication = "/replication/failover"
+	AdminDeleteReplication   = "/replication/delete"
+
+	// data balance APIs
+	AdminDataBalancePlan   = "/dataBalance/plan"
+	AdminDataBalanceStart  = "/dataBalance/start"
+	AdminDataBalanceStop   = "/dataBalance/stop"
+	AdminDataBalanceStatus = "/dataBalance/status"
+
 	QueryDisableDisk = "/dataNode/queryDisableDisk"
 	// Operation response
 	GetMetaNodeTaskResponse = "/metaNode/response" // Method: 'POST', ContentType: 'application/json'
@@ -517,6 +533,9 @@ type CreateDataPartitionRequest struct {
 	DecommissionedDisks []string
 	IsMultiVer          bool
 	VerSeq              uint64
+	ECDataNum           uint8
+	ECParityNum         uint8
+	ECHosts             []string
 }
 
 // CreateDataPartitionResponse defines the response to the request of creating a data partition.
@@ -593,6 +612,13 @@ type StopDataPartitionRepairRequest struct {
 	Stop        bool
 }
 
+// RepairDataPartitionShardRequest defines the request to rebuild the shards of the extents of the erasure
+// coded data partition kept by the host it is sent to.
+type RepairDataPartitionShardRequest struct {
+	PartitionId uint64
+	ExtentIDs   []uint64
+}
+
 // DeleteDataPartitionResponse defines the response to the request of deleting a data partition.
 type StopDataPartitionRepairResponse struct {
 	Status      uint8
@@ -714,6 +740,16 @@ type BadDiskStat struct {
 	DiskErrPartitionList []uint64
 }
 
+// DiskStat is the space usage of a disk of the data node reported by the heartbeat.
+type DiskStat struct {
+	DiskPath     string
+	Total        uint64
+	Used         uint64
+	Available    uint64
+	Status       int
+	PartitionCnt int
+}
+
 type MetaNodeRocksdbInfo struct {
 	Path           string
 	Total          uint64
@@ -737,8 +773,9 @@ type DataNodeHeartbeatResponse struct {
 	PartitionReports    []*DataPartitionReport
 	Status              uint8
 	Result              string
-	BadDisks            []string           // Keep this old field for compatibility
-	BadDiskStats        []BadDiskStat      // key: disk path
+	BadDisks            []string      // Keep this old field for compatibility
+	BadDiskStats        []BadDiskStat // key: disk path
+	DiskStats           []DiskStat
 	CpuUtil             float64            `json:"cpuUtil"`
 	IoUtils             map[string]float64 `json:"ioUtil"`
 }
@@ -783,6 +820,8 @@ type LcNodeHeartbeatResponse struct {
 	LcTaskCountLimit      int
 	LcScanningTasks       map[string]*LcNodeRuleTaskResponse
 	SnapshotScanningTasks map[string]*SnapshotVerDelTaskResponse
+	TrashScanningTasks    map[string]*TrashCleanTaskResponse
+	ReplicationTasks      map[string]*ReplicationTaskResponse
 }
 
 // DeleteFileRequest defines the request to delete a file.
@@ -873,6 +912,14 @@ type DataPartitionResponse struct {
 	IsRecover     bool
 	PartitionTTL  int64
 	IsDiscard     bool
+	ECDataNum     uint8 // the partition is erasure coded if it is not 0
+	ECParityNum   uint8
+	ECHosts       []string // the host keeping the shard i, "" if the shard is lost
+}
+
+// IsErasureCoded returns whether the partition keeps the data in the stripes of the erasure code.
+func (dp *DataPartitionResponse) IsErasureCoded() bool {
+	return dp.ECDataNum > 0
 }
 
 // DataPartitionsView defines the view of a data partition
@@ -918,18 +965,21 @@ type OSSSecure struct {
 
 // VolView defines the view of a volume
 type VolView struct {
-	Name           string
-	Owner          string
-	Status         uint8
-	FollowerRead   bool
-	MetaPartitions []*MetaPartitionView
-	DataPartitions []*DataPartitionResponse
-	DomainOn       bool
-	OSSSecure      *OSSSecure
-	CreateTime     int64
-	DeleteLockTime int64
-	CacheTTL       int
-	VolType        int
+	Name             string
+	Owner            string
+	Status           uint8
+	FollowerRead     bool
+	MetaPartitions   []*MetaPartitionView
+	DataPartitions   []*DataPartitionResponse
+	DomainOn         bool
+	OSSSecure        *OSSSecure
+	CreateTime       int64
+	DeleteLockTime   int64
+	TrashInterval    int64
+	EnablePosixAcl   bool
+	EnableEncryption bool
+	CacheTTL         int
+	VolType          int
 }
 
 func (v *VolView) SetOwner(owner string) {
@@ -1069,6 +1119,13 @@ type SimpleVolView struct {
 	DomainOn                bool
 	CreateTime              string
 	DeleteLockTime          int64
+	TrashInterval           int64  // min, 0 disables the trash
+	EnableEncryption        bool   // the files are encrypted by the clients with the keys from authnode
+	Compression             string // the codec of the data compressed by the clients, empty if not compressed
+	EnableDedup             bool   // the data appended is deduplicated by the clients
+	EnableCDC               bool   // the mutations are recorded by the meta partitions for the change-data-capture
+	ECDataNum               uint8  // the data partitions are erasure coded if it is not 0
+	ECParityNum             uint8
 	EnableToken             bool
 	EnablePosixAcl          bool
 	EnableQuota             bool
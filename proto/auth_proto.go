@@ -53,7 +53,8 @@ const (
 // api
 const (
 	// Client APIs
-	ClientGetTicket = "/client/getticket"
+	ClientGetTicket    = "/client/getticket"
+	ClientGetVolumeKey = "/client/getvolumekey"
 
 	// Admin APIs
 	AdminCreateKey  = "/admin/createkey"
@@ -190,6 +191,12 @@ const (
 	// MsgAuthOSGetCapsResp response type from ObjectNode to get caps
 	MsgAuthOSGetCapsResp MsgType = MsgAuthBase + 0x63001
 
+	// MsgAuthGetVolumeKeyReq request type from clients to get the master key of an encrypted volume
+	MsgAuthGetVolumeKeyReq MsgType = MsgAuthBase + 0x71000
+
+	// MsgAuthGetVolumeKeyResp response type from clients to get the master key of an encrypted volume
+	MsgAuthGetVolumeKeyResp MsgType = MsgAuthBase + 0x71001
+
 	// MsgMasterAPIAccessReq request type for master api access
 	MsgMasterAPIAccessReq MsgType = 0x60000
 
@@ -284,6 +291,7 @@ var MsgType2ResourceMap = map[MsgType]string{
 	MsgAuthOSAddCapsReq:      "auth:osaddcaps",
 	MsgAuthOSDeleteCapsReq:   "auth:osdeletecaps",
 	MsgAuthOSGetCapsReq:      "auth:osgetcaps",
+	MsgAuthGetVolumeKeyReq:   "auth:getvolumekey",
 
 	MsgMasterFetchVolViewReq: "master:getvol",
 
@@ -440,6 +448,19 @@ type AuthOSAccessKeyResp struct {
 	AKCaps  keystore.AccessKeyCaps `json:"access_key_caps"`
 }
 
+// AuthVolumeKeyReq defines Auth API request to get the master key of an encrypted volume
+type AuthVolumeKeyReq struct {
+	APIReq  APIAccessReq `json:"api_req"`
+	VolName string       `json:"vol_name"`
+}
+
+// AuthVolumeKeyResp defines the response with the master key of an encrypted volume
+type AuthVolumeKeyResp struct {
+	APIResp APIAccessResp `json:"api_resp"`
+	VolName string        `json:"vol_name"`
+	Key     []byte        `json:"key"`
+}
+
 // IsValidServiceID determine the validity of a serviceID
 func IsValidServiceID(serviceID string) (err error) {
 	if serviceID != AuthServiceID && serviceID != MasterServiceID && serviceID != MetaServiceID && serviceID != DataServiceID {
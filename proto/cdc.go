@@ -0,0 +1,102 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import "fmt"
+
+// The ops of the change events recorded by the meta partitions. The events are scoped by the
+// partition, so a rename is the dentry created in the partition of the new parent and the dentry
+// deleted in the one of the old parent.
+const (
+	CDCCreateInode uint8 = iota + 1
+	CDCLinkInode
+	CDCUnlinkInode
+	CDCEvictInode
+	CDCSetAttr
+	CDCTruncate
+	CDCCreateDentry
+	CDCDeleteDentry
+	CDCUpdateDentry
+	CDCSetXAttr
+	CDCRemoveXAttr
+)
+
+const DefaultCDCReadLimit = 1000
+
+func CDCOpString(op uint8) string {
+	switch op {
+	case CDCCreateInode:
+		return "createInode"
+	case CDCLinkInode:
+		return "linkInode"
+	case CDCUnlinkInode:
+		return "unlinkInode"
+	case CDCEvictInode:
+		return "evictInode"
+	case CDCSetAttr:
+		return "setAttr"
+	case CDCTruncate:
+		return "truncate"
+	case CDCCreateDentry:
+		return "createDentry"
+	case CDCDeleteDentry:
+		return "deleteDentry"
+	case CDCUpdateDentry:
+		return "updateDentry"
+	case CDCSetXAttr:
+		return "setXAttr"
+	case CDCRemoveXAttr:
+		return "removeXAttr"
+	default:
+		return fmt.Sprintf("unknown(%v)", op)
+	}
+}
+
+// CDCEvent is a mutation applied by the meta partition. The events of a partition are ordered by
+// ApplyID, the raft index of the mutation, and the events of a batch share the ApplyID.
+type CDCEvent struct {
+	Vol         string   `json:"vol"`
+	PartitionID uint64   `json:"pid"`
+	ApplyID     uint64   `json:"applyId"`
+	Time        int64    `json:"time"` // unix time of the apply
+	Op          uint8    `json:"op"`
+	ParentId    uint64   `json:"pino,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Inode       uint64   `json:"ino"`
+	OldInode    uint64   `json:"oldIno,omitempty"` // the inode replaced by CDCUpdateDentry
+	Type        uint32   `json:"type,omitempty"`
+	Size        uint64   `json:"size,omitempty"`
+	Keys        []string `json:"keys,omitempty"` // the xattrs set or removed
+}
+
+// CDCReadRequest reads the events recorded after the cursor From, 0 reads from the oldest one retained.
+type CDCReadRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	From        uint64 `json:"from"`
+	Limit       int    `json:"limit"`
+}
+
+// CDCReadResponse returns the events after the cursor, Next is the cursor to resume from. Oldest is
+// the ApplyID of the oldest event retained, so the events between From and Oldest may have been
+// dropped by the retention if From+1 < Oldest. The events of an ApplyID are never split into two
+// responses.
+type CDCReadResponse struct {
+	Events  []*CDCEvent `json:"events"`
+	Next    uint64      `json:"next"`
+	Oldest  uint64      `json:"oldest"`
+	Last    uint64      `json:"last"` // the ApplyID of the newest event recorded
+	Enabled bool        `json:"enabled"`
+}
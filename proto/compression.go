@@ -0,0 +1,99 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import "github.com/cubefs/cubefs/util"
+
+const (
+	// CompressChunkSize is the size of the file chunks compressed alone, a compressed key always
+	// starts at a multiple of the size and every chunk of it is full except the last one.
+	CompressChunkSize = util.BlockSize
+	// CompressKeyMaxChunks is the max count of the chunks of a compressed key.
+	CompressKeyMaxChunks = 256
+	// CompressFrameRaw is set in the size of the frame of a chunk kept raw, which is the case if
+	// the chunk is not smaller once compressed.
+	CompressFrameRaw uint32 = 1 << 31
+)
+
+// ExtCompression is the frame index of a compressed key, the frames of the chunks are kept one by
+// one in the extent from ExtentOffset, and the Size of the key is the size of the data of the chunks.
+type ExtCompression struct {
+	Codec  uint8
+	Frames []uint32 // the size of the frame of every chunk, with CompressFrameRaw if it is raw
+}
+
+func (c *ExtCompression) Equals(o *ExtCompression) bool {
+	if c == nil || o == nil {
+		return c == nil && o == nil
+	}
+	if c.Codec != o.Codec || len(c.Frames) != len(o.Frames) {
+		return false
+	}
+	for i := range c.Frames {
+		if c.Frames[i] != o.Frames[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Frame returns the size of the frame of the chunk i, and whether the chunk is kept raw.
+func (c *ExtCompression) Frame(i int) (size uint32, raw bool) {
+	return c.Frames[i] &^ CompressFrameRaw, c.Frames[i]&CompressFrameRaw != 0
+}
+
+// StoredSize returns the size of the frames of the chunks [from, to) kept in the extent.
+func (c *ExtCompression) StoredSize(from, to int) (size uint64) {
+	for i := from; i < to; i++ {
+		n, _ := c.Frame(i)
+		size += uint64(n)
+	}
+	return
+}
+
+func (k *ExtentKey) IsCompressed() bool {
+	return k.Compression != nil
+}
+
+// StorageKey returns the key of the bytes kept in the extent, which are the frames of a compressed
+// key or the data of the key itself.
+func (k *ExtentKey) StorageKey() ExtentKey {
+	sk := *k
+	if k.Compression != nil {
+		sk.Size = uint32(k.Compression.StoredSize(0, len(k.Compression.Frames)))
+		sk.Compression = nil
+	}
+	return sk
+}
+
+// CompressedSubKey returns the part [from, to) of the file range of the compressed key, the part
+// starts at a chunk of the key and ends at a chunk or the end of the key.
+func (k *ExtentKey) CompressedSubKey(from, to uint64) (ek ExtentKey) {
+	ek = *k
+	if k.SnapInfo != nil {
+		snap := *k.SnapInfo
+		ek.SnapInfo = &snap
+	}
+	first := int((from - k.FileOffset) / CompressChunkSize)
+	last := int((to - k.FileOffset + CompressChunkSize - 1) / CompressChunkSize)
+	ek.FileOffset = from
+	ek.ExtentOffset = k.ExtentOffset + k.Compression.StoredSize(0, first)
+	ek.Size = uint32(to - from)
+	ek.Compression = &ExtCompression{
+		Codec:  k.Compression.Codec,
+		Frames: append([]uint32(nil), k.Compression.Frames[first:last]...),
+	}
+	return
+}
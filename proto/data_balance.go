@@ -0,0 +1,116 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"fmt"
+)
+
+// The data balancer of the master migrates the replicas of the data partitions from the fullest disks to
+// the emptiest nodes of the same nodeset, once the usage of a disk exceeds the average of the nodeset by
+// the threshold.
+const (
+	DefaultDataBalanceThreshold   = 0.1
+	DefaultDataBalanceConcurrency = 4
+	DefaultDataBalanceBandwidthMB = 100
+)
+
+// status of the migration of a replica
+const (
+	DataBalanceMovePlanned   = "planned"
+	DataBalanceMoveRunning   = "running"
+	DataBalanceMoveSucceeded = "succeeded"
+	DataBalanceMoveFailed    = "failed"
+)
+
+// DataBalanceConfig is the config of the data balancer persisted by the master.
+type DataBalanceConfig struct {
+	Enable      bool    `json:"enable"`
+	Threshold   float64 `json:"threshold"`   // the usage above the average of the nodeset to be balanced
+	Concurrency int     `json:"concurrency"` // the replicas migrating at the same time
+	BandwidthMB uint64  `json:"bandwidthMB"` // the size of the replicas started to migrate per second
+}
+
+func NewDataBalanceConfig() *DataBalanceConfig {
+	return &DataBalanceConfig{
+		Threshold:   DefaultDataBalanceThreshold,
+		Concurrency: DefaultDataBalanceConcurrency,
+		BandwidthMB: DefaultDataBalanceBandwidthMB,
+	}
+}
+
+func (cfg *DataBalanceConfig) Validate() error {
+	if cfg.Threshold <= 0 || cfg.Threshold >= 1 {
+		return fmt.Errorf("threshold %v is not in (0, 1)", cfg.Threshold)
+	}
+	if cfg.Concurrency <= 0 {
+		return fmt.Errorf("concurrency %v is not positive", cfg.Concurrency)
+	}
+	if cfg.BandwidthMB == 0 {
+		return fmt.Errorf("bandwidth is not positive")
+	}
+	return nil
+}
+
+// DataBalanceMove is the migration of a replica of a data partition.
+type DataBalanceMove struct {
+	PartitionID uint64 `json:"pid"`
+	VolName     string `json:"vol"`
+	SrcAddr     string `json:"src"`
+	SrcDisk     string `json:"srcDisk"`
+	DstAddr     string `json:"dst"`
+	Size        uint64 `json:"size"`
+	Status      string `json:"status"`
+	StartTime   int64  `json:"startTime,omitempty"`
+	EndTime     int64  `json:"endTime,omitempty"`
+	Result      string `json:"result,omitempty"`
+}
+
+// DataBalanceUsage is the space usage of a node or a disk.
+type DataBalanceUsage struct {
+	Addr  string  `json:"addr"`
+	Disk  string  `json:"disk,omitempty"`
+	Total uint64  `json:"total"`
+	Used  uint64  `json:"used"`
+	Usage float64 `json:"usage"`
+}
+
+// NodeSetBalanceView is the skew of the usage of the nodes and the disks of a nodeset, which is the
+// difference between the fullest and the emptiest ones.
+type NodeSetBalanceView struct {
+	ID           uint64            `json:"id"`
+	Usage        float64           `json:"usage"`
+	NodeSkew     float64           `json:"nodeSkew"`
+	DiskSkew     float64           `json:"diskSkew"`
+	FullestDisk  *DataBalanceUsage `json:"fullestDisk,omitempty"`
+	EmptiestDisk *DataBalanceUsage `json:"emptiestDisk,omitempty"`
+}
+
+type ZoneBalanceView struct {
+	Zone     string                `json:"zone"`
+	Usage    float64               `json:"usage"`
+	NodeSkew float64               `json:"nodeSkew"`
+	DiskSkew float64               `json:"diskSkew"`
+	NodeSets []*NodeSetBalanceView `json:"nodeSets"`
+}
+
+// DataBalanceView is the plan of the data balancer, or the status of it with the migrations running and
+// finished recently.
+type DataBalanceView struct {
+	Config   *DataBalanceConfig `json:"config"`
+	Zones    []*ZoneBalanceView `json:"zones"`
+	Moves    []*DataBalanceMove `json:"moves"`
+	Finished []*DataBalanceMove `json:"finished,omitempty"`
+}
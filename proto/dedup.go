@@ -0,0 +1,129 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import "crypto/sha256"
+
+const (
+	// FingerprintSize is the size of the fingerprint of a deduplicated chunk, which is the sha256 of the data.
+	FingerprintSize = sha256.Size
+	// MaxDedupChunkSize is the max size of the chunks cut by the client, the data nodes hash no more than it.
+	MaxDedupChunkSize = 256 * 1024
+)
+
+// DedupChunk is a chunk of the file cut by the content defined chunking of the client.
+type DedupChunk struct {
+	Fingerprint []byte `json:"fp"`
+	FileOffset  uint64 `json:"off"`
+	Size        uint32 `json:"sz"`
+}
+
+// DedupExtentsRequest makes the chunks of the inode refer to the data of the same fingerprints written
+// before, the fingerprints are looked up in the meta partitions of the volume they are sharded to.
+type DedupExtentsRequest struct {
+	VolName     string       `json:"vol"`
+	PartitionID uint64       `json:"pid"`
+	Inode       uint64       `json:"ino"`
+	Chunks      []DedupChunk `json:"chunks"`
+	ModifyTime  int64        `json:"mt"`
+}
+
+// DedupExtentsResponse returns the extent keys of the chunks deduplicated in the order of the request,
+// the key is nil if the chunk is not found and must be written by the client.
+type DedupExtentsResponse struct {
+	Extents []*ExtentKey `json:"eks"`
+}
+
+// AddFingerprintsRequest adds the fingerprints of the chunks written by the inode to the index, so that
+// the later writes of the same data refer to the chunks.
+type AddFingerprintsRequest struct {
+	VolName     string       `json:"vol"`
+	PartitionID uint64       `json:"pid"`
+	Inode       uint64       `json:"ino"`
+	Chunks      []DedupChunk `json:"chunks"`
+}
+
+// DedupExtent is the data of a chunk indexed by the fingerprint.
+type DedupExtent struct {
+	Fingerprint  []byte `json:"fp"`
+	PartitionId  uint64 `json:"dp"`
+	ExtentId     uint64 `json:"eid"`
+	ExtentOffset uint64 `json:"eoff"`
+	Size         uint32 `json:"sz"`
+}
+
+// ExtentKey returns the key of the chunk at the file offset.
+func (e *DedupExtent) ExtentKey(fileOffset uint64) ExtentKey {
+	return ExtentKey{
+		FileOffset:   fileOffset,
+		PartitionId:  e.PartitionId,
+		ExtentId:     e.ExtentId,
+		ExtentOffset: e.ExtentOffset,
+		Size:         e.Size,
+	}
+}
+
+// AcquireFingerprintsRequest looks up the chunks in the meta partition the fingerprints are sharded to, the
+// chunks found are referred by the partition RefPartitionID with the token until the token is released. The
+// token is unique to the request, so that releasing it never drops the references of the other requests.
+type AcquireFingerprintsRequest struct {
+	VolName        string       `json:"vol"`
+	PartitionID    uint64       `json:"pid"`
+	RefPartitionID uint64       `json:"refPid"`
+	RefToken       uint64       `json:"refToken"`
+	Chunks         []DedupChunk `json:"chunks"`
+}
+
+// AcquireFingerprintsResponse returns the data of the chunks in the order of the request, the extent is
+// nil if the chunk is not found or the data of it does not match the fingerprint.
+type AcquireFingerprintsResponse struct {
+	Extents []*DedupExtent `json:"exts"`
+}
+
+// IndexFingerprintsRequest indexes the chunks written by the partition RefPartitionID in the meta partition
+// the fingerprints are sharded to, the chunks indexed are referred with the token.
+type IndexFingerprintsRequest struct {
+	VolName        string        `json:"vol"`
+	PartitionID    uint64        `json:"pid"`
+	RefPartitionID uint64        `json:"refPid"`
+	RefToken       uint64        `json:"refToken"`
+	Extents        []DedupExtent `json:"exts"`
+}
+
+// IndexFingerprintsResponse returns whether the chunks are indexed in the order of the request.
+type IndexFingerprintsResponse struct {
+	Indexed []bool `json:"indexed"`
+}
+
+// ReleaseFingerprintsRequest drops the references of the partition RefPartitionID to the chunks, the data
+// of a chunk is freed by the partition indexing it once nothing refers to it.
+type ReleaseFingerprintsRequest struct {
+	VolName        string               `json:"vol"`
+	PartitionID    uint64               `json:"pid"`
+	RefPartitionID uint64               `json:"refPid"`
+	Releases       []FingerprintRelease `json:"releases"`
+}
+
+// FingerprintRelease is a chunk released with the tokens it is referred by.
+type FingerprintRelease struct {
+	DedupExtent
+	RefTokens []uint64 `json:"refTokens"`
+}
+
+// ReleaseFingerprintsResponse returns whether the chunks are indexed by the partition in the order of the
+// request, the partition releasing a chunk not indexed frees the data of it by itself.
+type ReleaseFingerprintsResponse struct {
+	Owned []bool `json:"owned"`
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// XAttrFileKey is the xattr keeping the data key of an encrypted file, which is set by the
+// meta node at the creation of the file and could not be changed by the clients.
+const XAttrFileKey = "cfs.encryption.key"
+
+const FileKeyVersion = 1
+
+// FileKey is the data key of an encrypted file wrapped by the master key of the volume,
+// the master key is only known by the authnodes and the clients allowed.
+type FileKey struct {
+	Version uint8  `json:"v"`
+	Wrapped []byte `json:"k"`
+}
+
+func (k *FileKey) Marshal() ([]byte, error) {
+	return json.Marshal(k)
+}
+
+func UnmarshalFileKey(data []byte) (k *FileKey, err error) {
+	k = new(FileKey)
+	if err = json.Unmarshal(data, k); err != nil {
+		return nil, err
+	}
+	if k.Version != FileKeyVersion || len(k.Wrapped) == 0 {
+		return nil, fmt.Errorf("unsupported file key version %v", k.Version)
+	}
+	return k, nil
+}
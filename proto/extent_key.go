@@ -45,6 +45,9 @@ type ExtSnapInfo struct {
 	VerSeq  uint64
 	IsSplit bool
 	ModGen  uint64
+	// Shared is only set in the extents list to the client, the extent is also
+	// referred by other inodes and must not be overwritten in place.
+	Shared bool `json:",omitempty"`
 }
 
 // ExtentKey defines the extent key struct.
@@ -57,6 +60,10 @@ type ExtentKey struct {
 	CRC          uint32
 	// snapshot
 	SnapInfo *ExtSnapInfo
+	// Compression is set if the data of the key is the frames of compressed chunks
+	Compression *ExtCompression `json:",omitempty"`
+	// CipherNonce is the nonce the data of the encrypted file is written with, 0 if not encrypted
+	CipherNonce uint64 `json:",omitempty"`
 }
 
 func (k *ExtentKey) GetModGen() uint64 {
@@ -90,6 +97,9 @@ func (k *ExtentKey) Equals(ek *ExtentKey) bool {
 		k.CRC != ek.CRC {
 		return false
 	}
+	if !k.Compression.Equals(ek.Compression) || k.CipherNonce != ek.CipherNonce {
+		return false
+	}
 	if k.SnapInfo == nil && ek.SnapInfo == nil {
 		return true
 	} else if k.SnapInfo == nil || ek.SnapInfo == nil {
@@ -106,6 +116,13 @@ func (k *ExtentKey) IsSplit() bool {
 	return k.SnapInfo.IsSplit
 }
 
+func (k *ExtentKey) IsShared() bool {
+	if k.SnapInfo == nil {
+		return false
+	}
+	return k.SnapInfo.Shared
+}
+
 func (k *ExtentKey) GetSeq() uint64 {
 	if k.SnapInfo == nil {
 		return 0
@@ -181,7 +198,9 @@ func (k *ExtentKey) IsEqual(rightKey *ExtentKey) bool {
 }
 
 func (k *ExtentKey) IsCoveredWithDiffSeq(rightKey *ExtentKey) bool {
-	return k.PartitionId == rightKey.PartitionId &&
+	return !k.IsCompressed() && !rightKey.IsCompressed() &&
+		k.CipherNonce == rightKey.CipherNonce &&
+		k.PartitionId == rightKey.PartitionId &&
 		k.ExtentId == rightKey.ExtentId &&
 		k.GetSeq() < rightKey.GetSeq() &&
 		k.ExtentOffset+uint64(k.Size) == rightKey.ExtentOffset &&
@@ -189,7 +208,9 @@ func (k *ExtentKey) IsCoveredWithDiffSeq(rightKey *ExtentKey) bool {
 }
 
 func (k *ExtentKey) IsSequenceWithSameSeq(rightKey *ExtentKey) bool {
-	return k.PartitionId == rightKey.PartitionId &&
+	return !k.IsCompressed() && !rightKey.IsCompressed() &&
+		k.CipherNonce == rightKey.CipherNonce &&
+		k.PartitionId == rightKey.PartitionId &&
 		k.ExtentId == rightKey.ExtentId &&
 		k.GetSeq() == rightKey.GetSeq() &&
 		k.ExtentOffset+uint64(k.Size) == rightKey.ExtentOffset &&
@@ -202,7 +223,9 @@ func (k *ExtentKey) IsSameExtent(rightKey *ExtentKey) bool {
 }
 
 func (k *ExtentKey) IsSequenceWithDiffSeq(rightKey *ExtentKey) bool {
-	return k.PartitionId == rightKey.PartitionId &&
+	return !k.IsCompressed() && !rightKey.IsCompressed() &&
+		k.CipherNonce == rightKey.CipherNonce &&
+		k.PartitionId == rightKey.PartitionId &&
 		k.ExtentId == rightKey.ExtentId &&
 		!(k.GetSeq() == rightKey.GetSeq()) &&
 		k.ExtentOffset+uint64(k.Size) == rightKey.ExtentOffset &&
@@ -210,13 +233,19 @@ func (k *ExtentKey) IsSequenceWithDiffSeq(rightKey *ExtentKey) bool {
 }
 
 func (k *ExtentKey) IsFileInSequence(rightKey *ExtentKey) bool {
-	return k.PartitionId == rightKey.PartitionId &&
+	return !k.IsCompressed() && !rightKey.IsCompressed() &&
+		k.CipherNonce == rightKey.CipherNonce &&
+		k.PartitionId == rightKey.PartitionId &&
 		k.ExtentId == rightKey.ExtentId &&
 		k.ExtentOffset+uint64(k.Size) == rightKey.ExtentOffset
 }
 
 // String returns the string format of the extentKey.
 func (k ExtentKey) String() string {
+	if k.IsCompressed() {
+		return fmt.Sprintf("ExtentKey{FileOffset(%v),VerSeq(%v) Partition(%v),ExtentID(%v),ExtentOffset(%v),isSplit(%v),Size(%v),CRC(%v),Codec(%v),Frames(%v)}",
+			k.FileOffset, k.GetSeq(), k.PartitionId, k.ExtentId, k.ExtentOffset, k.IsSplit(), k.Size, k.CRC, k.Compression.Codec, len(k.Compression.Frames))
+	}
 	return fmt.Sprintf("ExtentKey{FileOffset(%v),VerSeq(%v) Partition(%v),ExtentID(%v),ExtentOffset(%v),isSplit(%v),Size(%v),CRC(%v)}",
 		k.FileOffset, k.GetSeq(), k.PartitionId, k.ExtentId, k.ExtentOffset, k.IsSplit(), k.Size, k.CRC)
 }
@@ -0,0 +1,85 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import "fmt"
+
+// The modes of fallocate(2), the values are the same as FALLOC_FL_* of linux.
+const (
+	FallocKeepSize  uint32 = 0x01
+	FallocPunchHole uint32 = 0x02
+	FallocZeroRange uint32 = 0x10
+)
+
+// FallocateRequest preallocates, punches or zeroes a range of the file. For
+// punch and zero the extent keys in the range are removed and the data is freed
+// by the data nodes later. For preallocation the client reserves the holes of
+// the range with extents created by OpPreallocExtent, which are filled with zeros
+// on the data nodes, and the keys of them are inserted where the range is still
+// a hole, so ENOSPC is returned by fallocate before any data is written.
+type FallocateRequest struct {
+	VolName     string      `json:"vol"`
+	PartitionID uint64      `json:"pid"`
+	Inode       uint64      `json:"ino"`
+	Mode        uint32      `json:"mode"`
+	Offset      uint64      `json:"off"`
+	Length      uint64      `json:"len"`
+	ModifyTime  int64       `json:"mt"`
+	Extents     []ExtentKey `json:"eks"`
+}
+
+// IsPreallocate returns whether the mode of fallocate reserves the space.
+func IsPreallocate(mode uint32) bool {
+	return mode&(FallocPunchHole|FallocZeroRange) == 0
+}
+
+// ValidateFallocate checks the mode and the range of fallocate as fallocate(2) does.
+func ValidateFallocate(mode uint32, offset, length uint64) error {
+	if length == 0 {
+		return fmt.Errorf("invalid length 0")
+	}
+	if offset+length < offset || offset+length > FileLockMaxOffset {
+		return fmt.Errorf("range [%v, +%v) overflows", offset, length)
+	}
+	if mode&^(FallocKeepSize|FallocPunchHole|FallocZeroRange) != 0 {
+		return fmt.Errorf("unsupported mode %#x", mode)
+	}
+	if mode&FallocPunchHole != 0 && (mode&FallocKeepSize == 0 || mode&FallocZeroRange != 0) {
+		return fmt.Errorf("punch hole must be used with keep size only, mode %#x", mode)
+	}
+	return nil
+}
+
+// CloneExtentsRequest makes the range of the destination inode refer to the
+// extents of the source range, as copy_file_range(2) does without copying data.
+// The references of the shared extents are kept by the meta partition, so both
+// inodes must belong to the same meta partition. The clients return EXDEV for the
+// inodes of different meta partitions, and the kernel copies the data instead.
+type CloneExtentsRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	SrcInode    uint64 `json:"src"`
+	DstInode    uint64 `json:"dst"`
+	SrcOffset   uint64 `json:"soff"`
+	DstOffset   uint64 `json:"doff"`
+	Length      uint64 `json:"len"`
+	ModifyTime  int64  `json:"mt"`
+}
+
+// CloneExtentsResponse returns the bytes cloned, which is less than the requested
+// length if the source range reaches the end of file.
+type CloneExtentsResponse struct {
+	Size uint64 `json:"sz"`
+}
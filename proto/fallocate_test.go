@@ -0,0 +1,38 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFallocate(t *testing.T) {
+	require.NoError(t, ValidateFallocate(FallocPunchHole|FallocKeepSize, 100, 1))
+	require.NoError(t, ValidateFallocate(FallocZeroRange, 100, 1))
+	require.NoError(t, ValidateFallocate(FallocZeroRange|FallocKeepSize, 100, 1))
+	require.NoError(t, ValidateFallocate(0, 0, 4096))
+	require.NoError(t, ValidateFallocate(FallocKeepSize, 100, 1))
+	require.True(t, IsPreallocate(0))
+	require.True(t, IsPreallocate(FallocKeepSize))
+	require.False(t, IsPreallocate(FallocZeroRange|FallocKeepSize))
+
+	require.Error(t, ValidateFallocate(FallocZeroRange, 0, 0))
+	require.Error(t, ValidateFallocate(FallocZeroRange, FileLockMaxOffset, 1))
+	require.Error(t, ValidateFallocate(FallocPunchHole, 0, 1))
+	require.Error(t, ValidateFallocate(FallocPunchHole|FallocKeepSize|FallocZeroRange, 0, 1))
+	require.Error(t, ValidateFallocate(0x08, 0, 1))
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"fmt"
+	"math"
+)
+
+// FileLockKind distinguishes the flock(2) locks from the fcntl(2) byte-range locks,
+// the locks of different kinds never conflict with each other.
+type FileLockKind uint8
+
+const (
+	FileLockPOSIX FileLockKind = iota
+	FileLockFlock
+)
+
+type FileLockType uint8
+
+const (
+	FileLockUnlock FileLockType = iota
+	FileLockRead
+	FileLockWrite
+)
+
+// FileLockMaxOffset is the end of a lock which extends to the end of file.
+const FileLockMaxOffset uint64 = math.MaxInt64
+
+// FileLock is an advisory lock on the byte range [Start, End] of the inode. The owner of lock
+// is identified by the client and the lock owner assigned by the kernel of the client.
+type FileLock struct {
+	Inode  uint64       `json:"ino"`
+	Client string       `json:"client"`
+	Owner  uint64       `json:"owner"`
+	Pid    uint32       `json:"pid"`
+	Kind   FileLockKind `json:"kind"`
+	Type   FileLockType `json:"type"`
+	Start  uint64       `json:"start"`
+	End    uint64       `json:"end"`
+}
+
+func (l *FileLock) String() string {
+	return fmt.Sprintf("FileLock{ino(%v) client(%v) owner(%v) pid(%v) kind(%v) type(%v) range[%v, %v]}",
+		l.Inode, l.Client, l.Owner, l.Pid, l.Kind, l.Type, l.Start, l.End)
+}
+
+// SameOwner reports whether the locks are held by the same owner of the same kind.
+func (l *FileLock) SameOwner(o *FileLock) bool {
+	return l.Client == o.Client && l.Owner == o.Owner && l.Kind == o.Kind
+}
+
+// Conflicts reports whether the lock cannot be held together with o.
+func (l *FileLock) Conflicts(o *FileLock) bool {
+	if l.Kind != o.Kind || l.SameOwner(o) || !l.overlaps(o) {
+		return false
+	}
+	return l.Type == FileLockWrite || o.Type == FileLockWrite
+}
+
+func (l *FileLock) overlaps(o *FileLock) bool {
+	return l.Start <= o.End && o.Start <= l.End
+}
+
+func (l *FileLock) valid() bool {
+	if l.Start > l.End {
+		return false
+	}
+	switch l.Type {
+	case FileLockUnlock, FileLockRead, FileLockWrite:
+	default:
+		return false
+	}
+	return l.Kind == FileLockPOSIX || l.Kind == FileLockFlock
+}
+
+// FindFileLockConflict returns the first lock of locks which conflicts with lock, or nil.
+func FindFileLockConflict(locks []*FileLock, lock *FileLock) *FileLock {
+	for _, l := range locks {
+		if l.Conflicts(lock) {
+			return l
+		}
+	}
+	return nil
+}
+
+// ApplyFileLock sets or unlocks the range of lock for its owner and returns the new locks, the
+// overlapped locks of the owner are split and the adjacent ones of the same type are merged like
+// the fcntl(2) locks. The locks must be checked by FindFileLockConflict before.
+func ApplyFileLock(locks []*FileLock, lock *FileLock) []*FileLock {
+	result := make([]*FileLock, 0, len(locks)+2)
+	merged := *lock
+	for _, l := range locks {
+		if !l.SameOwner(lock) {
+			result = append(result, l)
+			continue
+		}
+		// merge the overlapped or adjacent locks of the same type
+		if l.Type == merged.Type && merged.Type != FileLockUnlock &&
+			l.Start <= saturatingInc(merged.End) && merged.Start <= saturatingInc(l.End) {
+			if l.Start < merged.Start {
+				merged.Start = l.Start
+			}
+			if l.End > merged.End {
+				merged.End = l.End
+			}
+			continue
+		}
+		if !l.overlaps(lock) {
+			result = append(result, l)
+			continue
+		}
+		// keep the parts which are out of the range of lock
+		if l.Start < lock.Start {
+			head := *l
+			head.End = lock.Start - 1
+			result = append(result, &head)
+		}
+		if l.End > lock.End {
+			tail := *l
+			tail.Start = lock.End + 1
+			result = append(result, &tail)
+		}
+	}
+	if merged.Type != FileLockUnlock {
+		result = append(result, &merged)
+	}
+	return result
+}
+
+// RemoveFileLocks removes the locks of the owner of lock, the type and range of lock are ignored.
+func RemoveFileLocks(locks []*FileLock, lock *FileLock) []*FileLock {
+	result := make([]*FileLock, 0, len(locks))
+	for _, l := range locks {
+		if !l.SameOwner(lock) {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+func saturatingInc(v uint64) uint64 {
+	if v == math.MaxUint64 {
+		return v
+	}
+	return v + 1
+}
+
+type SetFileLockRequest struct {
+	VolName     string   `json:"vol"`
+	PartitionID uint64   `json:"pid"`
+	Lock        FileLock `json:"lock"`
+}
+
+// ValidateFileLock checks the fields of the lock in the requests.
+func ValidateFileLock(lock *FileLock) error {
+	if lock.Client == "" {
+		return fmt.Errorf("missing client of file lock")
+	}
+	if !lock.valid() {
+		return fmt.Errorf("invalid file lock: %v", lock)
+	}
+	return nil
+}
+
+type GetFileLockRequest struct {
+	VolName     string   `json:"vol"`
+	PartitionID uint64   `json:"pid"`
+	Lock        FileLock `json:"lock"`
+}
+
+type GetFileLockResponse struct {
+	Conflict *FileLock `json:"conflict"`
+}
+
+// RenewFileLocksRequest extends the lease of the client on the meta partition, the locks
+// held by the client are reclaimed if the lease has been lost, such as the leader changes.
+type RenewFileLocksRequest struct {
+	VolName     string      `json:"vol"`
+	PartitionID uint64      `json:"pid"`
+	Client      string      `json:"client"`
+	Locks       []*FileLock `json:"locks"`
+}
+
+type RenewFileLocksResponse struct {
+	Lost []*FileLock `json:"lost"`
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFileLock(client string, owner uint64, typ FileLockType, start, end uint64) *FileLock {
+	return &FileLock{Inode: 1, Client: client, Owner: owner, Kind: FileLockPOSIX, Type: typ, Start: start, End: end}
+}
+
+func TestFileLockConflicts(t *testing.T) {
+	w := newTestFileLock("a", 1, FileLockWrite, 0, 99)
+	require.True(t, w.Conflicts(newTestFileLock("b", 1, FileLockRead, 99, 100)))
+	require.True(t, w.Conflicts(newTestFileLock("a", 2, FileLockWrite, 50, 60)))
+	require.False(t, w.Conflicts(newTestFileLock("a", 1, FileLockWrite, 50, 60)))
+	require.False(t, w.Conflicts(newTestFileLock("b", 1, FileLockWrite, 100, 200)))
+
+	r := newTestFileLock("a", 1, FileLockRead, 0, 99)
+	require.False(t, r.Conflicts(newTestFileLock("b", 1, FileLockRead, 0, 99)))
+
+	flock := newTestFileLock("b", 1, FileLockWrite, 0, FileLockMaxOffset)
+	flock.Kind = FileLockFlock
+	require.False(t, w.Conflicts(flock))
+}
+
+func TestApplyFileLock(t *testing.T) {
+	var locks []*FileLock
+	locks = ApplyFileLock(locks, newTestFileLock("a", 1, FileLockRead, 0, 9))
+	locks = ApplyFileLock(locks, newTestFileLock("a", 1, FileLockRead, 10, 19))
+	require.Len(t, locks, 1)
+	require.Equal(t, uint64(0), locks[0].Start)
+	require.Equal(t, uint64(19), locks[0].End)
+
+	// split the read lock by a write lock in the middle
+	locks = ApplyFileLock(locks, newTestFileLock("a", 1, FileLockWrite, 5, 14))
+	require.Len(t, locks, 3)
+	ranges := make(map[[2]uint64]FileLockType)
+	for _, l := range locks {
+		ranges[[2]uint64{l.Start, l.End}] = l.Type
+	}
+	require.Equal(t, map[[2]uint64]FileLockType{
+		{0, 4}:   FileLockRead,
+		{15, 19}: FileLockRead,
+		{5, 14}:  FileLockWrite,
+	}, ranges)
+
+	// the locks of others are kept
+	locks = ApplyFileLock(locks, newTestFileLock("b", 1, FileLockRead, 100, FileLockMaxOffset))
+	require.Len(t, locks, 4)
+
+	locks = ApplyFileLock(locks, newTestFileLock("a", 1, FileLockUnlock, 0, FileLockMaxOffset))
+	require.Len(t, locks, 1)
+	require.Equal(t, "b", locks[0].Client)
+
+	locks = RemoveFileLocks(locks, newTestFileLock("b", 1, FileLockUnlock, 0, 0))
+	require.Len(t, locks, 0)
+}
+
+func TestValidateFileLock(t *testing.T) {
+	require.NoError(t, ValidateFileLock(newTestFileLock("a", 1, FileLockRead, 0, 0)))
+	require.Error(t, ValidateFileLock(newTestFileLock("", 1, FileLockRead, 0, 0)))
+	require.Error(t, ValidateFileLock(newTestFileLock("a", 1, FileLockRead, 10, 0)))
+	require.Error(t, ValidateFileLock(newTestFileLock("a", 1, FileLockType(9), 0, 0)))
+}
@@ -205,6 +205,8 @@ type QuotaCreateInodeRequest struct {
 	Gid         uint32   `json:"gid"`
 	Target      []byte   `json:"tgt"`
 	QuotaIds    []uint32 `json:"qids"`
+	DefaultACL  []byte   `json:"dacl,omitempty"`
+	FileKey     []byte   `json:"fkey,omitempty"`
 	RequestExtend
 }
 
@@ -215,6 +217,8 @@ type CreateInodeRequest struct {
 	Uid         uint32 `json:"uid"`
 	Gid         uint32 `json:"gid"`
 	Target      []byte `json:"tgt"`
+	DefaultACL  []byte `json:"dacl,omitempty"` // default acl of the parent to inherit
+	FileKey     []byte `json:"fkey,omitempty"` // wrapped data key of the encrypted file
 	RequestExtend
 }
 
@@ -249,6 +253,8 @@ type TxCreateInodeRequest struct {
 	Target      []byte           `json:"tgt"`
 	QuotaIds    []uint32         `json:"qids"`
 	TxInfo      *TransactionInfo `json:"tx"`
+	DefaultACL  []byte           `json:"dacl,omitempty"`
+	FileKey     []byte           `json:"fkey,omitempty"`
 	RequestExtend
 }
 
@@ -28,10 +28,13 @@ type LcConfiguration struct {
 }
 
 type Rule struct {
-	Expire *ExpirationConfig
-	Filter *FilterConfig
-	ID     string
-	Status string
+	Expire                         *ExpirationConfig
+	Transition                     *TransitionConfig
+	NoncurrentVersionExpire        *NoncurrentVersionExpirationConfig
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUploadConfig
+	Filter                         *FilterConfig
+	ID                             string
+	Status                         string
 }
 
 type ExpirationConfig struct {
@@ -39,6 +42,23 @@ type ExpirationConfig struct {
 	Days int
 }
 
+// TransitionConfig moves the data of the files not accessed for the days, or since the date, from
+// the extents of the hot volume to the blobstore.
+type TransitionConfig struct {
+	Date *time.Time
+	Days int
+}
+
+// NoncurrentVersionExpirationConfig removes the versions of the S3 objects noncurrent for the days.
+type NoncurrentVersionExpirationConfig struct {
+	NoncurrentDays int
+}
+
+// AbortIncompleteMultipartUploadConfig aborts the multipart uploads initiated the days ago.
+type AbortIncompleteMultipartUploadConfig struct {
+	DaysAfterInitiation int
+}
+
 type FilterConfig struct {
 	Prefix string
 }
@@ -48,6 +68,23 @@ const (
 	RuleDisabled string = "Disabled"
 )
 
+// The objects of the S3 buckets keep the lifecycle states in the xattrs, which are shared by the objectnode
+// and the lcnode.
+const (
+	// S3VersionsDirName is the hidden directory of the volume root the noncurrent versions are kept in.
+	S3VersionsDirName = ".cfs_s3_versions"
+
+	S3XAttrKeyDeleteMarker = "oss:delete-marker"
+	// S3XAttrKeyStorageClass is set to S3StorageClassGlacier once the data is transitioned to the blobstore.
+	S3XAttrKeyStorageClass = "oss:storage-class"
+	// S3XAttrKeyRestoreExpiry is the unix time the copy of the data restored from the blobstore expires at.
+	S3XAttrKeyRestoreExpiry = "oss:restore-expiry"
+	// S3XAttrKeyNoncurrentTime is the unix time the version becomes noncurrent at.
+	S3XAttrKeyNoncurrentTime = "oss:noncurrent-time"
+
+	S3StorageClassGlacier = "GLACIER"
+)
+
 func (lcConf *LcConfiguration) GenEnabledRuleTasks() []*RuleTask {
 	tasks := make([]*RuleTask, 0)
 	for _, r := range lcConf.Rules {
@@ -101,6 +138,9 @@ type LcNodeRuleTaskStatistics struct {
 	FileScannedNum       int64
 	DirScannedNum        int64
 	ExpiredNum           int64
+	TransitionedNum      int64
+	NoncurrentExpiredNum int64
+	AbortedMultipartNum  int64
 	ErrorSkippedNum      int64
 }
 
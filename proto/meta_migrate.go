@@ -0,0 +1,115 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+// The trees of a meta partition copied while migrating an inode range.
+const (
+	MetaRangeInodeTree uint8 = iota
+	MetaRangeDentryTree
+	MetaRangeExtendTree
+)
+
+// MetaRange is the inode range [Start, End] of a meta partition.
+type MetaRange struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+func (r *MetaRange) Contains(ino uint64) bool {
+	return r != nil && ino >= r.Start && ino <= r.End
+}
+
+// MovedMetaRange is the inode range migrated from a meta partition to the partition of PartitionID.
+type MovedMetaRange struct {
+	MetaRange
+	PartitionID uint64 `json:"pid"`
+}
+
+// MigrateMetaRangeRequest asks the leader of the target meta partition to copy the inode range of the
+// source partition, the range of the source is frozen before the last copy and the response is sent
+// back to the master once the target holds the same items as the source.
+type MigrateMetaRangeRequest struct {
+	PartitionID    uint64
+	VolName        string
+	SrcPartitionID uint64
+	SrcAddrs       []string
+	Start          uint64
+	End            uint64
+}
+
+// MigrateMetaRangeResponse defines the response to the request of migrating the inode range.
+type MigrateMetaRangeResponse struct {
+	PartitionID    uint64
+	VolName        string
+	SrcPartitionID uint64
+	Start          uint64
+	End            uint64
+	InodeCount     uint64
+	DentryCount    uint64
+	Status         uint8
+	Result         string
+}
+
+// CutoverMetaRangeRequest switches the meta partition to the range [Start, End] once the migration is
+// done, the requests of the moved range are redirected to the target partition then. The frozen range
+// of the source partition is dropped only if the migration is aborted.
+type CutoverMetaRangeRequest struct {
+	PartitionID uint64
+	VolName     string
+	Start       uint64
+	End         uint64
+	Moved       *MovedMetaRange
+	Abort       bool
+}
+
+// FreezeMetaRangeRequest holds off the requests of the inode range of the source partition before the
+// last copy of the range.
+type FreezeMetaRangeRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	Start       uint64 `json:"start"`
+	End         uint64 `json:"end"`
+}
+
+// ReadMetaRangeRequest reads the items of the tree in the inode range from the key of Marker in order.
+// The items of Keys are read only if it is set, and only the keys and the crc of the items are read in
+// the digest mode.
+type ReadMetaRangeRequest struct {
+	VolName     string   `json:"vol"`
+	PartitionID uint64   `json:"pid"`
+	Tree        uint8    `json:"tree"`
+	Start       uint64   `json:"start"`
+	End         uint64   `json:"end"`
+	Marker      []byte   `json:"marker"`
+	Keys        [][]byte `json:"keys"`
+	Digest      bool     `json:"digest"`
+	Limit       int      `json:"limit"`
+}
+
+// ReadMetaRangeResponse defines the response to the request of reading the inode range, Next is the key
+// to read from next time and it is empty once the range is read up.
+type ReadMetaRangeResponse struct {
+	Items  [][]byte `json:"items"`
+	Keys   [][]byte `json:"keys"`
+	Crcs   []uint32 `json:"crcs"`
+	Next   []byte   `json:"next"`
+	Cursor uint64   `json:"cursor"`
+}
+
+// MetaRangeMovedResponse is the body of the OpMetaRangeMoved result, the request should be sent to the
+// partition of PartitionID.
+type MetaRangeMovedResponse struct {
+	PartitionID uint64 `json:"pid"`
+}
@@ -302,6 +302,9 @@ type DataPartitionInfo struct {
 	RdOnly                   bool
 	IsDiscard                bool
 	Forbidden                bool
+	ECDataNum                uint8
+	ECParityNum              uint8
+	ECHosts                  []string // the host keeping the shard i, "" if the shard is lost
 }
 
 // FileInCore define file in data partition
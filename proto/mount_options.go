@@ -49,6 +49,7 @@ const (
 	EnableSummary
 	EnableUnixPermission
 	RequestTimeout
+	EnableFileLock
 
 	// adls
 	VolType
@@ -140,6 +141,7 @@ func InitMountOptions(opts []MountOption) {
 	opts[MaxCPUs] = MountOption{"maxcpus", "The maximum number of CPUs that can be executing", "", int64(-1)}
 	opts[EnableXattr] = MountOption{"enableXattr", "Enable xattr support", "", false}
 	opts[EnablePosixACL] = MountOption{"enablePosixACL", "Enable posix ACL support", "", false}
+	opts[EnableFileLock] = MountOption{"enableFileLock", "Enable cluster-wide flock and fcntl locks", "", false}
 	opts[EnableSummary] = MountOption{"enableSummary", "Enable content summary", "", false}
 	opts[EnableUnixPermission] = MountOption{"enableUnixPermission", "Enable unix permission check(e.g: 777/755)", "", false}
 
@@ -298,6 +300,7 @@ type MountOptions struct {
 	EnableXattr                  bool
 	NearRead                     bool
 	EnablePosixACL               bool
+	EnableFileLock               bool
 	EnableQuota                  bool
 	EnableTransaction            string
 	TxTimeout                    int64
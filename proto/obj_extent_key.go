@@ -41,6 +41,8 @@ type ObjExtentKey struct {
 	// snapshot
 	VerSeq uint64
 	ModGen uint64
+	// CipherNonce is the nonce the data of the encrypted file is written with, 0 if not encrypted
+	CipherNonce uint64 `json:",omitempty"`
 }
 
 // String returns the string format of the extentKey.
@@ -78,7 +80,7 @@ func (k *ObjExtentKey) IsEquals(obj *ObjExtentKey) bool {
 	if k.Size != obj.Size {
 		return false
 	}
-	if k.Crc != obj.Crc {
+	if k.Crc != obj.Crc || k.CipherNonce != obj.CipherNonce {
 		return false
 	}
 	if len(k.Blobs) > 0 {
@@ -69,6 +69,8 @@ const (
 	OpReadTinyDeleteRecord           uint8 = 0x14
 	OpTinyExtentRepairRead           uint8 = 0x15
 	OpGetMaxExtentIDAndPartitionSize uint8 = 0x16
+	OpGetExtentHash                  uint8 = 0x17
+	OpPreallocExtent                 uint8 = 0x18
 
 	// Operations: Client -> MetaNode.
 	OpMetaCreateInode   uint8 = 0x20
@@ -114,6 +116,10 @@ const (
 	OpRemoveMetaPartitionRaftMember uint8 = 0x47
 	OpMetaPartitionTryToLeader      uint8 = 0x48
 
+	// Migration of the inode ranges between the meta partitions: Master -> MetaNode.
+	OpMigrateMetaRange uint8 = 0x49
+	OpCutoverMetaRange uint8 = 0x4A
+
 	// Quota
 	OpMetaBatchSetInodeQuota    uint8 = 0x50
 	OpMetaBatchDeleteInodeQuota uint8 = 0x51
@@ -126,6 +132,8 @@ const (
 	OpLcNodeHeartbeat      uint8 = 0x55
 	OpLcNodeScan           uint8 = 0x56
 	OpLcNodeSnapshotVerDel uint8 = 0x57
+	OpLcNodeTrashClean     uint8 = 0x58
+	OpLcNodeReplication    uint8 = 0x59
 
 	// Operations: Master -> DataNode
 	OpCreateDataPartition           uint8 = 0x60
@@ -140,6 +148,7 @@ const (
 	OpDataPartitionTryToLeader      uint8 = 0x69
 	OpQos                           uint8 = 0x6A
 	OpStopDataPartitionRepair       uint8 = 0x6B
+	OpRepairDataPartitionShard      uint8 = 0x6C
 
 	// Operations: MultipartInfo
 	OpCreateMultipart  uint8 = 0x70
@@ -174,6 +183,40 @@ const (
 	// Operations: Client -> MetaNode.
 	OpMetaGetUniqID uint8 = 0xAC
 
+	// Advisory file locks: Client -> MetaNode.
+	OpMetaSetFileLock    uint8 = 0xC0
+	OpMetaGetFileLock    uint8 = 0xC1
+	OpMetaRenewFileLocks uint8 = 0xC2
+	OpFileLockConflict   uint8 = 0xC3
+
+	// fallocate and copy_file_range: Client -> MetaNode.
+	OpMetaFallocate    uint8 = 0xC4
+	OpMetaCloneExtents uint8 = 0xC5
+
+	// Deduplication: Client -> MetaNode.
+	OpMetaDedupExtents    uint8 = 0xC6
+	OpMetaAddFingerprints uint8 = 0xC7
+
+	// Fingerprints sharded among the meta partitions of the volume: MetaNode -> MetaNode.
+	OpMetaAcquireFingerprints uint8 = 0xCF
+	OpMetaIndexFingerprints   uint8 = 0xD4
+	OpMetaReleaseFingerprints uint8 = 0xD9
+
+	// Writable clones and diffs of snapshots: Client -> MetaNode.
+	OpMetaCloneSnapshotInode uint8 = 0xC8
+	OpMetaSnapshotDiff       uint8 = 0xC9
+
+	// Change-data-capture of the mutations: Client -> MetaNode.
+	OpMetaCDCRead uint8 = 0xCA
+
+	// Tiering of the data to the blobstore: LcNode -> MetaNode.
+	OpMetaTransitionExtents uint8 = 0xCB
+	OpMetaRestoreExtents    uint8 = 0xCC
+
+	// Copy of the migrating inode range: MetaNode -> MetaNode.
+	OpMetaReadRange   uint8 = 0xCD
+	OpMetaFreezeRange uint8 = 0xCE
+
 	// Multi version snapshot
 	OpRandomWriteAppend     uint8 = 0xB1
 	OpSyncRandomWriteAppend uint8 = 0xB2
@@ -242,6 +285,9 @@ const (
 	OpVersionOperation uint8 = 0xD5
 	OpSplitMarkDelete  uint8 = 0xD6
 	OpTryOtherExtent   uint8 = 0xD7
+
+	// the inode range of the request has been migrated to the partition in the data
+	OpMetaRangeMoved uint8 = 0xD8
 )
 
 const (
@@ -510,6 +556,10 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpTinyExtentRepairRead"
 	case OpGetMaxExtentIDAndPartitionSize:
 		m = "OpGetMaxExtentIDAndPartitionSize"
+	case OpGetExtentHash:
+		m = "OpGetExtentHash"
+	case OpPreallocExtent:
+		m = "OpPreallocExtent"
 	case OpBroadcastMinAppliedID:
 		m = "OpBroadcastMinAppliedID"
 	case OpRemoveDataPartitionRaftMember:
@@ -590,14 +640,58 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpMetaGetInodeQuota"
 	case OpStopDataPartitionRepair:
 		m = "OpStopDataPartitionRepair"
+	case OpRepairDataPartitionShard:
+		m = "OpRepairDataPartitionShard"
 	case OpLcNodeHeartbeat:
 		m = "OpLcNodeHeartbeat"
 	case OpLcNodeScan:
 		m = "OpLcNodeScan"
 	case OpLcNodeSnapshotVerDel:
 		m = "OpLcNodeSnapshotVerDel"
+	case OpLcNodeTrashClean:
+		m = "OpLcNodeTrashClean"
+	case OpLcNodeReplication:
+		m = "OpLcNodeReplication"
 	case OpMetaReadDirOnly:
 		m = "OpMetaReadDirOnly"
+	case OpMetaSetFileLock:
+		m = "OpMetaSetFileLock"
+	case OpMetaGetFileLock:
+		m = "OpMetaGetFileLock"
+	case OpMetaRenewFileLocks:
+		m = "OpMetaRenewFileLocks"
+	case OpMetaFallocate:
+		m = "OpMetaFallocate"
+	case OpMetaCloneExtents:
+		m = "OpMetaCloneExtents"
+	case OpMetaDedupExtents:
+		m = "OpMetaDedupExtents"
+	case OpMetaAddFingerprints:
+		m = "OpMetaAddFingerprints"
+	case OpMetaAcquireFingerprints:
+		m = "OpMetaAcquireFingerprints"
+	case OpMetaIndexFingerprints:
+		m = "OpMetaIndexFingerprints"
+	case OpMetaReleaseFingerprints:
+		m = "OpMetaReleaseFingerprints"
+	case OpMetaCloneSnapshotInode:
+		m = "OpMetaCloneSnapshotInode"
+	case OpMetaSnapshotDiff:
+		m = "OpMetaSnapshotDiff"
+	case OpMetaCDCRead:
+		m = "OpMetaCDCRead"
+	case OpMetaTransitionExtents:
+		m = "OpMetaTransitionExtents"
+	case OpMetaRestoreExtents:
+		m = "OpMetaRestoreExtents"
+	case OpMetaReadRange:
+		m = "OpMetaReadRange"
+	case OpMetaFreezeRange:
+		m = "OpMetaFreezeRange"
+	case OpMigrateMetaRange:
+		m = "OpMigrateMetaRange"
+	case OpCutoverMetaRange:
+		m = "OpCutoverMetaRange"
 	default:
 		m = fmt.Sprintf("op:%v not found", p.Opcode)
 	}
@@ -679,6 +773,10 @@ func (p *Packet) GetResultMsg() (m string) {
 		m = "OpTxRollbackErr"
 	case OpUploadPartConflictErr:
 		m = "OpUploadPartConflictErr"
+	case OpFileLockConflict:
+		m = "FileLockConflict"
+	case OpMetaRangeMoved:
+		m = "MetaRangeMoved: " + string(p.Data)
 	default:
 		return fmt.Sprintf("Unknown ResultCode(%v)", p.ResultCode)
 	}
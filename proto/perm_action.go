@@ -68,7 +68,7 @@ const (
 	OSSGetBucketPolicyAction       Action = OSSActionPrefix + "GetBucketPolicy"
 	OSSPutBucketPolicyAction       Action = OSSActionPrefix + "PutBucketPolicy"
 	OSSDeleteBucketPolicyAction    Action = OSSActionPrefix + "DeleteBucketPolicy"
-	OSSGetBucketPolicyStatusAction Action = OSSActionPrefix + "GetBucketPolicyStatus" // unsupported
+	OSSGetBucketPolicyStatusAction Action = OSSActionPrefix + "GetBucketPolicyStatus"
 
 	// Bucket ACL actions
 	OSSGetBucketAclAction Action = OSSActionPrefix + "GetBucketAcl"
@@ -124,44 +124,52 @@ const (
 	OSSDeleteBucketLifecycleConfigurationAction Action = OSSActionPrefix + "DeleteBucketLifecycleConfiguration"
 
 	// Object storage version actions
-	OSSGetBucketVersioningAction Action = OSSActionPrefix + "GetBucketVersioning" // unsupported
-	OSSPutBucketVersioningAction Action = OSSActionPrefix + "PutBucketVersioning" // unsupported
-	OSSListObjectVersionsAction  Action = OSSActionPrefix + "ListObjectVersions"  // unsupported
+	OSSGetBucketVersioningAction Action = OSSActionPrefix + "GetBucketVersioning"
+	OSSPutBucketVersioningAction Action = OSSActionPrefix + "PutBucketVersioning"
+	OSSListObjectVersionsAction  Action = OSSActionPrefix + "ListObjectVersions"
 
 	// Object legal hold actions
-	OSSGetObjectLegalHoldAction Action = OSSActionPrefix + "GetObjectLegalHold" // unsupported
-	OSSPutObjectLegalHoldAction Action = OSSActionPrefix + "PutObjectLegalHold" // unsupported
+	OSSGetObjectLegalHoldAction Action = OSSActionPrefix + "GetObjectLegalHold"
+	OSSPutObjectLegalHoldAction Action = OSSActionPrefix + "PutObjectLegalHold"
 
 	// Object retention actions
-	OSSGetObjectRetentionAction Action = OSSActionPrefix + "GetObjectRetention" // unsupported
-	OSSPutObjectRetentionAction Action = OSSActionPrefix + "PutObjectRetention" // unsupported
+	OSSGetObjectRetentionAction        Action = OSSActionPrefix + "GetObjectRetention"
+	OSSPutObjectRetentionAction        Action = OSSActionPrefix + "PutObjectRetention"
+	OSSBypassGovernanceRetentionAction Action = OSSActionPrefix + "BypassGovernanceRetention"
 
 	// Bucket encryption actions
-	OSSGetBucketEncryptionAction    Action = OSSActionPrefix + "GetBucketEncryption"    // unsupported
-	OSSPutBucketEncryptionAction    Action = OSSActionPrefix + "PutBucketEncryption"    // unsupported
-	OSSDeleteBucketEncryptionAction Action = OSSActionPrefix + "DeleteBucketEncryption" // unsupported
+	OSSGetBucketEncryptionAction    Action = OSSActionPrefix + "GetBucketEncryption"
+	OSSPutBucketEncryptionAction    Action = OSSActionPrefix + "PutBucketEncryption"
+	OSSDeleteBucketEncryptionAction Action = OSSActionPrefix + "DeleteBucketEncryption"
 
 	// Bucket website actions
-	OSSGetBucketWebsiteAction    Action = OSSActionPrefix + "GetBucketWebsite"    // unsupported
-	OSSPutBucketWebsiteAction    Action = OSSActionPrefix + "PutBucketWebsite"    // unsupported
-	OSSDeleteBucketWebsiteAction Action = OSSActionPrefix + "DeleteBucketWebsite" // unsupported
+	OSSGetBucketWebsiteAction    Action = OSSActionPrefix + "GetBucketWebsite"
+	OSSPutBucketWebsiteAction    Action = OSSActionPrefix + "PutBucketWebsite"
+	OSSDeleteBucketWebsiteAction Action = OSSActionPrefix + "DeleteBucketWebsite"
+
+	// Object select actions
+	OSSSelectObjectContentAction Action = OSSActionPrefix + "SelectObjectContent"
 
 	// Object restore actions
-	OSSRestoreObjectAction Action = OSSActionPrefix + "RestoreObject" // unsupported
+	OSSRestoreObjectAction Action = OSSActionPrefix + "RestoreObject"
 
 	// Public access block actions
-	OSSGetPublicAccessBlockAction    Action = OSSActionPrefix + "GetPublicAccessBlock"   // unsupported
-	OSSPutPublicAccessBlockAction    Action = OSSActionPrefix + "PutPublicAccessBlock"   // unsupported
-	OSSDeletePublicAccessBlockAction Action = OSSActionPrefix + "DeletePulicAccessBlock" // unuspported
+	OSSGetPublicAccessBlockAction    Action = OSSActionPrefix + "GetPublicAccessBlock"
+	OSSPutPublicAccessBlockAction    Action = OSSActionPrefix + "PutPublicAccessBlock"
+	OSSDeletePublicAccessBlockAction Action = OSSActionPrefix + "DeletePublicAccessBlock"
 
 	// Bucket request payment actions
 	OSSGetBucketRequestPaymentAction Action = OSSActionPrefix + "GetBucketRequestPayment" // unsupported
 	OSSPutBucketRequestPaymentAction Action = OSSActionPrefix + "PutBucketRequestPayment" // unsupported
 
 	// Bucket replication actions
-	OSSGetBucketReplicationAction    Action = OSSActionPrefix + "GetBucketReplicationAction"    // unsupported
-	OSSPutBucketReplicationAction    Action = OSSActionPrefix + "PutBucketReplicationAction"    // unsupported
-	OSSDeleteBucketReplicationAction Action = OSSActionPrefix + "DeleteBucketReplicationAction" // unsupported
+	OSSGetBucketReplicationAction    Action = OSSActionPrefix + "GetBucketReplicationAction"
+	OSSPutBucketReplicationAction    Action = OSSActionPrefix + "PutBucketReplicationAction"
+	OSSDeleteBucketReplicationAction Action = OSSActionPrefix + "DeleteBucketReplicationAction"
+
+	// Bucket notification actions
+	OSSGetBucketNotificationAction Action = OSSActionPrefix + "GetBucketNotification"
+	OSSPutBucketNotificationAction Action = OSSActionPrefix + "PutBucketNotification"
 
 	// STS actions
 	OSSGetFederationTokenAction Action = OSSActionPrefix + "GetFederationToken"
@@ -231,6 +239,7 @@ var AllActions = []Action{
 	OSSPutObjectLegalHoldAction,
 	OSSGetObjectRetentionAction,
 	OSSPutObjectRetentionAction,
+	OSSBypassGovernanceRetentionAction,
 	OSSGetBucketEncryptionAction,
 	OSSPutBucketEncryptionAction,
 	OSSDeleteBucketEncryptionAction,
@@ -240,6 +249,7 @@ var AllActions = []Action{
 	OSSGetBucketWebsiteAction,
 	OSSPutBucketWebsiteAction,
 	OSSDeleteBucketWebsiteAction,
+	OSSSelectObjectContentAction,
 	OSSRestoreObjectAction,
 	OSSGetPublicAccessBlockAction,
 	OSSPutPublicAccessBlockAction,
@@ -249,6 +259,8 @@ var AllActions = []Action{
 	OSSGetBucketReplicationAction,
 	OSSPutBucketReplicationAction,
 	OSSDeleteBucketReplicationAction,
+	OSSGetBucketNotificationAction,
+	OSSPutBucketNotificationAction,
 	OSSOptionsObjectAction,
 	OSSGetFederationTokenAction,
 
@@ -403,6 +415,7 @@ var builtinPermissionActionsMap = map[Permission]Actions{
 	BuiltinPermissionReadOnly: {
 		// Object storage interface actions
 		OSSGetObjectAction,
+		OSSSelectObjectContentAction,
 		OSSListObjectsAction,
 		OSSHeadObjectAction,
 		OSSHeadBucketAction,
@@ -422,6 +435,7 @@ var builtinPermissionActionsMap = map[Permission]Actions{
 	BuiltinPermissionWritable: {
 		// Object storage interface actions
 		OSSGetObjectAction,
+		OSSSelectObjectContentAction,
 		OSSPutObjectAction,
 		OSSCopyObjectAction,
 		OSSListObjectsAction,
@@ -0,0 +1,314 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// The POSIX ACLs are kept in the xattrs of the inode as json, which are converted from
+// and to the binary format of the linux kernel at the fuse client.
+const (
+	XAttrPosixACLAccess  = "system.posix_acl_access"
+	XAttrPosixACLDefault = "system.posix_acl_default"
+
+	PosixACLVersion            = 2
+	posixACLHeaderLen          = 4
+	posixACLEntryLen           = 8
+	PosixACLUndefinedID uint32 = 0xFFFFFFFF
+)
+
+// The tags of the POSIX ACL entries.
+const (
+	ACLUserObj  uint16 = 0x01
+	ACLUser     uint16 = 0x02
+	ACLGroupObj uint16 = 0x04
+	ACLGroup    uint16 = 0x08
+	ACLMask     uint16 = 0x10
+	ACLOther    uint16 = 0x20
+)
+
+// The permissions of the POSIX ACL entries.
+const (
+	ACLExecute uint16 = 0x01
+	ACLWrite   uint16 = 0x02
+	ACLRead    uint16 = 0x04
+)
+
+type PosixACLEntry struct {
+	Tag  uint16 `json:"tag"`
+	Perm uint16 `json:"perm"`
+	Id   uint32 `json:"id"`
+}
+
+type PosixACL struct {
+	Entries []PosixACLEntry `json:"entries"`
+}
+
+// IsPosixACLXAttr checks whether the xattr keeps a POSIX ACL.
+func IsPosixACLXAttr(name string) bool {
+	return name == XAttrPosixACLAccess || name == XAttrPosixACLDefault
+}
+
+// UnmarshalPosixACL decodes and validates the POSIX ACL kept in the xattr of the inode.
+func UnmarshalPosixACL(data []byte) (acl *PosixACL, err error) {
+	acl = &PosixACL{}
+	if err = json.Unmarshal(data, acl); err != nil {
+		return nil, err
+	}
+	if err = acl.Validate(); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+// Marshal encodes the ACL to be kept in the xattr of the inode.
+func (acl *PosixACL) Marshal() ([]byte, error) {
+	return json.Marshal(acl)
+}
+
+// ParsePosixACLXAttr decodes and validates the POSIX ACL in the binary format of the kernel.
+func ParsePosixACLXAttr(data []byte) (acl *PosixACL, err error) {
+	if len(data) < posixACLHeaderLen || (len(data)-posixACLHeaderLen)%posixACLEntryLen != 0 {
+		return nil, fmt.Errorf("invalid posix acl length %v", len(data))
+	}
+	if version := binary.LittleEndian.Uint32(data); version != PosixACLVersion {
+		return nil, fmt.Errorf("unsupported posix acl version %v", version)
+	}
+	acl = &PosixACL{}
+	for off := posixACLHeaderLen; off < len(data); off += posixACLEntryLen {
+		acl.Entries = append(acl.Entries, PosixACLEntry{
+			Tag:  binary.LittleEndian.Uint16(data[off:]),
+			Perm: binary.LittleEndian.Uint16(data[off+2:]),
+			Id:   binary.LittleEndian.Uint32(data[off+4:]),
+		})
+	}
+	if err = acl.Validate(); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+// XAttrBytes encodes the ACL in the binary format of the kernel, the entries are sorted
+// by tag and id.
+func (acl *PosixACL) XAttrBytes() []byte {
+	entries := make([]PosixACLEntry, len(acl.Entries))
+	copy(entries, acl.Entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Tag != entries[j].Tag {
+			return entries[i].Tag < entries[j].Tag
+		}
+		return entries[i].Id < entries[j].Id
+	})
+	data := make([]byte, posixACLHeaderLen+len(entries)*posixACLEntryLen)
+	binary.LittleEndian.PutUint32(data, PosixACLVersion)
+	off := posixACLHeaderLen
+	for _, e := range entries {
+		binary.LittleEndian.PutUint16(data[off:], e.Tag)
+		binary.LittleEndian.PutUint16(data[off+2:], e.Perm)
+		binary.LittleEndian.PutUint32(data[off+4:], e.Id)
+		off += posixACLEntryLen
+	}
+	return data
+}
+
+// Validate checks that the ACL has exactly one entry of the owner, the owning group and
+// the others, and has a mask entry if there are named user or group entries.
+func (acl *PosixACL) Validate() error {
+	count := make(map[uint16]int)
+	users := make(map[uint32]bool)
+	groups := make(map[uint32]bool)
+	for _, e := range acl.Entries {
+		if e.Perm&^(ACLRead|ACLWrite|ACLExecute) != 0 {
+			return fmt.Errorf("invalid posix acl perm %v", e.Perm)
+		}
+		switch e.Tag {
+		case ACLUserObj, ACLGroupObj, ACLMask, ACLOther:
+		case ACLUser:
+			if users[e.Id] {
+				return fmt.Errorf("duplicated posix acl user %v", e.Id)
+			}
+			users[e.Id] = true
+		case ACLGroup:
+			if groups[e.Id] {
+				return fmt.Errorf("duplicated posix acl group %v", e.Id)
+			}
+			groups[e.Id] = true
+		default:
+			return fmt.Errorf("invalid posix acl tag %v", e.Tag)
+		}
+		count[e.Tag]++
+	}
+	if count[ACLUserObj] != 1 || count[ACLGroupObj] != 1 || count[ACLOther] != 1 || count[ACLMask] > 1 {
+		return fmt.Errorf("posix acl must have one owner, owning group and other entry")
+	}
+	if (len(users) > 0 || len(groups) > 0) && count[ACLMask] == 0 {
+		return fmt.Errorf("posix acl with named entries must have a mask entry")
+	}
+	return nil
+}
+
+// PosixACLFromMode returns the minimal ACL equivalent to the permission bits.
+func PosixACLFromMode(mode uint32) *PosixACL {
+	return &PosixACL{Entries: []PosixACLEntry{
+		{Tag: ACLUserObj, Perm: uint16(mode>>6) & 7, Id: PosixACLUndefinedID},
+		{Tag: ACLGroupObj, Perm: uint16(mode>>3) & 7, Id: PosixACLUndefinedID},
+		{Tag: ACLOther, Perm: uint16(mode) & 7, Id: PosixACLUndefinedID},
+	}}
+}
+
+func (acl *PosixACL) find(tag uint16) *PosixACLEntry {
+	for i := range acl.Entries {
+		if acl.Entries[i].Tag == tag {
+			return &acl.Entries[i]
+		}
+	}
+	return nil
+}
+
+// groupClass returns the entry that maps to the group permission bits of the mode,
+// which is the mask entry if present or else the owning group entry.
+func (acl *PosixACL) groupClass() *PosixACLEntry {
+	if mask := acl.find(ACLMask); mask != nil {
+		return mask
+	}
+	return acl.find(ACLGroupObj)
+}
+
+// IsEquivalentMode checks whether the ACL can be fully represented by the permission bits.
+func (acl *PosixACL) IsEquivalentMode() bool {
+	for _, e := range acl.Entries {
+		if e.Tag != ACLUserObj && e.Tag != ACLGroupObj && e.Tag != ACLOther {
+			return false
+		}
+	}
+	return true
+}
+
+// Mode returns the permission bits reflected by the ACL.
+func (acl *PosixACL) Mode() uint32 {
+	var mode uint32
+	if e := acl.find(ACLUserObj); e != nil {
+		mode |= uint32(e.Perm) << 6
+	}
+	if e := acl.groupClass(); e != nil {
+		mode |= uint32(e.Perm) << 3
+	}
+	if e := acl.find(ACLOther); e != nil {
+		mode |= uint32(e.Perm)
+	}
+	return mode
+}
+
+// Chmod updates the ACL by the permission bits set by chmod.
+func (acl *PosixACL) Chmod(mode uint32) {
+	if e := acl.find(ACLUserObj); e != nil {
+		e.Perm = uint16(mode>>6) & 7
+	}
+	if e := acl.groupClass(); e != nil {
+		e.Perm = uint16(mode>>3) & 7
+	}
+	if e := acl.find(ACLOther); e != nil {
+		e.Perm = uint16(mode) & 7
+	}
+}
+
+// Clone returns a deep copy of the ACL.
+func (acl *PosixACL) Clone() *PosixACL {
+	entries := make([]PosixACLEntry, len(acl.Entries))
+	copy(entries, acl.Entries)
+	return &PosixACL{Entries: entries}
+}
+
+// InheritPosixACL computes the ACLs and the permission bits of a new inode created in
+// a directory with the default ACL, the same way as posix_acl_create of linux. The
+// access ACL is nil if it is equivalent to the permission bits, and the default ACL is
+// only inherited by directories.
+func InheritPosixACL(dflt *PosixACL, mode uint32, isDir bool) (access, inherited *PosixACL, perm uint32) {
+	access = dflt.Clone()
+	if e := access.find(ACLUserObj); e != nil {
+		e.Perm &= uint16(mode>>6) & 7
+	}
+	if e := access.groupClass(); e != nil {
+		e.Perm &= uint16(mode>>3) & 7
+	}
+	if e := access.find(ACLOther); e != nil {
+		e.Perm &= uint16(mode) & 7
+	}
+	perm = access.Mode()
+	if access.IsEquivalentMode() {
+		access = nil
+	}
+	if isDir {
+		inherited = dflt.Clone()
+	}
+	return
+}
+
+// CheckPosixPermission checks whether the user is granted the wanted permissions on the
+// inode, by the access ACL if not nil or else by the permission bits. The root user is
+// always granted except execute on a file without any execute bit.
+func CheckPosixPermission(mode, ownerUid, ownerGid, uid uint32, gids []uint32, want uint16, acl *PosixACL) bool {
+	if uid == 0 {
+		return want&ACLExecute == 0 || IsDir(mode) || mode&0o111 != 0
+	}
+	inGroup := func(gid uint32) bool {
+		for _, g := range gids {
+			if g == gid {
+				return true
+			}
+		}
+		return false
+	}
+	if acl == nil {
+		acl = PosixACLFromMode(mode)
+	}
+	if uid == ownerUid {
+		if e := acl.find(ACLUserObj); e != nil {
+			return e.Perm&want == want
+		}
+		return false
+	}
+
+	maskPerm := ACLRead | ACLWrite | ACLExecute
+	if mask := acl.find(ACLMask); mask != nil {
+		maskPerm = mask.Perm
+	}
+	for _, e := range acl.Entries {
+		if e.Tag == ACLUser && e.Id == uid {
+			return e.Perm&maskPerm&want == want
+		}
+	}
+
+	matched := false
+	for _, e := range acl.Entries {
+		if (e.Tag == ACLGroupObj && inGroup(ownerGid)) || (e.Tag == ACLGroup && inGroup(e.Id)) {
+			if e.Perm&maskPerm&want == want {
+				return true
+			}
+			matched = true
+		}
+	}
+	if matched {
+		return false
+	}
+	if e := acl.find(ACLOther); e != nil {
+		return e.Perm&want == want
+	}
+	return false
+}
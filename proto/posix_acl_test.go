@@ -0,0 +1,111 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPosixACLCodec(t *testing.T) {
+	acl := &PosixACL{Entries: []PosixACLEntry{
+		{Tag: ACLOther, Perm: 0, Id: PosixACLUndefinedID},
+		{Tag: ACLUser, Perm: ACLRead | ACLWrite, Id: 1001},
+		{Tag: ACLUserObj, Perm: 7, Id: PosixACLUndefinedID},
+		{Tag: ACLMask, Perm: ACLRead, Id: PosixACLUndefinedID},
+		{Tag: ACLGroupObj, Perm: ACLRead | ACLExecute, Id: PosixACLUndefinedID},
+	}}
+	data := acl.XAttrBytes()
+	require.Len(t, data, 4+5*8)
+
+	parsed, err := ParsePosixACLXAttr(data)
+	require.NoError(t, err)
+	require.Equal(t, ACLUserObj, parsed.Entries[0].Tag)
+	require.Equal(t, ACLUser, parsed.Entries[1].Tag)
+	require.EqualValues(t, 1001, parsed.Entries[1].Id)
+	require.EqualValues(t, 0o740, parsed.Mode())
+	require.False(t, parsed.IsEquivalentMode())
+
+	_, err = ParsePosixACLXAttr(data[:7])
+	require.Error(t, err)
+	// named entries without mask
+	noMask := &PosixACL{Entries: []PosixACLEntry{
+		{Tag: ACLUserObj, Perm: 7}, {Tag: ACLUser, Perm: 7, Id: 1}, {Tag: ACLGroupObj}, {Tag: ACLOther},
+	}}
+	_, err = ParsePosixACLXAttr(noMask.XAttrBytes())
+	require.Error(t, err)
+	_, err = ParsePosixACLXAttr(PosixACLFromMode(0o644).XAttrBytes())
+	require.NoError(t, err)
+
+	stored, err := parsed.Marshal()
+	require.NoError(t, err)
+	unmarshaled, err := UnmarshalPosixACL(stored)
+	require.NoError(t, err)
+	require.Equal(t, data, unmarshaled.XAttrBytes())
+	_, err = UnmarshalPosixACL(data)
+	require.Error(t, err)
+}
+
+func TestPosixACLCheck(t *testing.T) {
+	mode := Mode(0o640)
+	acl := PosixACLFromMode(mode)
+	acl.Entries = append(acl.Entries,
+		PosixACLEntry{Tag: ACLUser, Perm: ACLRead | ACLWrite, Id: 1001},
+		PosixACLEntry{Tag: ACLGroup, Perm: ACLRead | ACLWrite, Id: 2001},
+		PosixACLEntry{Tag: ACLMask, Perm: ACLRead | ACLWrite, Id: PosixACLUndefinedID})
+
+	// owner
+	require.True(t, CheckPosixPermission(mode, 1000, 100, 1000, nil, ACLRead|ACLWrite, acl))
+	// named user
+	require.True(t, CheckPosixPermission(mode, 1000, 100, 1001, nil, ACLWrite, acl))
+	// owning group without write
+	require.False(t, CheckPosixPermission(mode, 1000, 100, 1002, []uint32{100}, ACLWrite, acl))
+	require.True(t, CheckPosixPermission(mode, 1000, 100, 1002, []uint32{100}, ACLRead, acl))
+	// named group
+	require.True(t, CheckPosixPermission(mode, 1000, 100, 1002, []uint32{100, 2001}, ACLWrite, acl))
+	// other
+	require.False(t, CheckPosixPermission(mode, 1000, 100, 1003, []uint32{300}, ACLRead, acl))
+	// mask limits the named user
+	acl.Chmod(0o600)
+	require.EqualValues(t, 0o600, acl.Mode())
+	require.False(t, CheckPosixPermission(mode, 1000, 100, 1001, nil, ACLWrite, acl))
+
+	// root and permission bits only
+	require.True(t, CheckPosixPermission(Mode(0o600), 1000, 100, 0, nil, ACLRead|ACLWrite, nil))
+	require.False(t, CheckPosixPermission(Mode(0o600), 1000, 100, 0, nil, ACLExecute, nil))
+	require.True(t, CheckPosixPermission(Mode(os.ModeDir|0o700), 1000, 100, 0, nil, ACLExecute, nil))
+	require.True(t, CheckPosixPermission(Mode(0o604), 1000, 100, 1003, nil, ACLRead, nil))
+}
+
+func TestInheritPosixACL(t *testing.T) {
+	dflt := PosixACLFromMode(0o750)
+	access, inherited, perm := InheritPosixACL(dflt, 0o666, false)
+	require.Nil(t, access)
+	require.Nil(t, inherited)
+	require.EqualValues(t, 0o640, perm)
+
+	dflt.Entries = append(dflt.Entries,
+		PosixACLEntry{Tag: ACLUser, Perm: 7, Id: 1001},
+		PosixACLEntry{Tag: ACLMask, Perm: 7, Id: PosixACLUndefinedID})
+	access, inherited, perm = InheritPosixACL(dflt, 0o755, true)
+	require.NotNil(t, access)
+	require.NotNil(t, inherited)
+	require.EqualValues(t, 0o750, perm)
+	require.Equal(t, dflt.XAttrBytes(), inherited.XAttrBytes())
+	require.True(t, CheckPosixPermission(Mode(os.ModeDir)|perm, 1000, 100, 1001, nil, ACLRead|ACLExecute, access))
+	require.False(t, CheckPosixPermission(Mode(os.ModeDir)|perm, 1000, 100, 1001, nil, ACLWrite, access))
+}
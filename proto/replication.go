@@ -0,0 +1,121 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"time"
+)
+
+// A volume replicated asynchronously to a volume of another cluster. Every round the lcnode
+// creates a snapshot of the source volume, and applies the paths changed since the snapshot
+// replicated last time to the target volume, so the target lags behind by about the interval.
+const (
+	ReplicationActive uint8 = iota + 1
+	ReplicationPaused
+	ReplicationFailedOver
+)
+
+const DefaultReplicationInterval = 3600 // sec
+
+func ReplicationStatusString(status uint8) string {
+	switch status {
+	case ReplicationActive:
+		return "active"
+	case ReplicationPaused:
+		return "paused"
+	case ReplicationFailedOver:
+		return "failedOver"
+	default:
+		return "unknown"
+	}
+}
+
+// VolReplication is the replication of a source volume persisted by the master.
+type VolReplication struct {
+	SrcVol       string
+	DstMasters   string // comma separated addresses of the masters of the target cluster
+	DstVol       string
+	Interval     int64 // sec
+	Status       uint8
+	LastVerSeq   uint64 // the snapshot of the source replicated last time, 0 before the first round
+	LastVerTime  int64  // unix time of the snapshot replicated last time
+	LastSyncTime int64  // unix time of the end of the last round
+	FailoverTime int64
+	LastError    string
+	Rounds       uint64
+	SyncedFiles  int64
+	SyncedBytes  int64
+}
+
+// Lag returns how far the target volume is behind the source.
+func (r *VolReplication) Lag(now time.Time) time.Duration {
+	if r.LastVerTime == 0 {
+		return 0
+	}
+	return now.Sub(time.Unix(r.LastVerTime, 0))
+}
+
+// IsDue checks whether the next round of the replication should be started.
+func (r *VolReplication) IsDue(now time.Time) bool {
+	if r.Status != ReplicationActive {
+		return false
+	}
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultReplicationInterval
+	}
+	return now.Unix()-r.LastSyncTime >= interval
+}
+
+// VolReplicationView is the replication with the lag computed by the master.
+type VolReplicationView struct {
+	VolReplication
+	Lag int64 // sec
+}
+
+type ReplicationTaskRequest struct {
+	MasterAddr string
+	LcNodeAddr string
+	Task       *ReplicationTask
+}
+
+type ReplicationTask struct {
+	Id          string
+	Replication *VolReplication
+}
+
+type ReplicationTaskResponse struct {
+	ID              string
+	LcNode          string
+	StartTime       *time.Time
+	EndTime         *time.Time
+	UpdateTime      *time.Time
+	Done            bool
+	Status          uint8
+	Result          string
+	ReplicationTask *ReplicationTask
+	VerSeq          uint64 // the snapshot replicated by the round
+	VerTime         int64
+	ReplicationStatistics
+}
+
+type ReplicationStatistics struct {
+	SrcVol     string
+	DirNum     int64
+	FileNum    int64
+	DeletedNum int64
+	RenamedNum int64
+	TotalBytes int64
+}
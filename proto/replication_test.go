@@ -0,0 +1,42 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVolReplication(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	r := &VolReplication{Status: ReplicationActive, Interval: 600}
+	require.True(t, r.IsDue(now))
+	require.Equal(t, time.Duration(0), r.Lag(now))
+
+	r.LastSyncTime = now.Unix() - 300
+	r.LastVerTime = now.Unix() - 400
+	require.False(t, r.IsDue(now))
+	require.True(t, r.IsDue(now.Add(300*time.Second)))
+	require.Equal(t, 400*time.Second, r.Lag(now))
+
+	r.Interval = 0
+	require.False(t, r.IsDue(now.Add(DefaultReplicationInterval*time.Second-time.Hour/2)))
+	require.True(t, r.IsDue(now.Add(DefaultReplicationInterval*time.Second)))
+
+	r.Status = ReplicationPaused
+	require.False(t, r.IsDue(now.Add(time.Hour*24)))
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+// CloneSnapshotInodeRequest creates a writable inode from the inode of the snapshot, the new inode
+// is in the same meta partition and refers to the extents of the snapshot without copying data.
+type CloneSnapshotInodeRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	Inode       uint64 `json:"ino"`
+	VerSeq      uint64 `json:"seq"`
+	NewInode    uint64 `json:"nino"` // allocated by the meta partition
+	CreateTime  int64  `json:"ct"`
+}
+
+// CloneSnapshotInodeResponse returns the inode created.
+type CloneSnapshotInodeResponse struct {
+	Info *InodeInfo `json:"info"`
+}
+
+// CloneSnapshotStat is the statistics of the tree cloned from a snapshot.
+type CloneSnapshotStat struct {
+	DirNum     int64 `json:"dirs"`
+	FileNum    int64 `json:"files"`
+	LinkNum    int64 `json:"links"`
+	TotalBytes int64 `json:"bytes"`
+}
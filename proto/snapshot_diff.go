@@ -0,0 +1,88 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import "fmt"
+
+// The scans of a meta partition to diff two versions of the volume.
+const (
+	SnapshotDiffScanInode  uint8 = iota // the inodes modified
+	SnapshotDiffScanDentry              // the dentries created and deleted, and the directories kept
+	SnapshotDiffScanLookup              // the dentries of the inodes modified
+)
+
+// The ops of the entries of a diff.
+const (
+	SnapshotDiffCreated uint8 = iota + 1
+	SnapshotDiffDeleted
+	SnapshotDiffModified
+	SnapshotDiffRenamed
+	SnapshotDiffDir // the directory kept by both versions, only used to build the paths
+)
+
+// SnapshotDiffRequest scans the inodes or the dentries of the meta partition from Start, the dentries
+// are scanned by the parent inode and the dentries of a parent are never split into two pages.
+type SnapshotDiffRequest struct {
+	VolName     string   `json:"vol"`
+	PartitionID uint64   `json:"pid"`
+	FromVer     uint64   `json:"from"`
+	ToVer       uint64   `json:"to"` // 0 is the current version
+	Scan        uint8    `json:"scan"`
+	Start       uint64   `json:"start"`
+	Limit       uint64   `json:"limit"`
+	Inodes      []uint64 `json:"inos"` // the inodes modified, sorted, for SnapshotDiffScanLookup
+}
+
+// SnapshotDiffEntry is a dentry changed between the versions.
+type SnapshotDiffEntry struct {
+	Op       uint8  `json:"op"`
+	ParentId uint64 `json:"pino"`
+	Name     string `json:"name"`
+	Inode    uint64 `json:"ino"`
+	Type     uint32 `json:"type"`
+}
+
+// SnapshotDiffResponse returns the result of a page, Next is 0 once the partition is scanned.
+type SnapshotDiffResponse struct {
+	Entries  []SnapshotDiffEntry `json:"entries"`
+	Modified []uint64            `json:"modified"`
+	Next     uint64              `json:"next"`
+}
+
+// SnapshotDiffItem is a path changed between the versions.
+type SnapshotDiffItem struct {
+	Op      uint8  `json:"op"`
+	Path    string `json:"path"`
+	OldPath string `json:"oldPath,omitempty"` // the path in the older version if renamed
+	Inode   uint64 `json:"ino"`
+	Type    uint32 `json:"type"`
+}
+
+func SnapshotDiffOpString(op uint8) string {
+	switch op {
+	case SnapshotDiffCreated:
+		return "created"
+	case SnapshotDiffDeleted:
+		return "deleted"
+	case SnapshotDiffModified:
+		return "modified"
+	case SnapshotDiffRenamed:
+		return "renamed"
+	case SnapshotDiffDir:
+		return "dir"
+	default:
+		return fmt.Sprintf("unknown(%v)", op)
+	}
+}
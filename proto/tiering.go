@@ -0,0 +1,34 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+// TransitionExtentsRequest replaces the extents of the inode with the object extents the data is copied
+// to in the blobstore, the extents are freed then. The request is rejected if the inode is modified since
+// the generation the data is read at.
+type TransitionExtentsRequest struct {
+	VolName     string         `json:"vol"`
+	PartitionID uint64         `json:"pid"`
+	Inode       uint64         `json:"ino"`
+	Generation  uint64         `json:"gen"`
+	ObjExtents  []ObjExtentKey `json:"oeks"`
+}
+
+// RestoreExtentsRequest drops the object extents of the inode once the data of them is written back to
+// the extents, the data in the blobstore is freed then.
+type RestoreExtentsRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	Inode       uint64 `json:"ino"`
+}
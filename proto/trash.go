@@ -0,0 +1,99 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// The deleted entries of a volume with trash enabled are moved to the bucket
+// /.Trash/<timestamp> instead of being deleted. The timestamp is the unix time
+// of the start of the bucket, and the entries in the bucket are purged by the
+// lcnode once the whole bucket is older than the trash interval of the volume.
+const (
+	TrashDirName         = ".Trash"
+	TrashBucketInterval  = time.Hour
+	TrashXAttrOrigPath   = "trash.origpath"
+	TrashXAttrDeleteTime = "trash.deltime"
+)
+
+// TrashBucketName returns the name of the bucket which the entries deleted at t are moved to.
+func TrashBucketName(t time.Time) string {
+	return strconv.FormatInt(t.Truncate(TrashBucketInterval).Unix(), 10)
+}
+
+// ParseTrashBucket returns the start time of the bucket.
+func ParseTrashBucket(name string) (t time.Time, err error) {
+	sec, err := strconv.ParseInt(name, 10, 64)
+	if err != nil || sec <= 0 {
+		return t, fmt.Errorf("invalid trash bucket %v", name)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// IsTrashBucketExpired checks whether all the entries of the bucket are kept longer than
+// the trash interval in minutes.
+func IsTrashBucketExpired(name string, trashInterval int64, now time.Time) bool {
+	start, err := ParseTrashBucket(name)
+	if err != nil {
+		return false
+	}
+	return !now.Before(start.Add(TrashBucketInterval + time.Duration(trashInterval)*time.Minute))
+}
+
+// TrashEntry is a deleted entry in the trash.
+type TrashEntry struct {
+	Bucket     string `json:"bucket"`
+	Name       string `json:"name"`
+	Inode      uint64 `json:"ino"`
+	IsDir      bool   `json:"dir"`
+	OrigPath   string `json:"path"`
+	DeleteTime int64  `json:"dtime"`
+}
+
+type TrashCleanTaskRequest struct {
+	MasterAddr string
+	LcNodeAddr string
+	Task       *TrashCleanTask
+}
+
+type TrashCleanTask struct {
+	Id            string
+	VolName       string
+	TrashInterval int64
+}
+
+type TrashCleanTaskResponse struct {
+	ID             string
+	LcNode         string
+	StartTime      *time.Time
+	EndTime        *time.Time
+	UpdateTime     *time.Time
+	Done           bool
+	Status         uint8
+	Result         string
+	TrashCleanTask *TrashCleanTask
+	TrashStatistics
+}
+
+type TrashStatistics struct {
+	VolName         string
+	BucketNum       int64
+	FileNum         int64
+	DirNum          int64
+	ErrorSkippedNum int64
+}
@@ -0,0 +1,40 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashBucket(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	name := TrashBucketName(now)
+	start, err := ParseTrashBucket(name)
+	require.NoError(t, err)
+	require.Equal(t, now.Truncate(TrashBucketInterval), start)
+	require.Equal(t, name, TrashBucketName(start.Add(TrashBucketInterval-time.Second)))
+
+	end := start.Add(TrashBucketInterval)
+	require.False(t, IsTrashBucketExpired(name, 30, end.Add(29*time.Minute)))
+	require.True(t, IsTrashBucketExpired(name, 30, end.Add(30*time.Minute)))
+	require.True(t, IsTrashBucketExpired(name, 0, end))
+
+	_, err = ParseTrashBucket("foo")
+	require.Error(t, err)
+	require.False(t, IsTrashBucketExpired("foo", 0, end))
+}
@@ -26,6 +26,7 @@ var (
 	WriteS3Api = []string{
 		"PostObject", "PutObject", "CopyObject", "CreateMultipartUpload", "UploadPart", "UploadPartCopy",
 		"CompleteMultipartUpload", "AbortMultipartUpload", "DeleteObjects", "DeleteObject",
+		"PutObjectRetention", "PutObjectLegalHold",
 	}
 )
 
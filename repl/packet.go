@@ -348,7 +348,8 @@ func (p *Packet) IsMasterCommand() bool {
 		proto.OpDecommissionDataPartition,
 		proto.OpAddDataPartitionRaftMember,
 		proto.OpRemoveDataPartitionRaftMember,
-		proto.OpDataPartitionTryToLeader:
+		proto.OpDataPartitionTryToLeader,
+		proto.OpRepairDataPartitionShard:
 		return true
 	default:
 		return false
@@ -388,7 +389,7 @@ func (p *Packet) IsSnapshotModWriteAppendOperation() bool {
 }
 
 func (p *Packet) IsCreateExtentOperation() bool {
-	return p.Opcode == proto.OpCreateExtent
+	return p.Opcode == proto.OpCreateExtent || p.Opcode == proto.OpPreallocExtent
 }
 
 func (p *Packet) IsMarkDeleteExtentOperation() bool {
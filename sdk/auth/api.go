@@ -43,3 +43,45 @@ func (api *API) GetTicket(clientId string, clientKey string, serviceID string) (
 	}
 	return
 }
+
+// GetVolumeKey returns the master key of the encrypted volume, the client must have the
+// 'auth:getvolumekey' API capability and the capability of the volume.
+func (api *API) GetVolumeKey(clientID, clientKey, volName string) (key []byte, err error) {
+	if api.ac.ticket == nil {
+		if api.ac.ticket, err = api.GetTicket(clientID, clientKey, proto.AuthServiceID); err != nil {
+			return
+		}
+	}
+	var (
+		sessionKey []byte
+		ts         int64
+		resp       proto.AuthVolumeKeyResp
+		respData   []byte
+	)
+	apiReq := &proto.APIAccessReq{
+		Type:      proto.MsgAuthGetVolumeKeyReq,
+		ClientID:  clientID,
+		ServiceID: proto.AuthServiceID,
+		Ticket:    api.ac.ticket.Ticket,
+	}
+	if sessionKey, err = cryptoutil.Base64Decode(api.ac.ticket.SessionKey); err != nil {
+		return
+	}
+	if apiReq.Verifier, ts, err = cryptoutil.GenVerifier(sessionKey); err != nil {
+		return
+	}
+	message := &proto.AuthVolumeKeyReq{
+		APIReq:  *apiReq,
+		VolName: volName,
+	}
+	if respData, err = api.ac.request(clientID, clientKey, sessionKey, message, proto.ClientGetVolumeKey, proto.AuthServiceID); err != nil {
+		return
+	}
+	if err = json.Unmarshal(respData, &resp); err != nil {
+		return
+	}
+	if err = proto.VerifyAPIRespComm(&resp.APIResp, proto.MsgAuthGetVolumeKeyReq, clientID, proto.AuthServiceID, ts); err != nil {
+		return
+	}
+	return resp.Key, nil
+}
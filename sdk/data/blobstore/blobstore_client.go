@@ -146,6 +146,29 @@ func (ebs *BlobStoreClient) Write(ctx context.Context, volName string, data []by
 	return location, nil
 }
 
+// NewObjExtentKey returns the object extent key of the data written to the location at the file offset.
+func NewObjExtentKey(location access.Location, fileOffset uint64) proto.ObjExtentKey {
+	blobs := make([]proto.Blob, 0, len(location.Blobs))
+	for _, info := range location.Blobs {
+		blob := proto.Blob{
+			MinBid: uint64(info.MinBid),
+			Count:  uint64(info.Count),
+			Vid:    uint64(info.Vid),
+		}
+		blobs = append(blobs, blob)
+	}
+	return proto.ObjExtentKey{
+		Cid:        uint64(location.ClusterID),
+		CodeMode:   uint8(location.CodeMode),
+		Size:       location.Size,
+		BlobSize:   location.BlobSize,
+		Blobs:      blobs,
+		BlobsLen:   uint32(len(blobs)),
+		FileOffset: fileOffset,
+		Crc:        location.Crc,
+	}
+}
+
 func (ebs *BlobStoreClient) Delete(oeks []proto.ObjExtentKey) (err error) {
 	bgTime := stat.BeginStat()
 	defer func() {
@@ -0,0 +1,129 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package blobstore
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/stream"
+	"github.com/cubefs/cubefs/sdk/meta"
+	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const restoreBlockSize = 4 * util.MB
+
+type fileRange struct {
+	offset uint64
+	size   uint64
+}
+
+// extentHoles returns the ranges of [start, end) not covered by the extents sorted by the file offset.
+func extentHoles(eks []*proto.ExtentKey, start, end uint64) (holes []fileRange) {
+	for _, ek := range eks {
+		if start >= end {
+			break
+		}
+		ekEnd := ek.FileOffset + uint64(ek.Size)
+		if ekEnd <= start {
+			continue
+		}
+		if ek.FileOffset >= end {
+			break
+		}
+		if ek.FileOffset > start {
+			holes = append(holes, fileRange{offset: start, size: ek.FileOffset - start})
+		}
+		start = ekEnd
+	}
+	if start < end {
+		holes = append(holes, fileRange{offset: start, size: end - start})
+	}
+	return
+}
+
+// Restore writes the data of the inode transitioned to the blobstore back to the extents of the hot volume,
+// and drops the object extents then.
+func Restore(ec *stream.ExtentClient, mw *meta.MetaWrapper, inode uint64) (err error) {
+	restored, err := RestoreCopy(ec, mw, inode)
+	if err != nil || !restored {
+		return
+	}
+	if err = mw.RestoreExtents(inode); err != nil {
+		return
+	}
+	log.LogInfof("Restore: vol(%v) ino(%v) restored", ec.GetVolumeName(), inode)
+	return
+}
+
+// RestoreCopy writes the data of the inode transitioned to the blobstore back to the extents of the hot
+// volume, and keeps the object extents, so the extents are a copy of the data for the reads, which is dropped
+// by the transition of the same object extents later. The data is read and written through the extent client,
+// which must read the object extents, and only the holes of the extents are filled, so the data written since
+// the transition is kept. False is returned if the inode is not transitioned.
+func RestoreCopy(ec *stream.ExtentClient, mw *meta.MetaWrapper, inode uint64) (restored bool, err error) {
+	if !ec.ReadsObjExtents() {
+		return false, fmt.Errorf("extent client of vol(%v) does not read obj extents", ec.GetVolumeName())
+	}
+	_, size, _, oeks, err := mw.GetObjExtents(inode)
+	if err != nil || len(oeks) == 0 {
+		return
+	}
+	if err = ec.OpenStream(inode); err != nil {
+		return
+	}
+	defer ec.CloseStream(inode)
+	if err = ec.ForceRefreshExtentsCache(inode); err != nil {
+		return
+	}
+
+	buf := make([]byte, restoreBlockSize)
+	for _, oek := range oeks {
+		end := oek.FileOffset + oek.Size
+		if end > size {
+			end = size
+		}
+		for offset := oek.FileOffset; offset < end; offset += restoreBlockSize {
+			blockEnd := offset + restoreBlockSize
+			if blockEnd > end {
+				blockEnd = end
+			}
+			for _, hole := range extentHoles(ec.GetExtents(inode), offset, blockEnd) {
+				data := buf[:hole.size]
+				var read int
+				read, err = ec.Read(inode, data, int(hole.offset), int(hole.size))
+				if err == io.EOF && read == len(data) {
+					err = nil
+				}
+				if err != nil {
+					return
+				}
+				if read < len(data) {
+					return false, io.ErrUnexpectedEOF
+				}
+				if _, err = ec.Write(inode, int(hole.offset), data, 0, nil); err != nil {
+					return
+				}
+			}
+		}
+	}
+	if err = ec.Flush(inode); err != nil {
+		return
+	}
+	log.LogInfof("RestoreCopy: vol(%v) ino(%v) size(%v) obj extents(%v) copied", ec.GetVolumeName(), inode, size, len(oeks))
+	return true, nil
+}
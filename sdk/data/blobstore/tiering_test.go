@@ -0,0 +1,36 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package blobstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cubefs/cubefs/proto"
+)
+
+func TestExtentHoles(t *testing.T) {
+	assert.Equal(t, []fileRange{{offset: 0, size: 100}}, extentHoles(nil, 0, 100))
+
+	eks := []*proto.ExtentKey{
+		{FileOffset: 10, Size: 20},
+		{FileOffset: 50, Size: 10},
+	}
+	assert.Equal(t, []fileRange{{offset: 0, size: 10}, {offset: 30, size: 20}, {offset: 60, size: 40}},
+		extentHoles(eks, 0, 100))
+	assert.Equal(t, []fileRange{{offset: 30, size: 10}}, extentHoles(eks, 20, 40))
+	assert.Empty(t, extentHoles(eks, 12, 28))
+}
@@ -477,25 +477,7 @@ func (writer *Writer) writeSlice(ctx context.Context, wSlice *rwSlice, wg bool)
 		return err
 	}
 	log.LogDebugf("TRACE blobStore,location(%v)", location)
-	blobs := make([]proto.Blob, 0)
-	for _, info := range location.Blobs {
-		blob := proto.Blob{
-			MinBid: uint64(info.MinBid),
-			Count:  uint64(info.Count),
-			Vid:    uint64(info.Vid),
-		}
-		blobs = append(blobs, blob)
-	}
-	wSlice.objExtentKey = proto.ObjExtentKey{
-		Cid:        uint64(location.ClusterID),
-		CodeMode:   uint8(location.CodeMode),
-		Size:       location.Size,
-		BlobSize:   location.BlobSize,
-		Blobs:      blobs,
-		BlobsLen:   uint32(len(blobs)),
-		FileOffset: wSlice.fileOffset,
-		Crc:        location.Crc,
-	}
+	wSlice.objExtentKey = NewObjExtentKey(location, wSlice.fileOffset)
 	log.LogDebugf("TRACE blobStore,objExtentKey(%v)", wSlice.objExtentKey)
 
 	if wg {
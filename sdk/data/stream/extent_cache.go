@@ -56,6 +56,8 @@ type ExtentCache struct {
 	root    *btree.BTree
 	discard *btree.BTree
 	verSeq  uint64
+
+	objExtents []proto.ObjExtentKey // the data transitioned to the blobstore, read through the holes
 }
 
 // NewExtentCache returns a new extent cache.
@@ -122,6 +124,19 @@ func (cache *ExtentCache) update(gen, size uint64, force bool, eks []proto.Exten
 	}
 }
 
+func (cache *ExtentCache) setObjExtents(oeks []proto.ObjExtentKey) {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.objExtents = oeks
+}
+
+// ObjExtents returns the object extents of the data transitioned to the blobstore.
+func (cache *ExtentCache) ObjExtents() []proto.ObjExtentKey {
+	cache.RLock()
+	defer cache.RUnlock()
+	return cache.objExtents
+}
+
 // Split extent key.
 func (cache *ExtentCache) SplitExtentKey(inodeID uint64, ekPivot *proto.ExtentKey) (err error) {
 	cache.Lock()
@@ -202,6 +217,7 @@ func (cache *ExtentCache) SplitExtentKey(inodeID uint64, ekPivot *proto.ExtentKe
 			ExtentId:     ek.ExtentId,
 			ExtentOffset: ek.ExtentOffset + uint64(newSize+ekPivot.Size),
 			Size:         ek.Size - newSize - ekPivot.Size,
+			CipherNonce:  ek.CipherNonce,
 			SnapInfo: &proto.ExtSnapInfo{
 				VerSeq: ek.GetSeq(),
 				ModGen: ek.GetModGen(),
@@ -235,6 +251,10 @@ func (cache *ExtentCache) Append(ek *proto.ExtentKey, sync bool) (discardExtents
 	cache.Lock()
 	defer cache.Unlock()
 
+	if ek.IsCompressed() {
+		cache.splitCompressed(ek.FileOffset, ek.FileOffset+uint64(len(ek.Compression.Frames))*proto.CompressChunkSize, sync)
+	}
+
 	//cache.root.Descend(func(i btree.Item) bool {
 	//	ek := i.(*proto.ExtentKey)
 	//	// skip if the start offset matches with the given offset
@@ -283,6 +303,49 @@ func (cache *ExtentCache) Append(ek *proto.ExtentKey, sync bool) (discardExtents
 	return
 }
 
+// splitCompressed cuts the compressed keys across the boundaries of the range [start, end) at the
+// chunks as the meta node does, the parts out of the range are kept and the parts in the range are
+// discarded.
+func (cache *ExtentCache) splitCompressed(start, end uint64, sync bool) {
+	pivot := &proto.ExtentKey{FileOffset: start}
+	upper := &proto.ExtentKey{FileOffset: end}
+	var found []*proto.ExtentKey
+	cache.root.DescendLessOrEqual(pivot, func(i btree.Item) bool {
+		if ek := i.(*proto.ExtentKey); ek.FileOffset < start {
+			found = append(found, ek)
+		}
+		return false
+	})
+	cache.root.AscendRange(pivot, upper, func(i btree.Item) bool {
+		found = append(found, i.(*proto.ExtentKey))
+		return true
+	})
+
+	for _, key := range found {
+		keyEnd := key.FileOffset + uint64(key.Size)
+		if !key.IsCompressed() || keyEnd <= start || (key.FileOffset >= start && keyEnd <= end) {
+			continue
+		}
+		cache.root.Delete(key)
+		from, to := key.FileOffset, keyEnd
+		if from < start {
+			left := key.CompressedSubKey(from, start)
+			cache.root.ReplaceOrInsert(&left)
+			from = start
+		}
+		if to > end {
+			right := key.CompressedSubKey(end, keyEnd)
+			cache.root.ReplaceOrInsert(&right)
+			to = end
+		}
+		if sync {
+			middle := key.CompressedSubKey(from, to)
+			cache.discard.ReplaceOrInsert(&middle)
+		}
+		log.LogDebugf("ExtentCache splitCompressed: ino(%v) ek(%v) cut at [%v, %v)", cache.inode, key, start, end)
+	}
+}
+
 func (cache *ExtentCache) RemoveDiscard(discardExtents []proto.ExtentKey) {
 	cache.Lock()
 	defer cache.Unlock()
@@ -413,6 +476,82 @@ func (cache *ExtentCache) GetEndForAppendWrite(offset uint64, verSeq uint64, nee
 	return ret
 }
 
+// HasCompressed returns true if any compressed chunk is in the range [start, end).
+func (cache *ExtentCache) HasCompressed(start, end int) (found bool) {
+	pivot := &proto.ExtentKey{FileOffset: uint64(start)}
+	upper := &proto.ExtentKey{FileOffset: uint64(end)}
+	cache.RLock()
+	defer cache.RUnlock()
+
+	cache.root.DescendLessOrEqual(pivot, func(i btree.Item) bool {
+		ek := i.(*proto.ExtentKey)
+		found = ek.IsCompressed() && ek.FileOffset+uint64(ek.Size) > uint64(start)
+		return false
+	})
+	if found {
+		return
+	}
+	cache.root.AscendRange(pivot, upper, func(i btree.Item) bool {
+		found = i.(*proto.ExtentKey).IsCompressed()
+		return !found
+	})
+	return
+}
+
+// MarkShared marks the keys within the range [start, end) as shared, so that the data of them is
+// written by append rather than overwrite.
+func (cache *ExtentCache) MarkShared(start, end int) {
+	pivot := &proto.ExtentKey{FileOffset: uint64(start)}
+	upper := &proto.ExtentKey{FileOffset: uint64(end)}
+	cache.Lock()
+	defer cache.Unlock()
+
+	cache.root.AscendRange(pivot, upper, func(i btree.Item) bool {
+		ek := i.(*proto.ExtentKey)
+		snapInfo := &proto.ExtSnapInfo{}
+		if ek.SnapInfo != nil {
+			*snapInfo = *ek.SnapInfo
+		}
+		snapInfo.Shared = true
+		ek.SnapInfo = snapInfo
+		return true
+	})
+}
+
+// PrepareChunkWrite checks the keys of the chunk from the start to be replaced by a compressed key.
+// The chunk is not whole if a raw key is across the start or the end of it, and compressed is true
+// if any compressed key is in the chunk.
+func (cache *ExtentCache) PrepareChunkWrite(start int) (whole, compressed bool) {
+	end := uint64(start + proto.CompressChunkSize)
+	pivot := &proto.ExtentKey{FileOffset: uint64(start)}
+	upper := &proto.ExtentKey{FileOffset: end}
+	whole = true
+	cache.RLock()
+	defer cache.RUnlock()
+
+	cache.root.DescendLessOrEqual(pivot, func(i btree.Item) bool {
+		ek := i.(*proto.ExtentKey)
+		if ek.FileOffset < uint64(start) && ek.FileOffset+uint64(ek.Size) > uint64(start) {
+			if ek.IsCompressed() {
+				compressed = true
+			} else {
+				whole = false
+			}
+		}
+		return false
+	})
+	cache.root.AscendRange(pivot, upper, func(i btree.Item) bool {
+		ek := i.(*proto.ExtentKey)
+		if ek.IsCompressed() {
+			compressed = true
+		} else if ek.FileOffset+uint64(ek.Size) > end {
+			whole = false
+		}
+		return true
+	})
+	return
+}
+
 // PrepareReadRequests classifies the incoming request.
 func (cache *ExtentCache) PrepareReadRequests(offset, size int, data []byte) []*ExtentRequest {
 	requests := make([]*ExtentRequest, 0)
@@ -492,6 +631,36 @@ func (cache *ExtentCache) PrepareReadRequests(offset, size int, data []byte) []*
 	return requests
 }
 
+// Holes returns the ranges in [start, end) not covered by any extent key.
+func (cache *ExtentCache) Holes(start, end int) (holes [][2]int) {
+	pivot := &proto.ExtentKey{FileOffset: uint64(start)}
+	upper := &proto.ExtentKey{FileOffset: uint64(end)}
+
+	cache.RLock()
+	defer cache.RUnlock()
+
+	lower := &proto.ExtentKey{}
+	cache.root.DescendLessOrEqual(pivot, func(i btree.Item) bool {
+		lower.FileOffset = i.(*proto.ExtentKey).FileOffset
+		return false
+	})
+	cache.root.AscendRange(lower, upper, func(i btree.Item) bool {
+		ek := i.(*proto.ExtentKey)
+		ekStart, ekEnd := int(ek.FileOffset), int(ek.FileOffset)+int(ek.Size)
+		if start < ekStart {
+			holes = append(holes, [2]int{start, ekStart})
+		}
+		if start < ekEnd {
+			start = ekEnd
+		}
+		return start < end
+	})
+	if start < end {
+		holes = append(holes, [2]int{start, end})
+	}
+	return
+}
+
 // PrepareWriteRequests TODO explain
 func (cache *ExtentCache) PrepareWriteRequests(offset, size int, data []byte) []*ExtentRequest {
 	requests := make([]*ExtentRequest, 0)
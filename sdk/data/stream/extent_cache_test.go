@@ -0,0 +1,43 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtentCacheHoles(t *testing.T) {
+	cache := NewExtentCache(1)
+	cache.Append(&proto.ExtentKey{FileOffset: 100, PartitionId: 1, ExtentId: 1025, Size: 100}, true)
+	cache.Append(&proto.ExtentKey{FileOffset: 300, PartitionId: 1, ExtentId: 1026, Size: 100}, true)
+
+	cases := []struct {
+		start, end int
+		holes      [][2]int
+	}{
+		{0, 500, [][2]int{{0, 100}, {200, 300}, {400, 500}}},
+		{150, 350, [][2]int{{200, 300}}},
+		{100, 200, nil},
+		{120, 180, nil},
+		{0, 50, [][2]int{{0, 50}}},
+		{450, 600, [][2]int{{450, 600}}},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.holes, cache.Holes(c.start, c.end), "range [%v, %v)", c.start, c.end)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/cubefs/cubefs/sdk/data/wrapper"
 	"github.com/cubefs/cubefs/sdk/meta"
 	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/cryptoutil"
 	"github.com/cubefs/cubefs/util/errors"
 	"github.com/cubefs/cubefs/util/exporter"
 	"github.com/cubefs/cubefs/util/log"
@@ -46,6 +47,11 @@ type (
 	LoadBcacheFunc      func(key string, buf []byte, offset uint64, size uint32) (int, error)
 	CacheBcacheFunc     func(key string, buf []byte) error
 	EvictBacheFunc      func(key string) error
+	LoadFileCipherFunc  func(inode uint64) (*cryptoutil.FileCipher, error)
+	DedupExtentsFunc    func(inode uint64, chunks []proto.DedupChunk) ([]*proto.ExtentKey, error)
+	AddFingerprintsFunc func(inode uint64, chunks []proto.DedupChunk) error
+	GetObjExtentsFunc   func(inode uint64) (uint64, uint64, []proto.ExtentKey, []proto.ObjExtentKey, error)
+	ReadObjExtentFunc   func(ctx context.Context, volName string, buf []byte, offset, size uint64, oek proto.ObjExtentKey) (int, error)
 )
 
 const (
@@ -118,6 +124,11 @@ type ExtentConfig struct {
 	OnLoadBcache      LoadBcacheFunc
 	OnCacheBcache     CacheBcacheFunc
 	OnEvictBcache     EvictBacheFunc
+	OnLoadFileCipher  LoadFileCipherFunc  // May be null if the volume is not encrypted
+	OnDedupExtents    DedupExtentsFunc    // May be null if the data is never deduplicated
+	OnAddFingerprints AddFingerprintsFunc // May be null if the data is never deduplicated
+	OnGetObjExtents   GetObjExtentsFunc   // May be null if the data is never transitioned to the blobstore
+	OnReadObjExtent   ReadObjExtentFunc   // May be null if the data is never transitioned to the blobstore
 
 	DisableMetaCache             bool
 	MinWriteAbleDataPartitionCnt int
@@ -155,6 +166,12 @@ type ExtentClient struct {
 	loadBcache         LoadBcacheFunc
 	cacheBcache        CacheBcacheFunc
 	evictBcache        EvictBacheFunc
+	loadFileCipher     LoadFileCipherFunc
+	dedupExtents       DedupExtentsFunc
+	addFingerprints    AddFingerprintsFunc
+	getObjExtents      GetObjExtentsFunc
+	readObjExtent      ReadObjExtentFunc
+	dedupStat          dedupStat
 	inflightL1cache    sync.Map
 	inflightL1BigBlock int32
 	multiVerMgr        *MultiVerMgr
@@ -266,6 +283,13 @@ retry:
 	client.loadBcache = config.OnLoadBcache
 	client.cacheBcache = config.OnCacheBcache
 	client.evictBcache = config.OnEvictBcache
+	client.loadFileCipher = config.OnLoadFileCipher
+	client.dedupExtents = config.OnDedupExtents
+	client.addFingerprints = config.OnAddFingerprints
+	if config.OnGetObjExtents != nil && config.OnReadObjExtent != nil {
+		client.getObjExtents = config.OnGetObjExtents
+		client.readObjExtent = config.OnReadObjExtent
+	}
 	client.volumeType = config.VolumeType
 	client.volumeName = config.Volume
 	client.bcacheEnable = config.BcacheEnable
@@ -486,6 +510,11 @@ func (client *ExtentClient) GetExtents(inode uint64) []*proto.ExtentKey {
 	return s.extents.List()
 }
 
+// ReadsObjExtents returns true if the client reads the data transitioned to the blobstore.
+func (client *ExtentClient) ReadsObjExtents() bool {
+	return client.readObjExtent != nil
+}
+
 // FileSize returns the file size.
 func (client *ExtentClient) FileSize(inode uint64) (size int, gen uint64, valid bool) {
 	s := client.GetStreamer(inode)
@@ -520,6 +549,11 @@ func (client *ExtentClient) Write(inode uint64, offset int, data []byte, flags i
 		s.GetExtents()
 	})
 
+	if err = s.loadCipher(); err != nil {
+		log.LogErrorf("Prefix(%v): load file cipher failed, err(%v)", prefix, err)
+		return 0, syscall.EIO
+	}
+
 	write, err = s.IssueWriteRequest(offset, data, flags, checkFunc)
 	if err != nil {
 		log.LogError(errors.Stack(err))
@@ -563,6 +597,39 @@ func (client *ExtentClient) Flush(inode uint64) error {
 	return s.IssueFlushRequest()
 }
 
+// ZeroCompressedChunks writes zeros to the range of the compressed chunks partially covered by the
+// range, which are kept whole by the meta node when the range is punched.
+func (client *ExtentClient) ZeroCompressedChunks(inode uint64, offset, size int) (err error) {
+	s := client.GetStreamer(inode)
+	if s == nil {
+		log.LogErrorf("ZeroCompressedChunks: stream is not opened yet, ino(%v)", inode)
+		return syscall.EBADF
+	}
+	s.once.Do(func() {
+		s.GetExtents()
+	})
+
+	filesize, _ := s.extents.Size()
+	end := util.Min(offset+size, filesize)
+	var ranges [][2]int
+	if offset%proto.CompressChunkSize != 0 {
+		ranges = append(ranges, [2]int{offset, util.Min(end, offset/proto.CompressChunkSize*proto.CompressChunkSize+proto.CompressChunkSize)})
+	}
+	if tail := end / proto.CompressChunkSize * proto.CompressChunkSize; end%proto.CompressChunkSize != 0 && tail > offset {
+		ranges = append(ranges, [2]int{tail, end})
+	}
+	for _, r := range ranges {
+		if r[0] >= r[1] || !s.extents.HasCompressed(r[0], r[1]) {
+			continue
+		}
+		if _, err = s.IssueWriteRequest(r[0], make([]byte, r[1]-r[0]), 0, nil); err != nil {
+			log.LogErrorf("ZeroCompressedChunks: ino(%v) range(%v) err(%v)", inode, r, err)
+			return
+		}
+	}
+	return s.IssueFlushRequest()
+}
+
 func (client *ExtentClient) Read(inode uint64, data []byte, offset int, size int) (read int, err error) {
 	// log.LogErrorf("======> ExtentClient Read Enter, inode(%v), len(data)=(%v), offset(%v), size(%v).", inode, len(data), offset, size)
 	// t1 := time.Now()
@@ -580,6 +647,11 @@ func (client *ExtentClient) Read(inode uint64, data []byte, offset int, size int
 		s.GetExtents()
 	})
 
+	if err = s.loadCipher(); err != nil {
+		log.LogErrorf("Read: load file cipher failed, ino(%v) err(%v)", inode, err)
+		return 0, syscall.EIO
+	}
+
 	err = s.IssueFlushRequest()
 	if err != nil {
 		return
@@ -0,0 +1,368 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/manager"
+	"github.com/cubefs/cubefs/sdk/data/wrapper"
+	"github.com/cubefs/cubefs/storage"
+	"github.com/cubefs/cubefs/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// testDataNode keeps the extents written in memory, every host of the partitions is a data node of
+// its own, so the data is never forwarded to the followers.
+type testDataNode struct {
+	sync.Mutex
+	addr     string
+	ln       net.Listener
+	nextID   uint64
+	extents  map[uint64][]byte // extent id to the data
+	requests map[uint8]int     // opcode to the count of the requests
+}
+
+func newTestDataNode(t *testing.T) *testDataNode {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	node := &testDataNode{
+		addr:     ln.Addr().String(),
+		ln:       ln,
+		nextID:   storage.TinyExtentCount + 1024,
+		extents:  make(map[uint64][]byte),
+		requests: make(map[uint8]int),
+	}
+	t.Cleanup(func() { ln.Close() })
+	go node.serve()
+	return node
+}
+
+func (node *testDataNode) serve() {
+	for {
+		conn, err := node.ln.Accept()
+		if err != nil {
+			return
+		}
+		go node.serveConn(conn)
+	}
+}
+
+func (node *testDataNode) serveConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		p := proto.NewPacket()
+		if err := p.ReadFromConnWithVer(conn, proto.NoReadDeadlineTime); err != nil {
+			return
+		}
+		node.Lock()
+		node.requests[p.Opcode]++
+		node.Unlock()
+
+		var replies []*proto.Packet
+		switch p.Opcode {
+		case proto.OpCreateExtent, proto.OpPreallocExtent:
+			replies = node.createExtent(p)
+		case proto.OpWrite, proto.OpSyncWrite, proto.OpRandomWrite, proto.OpSyncRandomWrite,
+			proto.OpRandomWriteAppend, proto.OpSyncRandomWriteAppend, proto.OpTryWriteAppend, proto.OpSyncTryWriteAppend:
+			replies = node.write(p)
+		case proto.OpStreamRead, proto.OpStreamFollowerRead:
+			replies = node.read(p)
+		default:
+			replies = node.fail(p, fmt.Sprintf("unknown opcode(%v)", p.GetOpMsg()))
+		}
+		for _, reply := range replies {
+			if err := reply.WriteToConn(conn); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (node *testDataNode) fail(p *proto.Packet, msg string) []*proto.Packet {
+	p.ExtentType &^= proto.VersionListFlag
+	p.PacketErrorWithBody(proto.OpErr, []byte(msg))
+	return []*proto.Packet{p}
+}
+
+func (node *testDataNode) createExtent(p *proto.Packet) []*proto.Packet {
+	node.Lock()
+	defer node.Unlock()
+	node.nextID++
+	p.ExtentID = node.nextID
+	node.extents[p.ExtentID] = nil
+	if p.Opcode == proto.OpPreallocExtent {
+		node.extents[p.ExtentID] = make([]byte, binary.BigEndian.Uint64(p.Data[8:16]))
+	}
+	p.PacketOkReply()
+	return []*proto.Packet{p}
+}
+
+func (node *testDataNode) write(p *proto.Packet) []*proto.Packet {
+	if crc32.ChecksumIEEE(p.Data[:p.Size]) != p.CRC {
+		return node.fail(p, "inconsistent crc")
+	}
+	node.Lock()
+	defer node.Unlock()
+	if proto.IsTinyExtentType(p.ExtentType) {
+		// the tiny extent 1 is shared by all the small files
+		p.ExtentID = 1
+		p.ExtentOffset = int64(len(node.extents[p.ExtentID]))
+	}
+	data := node.extents[p.ExtentID]
+	switch p.Opcode {
+	case proto.OpRandomWriteAppend, proto.OpSyncRandomWriteAppend:
+		p.ExtentOffset = int64(len(data))
+	case proto.OpTryWriteAppend, proto.OpSyncTryWriteAppend:
+		if p.ExtentOffset != int64(len(data)) {
+			p.ExtentType &^= proto.VersionListFlag
+			p.PacketErrorWithBody(proto.OpTryOtherExtent, nil)
+			return []*proto.Packet{p}
+		}
+	}
+	if end := int(p.ExtentOffset) + int(p.Size); end > len(data) {
+		if p.Opcode == proto.OpRandomWrite || p.Opcode == proto.OpSyncRandomWrite {
+			return node.fail(p, "random write out of extent")
+		}
+		data = append(data, make([]byte, end-len(data))...)
+	}
+	copy(data[p.ExtentOffset:], p.Data[:p.Size])
+	node.extents[p.ExtentID] = data
+	p.ExtentType &^= proto.VersionListFlag
+	p.PacketOkReply()
+	return []*proto.Packet{p}
+}
+
+func (node *testDataNode) read(p *proto.Packet) (replies []*proto.Packet) {
+	node.Lock()
+	data, ok := node.extents[p.ExtentID]
+	node.Unlock()
+	if !ok || int(p.ExtentOffset)+int(p.Size) > len(data) {
+		return node.fail(p, fmt.Sprintf("read extent(%v) offset(%v) size(%v) out of range", p.ExtentID, p.ExtentOffset, p.Size))
+	}
+	for pos := 0; pos < int(p.Size); pos += util.ReadBlockSize {
+		n := util.Min(int(p.Size)-pos, util.ReadBlockSize)
+		reply := proto.NewPacket()
+		reply.Magic = proto.ProtoMagic
+		reply.Opcode = p.Opcode
+		reply.ReqID = p.ReqID
+		reply.PartitionID = p.PartitionID
+		reply.ExtentID = p.ExtentID
+		reply.ExtentOffset = p.ExtentOffset + int64(pos)
+		reply.KernelOffset = p.KernelOffset + uint64(pos)
+		reply.PacketOkWithBody(data[int(p.ExtentOffset)+pos : int(p.ExtentOffset)+pos+n])
+		reply.CRC = crc32.ChecksumIEEE(reply.Data)
+		replies = append(replies, reply)
+	}
+	return
+}
+
+// requestCount returns how many requests of the opcode are received.
+func (node *testDataNode) requestCount(op uint8) int {
+	node.Lock()
+	defer node.Unlock()
+	return node.requests[op]
+}
+
+// testMeta keeps the extent keys of the inodes and the fingerprints of the chunks in memory.
+type testMeta struct {
+	sync.Mutex
+	inodes       map[uint64]*ExtentCache
+	fingerprints map[string]proto.ExtentKey // fingerprint to the key of the chunk at file offset 0
+	discards     []proto.ExtentKey          // keys discarded by the clients
+}
+
+func newTestMeta() *testMeta {
+	return &testMeta{
+		inodes:       make(map[uint64]*ExtentCache),
+		fingerprints: make(map[string]proto.ExtentKey),
+	}
+}
+
+func (mw *testMeta) inode(ino uint64) *ExtentCache {
+	cache, ok := mw.inodes[ino]
+	if !ok {
+		cache = NewExtentCache(ino)
+		mw.inodes[ino] = cache
+	}
+	return cache
+}
+
+func (mw *testMeta) appendExtentKey(parentInode, inode uint64, key proto.ExtentKey, discard []proto.ExtentKey) (int, error) {
+	mw.Lock()
+	defer mw.Unlock()
+	mw.inode(inode).Append(&key, true)
+	mw.discards = append(mw.discards, discard...)
+	return 0, nil
+}
+
+func (mw *testMeta) splitExtentKey(parentInode, inode uint64, key proto.ExtentKey) error {
+	mw.Lock()
+	defer mw.Unlock()
+	return mw.inode(inode).SplitExtentKey(inode, &key)
+}
+
+func (mw *testMeta) getExtents(inode uint64) (uint64, uint64, []proto.ExtentKey, error) {
+	mw.Lock()
+	defer mw.Unlock()
+	cache := mw.inode(inode)
+	size, gen := cache.Size()
+	var eks []proto.ExtentKey
+	for _, ek := range cache.List() {
+		eks = append(eks, *ek)
+	}
+	return gen, uint64(size), eks, nil
+}
+
+func (mw *testMeta) truncate(inode, size uint64, fullPath string) error {
+	mw.Lock()
+	defer mw.Unlock()
+	mw.inode(inode).SetSize(size, true)
+	return nil
+}
+
+func (mw *testMeta) dedupExtents(inode uint64, chunks []proto.DedupChunk) ([]*proto.ExtentKey, error) {
+	mw.Lock()
+	defer mw.Unlock()
+	eks := make([]*proto.ExtentKey, len(chunks))
+	for idx, chunk := range chunks {
+		ek, ok := mw.fingerprints[string(chunk.Fingerprint)]
+		if !ok || ek.Size != chunk.Size {
+			continue
+		}
+		ek.FileOffset = chunk.FileOffset
+		mw.inode(inode).Append(&ek, true)
+		eks[idx] = &ek
+	}
+	return eks, nil
+}
+
+func (mw *testMeta) addFingerprints(inode uint64, chunks []proto.DedupChunk) error {
+	mw.Lock()
+	defer mw.Unlock()
+	cache := mw.inode(inode)
+	for _, chunk := range chunks {
+		ek := cache.Get(chunk.FileOffset)
+		if ek == nil || ek.FileOffset+uint64(ek.Size) < chunk.FileOffset+uint64(chunk.Size) {
+			continue
+		}
+		mw.fingerprints[string(chunk.Fingerprint)] = proto.ExtentKey{
+			PartitionId:  ek.PartitionId,
+			ExtentId:     ek.ExtentId,
+			ExtentOffset: ek.ExtentOffset + chunk.FileOffset - ek.FileOffset,
+			Size:         chunk.Size,
+		}
+	}
+	return nil
+}
+
+// initBufferPool inits the buffer pool once, since the data nodes of the tests run before keep reading
+// the packets with it.
+var initBufferPool sync.Once
+
+// newTestExtentClient returns an extent client of the volume view, which writes to a partition kept
+// by the data nodes given and records the keys in the meta given.
+func newTestExtentClient(t *testing.T, view *proto.SimpleVolView, nodes []*testDataNode, mw *testMeta) *ExtentClient {
+	initBufferPool.Do(func() { proto.InitBufferPool(int64(32768)) })
+	dp := &proto.DataPartitionResponse{
+		PartitionID: 1,
+		Status:      proto.ReadWrite,
+		ReplicaNum:  uint8(len(nodes)),
+		ECDataNum:   view.ECDataNum,
+		ECParityNum: view.ECParityNum,
+	}
+	for _, node := range nodes {
+		dp.Hosts = append(dp.Hosts, node.addr)
+	}
+	dp.LeaderAddr = dp.Hosts[0]
+	if dp.IsErasureCoded() {
+		dp.ECHosts = dp.Hosts
+	}
+	client := &ExtentClient{
+		streamers:        make(map[uint64]*Streamer),
+		streamerList:     list.New(),
+		disableMetaCache: true,
+		volumeName:       view.Name,
+		multiVerMgr:      &MultiVerMgr{verList: &proto.VolVersionInfoList{}},
+		readLimiter:      rate.NewLimiter(defaultReadLimitRate, defaultReadLimitBurst),
+		writeLimiter:     rate.NewLimiter(defaultWriteLimitRate, defaultWriteLimitBurst),
+		appendExtentKey:  mw.appendExtentKey,
+		splitExtentKey:   mw.splitExtentKey,
+		getExtents:       mw.getExtents,
+		truncate:         mw.truncate,
+		dedupExtents:     mw.dedupExtents,
+		addFingerprints:  mw.addFingerprints,
+	}
+	w, err := wrapper.NewLocalDataPartitionWrapper(client, view, []*proto.DataPartitionResponse{dp})
+	require.NoError(t, err)
+	client.dataWrapper = w
+	client.LimitManager = manager.NewLimitManager(client)
+	t.Cleanup(func() { w.Stop() })
+	return client
+}
+
+// testFileData returns the data of the size, the blocks of which repeat every period bytes if the
+// period is not 0.
+func testFileData(size, period int) []byte {
+	data := make([]byte, size)
+	rand.Read(data)
+	for pos := period; period > 0 && pos < size; pos += period {
+		copy(data[pos:], data[:period])
+	}
+	return data
+}
+
+// readTestFile reads the range of the file back through the extent client.
+func readTestFile(t *testing.T, client *ExtentClient, ino uint64, offset, size int) []byte {
+	data := make([]byte, size)
+	n, err := client.Read(ino, data, offset, size)
+	if err != nil {
+		require.EqualError(t, err, "EOF")
+	}
+	require.Equal(t, size, n)
+	return data
+}
+
+func TestExtentClientWriteRead(t *testing.T) {
+	node := newTestDataNode(t)
+	mw := newTestMeta()
+	client := newTestExtentClient(t, &proto.SimpleVolView{Name: "vol"}, []*testDataNode{node}, mw)
+
+	const ino = 10
+	require.NoError(t, client.OpenStream(ino))
+	data := testFileData(3*util.BlockSize+1000, 0)
+	n, err := client.Write(ino, 0, data, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, len(data), n)
+	require.Equal(t, data, readTestFile(t, client, ino, 0, len(data)))
+
+	require.NoError(t, client.Flush(ino))
+	require.NoError(t, client.CloseStream(ino))
+	require.NoError(t, client.EvictStream(ino))
+
+	// the data is read with the keys recorded by the meta
+	require.NoError(t, client.OpenStream(ino))
+	require.Equal(t, data[1000:util.BlockSize+1000], readTestFile(t, client, ino, 1000, util.BlockSize))
+	require.NoError(t, client.CloseStream(ino))
+}
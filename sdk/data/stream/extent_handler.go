@@ -88,6 +88,10 @@ type ExtentHandler struct {
 	key   *proto.ExtentKey
 	dirty bool // indicate if open handler is dirty.
 
+	// The nonce the data of the encrypted file is written with, taken in the stream writer
+	// before the first packet, and kept in the key.
+	cipherNonce uint64
+
 	// Created in receiver ONLY in recovery status.
 	// Will not be changed once assigned.
 	recoverHandler *ExtentHandler
@@ -196,8 +200,9 @@ func (eh *ExtentHandler) write(data []byte, offset, size int, direct bool) (ek *
 	// This is just a local cache to prepare write requests.
 	// Partition and extent are not allocated.
 	ek = &proto.ExtentKey{
-		FileOffset: uint64(eh.fileOffset),
-		Size:       uint32(eh.size),
+		FileOffset:  uint64(eh.fileOffset),
+		Size:        uint32(eh.size),
+		CipherNonce: eh.cipherNonce,
 	}
 	return ek, nil
 }
@@ -372,6 +377,7 @@ func (eh *ExtentHandler) processReply(packet *Packet) {
 			SnapInfo: &proto.ExtSnapInfo{
 				VerSeq: reply.VerSeq,
 			},
+			CipherNonce: eh.cipherNonce,
 		}
 	} else {
 		eh.key.Size += packet.Size
@@ -546,6 +552,8 @@ func (eh *ExtentHandler) recoverPacket(packet *Packet) error {
 		// Because tiny extent files are limited, tiny store
 		// failures might due to lack of tiny extent file.
 		handler = NewExtentHandler(eh.stream, int(packet.KernelOffset), proto.NormalExtentType, 0)
+		// the packets are encrypted already
+		handler.cipherNonce = eh.cipherNonce
 		handler.setClosed()
 	}
 	handler.pushToRequest(packet)
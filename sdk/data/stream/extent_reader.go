@@ -23,6 +23,7 @@ import (
 	"github.com/cubefs/cubefs/proto"
 	"github.com/cubefs/cubefs/sdk/data/wrapper"
 	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/compressutil"
 	"github.com/cubefs/cubefs/util/errors"
 	"github.com/cubefs/cubefs/util/log"
 )
@@ -55,7 +56,60 @@ func (reader *ExtentReader) String() (m string) {
 
 // Read reads the extent request.
 func (reader *ExtentReader) Read(req *ExtentRequest) (readBytes int, err error) {
+	if reader.key.IsCompressed() {
+		return reader.readCompressed(req)
+	}
 	offset := req.FileOffset - int(reader.key.FileOffset) + int(reader.key.ExtentOffset)
+	return reader.read(req, offset)
+}
+
+// readCompressed reads the frames of the chunks the request covers, and copies the range of the
+// request from the chunks decompressed.
+func (reader *ExtentReader) readCompressed(req *ExtentRequest) (readBytes int, err error) {
+	key := reader.key
+	pos := req.FileOffset - int(key.FileOffset)
+	first, last := pos/proto.CompressChunkSize, (pos+req.Size-1)/proto.CompressChunkSize
+	if last >= len(key.Compression.Frames) {
+		err = errors.New(fmt.Sprintf("readCompressed: req(%v) out of the frames, ino(%v) ek(%v)", req, reader.inode, key))
+		return
+	}
+	stored := int(key.Compression.StoredSize(first, last+1))
+	frames := make([]byte, stored)
+	framesReq := NewExtentRequest(int(key.FileOffset)+first*proto.CompressChunkSize, stored, frames, key)
+	var n int
+	if n, err = reader.read(framesReq, int(key.ExtentOffset+key.Compression.StoredSize(0, first))); err != nil {
+		return
+	}
+	if n < stored {
+		err = errors.New(fmt.Sprintf("readCompressed: short frames, ino(%v) ek(%v) read(%v)", reader.inode, key, n))
+		return
+	}
+
+	chunkStart := first * proto.CompressChunkSize
+	for i := first; i <= last; i++ {
+		size, raw := key.Compression.Frame(i)
+		frame := frames[:size]
+		frames = frames[size:]
+		data := frame
+		if !raw {
+			if data, err = compressutil.Decompress(key.Compression.Codec, frame, proto.CompressChunkSize); err != nil {
+				log.LogErrorf("readCompressed: ino(%v) ek(%v) chunk(%v) err(%v)", reader.inode, key, i, err)
+				return
+			}
+		}
+		from := util.Max(pos+readBytes, chunkStart) - chunkStart
+		to := util.Min(pos+req.Size, chunkStart+proto.CompressChunkSize) - chunkStart
+		if to > len(data) {
+			err = errors.New(fmt.Sprintf("readCompressed: req(%v) out of chunk(%v) size(%v), ino(%v) ek(%v)", req, i, len(data), reader.inode, key))
+			return
+		}
+		readBytes += copy(req.Data[readBytes:req.Size], data[from:to])
+		chunkStart += proto.CompressChunkSize
+	}
+	return
+}
+
+func (reader *ExtentReader) read(req *ExtentRequest, offset int) (readBytes int, err error) {
 	size := req.Size
 
 	reqPacket := NewReadPacket(reader.key, offset, size, reader.inode, req.FileOffset, reader.followerRead)
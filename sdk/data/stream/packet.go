@@ -157,6 +157,17 @@ func NewCreateExtentPacket(dp *wrapper.DataPartition, inode uint64) *Packet {
 	return p
 }
 
+// NewPreallocExtentPacket returns a new packet to create the extent filled with zeros of the size.
+func NewPreallocExtentPacket(dp *wrapper.DataPartition, inode uint64, size int) *Packet {
+	p := NewCreateExtentPacket(dp, inode)
+	p.Opcode = proto.OpPreallocExtent
+	p.Data = make([]byte, 16)
+	binary.BigEndian.PutUint64(p.Data[:8], inode)
+	binary.BigEndian.PutUint64(p.Data[8:], uint64(size))
+	p.Size = uint32(len(p.Data))
+	return p
+}
+
 // NewReply returns a new reply packet. TODO rename to NewReplyPacket?
 func NewReply(reqID int64, partitionID uint64, extentID uint64) *Packet {
 	p := new(Packet)
@@ -0,0 +1,432 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync/atomic"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/wrapper"
+	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/compressutil"
+	"github.com/cubefs/cubefs/util/errors"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// The chunks of the compressed file are buffered by the streamer and written batch by batch. Every
+// chunk is compressed alone into a frame, the frames of the chunks following each other are appended
+// to a normal extent one by one and indexed by a single key.
+
+// compressBatchChunks is how many full chunks are buffered before they are written.
+const compressBatchChunks = 8
+
+// compressExtent is the extent the frames of the streamer are appended to.
+type compressExtent struct {
+	dp       *wrapper.DataPartition
+	extentID uint64
+	size     int64            // size of the frames written
+	key      *proto.ExtentKey // the key of the frames written last
+}
+
+// compressible returns true if the write is done by replacing the chunks it touches, which is the
+// case if the volume compresses the data, or the chunks have compressed keys or are buffered. The
+// encrypted files are never compressed.
+func (s *Streamer) compressible(offset, size int) bool {
+	if s.cipher != nil {
+		return false
+	}
+	if s.client.dataWrapper.Compression() != compressutil.None {
+		return true
+	}
+	start := offset / proto.CompressChunkSize * proto.CompressChunkSize
+	end := (offset + size + proto.CompressChunkSize - 1) / proto.CompressChunkSize * proto.CompressChunkSize
+	if len(s.compressBuf) > 0 && start < s.compressOffset+len(s.compressBuf) && end > s.compressOffset {
+		return true
+	}
+	return s.extents.HasCompressed(start, end)
+}
+
+// writeCompressed merges the data into the chunks buffered, the full chunks are written once the
+// buffer is large enough, and the partial chunk at the tail is kept until it is full or flushed.
+func (s *Streamer) writeCompressed(data []byte, offset, size, flags int, checkFunc func() error) (total int, err error) {
+	filesize, _ := s.extents.Size()
+	if checkFunc != nil && offset+size > filesize {
+		if err = checkFunc(); err != nil {
+			return
+		}
+	}
+
+	log.LogDebugf("Streamer writeCompressed enter: ino(%v) offset(%v) size(%v) flags(%v) buffered(%v,%v)",
+		s.inode, offset, size, flags, s.compressOffset, len(s.compressBuf))
+
+	flags &^= proto.FlagsAppend
+	for total < size {
+		pos := offset + total
+		chunk := pos / proto.CompressChunkSize * proto.CompressChunkSize
+		n := util.Min(size-total, chunk+proto.CompressChunkSize-pos)
+		var buffered bool
+		if buffered, err = s.bufferChunk(chunk); err != nil {
+			break
+		}
+		if !buffered {
+			var written int
+			written, err = s.doWrite(data[total:total+n], pos, n, flags, nil)
+			total += written
+			if err != nil {
+				break
+			}
+			continue
+		}
+
+		from := pos - s.compressOffset
+		if gap := from + n - len(s.compressBuf); gap > 0 {
+			s.compressBuf = append(s.compressBuf, make([]byte, gap)...)
+		}
+		copy(s.compressBuf[from:], data[total:total+n])
+		s.compressDirty = true
+		s.updateCompressPending()
+		total += n
+		if filesize, _ = s.extents.Size(); pos+n > filesize {
+			s.extents.SetSize(uint64(pos+n), false)
+		}
+
+		if len(s.compressBuf) >= compressBatchChunks*proto.CompressChunkSize {
+			full := len(s.compressBuf) / proto.CompressChunkSize * proto.CompressChunkSize
+			if err = s.writeChunks(s.compressBuf[:full], s.compressOffset); err != nil {
+				break
+			}
+			s.compressBuf = append(s.compressBuf[:0], s.compressBuf[full:]...)
+			s.compressOffset += full
+			s.compressDirty = len(s.compressBuf) > 0
+			s.updateCompressPending()
+		}
+	}
+	if err == nil && flags&proto.FlagsSyncWrite != 0 {
+		err = s.flushCompressed()
+	}
+	if err != nil {
+		log.LogErrorf("Streamer writeCompressed: ino(%v) offset(%v) total(%v) err(%v)", s.inode, offset, total, err)
+	}
+	log.LogDebugf("Streamer writeCompressed exit: ino(%v) offset(%v) size(%v) done total(%v) err(%v)", s.inode, offset, size, total, err)
+	return
+}
+
+// bufferChunk makes the chunk from the offset buffered. The chunk is read into the buffer unless it is
+// buffered already, and false is returned if the chunk is to be written raw, which is the case if a
+// raw key is across the boundaries of it, or neither the volume compresses the data nor the chunk
+// has compressed keys.
+func (s *Streamer) bufferChunk(chunk int) (ok bool, err error) {
+	if len(s.compressBuf) > 0 && !s.compressDirty && !s.compressTailValid() {
+		s.resetCompressed()
+	}
+	end := s.compressOffset + len(s.compressBuf)
+	if len(s.compressBuf) > 0 && chunk >= s.compressOffset && chunk < end {
+		return true, nil
+	}
+	// only the chunk next to the full chunks buffered is buffered together
+	if len(s.compressBuf) == 0 || chunk != end || end%proto.CompressChunkSize != 0 {
+		if err = s.flushCompressed(); err != nil {
+			return
+		}
+		s.resetCompressed()
+	}
+
+	whole, compressed := s.extents.PrepareChunkWrite(chunk)
+	if !whole || (s.client.dataWrapper.Compression() == compressutil.None && !compressed) {
+		if err = s.flushCompressed(); err != nil {
+			return
+		}
+		s.resetCompressed()
+		return false, nil
+	}
+
+	// the keys of the chunk must be known before it is read
+	if s.handler != nil || s.dirtylist.Len() > 0 {
+		if err = s.closeOpenHandler(); err != nil {
+			return
+		}
+		if err = s.flush(); err != nil {
+			return
+		}
+	}
+	if len(s.compressBuf) == 0 {
+		s.compressOffset = chunk
+	}
+	filesize, _ := s.extents.Size()
+	if loadEnd := util.Min(chunk+proto.CompressChunkSize, filesize); loadEnd > chunk {
+		buf := make([]byte, loadEnd-chunk)
+		if _, err = s.read(buf, chunk, len(buf)); err != nil && err != io.EOF {
+			log.LogErrorf("Streamer bufferChunk: read ino(%v) chunk(%v) err(%v)", s.inode, chunk, err)
+			return
+		}
+		err = nil
+		s.compressBuf = append(s.compressBuf, buf...)
+	}
+	return true, nil
+}
+
+// compressTailValid returns true if the chunk kept after the flush is still the tail of the file
+// written last, so that it is appended without reading it back.
+func (s *Streamer) compressTailValid() bool {
+	end := s.compressOffset + len(s.compressBuf)
+	filesize, _ := s.extents.Size()
+	ek := s.extents.Get(uint64(s.compressOffset))
+	return filesize == end && ek != nil && ek.IsCompressed() && ek.FileOffset+uint64(ek.Size) == uint64(end)
+}
+
+// flushCompressed writes the chunks buffered, the partial chunk at the tail is kept in the buffer.
+func (s *Streamer) flushCompressed() (err error) {
+	if !s.compressDirty {
+		return
+	}
+	if err = s.writeChunks(s.compressBuf, s.compressOffset); err != nil {
+		return
+	}
+	tail := len(s.compressBuf) / proto.CompressChunkSize * proto.CompressChunkSize
+	if tail == len(s.compressBuf) {
+		s.resetCompressed()
+		return
+	}
+	s.compressBuf = append(s.compressBuf[:0], s.compressBuf[tail:]...)
+	s.compressOffset += tail
+	s.compressDirty = false
+	s.updateCompressPending()
+	return
+}
+
+func (s *Streamer) resetCompressed() {
+	s.compressBuf = nil
+	s.compressOffset = 0
+	s.compressDirty = false
+	s.updateCompressPending()
+}
+
+// updateCompressPending publishes the range not written to the readers.
+func (s *Streamer) updateCompressPending() {
+	if !s.compressDirty {
+		atomic.StoreInt32(&s.compressPending, 0)
+		return
+	}
+	atomic.StoreInt64(&s.compressPendingOffset, int64(s.compressOffset))
+	atomic.StoreInt64(&s.compressPendingEnd, int64(s.compressOffset+len(s.compressBuf)))
+	atomic.StoreInt32(&s.compressPending, 1)
+}
+
+// compressPendingFlush writes the chunks buffered before the range overlapping them is read.
+func (s *Streamer) compressPendingFlush(offset, size int) (err error) {
+	if atomic.LoadInt32(&s.compressPending) == 0 || int64(offset+size) <= atomic.LoadInt64(&s.compressPendingOffset) ||
+		int64(offset) >= atomic.LoadInt64(&s.compressPendingEnd) {
+		return
+	}
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return s.IssueFlushRequest()
+}
+
+// writeChunks compresses the chunks from the file offset and appends the frames to the extent, every
+// chunk is full except the last one. The chunk is kept raw if it is not smaller once compressed.
+func (s *Streamer) writeChunks(data []byte, fileOffset int) (err error) {
+	codec := s.client.dataWrapper.Compression()
+	frames := make([]uint32, 0, (len(data)+proto.CompressChunkSize-1)/proto.CompressChunkSize)
+	stored := make([]byte, 0, len(data))
+	for pos := 0; pos < len(data); pos += proto.CompressChunkSize {
+		chunk := data[pos:util.Min(len(data), pos+proto.CompressChunkSize)]
+		frame := uint32(len(chunk)) | proto.CompressFrameRaw
+		if codec != compressutil.None {
+			var compressed []byte
+			if compressed, err = compressutil.Compress(codec, chunk); err != nil {
+				log.LogErrorf("Streamer writeChunks: ino(%v) codec(%v) err(%v)", s.inode, compressutil.Name(codec), err)
+				return
+			}
+			if len(compressed) < len(chunk) {
+				chunk, frame = compressed, uint32(len(compressed))
+			}
+		}
+		frames = append(frames, frame)
+		stored = append(stored, chunk...)
+	}
+
+	ctx := context.Background()
+	s.client.writeLimiter.Wait(ctx)
+
+	exclude := make(map[string]struct{})
+	for i := 0; i < MaxSelectDataPartitionForWrite; i++ {
+		ext := s.compressExtent
+		if ext != nil && ext.size+int64(len(stored)) > util.ExtentSize {
+			ext = nil
+		}
+		if ext == nil {
+			if ext, err = s.allocateCompressExtent(exclude); err != nil {
+				continue
+			}
+		}
+		if err = s.sendFrames(ext, stored, fileOffset); err != nil {
+			log.LogWarnf("Streamer writeChunks: exclude dp(%v) for write, ino(%v) extent(%v) err(%v) exclude(%v)",
+				ext.dp, s.inode, ext.extentID, err, exclude)
+			// the frames may be written partially, never append to the extent
+			s.compressExtent = nil
+			ext.dp.CheckAllHostsIsAvail(exclude)
+			continue
+		}
+		s.compressExtent = ext
+		break
+	}
+	if err != nil {
+		return
+	}
+	return s.appendCompressedKey(s.compressExtent, int64(len(stored)), fileOffset, len(data), codec, frames)
+}
+
+// appendCompressedKey records the frames written to the extent, the key written last is extended if
+// its chunks are full and the frames follow it in both the file and the extent.
+func (s *Streamer) appendCompressedKey(ext *compressExtent, written int64, fileOffset, size int, codec uint8, frames []uint32) (err error) {
+	var ek *proto.ExtentKey
+	if key := ext.key; key != nil && s.extendable(ext, key, fileOffset, codec, len(frames)) {
+		ek = &proto.ExtentKey{}
+		*ek = *key
+		ek.Size += uint32(size)
+		ek.Compression = &proto.ExtCompression{
+			Codec:  codec,
+			Frames: append(append([]uint32(nil), key.Compression.Frames...), frames...),
+		}
+	} else {
+		ek = &proto.ExtentKey{
+			FileOffset:   uint64(fileOffset),
+			PartitionId:  ext.dp.PartitionID,
+			ExtentId:     ext.extentID,
+			ExtentOffset: uint64(ext.size),
+			Size:         uint32(size),
+			SnapInfo: &proto.ExtSnapInfo{
+				VerSeq: s.verSeq,
+			},
+			Compression: &proto.ExtCompression{Codec: codec, Frames: frames},
+		}
+	}
+	ext.size += written
+
+	discards := s.extents.Append(ek, true)
+	if _, err = s.client.appendExtentKey(s.parentInode, s.inode, *ek, discards); err != nil {
+		log.LogErrorf("Streamer appendCompressedKey: ino(%v) ek(%v) discards(%v) err(%v)", s.inode, ek, discards, err)
+		ext.key = nil
+		if e := s.GetExtentsForce(); e != nil {
+			log.LogErrorf("Streamer appendCompressedKey: ino(%v) GetExtents err(%v)", s.inode, e)
+		}
+		return
+	}
+	s.extents.RemoveDiscard(discards)
+	if s.client.bcacheEnable {
+		for _, key := range discards {
+			cacheKey := util.GenerateRepVolKey(s.client.volumeName, s.inode, key.PartitionId, key.ExtentId, key.FileOffset)
+			go s.client.evictBcache(cacheKey)
+		}
+	}
+	ext.key = ek
+	log.LogDebugf("Streamer appendCompressedKey: ino(%v) ek(%v) discards(%v)", s.inode, ek, discards)
+	return
+}
+
+// extendable returns true if the frames of the chunks from the file offset could be indexed by the
+// key written last, which is still in the cache as it is written.
+func (s *Streamer) extendable(ext *compressExtent, key *proto.ExtentKey, fileOffset int, codec uint8, count int) bool {
+	chunks := len(key.Compression.Frames)
+	if key.Compression.Codec != codec || key.GetSeq() != s.verSeq || chunks+count > proto.CompressKeyMaxChunks ||
+		uint64(key.Size) != uint64(chunks)*proto.CompressChunkSize || key.FileOffset+uint64(key.Size) != uint64(fileOffset) ||
+		int64(key.ExtentOffset+key.Compression.StoredSize(0, chunks)) != ext.size {
+		return false
+	}
+	cur := s.extents.Get(key.FileOffset)
+	return cur != nil && cur.FileOffset == key.FileOffset && cur.Size == key.Size && cur.PartitionId == key.PartitionId &&
+		cur.ExtentId == key.ExtentId && cur.ExtentOffset == key.ExtentOffset
+}
+
+func (s *Streamer) allocateCompressExtent(exclude map[string]struct{}) (ext *compressExtent, err error) {
+	var dp *wrapper.DataPartition
+	if dp, err = s.client.dataWrapper.GetDataPartitionForWrite(exclude); err != nil {
+		log.LogWarnf("Streamer allocateCompressExtent: failed to get write data partition, ino(%v) exclude(%v), clear exclude and try again!", s.inode, exclude)
+		for k := range exclude {
+			delete(exclude, k)
+		}
+		return
+	}
+	var extID uint64
+	if extID, err = s.createExtent(dp); err != nil {
+		log.LogWarnf("Streamer allocateCompressExtent: exclude dp(%v) for write caused by create extent failed, ino(%v) err(%v) exclude(%v)",
+			dp, s.inode, err, exclude)
+		s.client.dataWrapper.RemoveDataPartitionForWrite(dp.PartitionID)
+		dp.CheckAllHostsIsAvail(exclude)
+		return
+	}
+	return &compressExtent{dp: dp, extentID: extID}, nil
+}
+
+// sendFrames appends the frames to the extent by the leader, which forwards them to the followers.
+func (s *Streamer) sendFrames(ext *compressExtent, frames []byte, fileOffset int) (err error) {
+	host := ext.dp.Hosts[0]
+	conn, err := StreamConnPool.GetConnect(host)
+	if err != nil {
+		return
+	}
+	defer func() {
+		StreamConnPool.PutConnect(conn, err != nil)
+	}()
+
+	for pos := 0; pos < len(frames); {
+		n := util.Min(len(frames)-pos, util.BlockSize)
+		p := newFramesPacket(ext, ext.size+int64(pos), frames[pos:pos+n], s.inode, fileOffset)
+		if err = p.WriteToConn(conn); err != nil {
+			return
+		}
+		reply := NewReply(p.ReqID, p.PartitionID, p.ExtentID)
+		if err = reply.ReadFromConnWithVer(conn, proto.ReadDeadlineTime); err != nil {
+			return
+		}
+		if reply.ResultCode != proto.OpOk || !p.isValidWriteReply(reply) || p.CRC != reply.CRC {
+			return errors.New(fmt.Sprintf("sendFrames: failed or invalid reply, ino(%v) host(%v) req(%v) reply(%v)", s.inode, host, p, reply))
+		}
+		if reply.VerSeq > s.verSeq {
+			s.client.UpdateLatestVer(&proto.VolVersionInfoList{VerList: reply.VerList})
+		}
+		pos += n
+	}
+	return
+}
+
+// newFramesPacket returns the packet appending the frames to the extent of all the hosts.
+func newFramesPacket(ext *compressExtent, extentOffset int64, data []byte, inode uint64, fileOffset int) *Packet {
+	p := new(Packet)
+	p.ReqID = proto.GenerateRequestID()
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpWrite
+	p.ExtentType = proto.NormalExtentType
+	p.PartitionID = ext.dp.PartitionID
+	p.ExtentID = ext.extentID
+	p.ExtentOffset = extentOffset
+	p.Arg = ([]byte)(ext.dp.GetAllAddrs())
+	p.ArgLen = uint32(len(p.Arg))
+	p.RemainingFollowers = uint8(len(ext.dp.Hosts) - 1)
+	if len(ext.dp.Hosts) == 1 {
+		p.RemainingFollowers = 127
+	}
+	p.Data = data
+	p.Size = uint32(len(data))
+	p.CRC = crc32.ChecksumIEEE(data)
+	p.inode = inode
+	p.KernelOffset = uint64(fileOffset)
+	return p
+}
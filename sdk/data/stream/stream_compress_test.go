@@ -0,0 +1,152 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+const testChunkSize = proto.CompressChunkSize
+
+func newTestCompressClient(t *testing.T) (*ExtentClient, *testDataNode, *testMeta) {
+	node := newTestDataNode(t)
+	mw := newTestMeta()
+	view := &proto.SimpleVolView{Name: "vol", Compression: "zstd"}
+	return newTestExtentClient(t, view, []*testDataNode{node}, mw), node, mw
+}
+
+func TestStreamerCompressRoundTrip(t *testing.T) {
+	client, node, mw := newTestCompressClient(t)
+	const (
+		ino    = 10
+		chunks = 20
+	)
+	// the chunk 2 is not compressible and kept raw
+	data := testFileData(chunks*testChunkSize+5000, 4096)
+	copy(data[2*testChunkSize:3*testChunkSize], testFileData(testChunkSize, 0))
+
+	require.NoError(t, client.OpenStream(ino))
+	writeTestFile(t, client, ino, 0, data, 100*1024)
+	s := client.GetStreamer(ino)
+	require.EqualValues(t, 1, atomic.LoadInt32(&s.compressPending))
+
+	// the chunks buffered are written before they are read
+	buf := make([]byte, 10000)
+	n, err := s.read(buf, 0, 1000)
+	require.NoError(t, err)
+	require.Equal(t, data[:1000], buf[:n])
+	require.EqualValues(t, 1, atomic.LoadInt32(&s.compressPending))
+	n, err = s.read(buf, len(data)-10000, 10000)
+	require.NoError(t, err)
+	require.Equal(t, data[len(data)-10000:], buf[:n])
+	require.EqualValues(t, 0, atomic.LoadInt32(&s.compressPending))
+	require.Equal(t, data, readTestFile(t, client, ino, 0, len(data)))
+
+	// the frames of all the chunks are appended to a normal extent and indexed by a single key
+	_, _, eks, err := mw.getExtents(ino)
+	require.NoError(t, err)
+	require.Len(t, eks, 1)
+	require.EqualValues(t, len(data), eks[0].Size)
+	require.Len(t, eks[0].Compression.Frames, chunks+1)
+	_, raw := eks[0].Compression.Frame(2)
+	require.True(t, raw)
+	_, raw = eks[0].Compression.Frame(3)
+	require.False(t, raw)
+	require.Less(t, node.requestCount(proto.OpWrite), chunks)
+	node.Lock()
+	require.Empty(t, node.extents[1])
+	node.Unlock()
+
+	// the partial chunk at the tail is appended without reading it back
+	reads := node.requestCount(proto.OpStreamRead)
+	tail := testFileData(3000, 1000)
+	writeTestFile(t, client, ino, len(data), tail, len(tail))
+	require.NoError(t, client.Flush(ino))
+	require.Equal(t, reads, node.requestCount(proto.OpStreamRead))
+	data = append(data, tail...)
+
+	// the frame of the tail chunk written before is discarded
+	_, _, eks, err = mw.getExtents(ino)
+	require.NoError(t, err)
+	require.Len(t, eks, 2)
+	require.Len(t, eks[0].Compression.Frames, chunks)
+	require.EqualValues(t, chunks*testChunkSize, eks[1].FileOffset)
+	require.EqualValues(t, 8000, eks[1].Size)
+	require.Len(t, mw.discards, 1)
+	require.EqualValues(t, chunks*testChunkSize, mw.discards[0].FileOffset)
+	require.Equal(t, data, readTestFile(t, client, ino, 0, len(data)))
+	require.NoError(t, client.CloseStream(ino))
+
+	// the keys are read back after the streamer is evicted
+	require.NoError(t, client.EvictStream(ino))
+	require.NoError(t, client.OpenStream(ino))
+	require.Equal(t, data[testChunkSize-10:5*testChunkSize], readTestFile(t, client, ino, testChunkSize-10, 4*testChunkSize+10))
+	require.Equal(t, data, readTestFile(t, client, ino, 0, len(data)))
+	require.NoError(t, client.CloseStream(ino))
+}
+
+func TestStreamerCompressOverwrite(t *testing.T) {
+	client, _, mw := newTestCompressClient(t)
+	const ino = 10
+	data := testFileData(10*testChunkSize, 4096)
+	require.NoError(t, client.OpenStream(ino))
+	writeTestFile(t, client, ino, 0, data, testChunkSize)
+	require.NoError(t, client.Flush(ino))
+	_, _, eks, err := mw.getExtents(ino)
+	require.NoError(t, err)
+	require.Len(t, eks, 1)
+	origin := eks[0]
+
+	// the chunks overwritten are buffered together, and read back before they are written
+	patch := testFileData(1000, 0)
+	writeTestFile(t, client, ino, 3*testChunkSize+100, patch, len(patch))
+	copy(data[3*testChunkSize+100:], patch)
+	writeTestFile(t, client, ino, 5*testChunkSize-50, patch[:100], 100)
+	copy(data[5*testChunkSize-50:], patch[:100])
+	s := client.GetStreamer(ino)
+	require.EqualValues(t, 1, atomic.LoadInt32(&s.compressPending))
+	buf := make([]byte, 2000)
+	n, err := s.read(buf, 3*testChunkSize, len(buf))
+	require.NoError(t, err)
+	require.Equal(t, data[3*testChunkSize:3*testChunkSize+len(buf)], buf[:n])
+	require.EqualValues(t, 0, atomic.LoadInt32(&s.compressPending))
+	require.Equal(t, data, readTestFile(t, client, ino, 0, len(data)))
+
+	// the key written before is split at the chunks, the frames of the chunks replaced are discarded
+	_, _, eks, err = mw.getExtents(ino)
+	require.NoError(t, err)
+	require.Len(t, eks, 3)
+	require.EqualValues(t, []uint64{0, 3 * testChunkSize, 6 * testChunkSize}, []uint64{eks[0].FileOffset, eks[1].FileOffset, eks[2].FileOffset})
+	require.Equal(t, origin.ExtentOffset+origin.Compression.StoredSize(0, 6), eks[2].ExtentOffset)
+	require.Len(t, mw.discards, 1)
+	require.Equal(t, origin.ExtentOffset+origin.Compression.StoredSize(0, 3), mw.discards[0].ExtentOffset)
+	require.Len(t, mw.discards[0].Compression.Frames, 3)
+
+	// the chunk before the chunks buffered is written alone
+	writeTestFile(t, client, ino, testChunkSize+10, patch, len(patch))
+	copy(data[testChunkSize+10:], patch)
+	require.NoError(t, client.Flush(ino))
+	require.Equal(t, data, readTestFile(t, client, ino, 0, len(data)))
+	require.NoError(t, client.CloseStream(ino))
+
+	require.NoError(t, client.EvictStream(ino))
+	require.NoError(t, client.OpenStream(ino))
+	require.Equal(t, data, readTestFile(t, client, ino, 0, len(data)))
+	require.NoError(t, client.CloseStream(ino))
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"crypto/sha256"
+	"sync/atomic"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/chunker"
+	"github.com/cubefs/cubefs/util/compressutil"
+	"github.com/cubefs/cubefs/util/exporter"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// the data appended is drained once the buffer reaches the size, the tail not cut is kept in the buffer
+const dedupDrainSize = 4 * 1024 * 1024
+
+type dedupStat struct {
+	total   uint64 // bytes of the chunks drained
+	deduped uint64 // bytes of the chunks referring to the data written before
+}
+
+// updateDedupRatio exports the ratio of the data deduplicated to the data appended of the volume.
+func (client *ExtentClient) updateDedupRatio(total, deduped uint64) {
+	total = atomic.AddUint64(&client.dedupStat.total, total)
+	deduped = atomic.AddUint64(&client.dedupStat.deduped, deduped)
+	if total == 0 {
+		return
+	}
+	exporter.NewGauge("dedupRatio").SetWithLabels(float64(deduped)/float64(total),
+		map[string]string{exporter.Vol: client.volumeName})
+}
+
+// dedupable returns true if the write appends data to be deduplicated, which is buffered until the
+// chunks are cut. The encrypted and compressed data is never deduplicated, since the same data is
+// stored differently.
+func (s *Streamer) dedupable(offset, flags int) bool {
+	if s.cipher != nil || flags&proto.FlagsSyncWrite != 0 {
+		return false
+	}
+	if s.client.dedupExtents == nil || s.client.addFingerprints == nil || !s.client.dataWrapper.DedupEnabled() {
+		return false
+	}
+	if s.client.dataWrapper.Compression() != compressutil.None {
+		return false
+	}
+	filesize, _ := s.extents.Size()
+	return offset == filesize
+}
+
+// writeDedup appends the data to the buffer, the size of the file covers the data buffered.
+func (s *Streamer) writeDedup(data []byte, offset, size int, checkFunc func() error) (total int, err error) {
+	if checkFunc != nil {
+		if err = checkFunc(); err != nil {
+			return
+		}
+	}
+	if len(s.dedupBuf) == 0 {
+		s.dedupOffset = offset
+	}
+	s.dedupBuf = append(s.dedupBuf, data[:size]...)
+	s.updateDedupPending()
+	s.extents.SetSize(uint64(offset+size), false)
+	log.LogDebugf("Streamer writeDedup: ino(%v) offset(%v) size(%v) buffered(%v)", s.inode, offset, size, len(s.dedupBuf))
+
+	if len(s.dedupBuf) >= dedupDrainSize {
+		if err = s.drainDedup(false); err != nil {
+			return
+		}
+	}
+	return size, nil
+}
+
+// drainDedup cuts the data buffered into chunks, the chunks found by the fingerprints refer to the data
+// written before and the others are written and indexed. The tail not ending at a cut point is kept in
+// the buffer unless it is final.
+func (s *Streamer) drainDedup(final bool) (err error) {
+	data, offset := s.dedupBuf, s.dedupOffset
+	if len(data) == 0 {
+		return
+	}
+
+	var chunks []proto.DedupChunk
+	pos := 0
+	for pos < len(data) {
+		n, cut := chunker.Cut(data[pos:])
+		if !cut && !final {
+			break
+		}
+		sum := sha256.Sum256(data[pos : pos+n])
+		chunks = append(chunks, proto.DedupChunk{Fingerprint: sum[:], FileOffset: uint64(offset + pos), Size: uint32(n)})
+		pos += n
+	}
+	s.dedupBuf = nil
+	if pos < len(data) {
+		s.dedupBuf = append(s.dedupBuf, data[pos:]...)
+		s.dedupOffset = offset + pos
+	}
+	s.updateDedupPending()
+	if len(chunks) == 0 {
+		return
+	}
+	return s.writeDedupChunks(data[:pos], offset, chunks)
+}
+
+// updateDedupPending publishes the range buffered to the readers.
+func (s *Streamer) updateDedupPending() {
+	if len(s.dedupBuf) == 0 {
+		atomic.StoreInt32(&s.dedupPending, 0)
+		return
+	}
+	atomic.StoreInt64(&s.dedupPendingOffset, int64(s.dedupOffset))
+	atomic.StoreInt32(&s.dedupPending, 1)
+}
+
+// dedupPendingFlush drains the data buffered before the range overlapping it is read, the data buffered
+// is always the tail of the file.
+func (s *Streamer) dedupPendingFlush(offset, size int) (err error) {
+	if atomic.LoadInt32(&s.dedupPending) == 0 || int64(offset+size) <= atomic.LoadInt64(&s.dedupPendingOffset) {
+		return
+	}
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return s.IssueFlushRequest()
+}
+
+func (s *Streamer) writeDedupChunks(data []byte, offset int, chunks []proto.DedupChunk) (err error) {
+	// the chunks written must not be merged into the keys written before
+	if err = s.closeOpenHandler(); err != nil {
+		return
+	}
+	if err = s.flush(); err != nil {
+		return
+	}
+
+	eks, err := s.client.dedupExtents(s.inode, chunks)
+	if err != nil {
+		// the chunks are written as usual
+		log.LogWarnf("Streamer writeDedupChunks: ino(%v) offset(%v) dedup err(%v)", s.inode, offset, err)
+		eks, err = nil, nil
+	}
+
+	var deduped uint64
+	written := make([]proto.DedupChunk, 0, len(chunks))
+	for idx, chunk := range chunks {
+		if eks != nil && eks[idx] != nil {
+			ek := eks[idx]
+			ek.SnapInfo = &proto.ExtSnapInfo{VerSeq: s.verSeq, Shared: true}
+			s.extents.RemoveDiscard(s.extents.Append(ek, true))
+			deduped += uint64(chunk.Size)
+			continue
+		}
+		start := int(chunk.FileOffset) - offset
+		if _, err = s.doWrite(data[start:start+int(chunk.Size)], int(chunk.FileOffset), int(chunk.Size), 0, nil); err != nil {
+			log.LogErrorf("Streamer writeDedupChunks: ino(%v) chunk(%v) err(%v)", s.inode, chunk.FileOffset, err)
+			return
+		}
+		written = append(written, chunk)
+	}
+	s.client.updateDedupRatio(uint64(len(data)), deduped)
+	log.LogDebugf("Streamer writeDedupChunks: ino(%v) offset(%v) size(%v) chunks(%v) deduped(%v)",
+		s.inode, offset, len(data), len(chunks), deduped)
+	if len(written) == 0 {
+		return
+	}
+
+	// the keys of the chunks must be known before the fingerprints are indexed
+	if err = s.closeOpenHandler(); err != nil {
+		return
+	}
+	if err = s.flush(); err != nil {
+		return
+	}
+	// the data indexed must not be overwritten, even if the result of the request is unknown
+	s.extents.MarkShared(offset, offset+len(data))
+	if err = s.client.addFingerprints(s.inode, written); err != nil {
+		// the chunks are just not deduplicated later
+		log.LogWarnf("Streamer writeDedupChunks: ino(%v) offset(%v) add fingerprints err(%v)", s.inode, offset, err)
+		err = nil
+	}
+	return
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDedupClient(t *testing.T) (*ExtentClient, *testDataNode) {
+	node := newTestDataNode(t)
+	view := &proto.SimpleVolView{Name: "vol", EnableDedup: true}
+	return newTestExtentClient(t, view, []*testDataNode{node}, newTestMeta()), node
+}
+
+func writeTestFile(t *testing.T, client *ExtentClient, ino uint64, offset int, data []byte, writeSize int) {
+	for pos := 0; pos < len(data); pos += writeSize {
+		end := pos + writeSize
+		if end > len(data) {
+			end = len(data)
+		}
+		n, err := client.Write(ino, offset+pos, data[pos:end], 0, nil)
+		require.NoError(t, err)
+		require.Equal(t, end-pos, n)
+	}
+}
+
+func TestStreamerDedupReadBeforeFlush(t *testing.T) {
+	client, _ := newTestDedupClient(t)
+	const ino = 10
+	require.NoError(t, client.OpenStream(ino))
+	data := testFileData(1024*1024+123, 0)
+	writeTestFile(t, client, ino, 0, data[:300*1024], 100*1024)
+	require.NoError(t, client.Flush(ino))
+	writeTestFile(t, client, ino, 300*1024, data[300*1024:], 100*1024)
+
+	s := client.GetStreamer(ino)
+	require.EqualValues(t, 1, atomic.LoadInt32(&s.dedupPending))
+	require.EqualValues(t, 300*1024, atomic.LoadInt64(&s.dedupPendingOffset))
+
+	// the range before the data buffered is read without draining it
+	buf := make([]byte, len(data))
+	n, err := s.read(buf, 1000, 100*1024)
+	require.NoError(t, err)
+	require.Equal(t, 100*1024, n)
+	require.Equal(t, data[1000:1000+100*1024], buf[:n])
+	require.EqualValues(t, 1, atomic.LoadInt32(&s.dedupPending))
+
+	// the data buffered is drained before it is read
+	n, err = s.read(buf, 0, len(data))
+	if err != nil {
+		require.Equal(t, io.EOF, err)
+	}
+	require.Equal(t, len(data), n)
+	require.Equal(t, data, buf)
+	require.EqualValues(t, 0, atomic.LoadInt32(&s.dedupPending))
+
+	// the tail appended later is read back through the extent client as well
+	tail := testFileData(5000, 0)
+	writeTestFile(t, client, ino, len(data), tail, len(tail))
+	require.EqualValues(t, 1, atomic.LoadInt32(&s.dedupPending))
+	require.Equal(t, tail, readTestFile(t, client, ino, len(data), len(tail)))
+	require.NoError(t, client.CloseStream(ino))
+}
+
+func TestStreamerDedupRoundTrip(t *testing.T) {
+	client, node := newTestDedupClient(t)
+	data := testFileData(3*1024*1024+4567, 0)
+
+	require.NoError(t, client.OpenStream(10))
+	writeTestFile(t, client, 10, 0, data, 128*1024)
+	require.NoError(t, client.Flush(10))
+	require.Equal(t, data, readTestFile(t, client, 10, 0, len(data)))
+	require.NoError(t, client.CloseStream(10))
+	written := node.requestCount(proto.OpWrite)
+	require.EqualValues(t, 0, atomic.LoadUint64(&client.dedupStat.deduped))
+
+	// the same data is cut into the same chunks, which refer to the data written before
+	require.NoError(t, client.OpenStream(11))
+	writeTestFile(t, client, 11, 0, data, 100*1024)
+	require.Equal(t, data[1000:300*1024], readTestFile(t, client, 11, 1000, 300*1024-1000))
+	require.NoError(t, client.CloseStream(11))
+	require.EqualValues(t, len(data), atomic.LoadUint64(&client.dedupStat.deduped))
+	require.Equal(t, written, node.requestCount(proto.OpWrite))
+
+	// the keys linked are read back after the streamer is evicted
+	require.NoError(t, client.EvictStream(11))
+	require.NoError(t, client.OpenStream(11))
+	require.Equal(t, data, readTestFile(t, client, 11, 0, len(data)))
+	require.NoError(t, client.CloseStream(11))
+}
@@ -0,0 +1,456 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/wrapper"
+	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/cryptoutil"
+	"github.com/cubefs/cubefs/util/ecstripe"
+	"github.com/cubefs/cubefs/util/errors"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// The data of the erasure coded volume is appended to the normal extents stripe by stripe. The cells of
+// a stripe are written to the hosts of the data shards and the parity of them to the other hosts of
+// the partition, every host keeps its own shard of the extent, which is never forwarded.
+
+// ecExtent is the extent the stripes of the streamer are appended to.
+type ecExtent struct {
+	dp       *wrapper.DataPartition
+	extentID uint64
+	size     int64            // logical size of the stripes written
+	key      *proto.ExtentKey // the key of the data written last
+}
+
+// writeEC buffers the data appended, the full stripes are encoded and written once the buffer is
+// large enough, the data overwritten is flushed first.
+func (s *Streamer) writeEC(layout ecstripe.Layout, data []byte, offset, size, flags int, checkFunc func() error) (total int, err error) {
+	filesize, _ := s.extents.Size()
+	if checkFunc != nil && offset+size > filesize {
+		if err = checkFunc(); err != nil {
+			return
+		}
+	}
+	if len(s.ecBuf) > 0 && offset != s.ecOffset+len(s.ecBuf) {
+		if err = s.flushEC(); err != nil {
+			return
+		}
+	}
+
+	log.LogDebugf("Streamer writeEC: ino(%v) offset(%v) size(%v) buffered(%v)", s.inode, offset, size, len(s.ecBuf))
+	if len(s.ecBuf) == 0 {
+		s.ecOffset = offset
+	}
+	s.ecBuf = append(s.ecBuf, data[:size]...)
+	atomic.StoreInt32(&s.ecPending, 1)
+	if offset+size > filesize {
+		s.extents.SetSize(uint64(offset+size), false)
+	}
+
+	unit := layout.DataNum * util.BlockSize
+	for len(s.ecBuf) >= unit {
+		if err = s.writeStripes(layout, s.ecBuf[:unit], s.ecOffset, unit); err != nil {
+			return
+		}
+		s.ecBuf = append(s.ecBuf[:0], s.ecBuf[unit:]...)
+		s.ecOffset += unit
+	}
+	if flags&proto.FlagsSyncWrite != 0 {
+		if err = s.flushEC(); err != nil {
+			return
+		}
+	}
+	return size, nil
+}
+
+// flushEC writes the data buffered, the last stripe is padded with zeros.
+func (s *Streamer) flushEC() (err error) {
+	if len(s.ecBuf) == 0 {
+		return
+	}
+	layout, ok := s.client.dataWrapper.ECLayout()
+	if !ok {
+		return fmt.Errorf("flushEC: ino(%v) volume is not erasure coded", s.inode)
+	}
+	size := len(s.ecBuf)
+	stripe := int(layout.StripeSize())
+	if padding := (stripe - size%stripe) % stripe; padding > 0 {
+		s.ecBuf = append(s.ecBuf, make([]byte, padding)...)
+	}
+	if err = s.writeStripes(layout, s.ecBuf, s.ecOffset, size); err != nil {
+		s.ecBuf = s.ecBuf[:size]
+		return
+	}
+	s.resetEC()
+	return
+}
+
+func (s *Streamer) resetEC() {
+	s.ecBuf = nil
+	s.ecOffset = 0
+	atomic.StoreInt32(&s.ecPending, 0)
+}
+
+// writeStripes encodes the stripes and writes the shards to the hosts of the partition, the size is the
+// length of the data of the file, the rest is padding. The stripes of the encrypted file are encrypted
+// with a new nonce every time.
+func (s *Streamer) writeStripes(layout ecstripe.Layout, data []byte, fileOffset, size int) (err error) {
+	var nonce uint64
+	if s.cipher != nil {
+		if nonce, err = cryptoutil.GenNonce(); err != nil {
+			return
+		}
+		plain := data
+		data = make([]byte, len(plain))
+		s.cipher.XORKeyStreamAt(data, plain, nonce, uint64(fileOffset))
+	}
+	encoder, err := layout.Encoder()
+	if err != nil {
+		return
+	}
+	shards, err := layout.Split(data)
+	if err != nil {
+		return
+	}
+	if err = encoder.Encode(shards); err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	s.client.writeLimiter.Wait(ctx)
+
+	shardSize := int64(len(shards[0]))
+	exclude := make(map[string]struct{})
+	for i := 0; i < MaxSelectDataPartitionForWrite; i++ {
+		ext := s.ecExtent
+		if ext != nil && layout.ShardOffset(ext.size)+shardSize > util.ExtentSize {
+			ext = nil
+		}
+		if ext == nil {
+			if ext, err = s.allocateECExtent(exclude); err != nil {
+				continue
+			}
+		}
+		if err = s.sendShards(ext, layout.ShardOffset(ext.size), shards, fileOffset); err != nil {
+			log.LogWarnf("Streamer writeStripes: exclude dp(%v) for write, ino(%v) extent(%v) err(%v) exclude(%v)",
+				ext.dp, s.inode, ext.extentID, err, exclude)
+			// the shards of the extent may be written partially, never append to it
+			s.ecExtent = nil
+			ext.dp.CheckAllHostsIsAvail(exclude)
+			continue
+		}
+		s.ecExtent = ext
+		break
+	}
+	if err != nil {
+		return
+	}
+	return s.appendECKey(s.ecExtent, int64(len(data)), fileOffset, size, nonce)
+}
+
+// appendECKey records the stripes written to the extent, the key written last is extended if the
+// data follows it in both the file and the extent, and is written with the same nonce.
+func (s *Streamer) appendECKey(ext *ecExtent, written int64, fileOffset, size int, nonce uint64) (err error) {
+	var ek *proto.ExtentKey
+	if key := ext.key; key != nil && key.FileOffset+uint64(key.Size) == uint64(fileOffset) &&
+		int64(key.ExtentOffset)+int64(key.Size) == ext.size && key.GetSeq() == s.verSeq && key.CipherNonce == nonce {
+		ek = &proto.ExtentKey{}
+		*ek = *key
+		ek.Size += uint32(size)
+	} else {
+		ek = &proto.ExtentKey{
+			FileOffset:   uint64(fileOffset),
+			PartitionId:  ext.dp.PartitionID,
+			ExtentId:     ext.extentID,
+			ExtentOffset: uint64(ext.size),
+			Size:         uint32(size),
+			SnapInfo: &proto.ExtSnapInfo{
+				VerSeq: s.verSeq,
+			},
+			CipherNonce: nonce,
+		}
+	}
+	ext.size += written
+
+	discards := s.extents.Append(ek, true)
+	if _, err = s.client.appendExtentKey(s.parentInode, s.inode, *ek, discards); err != nil {
+		log.LogErrorf("Streamer appendECKey: ino(%v) ek(%v) discards(%v) err(%v)", s.inode, ek, discards, err)
+		ext.key = nil
+		if e := s.GetExtentsForce(); e != nil {
+			log.LogErrorf("Streamer appendECKey: ino(%v) GetExtents err(%v)", s.inode, e)
+		}
+		return
+	}
+	s.extents.RemoveDiscard(discards)
+	ext.key = ek
+	log.LogDebugf("Streamer appendECKey: ino(%v) ek(%v) discards(%v)", s.inode, ek, discards)
+	return
+}
+
+func (s *Streamer) allocateECExtent(exclude map[string]struct{}) (ext *ecExtent, err error) {
+	var dp *wrapper.DataPartition
+	if dp, err = s.client.dataWrapper.GetDataPartitionForWrite(exclude); err != nil {
+		log.LogWarnf("Streamer allocateECExtent: failed to get write data partition, ino(%v) exclude(%v), clear exclude and try again!", s.inode, exclude)
+		for k := range exclude {
+			delete(exclude, k)
+		}
+		return
+	}
+	if !dp.IsErasureCoded() || len(dp.ECHosts) != len(dp.Hosts) {
+		s.client.dataWrapper.RemoveDataPartitionForWrite(dp.PartitionID)
+		return nil, fmt.Errorf("allocateECExtent: dp(%v) shard hosts(%v) unavailable", dp.PartitionID, dp.ECHosts)
+	}
+	for i, host := range dp.ECHosts {
+		if host == "" {
+			s.client.dataWrapper.RemoveDataPartitionForWrite(dp.PartitionID)
+			return nil, fmt.Errorf("allocateECExtent: dp(%v) shard(%v) lost", dp.PartitionID, i)
+		}
+	}
+	var extID uint64
+	if extID, err = s.createExtent(dp); err != nil {
+		log.LogWarnf("Streamer allocateECExtent: exclude dp(%v) for write caused by create extent failed, ino(%v) err(%v) exclude(%v)",
+			dp, s.inode, err, exclude)
+		s.client.dataWrapper.RemoveDataPartitionForWrite(dp.PartitionID)
+		dp.CheckAllHostsIsAvail(exclude)
+		return
+	}
+	return &ecExtent{dp: dp, extentID: extID}, nil
+}
+
+// createExtent creates the normal extent on all the hosts of the partition by the leader.
+func (s *Streamer) createExtent(dp *wrapper.DataPartition) (extID uint64, err error) {
+	conn, err := StreamConnPool.GetConnect(dp.Hosts[0])
+	if err != nil {
+		return 0, errors.Trace(err, "createExtent: failed to create connection, ino(%v) host(%v)", s.inode, dp.Hosts[0])
+	}
+	defer func() {
+		StreamConnPool.PutConnect(conn, err != nil)
+	}()
+
+	p := NewCreateExtentPacket(dp, s.inode)
+	if err = p.WriteToConn(conn); err != nil {
+		return 0, errors.Trace(err, "createExtent: failed to WriteToConn, packet(%v) host(%v)", p, dp.Hosts[0])
+	}
+	if err = p.ReadFromConnWithVer(conn, proto.ReadDeadlineTime*2); err != nil {
+		return 0, errors.Trace(err, "createExtent: failed to ReadFromConn, packet(%v) host(%v)", p, dp.Hosts[0])
+	}
+	if p.ResultCode != proto.OpOk {
+		return 0, errors.New(fmt.Sprintf("createExtent: ResultCode NOK, packet(%v) host(%v) ResultCode(%v)", p, dp.Hosts[0], p.GetResultMsg()))
+	}
+	if p.ExtentID == 0 {
+		return 0, errors.New(fmt.Sprintf("createExtent: illegal extID(%v) from (%v)", p.ExtentID, dp.Hosts[0]))
+	}
+	return p.ExtentID, nil
+}
+
+// sendShards writes the shards to the hosts keeping them in parallel.
+func (s *Streamer) sendShards(ext *ecExtent, shardOffset int64, shards [][]byte, fileOffset int) (err error) {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(shards))
+	)
+	for i := range shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.sendShard(ext, ext.dp.ECHosts[i], shardOffset, shards[i], fileOffset)
+		}(i)
+	}
+	wg.Wait()
+	for i, e := range errs {
+		if e != nil {
+			return errors.Trace(e, "sendShards: shard(%v) host(%v)", i, ext.dp.ECHosts[i])
+		}
+	}
+	return
+}
+
+func (s *Streamer) sendShard(ext *ecExtent, host string, shardOffset int64, shard []byte, fileOffset int) (err error) {
+	conn, err := StreamConnPool.GetConnect(host)
+	if err != nil {
+		return
+	}
+	defer func() {
+		StreamConnPool.PutConnect(conn, err != nil)
+	}()
+
+	for pos := 0; pos < len(shard); {
+		n := util.Min(len(shard)-pos, util.BlockSize)
+		p := newECShardPacket(ext, shardOffset+int64(pos), shard[pos:pos+n], s.inode, fileOffset)
+		if err = p.WriteToConn(conn); err != nil {
+			return
+		}
+		reply := NewReply(p.ReqID, p.PartitionID, p.ExtentID)
+		if err = reply.ReadFromConnWithVer(conn, proto.ReadDeadlineTime); err != nil {
+			return
+		}
+		if reply.ResultCode != proto.OpOk || !p.isValidWriteReply(reply) || p.CRC != reply.CRC {
+			return errors.New(fmt.Sprintf("sendShard: failed or invalid reply, ino(%v) req(%v) reply(%v)", s.inode, p, reply))
+		}
+		pos += n
+	}
+	return
+}
+
+// newECShardPacket returns the packet appending the data to the shard kept by the host it is sent to.
+func newECShardPacket(ext *ecExtent, shardOffset int64, data []byte, inode uint64, fileOffset int) *Packet {
+	p := new(Packet)
+	p.ReqID = proto.GenerateRequestID()
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpWrite
+	p.ExtentType = proto.NormalExtentType
+	p.PartitionID = ext.dp.PartitionID
+	p.ExtentID = ext.extentID
+	p.ExtentOffset = shardOffset
+	p.RemainingFollowers = 127
+	p.Data = data
+	p.Size = uint32(len(data))
+	p.CRC = crc32.ChecksumIEEE(data)
+	p.inode = inode
+	p.KernelOffset = uint64(fileOffset)
+	return p
+}
+
+// readEC reads the stripes the request covers from the hosts of the data shards, the shards
+// unavailable are reconstructed from the others.
+func (s *Streamer) readEC(dp *wrapper.DataPartition, req *ExtentRequest) (readBytes int, err error) {
+	layout := ecstripe.Layout{DataNum: int(dp.ECDataNum), ParityNum: int(dp.ECParityNum)}
+	ek := req.ExtentKey
+	offset := int64(ek.ExtentOffset) + int64(req.FileOffset) - int64(ek.FileOffset)
+	start, end := layout.StripeRange(offset, int64(req.Size))
+	shardOffset := layout.ShardOffset(start)
+	shardSize := int(layout.ShardOffset(end) - shardOffset)
+	encoder, err := layout.Encoder()
+	if err != nil {
+		return
+	}
+
+	shards := make([][]byte, layout.ShardNum())
+	present := make([]bool, len(shards))
+	read := func(idx []int) {
+		var wg sync.WaitGroup
+		for _, i := range idx {
+			shards[i] = make([]byte, shardSize)
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				e := s.readShard(dp, i, ek.ExtentId, shardOffset, shards[i], req.FileOffset)
+				if e != nil {
+					log.LogWarnf("Streamer readEC: ino(%v) req(%v) dp(%v) shard(%v) err(%v)", s.inode, req, dp.PartitionID, i, e)
+					return
+				}
+				present[i] = true
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	wanted := layout.DataShardsOf(offset, int64(req.Size))
+	read(wanted)
+	degraded := false
+	for _, i := range wanted {
+		if !present[i] {
+			degraded = true
+		}
+	}
+	if degraded {
+		var badIdx []int
+		// read the other shards until there are enough ones to reconstruct the data
+		for {
+			var (
+				count  int
+				others []int
+			)
+			for i := range shards {
+				if present[i] {
+					count++
+				} else if shards[i] == nil {
+					others = append(others, i)
+				}
+			}
+			if count >= layout.DataNum || len(others) == 0 {
+				break
+			}
+			read(others[:util.Min(len(others), layout.DataNum-count)])
+		}
+		for i := range shards {
+			if !present[i] {
+				shards[i] = shards[i][:0]
+				badIdx = append(badIdx, i)
+			}
+		}
+		if len(badIdx) > layout.ParityNum {
+			return 0, fmt.Errorf("readEC: ino(%v) dp(%v) shards(%v) lost", s.inode, dp.PartitionID, badIdx)
+		}
+		if err = encoder.ReconstructData(shards, badIdx); err != nil {
+			return
+		}
+		log.LogWarnf("Streamer readEC: ino(%v) req(%v) dp(%v) reconstructed shards(%v)", s.inode, req, dp.PartitionID, badIdx)
+	}
+	layout.Join(req.Data[:req.Size], shards[:layout.DataNum], start, offset)
+	return req.Size, nil
+}
+
+// readShard reads the shard kept by the host from the shard offset.
+func (s *Streamer) readShard(dp *wrapper.DataPartition, idx int, extentID uint64, shardOffset int64, buf []byte, fileOffset int) (err error) {
+	host := dp.ECHosts[idx]
+	if host == "" {
+		return fmt.Errorf("readShard: shard lost")
+	}
+	conn, err := StreamConnPool.GetConnect(host)
+	if err != nil {
+		return
+	}
+	defer func() {
+		StreamConnPool.PutConnect(conn, err != nil)
+	}()
+
+	key := &proto.ExtentKey{PartitionId: dp.PartitionID, ExtentId: extentID}
+	p := NewReadPacket(key, int(shardOffset), len(buf), s.inode, fileOffset, true)
+	if err = p.WriteToConn(conn); err != nil {
+		return
+	}
+	for read := 0; read < len(buf); {
+		reply := NewReply(p.ReqID, dp.PartitionID, extentID)
+		reply.Data = buf[read:util.Min(len(buf), read+util.ReadBlockSize)]
+		if err = reply.readFromConn(conn, proto.ReadDeadlineTime); err != nil {
+			return
+		}
+		if reply.ResultCode != proto.OpOk || !p.isValidReadReply(reply) || reply.Size == 0 || int(reply.Size) > len(reply.Data) ||
+			crc32.ChecksumIEEE(reply.Data[:reply.Size]) != reply.CRC {
+			return errors.New(fmt.Sprintf("readShard: host(%v) failed or invalid reply(%v)", host, reply))
+		}
+		read += int(reply.Size)
+	}
+	return
+}
+
+// ecPendingFlush flushes the data buffered before it is read.
+func (s *Streamer) ecPendingFlush() (err error) {
+	if atomic.LoadInt32(&s.ecPending) == 0 {
+		return
+	}
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return s.IssueFlushRequest()
+}
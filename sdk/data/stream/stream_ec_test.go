@@ -0,0 +1,149 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/ecstripe"
+	"github.com/stretchr/testify/require"
+)
+
+var testECLayout = ecstripe.Layout{DataNum: 4, ParityNum: 2}
+
+// newTestECClient returns the client of an erasure coded volume, every shard of the partition is kept
+// by a data node of its own.
+func newTestECClient(t *testing.T) (*ExtentClient, []*testDataNode, *testMeta) {
+	nodes := make([]*testDataNode, testECLayout.ShardNum())
+	for i := range nodes {
+		nodes[i] = newTestDataNode(t)
+	}
+	mw := newTestMeta()
+	view := &proto.SimpleVolView{
+		Name:        "vol",
+		ECDataNum:   uint8(testECLayout.DataNum),
+		ECParityNum: uint8(testECLayout.ParityNum),
+	}
+	return newTestExtentClient(t, view, nodes, mw), nodes, mw
+}
+
+func TestStreamerECRoundTrip(t *testing.T) {
+	client, nodes, mw := newTestECClient(t)
+	const ino = 10
+	// two full stripes and a partial one
+	unit := testECLayout.DataNum * util.BlockSize
+	data := testFileData(2*unit+12345, 0)
+
+	require.NoError(t, client.OpenStream(ino))
+	writeTestFile(t, client, ino, 0, data, 100*1024)
+	s := client.GetStreamer(ino)
+	require.EqualValues(t, 1, atomic.LoadInt32(&s.ecPending))
+	require.Len(t, s.ecBuf, 12345)
+	require.NoError(t, client.Flush(ino))
+	require.EqualValues(t, 0, atomic.LoadInt32(&s.ecPending))
+	require.Equal(t, data, readTestFile(t, client, ino, 0, len(data)))
+
+	// the partial stripe is padded, every host keeps a shard of the same size
+	_, _, eks, err := mw.getExtents(ino)
+	require.NoError(t, err)
+	require.Len(t, eks, 1)
+	require.EqualValues(t, len(data), eks[0].Size)
+	_, end := testECLayout.StripeRange(0, int64(len(data)))
+	shardSize := int(testECLayout.ShardOffset(end))
+	for _, node := range nodes {
+		node.Lock()
+		require.Len(t, node.extents[eks[0].ExtentId], shardSize)
+		node.Unlock()
+	}
+
+	// the range across the stripes is read back after the streamer is evicted
+	require.NoError(t, client.CloseStream(ino))
+	require.NoError(t, client.EvictStream(ino))
+	require.NoError(t, client.OpenStream(ino))
+	require.Equal(t, data[unit-100:2*unit+100], readTestFile(t, client, ino, unit-100, unit+200))
+	require.NoError(t, client.CloseStream(ino))
+}
+
+func TestStreamerECReadBeforeFlush(t *testing.T) {
+	client, _, mw := newTestECClient(t)
+	const ino = 10
+	data := testFileData(3*util.BlockSize+100, 0)
+	_, padded := testECLayout.StripeRange(0, int64(len(data)))
+
+	require.NoError(t, client.OpenStream(ino))
+	writeTestFile(t, client, ino, 0, data, 64*1024)
+	s := client.GetStreamer(ino)
+	require.EqualValues(t, 1, atomic.LoadInt32(&s.ecPending))
+	_, _, eks, err := mw.getExtents(ino)
+	require.NoError(t, err)
+	require.Empty(t, eks)
+
+	// the partial stripe buffered is written before it is read
+	buf := make([]byte, 1000)
+	n, err := s.read(buf, 2*util.BlockSize, len(buf))
+	require.NoError(t, err)
+	require.Equal(t, data[2*util.BlockSize:2*util.BlockSize+n], buf[:n])
+	require.EqualValues(t, 0, atomic.LoadInt32(&s.ecPending))
+	_, _, eks, err = mw.getExtents(ino)
+	require.NoError(t, err)
+	require.Len(t, eks, 1)
+
+	// the data appended later follows the stripe padded in the extent
+	tail := testFileData(5000, 0)
+	writeTestFile(t, client, ino, len(data), tail, len(tail))
+	require.EqualValues(t, 1, atomic.LoadInt32(&s.ecPending))
+	data = append(data, tail...)
+	require.Equal(t, data, readTestFile(t, client, ino, 0, len(data)))
+	_, _, eks, err = mw.getExtents(ino)
+	require.NoError(t, err)
+	require.Len(t, eks, 2)
+	require.Equal(t, eks[0].ExtentId, eks[1].ExtentId)
+	require.EqualValues(t, padded, eks[1].ExtentOffset)
+	require.NoError(t, client.CloseStream(ino))
+}
+
+func TestStreamerECDegradedRead(t *testing.T) {
+	client, nodes, mw := newTestECClient(t)
+	const ino = 10
+	data := testFileData(testECLayout.DataNum*util.BlockSize+4321, 0)
+
+	require.NoError(t, client.OpenStream(ino))
+	writeTestFile(t, client, ino, 0, data, len(data))
+	require.NoError(t, client.Flush(ino))
+	_, _, eks, err := mw.getExtents(ino)
+	require.NoError(t, err)
+	require.Len(t, eks, 1)
+
+	// the shards of a data host and a parity host are lost
+	for _, node := range []*testDataNode{nodes[1], nodes[testECLayout.DataNum]} {
+		node.Lock()
+		delete(node.extents, eks[0].ExtentId)
+		node.Unlock()
+	}
+	require.Equal(t, data, readTestFile(t, client, ino, 0, len(data)))
+	require.Equal(t, data[util.BlockSize+10:2*util.BlockSize], readTestFile(t, client, ino, util.BlockSize+10, util.BlockSize-10))
+
+	// the data is lost once more shards than the parity are lost
+	nodes[2].Lock()
+	delete(nodes[2].extents, eks[0].ExtentId)
+	nodes[2].Unlock()
+	buf := make([]byte, util.BlockSize)
+	_, err = client.Read(ino, buf, util.BlockSize, len(buf))
+	require.Error(t, err)
+	require.NoError(t, client.CloseStream(ino))
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/wrapper"
+	"github.com/cubefs/cubefs/storage"
+	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/compressutil"
+	"github.com/cubefs/cubefs/util/errors"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// Preallocate reserves the holes of the range with the extents filled with zeros by the data nodes,
+// and returns the keys of them to be inserted by the meta node. The keys reserved before an error are
+// returned as well, so that the space allocated is kept as fallocate(2) does. The caller flushes the
+// data written before, or the holes might be written by the data buffered.
+func (client *ExtentClient) Preallocate(inode uint64, offset, size int) (eks []proto.ExtentKey, err error) {
+	s := client.GetStreamer(inode)
+	if s == nil {
+		log.LogErrorf("Preallocate: stream is not opened yet, ino(%v)", inode)
+		return nil, syscall.EBADF
+	}
+	s.once.Do(func() {
+		s.GetExtents()
+	})
+	if err = s.loadCipher(); err != nil {
+		log.LogErrorf("Preallocate: load file cipher failed, ino(%v) err(%v)", inode, err)
+		return nil, syscall.EIO
+	}
+	if _, ok := client.dataWrapper.ECLayout(); ok || s.cipher != nil ||
+		client.dataWrapper.Compression() != compressutil.None || s.extents.HasCompressed(offset, offset+size) {
+		// the zeros reserved are not the stripes, the ciphertext or the frames to be read
+		return nil, syscall.EOPNOTSUPP
+	}
+	for _, oek := range s.extents.ObjExtents() {
+		if oek.FileOffset < uint64(offset+size) && uint64(offset) < oek.FileOffset+oek.Size {
+			// the data in the blobstore is read through the holes of the extents
+			return nil, syscall.EOPNOTSUPP
+		}
+	}
+
+	for _, hole := range s.extents.Holes(offset, offset+size) {
+		for pos := hole[0]; pos < hole[1]; {
+			n := util.Min(hole[1]-pos, util.ExtentSize)
+			var ek *proto.ExtentKey
+			if ek, err = s.preallocExtent(pos, n); err != nil {
+				log.LogWarnf("Preallocate: ino(%v) range[%v, +%v) reserved(%v) err(%v)", inode, pos, n, eks, err)
+				return
+			}
+			eks = append(eks, *ek)
+			pos += n
+		}
+	}
+	log.LogDebugf("Preallocate: ino(%v) range[%v, +%v) reserved(%v)", inode, offset, size, eks)
+	return
+}
+
+// preallocExtent creates an extent of the size on a data partition for write, ENOSPC is returned if
+// none of the data partitions has the space.
+func (s *Streamer) preallocExtent(fileOffset, size int) (ek *proto.ExtentKey, err error) {
+	var (
+		dp      *wrapper.DataPartition
+		extID   uint64
+		noSpace = true
+		exclude = make(map[string]struct{})
+	)
+	for i := 0; i < MaxSelectDataPartitionForWrite; i++ {
+		if dp, err = s.client.dataWrapper.GetDataPartitionForWrite(exclude); err != nil {
+			break
+		}
+		if extID, err = s.sendPreallocExtent(dp, size); err == nil {
+			return &proto.ExtentKey{
+				FileOffset:  uint64(fileOffset),
+				PartitionId: dp.PartitionID,
+				ExtentId:    extID,
+				Size:        uint32(size),
+			}, nil
+		}
+		log.LogWarnf("Streamer preallocExtent: exclude dp(%v) for write, ino(%v) size(%v) err(%v)", dp, s.inode, size, err)
+		if !strings.Contains(err.Error(), storage.NoSpaceError.Error()) {
+			noSpace = false
+		}
+		s.client.dataWrapper.RemoveDataPartitionForWrite(dp.PartitionID)
+		dp.CheckAllHostsIsAvail(exclude)
+	}
+	if noSpace {
+		return nil, syscall.ENOSPC
+	}
+	return nil, syscall.EIO
+}
+
+func (s *Streamer) sendPreallocExtent(dp *wrapper.DataPartition, size int) (extID uint64, err error) {
+	conn, err := StreamConnPool.GetConnect(dp.Hosts[0])
+	if err != nil {
+		return 0, errors.Trace(err, "sendPreallocExtent: failed to create connection, ino(%v) host(%v)", s.inode, dp.Hosts[0])
+	}
+	defer func() {
+		StreamConnPool.PutConnect(conn, err != nil)
+	}()
+
+	p := NewPreallocExtentPacket(dp, s.inode, size)
+	if err = p.WriteToConn(conn); err != nil {
+		return 0, errors.Trace(err, "sendPreallocExtent: failed to WriteToConn, packet(%v) host(%v)", p, dp.Hosts[0])
+	}
+	if err = p.ReadFromConnWithVer(conn, proto.ReadDeadlineTime*2); err != nil {
+		return 0, errors.Trace(err, "sendPreallocExtent: failed to ReadFromConn, packet(%v) host(%v)", p, dp.Hosts[0])
+	}
+	if p.ResultCode != proto.OpOk {
+		return 0, errors.New(fmt.Sprintf("sendPreallocExtent: ResultCode NOK, packet(%v) host(%v) ResultCode(%v)", p, dp.Hosts[0], p.GetResultMsg()))
+	}
+	if p.ExtentID == 0 {
+		return 0, errors.New(fmt.Sprintf("sendPreallocExtent: illegal extID(%v) from (%v)", p.ExtentID, dp.Hosts[0]))
+	}
+	return p.ExtentID, nil
+}
@@ -24,8 +24,10 @@ import (
 
 	"github.com/cubefs/cubefs/blockcache/bcache"
 	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/sdk/data/wrapper"
 	"github.com/cubefs/cubefs/util"
 	"github.com/cubefs/cubefs/util/buf"
+	"github.com/cubefs/cubefs/util/cryptoutil"
 	"github.com/cubefs/cubefs/util/exporter"
 	"github.com/cubefs/cubefs/util/log"
 )
@@ -54,6 +56,26 @@ type Streamer struct {
 	pendingCache         chan bcacheKey
 	verSeq               uint64
 	needUpdateVer        int32
+	cipher               *cryptoutil.FileCipher // nil if the file is not encrypted
+	cipherLoaded         bool
+	cipherLock           sync.Mutex
+	dedupBuf             []byte // data appended not deduplicated yet
+	dedupOffset          int    // file offset of the data buffered
+	dedupPending         int32  // whether there is data buffered, read without the write lock
+	dedupPendingOffset   int64  // file offset of the data buffered, read without the write lock
+
+	ecBuf     []byte    // data appended not encoded yet
+	ecOffset  int       // file offset of the data not encoded
+	ecPending int32     // whether there is data buffered, read without the write lock
+	ecExtent  *ecExtent // the extent the stripes are appended to
+
+	compressBuf           []byte          // data of the chunks buffered
+	compressOffset        int             // file offset of the first chunk buffered
+	compressDirty         bool            // whether the data buffered is not written yet
+	compressPending       int32           // whether there is data not written, read without the write lock
+	compressPendingOffset int64           // file offset of the data not written, read without the write lock
+	compressPendingEnd    int64           // end of the data not written, read without the write lock
+	compressExtent        *compressExtent // the extent the frames are appended to
 }
 
 type bcacheKey struct {
@@ -92,14 +114,14 @@ func (s *Streamer) String() string {
 // TODO should we call it RefreshExtents instead?
 func (s *Streamer) GetExtents() error {
 	if s.client.disableMetaCache || !s.needBCache {
-		return s.extents.RefreshForce(s.inode, s.client.getExtents)
+		return s.extents.RefreshForce(s.inode, s.extentsGetter())
 	}
 
-	return s.extents.Refresh(s.inode, s.client.getExtents)
+	return s.extents.Refresh(s.inode, s.extentsGetter())
 }
 
 func (s *Streamer) GetExtentsForce() error {
-	return s.extents.RefreshForce(s.inode, s.client.getExtents)
+	return s.extents.RefreshForce(s.inode, s.extentsGetter())
 }
 
 // GetExtentReader returns the extent reader.
@@ -135,6 +157,15 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 	ctx := context.Background()
 	s.client.readLimiter.Wait(ctx)
 	s.client.LimitManager.ReadAlloc(ctx, size)
+	if err = s.ecPendingFlush(); err != nil {
+		return 0, err
+	}
+	if err = s.dedupPendingFlush(offset, size); err != nil {
+		return 0, err
+	}
+	if err = s.compressPendingFlush(offset, size); err != nil {
+		return 0, err
+	}
 	requests = s.extents.PrepareReadRequests(offset, size, data)
 	for _, req := range requests {
 		if req.ExtentKey == nil {
@@ -169,15 +200,39 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 					return
 				}
 				req.Size = filesize - req.FileOffset
+				if err = s.readObjExtents(req); err != nil {
+					return
+				}
 				total += req.Size
 				err = io.EOF
 				return
 			}
 
-			// Reading a hole, just fill zero
+			// Reading a hole, fill the data transitioned to the blobstore or zero
+			if err = s.readObjExtents(req); err != nil {
+				break
+			}
 			total += req.Size
 			log.LogDebugf("Stream read hole: ino(%v) req(%v) total(%v)", s.inode, req, total)
 		} else {
+			if _, ok := s.client.dataWrapper.ECLayout(); ok {
+				var dp *wrapper.DataPartition
+				if dp, err = s.client.dataWrapper.GetDataPartition(req.ExtentKey.PartitionId); err != nil {
+					log.LogErrorf("action[streamer.read] req %v err %v", req, err)
+					break
+				}
+				if dp.IsErasureCoded() {
+					readBytes, err = s.readEC(dp, req)
+					log.LogDebugf("TRACE Stream read ec: ino(%v) req(%v) readBytes(%v) err(%v)", s.inode, req, readBytes, err)
+					s.decrypt(req.Data[:readBytes], req.ExtentKey.CipherNonce, req.FileOffset)
+					total += readBytes
+					if err != nil {
+						log.LogErrorf("Stream read ec: ino(%v) req(%v) err(%v)", s.inode, req, err)
+						break
+					}
+					continue
+				}
+			}
 			log.LogDebugf("Stream read: ino(%v) req(%v) s.needBCache(%v) s.client.bcacheEnable(%v)", s.inode, req, s.needBCache, s.client.bcacheEnable)
 			if s.needBCache {
 				bcacheMetric := exporter.NewCounter("fileReadL1Cache")
@@ -192,6 +247,7 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 				if s.client.loadBcache != nil {
 					readBytes, err = s.client.loadBcache(cacheKey, req.Data, uint64(offset), uint32(req.Size))
 					if err == nil && readBytes == req.Size {
+						s.decrypt(req.Data[:readBytes], req.ExtentKey.CipherNonce, req.FileOffset)
 						total += req.Size
 						bcacheMetric := exporter.NewCounter("fileReadL1CacheHit")
 						bcacheMetric.AddWithLabels(1, map[string]string{exporter.Vol: s.client.volumeName})
@@ -231,6 +287,7 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 
 			readBytes, err = reader.Read(req)
 			log.LogDebugf("TRACE Stream read: ino(%v) req(%v) readBytes(%v) err(%v)", s.inode, req, readBytes, err)
+			s.decrypt(req.Data[:readBytes], req.ExtentKey.CipherNonce, req.FileOffset)
 
 			total += readBytes
 
@@ -246,6 +303,31 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 	return
 }
 
+// loadCipher loads the cipher of the encrypted file once, the data of the file is kept
+// encrypted on the data nodes and in the block cache.
+func (s *Streamer) loadCipher() (err error) {
+	if s.client.loadFileCipher == nil {
+		return
+	}
+	s.cipherLock.Lock()
+	defer s.cipherLock.Unlock()
+	if s.cipherLoaded {
+		return
+	}
+	if s.cipher, err = s.client.loadFileCipher(s.inode); err != nil {
+		return
+	}
+	s.cipherLoaded = true
+	return
+}
+
+// decrypt decrypts the data written with the nonce, the data without the nonce is not encrypted.
+func (s *Streamer) decrypt(data []byte, nonce uint64, offset int) {
+	if s.cipher != nil && nonce != 0 {
+		s.cipher.XORKeyStreamAt(data, data, nonce, uint64(offset))
+	}
+}
+
 func (s *Streamer) asyncBlockCache() {
 	if !s.needBCache || !s.isOpen {
 		return
@@ -0,0 +1,72 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"io"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// extentsGetter returns the function to get the extents of the inode, the object extents of the data
+// transitioned to the blobstore are loaded along with the extents if the client reads them.
+func (s *Streamer) extentsGetter() GetExtentsFunc {
+	if s.client.getObjExtents == nil {
+		return s.client.getExtents
+	}
+	return func(inode uint64) (gen uint64, size uint64, eks []proto.ExtentKey, err error) {
+		var oeks []proto.ObjExtentKey
+		if gen, size, eks, oeks, err = s.client.getObjExtents(inode); err != nil {
+			return
+		}
+		s.extents.setObjExtents(oeks)
+		return
+	}
+}
+
+// readObjExtents fills the hole of the extents with the data transitioned to the blobstore. The data of the
+// encrypted file is transitioned as it is kept on the data nodes, and the obj extent key keeps the nonce of it.
+func (s *Streamer) readObjExtents(req *ExtentRequest) (err error) {
+	if s.client.readObjExtent == nil {
+		return
+	}
+	start, end := uint64(req.FileOffset), uint64(req.FileOffset+req.Size)
+	for _, oek := range s.extents.ObjExtents() {
+		from, to := oek.FileOffset, oek.FileOffset+oek.Size
+		if to <= start || from >= end {
+			continue
+		}
+		if from < start {
+			from = start
+		}
+		if to > end {
+			to = end
+		}
+		buf := req.Data[from-start : to-start]
+		var read int
+		read, err = s.client.readObjExtent(context.Background(), s.client.volumeName, buf, from-oek.FileOffset, to-from, oek)
+		if err == nil && read < len(buf) {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			log.LogErrorf("Streamer readObjExtents: ino(%v) req(%v) oek(%v) err(%v)", s.inode, req, oek, err)
+			return
+		}
+		s.decrypt(buf, oek.CipherNonce, int(from))
+	}
+	return
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/cubefs/cubefs/sdk/data/wrapper"
 	"github.com/cubefs/cubefs/storage"
 	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/cryptoutil"
 	"github.com/cubefs/cubefs/util/errors"
 	"github.com/cubefs/cubefs/util/log"
 )
@@ -203,6 +204,21 @@ func (s *Streamer) server() {
 			log.LogDebugf("done server: evict, ino(%v)", s.inode)
 			return
 		case <-t.C:
+			if s.traversed > 0 && len(s.dedupBuf) > 0 {
+				if err := s.drainDedup(true); err != nil {
+					log.LogWarnf("Streamer server: drain dedup ino(%v) err(%v)", s.inode, err)
+				}
+			}
+			if s.traversed > 0 && len(s.ecBuf) > 0 {
+				if err := s.flushEC(); err != nil {
+					log.LogWarnf("Streamer server: flush ec ino(%v) err(%v)", s.inode, err)
+				}
+			}
+			if s.traversed > 0 && s.compressDirty {
+				if err := s.flushCompressed(); err != nil {
+					log.LogWarnf("Streamer server: flush compressed ino(%v) err(%v)", s.inode, err)
+				}
+			}
 			s.traverse()
 			if s.refcnt <= 0 {
 
@@ -279,25 +295,55 @@ func (s *Streamer) handleRequest(request interface{}) {
 		request.writeBytes, request.err = s.write(request.data, request.fileOffset, request.size, request.flags, request.checkFunc)
 		request.done <- struct{}{}
 	case *TruncRequest:
-		request.err = s.truncate(request.size, request.fullPath)
+		if request.err = s.drainDedup(true); request.err == nil {
+			request.err = s.truncate(request.size, request.fullPath)
+		}
 		request.done <- struct{}{}
 	case *FlushRequest:
-		request.err = s.flush()
+		if request.err = s.drainDedup(true); request.err == nil {
+			request.err = s.flush()
+		}
 		request.done <- struct{}{}
 	case *ReleaseRequest:
-		request.err = s.release()
+		err := s.drainDedup(true)
+		if request.err = s.release(); request.err == nil {
+			request.err = err
+		}
 		request.done <- struct{}{}
 	case *EvictRequest:
 		request.err = s.evict()
 		request.done <- struct{}{}
 	case *VerUpdateRequest:
-		request.err = s.updateVer(request.verSeq)
+		err := s.drainDedup(true)
+		if request.err = s.updateVer(request.verSeq); request.err == nil {
+			request.err = err
+		}
 		request.done <- struct{}{}
 	default:
 	}
 }
 
 func (s *Streamer) write(data []byte, offset, size, flags int, checkFunc func() error) (total int, err error) {
+	if flags&proto.FlagsAppend != 0 {
+		filesize, _ := s.extents.Size()
+		offset = filesize
+	}
+	if layout, ok := s.client.dataWrapper.ECLayout(); ok {
+		return s.writeEC(layout, data, offset, size, flags, checkFunc)
+	}
+	if s.dedupable(offset, flags) {
+		return s.writeDedup(data, offset, size, checkFunc)
+	}
+	if err = s.drainDedup(true); err != nil {
+		return
+	}
+	if s.compressible(offset, size) {
+		return s.writeCompressed(data, offset, size, flags, checkFunc)
+	}
+	return s.doWrite(data, offset, size, flags, checkFunc)
+}
+
+func (s *Streamer) doWrite(data []byte, offset, size, flags int, checkFunc func() error) (total int, err error) {
 	var (
 		direct     bool
 		retryTimes int8
@@ -352,7 +398,11 @@ begin:
 			}
 			log.LogDebugf("action[streamer.write] inode [%v] latest seq [%v] extentkey seq [%v]  info [%v] before compare seq",
 				s.inode, s.verSeq, req.ExtentKey.GetSeq(), req.ExtentKey)
-			if req.ExtentKey.GetSeq() == s.verSeq {
+			// the shared extents cloned by copy_file_range are copied on write, and so is the data of
+			// the encrypted files, which is written with a new nonce
+			if s.cipher != nil {
+				writeSize, err = s.doEncryptedOverwrite(req, direct)
+			} else if req.ExtentKey.GetSeq() == s.verSeq && !req.ExtentKey.IsShared() {
 				writeSize, err = s.doOverwrite(req, direct)
 				if err == proto.ErrCodeVersionOp {
 					log.LogDebugf("action[streamer.write] write need version update")
@@ -372,8 +422,8 @@ begin:
 				}
 				log.LogDebugf("action[streamer.write] err %v retryTimes %v", err, retryTimes)
 			} else {
-				log.LogDebugf("action[streamer.write] ino %v do OverWriteByAppend extent key (%v) because seq not equal", s.inode, req.ExtentKey)
-				writeSize, _, err, _ = s.doOverWriteByAppend(req, direct)
+				log.LogDebugf("action[streamer.write] ino %v do OverWriteByAppend extent key (%v) because seq not equal or shared", s.inode, req.ExtentKey)
+				writeSize, _, err, _ = s.doOverWriteByAppend(req, direct, 0)
 			}
 			if s.client.bcacheEnable {
 				cacheKey := util.GenerateKey(s.client.volumeName, s.inode, uint64(req.FileOffset))
@@ -395,7 +445,10 @@ begin:
 			// Next, try writing and directly checking the extent at the datanode. If the extent cannot be reused, create a new extent for writing.
 			if writeSize, err, status = s.doAppendWrite(req.Data, req.FileOffset, req.Size, direct, true); status == LastEKVersionNotEqual {
 				log.LogDebugf("action[streamer.write] tryDirectAppendWrite req %v FileOffset %v size %v", req.ExtentKey, req.FileOffset, req.Size)
-				if writeSize, _, err, status = s.tryDirectAppendWrite(req, direct); status == int32(proto.OpTryOtherExtent) {
+				if s.cipher != nil {
+					// the key of the encrypted file is never extended by the data of another nonce
+					writeSize, err, _ = s.doAppendWrite(req.Data, req.FileOffset, req.Size, direct, false)
+				} else if writeSize, _, err, status = s.tryDirectAppendWrite(req, direct); status == int32(proto.OpTryOtherExtent) {
 					log.LogDebugf("action[streamer.write] doAppendWrite again req %v FileOffset %v size %v", req.ExtentKey, req.FileOffset, req.Size)
 					writeSize, err, _ = s.doAppendWrite(req.Data, req.FileOffset, req.Size, direct, false)
 				}
@@ -416,20 +469,44 @@ begin:
 	return
 }
 
-func (s *Streamer) doOverWriteByAppend(req *ExtentRequest, direct bool) (total int, extKey *proto.ExtentKey, err error, status int32) {
+// doEncryptedOverwrite writes the data of the encrypted file over the range by appending it to the extent
+// with a new nonce block by block, the data is never overwritten in place so that no keystream is reused.
+func (s *Streamer) doEncryptedOverwrite(req *ExtentRequest, direct bool) (total int, err error) {
+	for total < req.Size {
+		var (
+			nonce   uint64
+			written int
+		)
+		if nonce, err = cryptoutil.GenNonce(); err != nil {
+			return
+		}
+		size := util.Min(req.Size-total, util.BlockSize)
+		part := &ExtentRequest{FileOffset: req.FileOffset + total, Size: size, Data: make([]byte, size)}
+		s.cipher.XORKeyStreamAt(part.Data, req.Data[total:total+size], nonce, uint64(part.FileOffset))
+		if written, _, err, _ = s.doOverWriteByAppend(part, direct, nonce); err != nil {
+			return
+		}
+		total += written
+	}
+	return
+}
+
+func (s *Streamer) doOverWriteByAppend(req *ExtentRequest, direct bool, nonce uint64) (total int, extKey *proto.ExtentKey, err error, status int32) {
 	// the extent key needs to be updated because when preparing the requests,
 	// the obtained extent key could be a local key which can be inconsistent with the remote key.
 	// the OpTryWriteAppend is a special case, ignore it
 	req.ExtentKey = s.extents.Get(uint64(req.FileOffset))
-	return s.doDirectWriteByAppend(req, direct, proto.OpRandomWriteAppend)
+	return s.doDirectWriteByAppend(req, direct, proto.OpRandomWriteAppend, nonce)
 }
 
 func (s *Streamer) tryDirectAppendWrite(req *ExtentRequest, direct bool) (total int, extKey *proto.ExtentKey, err error, status int32) {
 	req.ExtentKey = s.handler.key
-	return s.doDirectWriteByAppend(req, direct, proto.OpTryWriteAppend)
+	return s.doDirectWriteByAppend(req, direct, proto.OpTryWriteAppend, 0)
 }
 
-func (s *Streamer) doDirectWriteByAppend(req *ExtentRequest, direct bool, op uint8) (total int, extKey *proto.ExtentKey, err error, status int32) {
+// doDirectWriteByAppend appends the data of the request to the extent of the key, the nonce is the one the
+// data of the encrypted file is written with.
+func (s *Streamer) doDirectWriteByAppend(req *ExtentRequest, direct bool, op uint8, nonce uint64) (total int, extKey *proto.ExtentKey, err error, status int32) {
 	var (
 		dp        *wrapper.DataPartition
 		reqPacket *Packet
@@ -561,6 +638,7 @@ func (s *Streamer) doDirectWriteByAppend(req *ExtentRequest, direct bool, op uin
 		SnapInfo: &proto.ExtSnapInfo{
 			VerSeq: s.verSeq,
 		},
+		CipherNonce: nonce,
 	}
 	if op == proto.OpRandomWriteAppend || op == proto.OpSyncRandomWriteAppend {
 		log.LogDebugf("action[doDirectWriteByAppend] inode %v local cache process start extKey %v", s.inode, extKey)
@@ -706,6 +784,11 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 func (s *Streamer) tryInitExtentHandlerByLastEk(offset, size int) (isLastEkVerNotEqual bool) {
 	storeMode := s.GetStoreMod(offset, size)
 	getEndEkFunc := func() *proto.ExtentKey {
+		// the key of the encrypted file is never extended by another handler, which might write the
+		// data truncated again with the same nonce
+		if s.cipher != nil {
+			return nil
+		}
 		if ek := s.extents.GetEndForAppendWrite(uint64(offset), s.verSeq, false); ek != nil && !storage.IsTinyExtent(ek.ExtentId) {
 			return ek
 		}
@@ -818,7 +901,11 @@ func (s *Streamer) doAppendWrite(data []byte, offset, size int, direct bool, reU
 				s.closeOpenHandler()
 				continue
 			}
-			ek, err = s.handler.write(data, offset, size, direct)
+			var buf []byte
+			if buf, err = s.encryptAppend(s.handler, data, offset, size); err != nil {
+				break
+			}
+			ek, err = s.handler.write(buf, offset, size, direct)
 			if err == nil && ek != nil {
 				ek.SetSeq(s.verSeq)
 				if !s.dirty {
@@ -832,7 +919,10 @@ func (s *Streamer) doAppendWrite(data []byte, offset, size int, direct bool, reU
 	} else {
 		s.handler = NewExtentHandler(s, offset, storeMode, 0)
 		s.dirty = false
-		ek, err = s.handler.write(data, offset, size, direct)
+		var buf []byte
+		if buf, err = s.encryptAppend(s.handler, data, offset, size); err == nil {
+			ek, err = s.handler.write(buf, offset, size, direct)
+		}
 		if err == nil && ek != nil {
 			if !s.dirty {
 				s.dirtylist.Put(s.handler)
@@ -855,7 +945,31 @@ func (s *Streamer) doAppendWrite(data []byte, offset, size int, direct bool, reU
 	return
 }
 
+// encryptAppend returns the copy of the data appended by the handler encrypted with the nonce of the handler,
+// which is taken once the handler writes the data of the encrypted file and kept in the key of it.
+func (s *Streamer) encryptAppend(eh *ExtentHandler, data []byte, offset, size int) (buf []byte, err error) {
+	if s.cipher == nil {
+		return data, nil
+	}
+	if eh.cipherNonce == 0 {
+		if eh.cipherNonce, err = cryptoutil.GenNonce(); err != nil {
+			return
+		}
+	}
+	buf = make([]byte, size)
+	s.cipher.XORKeyStreamAt(buf, data[:size], eh.cipherNonce, uint64(offset))
+	return
+}
+
 func (s *Streamer) flush() (err error) {
+	if err = s.flushEC(); err != nil {
+		log.LogErrorf("Streamer flush ec failed: ino(%v) err(%v)", s.inode, err)
+		return
+	}
+	if err = s.flushCompressed(); err != nil {
+		log.LogErrorf("Streamer flush compressed failed: ino(%v) err(%v)", s.inode, err)
+		return
+	}
 	for {
 		element := s.dirtylist.Get()
 		if element == nil {
@@ -996,6 +1110,8 @@ func (s *Streamer) abort() {
 		// TODO unhandled error
 		eh.cleanup()
 	}
+	s.resetEC()
+	s.resetCompressed()
 }
 
 func (s *Streamer) truncate(size int, fullPath string) error {
@@ -1009,6 +1125,15 @@ func (s *Streamer) truncate(size int, fullPath string) error {
 	if err != nil {
 		return err
 	}
+	// the stripes and frames appended later must not extend the key truncated,
+	// and the tail chunk buffered is stale
+	if s.ecExtent != nil {
+		s.ecExtent.key = nil
+	}
+	s.resetCompressed()
+	if s.compressExtent != nil {
+		s.compressExtent.key = nil
+	}
 
 	oldsize, _ := s.extents.Size()
 	if oldsize <= size {
@@ -21,11 +21,14 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cubefs/cubefs/proto"
 	masterSDK "github.com/cubefs/cubefs/sdk/master"
 	"github.com/cubefs/cubefs/util"
+	"github.com/cubefs/cubefs/util/compressutil"
+	"github.com/cubefs/cubefs/util/ecstripe"
 	"github.com/cubefs/cubefs/util/errors"
 	"github.com/cubefs/cubefs/util/iputil"
 	"github.com/cubefs/cubefs/util/log"
@@ -58,6 +61,8 @@ type Wrapper struct {
 	volName               string
 	volType               int
 	EnablePosixAcl        bool
+	compression           uint32 // codec of the data compressed, updated atomically
+	dedup                 int32  // whether the data appended is deduplicated, updated atomically
 	masters               []string
 	partitions            map[uint64]*DataPartition
 	followerRead          bool
@@ -71,6 +76,7 @@ type Wrapper struct {
 	stopC                 chan struct{}
 
 	dpSelector DataPartitionSelector
+	ecLayout   ecstripe.Layout // the code of the data partitions erasure coded, fixed once the volume is created
 
 	HostsStatus map[string]bool
 	Uids        map[uint32]*proto.UidSimpleInfo
@@ -151,6 +157,40 @@ func NewDataPartitionWrapper(client SimpleClientInfo, volName string, masters []
 	return
 }
 
+// NewLocalDataPartitionWrapper returns a data partition wrapper of the volume view and the partitions
+// given, which is never updated by the masters. It is used to run the clients without any master.
+func NewLocalDataPartitionWrapper(client SimpleClientInfo, view *proto.SimpleVolView, partitions []*proto.DataPartitionResponse) (w *Wrapper, err error) {
+	w = new(Wrapper)
+	w.stopC = make(chan struct{})
+	w.mc = masterSDK.NewMasterClient(nil, false)
+	w.volName = view.Name
+	w.partitions = make(map[uint64]*DataPartition)
+	w.HostsStatus = make(map[string]bool)
+	w.followerRead = view.FollowerRead
+	w.dpSelectorName = view.DpSelectorName
+	w.dpSelectorParm = view.DpSelectorParm
+	w.volType = view.VolType
+	w.EnablePosixAcl = view.EnablePosixAcl
+	w.updateCompression(view.Compression)
+	w.updateDedup(view.EnableDedup)
+	w.ecLayout = ecstripe.Layout{DataNum: int(view.ECDataNum), ParityNum: int(view.ECParityNum)}
+	w.UpdateUidsView(view)
+
+	for _, dp := range partitions {
+		for _, host := range dp.Hosts {
+			w.HostsStatus[host] = true
+		}
+	}
+	if err = w.initDpSelector(); err != nil {
+		return nil, errors.Trace(err, "NewLocalDataPartitionWrapper:")
+	}
+	if err = w.updateDataPartitionByRsp(true, partitions); err != nil {
+		return nil, errors.Trace(err, "NewLocalDataPartitionWrapper:")
+	}
+	w.SimpleClient = client
+	return
+}
+
 func (w *Wrapper) Stop() {
 	w.stopOnce.Do(func() {
 		close(w.stopC)
@@ -217,6 +257,9 @@ func (w *Wrapper) GetSimpleVolView() (err error) {
 	w.dpSelectorParm = view.DpSelectorParm
 	w.volType = view.VolType
 	w.EnablePosixAcl = view.EnablePosixAcl
+	w.updateCompression(view.Compression)
+	w.updateDedup(view.EnableDedup)
+	w.ecLayout = ecstripe.Layout{DataNum: int(view.ECDataNum), ParityNum: int(view.ECParityNum)}
 	w.UpdateUidsView(view)
 
 	log.LogDebugf("GetSimpleVolView: get volume simple info: ID(%v) name(%v) owner(%v) status(%v) capacity(%v) "+
@@ -324,6 +367,43 @@ func (w *Wrapper) updateVerlist(client SimpleClientInfo) (err error) {
 	return
 }
 
+func (w *Wrapper) updateCompression(name string) {
+	codec, err := compressutil.Parse(name)
+	if err != nil {
+		log.LogWarnf("updateCompression: volume(%v) err(%v)", w.volName, err)
+		codec = compressutil.None
+	}
+	if old := uint8(atomic.SwapUint32(&w.compression, uint32(codec))); old != codec {
+		log.LogInfof("updateCompression: volume(%v) compression from old(%v) to new(%v)",
+			w.volName, compressutil.Name(old), compressutil.Name(codec))
+	}
+}
+
+// Compression returns the codec of the data written to the volume.
+func (w *Wrapper) Compression() uint8 {
+	return uint8(atomic.LoadUint32(&w.compression))
+}
+
+func (w *Wrapper) updateDedup(enable bool) {
+	var val int32
+	if enable {
+		val = 1
+	}
+	if old := atomic.SwapInt32(&w.dedup, val); old != val {
+		log.LogInfof("updateDedup: volume(%v) dedup from old(%v) to new(%v)", w.volName, old == 1, enable)
+	}
+}
+
+// DedupEnabled returns true if the data appended to the volume is deduplicated.
+func (w *Wrapper) DedupEnabled() bool {
+	return atomic.LoadInt32(&w.dedup) == 1
+}
+
+// ECLayout returns the code of the data partitions of the volume, ok is false if they are replicated.
+func (w *Wrapper) ECLayout() (layout ecstripe.Layout, ok bool) {
+	return w.ecLayout, w.ecLayout.DataNum > 0
+}
+
 func (w *Wrapper) updateSimpleVolView() (err error) {
 	var view *proto.SimpleVolView
 	if view, err = w.mc.AdminAPI().GetVolumeSimpleInfo(w.volName); err != nil {
@@ -332,6 +412,8 @@ func (w *Wrapper) updateSimpleVolView() (err error) {
 	}
 
 	w.UpdateUidsView(view)
+	w.updateCompression(view.Compression)
+	w.updateDedup(view.EnableDedup)
 
 	if w.followerRead != view.FollowerRead && !w.followerReadClientCfg {
 		log.LogDebugf("UpdateSimpleVolView: update followerRead from old(%v) to new(%v)",
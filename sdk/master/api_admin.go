@@ -373,6 +373,13 @@ func (api *AdminAPI) UpdateVolume(
 	request.addParam("replicaNum", strconv.FormatUint(uint64(vv.DpReplicaNum), 10))
 	request.addParam("enableQuota", strconv.FormatBool(vv.EnableQuota))
 	request.addParam("deleteLockTime", strconv.FormatInt(vv.DeleteLockTime, 10))
+	request.addParam("trashInterval", strconv.FormatInt(vv.TrashInterval, 10))
+	request.addParam("enableEncryption", strconv.FormatBool(vv.EnableEncryption))
+	if vv.Compression != "" {
+		request.addParam("compression", vv.Compression)
+	}
+	request.addParam("enableDedup", strconv.FormatBool(vv.EnableDedup))
+	request.addParam("enableCDC", strconv.FormatBool(vv.EnableCDC))
 	request.addParam("clientIDKey", clientIDKey)
 	request.addParam("storeMode", strconv.FormatInt(int64(vv.DefaultStoreMode), 10))
 
@@ -442,7 +449,7 @@ func (api *AdminAPI) CreateVolName(volName, owner string, capacity uint64, delet
 	mpCount, dpCount, replicaNum, dpSize, volType int, followerRead bool, zoneName, cacheRuleKey string, ebsBlkSize,
 	cacheCapacity, cacheAction, cacheThreshold, cacheTTL, cacheHighWater, cacheLowWater, cacheLRUInterval int,
 	dpReadOnlyWhenVolFull bool, txMask string, txTimeout uint32, txConflictRetryNum int64, txConflictRetryInterval int64, optEnableQuota string,
-	clientIDKey string, storeMode proto.StoreMode) (err error) {
+	clientIDKey string, storeMode proto.StoreMode, ecDataNum, ecParityNum int) (err error) {
 	request := newAPIRequest(http.MethodGet, proto.AdminCreateVol)
 	request.addParam("name", volName)
 	request.addParam("owner", owner)
@@ -487,6 +494,11 @@ func (api *AdminAPI) CreateVolName(volName, owner string, capacity uint64, delet
 		request.addParam("txConflictRetryInterval", strconv.FormatInt(txConflictRetryInterval, 10))
 	}
 
+	if ecDataNum > 0 {
+		request.addParam("ecDataNum", strconv.Itoa(ecDataNum))
+		request.addParam("ecParityNum", strconv.Itoa(ecParityNum))
+	}
+
 	if _, err = api.mc.serveRequest(request); err != nil {
 		return
 	}
@@ -1012,6 +1024,134 @@ func (api *AdminAPI) DelBucketLifecycle(volume string) (err error) {
 	return
 }
 
+func (api *AdminAPI) SetReplication(srcVol, dstMasters, dstVol string, interval int64) (view *proto.VolReplicationView, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminSetReplication)
+	request.addParam("name", srcVol)
+	request.addParam("dstAddr", dstMasters)
+	request.addParam("dstVol", dstVol)
+	request.addParam("interval", strconv.FormatInt(interval, 10))
+	return api.serveReplicationRequest(request)
+}
+
+func (api *AdminAPI) GetReplication(srcVol string) (view *proto.VolReplicationView, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminGetReplication)
+	request.addParam("name", srcVol)
+	return api.serveReplicationRequest(request)
+}
+
+func (api *AdminAPI) ListReplications() (views []*proto.VolReplicationView, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminListReplication)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	views = make([]*proto.VolReplicationView, 0)
+	if err = json.Unmarshal(buf, &views); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) PauseReplication(srcVol string) (view *proto.VolReplicationView, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminPauseReplication)
+	request.addParam("name", srcVol)
+	return api.serveReplicationRequest(request)
+}
+
+func (api *AdminAPI) ResumeReplication(srcVol string) (view *proto.VolReplicationView, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminResumeReplication)
+	request.addParam("name", srcVol)
+	return api.serveReplicationRequest(request)
+}
+
+func (api *AdminAPI) FailoverReplication(srcVol string) (view *proto.VolReplicationView, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminFailoverReplication)
+	request.addParam("name", srcVol)
+	return api.serveReplicationRequest(request)
+}
+
+func (api *AdminAPI) DeleteReplication(srcVol string) (msg string, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminDeleteReplication)
+	request.addParam("name", srcVol)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	if err = json.Unmarshal(buf, &msg); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) serveReplicationRequest(request *request) (view *proto.VolReplicationView, err error) {
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	view = &proto.VolReplicationView{}
+	if err = json.Unmarshal(buf, view); err != nil {
+		return
+	}
+	return
+}
+
+// GetDataBalancePlan returns the migrations the data balancer would start with the threshold, the
+// threshold of the balancer is used if it is zero.
+func (api *AdminAPI) GetDataBalancePlan(threshold float64) (view *proto.DataBalanceView, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminDataBalancePlan)
+	if threshold > 0 {
+		request.addParam("threshold", strconv.FormatFloat(threshold, 'f', -1, 64))
+	}
+	return api.serveDataBalanceRequest(request)
+}
+
+// StartDataBalance starts the data balancer, the config of the balancer is kept for the zero values.
+func (api *AdminAPI) StartDataBalance(threshold float64, concurrency int, bandwidthMB uint64) (msg string, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminDataBalanceStart)
+	if threshold > 0 {
+		request.addParam("threshold", strconv.FormatFloat(threshold, 'f', -1, 64))
+	}
+	if concurrency > 0 {
+		request.addParam("concurrency", strconv.Itoa(concurrency))
+	}
+	if bandwidthMB > 0 {
+		request.addParam("bandwidth", strconv.FormatUint(bandwidthMB, 10))
+	}
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	err = json.Unmarshal(buf, &msg)
+	return
+}
+
+func (api *AdminAPI) StopDataBalance() (msg string, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminDataBalanceStop)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	err = json.Unmarshal(buf, &msg)
+	return
+}
+
+func (api *AdminAPI) GetDataBalanceStatus() (view *proto.DataBalanceView, err error) {
+	request := newAPIRequest(http.MethodGet, proto.AdminDataBalanceStatus)
+	return api.serveDataBalanceRequest(request)
+}
+
+func (api *AdminAPI) serveDataBalanceRequest(request *request) (view *proto.DataBalanceView, err error) {
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	view = &proto.DataBalanceView{}
+	if err = json.Unmarshal(buf, view); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) GetS3QoSInfo() (data []byte, err error) {
 	request := newAPIRequest(http.MethodGet, proto.S3QoSGet)
 	if data, err = api.mc.serveRequest(request); err != nil {
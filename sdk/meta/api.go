@@ -112,6 +112,21 @@ func (mw *MetaWrapper) Statfs() (total, used, inodeCount uint64) {
 }
 
 func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid uint32, target []byte, fullPath string) (*proto.InodeInfo, error) {
+	return mw.CreateWithUmask_ll(parentID, name, mode, 0, uid, gid, target, fullPath)
+}
+
+// CreateWithUmask_ll creates the entry with the default ACL of the parent inherited, and
+// the umask is only applied if the parent has no default ACL.
+func (mw *MetaWrapper) CreateWithUmask_ll(parentID uint64, name string, mode, umask, uid, gid uint32, target []byte, fullPath string) (*proto.InodeInfo, error) {
+	defaultACL := mw.parentDefaultACL(parentID, mode)
+	if defaultACL == nil {
+		mode &^= umask & 0o777
+	}
+	fileKey, err := mw.newFileKey(mode)
+	if err != nil {
+		log.LogErrorf("CreateWithUmask_ll: new file key failed, parentID(%v) name(%v) err(%v)", parentID, name, err)
+		return nil, syscall.EIO
+	}
 	// if mw.EnableTransaction {
 	txMask := proto.TxOpMaskOff
 	if proto.IsRegular(mode) {
@@ -125,13 +140,13 @@ func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid ui
 	}
 	txType := proto.TxMaskToType(txMask)
 	if mw.enableTx(txMask) && txType != proto.TxTypeUndefined {
-		return mw.txCreate_ll(parentID, name, mode, uid, gid, target, txType, fullPath)
+		return mw.txCreate_ll(parentID, name, mode, uid, gid, target, defaultACL, fileKey, txType, fullPath)
 	} else {
-		return mw.create_ll(parentID, name, mode, uid, gid, target, fullPath)
+		return mw.create_ll(parentID, name, mode, uid, gid, target, defaultACL, fileKey, fullPath)
 	}
 }
 
-func (mw *MetaWrapper) txCreate_ll(parentID uint64, name string, mode, uid, gid uint32, target []byte, txType uint32, fullPath string) (info *proto.InodeInfo, err error) {
+func (mw *MetaWrapper) txCreate_ll(parentID uint64, name string, mode, uid, gid uint32, target []byte, defaultACL, fileKey []byte, txType uint32, fullPath string) (info *proto.InodeInfo, err error) {
 	var (
 		status int
 		// err          error
@@ -178,7 +193,7 @@ func (mw *MetaWrapper) txCreate_ll(parentID uint64, name string, mode, uid, gid
 			return nil, syscall.EAGAIN
 		}
 
-		status, info, err = mw.txIcreate(tx, mp, mode, uid, gid, target, quotaIds, fullPath)
+		status, info, err = mw.txIcreate(tx, mp, mode, uid, gid, target, defaultACL, fileKey, quotaIds, fullPath)
 		if err == nil && status == statusOK {
 			goto create_dentry
 		} else if status == statusNoSpace {
@@ -222,7 +237,7 @@ create_dentry:
 	return info, nil
 }
 
-func (mw *MetaWrapper) create_ll(parentID uint64, name string, mode, uid, gid uint32, target []byte, fullPath string) (*proto.InodeInfo, error) {
+func (mw *MetaWrapper) create_ll(parentID uint64, name string, mode, uid, gid uint32, target []byte, defaultACL, fileKey []byte, fullPath string) (*proto.InodeInfo, error) {
 	var (
 		status       int
 		err          error
@@ -267,7 +282,7 @@ get_rwmp:
 		for i := 0; i < length; i++ {
 			index := (int(epoch) + i) % length
 			mp = rwPartitions[index]
-			status, info, err = mw.quotaIcreate(mp, mode, uid, gid, target, quotaIds, fullPath)
+			status, info, err = mw.quotaIcreate(mp, mode, uid, gid, target, defaultACL, fileKey, quotaIds, fullPath)
 			if err == nil && status == statusOK {
 				goto create_dentry
 			} else if status == statusFull {
@@ -290,7 +305,7 @@ get_rwmp:
 		for i := 0; i < length; i++ {
 			index := (int(epoch) + i) % length
 			mp = rwPartitions[index]
-			status, info, err = mw.icreate(mp, mode, uid, gid, target, fullPath)
+			status, info, err = mw.icreate(mp, mode, uid, gid, target, defaultACL, fileKey, fullPath)
 			if err == nil && status == statusOK {
 				goto create_dentry
 			} else if status == statusFull {
@@ -565,7 +580,13 @@ func (mw *MetaWrapper) Delete_Ver_ll(parentID uint64, name string, isDir bool, v
 }
 
 func (mw *MetaWrapper) DeleteWithCond_ll(parentID, cond uint64, name string, isDir bool, fullPath string) (*proto.InodeInfo, error) {
-	return mw.deletewithcond_ll(parentID, cond, name, isDir, fullPath)
+	return mw.deletewithcond_ll(parentID, cond, name, isDir, fullPath, false)
+}
+
+// DeleteWithCondBypassGovernance_ll is the same as DeleteWithCond_ll, except that the object
+// retained in GOVERNANCE mode is allowed to be deleted.
+func (mw *MetaWrapper) DeleteWithCondBypassGovernance_ll(parentID, cond uint64, name string, isDir bool, fullPath string) (*proto.InodeInfo, error) {
+	return mw.deletewithcond_ll(parentID, cond, name, isDir, fullPath, true)
 }
 
 func (mw *MetaWrapper) txDelete_ll(parentID uint64, name string, isDir bool, fullPath string) (info *proto.InodeInfo, err error) {
@@ -809,12 +830,18 @@ func (mw *MetaWrapper) Delete_ll_EX(parentID uint64, name string, isDir bool, ve
 	return info, nil
 }
 
-func isObjectLocked(mw *MetaWrapper, inode uint64, name string) error {
-	xattrInfo, err := mw.XAttrGet_ll(inode, "oss:lock")
+// isObjectLocked checks the legal hold and the retention of the object. The retention
+// in GOVERNANCE mode does not protect the object if it is bypassed.
+func isObjectLocked(mw *MetaWrapper, inode uint64, name string, bypassGovernance bool) error {
+	xattrInfo, err := mw.XAttrGetAll_ll(inode)
 	if err != nil {
 		log.LogErrorf("isObjectLocked: check ObjectLock err(%v) name(%v)", err, name)
 		return err
 	}
+	if string(xattrInfo.Get("oss:legal-hold")) == "ON" {
+		log.LogWarnf("isObjectLocked: object is under legal hold, name(%v)", name)
+		return errors.New("Access Denied")
+	}
 	retainUntilDate := xattrInfo.Get("oss:lock")
 	if len(retainUntilDate) > 0 {
 		retainUntilDateInt64, err := strconv.ParseInt(string(retainUntilDate), 10, 64)
@@ -822,6 +849,11 @@ func isObjectLocked(mw *MetaWrapper, inode uint64, name string) error {
 			return err
 		}
 		if retainUntilDateInt64 > time.Now().UnixNano() {
+			if bypassGovernance && string(xattrInfo.Get("oss:lock-mode")) == "GOVERNANCE" {
+				log.LogInfof("isObjectLocked: governance retention is bypassed, retainUntilDate(%v) name(%v)",
+					retainUntilDateInt64, name)
+				return nil
+			}
 			log.LogWarnf("isObjectLocked: object is locked, retainUntilDate(%v) name(%v)", retainUntilDateInt64, name)
 			return errors.New("Access Denied")
 		}
@@ -829,8 +861,8 @@ func isObjectLocked(mw *MetaWrapper, inode uint64, name string) error {
 	return nil
 }
 
-func (mw *MetaWrapper) deletewithcond_ll(parentID, cond uint64, name string, isDir bool, fullPath string) (*proto.InodeInfo, error) {
-	err := isObjectLocked(mw, cond, name)
+func (mw *MetaWrapper) deletewithcond_ll(parentID, cond uint64, name string, isDir bool, fullPath string, bypassGovernance bool) (*proto.InodeInfo, error) {
+	err := isObjectLocked(mw, cond, name, bypassGovernance)
 	if err != nil {
 		return nil, err
 	}
@@ -1704,6 +1736,12 @@ func (mw *MetaWrapper) InodeCreate_ll(parentID uint64, mode, uid, gid uint32, ta
 		mp           *MetaPartition
 		rwPartitions []*MetaPartition
 	)
+	defaultACL := mw.parentDefaultACL(parentID, mode)
+	fileKey, err := mw.newFileKey(mode)
+	if err != nil {
+		log.LogErrorf("InodeCreate_ll: new file key failed, parentID(%v) err(%v)", parentID, err)
+		return nil, syscall.EIO
+	}
 
 get_rwmp:
 	rwPartitions = mw.getRWPartitions()
@@ -1728,7 +1766,7 @@ get_rwmp:
 		for i := 0; i < length; i++ {
 			index := (int(epoch) + i) % length
 			mp = rwPartitions[index]
-			status, info, err = mw.quotaIcreate(mp, mode, uid, gid, target, quotaIds, fullPath)
+			status, info, err = mw.quotaIcreate(mp, mode, uid, gid, target, defaultACL, fileKey, quotaIds, fullPath)
 			if err == nil && status == statusOK {
 				return info, nil
 			} else if status == statusFull {
@@ -1751,7 +1789,7 @@ get_rwmp:
 		for i := 0; i < length; i++ {
 			index := (int(epoch) + i) % length
 			mp = rwPartitions[index]
-			status, info, err = mw.icreate(mp, mode, uid, gid, target, fullPath)
+			status, info, err = mw.icreate(mp, mode, uid, gid, target, defaultACL, fileKey, fullPath)
 			if err == nil && status == statusOK {
 				return info, nil
 			} else if status == statusFull {
@@ -0,0 +1,45 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+
+	"github.com/cubefs/cubefs/proto"
+)
+
+// CDCPartitions returns the ids of the meta partitions of the volume, each of them has its own
+// feed of the change events.
+func (mw *MetaWrapper) CDCPartitions() (ids []uint64) {
+	for _, mp := range mw.getPartitions() {
+		ids = append(ids, mp.PartitionID)
+	}
+	return
+}
+
+// ReadCDC reads the change events of the meta partition recorded after the cursor from, and the
+// cursor to resume from is the Next of the response. The cursor is the raft index, so it is valid
+// for all the replicas of the partition.
+func (mw *MetaWrapper) ReadCDC(partitionID, from uint64, limit int) (*proto.CDCReadResponse, error) {
+	mp := mw.getPartitionByID(partitionID)
+	if mp == nil {
+		return nil, fmt.Errorf("meta partition %v of volume %v not found", partitionID, mw.volname)
+	}
+	status, resp, err := mw.cdcRead(mp, &proto.CDCReadRequest{From: from, Limit: limit})
+	if err != nil || status != statusOK {
+		return nil, statusErrToErrno(status, err)
+	}
+	return resp, nil
+}
@@ -28,6 +28,8 @@ import (
 const (
 	SendRetryLimit    = 200 // times
 	SendRetryInterval = 100 // ms
+
+	MovedRangeRedirectLimit = 3 // times
 )
 
 type MetaConn struct {
@@ -56,7 +58,21 @@ func (mw *MetaWrapper) putConn(mc *MetaConn, err error) {
 	mw.conns.PutConnect(mc.conn, err != nil)
 }
 
-func (mw *MetaWrapper) sendToMetaPartition(mp *MetaPartition, req *proto.Packet) (*proto.Packet, error) {
+// sendToMetaPartition sends the request to the partition, the request is redirected to the partition the
+// inode range is migrated to if the range is moved.
+func (mw *MetaWrapper) sendToMetaPartition(mp *MetaPartition, req *proto.Packet) (resp *proto.Packet, err error) {
+	for i := 0; ; i++ {
+		resp, err = mw.sendToMetaPartitionWithRetry(mp, req)
+		if err != nil || resp.ResultCode != proto.OpMetaRangeMoved || i >= MovedRangeRedirectLimit {
+			return
+		}
+		if mp, err = mw.redirectMovedRange(req, resp); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (mw *MetaWrapper) sendToMetaPartitionWithRetry(mp *MetaPartition, req *proto.Packet) (*proto.Packet, error) {
 	var (
 		resp    *proto.Packet
 		err     error
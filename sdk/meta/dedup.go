@@ -0,0 +1,57 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"syscall"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// DedupExtents makes the chunks of the inode refer to the data written before with the same fingerprints,
+// and returns the extent keys of the chunks in order, the key is nil if the chunk is not deduplicated.
+func (mw *MetaWrapper) DedupExtents(inode uint64, chunks []proto.DedupChunk) ([]*proto.ExtentKey, error) {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("DedupExtents: No inode partition, ino(%v)", inode)
+		return nil, syscall.ENOENT
+	}
+
+	status, eks, err := mw.dedupExtents(mp, inode, chunks)
+	if err != nil || status != statusOK {
+		return nil, statusErrToErrno(status, err)
+	}
+	if len(eks) != len(chunks) {
+		log.LogErrorf("DedupExtents: ino(%v) chunks(%v) extents(%v) mismatch", inode, len(chunks), len(eks))
+		return nil, syscall.EIO
+	}
+	return eks, nil
+}
+
+// AddFingerprints adds the fingerprints of the chunks written by the inode to the index.
+func (mw *MetaWrapper) AddFingerprints(inode uint64, chunks []proto.DedupChunk) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("AddFingerprints: No inode partition, ino(%v)", inode)
+		return syscall.ENOENT
+	}
+
+	status, err := mw.addFingerprints(mp, inode, chunks)
+	if err != nil || status != statusOK {
+		return statusErrToErrno(status, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/cryptoutil"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// EncryptionEnabled checks whether the files created in the volume are encrypted.
+func (mw *MetaWrapper) EncryptionEnabled() bool {
+	return mw.volEncryption
+}
+
+// volumeMasterKey returns the master key of the volume, which is fetched from authnode
+// with the ticket of the owner once and kept in memory.
+func (mw *MetaWrapper) volumeMasterKey() ([]byte, error) {
+	mw.volumeKeyLock.Lock()
+	defer mw.volumeKeyLock.Unlock()
+	if mw.volumeKey != nil {
+		return mw.volumeKey, nil
+	}
+	if mw.ac == nil {
+		return nil, fmt.Errorf("no authnode configured for the encrypted volume %v", mw.volname)
+	}
+	key, err := mw.ac.API().GetVolumeKey(mw.owner, mw.ticketMess.ClientKey, mw.volname)
+	if err != nil {
+		return nil, err
+	}
+	mw.volumeKey = key
+	return key, nil
+}
+
+// newFileKey returns the wrapped data key of the file to be created, or nil if the volume
+// is not encrypted or the inode has no data.
+func (mw *MetaWrapper) newFileKey(mode uint32) ([]byte, error) {
+	if !mw.EncryptionEnabled() || !proto.IsRegular(mode) {
+		return nil, nil
+	}
+	masterKey, err := mw.volumeMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	key, err := cryptoutil.GenDataKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := cryptoutil.WrapKey(masterKey, key, []byte(mw.volname))
+	if err != nil {
+		return nil, err
+	}
+	fileKey := &proto.FileKey{Version: proto.FileKeyVersion, Wrapped: wrapped}
+	return fileKey.Marshal()
+}
+
+// LoadFileCipher returns the cipher of the data of the inode, or nil if the file is not
+// encrypted, such as the files created before the encryption is enabled.
+func (mw *MetaWrapper) LoadFileCipher(inode uint64) (*cryptoutil.FileCipher, error) {
+	if !mw.EncryptionEnabled() {
+		return nil, nil
+	}
+	xattr, err := mw.XAttrGet_ll(inode, proto.XAttrFileKey)
+	if err != nil {
+		return nil, err
+	}
+	value := xattr.XAttrs[proto.XAttrFileKey]
+	if value == "" {
+		return nil, nil
+	}
+	fileKey, err := proto.UnmarshalFileKey([]byte(value))
+	if err != nil {
+		log.LogErrorf("LoadFileCipher: ino(%v) invalid file key, err(%v)", inode, err)
+		return nil, err
+	}
+	masterKey, err := mw.volumeMasterKey()
+	if err != nil {
+		log.LogErrorf("LoadFileCipher: ino(%v) get volume key failed, err(%v)", inode, err)
+		return nil, err
+	}
+	key, err := cryptoutil.UnwrapKey(masterKey, fileKey.Wrapped, []byte(mw.volname))
+	if err != nil {
+		log.LogErrorf("LoadFileCipher: ino(%v) unwrap file key failed, err(%v)", inode, err)
+		return nil, err
+	}
+	return cryptoutil.NewFileCipher(key)
+}
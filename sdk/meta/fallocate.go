@@ -0,0 +1,73 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"syscall"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// Fallocate_ll preallocates, punches or zeroes the range of the inode as fallocate(2) does. For
+// preallocation the extents reserved by the data nodes are inserted where the range is still a hole.
+func (mw *MetaWrapper) Fallocate_ll(inode uint64, mode uint32, offset, length uint64, eks []proto.ExtentKey) error {
+	if err := proto.ValidateFallocate(mode, offset, length); err != nil {
+		log.LogWarnf("Fallocate_ll: ino(%v) err(%v)", inode, err)
+		return syscall.EOPNOTSUPP
+	}
+
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("Fallocate_ll: No inode partition, ino(%v)", inode)
+		return syscall.ENOENT
+	}
+
+	status, err := mw.fallocate(mp, inode, mode, offset, length, eks)
+	if err != nil || status != statusOK {
+		return statusErrToErrno(status, err)
+	}
+	return nil
+}
+
+// CloneExtents_ll makes the range of the destination inode share the data of the source range,
+// and returns the bytes cloned. EXDEV is returned if the inodes could not share the extents, and
+// the caller is expected to copy the data instead.
+func (mw *MetaWrapper) CloneExtents_ll(src, dst, srcOff, dstOff, length uint64) (n uint64, err error) {
+	if mw.EncryptionEnabled() {
+		// the data is encrypted with the key and the offset of each file
+		return 0, syscall.EXDEV
+	}
+	mp := mw.getPartitionByInode(src)
+	if mp == nil {
+		log.LogErrorf("CloneExtents_ll: No inode partition, ino(%v)", src)
+		return 0, syscall.ENOENT
+	}
+	if dstMp := mw.getPartitionByInode(dst); dstMp == nil || dstMp.PartitionID != mp.PartitionID {
+		// the references of the shared extents are kept by a single meta partition, so the inodes of
+		// different meta partitions could not share the extents
+		log.LogDebugf("CloneExtents_ll: src(%v) and dst(%v) are in different meta partitions", src, dst)
+		return 0, syscall.EXDEV
+	}
+
+	status, n, err := mw.cloneExtents(mp, src, dst, srcOff, dstOff, length)
+	if err != nil || status != statusOK {
+		if status == statusNotPerm {
+			return 0, syscall.EXDEV
+		}
+		return 0, statusErrToErrno(status, err)
+	}
+	return n, nil
+}
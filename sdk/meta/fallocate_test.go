@@ -0,0 +1,57 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/btree"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetaWrapperForFallocateTest() *MetaWrapper {
+	mw := &MetaWrapper{ranges: btree.New(32)}
+	mw.ranges.ReplaceOrInsert(&MetaPartition{PartitionID: 1, Start: 1, End: 100})
+	mw.ranges.ReplaceOrInsert(&MetaPartition{PartitionID: 2, Start: 101, End: 200})
+	return mw
+}
+
+// The clone across the meta partitions falls back to EXDEV before any request is sent, so that
+// copy_file_range(2) copies the data instead.
+func TestCloneExtentsFallback(t *testing.T) {
+	mw := newMetaWrapperForFallocateTest()
+
+	n, err := mw.CloneExtents_ll(10, 150, 0, 0, 4096)
+	assert.Equal(t, syscall.EXDEV, err)
+	assert.Zero(t, n)
+	_, err = mw.CloneExtents_ll(10, 300, 0, 0, 4096)
+	assert.Equal(t, syscall.EXDEV, err)
+	_, err = mw.CloneExtents_ll(300, 10, 0, 0, 4096)
+	assert.Equal(t, syscall.ENOENT, err)
+
+	mw.volEncryption = true
+	_, err = mw.CloneExtents_ll(10, 20, 0, 0, 4096)
+	assert.Equal(t, syscall.EXDEV, err)
+}
+
+func TestFallocateInvalid(t *testing.T) {
+	mw := newMetaWrapperForFallocateTest()
+
+	assert.Equal(t, syscall.EOPNOTSUPP, mw.Fallocate_ll(10, proto.FallocPunchHole, 0, 4096, nil))
+	assert.Equal(t, syscall.EOPNOTSUPP, mw.Fallocate_ll(10, 0, 0, 0, nil))
+	assert.Equal(t, syscall.ENOENT, mw.Fallocate_ll(300, 0, 0, 4096, nil))
+}
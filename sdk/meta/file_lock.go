@@ -0,0 +1,154 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// the lease of the file locks on the metanode is 30 seconds
+const FileLockRenewInterval = 10 * time.Second
+
+// SetFileLock_ll acquires or releases the advisory lock on the inode without waiting,
+// the conflicting lock is returned with EAGAIN if the lock is held by others.
+func (mw *MetaWrapper) SetFileLock_ll(lock *proto.FileLock) (conflict *proto.FileLock, err error) {
+	lock.Client = mw.fileLockClient
+	if lock.Type == proto.FileLockUnlock && !mw.holdFileLock(lock) {
+		return nil, nil
+	}
+
+	mp := mw.getPartitionByInode(lock.Inode)
+	if mp == nil {
+		log.LogErrorf("SetFileLock_ll: No such partition, ino(%v)", lock.Inode)
+		return nil, syscall.EINVAL
+	}
+
+	status, conflict, err := mw.setFileLock(mp, lock)
+	if err != nil || status != statusOK {
+		if status != statusFileLockConflict {
+			log.LogErrorf("SetFileLock_ll: lock(%v) err(%v) status(%v)", lock, err, status)
+		}
+		return conflict, statusErrToErrno(status, err)
+	}
+
+	mw.fileLockMutex.Lock()
+	mw.setLocalFileLocks(lock.Inode, proto.ApplyFileLock(mw.fileLocks[lock.Inode], lock))
+	mw.fileLockMutex.Unlock()
+	log.LogDebugf("SetFileLock_ll: lock(%v)", lock)
+	return nil, nil
+}
+
+// GetFileLock_ll returns the lock which prevents the lock from being acquired, or nil if there is none.
+func (mw *MetaWrapper) GetFileLock_ll(lock *proto.FileLock) (conflict *proto.FileLock, err error) {
+	lock.Client = mw.fileLockClient
+	mp := mw.getPartitionByInode(lock.Inode)
+	if mp == nil {
+		log.LogErrorf("GetFileLock_ll: No such partition, ino(%v)", lock.Inode)
+		return nil, syscall.EINVAL
+	}
+
+	status, conflict, err := mw.getFileLock(mp, lock)
+	if err != nil || status != statusOK {
+		log.LogErrorf("GetFileLock_ll: lock(%v) err(%v) status(%v)", lock, err, status)
+		return nil, statusErrToErrno(status, err)
+	}
+	return conflict, nil
+}
+
+// ReleaseFileLocks_ll releases all the locks of the kind held by the owner on the inode.
+func (mw *MetaWrapper) ReleaseFileLocks_ll(ino uint64, owner uint64, kind proto.FileLockKind) error {
+	_, err := mw.SetFileLock_ll(&proto.FileLock{
+		Inode: ino,
+		Owner: owner,
+		Kind:  kind,
+		Type:  proto.FileLockUnlock,
+		Start: 0,
+		End:   proto.FileLockMaxOffset,
+	})
+	return err
+}
+
+// holdFileLock reports whether the owner of lock holds any lock in the range of lock.
+func (mw *MetaWrapper) holdFileLock(lock *proto.FileLock) bool {
+	mw.fileLockMutex.Lock()
+	defer mw.fileLockMutex.Unlock()
+	for _, l := range mw.fileLocks[lock.Inode] {
+		if l.SameOwner(lock) && l.Start <= lock.End && lock.Start <= l.End {
+			return true
+		}
+	}
+	return false
+}
+
+func (mw *MetaWrapper) setLocalFileLocks(ino uint64, locks []*proto.FileLock) {
+	if len(locks) == 0 {
+		delete(mw.fileLocks, ino)
+		return
+	}
+	mw.fileLocks[ino] = locks
+}
+
+func (mw *MetaWrapper) renewFileLocksTick() {
+	ticker := time.NewTicker(FileLockRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			mw.renewAllFileLocks()
+		case <-mw.closeCh:
+			return
+		}
+	}
+}
+
+// renewAllFileLocks renews the leases on the meta partitions where the client holds locks,
+// and drops the locks which are lost, e.g. the lease expired and others took the locks.
+func (mw *MetaWrapper) renewAllFileLocks() {
+	mw.fileLockMutex.Lock()
+	partitions := make(map[*MetaPartition][]*proto.FileLock)
+	for ino, locks := range mw.fileLocks {
+		mp := mw.getPartitionByInode(ino)
+		if mp == nil {
+			log.LogWarnf("renewAllFileLocks: No such partition, ino(%v)", ino)
+			continue
+		}
+		partitions[mp] = append(partitions[mp], locks...)
+	}
+	mw.fileLockMutex.Unlock()
+
+	for mp, locks := range partitions {
+		status, lost, err := mw.renewFileLocks(mp, locks)
+		if err != nil || status != statusOK {
+			log.LogWarnf("renewAllFileLocks: mp(%v) err(%v) status(%v)", mp.PartitionID, err, status)
+			continue
+		}
+		if len(lost) == 0 {
+			continue
+		}
+		log.LogWarnf("renewAllFileLocks: mp(%v) lost locks(%v)", mp.PartitionID, lost)
+		mw.fileLockMutex.Lock()
+		for _, l := range lost {
+			unlock := *l
+			unlock.Type = proto.FileLockUnlock
+			mw.setLocalFileLocks(l.Inode, proto.ApplyFileLock(mw.fileLocks[l.Inode], &unlock))
+		}
+		mw.fileLockMutex.Unlock()
+	}
+}
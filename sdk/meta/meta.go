@@ -21,6 +21,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"golang.org/x/sync/singleflight"
 	"golang.org/x/time/rate"
 
@@ -58,6 +59,7 @@ const (
 	statusTxTimeout
 	statusUploadPartConflict
 	statusNotEmpty
+	statusFileLockConflict
 )
 
 const (
@@ -105,6 +107,10 @@ type MetaWrapper struct {
 	ossSecure         *OSSSecure
 	volCreateTime     int64
 	volDeleteLockTime int64
+	volTrashInterval  int64
+	volEnablePosixAcl bool
+	volEncryption     bool
+	trashBucket       trashBucketCache
 	owner             string
 	ownerValidation   bool
 	mc                *masterSDK.MasterClient
@@ -156,6 +162,7 @@ type MetaWrapper struct {
 	TxConflictRetryNum      int64
 	TxConflictRetryInterval int64
 	EnableQuota             bool
+	EnablePosixACL          bool
 	QuotaInfoMap            map[uint32]*proto.QuotaInfo
 	QuotaLock               sync.RWMutex
 
@@ -165,6 +172,15 @@ type MetaWrapper struct {
 
 	qc *QuotaCache
 
+	// advisory file locks held by this client, renewed with the lease periodically
+	fileLockClient string
+	fileLocks      map[uint64][]*proto.FileLock
+	fileLockMutex  sync.Mutex
+
+	// master key of the encrypted volume fetched from authnode
+	volumeKey     []byte
+	volumeKeyLock sync.Mutex
+
 	VerReadSeq uint64
 	LastVerSeq uint64
 	Client     wrapper.SimpleClientInfo
@@ -201,6 +217,11 @@ func NewMetaWrapper(config *MetaConfig) (*MetaWrapper, error) {
 		mw.accessToken.ServiceID = proto.MasterServiceID
 		mw.sessionKey = ticket.SessionKey
 		mw.ticketMess = ticketMess
+	} else if len(config.TicketMess.TicketHosts) > 0 {
+		// the authnode is only asked for the master key of the encrypted volume
+		ticketMess := config.TicketMess
+		mw.ac = authSDK.NewAuthClient(ticketMess.TicketHosts, ticketMess.EnableHTTPS, ticketMess.CertFile)
+		mw.ticketMess = ticketMess
 	}
 
 	mw.volname = config.Volume
@@ -221,6 +242,8 @@ func NewMetaWrapper(config *MetaConfig) (*MetaWrapper, error) {
 	mw.uniqidRangeMap = make(map[uint64]*uniqidRange, 0)
 	mw.qc = NewQuotaCache(DefaultQuotaExpiration, MaxQuotaCache)
 	mw.VerReadSeq = config.VerReadSeq
+	mw.fileLockClient = uuid.New().String()
+	mw.fileLocks = make(map[uint64][]*proto.FileLock)
 
 	limit := 0
 	for limit < MaxMountRetryLimit {
@@ -243,6 +266,7 @@ func NewMetaWrapper(config *MetaConfig) (*MetaWrapper, error) {
 
 	go mw.updateQuotaInfoTick()
 	go mw.refresh()
+	go mw.renewFileLocksTick()
 	return mw, nil
 }
 
@@ -335,6 +359,8 @@ func parseStatus(result uint8) (status int) {
 		status = statusTxTimeout
 	case proto.OpUploadPartConflictErr:
 		status = statusUploadPartConflict
+	case proto.OpFileLockConflict:
+		status = statusFileLockConflict
 	default:
 		status = statusError
 	}
@@ -384,6 +410,8 @@ func statusToErrno(status int) error {
 		return syscall.EAGAIN
 	case statusUploadPartConflict:
 		return syscall.EEXIST
+	case statusFileLockConflict:
+		return syscall.EAGAIN
 	default:
 	}
 	return syscall.EIO
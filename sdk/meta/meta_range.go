@@ -0,0 +1,63 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const updateMovedRangeKey = "UpdateMovedRange"
+
+// redirectMovedRange rewrites the request to the partition the inode range is migrated to. The view of
+// the partitions is updated as the ranges of both partitions are changed by the migration.
+func (mw *MetaWrapper) redirectMovedRange(req, resp *proto.Packet) (mp *MetaPartition, err error) {
+	moved := &proto.MetaRangeMovedResponse{}
+	if err = json.Unmarshal(resp.Data, moved); err != nil {
+		return nil, fmt.Errorf("redirectMovedRange: unmarshal resp(%v) err(%v)", resp, err)
+	}
+
+	mw.singleflight.Do(updateMovedRangeKey, func() (interface{}, error) {
+		if err := mw.updateMetaPartitions(); err != nil {
+			log.LogWarnf("redirectMovedRange: update meta partitions err(%v)", err)
+		}
+		return nil, nil
+	})
+	if mp = mw.getPartitionByID(moved.PartitionID); mp == nil {
+		return nil, fmt.Errorf("redirectMovedRange: req(%v) moved to unknown mp(%v)", req, moved.PartitionID)
+	}
+
+	if req.Data, err = setRequestPartitionID(req.Data, mp.PartitionID); err != nil {
+		return nil, fmt.Errorf("redirectMovedRange: req(%v) err(%v)", req, err)
+	}
+	req.Size = uint32(len(req.Data))
+	req.PartitionID = mp.PartitionID
+	log.LogInfof("redirectMovedRange: req(%v) redirected to mp(%v)", req, mp)
+	return
+}
+
+// setRequestPartitionID replaces the partition id of the request body.
+func setRequestPartitionID(data []byte, partitionID uint64) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["pid"] = json.RawMessage(strconv.FormatUint(partitionID, 10))
+	return json.Marshal(fields)
+}
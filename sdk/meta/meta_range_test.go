@@ -0,0 +1,39 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRequestPartitionID(t *testing.T) {
+	req := &proto.CreateDentryRequest{VolName: "vol", PartitionID: 1, ParentID: 100, Inode: 101, Name: "a", Mode: 0o644}
+	data, err := json.Marshal(req)
+	assert.NoError(t, err)
+
+	data, err = setRequestPartitionID(data, 2)
+	assert.NoError(t, err)
+	got := &proto.CreateDentryRequest{}
+	assert.NoError(t, json.Unmarshal(data, got))
+	req.PartitionID = 2
+	assert.Equal(t, req, got)
+
+	_, err = setRequestPartitionID([]byte("not json"), 2)
+	assert.Error(t, err)
+}
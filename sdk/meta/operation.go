@@ -32,7 +32,7 @@ import (
 //
 // txIcreate create inode and tx together
 func (mw *MetaWrapper) txIcreate(tx *Transaction, mp *MetaPartition, mode, uid, gid uint32,
-	target []byte, quotaIds []uint32, fullPath string) (status int, info *proto.InodeInfo, err error) {
+	target []byte, defaultACL, fileKey []byte, quotaIds []uint32, fullPath string) (status int, info *proto.InodeInfo, err error) {
 	bgTime := stat.BeginStat()
 	defer func() {
 		stat.EndStat("txIcreate", err, bgTime, 1)
@@ -49,6 +49,8 @@ func (mw *MetaWrapper) txIcreate(tx *Transaction, mp *MetaPartition, mode, uid,
 		Target:      target,
 		QuotaIds:    quotaIds,
 		TxInfo:      tx.txInfo,
+		DefaultACL:  defaultACL,
+		FileKey:     fileKey,
 	}
 	req.FullPaths = []string{fullPath}
 
@@ -103,7 +105,7 @@ func (mw *MetaWrapper) txIcreate(tx *Transaction, mp *MetaPartition, mode, uid,
 	return status, resp.Info, nil
 }
 
-func (mw *MetaWrapper) quotaIcreate(mp *MetaPartition, mode, uid, gid uint32, target []byte, quotaIds []uint32, fullPath string) (status int,
+func (mw *MetaWrapper) quotaIcreate(mp *MetaPartition, mode, uid, gid uint32, target []byte, defaultACL, fileKey []byte, quotaIds []uint32, fullPath string) (status int,
 	info *proto.InodeInfo, err error) {
 	bgTime := stat.BeginStat()
 	defer func() {
@@ -118,6 +120,8 @@ func (mw *MetaWrapper) quotaIcreate(mp *MetaPartition, mode, uid, gid uint32, ta
 		Gid:         gid,
 		Target:      target,
 		QuotaIds:    quotaIds,
+		DefaultACL:  defaultACL,
+		FileKey:     fileKey,
 	}
 	req.FullPaths = []string{fullPath}
 
@@ -163,7 +167,7 @@ func (mw *MetaWrapper) quotaIcreate(mp *MetaPartition, mode, uid, gid uint32, ta
 	return statusOK, resp.Info, nil
 }
 
-func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target []byte, fullPath string) (status int,
+func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target []byte, defaultACL, fileKey []byte, fullPath string) (status int,
 	info *proto.InodeInfo, err error) {
 	bgTime := stat.BeginStat()
 	defer func() {
@@ -177,6 +181,8 @@ func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target
 		Uid:         uid,
 		Gid:         gid,
 		Target:      target,
+		DefaultACL:  defaultACL,
+		FileKey:     fileKey,
 	}
 	req.FullPaths = []string{fullPath}
 
@@ -2830,6 +2836,118 @@ func (mw *MetaWrapper) getUniqID(mp *MetaPartition, num uint32) (status int, sta
 	return
 }
 
+func (mw *MetaWrapper) setFileLock(mp *MetaPartition, lock *proto.FileLock) (status int, conflict *proto.FileLock, err error) {
+	req := &proto.SetFileLockRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Lock:        *lock,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaSetFileLock
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("setFileLock: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status == statusFileLockConflict {
+		resp := new(proto.GetFileLockResponse)
+		if err = packet.UnmarshalData(resp); err != nil {
+			log.LogErrorf("setFileLock: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
+			return
+		}
+		conflict = resp.Conflict
+		return
+	}
+	if status != statusOK {
+		log.LogErrorf("setFileLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+	}
+	return
+}
+
+func (mw *MetaWrapper) getFileLock(mp *MetaPartition, lock *proto.FileLock) (status int, conflict *proto.FileLock, err error) {
+	req := &proto.GetFileLockRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Lock:        *lock,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaGetFileLock
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("getFileLock: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("getFileLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.GetFileLockResponse)
+	err = packet.UnmarshalData(resp)
+	if err != nil {
+		log.LogErrorf("getFileLock: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
+		return
+	}
+	conflict = resp.Conflict
+	return
+}
+
+func (mw *MetaWrapper) renewFileLocks(mp *MetaPartition, locks []*proto.FileLock) (status int, lost []*proto.FileLock, err error) {
+	req := &proto.RenewFileLocksRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Client:      mw.fileLockClient,
+		Locks:       locks,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaRenewFileLocks
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("renewFileLocks: packet(%v) mp(%v) client(%v) err(%v)", packet, mp, req.Client, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("renewFileLocks: packet(%v) mp(%v) client(%v) result(%v)", packet, mp, req.Client, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.RenewFileLocksResponse)
+	err = packet.UnmarshalData(resp)
+	if err != nil {
+		log.LogErrorf("renewFileLocks: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
+		return
+	}
+	lost = resp.Lost
+	return
+}
+
 func (mw *MetaWrapper) checkVerFromMeta(packet *proto.Packet) {
 	if packet.VerSeq <= mw.Client.GetLatestVer() {
 		return
@@ -2838,3 +2956,408 @@ func (mw *MetaWrapper) checkVerFromMeta(packet *proto.Packet) {
 	log.LogDebugf("checkVerFromMeta.UpdateLatestVer.try update meta wrapper verSeq from %v to %v verlist[%v]", mw.Client.GetLatestVer(), packet.VerSeq, packet.VerList)
 	mw.Client.UpdateLatestVer(&proto.VolVersionInfoList{VerList: packet.VerList})
 }
+
+func (mw *MetaWrapper) fallocate(mp *MetaPartition, inode uint64, mode uint32, offset, length uint64, eks []proto.ExtentKey) (status int, err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("fallocate", err, bgTime, 1)
+	}()
+
+	req := &proto.FallocateRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Inode:       inode,
+		Mode:        mode,
+		Offset:      offset,
+		Length:      length,
+		Extents:     eks,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaFallocate
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("fallocate: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("fallocate: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+	log.LogDebugf("fallocate exit: packet(%v) mp(%v) req(%v)", packet, mp, *req)
+	return
+}
+
+func (mw *MetaWrapper) cloneExtents(mp *MetaPartition, src, dst, srcOff, dstOff, length uint64) (status int, size uint64, err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("cloneExtents", err, bgTime, 1)
+	}()
+
+	req := &proto.CloneExtentsRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		SrcInode:    src,
+		DstInode:    dst,
+		SrcOffset:   srcOff,
+		DstOffset:   dstOff,
+		Length:      length,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaCloneExtents
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("cloneExtents: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("cloneExtents: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.CloneExtentsResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("cloneExtents: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
+		return
+	}
+	size = resp.Size
+	log.LogDebugf("cloneExtents exit: packet(%v) mp(%v) req(%v) size(%v)", packet, mp, *req, size)
+	return
+}
+
+func (mw *MetaWrapper) dedupExtents(mp *MetaPartition, inode uint64, chunks []proto.DedupChunk) (status int, eks []*proto.ExtentKey, err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("dedupExtents", err, bgTime, 1)
+	}()
+
+	req := &proto.DedupExtentsRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Inode:       inode,
+		Chunks:      chunks,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaDedupExtents
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("dedupExtents: packet(%v) mp(%v) ino(%v) err(%v)", packet, mp, inode, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("dedupExtents: packet(%v) mp(%v) ino(%v) result(%v)", packet, mp, inode, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.DedupExtentsResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("dedupExtents: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
+		return
+	}
+	eks = resp.Extents
+	log.LogDebugf("dedupExtents exit: packet(%v) mp(%v) ino(%v) extents(%v)", packet, mp, inode, eks)
+	return
+}
+
+func (mw *MetaWrapper) addFingerprints(mp *MetaPartition, inode uint64, chunks []proto.DedupChunk) (status int, err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("addFingerprints", err, bgTime, 1)
+	}()
+
+	req := &proto.AddFingerprintsRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Inode:       inode,
+		Chunks:      chunks,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaAddFingerprints
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("addFingerprints: packet(%v) mp(%v) ino(%v) err(%v)", packet, mp, inode, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("addFingerprints: packet(%v) mp(%v) ino(%v) result(%v)", packet, mp, inode, packet.GetResultMsg())
+		return
+	}
+	log.LogDebugf("addFingerprints exit: packet(%v) mp(%v) ino(%v) chunks(%v)", packet, mp, inode, len(chunks))
+	return
+}
+
+func (mw *MetaWrapper) cloneSnapshotInode(mp *MetaPartition, inode, verSeq uint64) (status int, info *proto.InodeInfo, err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("cloneSnapshotInode", err, bgTime, 1)
+	}()
+
+	req := &proto.CloneSnapshotInodeRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Inode:       inode,
+		VerSeq:      verSeq,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaCloneSnapshotInode
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("cloneSnapshotInode: packet(%v) mp(%v) ino(%v) ver(%v) err(%v)", packet, mp, inode, verSeq, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("cloneSnapshotInode: packet(%v) mp(%v) ino(%v) ver(%v) result(%v)", packet, mp, inode, verSeq, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.CloneSnapshotInodeResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("cloneSnapshotInode: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
+		return
+	}
+	if resp.Info == nil {
+		err = fmt.Errorf("cloneSnapshotInode: info is nil, packet(%v) mp(%v) ino(%v)", packet, mp, inode)
+		log.LogError(err)
+		return
+	}
+	info = resp.Info
+	log.LogDebugf("cloneSnapshotInode exit: packet(%v) mp(%v) ino(%v) ver(%v) info(%v)", packet, mp, inode, verSeq, info)
+	return
+}
+
+func (mw *MetaWrapper) snapshotDiff(mp *MetaPartition, req *proto.SnapshotDiffRequest) (status int, resp *proto.SnapshotDiffResponse, err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("snapshotDiff", err, bgTime, 1)
+	}()
+
+	req.VolName = mw.volname
+	req.PartitionID = mp.PartitionID
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaSnapshotDiff
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("snapshotDiff: packet(%v) mp(%v) scan(%v) start(%v) err(%v)", packet, mp, req.Scan, req.Start, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("snapshotDiff: packet(%v) mp(%v) scan(%v) start(%v) result(%v)", packet, mp, req.Scan, req.Start, packet.GetResultMsg())
+		return
+	}
+
+	resp = new(proto.SnapshotDiffResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("snapshotDiff: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
+		return
+	}
+	log.LogDebugf("snapshotDiff exit: packet(%v) mp(%v) scan(%v) start(%v) entries(%v) modified(%v) next(%v)",
+		packet, mp, req.Scan, req.Start, len(resp.Entries), len(resp.Modified), resp.Next)
+	return
+}
+
+func (mw *MetaWrapper) cdcRead(mp *MetaPartition, req *proto.CDCReadRequest) (status int, resp *proto.CDCReadResponse, err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("cdcRead", err, bgTime, 1)
+	}()
+
+	req.VolName = mw.volname
+	req.PartitionID = mp.PartitionID
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaCDCRead
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("cdcRead: packet(%v) mp(%v) from(%v) err(%v)", packet, mp, req.From, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("cdcRead: packet(%v) mp(%v) from(%v) result(%v)", packet, mp, req.From, packet.GetResultMsg())
+		return
+	}
+
+	resp = new(proto.CDCReadResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("cdcRead: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
+		return
+	}
+	log.LogDebugf("cdcRead exit: packet(%v) mp(%v) from(%v) events(%v) next(%v)",
+		packet, mp, req.From, len(resp.Events), resp.Next)
+	return
+}
+
+func (mw *MetaWrapper) transitionExtents(mp *MetaPartition, inode, gen uint64, oeks []proto.ObjExtentKey) (status int, err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("transitionExtents", err, bgTime, 1)
+	}()
+
+	req := &proto.TransitionExtentsRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Inode:       inode,
+		Generation:  gen,
+		ObjExtents:  oeks,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaTransitionExtents
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("transitionExtents: packet(%v) mp(%v) ino(%v) err(%v)", packet, mp, inode, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("transitionExtents: packet(%v) mp(%v) ino(%v) result(%v)", packet, mp, inode, packet.GetResultMsg())
+		return
+	}
+	log.LogDebugf("transitionExtents exit: packet(%v) mp(%v) ino(%v) gen(%v) oeks(%v)", packet, mp, inode, gen, len(oeks))
+	return
+}
+
+func (mw *MetaWrapper) restoreExtents(mp *MetaPartition, inode uint64) (status int, err error) {
+	bgTime := stat.BeginStat()
+	defer func() {
+		stat.EndStat("restoreExtents", err, bgTime, 1)
+	}()
+
+	req := &proto.RestoreExtentsRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Inode:       inode,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaRestoreExtents
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("restoreExtents: packet(%v) mp(%v) ino(%v) err(%v)", packet, mp, inode, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("restoreExtents: packet(%v) mp(%v) ino(%v) result(%v)", packet, mp, inode, packet.GetResultMsg())
+		return
+	}
+	log.LogDebugf("restoreExtents exit: packet(%v) mp(%v) ino(%v)", packet, mp, inode)
+	return
+}
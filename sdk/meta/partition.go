@@ -121,6 +121,18 @@ func (mw *MetaWrapper) getRWPartitions() []*MetaPartition {
 	return rwPartitions
 }
 
+// getPartitions returns all the partitions in the order of the inode ranges.
+func (mw *MetaWrapper) getPartitions() []*MetaPartition {
+	mw.RLock()
+	defer mw.RUnlock()
+	partitions := make([]*MetaPartition, 0, mw.ranges.Len())
+	mw.ranges.Ascend(func(i btree.Item) bool {
+		partitions = append(partitions, i.(*MetaPartition))
+		return true
+	})
+	return partitions
+}
+
 // GetConnect the partition whose Start is Larger than ino.
 // Return nil if no successive partition.
 func (mw *MetaWrapper) getNextPartition(ino uint64) *MetaPartition {
@@ -0,0 +1,145 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"syscall"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// PosixACLEnabled checks whether the POSIX ACLs are enabled on the volume or by the client.
+func (mw *MetaWrapper) PosixACLEnabled() bool {
+	return mw.EnablePosixACL || mw.volEnablePosixAcl
+}
+
+// parentDefaultACL returns the default ACL of the parent to be inherited by the new inode,
+// or nil if there is none. Symlinks never inherit ACLs.
+func (mw *MetaWrapper) parentDefaultACL(parentID uint64, mode uint32) []byte {
+	if !mw.PosixACLEnabled() || parentID == 0 || proto.IsSymlink(mode) {
+		return nil
+	}
+	xattr, err := mw.XAttrGet_ll(parentID, proto.XAttrPosixACLDefault)
+	if err != nil {
+		log.LogWarnf("parentDefaultACL: get default acl of parent(%v) failed, err(%v)", parentID, err)
+		return nil
+	}
+	if value := xattr.XAttrs[proto.XAttrPosixACLDefault]; value != "" {
+		return []byte(value)
+	}
+	return nil
+}
+
+// GetPosixACL_ll returns the access or default ACL of the inode, or nil if it is not set.
+func (mw *MetaWrapper) GetPosixACL_ll(inode uint64, name string) (*proto.PosixACL, error) {
+	xattr, err := mw.XAttrGet_ll(inode, name)
+	if err != nil {
+		return nil, err
+	}
+	value := xattr.XAttrs[name]
+	if value == "" {
+		return nil, nil
+	}
+	acl, err := proto.UnmarshalPosixACL([]byte(value))
+	if err != nil {
+		log.LogErrorf("GetPosixACL_ll: ino(%v) name(%v) invalid acl, err(%v)", inode, name, err)
+		return nil, syscall.EIO
+	}
+	return acl, nil
+}
+
+// SetPosixACL_ll sets or removes with a nil acl the access or default ACL of the inode.
+// The permission bits of the inode are updated by the access ACL, which is only kept if
+// it can not be represented by the permission bits.
+func (mw *MetaWrapper) SetPosixACL_ll(inode uint64, name string, acl *proto.PosixACL) (err error) {
+	if !proto.IsPosixACLXAttr(name) {
+		return syscall.EINVAL
+	}
+	if acl != nil {
+		if err = acl.Validate(); err != nil {
+			log.LogWarnf("SetPosixACL_ll: ino(%v) name(%v) invalid acl, err(%v)", inode, name, err)
+			return syscall.EINVAL
+		}
+	}
+
+	if name == proto.XAttrPosixACLAccess && acl != nil {
+		var info *proto.InodeInfo
+		if info, err = mw.InodeGet_ll(inode); err != nil {
+			return
+		}
+		mode := info.Mode&^0o777 | acl.Mode()
+		if mode != info.Mode {
+			if err = mw.Setattr(inode, proto.AttrMode, mode, 0, 0, 0, 0); err != nil {
+				return
+			}
+		}
+		if acl.IsEquivalentMode() {
+			acl = nil
+		}
+	}
+	if name == proto.XAttrPosixACLDefault && acl != nil {
+		var info *proto.InodeInfo
+		if info, err = mw.InodeGet_ll(inode); err != nil {
+			return
+		}
+		if !proto.IsDir(info.Mode) {
+			return syscall.EACCES
+		}
+	}
+
+	if acl == nil {
+		return mw.XAttrDel_ll(inode, name)
+	}
+	data, err := acl.Marshal()
+	if err != nil {
+		return
+	}
+	return mw.XAttrSet_ll(inode, []byte(name), data)
+}
+
+// ChmodPosixACL_ll updates the access ACL of the inode by the permission bits set by chmod.
+func (mw *MetaWrapper) ChmodPosixACL_ll(inode uint64, mode uint32) (err error) {
+	if !mw.PosixACLEnabled() {
+		return nil
+	}
+	acl, err := mw.GetPosixACL_ll(inode, proto.XAttrPosixACLAccess)
+	if err != nil || acl == nil {
+		return
+	}
+	acl.Chmod(mode)
+	data, err := acl.Marshal()
+	if err != nil {
+		return
+	}
+	return mw.XAttrSet_ll(inode, []byte(proto.XAttrPosixACLAccess), data)
+}
+
+// CheckPermission_ll checks the wanted permissions of the user on the inode by the access
+// ACL and the permission bits. syscall.EACCES is returned if the user is not granted.
+func (mw *MetaWrapper) CheckPermission_ll(info *proto.InodeInfo, uid uint32, gids []uint32, want uint16) (err error) {
+	var acl *proto.PosixACL
+	if mw.PosixACLEnabled() {
+		if acl, err = mw.GetPosixACL_ll(info.Inode, proto.XAttrPosixACLAccess); err != nil {
+			return
+		}
+	}
+	if !proto.CheckPosixPermission(info.Mode, info.Uid, info.Gid, uid, gids, want, acl) {
+		log.LogDebugf("CheckPermission_ll: ino(%v) mode(%o) uid(%v) gids(%v) want(%v) denied",
+			info.Inode, info.Mode, uid, gids, want)
+		return syscall.EACCES
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"path"
+	"syscall"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const cloneSnapshotReadDirLimit = 1024
+
+// CloneSnapshotInode_ll creates a writable inode from the version of the inode, the new inode refers
+// to the data of the version without copying it and is not linked to any directory.
+func (mw *MetaWrapper) CloneSnapshotInode_ll(inode, verSeq uint64) (*proto.InodeInfo, error) {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("CloneSnapshotInode_ll: No inode partition, ino(%v)", inode)
+		return nil, syscall.ENOENT
+	}
+
+	status, info, err := mw.cloneSnapshotInode(mp, inode, verSeq)
+	if err != nil || status != statusOK {
+		return nil, statusErrToErrno(status, err)
+	}
+	return info, nil
+}
+
+type snapshotCloner struct {
+	mw    *MetaWrapper
+	snap  *MetaWrapper
+	stat  *proto.CloneSnapshotStat
+	links map[uint64]uint64 // inode of the snapshot -> inode cloned, for the hard links
+}
+
+// CloneSnapshotTree clones the tree of srcIno read by snap, which reads the snapshot by VerReadSeq,
+// to the entry name under dstParent of the current version. The directories are created and the
+// other inodes are cloned by the meta partitions, so the data is shared until it is overwritten.
+func (mw *MetaWrapper) CloneSnapshotTree(snap *MetaWrapper, srcIno, dstParent uint64, name, fullPath string) (*proto.CloneSnapshotStat, error) {
+	info, err := snap.InodeGet_ll(srcIno)
+	if err != nil {
+		log.LogErrorf("CloneSnapshotTree: get snapshot inode failed, ino(%v) ver(%v) err(%v)", srcIno, snap.VerReadSeq, err)
+		return nil, err
+	}
+	c := &snapshotCloner{
+		mw:    mw,
+		snap:  snap,
+		stat:  &proto.CloneSnapshotStat{},
+		links: make(map[uint64]uint64),
+	}
+	if err = c.clone(info, dstParent, name, fullPath); err != nil {
+		return c.stat, err
+	}
+	log.LogInfof("CloneSnapshotTree: ino(%v) ver(%v) cloned to %v, stat(%v)", srcIno, snap.VerReadSeq, fullPath, *c.stat)
+	return c.stat, nil
+}
+
+func (c *snapshotCloner) clone(info *proto.InodeInfo, parent uint64, name, fullPath string) (err error) {
+	if proto.IsDir(info.Mode) {
+		return c.cloneDir(info, parent, name, fullPath)
+	}
+
+	if ino, ok := c.links[info.Inode]; ok {
+		if _, err = c.mw.Link(parent, name, ino, fullPath); err != nil {
+			log.LogErrorf("snapshotCloner: link failed, path(%v) ino(%v) err(%v)", fullPath, ino, err)
+			return
+		}
+		c.stat.LinkNum++
+		return
+	}
+
+	newInfo, err := c.mw.CloneSnapshotInode_ll(info.Inode, c.snap.VerReadSeq)
+	if err != nil {
+		log.LogErrorf("snapshotCloner: clone inode failed, path(%v) ino(%v) err(%v)", fullPath, info.Inode, err)
+		return
+	}
+	if err = c.mw.DentryCreate_ll(parent, name, newInfo.Inode, newInfo.Mode, fullPath); err != nil {
+		log.LogErrorf("snapshotCloner: create dentry failed, path(%v) ino(%v) err(%v)", fullPath, newInfo.Inode, err)
+		c.mw.InodeUnlink_ll(newInfo.Inode, fullPath)
+		c.mw.Evict(newInfo.Inode, fullPath)
+		return
+	}
+	if info.Nlink > 1 {
+		c.links[info.Inode] = newInfo.Inode
+	}
+	if proto.IsSymlink(info.Mode) {
+		c.stat.LinkNum++
+	} else {
+		c.stat.FileNum++
+		c.stat.TotalBytes += int64(newInfo.Size)
+	}
+	return
+}
+
+func (c *snapshotCloner) cloneDir(info *proto.InodeInfo, parent uint64, name, fullPath string) (err error) {
+	dir, err := c.mw.Create_ll(parent, name, info.Mode, info.Uid, info.Gid, nil, fullPath)
+	if err != nil {
+		log.LogErrorf("snapshotCloner: create dir failed, path(%v) err(%v)", fullPath, err)
+		return
+	}
+	c.stat.DirNum++
+	// the xattrs of the directories are not versioned by the reader, the current ones are copied
+	if xattrs, e := c.mw.XAttrGetAll_ll(info.Inode); e == nil {
+		delete(xattrs.XAttrs, proto.QuotaKey)
+		if len(xattrs.XAttrs) > 0 {
+			if e = c.mw.BatchSetXAttr_ll(dir.Inode, xattrs.XAttrs); e != nil {
+				log.LogWarnf("snapshotCloner: set xattrs failed, path(%v) err(%v)", fullPath, e)
+			}
+		}
+	}
+
+	from := ""
+	for {
+		children, err := c.snap.ReadDirLimit_ll(info.Inode, from, cloneSnapshotReadDirLimit)
+		if err != nil {
+			log.LogErrorf("snapshotCloner: read dir failed, ino(%v) ver(%v) err(%v)", info.Inode, c.snap.VerReadSeq, err)
+			return err
+		}
+		fetched := len(children)
+		if from != "" && fetched > 0 && children[0].Name == from {
+			children = children[1:]
+		}
+		for _, child := range children {
+			childInfo, err := c.snap.InodeGet_ll(child.Inode)
+			if err == syscall.ENOENT {
+				continue
+			}
+			if err != nil {
+				log.LogErrorf("snapshotCloner: get inode failed, ino(%v) ver(%v) err(%v)", child.Inode, c.snap.VerReadSeq, err)
+				return err
+			}
+			if err = c.clone(childInfo, dir.Inode, child.Name, path.Join(fullPath, child.Name)); err != nil {
+				return err
+			}
+		}
+		if fetched < cloneSnapshotReadDirLimit {
+			return nil
+		}
+		from = children[len(children)-1].Name
+	}
+}
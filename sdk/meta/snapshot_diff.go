@@ -0,0 +1,201 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+const (
+	snapshotDiffPageLimit   = 10000
+	snapshotDiffLookupBatch = 10000
+	snapshotDiffMaxDepth    = 4096
+)
+
+type diffDirEntry struct {
+	parent uint64
+	name   string
+}
+
+// diffPaths builds the paths of a version from the dentries of the directories.
+type diffPaths map[uint64]diffDirEntry
+
+func (dirs diffPaths) path(parent uint64, name string) string {
+	elems := []string{name}
+	for depth := 0; parent != proto.RootIno; depth++ {
+		dir, ok := dirs[parent]
+		if !ok || depth >= snapshotDiffMaxDepth {
+			// the parent is not reachable from the root
+			elems = append(elems, fmt.Sprintf("<ino %v>", parent))
+			break
+		}
+		elems = append(elems, dir.name)
+		parent = dir.parent
+	}
+	for i, j := 0, len(elems)-1; i < j; i, j = i+1, j-1 {
+		elems[i], elems[j] = elems[j], elems[i]
+	}
+	return "/" + path.Join(elems...)
+}
+
+// scanSnapshotDiff pages through the scan of all the partitions.
+func (mw *MetaWrapper) scanSnapshotDiff(req *proto.SnapshotDiffRequest, fn func(resp *proto.SnapshotDiffResponse)) error {
+	for _, mp := range mw.getPartitions() {
+		req.Start = 0
+		for {
+			status, resp, err := mw.snapshotDiff(mp, req)
+			if err != nil || status != statusOK {
+				return statusErrToErrno(status, err)
+			}
+			fn(resp)
+			if resp.Next == 0 {
+				break
+			}
+			req.Start = resp.Next
+		}
+	}
+	return nil
+}
+
+// SnapshotDiff lists the paths created, modified, deleted and renamed from the version fromVer to
+// toVer, and toVer 0 is the current version. The meta partitions compare the versions of the inodes
+// and the dentries, so the tree is not walked. The items are passed to fn in the order of the paths.
+func (mw *MetaWrapper) SnapshotDiff(fromVer, toVer uint64, fn func(item *proto.SnapshotDiffItem) bool) (err error) {
+	req := &proto.SnapshotDiffRequest{FromVer: fromVer, ToVer: toVer, Limit: snapshotDiffPageLimit}
+
+	req.Scan = proto.SnapshotDiffScanInode
+	var modified []uint64
+	if err = mw.scanSnapshotDiff(req, func(resp *proto.SnapshotDiffResponse) {
+		modified = append(modified, resp.Modified...)
+	}); err != nil {
+		log.LogErrorf("SnapshotDiff: scan inodes failed, from(%v) to(%v) err(%v)", fromVer, toVer, err)
+		return
+	}
+	sort.Slice(modified, func(i, j int) bool { return modified[i] < modified[j] })
+
+	req.Scan = proto.SnapshotDiffScanDentry
+	var entries []proto.SnapshotDiffEntry
+	if err = mw.scanSnapshotDiff(req, func(resp *proto.SnapshotDiffResponse) {
+		entries = append(entries, resp.Entries...)
+	}); err != nil {
+		log.LogErrorf("SnapshotDiff: scan dentries failed, from(%v) to(%v) err(%v)", fromVer, toVer, err)
+		return
+	}
+
+	req.Scan = proto.SnapshotDiffScanLookup
+	for start := 0; start < len(modified); start += snapshotDiffLookupBatch {
+		end := start + snapshotDiffLookupBatch
+		if end > len(modified) {
+			end = len(modified)
+		}
+		req.Inodes = modified[start:end]
+		if err = mw.scanSnapshotDiff(req, func(resp *proto.SnapshotDiffResponse) {
+			entries = append(entries, resp.Entries...)
+		}); err != nil {
+			log.LogErrorf("SnapshotDiff: lookup inodes failed, from(%v) to(%v) err(%v)", fromVer, toVer, err)
+			return
+		}
+	}
+
+	items := buildSnapshotDiffItems(entries, modified)
+	log.LogInfof("SnapshotDiff: vol(%v) from(%v) to(%v) entries(%v) modified(%v) items(%v)",
+		mw.volname, fromVer, toVer, len(entries), len(modified), len(items))
+	for _, item := range items {
+		if !fn(item) {
+			break
+		}
+	}
+	return nil
+}
+
+// buildSnapshotDiffItems resolves the paths of the dentries changed, the dentries of an inode deleted
+// and created are paired as renamed.
+func buildSnapshotDiffItems(entries []proto.SnapshotDiffEntry, modified []uint64) (items []*proto.SnapshotDiffItem) {
+	fromDirs, toDirs := make(diffPaths), make(diffPaths)
+	deleted := make(map[uint64][]proto.SnapshotDiffEntry)
+	for _, e := range entries {
+		if !proto.IsDir(e.Type) {
+			if e.Op == proto.SnapshotDiffDeleted {
+				deleted[e.Inode] = append(deleted[e.Inode], e)
+			}
+			continue
+		}
+		switch e.Op {
+		case proto.SnapshotDiffDir:
+			fromDirs[e.Inode] = diffDirEntry{e.ParentId, e.Name}
+			toDirs[e.Inode] = diffDirEntry{e.ParentId, e.Name}
+		case proto.SnapshotDiffDeleted:
+			fromDirs[e.Inode] = diffDirEntry{e.ParentId, e.Name}
+			deleted[e.Inode] = append(deleted[e.Inode], e)
+		case proto.SnapshotDiffCreated:
+			toDirs[e.Inode] = diffDirEntry{e.ParentId, e.Name}
+		}
+	}
+
+	isModified := func(ino uint64) bool {
+		idx := sort.Search(len(modified), func(i int) bool { return modified[i] >= ino })
+		return idx < len(modified) && modified[idx] == ino
+	}
+	for _, e := range entries {
+		switch e.Op {
+		case proto.SnapshotDiffCreated:
+			item := &proto.SnapshotDiffItem{
+				Op:    proto.SnapshotDiffCreated,
+				Path:  toDirs.path(e.ParentId, e.Name),
+				Inode: e.Inode,
+				Type:  e.Type,
+			}
+			if olds := deleted[e.Inode]; len(olds) > 0 {
+				old := olds[0]
+				deleted[e.Inode] = olds[1:]
+				item.Op = proto.SnapshotDiffRenamed
+				item.OldPath = fromDirs.path(old.ParentId, old.Name)
+				if isModified(e.Inode) {
+					items = append(items, &proto.SnapshotDiffItem{Op: proto.SnapshotDiffModified, Path: item.Path, Inode: e.Inode, Type: e.Type})
+				}
+			}
+			items = append(items, item)
+		case proto.SnapshotDiffModified:
+			items = append(items, &proto.SnapshotDiffItem{
+				Op:    proto.SnapshotDiffModified,
+				Path:  toDirs.path(e.ParentId, e.Name),
+				Inode: e.Inode,
+				Type:  e.Type,
+			})
+		}
+	}
+	for _, olds := range deleted {
+		for _, e := range olds {
+			items = append(items, &proto.SnapshotDiffItem{
+				Op:    proto.SnapshotDiffDeleted,
+				Path:  fromDirs.path(e.ParentId, e.Name),
+				Inode: e.Inode,
+				Type:  e.Type,
+			})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Path != items[j].Path {
+			return items[i].Path < items[j].Path
+		}
+		return items[i].Op < items[j].Op
+	})
+	return
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSnapshotDiffItems(t *testing.T) {
+	dirMode := proto.Mode(os.ModeDir | 0o755)
+	fileMode := proto.Mode(0o644)
+	entries := []proto.SnapshotDiffEntry{
+		{Op: proto.SnapshotDiffDir, ParentId: proto.RootIno, Name: "a", Inode: 10, Type: dirMode},
+		{Op: proto.SnapshotDiffDeleted, ParentId: proto.RootIno, Name: "b", Inode: 11, Type: dirMode},
+		{Op: proto.SnapshotDiffCreated, ParentId: 10, Name: "c", Inode: 11, Type: dirMode},
+		{Op: proto.SnapshotDiffCreated, ParentId: 11, Name: "new", Inode: 20, Type: fileMode},
+		{Op: proto.SnapshotDiffDeleted, ParentId: 11, Name: "old", Inode: 21, Type: fileMode},
+		{Op: proto.SnapshotDiffModified, ParentId: 10, Name: "mod", Inode: 22, Type: fileMode},
+	}
+	items := buildSnapshotDiffItems(entries, []uint64{22})
+
+	got := make([][3]string, 0, len(items))
+	for _, item := range items {
+		got = append(got, [3]string{proto.SnapshotDiffOpString(item.Op), item.Path, item.OldPath})
+	}
+	assert.Equal(t, [][3]string{
+		{"renamed", "/a/c", "/b"},
+		{"created", "/a/c/new", ""},
+		{"modified", "/a/mod", ""},
+		{"deleted", "/b/old", ""},
+	}, got)
+}
+
+func TestDiffPathsUnreachable(t *testing.T) {
+	dirs := diffPaths{10: {parent: 9, name: "a"}}
+	assert.Equal(t, "/<ino 9>/a/f", dirs.path(10, "f"))
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"syscall"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// TransitionExtents replaces the extents of the inode with the object extents the data is copied to in the
+// blobstore, ENOTSUP is returned if the inode is modified since the generation.
+func (mw *MetaWrapper) TransitionExtents(inode, gen uint64, oeks []proto.ObjExtentKey) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("TransitionExtents: No inode partition, ino(%v)", inode)
+		return syscall.ENOENT
+	}
+
+	status, err := mw.transitionExtents(mp, inode, gen, oeks)
+	if err != nil || status != statusOK {
+		return statusErrToErrno(status, err)
+	}
+	return nil
+}
+
+// RestoreExtents drops the object extents of the inode once the data of them is written back to the extents,
+// ENOTSUP is returned if the extents do not cover the data yet.
+func (mw *MetaWrapper) RestoreExtents(inode uint64) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("RestoreExtents: No inode partition, ino(%v)", inode)
+		return syscall.ENOENT
+	}
+
+	status, err := mw.restoreExtents(mp, inode)
+	if err != nil || status != statusOK {
+		return statusErrToErrno(status, err)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"os"
+	gopath "path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cubefs/cubefs/proto"
+	"github.com/cubefs/cubefs/util/log"
+)
+
+// trashBucketCache caches the inode of the current trash bucket.
+type trashBucketCache struct {
+	sync.Mutex
+	name  string
+	inode uint64
+}
+
+// TrashEnabled checks whether the deleted files of the volume are moved to the trash.
+func (mw *MetaWrapper) TrashEnabled() bool {
+	return mw.volTrashInterval > 0
+}
+
+// IsTrashPath checks whether the path from the volume root is in the trash.
+func IsTrashPath(fullPath string) bool {
+	p := gopath.Clean("/" + fullPath)
+	trash := "/" + proto.TrashDirName
+	return p == trash || strings.HasPrefix(p, trash+"/")
+}
+
+// MoveToTrash_ll moves the non-directory entry to the current trash bucket instead of
+// deleting it. The entry is renamed to name_ino in the bucket, and the path from the
+// volume root and the delete time are kept in the xattrs of the inode.
+func (mw *MetaWrapper) MoveToTrash_ll(parentID uint64, name string, fullPath string) (err error) {
+	ino, mode, err := mw.Lookup_ll(parentID, name)
+	if err != nil {
+		return
+	}
+	if proto.IsDir(mode) {
+		return syscall.EISDIR
+	}
+
+	now := time.Now()
+	bucket := proto.TrashBucketName(now)
+	bucketIno, err := mw.getTrashBucket(bucket)
+	if err != nil {
+		log.LogErrorf("MoveToTrash_ll: get trash bucket(%v) failed, err(%v)", bucket, err)
+		return
+	}
+
+	attrs := map[string]string{
+		proto.TrashXAttrOrigPath:   gopath.Clean("/" + fullPath),
+		proto.TrashXAttrDeleteTime: strconv.FormatInt(now.Unix(), 10),
+	}
+	if err = mw.BatchSetXAttr_ll(ino, attrs); err != nil {
+		log.LogErrorf("MoveToTrash_ll: set xattrs of ino(%v) failed, err(%v)", ino, err)
+		return
+	}
+
+	dstName := fmt.Sprintf("%s_%d", name, ino)
+	dstPath := gopath.Join("/", proto.TrashDirName, bucket, dstName)
+	if err = mw.Rename_ll(parentID, name, bucketIno, dstName, fullPath, dstPath, false); err != nil {
+		log.LogErrorf("MoveToTrash_ll: rename(%v) to (%v) failed, err(%v)", fullPath, dstPath, err)
+		mw.trashBucket.Lock()
+		mw.trashBucket.name = ""
+		mw.trashBucket.Unlock()
+		return
+	}
+	log.LogDebugf("MoveToTrash_ll: move (%v) ino(%v) to (%v)", fullPath, ino, dstPath)
+	return
+}
+
+func (mw *MetaWrapper) getTrashBucket(bucket string) (ino uint64, err error) {
+	mw.trashBucket.Lock()
+	defer mw.trashBucket.Unlock()
+	if mw.trashBucket.name == bucket {
+		return mw.trashBucket.inode, nil
+	}
+
+	trashIno, err := mw.lookupOrMkdir(proto.RootIno, proto.TrashDirName, "/"+proto.TrashDirName)
+	if err != nil {
+		return
+	}
+	if ino, err = mw.lookupOrMkdir(trashIno, bucket, gopath.Join("/", proto.TrashDirName, bucket)); err != nil {
+		return
+	}
+	mw.trashBucket.name = bucket
+	mw.trashBucket.inode = ino
+	return
+}
+
+func (mw *MetaWrapper) lookupOrMkdir(parentID uint64, name string, fullPath string) (ino uint64, err error) {
+	ino, _, err = mw.Lookup_ll(parentID, name)
+	if err != syscall.ENOENT {
+		return
+	}
+	info, err := mw.Create_ll(parentID, name, proto.Mode(os.ModeDir|0755), 0, 0, nil, fullPath)
+	if err == syscall.EEXIST {
+		ino, _, err = mw.Lookup_ll(parentID, name)
+		return
+	}
+	if err != nil {
+		return
+	}
+	return info.Inode, nil
+}
+
+// ListTrash_ll lists the entries in all the trash buckets.
+func (mw *MetaWrapper) ListTrash_ll() (entries []*proto.TrashEntry, err error) {
+	entries = make([]*proto.TrashEntry, 0)
+	trashIno, _, err := mw.Lookup_ll(proto.RootIno, proto.TrashDirName)
+	if err == syscall.ENOENT {
+		return entries, nil
+	}
+	if err != nil {
+		return
+	}
+
+	buckets, err := mw.ReadDir_ll(trashIno)
+	if err != nil {
+		return
+	}
+	for _, bucket := range buckets {
+		if !proto.IsDir(bucket.Type) {
+			continue
+		}
+		var children []proto.Dentry
+		if children, err = mw.ReadDir_ll(bucket.Inode); err != nil {
+			return
+		}
+		if len(children) == 0 {
+			continue
+		}
+
+		inodes := make([]uint64, 0, len(children))
+		for _, child := range children {
+			inodes = append(inodes, child.Inode)
+		}
+		var xattrs []*proto.XAttrInfo
+		if xattrs, err = mw.BatchGetXAttr(inodes, []string{proto.TrashXAttrOrigPath, proto.TrashXAttrDeleteTime}); err != nil {
+			return
+		}
+		xattrMap := make(map[uint64]*proto.XAttrInfo, len(xattrs))
+		for _, xattr := range xattrs {
+			xattrMap[xattr.Inode] = xattr
+		}
+
+		for _, child := range children {
+			entry := &proto.TrashEntry{
+				Bucket: bucket.Name,
+				Name:   child.Name,
+				Inode:  child.Inode,
+				IsDir:  proto.IsDir(child.Type),
+			}
+			if xattr, ok := xattrMap[child.Inode]; ok {
+				entry.OrigPath = xattr.XAttrs[proto.TrashXAttrOrigPath]
+				entry.DeleteTime, _ = strconv.ParseInt(xattr.XAttrs[proto.TrashXAttrDeleteTime], 10, 64)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return
+}
+
+// RestoreTrash_ll moves the entry in the trash bucket back to dstPath, or to the original
+// path if dstPath is empty. The missing parent directories are created, and the existing
+// entry of dstPath is never overwritten. The path restored to is returned.
+func (mw *MetaWrapper) RestoreTrash_ll(bucket, name, dstPath string) (restored string, err error) {
+	trashIno, _, err := mw.Lookup_ll(proto.RootIno, proto.TrashDirName)
+	if err != nil {
+		return
+	}
+	bucketIno, _, err := mw.Lookup_ll(trashIno, bucket)
+	if err != nil {
+		return
+	}
+	ino, _, err := mw.Lookup_ll(bucketIno, name)
+	if err != nil {
+		return
+	}
+
+	if dstPath == "" {
+		var xattr *proto.XAttrInfo
+		if xattr, err = mw.XAttrGet_ll(ino, proto.TrashXAttrOrigPath); err != nil {
+			return
+		}
+		if dstPath = xattr.XAttrs[proto.TrashXAttrOrigPath]; dstPath == "" {
+			log.LogWarnf("RestoreTrash_ll: no original path of (%v/%v) ino(%v)", bucket, name, ino)
+			return "", syscall.EINVAL
+		}
+	}
+	restored = gopath.Clean("/" + dstPath)
+	if restored == "/" || IsTrashPath(restored) {
+		return "", syscall.EINVAL
+	}
+
+	parentIno := proto.RootIno
+	parentPath := "/"
+	for _, dir := range strings.Split(gopath.Dir(restored), "/") {
+		if dir == "" {
+			continue
+		}
+		parentPath = gopath.Join(parentPath, dir)
+		if parentIno, err = mw.lookupOrMkdir(parentIno, dir, parentPath); err != nil {
+			log.LogErrorf("RestoreTrash_ll: make parent dir(%v) failed, err(%v)", parentPath, err)
+			return
+		}
+	}
+
+	srcPath := gopath.Join("/", proto.TrashDirName, bucket, name)
+	if err = mw.Rename_ll(bucketIno, name, parentIno, gopath.Base(restored), srcPath, restored, false); err != nil {
+		log.LogErrorf("RestoreTrash_ll: rename(%v) to (%v) failed, err(%v)", srcPath, restored, err)
+		return
+	}
+	for _, key := range []string{proto.TrashXAttrOrigPath, proto.TrashXAttrDeleteTime} {
+		if e := mw.XAttrDel_ll(ino, key); e != nil {
+			log.LogWarnf("RestoreTrash_ll: remove xattr(%v) of ino(%v) failed, err(%v)", key, ino, e)
+		}
+	}
+	log.LogInfof("RestoreTrash_ll: restore (%v) ino(%v) to (%v)", srcPath, ino, restored)
+	return
+}
@@ -44,6 +44,9 @@ type VolumeView struct {
 	OSSSecure      *OSSSecure
 	CreateTime     int64
 	DeleteLockTime int64
+	TrashInterval  int64
+	EnablePosixAcl bool
+	Encryption     bool
 }
 
 type OSSSecure struct {
@@ -99,6 +102,9 @@ func (mw *MetaWrapper) fetchVolumeView() (view *VolumeView, err error) {
 			OSSSecure:      &OSSSecure{},
 			CreateTime:     volView.CreateTime,
 			DeleteLockTime: volView.DeleteLockTime,
+			TrashInterval:  volView.TrashInterval,
+			EnablePosixAcl: volView.EnablePosixAcl,
+			Encryption:     volView.EnableEncryption,
 		}
 		if volView.OSSSecure != nil {
 			result.OSSSecure.AccessKey = volView.OSSSecure.AccessKey
@@ -208,6 +214,9 @@ func (mw *MetaWrapper) updateMetaPartitions() error {
 	mw.ossSecure = view.OSSSecure
 	mw.volCreateTime = view.CreateTime
 	mw.volDeleteLockTime = view.DeleteLockTime
+	mw.volTrashInterval = view.TrashInterval
+	mw.volEnablePosixAcl = view.EnablePosixAcl
+	mw.volEncryption = view.Encryption
 
 	if len(rwPartitions) == 0 {
 		log.LogInfof("updateMetaPartition: no rw partitions")
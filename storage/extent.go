@@ -290,6 +290,37 @@ func (e *Extent) WriteTiny(data []byte, offset, size int64, crc uint32, writeTyp
 	return
 }
 
+// Preallocate reserves the space of the new created extent, the data of it is zeros of the size.
+// The last page is written since the unwritten blocks are holes for SEEK_DATA, or the size of the
+// extent would be lost once it is loaded from the disk again. The zeros are written for all the
+// size if the file system could not allocate the blocks.
+func (e *Extent) Preallocate(size int64) (err error) {
+	e.Lock()
+	defer e.Unlock()
+	if IsTinyExtent(e.extentID) || e.dataSize != 0 || size <= 0 || size > util.ExtentSize {
+		return newParameterError("extent current size=%d preallocate size=%d", e.dataSize, size)
+	}
+	var start int64
+	if err = fallocate(int(e.file.Fd()), 0, 0, size); err == nil {
+		start = (size - 1) / util.PageSize * util.PageSize
+	} else if err != syscall.EOPNOTSUPP {
+		return
+	}
+	zeros := make([]byte, util.BlockSize)
+	for offset := start; offset < size; offset += util.BlockSize {
+		n := util.Min(util.BlockSize, int(size-offset))
+		if _, err = e.file.WriteAt(zeros[:n], offset); err != nil {
+			return
+		}
+	}
+	if err = e.file.Sync(); err != nil {
+		return
+	}
+	e.dataSize = size
+	atomic.StoreInt64(&e.modifyTime, time.Now().Unix())
+	return
+}
+
 // Write writes data to an extent.
 func (e *Extent) Write(data []byte, offset, size int64, crc uint32, writeType int, isSync bool, crcFunc UpdateCrcFunc, ei *ExtentInfo) (status uint8, err error) {
 	log.LogDebugf("action[Extent.Write] path %v offset %v size %v writeType %v", e.filePath, offset, size, writeType)
@@ -408,6 +408,23 @@ func (s *ExtentStore) Write(extentID uint64, offset, size int64, data []byte, cr
 	return status, nil
 }
 
+// Preallocate reserves the space of the extent created just now with the given size.
+func (s *ExtentStore) Preallocate(extentID uint64, size int64) (err error) {
+	s.eiMutex.Lock()
+	ei := s.extentInfoMap[extentID]
+	e, err := s.extentWithHeader(ei)
+	s.eiMutex.Unlock()
+	if err != nil {
+		return
+	}
+	if err = e.Preallocate(size); err != nil {
+		log.LogWarnf("action[Preallocate] path %v size %v err %v", e.filePath, size, err)
+		return
+	}
+	ei.UpdateExtentInfo(e, 0)
+	return
+}
+
 func (s *ExtentStore) checkOffsetAndSize(extentID uint64, offset, size int64, writeType int) error {
 	if IsTinyExtent(extentID) {
 		return nil
@@ -470,11 +487,28 @@ func (s *ExtentStore) DumpExtents() (extInfos SortedExtentInfos) {
 	return
 }
 
+// alignPunchRange shrinks the range to the whole pages in it.
+func alignPunchRange(offset, size int64) (int64, int64) {
+	end := (offset + size) / util.PageSize * util.PageSize
+	offset = (offset + util.PageSize - 1) / util.PageSize * util.PageSize
+	if end <= offset {
+		return offset, 0
+	}
+	return offset, end - offset
+}
+
 func (s *ExtentStore) punchDelete(extentID uint64, offset, size int64) (err error) {
 	e, err := s.extentWithHeaderByExtentID(extentID)
 	if err != nil {
 		return nil
 	}
+	if !IsTinyExtent(extentID) {
+		// the rest of a normal extent may be still referred by the punched or cloned
+		// files, so the pages across the boundaries are kept
+		if offset, size = alignPunchRange(offset, size); size == 0 {
+			return
+		}
+	}
 	if offset+size > e.dataSize {
 		return
 	}
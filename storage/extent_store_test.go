@@ -256,3 +256,44 @@ func TestExtentStores(t *testing.T) {
 		extentStoreTest(t, ty)
 	}
 }
+
+func TestExtentStorePreallocate(t *testing.T) {
+	path, clean, err := getTestPathExtentStore()
+	require.NoError(t, err)
+	defer clean()
+	s, err := storage.NewExtentStore(path, 0, 1*util.GB, proto.PartitionTypeNormal, true)
+	require.NoError(t, err)
+	id, err := s.NextExtentID()
+	require.NoError(t, err)
+	require.NoError(t, s.Create(id))
+	size := int64(util.BlockSize*3 + 100)
+	require.NoError(t, s.Preallocate(id, size))
+	// the extent is reserved only once
+	require.Error(t, s.Preallocate(id, size))
+
+	ei, err := s.Watermark(id)
+	require.NoError(t, err)
+	require.EqualValues(t, size, ei.Size)
+	data := make([]byte, size)
+	_, err = s.Read(id, 0, size, data, false)
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, size), data)
+
+	// the reserved range is overwritten in place
+	data = []byte(dataStr)
+	_, err = s.Write(id, util.BlockSize, int64(len(data)), data, crc32.ChecksumIEEE(data), storage.RandomWriteType, true)
+	require.NoError(t, err)
+	s.Close()
+
+	// the size is kept once the extent is loaded again
+	s, err = storage.NewExtentStore(path, 0, 1*util.GB, proto.PartitionTypeNormal, false)
+	require.NoError(t, err)
+	defer s.Close()
+	ei, err = s.Watermark(id)
+	require.NoError(t, err)
+	require.EqualValues(t, size, ei.Size)
+	buf := make([]byte, len(data))
+	_, err = s.Read(id, util.BlockSize, int64(len(buf)), buf, false)
+	require.NoError(t, err)
+	require.Equal(t, data, buf)
+}
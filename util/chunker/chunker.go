@@ -0,0 +1,79 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package chunker cuts the data into chunks by the content, as FastCDC does, so that the same data
+// is cut into the same chunks wherever it is in the files.
+package chunker
+
+// The sizes of the chunks, the cut points of the same data must be the same on all the clients, so
+// the sizes and the gear table must not be changed.
+const (
+	MinSize = 16 * 1024
+	AvgSize = 64 * 1024
+	MaxSize = 256 * 1024
+)
+
+const (
+	// the cut points before the average size are harder to be found than the ones after it,
+	// so that the sizes of the chunks are close to the average size
+	maskS = uint64(1<<18-1) << (64 - 18)
+	maskL = uint64(1<<14-1) << (64 - 14)
+)
+
+var gear [256]uint64
+
+func init() {
+	// splitmix64 with a fixed seed, the table is the same everywhere
+	seed := uint64(0x6375626566730000)
+	for i := range gear {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// Cut returns the size of the first chunk of the data and whether the chunk ends at a cut point.
+// The chunk does not end at a cut point if the data is exhausted before one is found, in which
+// case the chunk may be extended by the data following.
+func Cut(data []byte) (size int, cut bool) {
+	n := len(data)
+	if n <= MinSize {
+		return n, false
+	}
+	if n > MaxSize {
+		n = MaxSize
+	}
+	normal := AvgSize
+	if n < normal {
+		normal = n
+	}
+
+	var hash uint64
+	i := MinSize
+	for ; i < normal; i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&maskS == 0 {
+			return i + 1, true
+		}
+	}
+	for ; i < n; i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&maskL == 0 {
+			return i + 1, true
+		}
+	}
+	return n, n == MaxSize
+}
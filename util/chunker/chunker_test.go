@@ -0,0 +1,66 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package chunker_test
+
+import (
+	"crypto/sha256"
+	"math/rand"
+	"testing"
+
+	"github.com/cubefs/cubefs/util/chunker"
+	"github.com/stretchr/testify/require"
+)
+
+func cutAll(data []byte) (sums map[[sha256.Size]byte]struct{}, sizes []int) {
+	sums = make(map[[sha256.Size]byte]struct{})
+	for len(data) > 0 {
+		n, _ := chunker.Cut(data)
+		sums[sha256.Sum256(data[:n])] = struct{}{}
+		sizes = append(sizes, n)
+		data = data[n:]
+	}
+	return
+}
+
+func TestCut(t *testing.T) {
+	data := make([]byte, 16*1024*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	sums, sizes := cutAll(data)
+	for i, n := range sizes {
+		require.LessOrEqual(t, n, chunker.MaxSize)
+		if i != len(sizes)-1 {
+			require.GreaterOrEqual(t, n, chunker.MinSize)
+		}
+	}
+	avg := len(data) / len(sizes)
+	require.Greater(t, avg, chunker.AvgSize/2)
+	require.Less(t, avg, chunker.AvgSize*2)
+
+	// the chunks after the inserted bytes are the same
+	shifted := append([]byte("inserted"), data...)
+	shiftedSums, _ := cutAll(shifted)
+	same := 0
+	for sum := range shiftedSums {
+		if _, ok := sums[sum]; ok {
+			same++
+		}
+	}
+	require.Greater(t, same, len(sums)*9/10)
+
+	n, cut := chunker.Cut(data[:chunker.MinSize])
+	require.Equal(t, chunker.MinSize, n)
+	require.False(t, cut)
+}
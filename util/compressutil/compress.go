@@ -0,0 +1,144 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package compressutil
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+)
+
+// The codecs of the compressed data, the values are kept in the extent keys and must not be changed.
+const (
+	None uint8 = iota
+	Zstd
+	Lz4
+	Snappy
+)
+
+var codecNames = map[uint8]string{
+	None:   "none",
+	Zstd:   "zstd",
+	Lz4:    "lz4",
+	Snappy: "snappy",
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// Name returns the name of the codec.
+func Name(codec uint8) string {
+	if name, ok := codecNames[codec]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%v)", codec)
+}
+
+// Parse returns the codec of the name, the empty name is the same as "none".
+func Parse(name string) (codec uint8, err error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return None, nil
+	}
+	for codec, n := range codecNames {
+		if n == name {
+			return codec, nil
+		}
+	}
+	return None, fmt.Errorf("unsupported compression codec %v", name)
+}
+
+func initZstd() error {
+	zstdOnce.Do(func() {
+		if zstdEncoder, zstdErr = zstd.NewWriter(nil); zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdErr
+}
+
+// Compress returns the compressed data of the src, which may be larger than the src if the src
+// could not be compressed.
+func Compress(codec uint8, src []byte) (dst []byte, err error) {
+	switch codec {
+	case Zstd:
+		if err = initZstd(); err != nil {
+			return
+		}
+		return zstdEncoder.EncodeAll(src, make([]byte, 0, len(src))), nil
+	case Lz4:
+		dst = make([]byte, lz4.CompressBlockBound(len(src)))
+		var n int
+		if n, err = lz4.CompressBlock(src, dst, nil); err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			// the src is not compressible
+			return src, nil
+		}
+		return dst[:n], nil
+	case Snappy:
+		return snappy.Encode(nil, src), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %v", codec)
+	}
+}
+
+// Decompress returns the data decompressed from the src, an error is returned if the data is larger
+// than the maxSize.
+func Decompress(codec uint8, src []byte, maxSize int) (dst []byte, err error) {
+	switch codec {
+	case Zstd:
+		if err = initZstd(); err != nil {
+			return
+		}
+		if dst, err = zstdDecoder.DecodeAll(src, make([]byte, 0, maxSize)); err != nil {
+			return nil, err
+		}
+	case Lz4:
+		dst = make([]byte, maxSize)
+		var n int
+		if n, err = lz4.UncompressBlock(src, dst); err != nil {
+			return nil, err
+		}
+		dst = dst[:n]
+	case Snappy:
+		var n int
+		if n, err = snappy.DecodedLen(src); err != nil {
+			return nil, err
+		}
+		if n > maxSize {
+			return nil, fmt.Errorf("decompressed size %v exceeds %v", n, maxSize)
+		}
+		if dst, err = snappy.Decode(nil, src); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %v", codec)
+	}
+	if len(dst) > maxSize {
+		return nil, fmt.Errorf("decompressed size %v exceeds %v", len(dst), maxSize)
+	}
+	return dst, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package compressutil_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/cubefs/cubefs/util/compressutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	text := bytes.Repeat([]byte("2023-10-16 12:00:00 INFO request done\n"), 3000)
+	random := make([]byte, 64*1024)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+
+	for _, codec := range []uint8{compressutil.Zstd, compressutil.Lz4, compressutil.Snappy} {
+		frame, err := compressutil.Compress(codec, text)
+		require.NoError(t, err, compressutil.Name(codec))
+		require.Less(t, len(frame), len(text)/3, compressutil.Name(codec))
+		data, err := compressutil.Decompress(codec, frame, len(text))
+		require.NoError(t, err, compressutil.Name(codec))
+		require.Equal(t, text, data, compressutil.Name(codec))
+		_, err = compressutil.Decompress(codec, frame, len(text)-1)
+		require.Error(t, err, compressutil.Name(codec))
+
+		frame, err = compressutil.Compress(codec, random)
+		require.NoError(t, err, compressutil.Name(codec))
+		data, err = compressutil.Decompress(codec, frame, len(random))
+		require.NoError(t, err, compressutil.Name(codec))
+		require.Equal(t, random, data, compressutil.Name(codec))
+	}
+
+	_, err = compressutil.Compress(compressutil.None, text)
+	require.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	for _, codec := range []uint8{compressutil.None, compressutil.Zstd, compressutil.Lz4, compressutil.Snappy} {
+		parsed, err := compressutil.Parse(compressutil.Name(codec))
+		require.NoError(t, err)
+		require.Equal(t, codec, parsed)
+	}
+	codec, err := compressutil.Parse("")
+	require.NoError(t, err)
+	require.Equal(t, compressutil.None, codec)
+	_, err = compressutil.Parse("gzip")
+	require.Error(t, err)
+}
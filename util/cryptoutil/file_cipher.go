@@ -0,0 +1,118 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cryptoutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+)
+
+// DataKeySize is the size of the data keys of the encrypted files, which selects AES-256.
+const DataKeySize = 32
+
+// FileCipher encrypts and decrypts the data of a file with AES-CTR, the IV of which is the nonce
+// of the write followed by the counter derived from the file offset, so that any range of the
+// file can be written and read alone and the size of the data is unchanged. Every write takes a
+// new nonce kept in the extent key, so the keystream is never reused by the data overwritten.
+// It provides confidentiality only, the data changed by the data nodes is not detected.
+type FileCipher struct {
+	block cipher.Block
+}
+
+// NewFileCipher returns the cipher of the data key.
+func NewFileCipher(key []byte) (c *FileCipher, err error) {
+	if len(key) != DataKeySize {
+		return nil, fmt.Errorf("invalid data key size %v", len(key))
+	}
+	c = new(FileCipher)
+	if c.block, err = aes.NewCipher(key); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// XORKeyStreamAt encrypts or decrypts the src written with the nonce at the file offset into
+// the dst, which may be the same slice as the src.
+func (c *FileCipher) XORKeyStreamAt(dst, src []byte, nonce, offset uint64) {
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[:8], nonce)
+	binary.BigEndian.PutUint64(iv[8:], offset/aes.BlockSize)
+	stream := cipher.NewCTR(c.block, iv)
+	if skip := int(offset % aes.BlockSize); skip > 0 {
+		pad := make([]byte, skip)
+		stream.XORKeyStream(pad, pad)
+	}
+	stream.XORKeyStream(dst, src)
+}
+
+// GenNonce returns a random nonce for a new write, which is never 0 so that 0 is left for the
+// data not encrypted, such as the holes of the file.
+func GenNonce() (nonce uint64, err error) {
+	buf := make([]byte, 8)
+	for nonce == 0 {
+		if _, err = rand.Read(buf); err != nil {
+			return 0, err
+		}
+		nonce = binary.BigEndian.Uint64(buf)
+	}
+	return
+}
+
+// GenDataKey returns a random data key for a new file.
+func GenDataKey() (key []byte, err error) {
+	key = make([]byte, DataKeySize)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// WrapKey encrypts the data key with the master key by AES-GCM, the additional data is
+// authenticated but not encrypted.
+func WrapKey(masterKey, key, additional []byte) (wrapped []byte, err error) {
+	aead, err := newKeyWrapper(masterKey)
+	if err != nil {
+		return
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+	return aead.Seal(nonce, nonce, key, additional), nil
+}
+
+// UnwrapKey decrypts the data key wrapped by WrapKey.
+func UnwrapKey(masterKey, wrapped, additional []byte) (key []byte, err error) {
+	aead, err := newKeyWrapper(masterKey)
+	if err != nil {
+		return
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key too short")
+	}
+	nonce := wrapped[:aead.NonceSize()]
+	return aead.Open(nil, nonce, wrapped[aead.NonceSize():], additional)
+}
+
+func newKeyWrapper(masterKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cryptoutil_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cubefs/cubefs/util/cryptoutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCipher(t *testing.T) {
+	key, err := cryptoutil.GenDataKey()
+	require.NoError(t, err)
+	c, err := cryptoutil.NewFileCipher(key)
+	require.NoError(t, err)
+
+	nonce, err := cryptoutil.GenNonce()
+	require.NoError(t, err)
+	require.NotZero(t, nonce)
+
+	data := bytes.Repeat([]byte("0123456789abcdefghijklmnopqrstuvwxyz"), 100)
+	whole := make([]byte, len(data))
+	c.XORKeyStreamAt(whole, data, nonce, 0)
+	require.NotEqual(t, data, whole)
+
+	// any range encrypted alone is the same as the range of the whole file
+	for _, r := range [][2]int{{0, 1}, {5, 100}, {16, 32}, {17, 3000}, {3599, 3600}} {
+		part := make([]byte, r[1]-r[0])
+		c.XORKeyStreamAt(part, data[r[0]:r[1]], nonce, uint64(r[0]))
+		require.Equal(t, whole[r[0]:r[1]], part, "range %v", r)
+	}
+
+	plain := make([]byte, len(whole))
+	copy(plain, whole)
+	c.XORKeyStreamAt(plain[7:], plain[7:], nonce, 7)
+	c.XORKeyStreamAt(plain[:7], plain[:7], nonce, 0)
+	require.Equal(t, data, plain)
+
+	// the data overwritten with another nonce takes another keystream
+	other := make([]byte, len(data))
+	c.XORKeyStreamAt(other, data, nonce+1, 0)
+	require.NotEqual(t, whole, other)
+
+	_, err = cryptoutil.NewFileCipher(key[:16])
+	require.Error(t, err)
+}
+
+func TestWrapKey(t *testing.T) {
+	master, err := cryptoutil.GenDataKey()
+	require.NoError(t, err)
+	key, err := cryptoutil.GenDataKey()
+	require.NoError(t, err)
+
+	wrapped, err := cryptoutil.WrapKey(master, key, []byte("vol1"))
+	require.NoError(t, err)
+	unwrapped, err := cryptoutil.UnwrapKey(master, wrapped, []byte("vol1"))
+	require.NoError(t, err)
+	require.Equal(t, key, unwrapped)
+
+	_, err = cryptoutil.UnwrapKey(master, wrapped, []byte("vol2"))
+	require.Error(t, err)
+	other, err := cryptoutil.GenDataKey()
+	require.NoError(t, err)
+	_, err = cryptoutil.UnwrapKey(other, wrapped, []byte("vol1"))
+	require.Error(t, err)
+	_, err = cryptoutil.UnwrapKey(master, wrapped[:4], []byte("vol1"))
+	require.Error(t, err)
+}
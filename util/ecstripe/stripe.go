@@ -0,0 +1,176 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ecstripe lays the data of the erasure coded extents out in stripes. The logical space of
+// an extent is cut into stripes of DataNum cells, the cell i of every stripe is kept by the shard i
+// one after another, and the parity shards keep the cells encoded from the ones of the same stripe.
+// So the shard of every host of the partition is an extent of the same id, which is the size of the
+// logical data written divided by DataNum.
+package ecstripe
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/cubefs/cubefs/blobstore/common/codemode"
+	"github.com/cubefs/cubefs/blobstore/common/ec"
+)
+
+// CellSize is the size of the data kept by a shard for a stripe, the layout of the data written
+// depends on it, so it must not be changed.
+const CellSize = 4 * 1024
+
+const (
+	MinDataNum   = 2
+	MaxDataNum   = 12
+	MinParityNum = 1
+	MaxParityNum = 4
+)
+
+var encoders sync.Map // Layout -> ec.Encoder
+
+// Check returns an error if the numbers of the data and parity shards are not supported.
+func Check(dataNum, parityNum int) error {
+	if dataNum < MinDataNum || dataNum > MaxDataNum {
+		return fmt.Errorf("ec data shards %v should be %v to %v", dataNum, MinDataNum, MaxDataNum)
+	}
+	if parityNum < MinParityNum || parityNum > MaxParityNum {
+		return fmt.Errorf("ec parity shards %v should be %v to %v", parityNum, MinParityNum, MaxParityNum)
+	}
+	return nil
+}
+
+// Layout is the code of the erasure coded partition, RS(DataNum, ParityNum).
+type Layout struct {
+	DataNum   int
+	ParityNum int
+}
+
+func (l Layout) String() string {
+	return fmt.Sprintf("RS %v+%v", l.DataNum, l.ParityNum)
+}
+
+// ShardNum returns the number of the hosts keeping the shards.
+func (l Layout) ShardNum() int {
+	return l.DataNum + l.ParityNum
+}
+
+// StripeSize returns the size of the logical data of a stripe.
+func (l Layout) StripeSize() int64 {
+	return int64(l.DataNum) * CellSize
+}
+
+// ShardOffset returns the offset in the shards of the stripe starting from the logical offset.
+func (l Layout) ShardOffset(offset int64) int64 {
+	return offset / l.StripeSize() * CellSize
+}
+
+// LogicalOffset returns the logical offset of the stripe starting from the offset in the shards.
+func (l Layout) LogicalOffset(shardOffset int64) int64 {
+	return shardOffset / CellSize * l.StripeSize()
+}
+
+// StripeRange returns the logical range of the stripes touched by the range.
+func (l Layout) StripeRange(offset, size int64) (start, end int64) {
+	stripeSize := l.StripeSize()
+	start = offset / stripeSize * stripeSize
+	end = (offset + size + stripeSize - 1) / stripeSize * stripeSize
+	return
+}
+
+// InnerStripeRange returns the logical range of the stripes covered by the range entirely, end
+// is not greater than start if there is none.
+func (l Layout) InnerStripeRange(offset, size int64) (start, end int64) {
+	stripeSize := l.StripeSize()
+	start = (offset + stripeSize - 1) / stripeSize * stripeSize
+	end = (offset + size) / stripeSize * stripeSize
+	return
+}
+
+// DataShardsOf returns the indexes of the data shards keeping the cells of the range.
+func (l Layout) DataShardsOf(offset, size int64) (indexes []int) {
+	if size <= 0 {
+		return
+	}
+	stripeSize := l.StripeSize()
+	if offset/stripeSize != (offset+size-1)/stripeSize {
+		// the range across stripes may leave out the middle cells of the first and last stripes
+		first := int(offset % stripeSize / CellSize)
+		last := int((offset + size - 1) % stripeSize / CellSize)
+		across := (offset+size-1)/stripeSize-offset/stripeSize > 1
+		for i := 0; i < l.DataNum; i++ {
+			if across || i >= first || i <= last {
+				indexes = append(indexes, i)
+			}
+		}
+		return
+	}
+	first := int(offset % stripeSize / CellSize)
+	last := int((offset + size - 1) % stripeSize / CellSize)
+	for i := first; i <= last; i++ {
+		indexes = append(indexes, i)
+	}
+	return
+}
+
+// Split cuts the data of whole stripes into the shards, the data shards keep the cells of the
+// stripes and the parity shards are allocated to be encoded.
+func (l Layout) Split(data []byte) (shards [][]byte, err error) {
+	stripeSize := int(l.StripeSize())
+	if len(data)%stripeSize != 0 {
+		return nil, fmt.Errorf("data size %v is not aligned to the stripe size %v", len(data), stripeSize)
+	}
+	stripes := len(data) / stripeSize
+	shards = make([][]byte, l.ShardNum())
+	for i := range shards {
+		shards[i] = make([]byte, stripes*CellSize)
+	}
+	for s := 0; s < stripes; s++ {
+		for i := 0; i < l.DataNum; i++ {
+			copy(shards[i][s*CellSize:(s+1)*CellSize], data[s*stripeSize+i*CellSize:])
+		}
+	}
+	return
+}
+
+// Join copies the logical data from the offset to the dst, the data shards keep the cells of the
+// stripes from the logical offset stripeStart.
+func (l Layout) Join(dst []byte, shards [][]byte, stripeStart, offset int64) {
+	stripeSize := l.StripeSize()
+	for n := 0; n < len(dst); {
+		pos := offset + int64(n)
+		stripe := (pos - stripeStart) / stripeSize
+		inStripe := pos % stripeSize
+		cellOffset := inStripe % CellSize
+		shard := shards[inStripe/CellSize]
+		from := stripe*CellSize + cellOffset
+		n += copy(dst[n:], shard[from:from+CellSize-cellOffset])
+	}
+}
+
+// Encoder returns the encoder of the code, which is shared by the callers.
+func (l Layout) Encoder() (encoder ec.Encoder, err error) {
+	if val, ok := encoders.Load(l); ok {
+		return val.(ec.Encoder), nil
+	}
+	if err = Check(l.DataNum, l.ParityNum); err != nil {
+		return
+	}
+	tactic := codemode.Tactic{N: l.DataNum, M: l.ParityNum, AZCount: 1, PutQuorum: l.ShardNum()}
+	if encoder, err = ec.NewEncoder(ec.Config{CodeMode: tactic}); err != nil {
+		return
+	}
+	val, _ := encoders.LoadOrStore(l, encoder)
+	return val.(ec.Encoder), nil
+}
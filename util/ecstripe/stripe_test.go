@@ -0,0 +1,91 @@
+// Copyright 2023 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ecstripe
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	require.NoError(t, Check(4, 2))
+	require.NoError(t, Check(6, 3))
+	require.Error(t, Check(1, 2))
+	require.Error(t, Check(4, 0))
+	require.Error(t, Check(MaxDataNum+1, 2))
+	require.Error(t, Check(4, MaxParityNum+1))
+}
+
+func TestRanges(t *testing.T) {
+	l := Layout{DataNum: 4, ParityNum: 2}
+	stripe := l.StripeSize()
+	require.Equal(t, int64(4*CellSize), stripe)
+	require.Equal(t, int64(2*CellSize), l.ShardOffset(2*stripe))
+	require.Equal(t, 2*stripe, l.LogicalOffset(2*CellSize))
+
+	start, end := l.StripeRange(stripe+1, stripe)
+	require.Equal(t, stripe, start)
+	require.Equal(t, 3*stripe, end)
+	start, end = l.InnerStripeRange(stripe+1, 2*stripe)
+	require.Equal(t, 2*stripe, start)
+	require.Equal(t, 3*stripe, end)
+	start, end = l.InnerStripeRange(1, stripe)
+	require.True(t, end <= start)
+
+	require.Equal(t, []int{1}, l.DataShardsOf(CellSize+1, 10))
+	require.Equal(t, []int{1, 2}, l.DataShardsOf(CellSize, 2*CellSize))
+	require.Equal(t, []int{0, 3}, l.DataShardsOf(stripe-1, 2))
+	require.Equal(t, []int{0, 1, 2, 3}, l.DataShardsOf(stripe-1, stripe+2))
+	require.Empty(t, l.DataShardsOf(0, 0))
+}
+
+func TestEncodeAndReconstruct(t *testing.T) {
+	l := Layout{DataNum: 4, ParityNum: 2}
+	data := make([]byte, 3*l.StripeSize())
+	rand.Read(data)
+	_, err := l.Split(data[1:])
+	require.Error(t, err)
+	shards, err := l.Split(data)
+	require.NoError(t, err)
+	require.Len(t, shards, l.ShardNum())
+	require.Equal(t, data[l.StripeSize():l.StripeSize()+CellSize], shards[0][CellSize:2*CellSize])
+
+	encoder, err := l.Encoder()
+	require.NoError(t, err)
+	require.NoError(t, encoder.Encode(shards))
+
+	// lose as many shards as the parity ones
+	saved := [][]byte{shards[1], shards[4]}
+	shards[1], shards[4] = shards[1][:0], shards[4][:0]
+	require.NoError(t, encoder.Reconstruct(shards, []int{1, 4}))
+	require.True(t, bytes.Equal(saved[0], shards[1]))
+	require.True(t, bytes.Equal(saved[1], shards[4]))
+
+	dst := make([]byte, l.StripeSize()+100)
+	offset := l.StripeSize() - 50
+	l.Join(dst, shards, 0, offset)
+	require.Equal(t, data[offset:offset+int64(len(dst))], dst)
+
+	// the shards from the second stripe
+	part := make([][]byte, l.DataNum)
+	for i := range part {
+		part[i] = shards[i][CellSize:]
+	}
+	l.Join(dst, part, l.StripeSize(), offset+100)
+	require.Equal(t, data[offset+100:offset+100+int64(len(dst))], dst)
+}